@@ -94,30 +94,30 @@ ORDER BY Role_Name;
 
 
 -- ============================================
--- Table: Role_Permission
+-- Table: RolePermission
 -- Columns: Role_ID, PermissionID
 -- ============================================
 
 -- INSERT
-INSERT INTO Role_Permission (Role_ID, PermissionID)
+INSERT INTO RolePermission (Role_ID, PermissionID)
 VALUES ($1, $2);
 
 -- UPDATE (Not applicable for junction table - delete and insert instead)
 -- N/A
 
 -- DELETE
-DELETE FROM Role_Permission
+DELETE FROM RolePermission
 WHERE Role_ID = $1 AND PermissionID = $2;
 
 -- VIEW by Role_ID
 SELECT rp.Role_ID, rp.PermissionID, p.ModuleName, p.ActionType
-FROM Role_Permission rp
+FROM RolePermission rp
 JOIN Permission p ON rp.PermissionID = p.PermissionID
 WHERE rp.Role_ID = $1;
 
 -- VIEW all
 SELECT rp.Role_ID, rp.PermissionID, r.Role_Name, p.ModuleName, p.ActionType
-FROM Role_Permission rp
+FROM RolePermission rp
 JOIN Role r ON rp.Role_ID = r.Role_ID
 JOIN Permission p ON rp.PermissionID = p.PermissionID
 ORDER BY r.Role_Name, p.ModuleName;
@@ -34,17 +34,17 @@ ORDER BY Name;
 
 -- ============================================
 -- Table: ProductType
--- Columns: ProductTypeID, Name, Description, Grade, UnitOfMeasure
+-- Columns: ProductTypeID, Name, Description, Price, Grade, UnitOfMeasure
 -- ============================================
 
 -- INSERT
-INSERT INTO ProductType (Name, Description, Grade, UnitOfMeasure)
-VALUES ($1, $2, $3, $4)
+INSERT INTO ProductType (Name, Description, Price, Grade, UnitOfMeasure)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING ProductTypeID;
 
 -- UPDATE
 UPDATE ProductType
-SET Name = $2, Description = $3, Grade = $4, UnitOfMeasure = $5
+SET Name = $2, Description = $3, Price = $4, Grade = $5, UnitOfMeasure = $6
 WHERE ProductTypeID = $1;
 
 -- DELETE
@@ -52,12 +52,12 @@ DELETE FROM ProductType
 WHERE ProductTypeID = $1;
 
 -- VIEW by ID
-SELECT ProductTypeID, Name, Description, Grade, UnitOfMeasure
+SELECT ProductTypeID, Name, Description, Price, Grade, UnitOfMeasure
 FROM ProductType
 WHERE ProductTypeID = $1;
 
 -- VIEW all
-SELECT ProductTypeID, Name, Description, Grade, UnitOfMeasure
+SELECT ProductTypeID, Name, Description, Price, Grade, UnitOfMeasure
 FROM ProductType
 ORDER BY Name;
 
@@ -199,7 +199,7 @@ SELECT u.User_ID, u.Email, u.First_Name, u.Last_Name, u.Status,
        p.PermissionID, p.ModuleName, p.ActionType
 FROM "User" u
 LEFT JOIN Role r ON u.User_ID = r.User_ID
-LEFT JOIN Role_Permission rp ON r.Role_ID = rp.Role_ID
+LEFT JOIN RolePermission rp ON r.Role_ID = rp.Role_ID
 LEFT JOIN Permission p ON rp.PermissionID = p.PermissionID
 WHERE u.User_ID = $1
 ORDER BY r.Role_Name, p.ModuleName, p.ActionType;
@@ -217,7 +217,7 @@ SELECT DISTINCT u.User_ID, u.Email, u.First_Name, u.Last_Name, u.Status,
        r.Role_Name
 FROM "User" u
 JOIN Role r ON u.User_ID = r.User_ID
-JOIN Role_Permission rp ON r.Role_ID = rp.Role_ID
+JOIN RolePermission rp ON r.Role_ID = rp.Role_ID
 JOIN Permission p ON rp.PermissionID = p.PermissionID
 WHERE p.ModuleName = $1 AND p.ActionType = $2
 ORDER BY u.Email;
@@ -237,7 +237,7 @@ SELECT r.Role_ID, r.Role_Name, r.Description,
        COUNT(DISTINCT u.User_ID) AS TotalUsers,
        STRING_AGG(DISTINCT p.ModuleName, ', ') AS Modules
 FROM Role r
-LEFT JOIN Role_Permission rp ON r.Role_ID = rp.Role_ID
+LEFT JOIN RolePermission rp ON r.Role_ID = rp.Role_ID
 LEFT JOIN Permission p ON rp.PermissionID = p.PermissionID
 LEFT JOIN "User" u ON r.User_ID = u.User_ID
 GROUP BY r.Role_ID, r.Role_Name, r.Description
@@ -248,7 +248,7 @@ SELECT p.ModuleName, p.ActionType,
        COUNT(DISTINCT rp.Role_ID) AS RolesWithPermission,
        STRING_AGG(DISTINCT r.Role_Name, ', ') AS Roles
 FROM Permission p
-LEFT JOIN Role_Permission rp ON p.PermissionID = rp.PermissionID
+LEFT JOIN RolePermission rp ON p.PermissionID = rp.PermissionID
 LEFT JOIN Role r ON rp.Role_ID = r.Role_ID
 GROUP BY p.ModuleName, p.ActionType
 ORDER BY p.ModuleName, p.ActionType;
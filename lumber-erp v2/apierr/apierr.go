@@ -0,0 +1,137 @@
+// Package apierr turns the errors of the repository layer into HTTP
+// responses with a stable envelope:
+//
+//	{"code": "conflict", "message": "plate_number already exists",
+//	 "details": {"field": "plate_number"}, "request_id": "3f2a9c1e7b4d8a06"}
+//
+// Database errors are classified by their Postgres error code, so clients
+// never see SQL text.
+package apierr
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"lumber-erp-api/repository"
+	"lumber-erp-api/utils"
+	"lumber-erp-api/validate"
+)
+
+// Error is an error with the status, code and details it is answered with
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// New returns an error answered with the given status and code
+func New(status int, code, message string, details interface{}) *Error {
+	return &Error{Status: status, Code: code, Message: message, Details: details}
+}
+
+// Postgres error codes that are the client's fault
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	notNullViolation    = "23502"
+	invalidText         = "22P02"
+)
+
+// internal hides the cause of unexpected errors from clients
+var internal = New(http.StatusInternalServerError, "internal", "Internal server error", nil)
+
+// From classifies err; errors it does not recognise are internal
+func From(err error) *Error {
+	var e *Error
+	var invalid validate.Errors
+	var conflict *repository.ConflictError
+	var reference *repository.ReferenceError
+	var rule *repository.RuleError
+	var pqErr *pq.Error
+
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, repository.ErrNotFound):
+		return New(http.StatusNotFound, "not_found", "Not found", nil)
+	case errors.As(err, &invalid):
+		return New(http.StatusUnprocessableEntity, "validation_failed", "Validation failed", invalid)
+	case errors.As(err, &conflict):
+		return conflictError(conflict.Field)
+	case errors.As(err, &reference):
+		return referenceError(reference.Field, reference.Table)
+	case errors.As(err, &rule):
+		return New(http.StatusConflict, rule.Code, rule.Message, rule.Details)
+	case errors.As(err, &pqErr):
+		return fromPostgres(pqErr)
+	}
+	return internal
+}
+
+// Respond answers with err's envelope. Internal errors are logged with the
+// request ID, since their cause is not sent.
+func Respond(w http.ResponseWriter, err error) {
+	e := From(err)
+	if e == internal {
+		slog.Error("request failed", "err", err, "request_id", w.Header().Get(utils.RequestIDHeader))
+	}
+	utils.RespondErrorCode(w, e.Status, e.Code, e.Message, e.Details)
+}
+
+func conflictError(field string) *Error {
+	return New(http.StatusConflict, "conflict", field+" already exists", map[string]string{"field": field})
+}
+
+func referenceError(field, table string) *Error {
+	return New(http.StatusUnprocessableEntity, "invalid_reference",
+		field+" refers to a "+table+" that does not exist",
+		map[string]string{"field": field, "table": table})
+}
+
+// keyDetail reads the columns and referenced table out of constraint
+// details such as
+//
+//	Key (supplierid)=(99) is not present in table "supplier".
+var keyDetail = regexp.MustCompile(`^Key \(([^)]*)\)=\(.*\) (?:already exists|is (not present in|still referenced from) table "([^"]+)")`)
+
+func fromPostgres(err *pq.Error) *Error {
+	var columns, relation, table string
+	if m := keyDetail.FindStringSubmatch(err.Detail); m != nil {
+		columns, relation, table = m[1], m[2], m[3]
+	}
+	fields := make([]string, 0, 2)
+	for _, column := range strings.Split(columns, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			fields = append(fields, repository.ColumnField(column))
+		}
+	}
+	field := strings.Join(fields, ", ")
+
+	switch string(err.Code) {
+	case uniqueViolation:
+		return conflictError(field)
+	case foreignKeyViolation:
+		if relation == "still referenced from" {
+			return New(http.StatusConflict, "in_use", "The row is still referenced from "+table,
+				map[string]string{"table": table})
+		}
+		return referenceError(field, table)
+	case notNullViolation:
+		field = repository.ColumnField(err.Column)
+		return New(http.StatusUnprocessableEntity, "validation_failed", "Validation failed", validate.Errors{{
+			Field: field, Code: validate.CodeRequired, Message: field + " is required",
+		}})
+	case invalidText:
+		// The message names the type and value, not the statement
+		return New(http.StatusBadRequest, "invalid_input", strings.ToUpper(err.Message[:1])+err.Message[1:], nil)
+	}
+	return internal
+}
@@ -0,0 +1,201 @@
+// Package auditchain verifies the hash chain of the audit log and signs
+// checkpoints of its head with Ed25519, so chain-of-custody auditors can
+// check an exported copy with the public key alone.
+package auditchain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+)
+
+// Algorithm names the checkpoint signature scheme in exports
+const Algorithm = "ed25519"
+
+var signingKey ed25519.PrivateKey
+
+// SetSigningKey sets the Ed25519 seed checkpoints are signed with
+func SetSigningKey(seed []byte) {
+	signingKey = ed25519.NewKeyFromSeed(seed)
+}
+
+func key() ed25519.PrivateKey {
+	if signingKey == nil {
+		log.Println("⚠️  audit.signing_key not configured, using a random key (checkpoints will not verify after a restart)")
+		seed := make([]byte, ed25519.SeedSize)
+		rand.Read(seed)
+		signingKey = ed25519.NewKeyFromSeed(seed)
+	}
+	return signingKey
+}
+
+// PublicKey returns the base64 key that verifies checkpoint signatures
+func PublicKey() string {
+	return base64.StdEncoding.EncodeToString(key().Public().(ed25519.PublicKey))
+}
+
+// message is what a checkpoint signature covers
+func message(cp models.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("lumber-erp audit checkpoint\nlog_id=%d\nhash=%s\ncreated_at=%s\n",
+		cp.LogID, cp.Hash, cp.CreatedAt))
+}
+
+// Signed reports whether cp carries a valid signature
+func Signed(cp models.AuditCheckpoint) bool {
+	signature, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key().Public().(ed25519.PublicKey), message(cp), signature)
+}
+
+// ErrNothingToCheckpoint is returned when no chained entry was added since
+// the last checkpoint
+var ErrNothingToCheckpoint = apierr.New(http.StatusConflict, "nothing_to_checkpoint",
+	"No audit entries were added since the last checkpoint", nil)
+
+// Checkpoint signs the current head of the chain
+func Checkpoint(ctx context.Context, repo repository.AuditRepository) (models.AuditCheckpoint, error) {
+	var cp models.AuditCheckpoint
+	head, err := repo.LatestAuditLog(ctx)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && head.Hash == "" {
+		return cp, ErrNothingToCheckpoint
+	}
+	if err != nil {
+		return cp, err
+	}
+	checkpoints, err := repo.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return cp, err
+	}
+	if n := len(checkpoints); n > 0 && checkpoints[n-1].LogID == head.LogID {
+		return cp, ErrNothingToCheckpoint
+	}
+
+	cp = models.AuditCheckpoint{
+		LogID:     head.LogID,
+		Hash:      head.Hash,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key(), message(cp)))
+	return cp, repo.CreateAuditCheckpoint(ctx, &cp)
+}
+
+// Run writes a checkpoint every interval until ctx is cancelled
+func Run(ctx context.Context, repo repository.AuditRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := Checkpoint(ctx, repo); err != nil && err != ErrNothingToCheckpoint {
+				log.Printf("⚠️  audit checkpoint failed: %v", err)
+			}
+		}
+	}
+}
+
+// Report is the outcome of Verify. BrokenAt is the LogID of the first entry
+// that does not match the chain, or of the entry a bad checkpoint names.
+type Report struct {
+	Valid       bool   `json:"valid"`
+	Entries     int    `json:"entries"`
+	Unchained   int    `json:"unchained"` // entries written before the chain existed
+	Checkpoints int    `json:"checkpoints"`
+	Head        string `json:"head,omitempty"`
+	BrokenAt    int    `json:"broken_at,omitempty"`
+	Checkpoint  int    `json:"checkpoint_id,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// errBroken stops the walk at the first broken link
+var errBroken = errors.New("chain broken")
+
+// Verify walks the chain from the first entry, recomputing every hash, and
+// then checks each checkpoint's signature against the entry it names
+func Verify(ctx context.Context, repo repository.AuditRepository) (Report, error) {
+	var report Report
+	hashes := map[int]string{}
+	prev, chained := "", false
+
+	err := repo.WalkAuditLogs(ctx, func(al models.AuditLog) error {
+		report.Entries++
+		broken := func(reason string) error {
+			report.BrokenAt, report.Reason = al.LogID, reason
+			return errBroken
+		}
+		switch {
+		case al.Hash == "" && !chained:
+			report.Unchained++
+			return nil
+		case al.Hash == "":
+			return broken("entry has no hash")
+		case al.PrevHash != prev:
+			return broken("previous hash does not match the entry before it")
+		case repository.AuditHash(al) != al.Hash:
+			return broken("content does not match its hash")
+		}
+		chained, prev = true, al.Hash
+		hashes[al.LogID] = al.Hash
+		return nil
+	})
+	if err != nil && err != errBroken {
+		return report, err
+	}
+	report.Head = prev
+	if report.Reason != "" {
+		return report, nil
+	}
+
+	checkpoints, err := repo.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, cp := range checkpoints {
+		report.Checkpoints++
+		reason := ""
+		switch {
+		case !Signed(cp):
+			reason = "checkpoint signature is invalid"
+		case hashes[cp.LogID] != cp.Hash:
+			reason = "checkpointed entry is missing or was changed"
+		}
+		if reason != "" {
+			report.BrokenAt, report.Checkpoint, report.Reason = cp.LogID, cp.CheckpointID, reason
+			return report, nil
+		}
+	}
+	report.Valid = true
+	return report, nil
+}
+
+// Export is the downloadable checkpoint file
+type Export struct {
+	Algorithm   string                   `json:"algorithm"`
+	PublicKey   string                   `json:"public_key"`
+	ExportedAt  string                   `json:"exported_at"`
+	Checkpoints []models.AuditCheckpoint `json:"checkpoints"`
+}
+
+// NewExport bundles every checkpoint with the key that verifies them
+func NewExport(ctx context.Context, repo repository.AuditRepository) (Export, error) {
+	checkpoints, err := repo.ListAuditCheckpoints(ctx)
+	return Export{
+		Algorithm:   Algorithm,
+		PublicKey:   PublicKey(),
+		ExportedAt:  time.Now().UTC().Format(time.RFC3339),
+		Checkpoints: checkpoints,
+	}, err
+}
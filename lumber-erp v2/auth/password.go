@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptCost is the work factor used for new password hashes
+const BcryptCost = 12
+
+// HashPassword returns a bcrypt hash of the given plaintext password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// IsHashed reports whether a stored password value is already a bcrypt hash.
+// Rows created before hashing was introduced still hold plaintext.
+func IsHashed(stored string) bool {
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// CheckPassword compares a plaintext password against the stored value.
+// needsRehash is true when the stored value was plaintext and matched, so the
+// caller should replace it with a proper hash.
+func CheckPassword(stored, password string) (ok bool, needsRehash bool) {
+	if IsHashed(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+	}
+	if stored == "" {
+		return false, false
+	}
+	ok = subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	return ok, ok
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims is the payload carried by both access and refresh tokens
+type Claims struct {
+	UserID    int    `json:"sub"`
+	Email     string `json:"email"`
+	SessionID int    `json:"sid"`
+	TokenType string `json:"typ"`
+	TokenID   string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+var (
+	signingKey []byte
+	// fallback makes the random key once, however many first requests race
+	fallback sync.Once
+)
+
+// SetSigningKey sets the HMAC key used to sign and verify tokens. Call it
+// before serving requests.
+func SetSigningKey(key []byte) {
+	signingKey = key
+}
+
+func key() []byte {
+	fallback.Do(func() {
+		if signingKey == nil {
+			log.Println("⚠️  auth.secret not configured, using a random signing key (tokens will not survive a restart)")
+			random := make([]byte, 32)
+			rand.Read(random)
+			signingKey = random
+		}
+	})
+	return signingKey
+}
+
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// IssueToken signs a token of the given type for the user and session
+func IssueToken(tokenType string, userID int, email string, sessionID int) (string, Claims, error) {
+	ttl := AccessTokenTTL
+	if tokenType == TokenTypeRefresh {
+		ttl = RefreshTokenTTL
+	}
+
+	now := time.Now()
+	claims := Claims{
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
+		TokenType: tokenType,
+		TokenID:   randomID(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", claims, err
+	}
+
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + sign(unsigned), claims, nil
+}
+
+// ParseToken verifies the signature, expiry and type of a token
+func ParseToken(token, expectedType string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, ErrInvalidToken
+	}
+
+	expected := sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.TokenType != expectedType {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// HashToken returns the hex SHA-256 of a token, used to store refresh tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer ..." header
+func BearerToken(header string) string {
+	const prefix = "Bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
+
+func sign(unsigned string) string {
+	mac := hmac.New(sha256.New, key())
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFallbackKey(t *testing.T) {
+	signingKey = nil
+
+	// Concurrent first uses must agree on one random key
+	tokens := make([]string, 8)
+	var wg sync.WaitGroup
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _, _ = IssueToken(TokenTypeAccess, 1, "a@example.com", 1)
+		}(i)
+	}
+	wg.Wait()
+	for i, token := range tokens {
+		if _, err := ParseToken(token, TokenTypeAccess); err != nil {
+			t.Errorf("token %d: %v", i, err)
+		}
+	}
+}
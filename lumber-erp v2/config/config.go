@@ -0,0 +1,420 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"lumber-erp-api/utils"
+)
+
+// Config holds every externally configurable setting of the API.
+// Values are resolved in order: built-in defaults, the config file, the
+// profile section selected by LUMBER_ENV, and finally LUMBER_* env variables.
+type Config struct {
+	Env        string           `yaml:"env"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	Database   DatabaseConfig   `yaml:"database"`
+	CORS       CORSConfig       `yaml:"cors"`
+	Log        LogConfig        `yaml:"log"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Audit      AuditConfig      `yaml:"audit"`
+	Processing ProcessingConfig `yaml:"processing"`
+	Quality    QualityConfig    `yaml:"quality"`
+}
+
+type HTTPConfig struct {
+	Addr string `yaml:"addr"`
+	// TrustedProxies are the proxies, as CIDR ranges or addresses, whose
+	// X-Forwarded-For header names the client
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+type DatabaseConfig struct {
+	DSN             string        `yaml:"dsn"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+}
+
+type AuthConfig struct {
+	Secret string `yaml:"secret"`
+}
+
+type AuditConfig struct {
+	// SigningKey is the base64 Ed25519 seed checkpoints are signed with
+	SigningKey string `yaml:"signing_key"`
+	// CheckpointInterval is how often the chain head is signed; 0 disables it
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval"`
+}
+
+type ProcessingConfig struct {
+	// MassBalanceTolerance is the percentage of an order's input volume its
+	// output plus waste may differ by before the order is flagged
+	MassBalanceTolerance float64 `yaml:"mass_balance_tolerance"`
+	// MaintenanceCheckInterval is how often due maintenance plans raise
+	// their work orders; 0 disables it
+	MaintenanceCheckInterval time.Duration `yaml:"maintenance_check_interval"`
+	// KilnMoistureTolerance is how many moisture points either side of its
+	// target a kiln charge may end at when it does not set its own
+	KilnMoistureTolerance float64 `yaml:"kiln_moisture_tolerance"`
+}
+
+type QualityConfig struct {
+	// SPCCheckInterval is how often the moisture control charts raise the
+	// alerts of their rule violations; 0 disables it
+	SPCCheckInterval time.Duration `yaml:"spc_check_interval"`
+}
+
+// Seed decodes the signing key
+func (a AuditConfig) Seed() ([]byte, error) {
+	seed, err := base64.StdEncoding.DecodeString(a.SigningKey)
+	if err == nil && len(seed) != ed25519.SeedSize {
+		err = fmt.Errorf("must decode to %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return seed, err
+}
+
+// fileConfig is the on-disk layout: a base config plus named profiles
+type fileConfig struct {
+	Config   `yaml:",inline"`
+	Profiles map[string]yaml.Node `yaml:"profiles"`
+}
+
+// DefaultPath is used when neither -config nor LUMBER_CONFIG is given
+const DefaultPath = "config.yaml"
+
+// Defaults returns the settings used for local development
+func Defaults() Config {
+	return Config{
+		Env:  "development",
+		HTTP: HTTPConfig{Addr: ":5000"},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Name:            "pern_todo",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		CORS:       CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}},
+		Log:        LogConfig{Level: "info"},
+		Audit:      AuditConfig{CheckpointInterval: 24 * time.Hour},
+		Processing: ProcessingConfig{MassBalanceTolerance: 2, MaintenanceCheckInterval: time.Hour, KilnMoistureTolerance: 1},
+		Quality:    QualityConfig{SPCCheckInterval: time.Hour},
+	}
+}
+
+// Load resolves the configuration. An explicitly requested file must exist;
+// the default config.yaml is optional.
+func Load(path string) (*Config, error) {
+	cfg := Defaults()
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("LUMBER_CONFIG")
+		explicit = path != ""
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+
+	env := os.Getenv("LUMBER_ENV")
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var file fileConfig
+		file.Config = cfg
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		cfg = file.Config
+
+		if env == "" {
+			env = cfg.Env
+		}
+		if profile, ok := file.Profiles[env]; ok {
+			if err := profile.Decode(&cfg); err != nil {
+				return nil, fmt.Errorf("parsing profile %q in %s: %w", env, path, err)
+			}
+		} else if env != "" && len(file.Profiles) > 0 && os.Getenv("LUMBER_ENV") != "" {
+			return nil, fmt.Errorf("profile %q not found in %s", env, path)
+		}
+	case os.IsNotExist(err) && !explicit:
+		// No config file: defaults plus environment only
+	default:
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	if env != "" {
+		cfg.Env = env
+	}
+	problems := applyEnv(&cfg)
+	if err, ok := cfg.Validate().(*ValidationError); ok {
+		problems = append(problems, err.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &cfg, nil
+}
+
+// applyEnv overlays LUMBER_* environment variables onto the config and
+// returns any values that could not be parsed
+func applyEnv(cfg *Config) []string {
+	var problems []string
+
+	str := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	num := func(key string, dst *int) {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not an integer", key, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	decimal := func(key string, dst *float64) {
+		if v, ok := os.LookupEnv(key); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a number", key, v))
+				return
+			}
+			*dst = f
+		}
+	}
+	dur := func(key string, dst *time.Duration) {
+		if v, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a duration (e.g. 30s, 5m)", key, v))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	str("LUMBER_HTTP_ADDR", &cfg.HTTP.Addr)
+	str("LUMBER_DB_DSN", &cfg.Database.DSN)
+	str("LUMBER_DB_HOST", &cfg.Database.Host)
+	num("LUMBER_DB_PORT", &cfg.Database.Port)
+	str("LUMBER_DB_USER", &cfg.Database.User)
+	str("LUMBER_DB_PASSWORD", &cfg.Database.Password)
+	str("LUMBER_DB_NAME", &cfg.Database.Name)
+	str("LUMBER_DB_SSLMODE", &cfg.Database.SSLMode)
+	num("LUMBER_DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	num("LUMBER_DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	dur("LUMBER_DB_CONN_MAX_LIFETIME", &cfg.Database.ConnMaxLifetime)
+	dur("LUMBER_DB_CONN_MAX_IDLE_TIME", &cfg.Database.ConnMaxIdleTime)
+	str("LUMBER_LOG_LEVEL", &cfg.Log.Level)
+	str("LUMBER_AUTH_SECRET", &cfg.Auth.Secret)
+	str("LUMBER_AUDIT_SIGNING_KEY", &cfg.Audit.SigningKey)
+	dur("LUMBER_AUDIT_CHECKPOINT_INTERVAL", &cfg.Audit.CheckpointInterval)
+	decimal("LUMBER_PROCESSING_MASS_BALANCE_TOLERANCE", &cfg.Processing.MassBalanceTolerance)
+	dur("LUMBER_PROCESSING_MAINTENANCE_CHECK_INTERVAL", &cfg.Processing.MaintenanceCheckInterval)
+	decimal("LUMBER_PROCESSING_KILN_MOISTURE_TOLERANCE", &cfg.Processing.KilnMoistureTolerance)
+	dur("LUMBER_QUALITY_SPC_CHECK_INTERVAL", &cfg.Quality.SPCCheckInterval)
+
+	list := func(key string, dst *[]string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = nil
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*dst = append(*dst, item)
+				}
+			}
+		}
+	}
+	list("LUMBER_CORS_ORIGINS", &cfg.CORS.AllowedOrigins)
+	list("LUMBER_TRUSTED_PROXIES", &cfg.HTTP.TrustedProxies)
+
+	return problems
+}
+
+// ValidationError lists every problem found so they can be fixed in one go
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the resolved configuration and reports all problems at once
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		add("http.addr: %q is not a valid listen address (e.g. :5000)", c.HTTP.Addr)
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if utils.ParseProxy(proxy) == nil {
+			add("http.trusted_proxies: %q is not an address or CIDR range like 10.0.0.0/8", proxy)
+		}
+	}
+
+	db := c.Database
+	if db.DSN == "" {
+		if db.Host == "" {
+			add("database.host is required when database.dsn is not set")
+		}
+		if db.Port <= 0 || db.Port > 65535 {
+			add("database.port: %d is out of range", db.Port)
+		}
+		if db.User == "" {
+			add("database.user is required when database.dsn is not set")
+		}
+		if db.Name == "" {
+			add("database.name is required when database.dsn is not set")
+		}
+	} else if strings.Contains(db.DSN, "://") {
+		if _, err := url.Parse(db.DSN); err != nil {
+			add("database.dsn: %v", err)
+		}
+	}
+	switch db.SSLMode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		add("database.sslmode: %q must be one of disable, require, verify-ca, verify-full", db.SSLMode)
+	}
+	if db.MaxOpenConns < 0 {
+		add("database.max_open_conns must not be negative")
+	}
+	if db.MaxIdleConns < 0 {
+		add("database.max_idle_conns must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		add("database.max_idle_conns (%d) cannot exceed max_open_conns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	if db.ConnMaxLifetime < 0 || db.ConnMaxIdleTime < 0 {
+		add("database connection timeouts must not be negative")
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		add("cors.allowed_origins must list at least one origin (use \"*\" to allow any)")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			add("cors.allowed_origins: %q is not an origin like https://erp.example.com", origin)
+		}
+	}
+
+	if _, err := c.Log.SlogLevel(); err != nil {
+		add("log.level: %v", err)
+	}
+
+	if c.Audit.SigningKey != "" {
+		if _, err := c.Audit.Seed(); err != nil {
+			add("audit.signing_key: %v", err)
+		}
+	}
+	if c.Audit.CheckpointInterval < 0 {
+		add("audit.checkpoint_interval must not be negative")
+	}
+	if t := c.Processing.MassBalanceTolerance; t < 0 || t > 100 {
+		add("processing.mass_balance_tolerance: %g must be between 0 and 100", t)
+	}
+	if c.Processing.MaintenanceCheckInterval < 0 {
+		add("processing.maintenance_check_interval must not be negative")
+	}
+	if t := c.Processing.KilnMoistureTolerance; t < 0 || t > 100 {
+		add("processing.kiln_moisture_tolerance: %g must be between 0 and 100", t)
+	}
+	if c.Quality.SPCCheckInterval < 0 {
+		add("quality.spc_check_interval must not be negative")
+	}
+
+	if c.Env == "production" {
+		if len(c.Auth.Secret) < 32 {
+			add("auth.secret must be at least 32 characters in production")
+		}
+		if c.Audit.SigningKey == "" {
+			add("audit.signing_key is required in production")
+		}
+		if db.DSN == "" && db.Password == "" {
+			add("database.password is required in production")
+		}
+		for _, origin := range c.CORS.AllowedOrigins {
+			if origin == "*" {
+				add("cors.allowed_origins must not contain \"*\" in production")
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// SlogLevel converts the configured level name to a slog.Level
+func (l LogConfig) SlogLevel() (slog.Level, error) {
+	switch strings.ToLower(l.Level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("%q must be one of debug, info, warn, error", l.Level)
+}
+
+// ConnString returns the lib/pq connection string, preferring an explicit DSN
+func (d DatabaseConfig) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, quoteConnValue(d.Password), d.Name, d.SSLMode)
+}
+
+// quoteConnValue quotes a key/value connection string value when needed
+func quoteConnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
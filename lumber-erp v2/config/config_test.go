@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// load writes yaml to a config file and loads it with the LUMBER_*
+// variables given set and every other one cleared
+func load(t *testing.T, yaml string, env map[string]string) (*Config, error) {
+	t.Helper()
+	for _, kv := range os.Environ() {
+		if key, _, _ := strings.Cut(kv, "="); strings.HasPrefix(key, "LUMBER_") {
+			t.Setenv(key, "")
+			os.Unsetenv(key)
+		}
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := load(t, `
+http:
+  addr: ":8080"
+log:
+  level: debug
+profiles:
+  staging:
+    log:
+      level: warn
+`, map[string]string{"LUMBER_ENV": "staging", "LUMBER_DB_PORT": "6543"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Env != "staging" || cfg.HTTP.Addr != ":8080" || cfg.Log.Level != "warn" || cfg.Database.Port != 6543 ||
+		cfg.Database.Host != "localhost" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		yaml     string
+		env      map[string]string
+		problems []string
+	}{
+		"bad values": {
+			yaml: `
+http:
+  addr: "5000"
+  trusted_proxies: ["not-an-address"]
+database:
+  port: 70000
+  sslmode: sometimes
+  max_open_conns: 2
+  max_idle_conns: 5
+cors:
+  allowed_origins: ["erp.example.com"]
+log:
+  level: loud
+processing:
+  mass_balance_tolerance: 150
+`,
+			problems: []string{"http.addr", "http.trusted_proxies", "database.port", "database.sslmode",
+				"database.max_idle_conns (5)", "cors.allowed_origins", "log.level", "processing.mass_balance_tolerance"},
+		},
+		"unparsable environment": {
+			env: map[string]string{"LUMBER_DB_PORT": "five", "LUMBER_AUDIT_CHECKPOINT_INTERVAL": "daily",
+				"LUMBER_PROCESSING_KILN_MOISTURE_TOLERANCE": "one"},
+			problems: []string{"LUMBER_DB_PORT", "LUMBER_AUDIT_CHECKPOINT_INTERVAL", "LUMBER_PROCESSING_KILN_MOISTURE_TOLERANCE"},
+		},
+		"production without secrets": {
+			yaml: `
+env: production
+cors:
+  allowed_origins: ["*"]
+`,
+			problems: []string{"auth.secret", "audit.signing_key", "database.password", "cors.allowed_origins"},
+		},
+		"short signing key": {
+			env:      map[string]string{"LUMBER_AUDIT_SIGNING_KEY": "c2hvcnQ="},
+			problems: []string{"audit.signing_key: must decode to 32 bytes"},
+		},
+	} {
+		_, err := load(t, tc.yaml, tc.env)
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) {
+			t.Errorf("%s: err = %v, want a ValidationError", name, err)
+			continue
+		}
+		if len(invalid.Problems) != len(tc.problems) {
+			t.Errorf("%s: problems = %q", name, invalid.Problems)
+			continue
+		}
+		for i, want := range tc.problems {
+			if !strings.HasPrefix(invalid.Problems[i], want) {
+				t.Errorf("%s: problem %d = %q, want it to start with %q", name, i, invalid.Problems[i], want)
+			}
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	if _, err := load(t, "http: [", nil); err == nil || !strings.Contains(err.Error(), "parsing") {
+		t.Errorf("malformed YAML: err = %v", err)
+	}
+	if _, err := load(t, "profiles:\n  staging: {}\n", map[string]string{"LUMBER_ENV": "qa"}); err == nil ||
+		!strings.Contains(err.Error(), `profile "qa" not found`) {
+		t.Errorf("unknown profile: err = %v", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("an explicit config file that does not exist loaded")
+	}
+}
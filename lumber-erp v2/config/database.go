@@ -10,24 +10,18 @@ import (
 
 var DB *sql.DB
 
-const (
-	Host     = "localhost"
-	Port     = 5432
-	User     = "postgres"
-	Password = "m7mdndgamer"
-	DBName   = "pern_todo"
-)
-
-func InitDB() {
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		Host, Port, User, Password, DBName)
-
+func InitDB(cfg DatabaseConfig) {
 	var err error
-	DB, err = sql.Open("postgres", psqlInfo)
+	DB, err = sql.Open("postgres", cfg.ConnString())
 	if err != nil {
 		log.Fatal("❌ Error opening database:", err)
 	}
 
+	DB.SetMaxOpenConns(cfg.MaxOpenConns)
+	DB.SetMaxIdleConns(cfg.MaxIdleConns)
+	DB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	DB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
 	err = DB.Ping()
 	if err != nil {
 		log.Fatal("❌ Error connecting to database:", err)
@@ -2,4 +2,8 @@ module lumber-erp-api
 
 go 1.21
 
-require github.com/lib/pq v1.10.9
\ No newline at end of file
+require (
+	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
+)
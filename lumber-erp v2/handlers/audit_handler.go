@@ -1,46 +1,85 @@
 package handlers
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
-	"lumber-erp-api/config"
-	"lumber-erp-api/models"
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/auditchain"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// AuditHandler serves the audit log
+type AuditHandler struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditHandler(repo repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
 // ==================== AUDIT LOGS ====================
-func CreateAuditLog(w http.ResponseWriter, r *http.Request) {
+func (h *AuditHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	var al models.AuditLog
-	json.NewDecoder(r.Body).Decode(&al)
+	spec, ok := listSpec(w, r, repository.AuditLogResource)
+	if !ok {
+		return
+	}
+	logs, err := h.repo.ListAuditLogs(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, logs)
+}
+
+// ==================== AUDIT CHAIN ====================
 
-	query := `INSERT INTO AuditLog (User_ID, ActionType, EntityAffected, Description, IPAddress)
-              VALUES ($1, $2, $3, $4, $5) RETURNING LogID`
-	err := config.DB.QueryRow(query, al.UserID, al.ActionType, al.EntityAffected,
-		al.Description, al.IPAddress).Scan(&al.LogID)
+// VerifyAuditLogs recomputes the hash chain and reports the first broken link
+func (h *AuditHandler) VerifyAuditLogs(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	report, err := auditchain.Verify(r.Context(), h.repo)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	utils.RespondJSON(w, http.StatusCreated, al)
+	utils.RespondJSON(w, http.StatusOK, report)
 }
 
-func GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+func (h *AuditHandler) GetAuditCheckpoints(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT LogID, User_ID, ActionType, EntityAffected, Timestamp, Description, IPAddress 
-                           FROM AuditLog ORDER BY Timestamp DESC`)
+	checkpoints, err := h.repo.ListAuditCheckpoints(r.Context())
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	utils.RespondJSON(w, http.StatusOK, checkpoints)
+}
 
-	logs := []models.AuditLog{}
-	for rows.Next() {
-		var a models.AuditLog
-		rows.Scan(&a.LogID, &a.UserID, &a.ActionType, &a.EntityAffected, &a.Timestamp, &a.Description, &a.IPAddress)
-		logs = append(logs, a)
+// CreateAuditCheckpoint signs the current head of the chain without waiting
+// for the periodic checkpoint
+func (h *AuditHandler) CreateAuditCheckpoint(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	checkpoint, err := auditchain.Checkpoint(r.Context(), h.repo)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, logs)
-}
\ No newline at end of file
+	utils.RespondJSON(w, http.StatusCreated, checkpoint)
+}
+
+// ExportAuditCheckpoints downloads every checkpoint with the public key that
+// verifies their signatures
+func (h *AuditHandler) ExportAuditCheckpoints(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	export, err := auditchain.NewExport(r.Context(), h.repo)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="audit-checkpoints-%s.json"`, time.Now().UTC().Format("20060102")))
+	utils.RespondJSON(w, http.StatusOK, export)
+}
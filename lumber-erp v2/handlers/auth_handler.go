@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/auth"
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/utils"
+)
+
+// AuthHandler signs users in and out and manages their sessions
+type AuthHandler struct {
+	users    repository.UserRepository
+	sessions repository.SessionRepository
+	access   repository.AccessRepository
+}
+
+func NewAuthHandler(users repository.UserRepository, sessions repository.SessionRepository,
+	access repository.AccessRepository) *AuthHandler {
+	return &AuthHandler{users: users, sessions: sessions, access: access}
+}
+
+// ==================== AUTHENTICATION ====================
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var creds struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.users.GetUserByEmail(r.Context(), strings.TrimSpace(creds.Email))
+	if err == repository.ErrNotFound {
+		utils.RespondError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	ok, needsRehash := auth.CheckPassword(user.Password, creds.Password)
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+	if user.Status != "active" {
+		utils.RespondError(w, http.StatusForbidden, "Account is inactive")
+		return
+	}
+
+	// Upgrade legacy plaintext passwords on first successful login
+	if needsRehash {
+		hash, err := auth.HashPassword(creds.Password)
+		if err != nil {
+			apierr.Respond(w, err)
+			return
+		}
+		if err := h.users.SetPassword(r.Context(), user.UserID, hash); err != nil {
+			apierr.Respond(w, err)
+			return
+		}
+	}
+
+	session := models.UserSession{
+		UserID:    user.UserID,
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL),
+		IPAddress: utils.ClientIP(r),
+	}
+	err = h.sessions.CreateSession(r.Context(), &session)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	h.issueSessionTokens(w, r, user, session.SessionID, session.RefreshTokenHash)
+}
+
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	claims, err := auth.ParseToken(body.RefreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		utils.RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	session, err := h.sessions.GetSession(r.Context(), claims.SessionID, claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusUnauthorized, "Session not found")
+		return
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		utils.RespondError(w, http.StatusUnauthorized, "Session has ended")
+		return
+	}
+
+	user, err := h.users.GetUser(r.Context(), claims.UserID)
+	if err != nil || user.Status != "active" {
+		utils.RespondError(w, http.StatusUnauthorized, "Account is not available")
+		return
+	}
+
+	h.issueSessionTokens(w, r, user, claims.SessionID, auth.HashToken(body.RefreshToken))
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	// Either the refresh token or the bearer access token identifies the session
+	claims, err := auth.ParseToken(body.RefreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		claims, err = auth.ParseToken(auth.BearerToken(r.Header.Get("Authorization")), auth.TokenTypeAccess)
+	}
+	if err != nil {
+		utils.RespondError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = h.sessions.RevokeSession(r.Context(), claims.SessionID, claims.UserID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "Logged out successfully")
+}
+
+// GetCurrentUser returns the caller's profile together with their roles and
+// resolved permissions, so clients do not need access to the role tables
+func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	claims := auth.ClaimsFromContext(r.Context())
+
+	user, err := h.users.GetUser(r.Context(), claims.UserID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	roles, err := h.access.ListRolesByUser(r.Context(), claims.UserID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	isAdmin := false
+	for _, role := range roles {
+		if strings.EqualFold(role.RoleName, repository.AdminRole) {
+			isAdmin = true
+		}
+	}
+
+	// Admins implicitly hold every permission
+	var perms []models.Permission
+	if isAdmin {
+		var all query.Page[models.Permission]
+		all, err = h.access.ListPermissions(r.Context(), query.Spec{})
+		perms = all.Data
+	} else {
+		perms, err = h.access.ListUserPermissions(r.Context(), claims.UserID)
+	}
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"user":        user,
+		"roles":       roles,
+		"permissions": perms,
+	})
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password" validate:"required,min=8"`
+	}
+	if !readBody(w, r, &body) {
+		return
+	}
+	// Users change their own password; the current password is required anyway
+	if claims := auth.ClaimsFromContext(r.Context()); claims == nil || claims.UserID != id {
+		utils.RespondError(w, http.StatusForbidden, "You can only change your own password")
+		return
+	}
+	if !validBody(w, &body) {
+		return
+	}
+
+	stored, err := h.users.PasswordHash(r.Context(), id)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if ok, _ := auth.CheckPassword(stored, body.CurrentPassword); !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Current password is incorrect")
+		return
+	}
+
+	hash, err := auth.HashPassword(body.NewPassword)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	// Changing the password signs the user out everywhere
+	err = h.users.ChangePassword(r.Context(), id, hash)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "Password changed successfully")
+}
+
+// issueSessionTokens signs a new access/refresh pair for the session and
+// rotates the stored refresh token hash from oldHash. A session no longer
+// holding oldHash was refreshed with a token already rotated out, which
+// means it leaked: the session is ended.
+func (h *AuthHandler) issueSessionTokens(w http.ResponseWriter, r *http.Request, user models.User, sessionID int, oldHash string) {
+	accessToken, accessClaims, err := auth.IssueToken(auth.TokenTypeAccess, user.UserID, user.Email, sessionID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	refreshToken, refreshClaims, err := auth.IssueToken(auth.TokenTypeRefresh, user.UserID, user.Email, sessionID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	err = h.sessions.RotateSession(r.Context(), sessionID, oldHash, auth.HashToken(refreshToken), time.Unix(refreshClaims.ExpiresAt, 0))
+	if errors.Is(err, repository.ErrNotFound) {
+		h.sessions.RevokeSession(r.Context(), sessionID, user.UserID)
+		utils.RespondError(w, http.StatusUnauthorized, "Refresh token reuse detected")
+		return
+	}
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+
+	user.Password = ""
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    accessClaims.ExpiresAt - accessClaims.IssuedAt,
+		"user":          user,
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/utils"
+	"lumber-erp-api/validate"
+)
+
+// decodeBody reads and validates a JSON request body; callers return when
+// ok is false
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	return readBody(w, r, v) && validBody(w, v)
+}
+
+// readBody decodes the JSON request body into v, answering 400 when it is
+// malformed and 422 when a field has the wrong type. Handlers that fill in
+// fields from the path validate afterwards with validBody.
+func readBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tooLarge):
+		respondTooLarge(w, tooLarge)
+	case errors.As(err, &typeErr):
+		respondInvalid(w, validate.Errors{{
+			Field:   typeErr.Field,
+			Code:    validate.CodeInvalidType,
+			Message: typeErr.Field + " must be " + jsonType(typeErr.Type),
+		}})
+	default:
+		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	}
+	return false
+}
+
+// validBody checks v against its validate tags and answers 422 listing
+// every invalid field
+func validBody(w http.ResponseWriter, v interface{}) bool {
+	if errs := validate.Struct(v); errs != nil {
+		respondInvalid(w, errs)
+		return false
+	}
+	return true
+}
+
+func respondTooLarge(w http.ResponseWriter, err *http.MaxBytesError) {
+	utils.RespondError(w, http.StatusRequestEntityTooLarge,
+		fmt.Sprintf("Request body exceeds %d bytes", err.Limit))
+}
+
+func respondInvalid(w http.ResponseWriter, errs validate.Errors) {
+	apierr.Respond(w, errs)
+}
+
+// jsonType names the JSON value a Go type decodes from
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
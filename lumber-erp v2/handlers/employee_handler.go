@@ -1,197 +1,223 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// EmployeeHandler serves employees, worker assignments and management insights
+type EmployeeHandler struct {
+	repo repository.EmployeeRepository
+}
+
+func NewEmployeeHandler(repo repository.EmployeeRepository) *EmployeeHandler {
+	return &EmployeeHandler{repo: repo}
+}
+
 // ==================== EMPLOYEES ====================
-func CreateEmployee(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) CreateEmployee(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var emp models.Employee
-	json.NewDecoder(r.Body).Decode(&emp)
+	if !decodeBody(w, r, &emp) {
+		return
+	}
 
-	query := `INSERT INTO Employee (FullName, Department, Position, HireDate, PerformanceRating)
-              VALUES ($1, $2, $3, $4, $5) RETURNING EmployeeID`
-	err := config.DB.QueryRow(query, emp.FullName, emp.Department, emp.Position,
-		emp.HireDate, emp.PerformanceRating).Scan(&emp.EmployeeID)
+	err := h.repo.CreateEmployee(r.Context(), &emp)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, emp)
 }
 
-func GetEmployees(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) GetEmployees(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT EmployeeID, FullName, Department, Position, 
-                           HireDate, PerformanceRating FROM Employee ORDER BY FullName`)
+	spec, ok := listSpec(w, r, repository.EmployeeResource)
+	if !ok {
+		return
+	}
+	emps, err := h.repo.ListEmployees(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, emps)
+}
 
-	emps := []models.Employee{}
-	for rows.Next() {
-		var e models.Employee
-		rows.Scan(&e.EmployeeID, &e.FullName, &e.Department, &e.Position, &e.HireDate, &e.PerformanceRating)
-		emps = append(emps, e)
-	}
-	utils.RespondJSON(w, http.StatusOK, emps)
+func (h *EmployeeHandler) GetEmployee(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.EmployeeResource, h.repo.ListEmployees)
 }
 
-func UpdateEmployee(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var emp models.Employee
-	json.NewDecoder(r.Body).Decode(&emp)
+	if !decodeBody(w, r, &emp) {
+		return
+	}
 
-	query := `UPDATE Employee SET FullName = $2, Department = $3, Position = $4,
-              HireDate = $5, PerformanceRating = $6 WHERE EmployeeID = $1`
-	_, err := config.DB.Exec(query, id, emp.FullName, emp.Department, emp.Position, emp.HireDate, emp.PerformanceRating)
+	err := h.repo.UpdateEmployee(r.Context(), id, &emp)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Employee updated successfully")
 }
 
-func DeleteEmployee(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) DeleteEmployee(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Employee WHERE EmployeeID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteEmployee(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Employee deleted successfully")
 }
 
 // ==================== WORKER ASSIGNMENTS ====================
-func CreateWorkerAssignment(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) CreateWorkerAssignment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var wa models.WorkerAssignment
-	json.NewDecoder(r.Body).Decode(&wa)
+	if !decodeBody(w, r, &wa) {
+		return
+	}
 
-	query := `INSERT INTO WorkerAssignment (EmployeeID, ProcessingID, RoleInTask, Notes)
-              VALUES ($1, $2, $3, $4) RETURNING AssignmentID`
-	err := config.DB.QueryRow(query, wa.EmployeeID, wa.ProcessingID, wa.RoleInTask, wa.Notes).Scan(&wa.AssignmentID)
+	err := h.repo.CreateWorkerAssignment(r.Context(), &wa)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, wa)
 }
 
-func GetWorkerAssignments(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) GetWorkerAssignments(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT AssignmentID, EmployeeID, ProcessingID, RoleInTask, Notes 
-                           FROM WorkerAssignment ORDER BY AssignmentID`)
+	spec, ok := listSpec(w, r, repository.WorkerAssignmentResource)
+	if !ok {
+		return
+	}
+	assignments, err := h.repo.ListWorkerAssignments(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, assignments)
+}
 
-	assignments := []models.WorkerAssignment{}
-	for rows.Next() {
-		var w models.WorkerAssignment
-		rows.Scan(&w.AssignmentID, &w.EmployeeID, &w.ProcessingID, &w.RoleInTask, &w.Notes)
-		assignments = append(assignments, w)
-	}
-	utils.RespondJSON(w, http.StatusOK, assignments)
+func (h *EmployeeHandler) GetWorkerAssignment(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.WorkerAssignmentResource, h.repo.ListWorkerAssignments)
 }
 
-func UpdateWorkerAssignment(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) UpdateWorkerAssignment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var wa models.WorkerAssignment
-	json.NewDecoder(r.Body).Decode(&wa)
+	if !decodeBody(w, r, &wa) {
+		return
+	}
 
-	query := `UPDATE WorkerAssignment SET EmployeeID = $2, ProcessingID = $3, RoleInTask = $4, Notes = $5 
-              WHERE AssignmentID = $1`
-	_, err := config.DB.Exec(query, id, wa.EmployeeID, wa.ProcessingID, wa.RoleInTask, wa.Notes)
+	err := h.repo.UpdateWorkerAssignment(r.Context(), id, &wa)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "WorkerAssignment updated successfully")
 }
 
-func DeleteWorkerAssignment(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) DeleteWorkerAssignment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM WorkerAssignment WHERE AssignmentID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteWorkerAssignment(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "WorkerAssignment deleted successfully")
 }
 
 // ==================== MANAGEMENT INSIGHTS ====================
-func CreateManagementInsights(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) CreateManagementInsights(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var mi models.ManagementInsights
-	json.NewDecoder(r.Body).Decode(&mi)
+	if !decodeBody(w, r, &mi) {
+		return
+	}
 
-	query := `INSERT INTO Management_Insights (EmployeeID, KPI_Type, Time_Period)
-              VALUES ($1, $2, $3) RETURNING Report_ID`
-	err := config.DB.QueryRow(query, mi.EmployeeID, mi.KPIType, mi.TimePeriod).Scan(&mi.ReportID)
+	err := h.repo.CreateManagementInsights(r.Context(), &mi)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, mi)
 }
 
-func GetManagementInsights(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) GetManagementInsights(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT Report_ID, EmployeeID, KPI_Type, Time_Period 
-                           FROM Management_Insights ORDER BY Report_ID DESC`)
+	spec, ok := listSpec(w, r, repository.ManagementInsightsResource)
+	if !ok {
+		return
+	}
+	insights, err := h.repo.ListManagementInsights(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, insights)
+}
 
-	insights := []models.ManagementInsights{}
-	for rows.Next() {
-		var m models.ManagementInsights
-		rows.Scan(&m.ReportID, &m.EmployeeID, &m.KPIType, &m.TimePeriod)
-		insights = append(insights, m)
-	}
-	utils.RespondJSON(w, http.StatusOK, insights)
+func (h *EmployeeHandler) GetManagementInsight(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.ManagementInsightsResource, h.repo.ListManagementInsights)
 }
 
-func UpdateManagementInsights(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) UpdateManagementInsights(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var mi models.ManagementInsights
-	json.NewDecoder(r.Body).Decode(&mi)
+	if !decodeBody(w, r, &mi) {
+		return
+	}
 
-	query := `UPDATE Management_Insights SET EmployeeID = $2, KPI_Type = $3, Time_Period = $4 
-              WHERE Report_ID = $1`
-	_, err := config.DB.Exec(query, id, mi.EmployeeID, mi.KPIType, mi.TimePeriod)
+	err := h.repo.UpdateManagementInsights(r.Context(), id, &mi)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ManagementInsights updated successfully")
 }
 
-func DeleteManagementInsights(w http.ResponseWriter, r *http.Request) {
+func (h *EmployeeHandler) DeleteManagementInsights(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Management_Insights WHERE Report_ID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteManagementInsights(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ManagementInsights deleted successfully")
-}
\ No newline at end of file
+}
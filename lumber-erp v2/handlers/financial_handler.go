@@ -1,138 +1,155 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// FinancialHandler serves invoices and payments
+type FinancialHandler struct {
+	repo repository.InvoiceRepository
+}
+
+func NewFinancialHandler(repo repository.InvoiceRepository) *FinancialHandler {
+	return &FinancialHandler{repo: repo}
+}
+
 // ==================== INVOICES ====================
-func CreateInvoice(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) CreateInvoice(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var inv models.Invoice
-	json.NewDecoder(r.Body).Decode(&inv)
+	if !decodeBody(w, r, &inv) {
+		return
+	}
 
-	query := `INSERT INTO Invoice (SOID, InvoiceDate, DueDate, TotalAmount, Tax, Currency, Status)
-              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING InvoiceID`
-	err := config.DB.QueryRow(query, inv.SOID, inv.InvoiceDate, inv.DueDate, inv.TotalAmount,
-		inv.Tax, inv.Currency, inv.Status).Scan(&inv.InvoiceID)
+	err := h.repo.CreateInvoice(r.Context(), &inv)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, inv)
 }
 
-func GetInvoices(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) GetInvoices(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT InvoiceID, SOID, InvoiceDate, DueDate, TotalAmount, Tax, Currency, Status 
-                           FROM Invoice ORDER BY InvoiceDate DESC`)
+	spec, ok := listSpec(w, r, repository.InvoiceResource)
+	if !ok {
+		return
+	}
+	invoices, err := h.repo.ListInvoices(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, invoices)
+}
 
-	invoices := []models.Invoice{}
-	for rows.Next() {
-		var i models.Invoice
-		rows.Scan(&i.InvoiceID, &i.SOID, &i.InvoiceDate, &i.DueDate, &i.TotalAmount, &i.Tax, &i.Currency, &i.Status)
-		invoices = append(invoices, i)
-	}
-	utils.RespondJSON(w, http.StatusOK, invoices)
+func (h *FinancialHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.InvoiceResource, h.repo.ListInvoices)
 }
 
-func UpdateInvoice(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) UpdateInvoice(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var inv models.Invoice
-	json.NewDecoder(r.Body).Decode(&inv)
+	if !decodeBody(w, r, &inv) {
+		return
+	}
 
-	query := `UPDATE Invoice SET SOID = $2, InvoiceDate = $3, DueDate = $4, TotalAmount = $5,
-              Tax = $6, Currency = $7, Status = $8 WHERE InvoiceID = $1`
-	_, err := config.DB.Exec(query, id, inv.SOID, inv.InvoiceDate, inv.DueDate, inv.TotalAmount,
-		inv.Tax, inv.Currency, inv.Status)
+	err := h.repo.UpdateInvoice(r.Context(), id, &inv)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Invoice updated successfully")
 }
 
-func DeleteInvoice(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) DeleteInvoice(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Invoice WHERE InvoiceID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteInvoice(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Invoice deleted successfully")
 }
 
 // ==================== PAYMENTS ====================
-func CreatePayment(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var pay models.Payment
-	json.NewDecoder(r.Body).Decode(&pay)
+	if !decodeBody(w, r, &pay) {
+		return
+	}
 
-	query := `INSERT INTO Payment (InvoiceID, PaymentDate, Amount, Method, ReferenceNo, Status)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING PaymentID`
-	err := config.DB.QueryRow(query, pay.InvoiceID, pay.PaymentDate, pay.Amount,
-		pay.Method, pay.ReferenceNo, pay.Status).Scan(&pay.PaymentID)
+	err := h.repo.CreatePayment(r.Context(), &pay)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, pay)
 }
 
-func GetPayments(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT PaymentID, InvoiceID, PaymentDate, Amount, Method, ReferenceNo, Status 
-                           FROM Payment ORDER BY PaymentDate DESC`)
+	spec, ok := listSpec(w, r, repository.PaymentResource)
+	if !ok {
+		return
+	}
+	payments, err := h.repo.ListPayments(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, payments)
+}
 
-	payments := []models.Payment{}
-	for rows.Next() {
-		var p models.Payment
-		rows.Scan(&p.PaymentID, &p.InvoiceID, &p.PaymentDate, &p.Amount, &p.Method, &p.ReferenceNo, &p.Status)
-		payments = append(payments, p)
-	}
-	utils.RespondJSON(w, http.StatusOK, payments)
+func (h *FinancialHandler) GetPayment(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.PaymentResource, h.repo.ListPayments)
 }
 
-func UpdatePayment(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) UpdatePayment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var pay models.Payment
-	json.NewDecoder(r.Body).Decode(&pay)
+	if !decodeBody(w, r, &pay) {
+		return
+	}
 
-	query := `UPDATE Payment SET InvoiceID = $2, PaymentDate = $3, Amount = $4,
-              Method = $5, ReferenceNo = $6, Status = $7 WHERE PaymentID = $1`
-	_, err := config.DB.Exec(query, id, pay.InvoiceID, pay.PaymentDate, pay.Amount,
-		pay.Method, pay.ReferenceNo, pay.Status)
+	err := h.repo.UpdatePayment(r.Context(), id, &pay)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Payment updated successfully")
 }
 
-func DeletePayment(w http.ResponseWriter, r *http.Request) {
+func (h *FinancialHandler) DeletePayment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Payment WHERE PaymentID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeletePayment(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Payment deleted successfully")
-}
\ No newline at end of file
+}
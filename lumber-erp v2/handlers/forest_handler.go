@@ -1,264 +1,291 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// ForestHandler serves forests, tree species, harvest schedules and harvest batches
+type ForestHandler struct {
+	repo repository.ForestRepository
+}
+
+func NewForestHandler(repo repository.ForestRepository) *ForestHandler {
+	return &ForestHandler{repo: repo}
+}
+
 // ==================== FORESTS ====================
-func CreateForest(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) CreateForest(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var forest models.Forest
-	json.NewDecoder(r.Body).Decode(&forest)
+	if !decodeBody(w, r, &forest) {
+		return
+	}
 
-	query := `INSERT INTO Forest (ForestName, GeoLocation, AreaSize, OwnershipType, Status)
-              VALUES ($1, $2, $3, $4, $5) RETURNING ForestID`
-	err := config.DB.QueryRow(query, forest.ForestName, forest.GeoLocation, forest.AreaSize,
-		forest.OwnershipType, forest.Status).Scan(&forest.ForestID)
+	err := h.repo.CreateForest(r.Context(), &forest)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, forest)
 }
 
-func GetForests(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) GetForests(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT ForestID, ForestName, GeoLocation, AreaSize, OwnershipType, Status 
-                           FROM Forest ORDER BY ForestName`)
+	spec, ok := listSpec(w, r, repository.ForestResource)
+	if !ok {
+		return
+	}
+	forests, err := h.repo.ListForests(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, forests)
+}
 
-	forests := []models.Forest{}
-	for rows.Next() {
-		var f models.Forest
-		rows.Scan(&f.ForestID, &f.ForestName, &f.GeoLocation, &f.AreaSize, &f.OwnershipType, &f.Status)
-		forests = append(forests, f)
-	}
-	utils.RespondJSON(w, http.StatusOK, forests)
+func (h *ForestHandler) GetForest(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.ForestResource, h.repo.ListForests)
 }
 
-func UpdateForest(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) UpdateForest(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var forest models.Forest
-	json.NewDecoder(r.Body).Decode(&forest)
+	if !decodeBody(w, r, &forest) {
+		return
+	}
 
-	query := `UPDATE Forest SET ForestName = $2, GeoLocation = $3, AreaSize = $4,
-              OwnershipType = $5, Status = $6 WHERE ForestID = $1`
-	_, err := config.DB.Exec(query, id, forest.ForestName, forest.GeoLocation, forest.AreaSize,
-		forest.OwnershipType, forest.Status)
+	err := h.repo.UpdateForest(r.Context(), id, &forest)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Forest updated successfully")
 }
 
-func DeleteForest(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) DeleteForest(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Forest WHERE ForestID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteForest(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Forest deleted successfully")
 }
 
 // ==================== TREE SPECIES ====================
-func CreateTreeSpecies(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) CreateTreeSpecies(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var ts models.TreeSpecies
-	json.NewDecoder(r.Body).Decode(&ts)
+	if !decodeBody(w, r, &ts) {
+		return
+	}
 
-	query := `INSERT INTO TreeSpecies (SpeciesName, AverageHeight, Density, MoistureContent, Grade)
-              VALUES ($1, $2, $3, $4, $5) RETURNING SpeciesID`
-	err := config.DB.QueryRow(query, ts.SpeciesName, ts.AverageHeight, ts.Density,
-		ts.MoistureContent, ts.Grade).Scan(&ts.SpeciesID)
+	err := h.repo.CreateTreeSpecies(r.Context(), &ts)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, ts)
 }
 
-func GetTreeSpecies(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) GetTreeSpecies(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT SpeciesID, SpeciesName, AverageHeight, Density, MoistureContent, Grade 
-                           FROM TreeSpecies ORDER BY SpeciesName`)
+	spec, ok := listSpec(w, r, repository.TreeSpeciesResource)
+	if !ok {
+		return
+	}
+	species, err := h.repo.ListTreeSpecies(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, species)
+}
 
-	species := []models.TreeSpecies{}
-	for rows.Next() {
-		var t models.TreeSpecies
-		rows.Scan(&t.SpeciesID, &t.SpeciesName, &t.AverageHeight, &t.Density, &t.MoistureContent, &t.Grade)
-		species = append(species, t)
-	}
-	utils.RespondJSON(w, http.StatusOK, species)
+func (h *ForestHandler) GetTreeSpeciesItem(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.TreeSpeciesResource, h.repo.ListTreeSpecies)
 }
 
-func UpdateTreeSpecies(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) UpdateTreeSpecies(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var ts models.TreeSpecies
-	json.NewDecoder(r.Body).Decode(&ts)
+	if !decodeBody(w, r, &ts) {
+		return
+	}
 
-	query := `UPDATE TreeSpecies SET SpeciesName = $2, AverageHeight = $3, Density = $4,
-              MoistureContent = $5, Grade = $6 WHERE SpeciesID = $1`
-	_, err := config.DB.Exec(query, id, ts.SpeciesName, ts.AverageHeight, ts.Density, ts.MoistureContent, ts.Grade)
+	err := h.repo.UpdateTreeSpecies(r.Context(), id, &ts)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "TreeSpecies updated successfully")
 }
 
-func DeleteTreeSpecies(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) DeleteTreeSpecies(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM TreeSpecies WHERE SpeciesID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteTreeSpecies(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "TreeSpecies deleted successfully")
 }
 
 // ==================== HARVEST SCHEDULES ====================
-func CreateHarvestSchedule(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) CreateHarvestSchedule(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var hs models.HarvestSchedule
-	json.NewDecoder(r.Body).Decode(&hs)
+	if !decodeBody(w, r, &hs) {
+		return
+	}
 
-	query := `INSERT INTO HarvestSchedule (ForestID, StartDate, EndDate, Status)
-              VALUES ($1, $2, $3, $4) RETURNING ScheduleID`
-	err := config.DB.QueryRow(query, hs.ForestID, hs.StartDate, hs.EndDate, hs.Status).Scan(&hs.ScheduleID)
+	err := h.repo.CreateHarvestSchedule(r.Context(), &hs)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, hs)
 }
 
-func GetHarvestSchedules(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) GetHarvestSchedules(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT ScheduleID, ForestID, StartDate, EndDate, Status 
-                           FROM HarvestSchedule ORDER BY StartDate DESC`)
+	spec, ok := listSpec(w, r, repository.HarvestScheduleResource)
+	if !ok {
+		return
+	}
+	schedules, err := h.repo.ListHarvestSchedules(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, schedules)
+}
 
-	schedules := []models.HarvestSchedule{}
-	for rows.Next() {
-		var h models.HarvestSchedule
-		rows.Scan(&h.ScheduleID, &h.ForestID, &h.StartDate, &h.EndDate, &h.Status)
-		schedules = append(schedules, h)
-	}
-	utils.RespondJSON(w, http.StatusOK, schedules)
+func (h *ForestHandler) GetHarvestSchedule(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.HarvestScheduleResource, h.repo.ListHarvestSchedules)
 }
 
-func UpdateHarvestSchedule(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) UpdateHarvestSchedule(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var hs models.HarvestSchedule
-	json.NewDecoder(r.Body).Decode(&hs)
+	if !decodeBody(w, r, &hs) {
+		return
+	}
 
-	query := `UPDATE HarvestSchedule SET ForestID = $2, StartDate = $3, EndDate = $4, Status = $5 
-              WHERE ScheduleID = $1`
-	_, err := config.DB.Exec(query, id, hs.ForestID, hs.StartDate, hs.EndDate, hs.Status)
+	err := h.repo.UpdateHarvestSchedule(r.Context(), id, &hs)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "HarvestSchedule updated successfully")
 }
 
-func DeleteHarvestSchedule(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) DeleteHarvestSchedule(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM HarvestSchedule WHERE ScheduleID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteHarvestSchedule(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "HarvestSchedule deleted successfully")
 }
 
 // ==================== HARVEST BATCHES ====================
-func CreateHarvestBatch(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) CreateHarvestBatch(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var hb models.HarvestBatch
-	json.NewDecoder(r.Body).Decode(&hb)
+	if !decodeBody(w, r, &hb) {
+		return
+	}
 
-	query := `INSERT INTO HarvestBatch (ForestID, SpeciesID, ScheduleID, Quantity, HarvestDate, QualityIndicator, QRCode)
-              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING BatchID`
-	err := config.DB.QueryRow(query, hb.ForestID, hb.SpeciesID, hb.ScheduleID, hb.Quantity,
-		hb.HarvestDate, hb.QualityIndicator, hb.QRCode).Scan(&hb.BatchID)
+	err := h.repo.CreateHarvestBatch(r.Context(), &hb)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, hb)
 }
 
-func GetHarvestBatches(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) GetHarvestBatches(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT BatchID, ForestID, SpeciesID, ScheduleID, Quantity, 
-                           HarvestDate, QualityIndicator, QRCode FROM HarvestBatch ORDER BY HarvestDate DESC`)
+	spec, ok := listSpec(w, r, repository.HarvestBatchResource)
+	if !ok {
+		return
+	}
+	batches, err := h.repo.ListHarvestBatches(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, batches)
+}
 
-	batches := []models.HarvestBatch{}
-	for rows.Next() {
-		var h models.HarvestBatch
-		rows.Scan(&h.BatchID, &h.ForestID, &h.SpeciesID, &h.ScheduleID, &h.Quantity,
-			&h.HarvestDate, &h.QualityIndicator, &h.QRCode)
-		batches = append(batches, h)
-	}
-	utils.RespondJSON(w, http.StatusOK, batches)
+func (h *ForestHandler) GetHarvestBatch(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.HarvestBatchResource, h.repo.ListHarvestBatches)
 }
 
-func UpdateHarvestBatch(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) UpdateHarvestBatch(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var hb models.HarvestBatch
-	json.NewDecoder(r.Body).Decode(&hb)
+	if !decodeBody(w, r, &hb) {
+		return
+	}
 
-	query := `UPDATE HarvestBatch SET ForestID = $2, SpeciesID = $3, ScheduleID = $4, Quantity = $5,
-              HarvestDate = $6, QualityIndicator = $7, QRCode = $8 WHERE BatchID = $1`
-	_, err := config.DB.Exec(query, id, hb.ForestID, hb.SpeciesID, hb.ScheduleID, hb.Quantity,
-		hb.HarvestDate, hb.QualityIndicator, hb.QRCode)
+	err := h.repo.UpdateHarvestBatch(r.Context(), id, &hb)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "HarvestBatch updated successfully")
 }
 
-func DeleteHarvestBatch(w http.ResponseWriter, r *http.Request) {
+func (h *ForestHandler) DeleteHarvestBatch(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM HarvestBatch WHERE BatchID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteHarvestBatch(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "HarvestBatch deleted successfully")
-}
\ No newline at end of file
+}
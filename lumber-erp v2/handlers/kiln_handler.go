@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/kiln"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/utils"
+	"lumber-erp-api/validate"
+)
+
+// KilnHandler serves kiln charges and the readings their loggers send
+type KilnHandler struct {
+	repo    repository.KilnRepository
+	service *kiln.Service
+}
+
+func NewKilnHandler(repos repository.Repositories) *KilnHandler {
+	return &KilnHandler{repo: repos.Kilns, service: kiln.NewService(repos)}
+}
+
+// ==================== KILN CHARGES ====================
+func (h *KilnHandler) GetKilnCharges(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.KilnChargeResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListKilnCharges(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// GetKilnCharge returns a charge with the stock lots it holds
+func (h *KilnHandler) GetKilnCharge(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	kc, err := h.repo.GetKilnCharge(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, kc)
+}
+
+// CreateKilnCharge loads stock lots into a kiln
+func (h *KilnHandler) CreateKilnCharge(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var kc models.KilnCharge
+	if !decodeBody(w, r, &kc) {
+		return
+	}
+	if err := h.service.Create(r.Context(), &kc); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, kc)
+}
+
+func (h *KilnHandler) UpdateKilnCharge(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var c kiln.Changes
+	if !decodeBody(w, r, &c) {
+		return
+	}
+	if _, err := h.service.Update(r.Context(), id, c); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "KilnCharge updated successfully")
+}
+
+func (h *KilnHandler) DeleteKilnCharge(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteKilnCharge(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "KilnCharge deleted successfully")
+}
+
+// UnloadKilnCharge takes a charge out of its kiln
+func (h *KilnHandler) UnloadKilnCharge(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	kc, err := h.service.Unload(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, kc)
+}
+
+// ==================== KILN READINGS ====================
+
+// GetKilnReadings answers the moisture curve of a charge, oldest reading
+// first unless ?sort says otherwise
+func (h *KilnHandler) GetKilnReadings(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	spec, ok := listSpec(w, r, repository.KilnReadingResource)
+	if !ok {
+		return
+	}
+	if _, err := h.repo.GetKilnCharge(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	spec.Where("charge_id", id)
+	page, err := h.repo.ListKilnReadings(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// RecordKilnReadings takes a batch of logger readings, as a JSON array or
+// as text/csv with a header row, and answers the re-evaluated charge.
+// Bodies over kiln.MaxUploadBytes get 413.
+func (h *KilnHandler) RecordKilnReadings(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, kiln.MaxUploadBytes)
+	var readings []models.KilnReading
+	if media, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); media == "text/csv" {
+		var err error
+		if readings, err = kiln.ReadCSV(r.Body); err != nil {
+			var errs validate.Errors
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &errs) {
+				respondInvalid(w, errs)
+			} else if errors.As(err, &tooLarge) {
+				respondTooLarge(w, tooLarge)
+			} else {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+			}
+			return
+		}
+	} else if !readBody(w, r, &readings) {
+		return
+	}
+	up, err := h.service.Record(r.Context(), id, readings)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, up)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/maintenance"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/utils"
+)
+
+// MaintenanceHandler serves preventive maintenance plans, their work orders
+// and the OEE of processing units
+type MaintenanceHandler struct {
+	repo    repository.MaintenanceRepository
+	service *maintenance.Service
+}
+
+func NewMaintenanceHandler(repos repository.Repositories) *MaintenanceHandler {
+	return &MaintenanceHandler{repo: repos.Maintenance, service: maintenance.NewService(repos)}
+}
+
+// ==================== MAINTENANCE PLANS ====================
+func (h *MaintenanceHandler) GetMaintenancePlans(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.MaintenancePlanResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListMaintenancePlans(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+func (h *MaintenanceHandler) GetMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.MaintenancePlanResource, h.repo.ListMaintenancePlans)
+}
+
+// CreateMaintenancePlan stores a plan, active unless the body says otherwise
+func (h *MaintenanceHandler) CreateMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	mp := models.MaintenancePlan{Active: true}
+	if !decodeBody(w, r, &mp) {
+		return
+	}
+	if err := h.service.CreatePlan(r.Context(), &mp); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, mp)
+}
+
+func (h *MaintenanceHandler) UpdateMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	mp := models.MaintenancePlan{Active: true}
+	if !decodeBody(w, r, &mp) {
+		return
+	}
+	if err := h.service.UpdatePlan(r.Context(), id, &mp); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "MaintenancePlan updated successfully")
+}
+
+func (h *MaintenanceHandler) DeleteMaintenancePlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteMaintenancePlan(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "MaintenancePlan deleted successfully")
+}
+
+// GenerateWorkOrders raises the work orders of the plans due now instead of
+// waiting for the periodic check
+func (h *MaintenanceHandler) GenerateWorkOrders(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	raised, err := h.service.Generate(r.Context())
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, raised)
+}
+
+// ==================== MAINTENANCE WORK ORDERS ====================
+func (h *MaintenanceHandler) GetWorkOrders(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.MaintenanceWorkOrderResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListWorkOrders(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// CreateWorkOrder raises a work order by hand
+func (h *MaintenanceHandler) CreateWorkOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var wo models.MaintenanceWorkOrder
+	if !decodeBody(w, r, &wo) {
+		return
+	}
+	if err := h.service.Open(r.Context(), &wo); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, wo)
+}
+
+// CompleteWorkOrder records the maintenance done for an open work order
+func (h *MaintenanceHandler) CompleteWorkOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var c maintenance.Completion
+	if r.ContentLength != 0 && !decodeBody(w, r, &c) {
+		return
+	}
+	wo, err := h.service.Complete(r.Context(), id, c)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, wo)
+}
+
+func (h *MaintenanceHandler) CancelWorkOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	wo, err := h.service.Cancel(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, wo)
+}
+
+// ==================== OEE ====================
+
+// period reads ?from and ?to, by default the last 30 days up to now
+func period(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		t, err := scheduling.ParseTime(raw)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid to")
+			return to, to, false
+		}
+		to = t
+	}
+	from := to.Add(-maintenance.Period)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		t, err := scheduling.ParseTime(raw)
+		if err != nil || !t.Before(to) {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid from")
+			return from, to, false
+		}
+		from = t
+	}
+	return from, to, true
+}
+
+// GetUnitOEE answers the OEE of a processing unit between ?from and ?to
+func (h *MaintenanceHandler) GetUnitOEE(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	from, to, ok := period(w, r)
+	if !ok {
+		return
+	}
+	oee, err := h.service.UnitOEE(r.Context(), id, from, to)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, oee)
+}
+
+// GetSawmillOEE answers the OEE of a sawmill and each of its units between
+// ?from and ?to
+func (h *MaintenanceHandler) GetSawmillOEE(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	from, to, ok := period(w, r)
+	if !ok {
+		return
+	}
+	oee, err := h.service.SawmillOEE(r.Context(), id, from, to)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, oee)
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/models"
+	"lumber-erp-api/ncr"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/utils"
+)
+
+// NCRHandler serves non-conformance reports and their corrective and
+// preventive actions
+type NCRHandler struct {
+	repo    repository.QualityRepository
+	service *ncr.Service
+}
+
+func NewNCRHandler(repos repository.Repositories) *NCRHandler {
+	return &NCRHandler{repo: repos.Quality, service: ncr.NewService(repos)}
+}
+
+// ==================== NON-CONFORMANCES ====================
+func (h *NCRHandler) GetNonConformances(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.NonConformanceResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListNonConformances(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// GetNonConformance answers a report with its actions
+func (h *NCRHandler) GetNonConformance(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	nc, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, nc)
+}
+
+// CreateNonConformance opens a report
+func (h *NCRHandler) CreateNonConformance(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var nc models.NonConformance
+	if !decodeBody(w, r, &nc) {
+		return
+	}
+	if err := h.service.Create(r.Context(), &nc); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, nc)
+}
+
+func (h *NCRHandler) UpdateNonConformance(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var nc models.NonConformance
+	if !decodeBody(w, r, &nc) {
+		return
+	}
+	if err := h.service.Update(r.Context(), id, &nc); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "NonConformance updated successfully")
+}
+
+func (h *NCRHandler) DeleteNonConformance(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "NonConformance deleted successfully")
+}
+
+// step moves a report on with the service method move, reading an optional
+// body
+func (h *NCRHandler) step(w http.ResponseWriter, r *http.Request, move func(*http.Request, int, ncr.Step) (models.NonConformance, error)) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var step ncr.Step
+	if r.ContentLength != 0 && !decodeBody(w, r, &step) {
+		return
+	}
+	nc, err := move(r, id, step)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, nc)
+}
+
+func (h *NCRHandler) InvestigateNonConformance(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, func(r *http.Request, id int, step ncr.Step) (models.NonConformance, error) {
+		return h.service.Investigate(r.Context(), id, step)
+	})
+}
+
+func (h *NCRHandler) ActOnNonConformance(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, func(r *http.Request, id int, step ncr.Step) (models.NonConformance, error) {
+		return h.service.Act(r.Context(), id, step)
+	})
+}
+
+func (h *NCRHandler) VerifyNonConformance(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, func(r *http.Request, id int, _ ncr.Step) (models.NonConformance, error) {
+		return h.service.Verify(r.Context(), id)
+	})
+}
+
+func (h *NCRHandler) CloseNonConformance(w http.ResponseWriter, r *http.Request) {
+	h.step(w, r, func(r *http.Request, id int, _ ncr.Step) (models.NonConformance, error) {
+		return h.service.Close(r.Context(), id)
+	})
+}
+
+// ==================== CORRECTIVE ACTIONS ====================
+
+// GetCorrectiveActions lists the actions of every report, or of the report
+// in the path
+func (h *NCRHandler) GetCorrectiveActions(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	ncrID, scoped, ok := parentID(w, r, "ncr_id")
+	if !ok {
+		return
+	}
+	spec, ok := listSpec(w, r, repository.CorrectiveActionResource)
+	if !ok {
+		return
+	}
+	if scoped {
+		spec.Where("ncr_id", ncrID)
+	}
+	page, err := h.repo.ListCorrectiveActions(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+func (h *NCRHandler) GetCorrectiveAction(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.CorrectiveActionResource, h.repo.ListCorrectiveActions, "ncr_id")
+}
+
+func (h *NCRHandler) CreateCorrectiveAction(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	ncrID, scoped, ok := parentID(w, r, "ncr_id")
+	if !ok {
+		return
+	}
+	var ca models.CorrectiveAction
+	if !readBody(w, r, &ca) {
+		return
+	}
+	if scoped {
+		ca.NCRID = ncrID
+	}
+	if !validBody(w, &ca) {
+		return
+	}
+	if err := h.service.CreateAction(r.Context(), &ca); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, ca)
+}
+
+func (h *NCRHandler) UpdateCorrectiveAction(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	ncrID, scoped, ok := parentID(w, r, "ncr_id")
+	if !ok {
+		return
+	}
+	var ca models.CorrectiveAction
+	if !readBody(w, r, &ca) {
+		return
+	}
+	if scoped {
+		ca.NCRID = ncrID
+	}
+	if !validBody(w, &ca) {
+		return
+	}
+	if err := h.service.UpdateAction(r.Context(), id, &ca); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "CorrectiveAction updated successfully")
+}
+
+func (h *NCRHandler) DeleteCorrectiveAction(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	ncrID, _, ok := parentID(w, r, "ncr_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteAction(r.Context(), ncrID, id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "CorrectiveAction deleted successfully")
+}
+
+// CompleteCorrectiveAction marks an action done; the body may say how
+func (h *NCRHandler) CompleteCorrectiveAction(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var body struct {
+		Notes string `json:"notes"`
+	}
+	if r.ContentLength != 0 && !decodeBody(w, r, &body) {
+		return
+	}
+	ca, err := h.service.CompleteAction(r.Context(), id, body.Notes)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, ca)
+}
+
+// GetOverdueCorrectiveActions answers the open actions past their due date
+// as of ?as_of, by default today
+func (h *NCRHandler) GetOverdueCorrectiveActions(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	at := time.Now().UTC()
+	if raw := r.URL.Query().Get("as_of"); raw != "" {
+		t, err := scheduling.ParseTime(raw)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid as_of")
+			return
+		}
+		at = t
+	}
+	overdue, err := h.service.Overdue(r.Context(), at)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, overdue)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/router"
+	"lumber-erp-api/utils"
+)
+
+// intParam parses a numeric path segment, falling back to the query string
+// for the legacy routes, and answers 400 when it is missing or malformed;
+// callers return when ok is false
+func intParam(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	raw := router.Param(r, name)
+	if raw == "" {
+		raw = r.URL.Query().Get(name)
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid "+name)
+		return 0, false
+	}
+	return value, true
+}
+
+// parentID reads the enclosing resource ID of a nested route such as
+// /salesorders/{soid}/items. scoped is false on routes without the segment.
+func parentID(w http.ResponseWriter, r *http.Request, name string) (id int, scoped, ok bool) {
+	if router.Param(r, name) == "" {
+		return 0, false, true
+	}
+	id, ok = intParam(w, r, name)
+	return id, true, ok
+}
+
+// getMember answers the row of res whose key is the id parameter, loading it
+// by filtering list on the key and on the parent segments of a nested route
+// such as /salesorders/{soid}/items/{id}; it answers 404 when none matches
+func getMember[T any](w http.ResponseWriter, r *http.Request, res query.Resource,
+	list func(context.Context, query.Spec) (query.Page[T], error), parents ...string) {
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	spec := query.Spec{Limit: 1}
+	spec.Where(res.Key[0], id)
+	for _, name := range parents {
+		parent, scoped, ok := parentID(w, r, name)
+		if !ok {
+			return
+		}
+		if scoped {
+			spec.Where(name, parent)
+		}
+	}
+	page, err := list(r.Context(), spec)
+	if err == nil && len(page.Data) == 0 {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, page.Data[0])
+}
+
+// listSpec parses the pagination, filter and sort parameters of a list
+// request and answers 400 when they are invalid. Legacy routes page the
+// same way, so no list returns more than query.MaxLimit rows.
+func listSpec(w http.ResponseWriter, r *http.Request, res query.Resource) (query.Spec, bool) {
+	spec, err := query.Parse(r.URL.Query(), res)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+		return spec, false
+	}
+	return spec, true
+}
+
+// respondPage answers with the page envelope. Legacy routes keep their bare
+// array body and report the total and next cursor in headers instead.
+func respondPage[T any](w http.ResponseWriter, r *http.Request, page query.Page[T]) {
+	if router.Routed(r) {
+		utils.RespondJSON(w, http.StatusOK, page)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	utils.RespondJSON(w, http.StatusOK, page.Data)
+}
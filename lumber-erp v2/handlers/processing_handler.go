@@ -1,326 +1,484 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// ProcessingHandler serves sawmills, processing units and orders, maintenance and waste
+type ProcessingHandler struct {
+	repo    repository.ProcessingRepository
+	service *processing.Service
+}
+
+func NewProcessingHandler(repos repository.Repositories) *ProcessingHandler {
+	return &ProcessingHandler{repo: repos.Processing, service: processing.NewService(repos)}
+}
+
 // ==================== SAWMILLS ====================
-func CreateSawmill(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) CreateSawmill(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var sm models.Sawmill
-	json.NewDecoder(r.Body).Decode(&sm)
+	if !decodeBody(w, r, &sm) {
+		return
+	}
 
-	query := `INSERT INTO Sawmill (Name, Location, Capacity, Status)
-              VALUES ($1, $2, $3, $4) RETURNING SawmillID`
-	err := config.DB.QueryRow(query, sm.Name, sm.Location, sm.Capacity, sm.Status).Scan(&sm.SawmillID)
+	err := h.repo.CreateSawmill(r.Context(), &sm)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, sm)
 }
 
-func GetSawmills(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) GetSawmills(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT SawmillID, Name, Location, Capacity, Status FROM Sawmill ORDER BY Name`)
+	spec, ok := listSpec(w, r, repository.SawmillResource)
+	if !ok {
+		return
+	}
+	sawmills, err := h.repo.ListSawmills(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, sawmills)
+}
 
-	sawmills := []models.Sawmill{}
-	for rows.Next() {
-		var s models.Sawmill
-		rows.Scan(&s.SawmillID, &s.Name, &s.Location, &s.Capacity, &s.Status)
-		sawmills = append(sawmills, s)
-	}
-	utils.RespondJSON(w, http.StatusOK, sawmills)
+func (h *ProcessingHandler) GetSawmill(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.SawmillResource, h.repo.ListSawmills)
 }
 
-func UpdateSawmill(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) UpdateSawmill(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var sm models.Sawmill
-	json.NewDecoder(r.Body).Decode(&sm)
+	if !decodeBody(w, r, &sm) {
+		return
+	}
 
-	query := `UPDATE Sawmill SET Name = $2, Location = $3, Capacity = $4, Status = $5 WHERE SawmillID = $1`
-	_, err := config.DB.Exec(query, id, sm.Name, sm.Location, sm.Capacity, sm.Status)
+	err := h.repo.UpdateSawmill(r.Context(), id, &sm)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Sawmill updated successfully")
 }
 
-func DeleteSawmill(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) DeleteSawmill(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Sawmill WHERE SawmillID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteSawmill(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Sawmill deleted successfully")
 }
 
 // ==================== PROCESSING UNITS ====================
-func CreateProcessingUnit(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) CreateProcessingUnit(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var pu models.ProcessingUnit
-	json.NewDecoder(r.Body).Decode(&pu)
+	if !decodeBody(w, r, &pu) {
+		return
+	}
 
-	query := `INSERT INTO ProcessingUnit (SawmillID, Cutting, Drying, Finishing, Capacity, Status)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING UnitID`
-	err := config.DB.QueryRow(query, pu.SawmillID, pu.Cutting, pu.Drying, pu.Finishing,
-		pu.Capacity, pu.Status).Scan(&pu.UnitID)
+	err := h.repo.CreateProcessingUnit(r.Context(), &pu)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, pu)
 }
 
-func GetProcessingUnits(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) GetProcessingUnits(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT UnitID, SawmillID, Cutting, Drying, Finishing, Capacity, Status 
-                           FROM ProcessingUnit ORDER BY UnitID`)
+	spec, ok := listSpec(w, r, repository.ProcessingUnitResource)
+	if !ok {
+		return
+	}
+	units, err := h.repo.ListProcessingUnits(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, units)
+}
 
-	units := []models.ProcessingUnit{}
-	for rows.Next() {
-		var p models.ProcessingUnit
-		rows.Scan(&p.UnitID, &p.SawmillID, &p.Cutting, &p.Drying, &p.Finishing, &p.Capacity, &p.Status)
-		units = append(units, p)
-	}
-	utils.RespondJSON(w, http.StatusOK, units)
+func (h *ProcessingHandler) GetProcessingUnit(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.ProcessingUnitResource, h.repo.ListProcessingUnits)
 }
 
-func UpdateProcessingUnit(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) UpdateProcessingUnit(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var pu models.ProcessingUnit
-	json.NewDecoder(r.Body).Decode(&pu)
+	if !decodeBody(w, r, &pu) {
+		return
+	}
 
-	query := `UPDATE ProcessingUnit SET SawmillID = $2, Cutting = $3, Drying = $4, Finishing = $5,
-              Capacity = $6, Status = $7 WHERE UnitID = $1`
-	_, err := config.DB.Exec(query, id, pu.SawmillID, pu.Cutting, pu.Drying, pu.Finishing, pu.Capacity, pu.Status)
+	err := h.repo.UpdateProcessingUnit(r.Context(), id, &pu)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ProcessingUnit updated successfully")
 }
 
-func DeleteProcessingUnit(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) DeleteProcessingUnit(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM ProcessingUnit WHERE UnitID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteProcessingUnit(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ProcessingUnit deleted successfully")
 }
 
 // ==================== PROCESSING ORDERS ====================
-func CreateProcessingOrder(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) CreateProcessingOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var po models.ProcessingOrder
-	json.NewDecoder(r.Body).Decode(&po)
+	if !decodeBody(w, r, &po) {
+		return
+	}
 
-	query := `INSERT INTO ProcessingOrder (ProductTypeID, UnitID, StartDate, EndDate, OutputQuantity, EfficiencyRate)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING ProcessingID`
-	err := config.DB.QueryRow(query, po.ProductTypeID, po.UnitID, po.StartDate, po.EndDate,
-		po.OutputQuantity, po.EfficiencyRate).Scan(&po.ProcessingID)
+	err := h.service.Create(r.Context(), &po)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, po)
 }
 
-func GetProcessingOrders(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) GetProcessingOrders(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT ProcessingID, ProductTypeID, UnitID, StartDate, EndDate, 
-                           OutputQuantity, EfficiencyRate FROM ProcessingOrder ORDER BY StartDate DESC`)
+	spec, ok := listSpec(w, r, repository.ProcessingOrderResource)
+	if !ok {
+		return
+	}
+	orders, err := h.repo.ListProcessingOrders(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, orders)
+}
 
-	orders := []models.ProcessingOrder{}
-	for rows.Next() {
-		var p models.ProcessingOrder
-		rows.Scan(&p.ProcessingID, &p.ProductTypeID, &p.UnitID, &p.StartDate, &p.EndDate,
-			&p.OutputQuantity, &p.EfficiencyRate)
-		orders = append(orders, p)
+// GetProcessingOrder answers an order with the harvest batches it consumed
+func (h *ProcessingHandler) GetProcessingOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	po, err := h.repo.GetProcessingOrder(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, orders)
+	utils.RespondJSON(w, http.StatusOK, po)
 }
 
-func UpdateProcessingOrder(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) UpdateProcessingOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var po models.ProcessingOrder
-	json.NewDecoder(r.Body).Decode(&po)
+	if !decodeBody(w, r, &po) {
+		return
+	}
 
-	query := `UPDATE ProcessingOrder SET ProductTypeID = $2, UnitID = $3, StartDate = $4, EndDate = $5,
-              OutputQuantity = $6, EfficiencyRate = $7 WHERE ProcessingID = $1`
-	_, err := config.DB.Exec(query, id, po.ProductTypeID, po.UnitID, po.StartDate, po.EndDate,
-		po.OutputQuantity, po.EfficiencyRate)
+	err := h.service.Update(r.Context(), id, &po)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ProcessingOrder updated successfully")
 }
 
-func DeleteProcessingOrder(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) DeleteProcessingOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM ProcessingOrder WHERE ProcessingID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.service.Delete(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ProcessingOrder deleted successfully")
 }
 
+// processingStep serves a lifecycle step that needs no body
+func (h *ProcessingHandler) processingStep(step func(context.Context, int) (models.ProcessingOrder, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.EnableCORS(&w)
+		id, ok := intParam(w, r, "id")
+		if !ok {
+			return
+		}
+		po, err := step(r.Context(), id)
+		if err != nil {
+			apierr.Respond(w, err)
+			return
+		}
+		utils.RespondJSON(w, http.StatusOK, po)
+	}
+}
+
+// ReleaseProcessingOrder hands a planned order to the floor
+func (h *ProcessingHandler) ReleaseProcessingOrder(w http.ResponseWriter, r *http.Request) {
+	h.processingStep(h.service.Release)(w, r)
+}
+
+// StartProcessingOrder begins a released order
+func (h *ProcessingHandler) StartProcessingOrder(w http.ResponseWriter, r *http.Request) {
+	h.processingStep(h.service.Start)(w, r)
+}
+
+// CompleteProcessingOrder posts the inputs and output of an order in
+// progress and computes its yield and mass balance
+func (h *ProcessingHandler) CompleteProcessingOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var c processing.Completion
+	if !readBody(w, r, &c) {
+		return
+	}
+	po, err := h.service.Complete(r.Context(), id, c)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, po)
+}
+
+// CloseProcessingOrder freezes a completed order
+func (h *ProcessingHandler) CloseProcessingOrder(w http.ResponseWriter, r *http.Request) {
+	h.processingStep(h.service.Close)(w, r)
+}
+
+// ==================== HARVEST BATCH PROCESSING ====================
+func (h *ProcessingHandler) GetHarvestBatchProcessing(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	orderID, ok := intParam(w, r, "processing_id")
+	if !ok {
+		return
+	}
+	spec, ok := listSpec(w, r, repository.HarvestBatchProcessingResource)
+	if !ok {
+		return
+	}
+	spec.Where("processing_id", orderID)
+
+	links, err := h.repo.ListHarvestBatchProcessing(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, links)
+}
+
+// AttachHarvestBatch records that the order consumed part of a harvest batch
+func (h *ProcessingHandler) AttachHarvestBatch(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	orderID, ok := intParam(w, r, "processing_id")
+	if !ok {
+		return
+	}
+	var link models.HarvestBatchProcessing
+	if !decodeBody(w, r, &link) {
+		return
+	}
+	link.ProcessingID = orderID
+
+	err := h.service.Attach(r.Context(), &link)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, link)
+}
+
+func (h *ProcessingHandler) DetachHarvestBatch(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	orderID, ok := intParam(w, r, "processing_id")
+	if !ok {
+		return
+	}
+	batchID, ok := intParam(w, r, "batch_id")
+	if !ok {
+		return
+	}
+	err := h.service.Detach(r.Context(), orderID, batchID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "HarvestBatch_Processing deleted successfully")
+}
+
 // ==================== MAINTENANCE RECORDS ====================
-func CreateMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) CreateMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var mr models.MaintenanceRecord
-	json.NewDecoder(r.Body).Decode(&mr)
+	if !decodeBody(w, r, &mr) {
+		return
+	}
 
-	query := `INSERT INTO MaintenanceRecord (UnitID, MaintenanceDate, Description, Cost, PartsUsed, DowntimeHours)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING MaintenanceID`
-	err := config.DB.QueryRow(query, mr.UnitID, mr.MaintenanceDate, mr.Description,
-		mr.Cost, mr.PartsUsed, mr.DowntimeHours).Scan(&mr.MaintenanceID)
+	err := h.repo.CreateMaintenanceRecord(r.Context(), &mr)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, mr)
 }
 
-func GetMaintenanceRecords(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) GetMaintenanceRecords(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT MaintenanceID, UnitID, MaintenanceDate, Description, Cost, PartsUsed, DowntimeHours 
-                           FROM MaintenanceRecord ORDER BY MaintenanceDate DESC`)
+	spec, ok := listSpec(w, r, repository.MaintenanceRecordResource)
+	if !ok {
+		return
+	}
+	records, err := h.repo.ListMaintenanceRecords(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, records)
+}
 
-	records := []models.MaintenanceRecord{}
-	for rows.Next() {
-		var m models.MaintenanceRecord
-		rows.Scan(&m.MaintenanceID, &m.UnitID, &m.MaintenanceDate, &m.Description,
-			&m.Cost, &m.PartsUsed, &m.DowntimeHours)
-		records = append(records, m)
-	}
-	utils.RespondJSON(w, http.StatusOK, records)
+func (h *ProcessingHandler) GetMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.MaintenanceRecordResource, h.repo.ListMaintenanceRecords)
 }
 
-func UpdateMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) UpdateMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var mr models.MaintenanceRecord
-	json.NewDecoder(r.Body).Decode(&mr)
+	if !decodeBody(w, r, &mr) {
+		return
+	}
 
-	query := `UPDATE MaintenanceRecord SET UnitID = $2, MaintenanceDate = $3, Description = $4,
-              Cost = $5, PartsUsed = $6, DowntimeHours = $7 WHERE MaintenanceID = $1`
-	_, err := config.DB.Exec(query, id, mr.UnitID, mr.MaintenanceDate, mr.Description,
-		mr.Cost, mr.PartsUsed, mr.DowntimeHours)
+	err := h.repo.UpdateMaintenanceRecord(r.Context(), id, &mr)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "MaintenanceRecord updated successfully")
 }
 
-func DeleteMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) DeleteMaintenanceRecord(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM MaintenanceRecord WHERE MaintenanceID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteMaintenanceRecord(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "MaintenanceRecord deleted successfully")
 }
 
 // ==================== WASTE RECORDS ====================
-func CreateWasteRecord(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) CreateWasteRecord(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var wr models.WasteRecord
-	json.NewDecoder(r.Body).Decode(&wr)
+	if !decodeBody(w, r, &wr) {
+		return
+	}
 
-	query := `INSERT INTO WasteRecord (ProcessingID, WasteType, Volume, DisposalMethod, Recycled)
-              VALUES ($1, $2, $3, $4, $5) RETURNING WasteID`
-	err := config.DB.QueryRow(query, wr.ProcessingID, wr.WasteType, wr.Volume,
-		wr.DisposalMethod, wr.Recycled).Scan(&wr.WasteID)
+	err := h.repo.CreateWasteRecord(r.Context(), &wr)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, wr)
 }
 
-func GetWasteRecords(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) GetWasteRecords(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT WasteID, ProcessingID, WasteType, Volume, DisposalMethod, Recycled 
-                           FROM WasteRecord ORDER BY WasteID`)
+	spec, ok := listSpec(w, r, repository.WasteRecordResource)
+	if !ok {
+		return
+	}
+	records, err := h.repo.ListWasteRecords(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, records)
+}
 
-	records := []models.WasteRecord{}
-	for rows.Next() {
-		var w models.WasteRecord
-		rows.Scan(&w.WasteID, &w.ProcessingID, &w.WasteType, &w.Volume, &w.DisposalMethod, &w.Recycled)
-		records = append(records, w)
-	}
-	utils.RespondJSON(w, http.StatusOK, records)
+func (h *ProcessingHandler) GetWasteRecord(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.WasteRecordResource, h.repo.ListWasteRecords)
 }
 
-func UpdateWasteRecord(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) UpdateWasteRecord(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var wr models.WasteRecord
-	json.NewDecoder(r.Body).Decode(&wr)
+	if !decodeBody(w, r, &wr) {
+		return
+	}
 
-	query := `UPDATE WasteRecord SET ProcessingID = $2, WasteType = $3, Volume = $4,
-              DisposalMethod = $5, Recycled = $6 WHERE WasteID = $1`
-	_, err := config.DB.Exec(query, id, wr.ProcessingID, wr.WasteType, wr.Volume, wr.DisposalMethod, wr.Recycled)
+	err := h.repo.UpdateWasteRecord(r.Context(), id, &wr)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "WasteRecord updated successfully")
 }
 
-func DeleteWasteRecord(w http.ResponseWriter, r *http.Request) {
+func (h *ProcessingHandler) DeleteWasteRecord(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM WasteRecord WHERE WasteID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteWasteRecord(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "WasteRecord deleted successfully")
-}
\ No newline at end of file
+}
@@ -1,138 +1,183 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// ProcurementHandler serves purchase orders and their line items
+type ProcurementHandler struct {
+	repo repository.PurchaseOrderRepository
+}
+
+func NewProcurementHandler(repo repository.PurchaseOrderRepository) *ProcurementHandler {
+	return &ProcurementHandler{repo: repo}
+}
+
 // ==================== PURCHASE ORDERS ====================
-func CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) CreatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var po models.PurchaseOrder
-	json.NewDecoder(r.Body).Decode(&po)
+	if !decodeBody(w, r, &po) {
+		return
+	}
 
-	query := `INSERT INTO PurchaseOrder (EmployeeID, SupplierID, OrderDate, ExpectedDeliveryDate, Status, TotalAmount)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING POID`
-	err := config.DB.QueryRow(query, po.EmployeeID, po.SupplierID, po.OrderDate,
-		po.ExpectedDeliveryDate, po.Status, po.TotalAmount).Scan(&po.POID)
+	err := h.repo.CreatePurchaseOrder(r.Context(), &po)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, po)
 }
 
-func GetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) GetPurchaseOrders(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT POID, EmployeeID, SupplierID, OrderDate, ExpectedDeliveryDate, Status, TotalAmount 
-                           FROM PurchaseOrder ORDER BY OrderDate DESC`)
+	spec, ok := listSpec(w, r, repository.PurchaseOrderResource)
+	if !ok {
+		return
+	}
+	orders, err := h.repo.ListPurchaseOrders(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, orders)
+}
 
-	orders := []models.PurchaseOrder{}
-	for rows.Next() {
-		var p models.PurchaseOrder
-		rows.Scan(&p.POID, &p.EmployeeID, &p.SupplierID, &p.OrderDate,
-			&p.ExpectedDeliveryDate, &p.Status, &p.TotalAmount)
-		orders = append(orders, p)
-	}
-	utils.RespondJSON(w, http.StatusOK, orders)
+func (h *ProcurementHandler) GetPurchaseOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.PurchaseOrderResource, h.repo.ListPurchaseOrders)
 }
 
-func UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) UpdatePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var po models.PurchaseOrder
-	json.NewDecoder(r.Body).Decode(&po)
+	if !decodeBody(w, r, &po) {
+		return
+	}
 
-	query := `UPDATE PurchaseOrder SET EmployeeID = $2, SupplierID = $3, OrderDate = $4,
-              ExpectedDeliveryDate = $5, Status = $6, TotalAmount = $7 WHERE POID = $1`
-	_, err := config.DB.Exec(query, id, po.EmployeeID, po.SupplierID, po.OrderDate,
-		po.ExpectedDeliveryDate, po.Status, po.TotalAmount)
+	err := h.repo.UpdatePurchaseOrder(r.Context(), id, &po)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "PurchaseOrder updated successfully")
 }
 
-func DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) DeletePurchaseOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM PurchaseOrder WHERE POID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeletePurchaseOrder(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "PurchaseOrder deleted successfully")
 }
 
 // ==================== PURCHASE ORDER ITEMS ====================
-func CreatePurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) CreatePurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
+	orderID, scoped, ok := parentID(w, r, "poid")
+	if !ok {
+		return
+	}
 	var poi models.PurchaseOrderItem
-	json.NewDecoder(r.Body).Decode(&poi)
+	if !readBody(w, r, &poi) {
+		return
+	}
+	if scoped {
+		poi.POID = orderID
+	}
+	if !validBody(w, &poi) {
+		return
+	}
 
-	query := `INSERT INTO PurchaseOrderItem (POID, ProductTypeID, Quantity, UnitPrice, Subtotal)
-              VALUES ($1, $2, $3, $4, $5) RETURNING POItemID`
-	err := config.DB.QueryRow(query, poi.POID, poi.ProductTypeID, poi.Quantity,
-		poi.UnitPrice, poi.Subtotal).Scan(&poi.POItemID)
+	err := h.repo.CreatePurchaseOrderItem(r.Context(), &poi)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, poi)
 }
 
-func GetPurchaseOrderItems(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) GetPurchaseOrderItems(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT POItemID, POID, ProductTypeID, Quantity, UnitPrice, Subtotal 
-                           FROM PurchaseOrderItem ORDER BY POItemID`)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	orderID, scoped, ok := parentID(w, r, "poid")
+	if !ok {
+		return
+	}
+	spec, ok := listSpec(w, r, repository.PurchaseOrderItemResource)
+	if !ok {
 		return
 	}
-	defer rows.Close()
+	if scoped {
+		spec.Where("poid", orderID)
+	}
 
-	items := []models.PurchaseOrderItem{}
-	for rows.Next() {
-		var p models.PurchaseOrderItem
-		rows.Scan(&p.POItemID, &p.POID, &p.ProductTypeID, &p.Quantity, &p.UnitPrice, &p.Subtotal)
-		items = append(items, p)
+	items, err := h.repo.ListPurchaseOrderItems(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, items)
+	respondPage(w, r, items)
 }
 
-func UpdatePurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) GetPurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	getMember(w, r, repository.PurchaseOrderItemResource, h.repo.ListPurchaseOrderItems, "poid")
+}
+
+func (h *ProcurementHandler) UpdatePurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	orderID, scoped, ok := parentID(w, r, "poid")
+	if !ok {
+		return
+	}
 	var poi models.PurchaseOrderItem
-	json.NewDecoder(r.Body).Decode(&poi)
+	if !readBody(w, r, &poi) {
+		return
+	}
+	if scoped {
+		poi.POID = orderID
+	}
+	if !validBody(w, &poi) {
+		return
+	}
 
-	query := `UPDATE PurchaseOrderItem SET POID = $2, ProductTypeID = $3, Quantity = $4,
-              UnitPrice = $5, Subtotal = $6 WHERE POItemID = $1`
-	_, err := config.DB.Exec(query, id, poi.POID, poi.ProductTypeID, poi.Quantity, poi.UnitPrice, poi.Subtotal)
+	err := h.repo.UpdatePurchaseOrderItem(r.Context(), id, &poi)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "PurchaseOrderItem updated successfully")
 }
 
-func DeletePurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
+func (h *ProcurementHandler) DeletePurchaseOrderItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM PurchaseOrderItem WHERE POItemID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeletePurchaseOrderItem(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "PurchaseOrderItem deleted successfully")
-}
\ No newline at end of file
+}
@@ -1,84 +1,240 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/inspection"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// QualityHandler serves quality inspections, the defect catalog and the
+// templates inspections are graded against
+type QualityHandler struct {
+	repo    repository.QualityRepository
+	service *inspection.Service
+}
+
+func NewQualityHandler(repos repository.Repositories) *QualityHandler {
+	return &QualityHandler{repo: repos.Quality, service: inspection.NewService(repos)}
+}
+
 // ==================== QUALITY INSPECTIONS ====================
-func CreateQualityInspection(w http.ResponseWriter, r *http.Request) {
+
+// CreateQualityInspection records an inspection; one made against a
+// template is graded and answered with its measurements and defects
+func (h *QualityHandler) CreateQualityInspection(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var qi models.QualityInspection
-	if err := json.NewDecoder(r.Body).Decode(&qi); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeBody(w, r, &qi) {
 		return
 	}
 
-	query := `INSERT INTO QualityInspection (EmployeeID, ProcessingID, POItemID, BatchID, Result, MoistureLevel, CertificationID, Date)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING InspectionID`
-	err := config.DB.QueryRow(query, qi.EmployeeID, qi.ProcessingID, qi.POItemID, qi.BatchID,
-		qi.Result, qi.MoistureLevel, qi.CertificationID, qi.Date).Scan(&qi.InspectionID)
+	err := h.service.Create(r.Context(), &qi)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, qi)
 }
 
-func GetQualityInspections(w http.ResponseWriter, r *http.Request) {
+func (h *QualityHandler) GetQualityInspections(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT InspectionID, EmployeeID, ProcessingID, POItemID, BatchID, 
-                           Result, MoistureLevel, CertificationID, Date 
-                           FROM QualityInspection ORDER BY Date DESC`)
+	spec, ok := listSpec(w, r, repository.QualityInspectionResource)
+	if !ok {
+		return
+	}
+	inspections, err := h.repo.ListQualityInspections(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, inspections)
+}
 
-	inspections := []models.QualityInspection{}
-	for rows.Next() {
-		var q models.QualityInspection
-		if err := rows.Scan(&q.InspectionID, &q.EmployeeID, &q.ProcessingID, &q.POItemID, &q.BatchID,
-			&q.Result, &q.MoistureLevel, &q.CertificationID, &q.Date); err != nil {
-			continue
-		}
-		inspections = append(inspections, q)
+// GetQualityInspection returns an inspection with its measurements and
+// defects
+func (h *QualityHandler) GetQualityInspection(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, inspections)
+	qi, err := h.repo.GetQualityInspection(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, qi)
 }
 
-func UpdateQualityInspection(w http.ResponseWriter, r *http.Request) {
+func (h *QualityHandler) UpdateQualityInspection(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var qi models.QualityInspection
-	if err := json.NewDecoder(r.Body).Decode(&qi); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	if !decodeBody(w, r, &qi) {
 		return
 	}
 
-	query := `UPDATE QualityInspection SET EmployeeID = $2, ProcessingID = $3, POItemID = $4, BatchID = $5,
-              Result = $6, MoistureLevel = $7, CertificationID = $8, Date = $9 WHERE InspectionID = $1`
-	_, err := config.DB.Exec(query, id, qi.EmployeeID, qi.ProcessingID, qi.POItemID, qi.BatchID,
-		qi.Result, qi.MoistureLevel, qi.CertificationID, qi.Date)
+	err := h.service.Update(r.Context(), id, &qi)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "QualityInspection updated successfully")
 }
 
-func DeleteQualityInspection(w http.ResponseWriter, r *http.Request) {
+func (h *QualityHandler) DeleteQualityInspection(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM QualityInspection WHERE InspectionID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteQualityInspection(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "QualityInspection deleted successfully")
-}
\ No newline at end of file
+}
+
+// ==================== DEFECT CODES ====================
+func (h *QualityHandler) GetDefectCodes(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.DefectCodeResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListDefectCodes(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+func (h *QualityHandler) GetDefectCode(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.DefectCodeResource, h.repo.ListDefectCodes)
+}
+
+func (h *QualityHandler) CreateDefectCode(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var dc models.DefectCode
+	if !decodeBody(w, r, &dc) {
+		return
+	}
+	if err := h.service.CreateDefectCode(r.Context(), &dc); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, dc)
+}
+
+func (h *QualityHandler) UpdateDefectCode(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var dc models.DefectCode
+	if !decodeBody(w, r, &dc) {
+		return
+	}
+	if err := h.service.UpdateDefectCode(r.Context(), id, &dc); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "DefectCode updated successfully")
+}
+
+func (h *QualityHandler) DeleteDefectCode(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteDefectCode(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "DefectCode deleted successfully")
+}
+
+// ==================== INSPECTION TEMPLATES ====================
+func (h *QualityHandler) GetInspectionTemplates(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.InspectionTemplateResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListInspectionTemplates(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// GetInspectionTemplate returns a template with its items and grades
+func (h *QualityHandler) GetInspectionTemplate(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	t, err := h.repo.GetInspectionTemplate(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, t)
+}
+
+func (h *QualityHandler) CreateInspectionTemplate(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var t models.InspectionTemplate
+	if !decodeBody(w, r, &t) {
+		return
+	}
+	if err := h.service.CreateTemplate(r.Context(), &t); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, t)
+}
+
+func (h *QualityHandler) UpdateInspectionTemplate(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var t models.InspectionTemplate
+	if !decodeBody(w, r, &t) {
+		return
+	}
+	if err := h.service.UpdateTemplate(r.Context(), id, &t); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "InspectionTemplate updated successfully")
+}
+
+func (h *QualityHandler) DeleteInspectionTemplate(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteInspectionTemplate(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "InspectionTemplate deleted successfully")
+}
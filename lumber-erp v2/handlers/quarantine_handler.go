@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/utils"
+)
+
+// QuarantineHandler serves the status steps of stock lots
+type QuarantineHandler struct {
+	repo    repository.StockRepository
+	service *quarantine.Service
+}
+
+func NewQuarantineHandler(repos repository.Repositories) *QuarantineHandler {
+	return &QuarantineHandler{repo: repos.Stock, service: quarantine.NewService(repos)}
+}
+
+// GetStockDispositions lists the dispositions of every lot, or of the lot
+// in the path, newest first
+func (h *QuarantineHandler) GetStockDispositions(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	stockID, scoped, ok := parentID(w, r, "id")
+	if !ok {
+		return
+	}
+	spec, ok := listSpec(w, r, repository.StockDispositionResource)
+	if !ok {
+		return
+	}
+	if scoped {
+		lot := query.Spec{Limit: 1}
+		lot.Where("stock_id", stockID)
+		if found, err := h.repo.ListStockItems(r.Context(), lot); err != nil || len(found.Data) == 0 {
+			if err == nil {
+				err = repository.ErrNotFound
+			}
+			apierr.Respond(w, err)
+			return
+		}
+		spec.Where("stock_id", stockID)
+	}
+	page, err := h.repo.ListStockDispositions(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// DisposeStockItem releases, reworks, scraps, returns, quarantines or
+// reserves a lot
+func (h *QuarantineHandler) DisposeStockItem(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var d quarantine.Disposition
+	if !decodeBody(w, r, &d) {
+		return
+	}
+	out, err := h.service.Dispose(r.Context(), id, d)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, out)
+}
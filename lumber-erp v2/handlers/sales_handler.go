@@ -1,203 +1,266 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// SalesHandler serves customers, sales orders and their line items. Items
+// cannot be picked from lots in quarantine.
+type SalesHandler struct {
+	tx        repository.Transactor
+	customers repository.CustomerRepository
+	orders    repository.SalesOrderRepository
+	stock     *quarantine.Service
+}
+
+func NewSalesHandler(repos repository.Repositories) *SalesHandler {
+	return &SalesHandler{tx: repos.Tx, customers: repos.Customers, orders: repos.SalesOrders,
+		stock: quarantine.NewService(repos)}
+}
+
 // ==================== CUSTOMERS ====================
-func CreateCustomer(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var cust models.Customer
-	json.NewDecoder(r.Body).Decode(&cust)
+	if !decodeBody(w, r, &cust) {
+		return
+	}
 
-	query := `INSERT INTO Customer (Name, Retailer, EndUser, ContactInfo, Address, TaxNumber)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING CustomerID`
-	err := config.DB.QueryRow(query, cust.Name, cust.Retailer, cust.EndUser, cust.ContactInfo,
-		cust.Address, cust.TaxNumber).Scan(&cust.CustomerID)
+	err := h.customers.CreateCustomer(r.Context(), &cust)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, cust)
 }
 
-func GetCustomers(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT CustomerID, Name, Retailer, EndUser, ContactInfo, Address, TaxNumber 
-                           FROM Customer ORDER BY Name`)
+	spec, ok := listSpec(w, r, repository.CustomerResource)
+	if !ok {
+		return
+	}
+	custs, err := h.customers.ListCustomers(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, custs)
+}
 
-	custs := []models.Customer{}
-	for rows.Next() {
-		var c models.Customer
-		rows.Scan(&c.CustomerID, &c.Name, &c.Retailer, &c.EndUser, &c.ContactInfo, &c.Address, &c.TaxNumber)
-		custs = append(custs, c)
-	}
-	utils.RespondJSON(w, http.StatusOK, custs)
+func (h *SalesHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.CustomerResource, h.customers.ListCustomers)
 }
 
-func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var cust models.Customer
-	json.NewDecoder(r.Body).Decode(&cust)
+	if !decodeBody(w, r, &cust) {
+		return
+	}
 
-	query := `UPDATE Customer SET Name = $2, Retailer = $3, EndUser = $4, ContactInfo = $5,
-              Address = $6, TaxNumber = $7 WHERE CustomerID = $1`
-	_, err := config.DB.Exec(query, id, cust.Name, cust.Retailer, cust.EndUser, cust.ContactInfo,
-		cust.Address, cust.TaxNumber)
+	err := h.customers.UpdateCustomer(r.Context(), id, &cust)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Customer updated successfully")
 }
 
-func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Customer WHERE CustomerID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.customers.DeleteCustomer(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Customer deleted successfully")
 }
 
 // ==================== SALES ORDERS ====================
-func CreateSalesOrder(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) CreateSalesOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var so models.SalesOrder
-	json.NewDecoder(r.Body).Decode(&so)
+	if !decodeBody(w, r, &so) {
+		return
+	}
 
-	query := `INSERT INTO SalesOrder (EmployeeID, CustomerID, OrderDate, DeliveryDate, Status, TotalAmount)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING SOID`
-	err := config.DB.QueryRow(query, so.EmployeeID, so.CustomerID, so.OrderDate,
-		so.DeliveryDate, so.Status, so.TotalAmount).Scan(&so.SOID)
+	err := h.orders.CreateSalesOrder(r.Context(), &so)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, so)
 }
 
-func GetSalesOrders(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) GetSalesOrders(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT SOID, EmployeeID, CustomerID, OrderDate, DeliveryDate, Status, TotalAmount 
-                           FROM SalesOrder ORDER BY OrderDate DESC`)
+	spec, ok := listSpec(w, r, repository.SalesOrderResource)
+	if !ok {
+		return
+	}
+	orders, err := h.orders.ListSalesOrders(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, orders)
+}
 
-	orders := []models.SalesOrder{}
-	for rows.Next() {
-		var s models.SalesOrder
-		rows.Scan(&s.SOID, &s.EmployeeID, &s.CustomerID, &s.OrderDate,
-			&s.DeliveryDate, &s.Status, &s.TotalAmount)
-		orders = append(orders, s)
-	}
-	utils.RespondJSON(w, http.StatusOK, orders)
+func (h *SalesHandler) GetSalesOrder(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.SalesOrderResource, h.orders.ListSalesOrders)
 }
 
-func UpdateSalesOrder(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) UpdateSalesOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var so models.SalesOrder
-	json.NewDecoder(r.Body).Decode(&so)
+	if !decodeBody(w, r, &so) {
+		return
+	}
 
-	query := `UPDATE SalesOrder SET EmployeeID = $2, CustomerID = $3, OrderDate = $4,
-              DeliveryDate = $5, Status = $6, TotalAmount = $7 WHERE SOID = $1`
-	_, err := config.DB.Exec(query, id, so.EmployeeID, so.CustomerID, so.OrderDate,
-		so.DeliveryDate, so.Status, so.TotalAmount)
+	err := h.orders.UpdateSalesOrder(r.Context(), id, &so)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SalesOrder updated successfully")
 }
 
-func DeleteSalesOrder(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) DeleteSalesOrder(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM SalesOrder WHERE SOID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.orders.DeleteSalesOrder(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SalesOrder deleted successfully")
 }
 
 // ==================== SALES ORDER ITEMS ====================
-func CreateSalesOrderItem(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) CreateSalesOrderItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
+	orderID, scoped, ok := parentID(w, r, "soid")
+	if !ok {
+		return
+	}
 	var soi models.SalesOrderItem
-	json.NewDecoder(r.Body).Decode(&soi)
-
-	query := `INSERT INTO SalesOrderItem (SOID, ProductTypeID, Quantity, UnitPrice, Discount, Subtotal)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING SOItemID`
-	err := config.DB.QueryRow(query, soi.SOID, soi.ProductTypeID, soi.Quantity,
-		soi.UnitPrice, soi.Discount, soi.Subtotal).Scan(&soi.SOItemID)
+	if !readBody(w, r, &soi) {
+		return
+	}
+	if scoped {
+		soi.SOID = orderID
+	}
+	if !validBody(w, &soi) {
+		return
+	}
+	err := h.tx.WithinTx(r.Context(), func(ctx context.Context) error {
+		if err := h.stock.CheckAllocation(ctx, soi); err != nil {
+			return err
+		}
+		return h.orders.CreateSalesOrderItem(ctx, &soi)
+	})
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, soi)
 }
 
-func GetSalesOrderItems(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) GetSalesOrderItems(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT SOItemID, SOID, ProductTypeID, Quantity, UnitPrice, Discount, Subtotal 
-                           FROM SalesOrderItem ORDER BY SOItemID`)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	orderID, scoped, ok := parentID(w, r, "soid")
+	if !ok {
+		return
+	}
+	spec, ok := listSpec(w, r, repository.SalesOrderItemResource)
+	if !ok {
 		return
 	}
-	defer rows.Close()
+	if scoped {
+		spec.Where("soid", orderID)
+	}
 
-	items := []models.SalesOrderItem{}
-	for rows.Next() {
-		var s models.SalesOrderItem
-		rows.Scan(&s.SOItemID, &s.SOID, &s.ProductTypeID, &s.Quantity, &s.UnitPrice, &s.Discount, &s.Subtotal)
-		items = append(items, s)
+	items, err := h.orders.ListSalesOrderItems(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, items)
+	respondPage(w, r, items)
 }
 
-func UpdateSalesOrderItem(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) GetSalesOrderItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	var soi models.SalesOrderItem
-	json.NewDecoder(r.Body).Decode(&soi)
+	getMember(w, r, repository.SalesOrderItemResource, h.orders.ListSalesOrderItems, "soid")
+}
 
-	query := `UPDATE SalesOrderItem SET SOID = $2, ProductTypeID = $3, Quantity = $4,
-              UnitPrice = $5, Discount = $6, Subtotal = $7 WHERE SOItemID = $1`
-	_, err := config.DB.Exec(query, id, soi.SOID, soi.ProductTypeID, soi.Quantity,
-		soi.UnitPrice, soi.Discount, soi.Subtotal)
+func (h *SalesHandler) UpdateSalesOrderItem(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	orderID, scoped, ok := parentID(w, r, "soid")
+	if !ok {
+		return
+	}
+	var soi models.SalesOrderItem
+	if !readBody(w, r, &soi) {
+		return
+	}
+	if scoped {
+		soi.SOID = orderID
+	}
+	if !validBody(w, &soi) {
+		return
+	}
+	err := h.tx.WithinTx(r.Context(), func(ctx context.Context) error {
+		if err := h.stock.CheckAllocation(ctx, soi); err != nil {
+			return err
+		}
+		return h.orders.UpdateSalesOrderItem(ctx, id, &soi)
+	})
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SalesOrderItem updated successfully")
 }
 
-func DeleteSalesOrderItem(w http.ResponseWriter, r *http.Request) {
+func (h *SalesHandler) DeleteSalesOrderItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM SalesOrderItem WHERE SOItemID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.orders.DeleteSalesOrderItem(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SalesOrderItem deleted successfully")
-}
\ No newline at end of file
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/sampling"
+	"lumber-erp-api/utils"
+)
+
+// SamplingHandler serves the acceptance sampling plans of received
+// purchase order items and the inspection lots they are applied to
+type SamplingHandler struct {
+	repo    repository.QualityRepository
+	service *sampling.Service
+}
+
+func NewSamplingHandler(repos repository.Repositories) *SamplingHandler {
+	return &SamplingHandler{repo: repos.Quality, service: sampling.NewService(repos)}
+}
+
+// ==================== SAMPLING PLANS ====================
+func (h *SamplingHandler) GetSamplingPlans(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.SamplingPlanResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListSamplingPlans(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+func (h *SamplingHandler) GetSamplingPlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.SamplingPlanResource, h.repo.ListSamplingPlans)
+}
+
+func (h *SamplingHandler) CreateSamplingPlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var sp models.SamplingPlan
+	if !decodeBody(w, r, &sp) {
+		return
+	}
+	if err := h.service.CreatePlan(r.Context(), &sp); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, sp)
+}
+
+func (h *SamplingHandler) UpdateSamplingPlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var sp models.SamplingPlan
+	if !decodeBody(w, r, &sp) {
+		return
+	}
+	if err := h.service.UpdatePlan(r.Context(), id, &sp); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "SamplingPlan updated successfully")
+}
+
+func (h *SamplingHandler) DeleteSamplingPlan(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteSamplingPlan(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "SamplingPlan deleted successfully")
+}
+
+// GetSample answers the sample size and accept and reject numbers a lot of
+// ?lot_size from ?supplier_id, of an optional ?product_type_id, calls for
+func (h *SamplingHandler) GetSample(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	supplierID, ok := intParam(w, r, "supplier_id")
+	if !ok {
+		return
+	}
+	lotSize, ok := intParam(w, r, "lot_size")
+	if !ok {
+		return
+	}
+	var productTypeID *int
+	if raw := r.URL.Query().Get("product_type_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid product_type_id")
+			return
+		}
+		productTypeID = &id
+	}
+	sample, err := h.service.Lookup(r.Context(), supplierID, productTypeID, lotSize)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, sample)
+}
+
+// GetSamplingState answers where a supplier stands in the switching rules
+func (h *SamplingHandler) GetSamplingState(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	st, err := h.service.State(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, st)
+}
+
+// ==================== INSPECTION LOTS ====================
+func (h *SamplingHandler) GetInspectionLots(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.InspectionLotResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListInspectionLots(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+func (h *SamplingHandler) GetInspectionLot(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	il, err := h.repo.GetInspectionLot(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, il)
+}
+
+// CreateInspectionLot receives a purchase order item for acceptance
+// sampling, answering the lot with the sample it calls for
+func (h *SamplingHandler) CreateInspectionLot(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var il models.InspectionLot
+	if !decodeBody(w, r, &il) {
+		return
+	}
+	if err := h.service.Open(r.Context(), &il); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, il)
+}
+
+func (h *SamplingHandler) DeleteInspectionLot(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteInspectionLot(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "InspectionLot deleted successfully")
+}
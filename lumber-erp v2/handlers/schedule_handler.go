@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/utils"
+)
+
+// ScheduleHandler serves the processing schedule of sawmills
+type ScheduleHandler struct {
+	service *scheduling.Service
+}
+
+func NewScheduleHandler(repos repository.Repositories) *ScheduleHandler {
+	return &ScheduleHandler{service: scheduling.NewService(repos)}
+}
+
+// GetSawmillSchedule answers the timeline of a sawmill between ?from and
+// ?to, by default the coming two weeks
+func (h *ScheduleHandler) GetSawmillSchedule(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		t, err := scheduling.ParseTime(raw)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid from")
+			return
+		}
+		from = t
+	}
+	to := from.Add(scheduling.Horizon)
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		t, err := scheduling.ParseTime(raw)
+		if err != nil || !t.After(from) {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid to")
+			return
+		}
+		to = t
+	}
+
+	timeline, err := h.service.Timeline(r.Context(), id, from, to)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, timeline)
+}
+
+// ScheduleSawmill places orders on the sawmill's units and stores their slots
+func (h *ScheduleHandler) ScheduleSawmill(w http.ResponseWriter, r *http.Request) {
+	h.schedule(w, r, false)
+}
+
+// DryRunSchedule answers where orders would be placed without storing
+// anything, so planners can try what-ifs
+func (h *ScheduleHandler) DryRunSchedule(w http.ResponseWriter, r *http.Request) {
+	h.schedule(w, r, true)
+}
+
+func (h *ScheduleHandler) schedule(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req scheduling.Request
+	if r.ContentLength != 0 && !readBody(w, r, &req) {
+		return
+	}
+	plan, err := h.service.Schedule(r.Context(), id, req, dryRun)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, plan)
+}
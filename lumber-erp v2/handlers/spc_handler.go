@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/spc"
+	"lumber-erp-api/utils"
+)
+
+// SPCHandler serves the control charts of inspections and the quality
+// alerts their violations raise
+type SPCHandler struct {
+	repo    repository.QualityRepository
+	service *spc.Service
+}
+
+func NewSPCHandler(repos repository.Repositories) *SPCHandler {
+	return &SPCHandler{repo: repos.Quality, service: spc.NewService(repos)}
+}
+
+// spcParams reads ?metric, ?subgroup_size, ?unit_id, ?species_id, ?from,
+// ?to, ?baseline_from and ?baseline_to, answering 400 when one is malformed
+func spcParams(w http.ResponseWriter, r *http.Request) (spc.Params, bool) {
+	q := r.URL.Query()
+	p := spc.Params{Metric: q.Get("metric")}
+	ints := []struct {
+		name string
+		dst  **int
+	}{{"unit_id", &p.UnitID}, {"species_id", &p.SpeciesID}}
+	for _, param := range ints {
+		if raw := q.Get(param.name); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid "+param.name)
+				return p, false
+			}
+			*param.dst = &id
+		}
+	}
+	if raw := q.Get("subgroup_size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid subgroup_size")
+			return p, false
+		}
+		p.SubgroupSize = n
+	}
+	dates := []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &p.From}, {"to", &p.To}, {"baseline_from", &p.BaselineFrom}, {"baseline_to", &p.BaselineTo}}
+	for _, param := range dates {
+		if raw := q.Get(param.name); raw != "" {
+			t, err := scheduling.ParseTime(raw)
+			if err != nil {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid "+param.name)
+				return p, false
+			}
+			*param.dst = &t
+		}
+	}
+	return p, true
+}
+
+// GetSPCCharts answers the X-bar, R and p-charts of each processing unit
+// and species
+func (h *SPCHandler) GetSPCCharts(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	p, ok := spcParams(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.service.Charts(r.Context(), p)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, report)
+}
+
+// RaiseSPCAlerts raises the alerts of the charts now instead of waiting for
+// the periodic check
+func (h *SPCHandler) RaiseSPCAlerts(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	p, ok := spcParams(w, r)
+	if !ok {
+		return
+	}
+	raised, err := h.service.Alert(r.Context(), p)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, raised)
+}
+
+// ==================== QUALITY ALERTS ====================
+func (h *SPCHandler) GetQualityAlerts(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.QualityAlertResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListQualityAlerts(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// ResolveQualityAlert marks an alert dealt with
+func (h *SPCHandler) ResolveQualityAlert(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	qa, err := h.service.Resolve(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, qa)
+}
@@ -1,201 +1,223 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// SupplierHandler serves suppliers with their performance reviews and contracts
+type SupplierHandler struct {
+	repo repository.SupplierRepository
+}
+
+func NewSupplierHandler(repo repository.SupplierRepository) *SupplierHandler {
+	return &SupplierHandler{repo: repo}
+}
+
 // ==================== SUPPLIERS ====================
-func CreateSupplier(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var sup models.Supplier
-	json.NewDecoder(r.Body).Decode(&sup)
+	if !decodeBody(w, r, &sup) {
+		return
+	}
 
-	query := `INSERT INTO Supplier (CompanyName, ContactPerson, Email, Phone, ComplianceStatus, Raw, Semi_Processed)
-              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING SupplierID`
-	err := config.DB.QueryRow(query, sup.CompanyName, sup.ContactPerson, sup.Email, sup.Phone,
-		sup.ComplianceStatus, sup.Raw, sup.SemiProcessed).Scan(&sup.SupplierID)
+	err := h.repo.CreateSupplier(r.Context(), &sup)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, sup)
 }
 
-func GetSuppliers(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT SupplierID, CompanyName, ContactPerson, Email, Phone, 
-                           ComplianceStatus, Raw, Semi_Processed FROM Supplier ORDER BY CompanyName`)
+	spec, ok := listSpec(w, r, repository.SupplierResource)
+	if !ok {
+		return
+	}
+	sups, err := h.repo.ListSuppliers(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, sups)
+}
 
-	sups := []models.Supplier{}
-	for rows.Next() {
-		var s models.Supplier
-		rows.Scan(&s.SupplierID, &s.CompanyName, &s.ContactPerson, &s.Email, &s.Phone,
-			&s.ComplianceStatus, &s.Raw, &s.SemiProcessed)
-		sups = append(sups, s)
-	}
-	utils.RespondJSON(w, http.StatusOK, sups)
+func (h *SupplierHandler) GetSupplier(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.SupplierResource, h.repo.ListSuppliers)
 }
 
-func UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var sup models.Supplier
-	json.NewDecoder(r.Body).Decode(&sup)
+	if !decodeBody(w, r, &sup) {
+		return
+	}
 
-	query := `UPDATE Supplier SET CompanyName = $2, ContactPerson = $3, Email = $4, Phone = $5,
-              ComplianceStatus = $6, Raw = $7, Semi_Processed = $8 WHERE SupplierID = $1`
-	_, err := config.DB.Exec(query, id, sup.CompanyName, sup.ContactPerson, sup.Email, sup.Phone,
-		sup.ComplianceStatus, sup.Raw, sup.SemiProcessed)
+	err := h.repo.UpdateSupplier(r.Context(), id, &sup)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Supplier updated successfully")
 }
 
-func DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Supplier WHERE SupplierID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteSupplier(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Supplier deleted successfully")
 }
 
 // ==================== SUPPLIER PERFORMANCE ====================
-func CreateSupplierPerformance(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) CreateSupplierPerformance(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var sp models.SupplierPerformance
-	json.NewDecoder(r.Body).Decode(&sp)
+	if !decodeBody(w, r, &sp) {
+		return
+	}
 
-	query := `INSERT INTO SupplierPerformance (SupplierID, Rating, DeliveryTimeliness, QualityScore, ReviewDate)
-              VALUES ($1, $2, $3, $4, $5) RETURNING PerformanceID`
-	err := config.DB.QueryRow(query, sp.SupplierID, sp.Rating, sp.DeliveryTimeliness,
-		sp.QualityScore, sp.ReviewDate).Scan(&sp.PerformanceID)
+	err := h.repo.CreateSupplierPerformance(r.Context(), &sp)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, sp)
 }
 
-func GetSupplierPerformances(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) GetSupplierPerformances(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT PerformanceID, SupplierID, Rating, DeliveryTimeliness, QualityScore, ReviewDate 
-                           FROM SupplierPerformance ORDER BY ReviewDate DESC`)
+	spec, ok := listSpec(w, r, repository.SupplierPerformanceResource)
+	if !ok {
+		return
+	}
+	performances, err := h.repo.ListSupplierPerformances(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, performances)
+}
 
-	performances := []models.SupplierPerformance{}
-	for rows.Next() {
-		var s models.SupplierPerformance
-		rows.Scan(&s.PerformanceID, &s.SupplierID, &s.Rating, &s.DeliveryTimeliness, &s.QualityScore, &s.ReviewDate)
-		performances = append(performances, s)
-	}
-	utils.RespondJSON(w, http.StatusOK, performances)
+func (h *SupplierHandler) GetSupplierPerformance(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.SupplierPerformanceResource, h.repo.ListSupplierPerformances)
 }
 
-func UpdateSupplierPerformance(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) UpdateSupplierPerformance(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var sp models.SupplierPerformance
-	json.NewDecoder(r.Body).Decode(&sp)
+	if !decodeBody(w, r, &sp) {
+		return
+	}
 
-	query := `UPDATE SupplierPerformance SET SupplierID = $2, Rating = $3, DeliveryTimeliness = $4,
-              QualityScore = $5, ReviewDate = $6 WHERE PerformanceID = $1`
-	_, err := config.DB.Exec(query, id, sp.SupplierID, sp.Rating, sp.DeliveryTimeliness, sp.QualityScore, sp.ReviewDate)
+	err := h.repo.UpdateSupplierPerformance(r.Context(), id, &sp)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SupplierPerformance updated successfully")
 }
 
-func DeleteSupplierPerformance(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) DeleteSupplierPerformance(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM SupplierPerformance WHERE PerformanceID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteSupplierPerformance(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SupplierPerformance deleted successfully")
 }
 
 // ==================== SUPPLIER CONTRACT ====================
-func CreateSupplierContract(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) CreateSupplierContract(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var sc models.SupplierContract
-	json.NewDecoder(r.Body).Decode(&sc)
+	if !decodeBody(w, r, &sc) {
+		return
+	}
 
-	query := `INSERT INTO SupplierContract (SupplierID, StartDate, EndDate, Terms, ContractValue, Status)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING ContractID`
-	err := config.DB.QueryRow(query, sc.SupplierID, sc.StartDate, sc.EndDate,
-		sc.Terms, sc.ContractValue, sc.Status).Scan(&sc.ContractID)
+	err := h.repo.CreateSupplierContract(r.Context(), &sc)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, sc)
 }
 
-func GetSupplierContracts(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) GetSupplierContracts(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT ContractID, SupplierID, StartDate, EndDate, Terms, ContractValue, Status 
-                           FROM SupplierContract ORDER BY StartDate DESC`)
+	spec, ok := listSpec(w, r, repository.SupplierContractResource)
+	if !ok {
+		return
+	}
+	contracts, err := h.repo.ListSupplierContracts(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, contracts)
+}
 
-	contracts := []models.SupplierContract{}
-	for rows.Next() {
-		var s models.SupplierContract
-		rows.Scan(&s.ContractID, &s.SupplierID, &s.StartDate, &s.EndDate, &s.Terms, &s.ContractValue, &s.Status)
-		contracts = append(contracts, s)
-	}
-	utils.RespondJSON(w, http.StatusOK, contracts)
+func (h *SupplierHandler) GetSupplierContract(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.SupplierContractResource, h.repo.ListSupplierContracts)
 }
 
-func UpdateSupplierContract(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) UpdateSupplierContract(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var sc models.SupplierContract
-	json.NewDecoder(r.Body).Decode(&sc)
+	if !decodeBody(w, r, &sc) {
+		return
+	}
 
-	query := `UPDATE SupplierContract SET SupplierID = $2, StartDate = $3, EndDate = $4,
-              Terms = $5, ContractValue = $6, Status = $7 WHERE ContractID = $1`
-	_, err := config.DB.Exec(query, id, sc.SupplierID, sc.StartDate, sc.EndDate, sc.Terms, sc.ContractValue, sc.Status)
+	err := h.repo.UpdateSupplierContract(r.Context(), id, &sc)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SupplierContract updated successfully")
 }
 
-func DeleteSupplierContract(w http.ResponseWriter, r *http.Request) {
+func (h *SupplierHandler) DeleteSupplierContract(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM SupplierContract WHERE ContractID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteSupplierContract(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "SupplierContract deleted successfully")
-}
\ No newline at end of file
+}
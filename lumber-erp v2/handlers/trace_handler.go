@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/router"
+	"lumber-erp-api/trace"
+	"lumber-erp-api/utils"
+)
+
+// TraceHandler serves lot genealogy across forests, processing, warehouses,
+// sales and transport
+type TraceHandler struct {
+	repos repository.Repositories
+}
+
+func NewTraceHandler(repos repository.Repositories) *TraceHandler {
+	return &TraceHandler{repos: repos}
+}
+
+// ==================== TRACEABILITY ====================
+
+// TraceLot answers the genealogy of the harvest batch with the QR code in
+// the path
+func (h *TraceHandler) TraceLot(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	genealogy, err := trace.Lot(r.Context(), h.repos, router.Param(r, "qr_code"))
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, genealogy)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/transfers"
+	"lumber-erp-api/utils"
+)
+
+// TransferHandler serves stock transfers between warehouses
+type TransferHandler struct {
+	repo    repository.TransferRepository
+	service *transfers.Service
+}
+
+func NewTransferHandler(repos repository.Repositories) *TransferHandler {
+	return &TransferHandler{repo: repos.Transfers, service: transfers.NewService(repos)}
+}
+
+// ==================== STOCK TRANSFERS ====================
+func (h *TransferHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	spec, ok := listSpec(w, r, repository.StockTransferResource)
+	if !ok {
+		return
+	}
+	page, err := h.repo.ListTransfers(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	respondPage(w, r, page)
+}
+
+// GetTransfer answers a transfer with its lines
+func (h *TransferHandler) GetTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	t, err := h.repo.GetTransfer(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, t)
+}
+
+func (h *TransferHandler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	var t models.StockTransfer
+	if !decodeBody(w, r, &t) {
+		return
+	}
+	if err := h.service.Create(r.Context(), &t); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, t)
+}
+
+func (h *TransferHandler) UpdateTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var t models.StockTransfer
+	if !decodeBody(w, r, &t) {
+		return
+	}
+	if err := h.service.Update(r.Context(), id, &t); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "StockTransfer updated successfully")
+}
+
+func (h *TransferHandler) DeleteTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondSuccess(w, "StockTransfer deleted successfully")
+}
+
+// transferStepRequest is the optional body of the workflow steps
+type transferStepRequest struct {
+	EmployeeID *int                `json:"employee_id"`
+	ShipmentID *int                `json:"shipment_id"`
+	TruckID    *int                `json:"truck_id"`
+	Lines      []transfers.Receipt `json:"lines"`
+}
+
+// DispatchTransfer posts the transfer_out entries of a draft
+func (h *TransferHandler) DispatchTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req transferStepRequest
+	if r.ContentLength != 0 && !readBody(w, r, &req) {
+		return
+	}
+	t, err := h.service.Dispatch(r.Context(), id, req.EmployeeID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, t)
+}
+
+// TransitTransfer puts a dispatched transfer on its way, linking the
+// shipment or truck carrying it
+func (h *TransferHandler) TransitTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req transferStepRequest
+	if r.ContentLength != 0 && !readBody(w, r, &req) {
+		return
+	}
+	t, err := h.service.Transit(r.Context(), id, req.ShipmentID, req.TruckID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, t)
+}
+
+// ReceiveTransfer books what arrived; lines not listed arrived in full
+func (h *TransferHandler) ReceiveTransfer(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var req transferStepRequest
+	if r.ContentLength != 0 && !readBody(w, r, &req) {
+		return
+	}
+	t, err := h.service.Receive(r.Context(), id, req.Lines, req.EmployeeID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, t)
+}
+
+// GetTransferDiscrepancies reports the lines received short or over
+func (h *TransferHandler) GetTransferDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	report, err := h.service.Discrepancies(r.Context(), id)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, report)
+}
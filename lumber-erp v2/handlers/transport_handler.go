@@ -1,388 +1,440 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// TransportHandler serves carriers, trucks, drivers, routes, shipments and fuel logs.
+// Sales orders picked from lots in quarantine are not shipped.
+type TransportHandler struct {
+	tx    repository.Transactor
+	repo  repository.TransportRepository
+	stock *quarantine.Service
+}
+
+func NewTransportHandler(repos repository.Repositories) *TransportHandler {
+	return &TransportHandler{tx: repos.Tx, repo: repos.Transport, stock: quarantine.NewService(repos)}
+}
+
 // ==================== TRANSPORT COMPANIES ====================
-func CreateTransportCompany(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) CreateTransportCompany(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var tc models.TransportCompany
-	json.NewDecoder(r.Body).Decode(&tc)
+	if !decodeBody(w, r, &tc) {
+		return
+	}
 
-	query := `INSERT INTO TransportCompany (CompanyName, ContactInfo, LicenseNumber, Rating)
-              VALUES ($1, $2, $3, $4) RETURNING CompanyID`
-	err := config.DB.QueryRow(query, tc.CompanyName, tc.ContactInfo, tc.LicenseNumber, tc.Rating).Scan(&tc.CompanyID)
+	err := h.repo.CreateTransportCompany(r.Context(), &tc)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, tc)
 }
 
-func GetTransportCompanies(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) GetTransportCompanies(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT CompanyID, CompanyName, ContactInfo, LicenseNumber, Rating 
-                           FROM TransportCompany ORDER BY CompanyName`)
+	spec, ok := listSpec(w, r, repository.TransportCompanyResource)
+	if !ok {
+		return
+	}
+	companies, err := h.repo.ListTransportCompanies(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, companies)
+}
 
-	companies := []models.TransportCompany{}
-	for rows.Next() {
-		var t models.TransportCompany
-		rows.Scan(&t.CompanyID, &t.CompanyName, &t.ContactInfo, &t.LicenseNumber, &t.Rating)
-		companies = append(companies, t)
-	}
-	utils.RespondJSON(w, http.StatusOK, companies)
+func (h *TransportHandler) GetTransportCompany(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.TransportCompanyResource, h.repo.ListTransportCompanies)
 }
 
-func UpdateTransportCompany(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) UpdateTransportCompany(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var tc models.TransportCompany
-	json.NewDecoder(r.Body).Decode(&tc)
+	if !decodeBody(w, r, &tc) {
+		return
+	}
 
-	query := `UPDATE TransportCompany SET CompanyName = $2, ContactInfo = $3, LicenseNumber = $4, Rating = $5 
-              WHERE CompanyID = $1`
-	_, err := config.DB.Exec(query, id, tc.CompanyName, tc.ContactInfo, tc.LicenseNumber, tc.Rating)
+	err := h.repo.UpdateTransportCompany(r.Context(), id, &tc)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "TransportCompany updated successfully")
 }
 
-func DeleteTransportCompany(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) DeleteTransportCompany(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM TransportCompany WHERE CompanyID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteTransportCompany(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "TransportCompany deleted successfully")
 }
 
 // ==================== TRUCKS ====================
-func CreateTruck(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) CreateTruck(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var truck models.Truck
-	json.NewDecoder(r.Body).Decode(&truck)
+	if !decodeBody(w, r, &truck) {
+		return
+	}
 
-	query := `INSERT INTO Truck (CompanyID, PlateNumber, Capacity, FuelType, Status)
-              VALUES ($1, $2, $3, $4, $5) RETURNING TruckID`
-	err := config.DB.QueryRow(query, truck.CompanyID, truck.PlateNumber, truck.Capacity,
-		truck.FuelType, truck.Status).Scan(&truck.TruckID)
+	err := h.repo.CreateTruck(r.Context(), &truck)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, truck)
 }
 
-func GetTrucks(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) GetTrucks(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT TruckID, CompanyID, PlateNumber, Capacity, FuelType, Status 
-                           FROM Truck ORDER BY PlateNumber`)
+	spec, ok := listSpec(w, r, repository.TruckResource)
+	if !ok {
+		return
+	}
+	trucks, err := h.repo.ListTrucks(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, trucks)
+}
 
-	trucks := []models.Truck{}
-	for rows.Next() {
-		var t models.Truck
-		rows.Scan(&t.TruckID, &t.CompanyID, &t.PlateNumber, &t.Capacity, &t.FuelType, &t.Status)
-		trucks = append(trucks, t)
-	}
-	utils.RespondJSON(w, http.StatusOK, trucks)
+func (h *TransportHandler) GetTruck(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.TruckResource, h.repo.ListTrucks)
 }
 
-func UpdateTruck(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) UpdateTruck(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var truck models.Truck
-	json.NewDecoder(r.Body).Decode(&truck)
+	if !decodeBody(w, r, &truck) {
+		return
+	}
 
-	query := `UPDATE Truck SET CompanyID = $2, PlateNumber = $3, Capacity = $4,
-              FuelType = $5, Status = $6 WHERE TruckID = $1`
-	_, err := config.DB.Exec(query, id, truck.CompanyID, truck.PlateNumber, truck.Capacity, truck.FuelType, truck.Status)
+	err := h.repo.UpdateTruck(r.Context(), id, &truck)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Truck updated successfully")
 }
 
-func DeleteTruck(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) DeleteTruck(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Truck WHERE TruckID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteTruck(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Truck deleted successfully")
 }
 
 // ==================== DRIVERS ====================
-func CreateDriver(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) CreateDriver(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var driver models.Driver
-	json.NewDecoder(r.Body).Decode(&driver)
+	if !decodeBody(w, r, &driver) {
+		return
+	}
 
-	query := `INSERT INTO Driver (EmployeeID, LicenseNumber, ExperienceYears, Status)
-              VALUES ($1, $2, $3, $4) RETURNING DriverID`
-	err := config.DB.QueryRow(query, driver.EmployeeID, driver.LicenseNumber,
-		driver.ExperienceYears, driver.Status).Scan(&driver.DriverID)
+	err := h.repo.CreateDriver(r.Context(), &driver)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, driver)
 }
 
-func GetDrivers(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) GetDrivers(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT DriverID, EmployeeID, LicenseNumber, ExperienceYears, Status 
-                           FROM Driver ORDER BY DriverID`)
+	spec, ok := listSpec(w, r, repository.DriverResource)
+	if !ok {
+		return
+	}
+	drivers, err := h.repo.ListDrivers(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, drivers)
+}
 
-	drivers := []models.Driver{}
-	for rows.Next() {
-		var d models.Driver
-		rows.Scan(&d.DriverID, &d.EmployeeID, &d.LicenseNumber, &d.ExperienceYears, &d.Status)
-		drivers = append(drivers, d)
-	}
-	utils.RespondJSON(w, http.StatusOK, drivers)
+func (h *TransportHandler) GetDriver(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.DriverResource, h.repo.ListDrivers)
 }
 
-func UpdateDriver(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) UpdateDriver(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var driver models.Driver
-	json.NewDecoder(r.Body).Decode(&driver)
+	if !decodeBody(w, r, &driver) {
+		return
+	}
 
-	query := `UPDATE Driver SET EmployeeID = $2, LicenseNumber = $3, ExperienceYears = $4, Status = $5 
-              WHERE DriverID = $1`
-	_, err := config.DB.Exec(query, id, driver.EmployeeID, driver.LicenseNumber, driver.ExperienceYears, driver.Status)
+	err := h.repo.UpdateDriver(r.Context(), id, &driver)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Driver updated successfully")
 }
 
-func DeleteDriver(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) DeleteDriver(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Driver WHERE DriverID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteDriver(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Driver deleted successfully")
 }
 
 // ==================== ROUTES ====================
-func CreateRoute(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) CreateRoute(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var route models.Route
-	json.NewDecoder(r.Body).Decode(&route)
+	if !decodeBody(w, r, &route) {
+		return
+	}
 
-	query := `INSERT INTO Route (StartLocation, EndLocation, DistanceKM, EstimatedTime)
-              VALUES ($1, $2, $3, $4) RETURNING RouteID`
-	err := config.DB.QueryRow(query, route.StartLocation, route.EndLocation,
-		route.DistanceKM, route.EstimatedTime).Scan(&route.RouteID)
+	err := h.repo.CreateRoute(r.Context(), &route)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, route)
 }
 
-func GetRoutes(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) GetRoutes(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT RouteID, StartLocation, EndLocation, DistanceKM, EstimatedTime 
-                           FROM Route ORDER BY RouteID`)
+	spec, ok := listSpec(w, r, repository.RouteResource)
+	if !ok {
+		return
+	}
+	routes, err := h.repo.ListRoutes(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, routes)
+}
 
-	routes := []models.Route{}
-	for rows.Next() {
-		var r models.Route
-		rows.Scan(&r.RouteID, &r.StartLocation, &r.EndLocation, &r.DistanceKM, &r.EstimatedTime)
-		routes = append(routes, r)
-	}
-	utils.RespondJSON(w, http.StatusOK, routes)
+func (h *TransportHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.RouteResource, h.repo.ListRoutes)
 }
 
-func UpdateRoute(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) UpdateRoute(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var route models.Route
-	json.NewDecoder(r.Body).Decode(&route)
+	if !decodeBody(w, r, &route) {
+		return
+	}
 
-	query := `UPDATE Route SET StartLocation = $2, EndLocation = $3, DistanceKM = $4, EstimatedTime = $5 
-              WHERE RouteID = $1`
-	_, err := config.DB.Exec(query, id, route.StartLocation, route.EndLocation, route.DistanceKM, route.EstimatedTime)
+	err := h.repo.UpdateRoute(r.Context(), id, &route)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Route updated successfully")
 }
 
-func DeleteRoute(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) DeleteRoute(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Route WHERE RouteID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteRoute(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Route deleted successfully")
 }
 
 // ==================== SHIPMENTS ====================
-func CreateShipment(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) CreateShipment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var ship models.Shipment
-	json.NewDecoder(r.Body).Decode(&ship)
-
-	query := `INSERT INTO Shipment (SOID, TruckID, DriverID, CompanyID, RouteID, ShipmentDate, Status, ProofOfDelivery)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ShipmentID`
-	err := config.DB.QueryRow(query, ship.SOID, ship.TruckID, ship.DriverID, ship.CompanyID,
-		ship.RouteID, ship.ShipmentDate, ship.Status, ship.ProofOfDelivery).Scan(&ship.ShipmentID)
+	if !decodeBody(w, r, &ship) {
+		return
+	}
+	err := h.tx.WithinTx(r.Context(), func(ctx context.Context) error {
+		if err := h.stock.CheckShipment(ctx, ship); err != nil {
+			return err
+		}
+		return h.repo.CreateShipment(ctx, &ship)
+	})
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, ship)
 }
 
-func GetShipments(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) GetShipments(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT ShipmentID, SOID, TruckID, DriverID, CompanyID, RouteID, 
-                           ShipmentDate, Status, ProofOfDelivery FROM Shipment ORDER BY ShipmentDate DESC`)
+	spec, ok := listSpec(w, r, repository.ShipmentResource)
+	if !ok {
+		return
+	}
+	shipments, err := h.repo.ListShipments(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, shipments)
+}
 
-	shipments := []models.Shipment{}
-	for rows.Next() {
-		var s models.Shipment
-		rows.Scan(&s.ShipmentID, &s.SOID, &s.TruckID, &s.DriverID, &s.CompanyID,
-			&s.RouteID, &s.ShipmentDate, &s.Status, &s.ProofOfDelivery)
-		shipments = append(shipments, s)
-	}
-	utils.RespondJSON(w, http.StatusOK, shipments)
+func (h *TransportHandler) GetShipment(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.ShipmentResource, h.repo.ListShipments)
 }
 
-func UpdateShipment(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) UpdateShipment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var ship models.Shipment
-	json.NewDecoder(r.Body).Decode(&ship)
-
-	query := `UPDATE Shipment SET SOID = $2, TruckID = $3, DriverID = $4, CompanyID = $5,
-              RouteID = $6, ShipmentDate = $7, Status = $8, ProofOfDelivery = $9 WHERE ShipmentID = $1`
-	_, err := config.DB.Exec(query, id, ship.SOID, ship.TruckID, ship.DriverID, ship.CompanyID,
-		ship.RouteID, ship.ShipmentDate, ship.Status, ship.ProofOfDelivery)
+	if !decodeBody(w, r, &ship) {
+		return
+	}
+	err := h.tx.WithinTx(r.Context(), func(ctx context.Context) error {
+		if err := h.stock.CheckShipment(ctx, ship); err != nil {
+			return err
+		}
+		return h.repo.UpdateShipment(ctx, id, &ship)
+	})
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Shipment updated successfully")
 }
 
-func DeleteShipment(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) DeleteShipment(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Shipment WHERE ShipmentID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteShipment(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Shipment deleted successfully")
 }
 
 // ==================== FUEL LOGS ====================
-func CreateFuelLog(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) CreateFuelLog(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var fl models.FuelLog
-	json.NewDecoder(r.Body).Decode(&fl)
+	if !decodeBody(w, r, &fl) {
+		return
+	}
 
-	query := `INSERT INTO FuelLog (DriverID, TruckID, TripDate, DistanceTraveled)
-              VALUES ($1, $2, $3, $4) RETURNING FuelLogID`
-	err := config.DB.QueryRow(query, fl.DriverID, fl.TruckID, fl.TripDate, fl.DistanceTraveled).Scan(&fl.FuelLogID)
+	err := h.repo.CreateFuelLog(r.Context(), &fl)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, fl)
 }
 
-func GetFuelLogs(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) GetFuelLogs(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT FuelLogID, DriverID, TruckID, TripDate, DistanceTraveled 
-                           FROM FuelLog ORDER BY TripDate DESC`)
+	spec, ok := listSpec(w, r, repository.FuelLogResource)
+	if !ok {
+		return
+	}
+	logs, err := h.repo.ListFuelLogs(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, logs)
+}
 
-	logs := []models.FuelLog{}
-	for rows.Next() {
-		var f models.FuelLog
-		rows.Scan(&f.FuelLogID, &f.DriverID, &f.TruckID, &f.TripDate, &f.DistanceTraveled)
-		logs = append(logs, f)
-	}
-	utils.RespondJSON(w, http.StatusOK, logs)
+func (h *TransportHandler) GetFuelLog(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.FuelLogResource, h.repo.ListFuelLogs)
 }
 
-func UpdateFuelLog(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) UpdateFuelLog(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var fl models.FuelLog
-	json.NewDecoder(r.Body).Decode(&fl)
+	if !decodeBody(w, r, &fl) {
+		return
+	}
 
-	query := `UPDATE FuelLog SET DriverID = $2, TruckID = $3, TripDate = $4, DistanceTraveled = $5 
-              WHERE FuelLogID = $1`
-	_, err := config.DB.Exec(query, id, fl.DriverID, fl.TruckID, fl.TripDate, fl.DistanceTraveled)
+	err := h.repo.UpdateFuelLog(r.Context(), id, &fl)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "FuelLog updated successfully")
 }
 
-func DeleteFuelLog(w http.ResponseWriter, r *http.Request) {
+func (h *TransportHandler) DeleteFuelLog(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM FuelLog WHERE FuelLogID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.repo.DeleteFuelLog(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "FuelLog deleted successfully")
-}
\ No newline at end of file
+}
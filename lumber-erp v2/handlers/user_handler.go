@@ -1,338 +1,372 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
-	"lumber-erp-api/config"
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/auth"
 	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
+// UserHandler serves user accounts, permissions, roles and role grants
+type UserHandler struct {
+	users  repository.UserRepository
+	access repository.AccessRepository
+}
+
+func NewUserHandler(users repository.UserRepository, access repository.AccessRepository) *UserHandler {
+	return &UserHandler{users: users, access: access}
+}
+
 // ==================== USERS ====================
-func CreateUser(w http.ResponseWriter, r *http.Request) {
+
+// newUser is the body of CreateUser: a user that must come with a password
+type newUser struct {
+	models.User
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	var user models.User
-	json.NewDecoder(r.Body).Decode(&user)
+	var body newUser
+	if !decodeBody(w, r, &body) {
+		return
+	}
 
-	query := `INSERT INTO "User" (Email, Password, First_Name, Last_Name, Phone_Number, Status)
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING User_ID`
-	err := config.DB.QueryRow(query, user.Email, user.Password, user.FirstName,
-		user.LastName, user.PhoneNumber, user.Status).Scan(&user.UserID)
+	user := body.User
+	hash, err := auth.HashPassword(body.Password)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
 
+	user.Password = hash
+	err = h.users.CreateUser(r.Context(), &user)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
+	user.Password = ""
 	utils.RespondJSON(w, http.StatusCreated, user)
 }
 
-func GetUsers(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT User_ID, Email, First_Name, Last_Name, 
-                           Phone_Number, Status, CreatedAt FROM "User" ORDER BY CreatedAt DESC`)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	spec, ok := listSpec(w, r, repository.UserResource)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-
-	users := []models.User{}
-	for rows.Next() {
-		var user models.User
-		rows.Scan(&user.UserID, &user.Email, &user.FirstName,
-			&user.LastName, &user.PhoneNumber, &user.Status, &user.CreatedAt)
-		users = append(users, user)
+	users, err := h.users.ListUsers(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, users)
+	respondPage(w, r, users)
 }
 
-func GetUser(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	var user models.User
-	query := `SELECT User_ID, Email, First_Name, Last_Name, Phone_Number, Status, CreatedAt
-              FROM "User" WHERE User_ID = $1`
-	err := config.DB.QueryRow(query, id).Scan(&user.UserID, &user.Email, &user.FirstName,
-		&user.LastName, &user.PhoneNumber, &user.Status, &user.CreatedAt)
-	if err != nil {
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	user, err := h.users.GetUser(r.Context(), id)
+	if err == repository.ErrNotFound {
 		utils.RespondError(w, http.StatusNotFound, "User not found")
 		return
 	}
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
 	utils.RespondJSON(w, http.StatusOK, user)
 }
 
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var user models.User
-	json.NewDecoder(r.Body).Decode(&user)
+	if !readBody(w, r, &user) {
+		return
+	}
 
-	query := `UPDATE "User" SET Email = $2, First_Name = $3, 
-              Last_Name = $4, Phone_Number = $5, Status = $6 WHERE User_ID = $1`
-	_, err := config.DB.Exec(query, id, user.Email, user.FirstName,
-		user.LastName, user.PhoneNumber, user.Status)
+	// Passwords are only changed through PUT /api/user/password
+	if user.Password != "" {
+		utils.RespondError(w, http.StatusBadRequest, "Use PUT /api/user/password?id={id} to change the password")
+		return
+	}
+	if !validBody(w, &user) {
+		return
+	}
+
+	err := h.users.UpdateUser(r.Context(), id, &user)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "User updated successfully")
 }
 
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM "User" WHERE User_ID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.users.DeleteUser(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "User deleted successfully")
 }
 
 // ==================== PERMISSIONS ====================
-func CreatePermission(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) CreatePermission(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var perm models.Permission
-	json.NewDecoder(r.Body).Decode(&perm)
+	if !decodeBody(w, r, &perm) {
+		return
+	}
 
-	query := `INSERT INTO Permission (ModuleName, ActionType) VALUES ($1, $2) RETURNING PermissionID`
-	err := config.DB.QueryRow(query, perm.ModuleName, perm.ActionType).Scan(&perm.PermissionID)
+	err := h.access.CreatePermission(r.Context(), &perm)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, perm)
 }
 
-func GetPermissions(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT PermissionID, ModuleName, ActionType FROM Permission ORDER BY ModuleName`)
+	spec, ok := listSpec(w, r, repository.PermissionResource)
+	if !ok {
+		return
+	}
+	perms, err := h.access.ListPermissions(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, perms)
+}
 
-	perms := []models.Permission{}
-	for rows.Next() {
-		var p models.Permission
-		rows.Scan(&p.PermissionID, &p.ModuleName, &p.ActionType)
-		perms = append(perms, p)
-	}
-	utils.RespondJSON(w, http.StatusOK, perms)
+func (h *UserHandler) GetPermission(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.PermissionResource, h.access.ListPermissions)
 }
 
-func UpdatePermission(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) UpdatePermission(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var perm models.Permission
-	json.NewDecoder(r.Body).Decode(&perm)
+	if !decodeBody(w, r, &perm) {
+		return
+	}
 
-	query := `UPDATE Permission SET ModuleName = $2, ActionType = $3 WHERE PermissionID = $1`
-	_, err := config.DB.Exec(query, id, perm.ModuleName, perm.ActionType)
+	err := h.access.UpdatePermission(r.Context(), id, &perm)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Permission updated successfully")
 }
 
-func DeletePermission(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) DeletePermission(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Permission WHERE PermissionID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.access.DeletePermission(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Permission deleted successfully")
 }
 
 // ==================== ROLES ====================
-func CreateRole(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var role models.Role
-	json.NewDecoder(r.Body).Decode(&role)
+	if !decodeBody(w, r, &role) {
+		return
+	}
 
-	query := `INSERT INTO Role (User_ID, Role_Name, Description) VALUES ($1, $2, $3) RETURNING Role_ID`
-	err := config.DB.QueryRow(query, role.UserID, role.RoleName, role.Description).Scan(&role.RoleID)
+	err := h.access.CreateRole(r.Context(), &role)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, role)
 }
 
-func GetRoles(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT Role_ID, User_ID, Role_Name, Description FROM Role ORDER BY Role_Name`)
+	spec, ok := listSpec(w, r, repository.RoleResource)
+	if !ok {
+		return
+	}
+	roles, err := h.access.ListRoles(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, roles)
+}
 
-	roles := []models.Role{}
-	for rows.Next() {
-		var role models.Role
-		rows.Scan(&role.RoleID, &role.UserID, &role.RoleName, &role.Description)
-		roles = append(roles, role)
-	}
-	utils.RespondJSON(w, http.StatusOK, roles)
+func (h *UserHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.RoleResource, h.access.ListRoles)
 }
 
-func UpdateRole(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var role models.Role
-	json.NewDecoder(r.Body).Decode(&role)
+	if !decodeBody(w, r, &role) {
+		return
+	}
 
-	query := `UPDATE Role SET User_ID = $2, Role_Name = $3, Description = $4 WHERE Role_ID = $1`
-	_, err := config.DB.Exec(query, id, role.UserID, role.RoleName, role.Description)
+	err := h.access.UpdateRole(r.Context(), id, &role)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Role updated successfully")
 }
 
-func DeleteRole(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Role WHERE Role_ID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.access.DeleteRole(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Role deleted successfully")
 }
+
 // ==================== ROLE PERMISSIONS ====================
-func CreateRolePermission(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) CreateRolePermission(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var rp models.RolePermission
-	json.NewDecoder(r.Body).Decode(&rp)
+	if !decodeBody(w, r, &rp) {
+		return
+	}
 
-	query := `INSERT INTO RolePermission (Role_ID, PermissionID) VALUES ($1, $2)`
-	_, err := config.DB.Exec(query, rp.RoleID, rp.PermissionID)
+	err := h.access.CreateRolePermission(r.Context(), &rp)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, rp)
 }
 
-func GetRolePermissions(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetRolePermissions(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT Role_ID, PermissionID FROM RolePermission ORDER BY Role_ID`)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	spec, ok := listSpec(w, r, repository.RolePermissionResource)
+	if !ok {
 		return
 	}
-	defer rows.Close()
-
-	rolePermissions := []models.RolePermission{}
-	for rows.Next() {
-		var rp models.RolePermission
-		rows.Scan(&rp.RoleID, &rp.PermissionID)
-		rolePermissions = append(rolePermissions, rp)
+	rolePermissions, err := h.access.ListRolePermissions(r.Context(), spec)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
-	utils.RespondJSON(w, http.StatusOK, rolePermissions)
+	respondPage(w, r, rolePermissions)
 }
 
-func GetRolePermissionsByRole(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetRolePermissionsByRole(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	roleID := r.URL.Query().Get("id")
-	
-	rows, err := config.DB.Query(`SELECT Role_ID, PermissionID FROM RolePermission WHERE Role_ID = $1`, roleID)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	roleID, ok := intParam(w, r, "id")
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
-	rolePermissions := []models.RolePermission{}
-	for rows.Next() {
-		var rp models.RolePermission
-		rows.Scan(&rp.RoleID, &rp.PermissionID)
-		rolePermissions = append(rolePermissions, rp)
+	rolePermissions, err := h.access.ListRolePermissionsByRole(r.Context(), roleID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
 	utils.RespondJSON(w, http.StatusOK, rolePermissions)
 }
 
-func GetRolePermissionsByPermission(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetRolePermissionsByPermission(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	permissionID := r.URL.Query().Get("id")
-	
-	rows, err := config.DB.Query(`SELECT Role_ID, PermissionID FROM RolePermission WHERE PermissionID = $1`, permissionID)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	permissionID, ok := intParam(w, r, "id")
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
-	rolePermissions := []models.RolePermission{}
-	for rows.Next() {
-		var rp models.RolePermission
-		rows.Scan(&rp.RoleID, &rp.PermissionID)
-		rolePermissions = append(rolePermissions, rp)
+	rolePermissions, err := h.access.ListRolePermissionsByPermission(r.Context(), permissionID)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
 	}
 	utils.RespondJSON(w, http.StatusOK, rolePermissions)
 }
 
-func DeleteRolePermission(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) DeleteRolePermission(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	roleID := r.URL.Query().Get("role_id")
-	permissionID := r.URL.Query().Get("permission_id")
-	
-	_, err := config.DB.Exec(`DELETE FROM RolePermission WHERE Role_ID = $1 AND PermissionID = $2`, roleID, permissionID)
+	roleID, ok := intParam(w, r, "role_id")
+	if !ok {
+		return
+	}
+	permissionID, ok := intParam(w, r, "permission_id")
+	if !ok {
+		return
+	}
+
+	err := h.access.DeleteRolePermission(r.Context(), roleID, permissionID)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "RolePermission deleted successfully")
 }
 
-func AssignPermissionsToRole(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) AssignPermissionsToRole(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	
+
 	var data struct {
-		RoleID        int   `json:"role_id"`
+		RoleID        int   `json:"role_id" validate:"required"`
 		PermissionIDs []int `json:"permission_ids"`
 	}
-	json.NewDecoder(r.Body).Decode(&data)
-
-	// Start transaction
-	tx, err := config.DB.Begin()
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	if !readBody(w, r, &data) {
 		return
 	}
-
-	// Delete existing permissions for this role
-	_, err = tx.Exec(`DELETE FROM RolePermission WHERE Role_ID = $1`, data.RoleID)
-	if err != nil {
-		tx.Rollback()
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+	roleID, scoped, ok := parentID(w, r, "id")
+	if !ok {
 		return
 	}
-
-	// Insert new permissions
-	for _, permID := range data.PermissionIDs {
-		_, err = tx.Exec(`INSERT INTO RolePermission (Role_ID, PermissionID) VALUES ($1, $2)`, data.RoleID, permID)
-		if err != nil {
-			tx.Rollback()
-			utils.RespondError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+	if scoped {
+		data.RoleID = roleID
+	}
+	if !validBody(w, &data) {
+		return
 	}
 
-	// Commit transaction
-	err = tx.Commit()
+	err := h.access.AssignPermissions(r.Context(), data.RoleID, data.PermissionIDs)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 
 	utils.RespondSuccess(w, "Permissions assigned successfully")
-}
\ No newline at end of file
+}
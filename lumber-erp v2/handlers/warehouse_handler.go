@@ -1,108 +1,182 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
-	"lumber-erp-api/config"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"lumber-erp-api/apierr"
 	"lumber-erp-api/models"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
-	"net/http"
+	"lumber-erp-api/validate"
+)
+
+// WarehouseHandler serves warehouses, product types and the stock held in them.
+// Product types, stock items, alerts and transactions keep the field names the
+// frontend uses rather than the column names.
+type WarehouseHandler struct {
+	tx         repository.Transactor
+	warehouses repository.WarehouseRepository
+	stock      repository.StockRepository
+	holds      *quarantine.Service
+}
+
+func NewWarehouseHandler(repos repository.Repositories) *WarehouseHandler {
+	return &WarehouseHandler{tx: repos.Tx, warehouses: repos.Warehouses, stock: repos.Stock,
+		holds: quarantine.NewService(repos)}
+}
+
+// Frontend field names that differ from the stored ones, so list filters
+// and sorts use the names the responses carry
+var (
+	productTypeFields          = map[string]string{"product_name": "name", "category": "grade", "unit_price": "price"}
+	stockItemFields            = map[string]string{"quantity_in_stock": "quantity"}
+	stockAlertFields           = map[string]string{"triggered_date": "created_at"}
+	inventoryTransactionFields = map[string]string{"reference_id": "remarks"}
 )
 
+// renameFields rewrites frontend field names in the filter and sort
+// parameters of r to the stored names
+func renameFields(r *http.Request, aliases map[string]string) {
+	rename := func(field string) string {
+		if stored, ok := aliases[field]; ok {
+			return stored
+		}
+		return field
+	}
+
+	renamed := url.Values{}
+	for key, values := range r.URL.Query() {
+		field, op := key, ""
+		if open := strings.IndexByte(key, '['); open > 0 {
+			field, op = key[:open], key[open:]
+		}
+		renamed[rename(field)+op] = values
+	}
+	if sort := renamed.Get("sort"); sort != "" {
+		fields := strings.Split(sort, ",")
+		for i, field := range fields {
+			if strings.HasPrefix(field, "-") {
+				fields[i] = "-" + rename(field[1:])
+			} else {
+				fields[i] = rename(field)
+			}
+		}
+		renamed.Set("sort", strings.Join(fields, ","))
+	}
+	r.URL.RawQuery = renamed.Encode()
+}
+
 // ==================== WAREHOUSES ====================
-func CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
 	var wh models.Warehouse
-	json.NewDecoder(r.Body).Decode(&wh)
+	if !decodeBody(w, r, &wh) {
+		return
+	}
 
-	query := `INSERT INTO Warehouse (Name, Location, Capacity, Contact)
-              VALUES ($1, $2, $3, $4) RETURNING WarehouseID`
-	err := config.DB.QueryRow(query, wh.Name, wh.Location, wh.Capacity, wh.Contact).Scan(&wh.WarehouseID)
+	err := h.warehouses.CreateWarehouse(r.Context(), &wh)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondJSON(w, http.StatusCreated, wh)
 }
 
-func GetWarehouses(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetWarehouses(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT WarehouseID, Name, Location, Capacity, Contact FROM Warehouse ORDER BY Name`)
+	spec, ok := listSpec(w, r, repository.WarehouseResource)
+	if !ok {
+		return
+	}
+	warehouses, err := h.warehouses.ListWarehouses(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
+	respondPage(w, r, warehouses)
+}
 
-	warehouses := []models.Warehouse{}
-	for rows.Next() {
-		var w models.Warehouse
-		rows.Scan(&w.WarehouseID, &w.Name, &w.Location, &w.Capacity, &w.Contact)
-		warehouses = append(warehouses, w)
-	}
-	utils.RespondJSON(w, http.StatusOK, warehouses)
+func (h *WarehouseHandler) GetWarehouse(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	getMember(w, r, repository.WarehouseResource, h.warehouses.ListWarehouses)
 }
 
-func UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var wh models.Warehouse
-	json.NewDecoder(r.Body).Decode(&wh)
+	if !decodeBody(w, r, &wh) {
+		return
+	}
 
-	query := `UPDATE Warehouse SET Name = $2, Location = $3, Capacity = $4, Contact = $5 WHERE WarehouseID = $1`
-	_, err := config.DB.Exec(query, id, wh.Name, wh.Location, wh.Capacity, wh.Contact)
+	err := h.warehouses.UpdateWarehouse(r.Context(), id, &wh)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Warehouse updated successfully")
 }
 
-func DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) DeleteWarehouse(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM Warehouse WHERE WarehouseID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.warehouses.DeleteWarehouse(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "Warehouse deleted successfully")
 }
 
 // ==================== PRODUCT TYPES ====================
-func CreateProductType(w http.ResponseWriter, r *http.Request) {
-	utils.EnableCORS(&w)
 
-	// Receive data with frontend field names
-	var requestData struct {
-		ProductName   string  `json:"product_name"`
-		Category      string  `json:"category"`
-		UnitPrice     float64 `json:"unit_price"`
-		UnitOfMeasure string  `json:"unit_of_measure"`
-	}
+// productTypeRequest carries a product type with frontend field names
+type productTypeRequest struct {
+	ProductName   string  `json:"product_name" validate:"required"`
+	Category      string  `json:"category"`
+	UnitPrice     float64 `json:"unit_price" validate:"min=0"`
+	UnitOfMeasure string  `json:"unit_of_measure"`
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
-		return
+func (req productTypeRequest) model() models.ProductType {
+	return models.ProductType{
+		Name:          req.ProductName,
+		Price:         req.UnitPrice,
+		Grade:         req.Category,
+		UnitOfMeasure: req.UnitOfMeasure,
 	}
+}
 
-	// Store price in Description field as string
-	priceStr := fmt.Sprintf("%.2f", requestData.UnitPrice)
+func (h *WarehouseHandler) CreateProductType(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
 
-	query := `INSERT INTO ProductType (Name, Description, Grade, UnitOfMeasure)
-              VALUES ($1, $2, $3, $4) RETURNING ProductTypeID`
+	// Receive data with frontend field names
+	var requestData productTypeRequest
+	if !decodeBody(w, r, &requestData) {
+		return
+	}
 
-	var productTypeID int
-	err := config.DB.QueryRow(query, requestData.ProductName, priceStr,
-		requestData.Category, requestData.UnitOfMeasure).Scan(&productTypeID)
+	pt := requestData.model()
+	err := h.warehouses.CreateProductType(r.Context(), &pt)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 
 	// Return with frontend field names
 	response := map[string]interface{}{
-		"product_type_id": productTypeID,
+		"product_type_id": pt.ProductTypeID,
 		"product_name":    requestData.ProductName,
 		"category":        requestData.Category,
 		"unit_price":      requestData.UnitPrice,
@@ -112,442 +186,446 @@ func CreateProductType(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusCreated, response)
 }
 
-func GetProductTypes(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetProductTypes(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT ProductTypeID, Name, Description, Grade, UnitOfMeasure 
-                           FROM ProductType ORDER BY Name`)
+	renameFields(r, productTypeFields)
+	spec, ok := listSpec(w, r, repository.ProductTypeResource)
+	if !ok {
+		return
+	}
+	types, err := h.warehouses.ListProductTypes(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
 
 	var productTypes []map[string]interface{}
-	for rows.Next() {
-		var id int
-		var name, description, grade, unitOfMeasure string
-
-		if err := rows.Scan(&id, &name, &description, &grade, &unitOfMeasure); err != nil {
-			continue
-		}
-
-		// Convert Description (stored price) to float
-		var unitPrice float64
-		if description != "" {
-			fmt.Sscanf(description, "%f", &unitPrice)
-		}
-
+	for _, t := range types.Data {
 		// Map database fields to frontend field names
 		pt := map[string]interface{}{
-			"product_type_id": id,
-			"product_name":    name,
-			"category":        grade,
-			"unit_price":      unitPrice,
-			"unit_of_measure": unitOfMeasure,
+			"product_type_id": t.ProductTypeID,
+			"product_name":    t.Name,
+			"category":        t.Grade,
+			"unit_price":      t.Price,
+			"unit_of_measure": t.UnitOfMeasure,
 		}
 		productTypes = append(productTypes, pt)
 	}
 
-	utils.RespondJSON(w, http.StatusOK, productTypes)
+	respondPage(w, r, query.Page[map[string]interface{}]{Data: productTypes, NextCursor: types.NextCursor, Total: types.Total})
 }
 
-func UpdateProductType(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetProductType(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
+	getMember(w, r, repository.ProductTypeResource, h.warehouses.ListProductTypes)
+}
 
-	// Receive data with frontend field names
-	var requestData struct {
-		ProductName   string  `json:"product_name"`
-		Category      string  `json:"category"`
-		UnitPrice     float64 `json:"unit_price"`
-		UnitOfMeasure string  `json:"unit_of_measure"`
+func (h *WarehouseHandler) UpdateProductType(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	// Receive data with frontend field names
+	var requestData productTypeRequest
+	if !decodeBody(w, r, &requestData) {
 		return
 	}
 
-	// Store price in Description field as string
-	priceStr := fmt.Sprintf("%.2f", requestData.UnitPrice)
-
-	query := `UPDATE ProductType SET Name = $2, Description = $3, Grade = $4, UnitOfMeasure = $5 
-              WHERE ProductTypeID = $1`
-	_, err := config.DB.Exec(query, id, requestData.ProductName, priceStr,
-		requestData.Category, requestData.UnitOfMeasure)
+	pt := requestData.model()
+	err := h.warehouses.UpdateProductType(r.Context(), id, &pt)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ProductType updated successfully")
 }
 
-func DeleteProductType(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) DeleteProductType(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM ProductType WHERE ProductTypeID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.warehouses.DeleteProductType(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "ProductType deleted successfully")
 }
 
 // ==================== STOCK ITEMS ====================
-func CreateStockItem(w http.ResponseWriter, r *http.Request) {
+
+// stockItemRequest carries a stock item with frontend field names. A stock
+// item is one lot, named by the harvest batch, processing order or purchase
+// order item it came from.
+type stockItemRequest struct {
+	WarehouseID     int     `json:"warehouse_id" validate:"required"`
+	ProductTypeID   int     `json:"product_type_id" validate:"required"`
+	BatchID         *int    `json:"batch_id"`
+	ProcessingID    *int    `json:"processing_id"`
+	POItemID        *int    `json:"po_item_id"`
+	QuantityInStock float64 `json:"quantity_in_stock" validate:"min=0"`
+	ShelfLocation   string  `json:"shelf_location"`
+	LastRestocked   string  `json:"last_restocked" validate:"date"`
+}
+
+func (req stockItemRequest) model() models.StockItem {
+	return models.StockItem{
+		ProductTypeID: req.ProductTypeID,
+		WarehouseID:   req.WarehouseID,
+		BatchID:       req.BatchID,
+		ProcessingID:  req.ProcessingID,
+		POItemID:      req.POItemID,
+		Quantity:      req.QuantityInStock,
+		ShelfLocation: req.ShelfLocation,
+	}
+}
+
+func (h *WarehouseHandler) CreateStockItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	
+
 	// Receive data with frontend field names
-	var requestData struct {
-		WarehouseID      int     `json:"warehouse_id"`
-		ProductTypeID    int     `json:"product_type_id"`
-		QuantityInStock  float64 `json:"quantity_in_stock"`
-		ShelfLocation    string  `json:"shelf_location"`
-		LastRestocked    string  `json:"last_restocked"`
-	}
-	
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	var requestData stockItemRequest
+	if !decodeBody(w, r, &requestData) {
 		return
 	}
 
-	query := `INSERT INTO StockItem (ProductTypeID, WarehouseID, BatchID, Quantity, ShelfLocation)
-              VALUES ($1, $2, NULL, $3, $4) RETURNING StockID`
-	
-	var stockID int
-	err := config.DB.QueryRow(query, requestData.ProductTypeID, requestData.WarehouseID,
-		requestData.QuantityInStock, requestData.ShelfLocation).Scan(&stockID)
+	si := requestData.model()
+	err := h.stock.CreateStockItem(r.Context(), &si)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	
+
 	response := map[string]interface{}{
-		"stock_id":          stockID,
+		"stock_id":          si.StockID,
 		"warehouse_id":      requestData.WarehouseID,
 		"product_type_id":   requestData.ProductTypeID,
+		"batch_id":          requestData.BatchID,
+		"processing_id":     requestData.ProcessingID,
+		"po_item_id":        requestData.POItemID,
+		"status":            si.Status,
 		"quantity_in_stock": requestData.QuantityInStock,
 		"shelf_location":    requestData.ShelfLocation,
 		"last_restocked":    requestData.LastRestocked,
 	}
-	
+
 	utils.RespondJSON(w, http.StatusCreated, response)
 }
 
-func GetStockItems(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetStockItems(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT StockID, ProductTypeID, WarehouseID, BatchID, Quantity, ShelfLocation 
-                           FROM StockItem ORDER BY StockID`)
+	renameFields(r, stockItemFields)
+	spec, ok := listSpec(w, r, repository.StockItemResource)
+	if !ok {
+		return
+	}
+	stock, err := h.stock.ListStockItems(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
 
 	var items []map[string]interface{}
-	for rows.Next() {
-		var stockID, productTypeID, warehouseID int
-		var batchID *int
-		var quantity float64
-		var shelfLocation string
-		
-		if err := rows.Scan(&stockID, &productTypeID, &warehouseID, &batchID, &quantity, &shelfLocation); err != nil {
-			continue
-		}
-		
+	for _, si := range stock.Data {
 		item := map[string]interface{}{
-			"stock_id":          stockID,
-			"warehouse_id":      warehouseID,
-			"product_type_id":   productTypeID,
-			"batch_id":          batchID,
-			"quantity_in_stock": quantity,
-			"shelf_location":    shelfLocation,
+			"stock_id":          si.StockID,
+			"warehouse_id":      si.WarehouseID,
+			"product_type_id":   si.ProductTypeID,
+			"batch_id":          si.BatchID,
+			"processing_id":     si.ProcessingID,
+			"quantity_in_stock": si.Quantity,
+			"shelf_location":    si.ShelfLocation,
+			"grade":             si.Grade,
+			"graded_by":         si.GradedBy,
+			"status":            si.Status,
+			"po_item_id":        si.POItemID,
 			"last_restocked":    nil,
 		}
 		items = append(items, item)
 	}
-	
-	utils.RespondJSON(w, http.StatusOK, items)
+
+	respondPage(w, r, query.Page[map[string]interface{}]{Data: items, NextCursor: stock.NextCursor, Total: stock.Total})
 }
 
-func UpdateStockItem(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetStockItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	
-	var requestData struct {
-		WarehouseID      int     `json:"warehouse_id"`
-		ProductTypeID    int     `json:"product_type_id"`
-		QuantityInStock  float64 `json:"quantity_in_stock"`
-		ShelfLocation    string  `json:"shelf_location"`
-		LastRestocked    string  `json:"last_restocked"`
+	getMember(w, r, repository.StockItemResource, h.stock.ListStockItems)
+}
+
+func (h *WarehouseHandler) UpdateStockItem(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
 	}
-	
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+
+	var requestData stockItemRequest
+	if !decodeBody(w, r, &requestData) {
 		return
 	}
 
-	query := `UPDATE StockItem SET ProductTypeID = $2, WarehouseID = $3, BatchID = NULL,
-              Quantity = $4, ShelfLocation = $5 WHERE StockID = $1`
-	_, err := config.DB.Exec(query, id, requestData.ProductTypeID, requestData.WarehouseID, 
-		requestData.QuantityInStock, requestData.ShelfLocation)
+	si := requestData.model()
+	err := h.stock.UpdateStockItem(r.Context(), id, &si)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "StockItem updated successfully")
 }
 
-func DeleteStockItem(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) DeleteStockItem(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM StockItem WHERE StockID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.stock.DeleteStockItem(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "StockItem deleted successfully")
 }
 
 // ==================== STOCK ALERTS ====================
-func CreateStockAlert(w http.ResponseWriter, r *http.Request) {
-	utils.EnableCORS(&w)
-	
-	// Receive data with frontend field names
-	var requestData struct {
-		StockID       int    `json:"stock_id"`
-		AlertType     string `json:"alert_type"`
-		TriggeredDate string `json:"triggered_date"`
-		Resolved      bool   `json:"resolved"`
-	}
-	
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
 
+// stockAlertRequest carries a stock alert with frontend field names
+type stockAlertRequest struct {
+	StockID       int    `json:"stock_id" validate:"required"`
+	AlertType     string `json:"alert_type" validate:"required"`
+	TriggeredDate string `json:"triggered_date" validate:"date"`
+	Resolved      bool   `json:"resolved"`
+}
+
+func (req stockAlertRequest) model() models.StockAlert {
 	// Map resolved boolean to status string
 	status := "Active"
-	if requestData.Resolved {
+	if req.Resolved {
 		status = "Resolved"
 	}
+	return models.StockAlert{StockID: req.StockID, AlertType: req.AlertType, Status: status}
+}
+
+func (h *WarehouseHandler) CreateStockAlert(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+
+	// Receive data with frontend field names
+	var requestData stockAlertRequest
+	if !decodeBody(w, r, &requestData) {
+		return
+	}
 
-	query := `INSERT INTO StockAlert (StockID, WarehouseID, AlertType, Status)
-              VALUES ($1, NULL, $2, $3) RETURNING AlertID`
-	
-	var alertID int
-	err := config.DB.QueryRow(query, requestData.StockID, requestData.AlertType, status).Scan(&alertID)
+	sa := requestData.model()
+	err := h.stock.CreateStockAlert(r.Context(), &sa)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	
+
 	response := map[string]interface{}{
-		"alert_id":       alertID,
+		"alert_id":       sa.AlertID,
 		"stock_id":       requestData.StockID,
 		"alert_type":     requestData.AlertType,
 		"triggered_date": requestData.TriggeredDate,
 		"resolved":       requestData.Resolved,
 	}
-	
+
 	utils.RespondJSON(w, http.StatusCreated, response)
 }
 
-func GetStockAlerts(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetStockAlerts(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT AlertID, StockID, WarehouseID, AlertType, CreatedAt, Status 
-                           FROM StockAlert ORDER BY CreatedAt DESC`)
+	renameFields(r, stockAlertFields)
+	spec, ok := listSpec(w, r, repository.StockAlertResource)
+	if !ok {
+		return
+	}
+	stockAlerts, err := h.stock.ListStockAlerts(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
 
 	alerts := make([]map[string]interface{}, 0) // Initialize empty array
-	
-	for rows.Next() {
-		var alertID, stockID int
-		var warehouseID *int
-		var alertType, createdAt, status string
-		
-		if err := rows.Scan(&alertID, &stockID, &warehouseID, &alertType, &createdAt, &status); err != nil {
-			continue
-		}
-		
+
+	for _, sa := range stockAlerts.Data {
 		// Map status string to resolved boolean
-		resolved := status == "Resolved"
-		
+		resolved := sa.Status == "Resolved"
+
 		alert := map[string]interface{}{
-			"alert_id":       alertID,
-			"stock_id":       stockID,
-			"warehouse_id":   warehouseID,
-			"alert_type":     alertType,
-			"triggered_date": createdAt,
+			"alert_id":       sa.AlertID,
+			"stock_id":       sa.StockID,
+			"warehouse_id":   sa.WarehouseID,
+			"alert_type":     sa.AlertType,
+			"triggered_date": sa.CreatedAt,
 			"resolved":       resolved,
-			"status":         status,
+			"status":         sa.Status,
 		}
 		alerts = append(alerts, alert)
 	}
-	
-	utils.RespondJSON(w, http.StatusOK, alerts)
+
+	respondPage(w, r, query.Page[map[string]interface{}]{Data: alerts, NextCursor: stockAlerts.NextCursor, Total: stockAlerts.Total})
 }
 
-func UpdateStockAlert(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetStockAlert(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	
-	var requestData struct {
-		StockID       int    `json:"stock_id"`
-		AlertType     string `json:"alert_type"`
-		TriggeredDate string `json:"triggered_date"`
-		Resolved      bool   `json:"resolved"`
-	}
-	
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	getMember(w, r, repository.StockAlertResource, h.stock.ListStockAlerts)
+}
+
+func (h *WarehouseHandler) UpdateStockAlert(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
 		return
 	}
 
-	// Map resolved boolean to status string
-	status := "Active"
-	if requestData.Resolved {
-		status = "Resolved"
+	var requestData stockAlertRequest
+	if !decodeBody(w, r, &requestData) {
+		return
 	}
 
-	query := `UPDATE StockAlert SET StockID = $2, WarehouseID = NULL, AlertType = $3, Status = $4 WHERE AlertID = $1`
-	_, err := config.DB.Exec(query, id, requestData.StockID, requestData.AlertType, status)
+	sa := requestData.model()
+	err := h.stock.UpdateStockAlert(r.Context(), id, &sa)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "StockAlert updated successfully")
 }
 
-func DeleteStockAlert(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) DeleteStockAlert(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM StockAlert WHERE AlertID = $1`, id)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	err := h.stock.DeleteStockAlert(r.Context(), id)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
 	utils.RespondSuccess(w, "StockAlert deleted successfully")
 }
+
 // ==================== INVENTORY TRANSACTIONS ====================
-func CreateInventoryTransaction(w http.ResponseWriter, r *http.Request) {
+
+// inventoryTransactionRequest carries a transaction with frontend field
+// names. Quantity is positive except for adjustments, whose sign says
+// whether stock was found or lost. TransactionDate defaults to when the
+// entry is posted.
+type inventoryTransactionRequest struct {
+	StockID         int     `json:"stock_id" validate:"required"`
+	EmployeeID      *int    `json:"employee_id"`
+	TransactionType string  `json:"transaction_type" validate:"required,oneof=receipt|issue|adjustment|transfer_out|transfer_in|scrap"`
+	Quantity        float64 `json:"quantity" validate:"required"`
+	TransactionDate string  `json:"transaction_date" validate:"date"`
+	ReferenceID     string  `json:"reference_id"`
+}
+
+func (req inventoryTransactionRequest) model() models.InventoryTransaction {
+	transactionType := validate.Normalize(req.TransactionType)
+	return models.InventoryTransaction{
+		EmployeeID:      req.EmployeeID,
+		StockID:         req.StockID,
+		TransactionType: transactionType,
+		Quantity:        repository.StockMovement(transactionType, req.Quantity),
+		TransactionDate: req.TransactionDate,
+		Remarks:         req.ReferenceID,
+	}
+}
+
+// inventoryTransactionResponse renders a ledger entry with frontend field names
+func inventoryTransactionResponse(it models.InventoryTransaction) map[string]interface{} {
+	return map[string]interface{}{
+		"transaction_id":   it.TransactionID,
+		"employee_id":      it.EmployeeID,
+		"stock_id":         it.StockID,
+		"warehouse_id":     it.WarehouseID,
+		"transaction_type": it.TransactionType,
+		"quantity":         it.Quantity,
+		"balance_after":    it.BalanceAfter,
+		"reverses_id":      it.ReversesID,
+		"transaction_date": it.TransactionDate,
+		"reference_id":     it.Remarks,
+	}
+}
+
+func (h *WarehouseHandler) CreateInventoryTransaction(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	
+
 	// Receive data with frontend field names
-	var requestData struct {
-		StockID         int     `json:"stock_id"`
-		TransactionType string  `json:"transaction_type"`
-		Quantity        float64 `json:"quantity"`
-		TransactionDate string  `json:"transaction_date"`
-		ReferenceID     string  `json:"reference_id"`
+	var requestData inventoryTransactionRequest
+	if !decodeBody(w, r, &requestData) {
+		return
 	}
-	
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	if requestData.Quantity < 0 && validate.Normalize(requestData.TransactionType) != repository.TxAdjustment {
+		respondInvalid(w, validate.Errors{{
+			Field: "quantity", Code: validate.CodeTooSmall,
+			Message: "quantity must be positive; only adjustments may be negative",
+		}})
 		return
 	}
 
-	query := `INSERT INTO InventoryTransaction (EmployeeID, StockID, WarehouseID, TransactionType, Quantity, Remarks)
-              VALUES (NULL, $1, NULL, $2, $3, $4) RETURNING TransactionID`
-	
-	var transactionID int
-	err := config.DB.QueryRow(query, requestData.StockID, requestData.TransactionType,
-		requestData.Quantity, requestData.ReferenceID).Scan(&transactionID)
+	it := requestData.model()
+	err := h.tx.WithinTx(r.Context(), func(ctx context.Context) error {
+		if err := h.holds.CheckIssue(ctx, it); err != nil {
+			return err
+		}
+		return h.stock.CreateInventoryTransaction(ctx, &it)
+	})
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	
-	response := map[string]interface{}{
-		"transaction_id":   transactionID,
-		"stock_id":         requestData.StockID,
-		"transaction_type": requestData.TransactionType,
-		"quantity":         requestData.Quantity,
-		"transaction_date": requestData.TransactionDate,
-		"reference_id":     requestData.ReferenceID,
-	}
-	
-	utils.RespondJSON(w, http.StatusCreated, response)
+	utils.RespondJSON(w, http.StatusCreated, inventoryTransactionResponse(it))
 }
 
-func GetInventoryTransactions(w http.ResponseWriter, r *http.Request) {
+func (h *WarehouseHandler) GetInventoryTransactions(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	rows, err := config.DB.Query(`SELECT TransactionID, EmployeeID, StockID, WarehouseID, TransactionType, 
-                           Quantity, TransactionDate, Remarks FROM InventoryTransaction ORDER BY TransactionDate DESC`)
+	renameFields(r, inventoryTransactionFields)
+	spec, ok := listSpec(w, r, repository.InventoryTransactionResource)
+	if !ok {
+		return
+	}
+	inventoryTransactions, err := h.stock.ListInventoryTransactions(r.Context(), spec)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	defer rows.Close()
 
 	transactions := make([]map[string]interface{}, 0) // Initialize empty array
-	
-	for rows.Next() {
-		var transactionID, stockID int
-		var employeeID, warehouseID *int
-		var transactionType, transactionDate, remarks string
-		var quantity float64
-		
-		if err := rows.Scan(&transactionID, &employeeID, &stockID, &warehouseID,
-			&transactionType, &quantity, &transactionDate, &remarks); err != nil {
-			continue
-		}
-		
-		transaction := map[string]interface{}{
-			"transaction_id":   transactionID,
-			"employee_id":      employeeID,
-			"stock_id":         stockID,
-			"warehouse_id":     warehouseID,
-			"transaction_type": transactionType,
-			"quantity":         quantity,
-			"transaction_date": transactionDate,
-			"reference_id":     remarks,
-		}
-		transactions = append(transactions, transaction)
+	for _, it := range inventoryTransactions.Data {
+		transactions = append(transactions, inventoryTransactionResponse(it))
 	}
-	
-	utils.RespondJSON(w, http.StatusOK, transactions)
+
+	respondPage(w, r, query.Page[map[string]interface{}]{Data: transactions, NextCursor: inventoryTransactions.NextCursor, Total: inventoryTransactions.Total})
 }
 
-func UpdateInventoryTransaction(w http.ResponseWriter, r *http.Request) {
+// ReverseInventoryTransaction posts the entry undoing a transaction, which
+// is how ledger mistakes are corrected
+func (h *WarehouseHandler) ReverseInventoryTransaction(w http.ResponseWriter, r *http.Request) {
 	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
 	var requestData struct {
-		StockID         int     `json:"stock_id"`
-		TransactionType string  `json:"transaction_type"`
-		Quantity        float64 `json:"quantity"`
-		TransactionDate string  `json:"transaction_date"`
-		ReferenceID     string  `json:"reference_id"`
+		EmployeeID  *int   `json:"employee_id"`
+		ReferenceID string `json:"reference_id"`
 	}
-	
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+	if r.ContentLength != 0 && !decodeBody(w, r, &requestData) {
 		return
 	}
 
-	query := `UPDATE InventoryTransaction SET EmployeeID = NULL, StockID = $2, WarehouseID = NULL,
-              TransactionType = $3, Quantity = $4, Remarks = $5 WHERE TransactionID = $1`
-	_, err := config.DB.Exec(query, id, requestData.StockID, requestData.TransactionType,
-		requestData.Quantity, requestData.ReferenceID)
+	it := models.InventoryTransaction{EmployeeID: requestData.EmployeeID, Remarks: requestData.ReferenceID}
+	err := h.stock.ReverseInventoryTransaction(r.Context(), id, &it)
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		apierr.Respond(w, err)
 		return
 	}
-	utils.RespondSuccess(w, "InventoryTransaction updated successfully")
+	utils.RespondJSON(w, http.StatusCreated, inventoryTransactionResponse(it))
 }
-
-func DeleteInventoryTransaction(w http.ResponseWriter, r *http.Request) {
-	utils.EnableCORS(&w)
-	id := r.URL.Query().Get("id")
-	_, err := config.DB.Exec(`DELETE FROM InventoryTransaction WHERE TransactionID = $1`, id)
-	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	utils.RespondSuccess(w, "InventoryTransaction deleted successfully")
-}
\ No newline at end of file
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/utils"
+	"lumber-erp-api/waste"
+)
+
+// WasteHandler turns waste records into by-product stock and reports on
+// the waste of processing orders
+type WasteHandler struct {
+	service *waste.Service
+}
+
+func NewWasteHandler(repos repository.Repositories) *WasteHandler {
+	return &WasteHandler{service: waste.NewService(repos)}
+}
+
+// ValoriseWasteRecord receives the by-product a waste record made into stock
+func (h *WasteHandler) ValoriseWasteRecord(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	id, ok := intParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var bp waste.ByProduct
+	if !decodeBody(w, r, &bp) {
+		return
+	}
+	v, err := h.service.Valorise(r.Context(), id, bp)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusCreated, v)
+}
+
+// GetWasteAnalytics reports waste grouped by ?group_by (month by default)
+// for the orders dated from ?from up to ?to; either end may be left open
+func (h *WasteHandler) GetWasteAnalytics(w http.ResponseWriter, r *http.Request) {
+	utils.EnableCORS(&w)
+	params := r.URL.Query()
+	groupBy := params.Get("group_by")
+	if groupBy == "" {
+		groupBy = waste.ByMonth
+	}
+	known := false
+	for _, g := range waste.Groupings {
+		known = known || g == groupBy
+	}
+	if !known {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid group_by")
+		return
+	}
+	bounds := []time.Time{{}, {}}
+	for i, name := range []string{"from", "to"} {
+		if raw := params.Get(name); raw != "" {
+			t, err := scheduling.ParseTime(raw)
+			if err != nil {
+				utils.RespondError(w, http.StatusBadRequest, "Invalid "+name)
+				return
+			}
+			bounds[i] = t
+		}
+	}
+	from, to := bounds[0], bounds[1]
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		utils.RespondError(w, http.StatusBadRequest, "Invalid from")
+		return
+	}
+	report, err := h.service.Analyse(r.Context(), groupBy, from, to)
+	if err != nil {
+		apierr.Respond(w, err)
+		return
+	}
+	utils.RespondJSON(w, http.StatusOK, report)
+}
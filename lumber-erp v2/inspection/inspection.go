@@ -0,0 +1,610 @@
+// Package inspection grades quality inspections against templates. A
+// template lists what to check of a product type at a stage of production
+// — knots, wane, splits, warp, moisture and dimensions with acceptance
+// bounds — and the grades a piece earns by what was found. An inspection
+// made against one records a measurement per checklist item and the
+// catalogued defects found; its result and grade are derived from them and
+// the grade is written onto the stock lots inspected.
+package inspection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/sampling"
+	"lumber-erp-api/validate"
+)
+
+// Stages of production a template applies to
+const (
+	StageLogIntake   = "log_intake"
+	StageGreenLumber = "green_lumber"
+	StageKilnDried   = "kiln_dried"
+	StageFinished    = "finished"
+)
+
+// Kinds of checklist item
+const (
+	KindKnots     = "knots"
+	KindWane      = "wane"
+	KindSplit     = "split"
+	KindWarp      = "warp"
+	KindMoisture  = "moisture"
+	KindDimension = "dimension"
+)
+
+// Defect severities, from the most tolerated
+const (
+	SeverityMinor    = "minor"
+	SeverityMajor    = "major"
+	SeverityCritical = "critical"
+)
+
+// Inspection results
+const (
+	ResultPass = repository.InspectionPass
+	ResultFail = repository.InspectionFail
+)
+
+var kinds = map[string]bool{KindKnots: true, KindWane: true, KindSplit: true, KindWarp: true,
+	KindMoisture: true, KindDimension: true}
+
+// Service grades inspections against their templates and keeps the
+// templates and the defect catalog they refer to
+type Service struct {
+	tx         repository.Transactor
+	quality    repository.QualityRepository
+	plant      repository.ProcessingRepository
+	orders     repository.PurchaseOrderRepository
+	stock      repository.StockRepository
+	warehouses repository.WarehouseRepository
+	holds      *quarantine.Service
+	lots       *sampling.Service
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, quality: repos.Quality, plant: repos.Processing, orders: repos.PurchaseOrders,
+		stock: repos.Stock, warehouses: repos.Warehouses, holds: quarantine.NewService(repos),
+		lots: sampling.NewService(repos)}
+}
+
+func whereIn(field string, values []string) query.Spec {
+	var spec query.Spec
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	spec.WhereIn(field, list...)
+	return spec
+}
+
+// ==================== DEFECT CODES ====================
+
+// normalizeDefectCode upper-cases the code and fills in the default
+// category and severity
+func normalizeDefectCode(dc *models.DefectCode) {
+	dc.Code = strings.ToUpper(strings.TrimSpace(dc.Code))
+	dc.Category = validate.Normalize(dc.Category)
+	if dc.Category == "" {
+		dc.Category = "other"
+	}
+	dc.Severity = validate.Normalize(dc.Severity)
+	if dc.Severity == "" {
+		dc.Severity = SeverityMinor
+	}
+}
+
+// CreateDefectCode adds a code to the defect catalog
+func (s *Service) CreateDefectCode(ctx context.Context, dc *models.DefectCode) error {
+	normalizeDefectCode(dc)
+	return s.quality.CreateDefectCode(ctx, dc)
+}
+
+// UpdateDefectCode changes a catalogued code; inspections that recorded it
+// follow a renamed code
+func (s *Service) UpdateDefectCode(ctx context.Context, id int, dc *models.DefectCode) error {
+	normalizeDefectCode(dc)
+	return s.quality.UpdateDefectCode(ctx, id, dc)
+}
+
+// ==================== TEMPLATES ====================
+
+// checkTemplate normalises the stage and kinds of t, numbers its items and
+// grades when they leave positions and ranks out, and reports what is
+// invalid
+func checkTemplate(t *models.InspectionTemplate) validate.Errors {
+	var errs validate.Errors
+	t.Stage = validate.Normalize(t.Stage)
+
+	positions := map[int]bool{}
+	for i := range t.Items {
+		item := &t.Items[i]
+		field := fmt.Sprintf("items[%d]", i)
+		if item.Position == 0 {
+			item.Position = i + 1
+		}
+		if item.Position < 0 {
+			errs = append(errs, validate.FieldError{Field: field + ".position", Code: validate.CodeTooSmall,
+				Message: field + ".position must be positive"})
+		} else if positions[item.Position] {
+			errs = append(errs, validate.FieldError{Field: field + ".position", Code: "duplicate",
+				Message: fmt.Sprintf("%s.position %d is used by another item", field, item.Position)})
+		}
+		positions[item.Position] = true
+		item.Kind = validate.Normalize(item.Kind)
+		if !kinds[item.Kind] {
+			errs = append(errs, validate.FieldError{Field: field + ".kind", Code: validate.CodeInvalidChoice,
+				Message: field + ".kind must be one of knots, wane, split, warp, moisture, dimension"})
+		}
+		if item.Min != nil && item.Max != nil && *item.Min > *item.Max {
+			errs = append(errs, validate.FieldError{Field: field + ".max", Code: validate.CodeTooSmall,
+				Message: field + ".max must not be below min"})
+		}
+	}
+	sort.SliceStable(t.Items, func(i, j int) bool { return t.Items[i].Position < t.Items[j].Position })
+
+	ranks := map[int]bool{}
+	grades := map[string]bool{}
+	for i := range t.Grades {
+		g := &t.Grades[i]
+		field := fmt.Sprintf("grades[%d]", i)
+		if g.Rank == 0 {
+			g.Rank = i + 1
+		}
+		if g.Rank < 0 {
+			errs = append(errs, validate.FieldError{Field: field + ".rank", Code: validate.CodeTooSmall,
+				Message: field + ".rank must be positive"})
+		} else if ranks[g.Rank] {
+			errs = append(errs, validate.FieldError{Field: field + ".rank", Code: "duplicate",
+				Message: fmt.Sprintf("%s.rank %d is used by another grade", field, g.Rank)})
+		}
+		ranks[g.Rank] = true
+		g.Grade = strings.TrimSpace(g.Grade)
+		if g.Grade == "" {
+			errs = append(errs, validate.FieldError{Field: field + ".grade", Code: validate.CodeRequired,
+				Message: field + ".grade is required"})
+		} else if grades[strings.ToLower(g.Grade)] {
+			errs = append(errs, validate.FieldError{Field: field + ".grade", Code: "duplicate",
+				Message: fmt.Sprintf("%s.grade %s is used by another grade", field, g.Grade)})
+		}
+		grades[strings.ToLower(g.Grade)] = true
+	}
+	sort.SliceStable(t.Grades, func(i, j int) bool { return t.Grades[i].Rank < t.Grades[j].Rank })
+	return errs
+}
+
+// checkScope makes sure the product type of t exists and that no other
+// active template covers its product type and stage
+func (s *Service) checkScope(ctx context.Context, t *models.InspectionTemplate) error {
+	if t.ProductTypeID != nil {
+		var spec query.Spec
+		spec.Where("product_type_id", *t.ProductTypeID)
+		types, err := s.warehouses.ListProductTypes(ctx, spec)
+		if err != nil {
+			return err
+		}
+		if len(types.Data) == 0 {
+			return &repository.ReferenceError{Field: "product_type_id", Table: "producttype"}
+		}
+	}
+	if !t.Active {
+		return nil
+	}
+	active, err := s.activeTemplates(ctx, t.Stage)
+	if err != nil {
+		return err
+	}
+	for _, other := range active {
+		if other.TemplateID != t.TemplateID && sameProductType(other.ProductTypeID, t.ProductTypeID) {
+			return &repository.ConflictError{Field: "product_type_id, stage"}
+		}
+	}
+	return nil
+}
+
+func sameProductType(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func (s *Service) activeTemplates(ctx context.Context, stage string) ([]models.InspectionTemplate, error) {
+	var spec query.Spec
+	spec.Where("stage", stage)
+	spec.Where("active", true)
+	page, err := s.quality.ListInspectionTemplates(ctx, spec)
+	return page.Data, err
+}
+
+// CreateTemplate adds an active template with its items and grades
+func (s *Service) CreateTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	if errs := checkTemplate(t); errs != nil {
+		return errs
+	}
+	t.Active = true
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkScope(ctx, t); err != nil {
+			return err
+		}
+		return s.quality.CreateInspectionTemplate(ctx, t)
+	})
+}
+
+// UpdateTemplate replaces a template with its items and grades. Past
+// inspections keep the grades they were given.
+func (s *Service) UpdateTemplate(ctx context.Context, id int, t *models.InspectionTemplate) error {
+	if errs := checkTemplate(t); errs != nil {
+		return errs
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.quality.GetInspectionTemplate(ctx, id); err != nil {
+			return err
+		}
+		t.TemplateID = id
+		if err := s.checkScope(ctx, t); err != nil {
+			return err
+		}
+		return s.quality.UpdateInspectionTemplate(ctx, id, t)
+	})
+}
+
+// ==================== INSPECTIONS ====================
+
+// productType finds the product type an inspection looks at: the output
+// of its processing order or the product of its purchase order item. Log
+// intake inspections of a harvest batch have none.
+func (s *Service) productType(ctx context.Context, qi *models.QualityInspection) (*int, error) {
+	if qi.ProcessingID != nil {
+		order, err := s.plant.GetProcessingOrder(ctx, *qi.ProcessingID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, &repository.ReferenceError{Field: "processing_id", Table: "processingorder"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &order.ProductTypeID, nil
+	}
+	if qi.POItemID != nil {
+		var spec query.Spec
+		spec.Where("po_item_id", *qi.POItemID)
+		items, err := s.orders.ListPurchaseOrderItems(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		if len(items.Data) == 0 {
+			return nil, &repository.ReferenceError{Field: "po_item_id", Table: "purchaseorderitem"}
+		}
+		return &items.Data[0].ProductTypeID, nil
+	}
+	return nil, nil
+}
+
+// template finds the template an inspection is made against: the one it
+// names, or else the active template of its stage for the product type
+// inspected, falling back to the stage's template for every product type.
+// ok is false when there is none.
+func (s *Service) template(ctx context.Context, qi *models.QualityInspection) (t models.InspectionTemplate, ok bool, err error) {
+	if qi.TemplateID != nil {
+		t, err = s.quality.GetInspectionTemplate(ctx, *qi.TemplateID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return t, false, &repository.ReferenceError{Field: "template_id", Table: "inspectiontemplate"}
+		}
+		return t, err == nil, err
+	}
+	if qi.Stage == "" {
+		return t, false, nil
+	}
+	productTypeID, err := s.productType(ctx, qi)
+	if err != nil {
+		return t, false, err
+	}
+	active, err := s.activeTemplates(ctx, qi.Stage)
+	if err != nil {
+		return t, false, err
+	}
+	var generic *models.InspectionTemplate
+	for i, candidate := range active {
+		if candidate.ProductTypeID == nil {
+			generic = &active[i]
+		} else if productTypeID != nil && *candidate.ProductTypeID == *productTypeID {
+			return s.fullTemplate(ctx, candidate.TemplateID)
+		}
+	}
+	if generic == nil {
+		return t, false, nil
+	}
+	return s.fullTemplate(ctx, generic.TemplateID)
+}
+
+func (s *Service) fullTemplate(ctx context.Context, id int) (models.InspectionTemplate, bool, error) {
+	t, err := s.quality.GetInspectionTemplate(ctx, id)
+	return t, err == nil, err
+}
+
+// defects merges the defects found by code and looks up their severity in
+// the catalog, reporting codes it does not hold
+func (s *Service) defects(ctx context.Context, found []models.InspectionDefect) ([]models.InspectionDefect, map[string]string, error) {
+	var merged []models.InspectionDefect
+	index := map[string]int{}
+	var errs validate.Errors
+	for i, d := range found {
+		field := fmt.Sprintf("defects[%d]", i)
+		d.Code = strings.ToUpper(strings.TrimSpace(d.Code))
+		if d.Code == "" {
+			errs = append(errs, validate.FieldError{Field: field + ".code", Code: validate.CodeRequired,
+				Message: field + ".code is required"})
+			continue
+		}
+		if d.Count == 0 {
+			d.Count = 1
+		}
+		if d.Count < 0 {
+			errs = append(errs, validate.FieldError{Field: field + ".count", Code: validate.CodeTooSmall,
+				Message: field + ".count must be positive"})
+			continue
+		}
+		if j, ok := index[d.Code]; ok {
+			merged[j].Count += d.Count
+			continue
+		}
+		index[d.Code] = len(merged)
+		merged = append(merged, d)
+	}
+	if errs != nil {
+		return nil, nil, errs
+	}
+	if len(merged) == 0 {
+		return nil, nil, nil
+	}
+
+	codes := make([]string, len(merged))
+	for i, d := range merged {
+		codes[i] = d.Code
+	}
+	catalog, err := s.quality.ListDefectCodes(ctx, whereIn("code", codes))
+	if err != nil {
+		return nil, nil, err
+	}
+	severity := map[string]string{}
+	for _, dc := range catalog.Data {
+		severity[dc.Code] = dc.Severity
+	}
+	for i, d := range found {
+		code := strings.ToUpper(strings.TrimSpace(d.Code))
+		if _, ok := severity[code]; !ok {
+			field := fmt.Sprintf("defects[%d].code", i)
+			errs = append(errs, validate.FieldError{Field: field, Code: "not_found",
+				Message: fmt.Sprintf("%s %s is not in the defect catalog", field, code)})
+		}
+	}
+	if errs != nil {
+		return nil, nil, errs
+	}
+	return merged, severity, nil
+}
+
+// measure checks each measurement against its checklist item, reporting
+// unknown items and required items left unmeasured
+func measure(t models.InspectionTemplate, measured []models.InspectionMeasurement) ([]models.InspectionMeasurement, validate.Errors) {
+	items := map[int]models.InspectionTemplateItem{}
+	for _, item := range t.Items {
+		items[item.Position] = item
+	}
+	var errs validate.Errors
+	seen := map[int]bool{}
+	var out []models.InspectionMeasurement
+	for i, m := range measured {
+		field := fmt.Sprintf("measurements[%d].position", i)
+		item, ok := items[m.Position]
+		switch {
+		case !ok:
+			errs = append(errs, validate.FieldError{Field: field, Code: "not_found",
+				Message: fmt.Sprintf("%s %d is not an item of template %d", field, m.Position, t.TemplateID)})
+			continue
+		case seen[m.Position]:
+			errs = append(errs, validate.FieldError{Field: field, Code: "duplicate",
+				Message: fmt.Sprintf("%s %d is measured twice", field, m.Position)})
+			continue
+		}
+		seen[m.Position] = true
+		m.Kind = item.Kind
+		m.Passed = (item.Min == nil || m.Value >= *item.Min) && (item.Max == nil || m.Value <= *item.Max)
+		out = append(out, m)
+	}
+	for _, item := range t.Items {
+		if item.Required && !seen[item.Position] {
+			label := item.Label
+			if label == "" {
+				label = item.Kind
+			}
+			errs = append(errs, validate.FieldError{Field: "measurements", Code: validate.CodeRequired,
+				Message: fmt.Sprintf("measurements must include item %d (%s)", item.Position, label)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Position < out[j].Position })
+	return out, errs
+}
+
+// Findings totals what an inspection found for grading: knots summed over
+// the knot items, the worst wane, split and warp measured, and the number
+// of defects of each severity
+type Findings struct {
+	Knots, Wane, Split, Warp float64
+	Defects                  map[string]int
+}
+
+func findings(measured []models.InspectionMeasurement, defects []models.InspectionDefect, severity map[string]string) Findings {
+	f := Findings{Defects: map[string]int{}}
+	for _, m := range measured {
+		switch m.Kind {
+		case KindKnots:
+			f.Knots += m.Value
+		case KindWane:
+			f.Wane = max(f.Wane, m.Value)
+		case KindSplit:
+			f.Split = max(f.Split, m.Value)
+		case KindWarp:
+			f.Warp = max(f.Warp, m.Value)
+		}
+	}
+	for _, d := range defects {
+		f.Defects[severity[d.Code]] += d.Count
+	}
+	return f
+}
+
+// Grade returns the best ranked grade whose limits the findings stay
+// within, or "" when none does
+func Grade(grades []models.InspectionGrade, f Findings) string {
+	within := func(v float64, limit *float64) bool { return limit == nil || v <= *limit }
+	count := func(n int, limit *int) bool { return limit == nil || n <= *limit }
+	sorted := append([]models.InspectionGrade(nil), grades...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Rank < sorted[j].Rank })
+	for _, g := range sorted {
+		if within(f.Knots, g.MaxKnots) && within(f.Wane, g.MaxWane) && within(f.Split, g.MaxSplit) &&
+			within(f.Warp, g.MaxWarp) && count(f.Defects[SeverityMinor], g.MaxMinorDefects) &&
+			count(f.Defects[SeverityMajor], g.MaxMajorDefects) &&
+			count(f.Defects[SeverityCritical], g.MaxCriticalDefects) {
+			return g.Grade
+		}
+	}
+	return ""
+}
+
+// evaluate checks what an inspection recorded and, when it is made against
+// a template, derives its result and grade. Inspections without one keep
+// the result they were given.
+func (s *Service) evaluate(ctx context.Context, qi *models.QualityInspection) error {
+	qi.Stage = validate.Normalize(qi.Stage)
+	t, ok, err := s.template(ctx, qi)
+	if err != nil {
+		return err
+	}
+	defects, severity, err := s.defects(ctx, qi.Defects)
+	if err != nil {
+		return err
+	}
+	qi.Defects = defects
+	if !ok {
+		if len(qi.Measurements) > 0 {
+			return validate.Errors{{Field: "template_id", Code: validate.CodeRequired,
+				Message: "template_id or the stage of a template is required to record measurements"}}
+		}
+		qi.Grade = ""
+		return nil
+	}
+
+	measured, errs := measure(t, qi.Measurements)
+	if errs != nil {
+		return errs
+	}
+	qi.TemplateID, qi.Stage, qi.Measurements = &t.TemplateID, t.Stage, measured
+
+	qi.Result = ResultPass
+	for _, m := range measured {
+		if !m.Passed {
+			qi.Result = ResultFail
+		}
+		if m.Kind == KindMoisture && qi.MoistureLevel == 0 {
+			qi.MoistureLevel = m.Value
+		}
+	}
+	// Pieces failing the checklist are not graded
+	qi.Grade = ""
+	if qi.Result == ResultPass {
+		qi.Grade = Grade(t.Grades, findings(measured, defects, severity))
+		if qi.Grade == "" && len(t.Grades) > 0 {
+			qi.Result = ResultFail
+		}
+	}
+	return nil
+}
+
+// settle writes the grade of an inspection onto its lots and quarantines
+// them when it failed. A sample inspection of a purchase order item under
+// acceptance sampling counts towards the item's inspection lot instead,
+// which holds the lots when it is rejected.
+func (s *Service) settle(ctx context.Context, qi models.QualityInspection) error {
+	if err := s.gradeLots(ctx, qi); err != nil {
+		return err
+	}
+	sampled, err := s.lots.Tally(ctx, qi)
+	if err != nil || sampled {
+		return err
+	}
+	if validate.Normalize(qi.Result) != ResultFail {
+		return nil
+	}
+	_, err = s.holds.Hold(ctx, qi)
+	return err
+}
+
+// gradeLots writes the grade of an inspection onto the lots it looked at:
+// the output of its processing order or, for a harvest batch, the batch's
+// raw lots
+func (s *Service) gradeLots(ctx context.Context, qi models.QualityInspection) error {
+	if qi.Grade == "" {
+		return nil
+	}
+	var spec query.Spec
+	switch {
+	case qi.ProcessingID != nil:
+		spec.Where("processing_id", *qi.ProcessingID)
+	case qi.BatchID != 0 && qi.POItemID == nil:
+		spec.Where("batch_id", qi.BatchID)
+	default:
+		return nil
+	}
+	lots, err := s.stock.ListStockItems(ctx, spec)
+	if err != nil {
+		return err
+	}
+	for _, lot := range lots.Data {
+		if qi.ProcessingID == nil && lot.ProcessingID != nil {
+			continue
+		}
+		if err := s.stock.GradeStockItem(ctx, lot.StockID, qi.Grade, qi.InspectionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create records an inspection, grading it and the lots it looked at when
+// it is made against a template and holding the lots when it fails
+func (s *Service) Create(ctx context.Context, qi *models.QualityInspection) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.evaluate(ctx, qi); err != nil {
+			return err
+		}
+		if err := s.quality.CreateQualityInspection(ctx, qi); err != nil {
+			return err
+		}
+		return s.settle(ctx, *qi)
+	})
+}
+
+// Update replaces what an inspection recorded and grades it again
+func (s *Service) Update(ctx context.Context, id int, qi *models.QualityInspection) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.quality.GetQualityInspection(ctx, id); err != nil {
+			return err
+		}
+		if err := s.evaluate(ctx, qi); err != nil {
+			return err
+		}
+		if err := s.quality.UpdateQualityInspection(ctx, id, qi); err != nil {
+			return err
+		}
+		qi.InspectionID = id
+		return s.settle(ctx, *qi)
+	})
+}
@@ -0,0 +1,80 @@
+package inspection
+
+import (
+	"testing"
+
+	"lumber-erp-api/models"
+)
+
+func TestGrade(t *testing.T) {
+	limit := func(v float64) *float64 { return &v }
+	count := func(n int) *int { return &n }
+	// Listed out of rank order; Grade ranks them itself
+	grades := []models.InspectionGrade{
+		{Rank: 3, Grade: "#2", MaxKnots: limit(12), MaxCriticalDefects: count(0)},
+		{Rank: 1, Grade: "FAS", MaxKnots: limit(2), MaxWane: limit(0), MaxSplit: limit(5),
+			MaxMinorDefects: count(1), MaxMajorDefects: count(0), MaxCriticalDefects: count(0)},
+		{Rank: 2, Grade: "#1", MaxKnots: limit(6), MaxWane: limit(10), MaxWarp: limit(3),
+			MaxMajorDefects: count(1), MaxCriticalDefects: count(0)},
+	}
+	for _, tc := range []struct {
+		name string
+		f    Findings
+		want string
+	}{
+		{"clear", Findings{}, "FAS"},
+		{"at every FAS limit", Findings{Knots: 2, Split: 5, Defects: map[string]int{SeverityMinor: 1}}, "FAS"},
+		{"one knot over", Findings{Knots: 3}, "#1"},
+		{"a major defect", Findings{Defects: map[string]int{SeverityMajor: 1}}, "#1"},
+		{"waney and warped", Findings{Knots: 1, Wane: 1, Warp: 4}, "#2"},
+		{"a critical defect", Findings{Defects: map[string]int{SeverityCritical: 1}}, ""},
+		{"too knotty for any", Findings{Knots: 13}, ""},
+	} {
+		if got := Grade(grades, tc.f); got != tc.want {
+			t.Errorf("%s: grade = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	if grades[0].Grade != "#2" {
+		t.Error("Grade reordered the template's grades")
+	}
+}
+
+func TestFindings(t *testing.T) {
+	measured := []models.InspectionMeasurement{
+		{Kind: KindKnots, Value: 2}, {Kind: KindKnots, Value: 3},
+		{Kind: KindWane, Value: 4}, {Kind: KindWane, Value: 1},
+		{Kind: KindWarp, Value: 2.5}, {Kind: KindMoisture, Value: 19},
+	}
+	defects := []models.InspectionDefect{{Code: "KN", Count: 2}, {Code: "SH", Count: 1}, {Code: "RT", Count: 1}}
+	severity := map[string]string{"KN": SeverityMinor, "SH": SeverityMinor, "RT": SeverityCritical}
+
+	f := findings(measured, defects, severity)
+	if f.Knots != 5 || f.Wane != 4 || f.Split != 0 || f.Warp != 2.5 ||
+		f.Defects[SeverityMinor] != 3 || f.Defects[SeverityCritical] != 1 || f.Defects[SeverityMajor] != 0 {
+		t.Errorf("findings = %+v", f)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	low, high := 8.0, 12.0
+	tmpl := models.InspectionTemplate{TemplateID: 1, Items: []models.InspectionTemplateItem{
+		{Position: 1, Kind: KindMoisture, Min: &low, Max: &high, Required: true},
+		{Position: 2, Kind: KindKnots, Max: &high},
+		{Position: 3, Kind: KindDimension, Label: "width", Required: true},
+	}}
+	out, errs := measure(tmpl, []models.InspectionMeasurement{
+		{Position: 2, Value: 14}, {Position: 1, Value: 9}, {Position: 1, Value: 10}, {Position: 7, Value: 1},
+	})
+	if len(out) != 2 || out[0].Position != 1 || !out[0].Passed || out[0].Kind != KindMoisture ||
+		out[1].Passed || out[1].Kind != KindKnots {
+		t.Errorf("measurements = %+v", out)
+	}
+	var codes []string
+	for _, e := range errs {
+		codes = append(codes, e.Field+" "+e.Code)
+	}
+	if len(codes) != 3 || codes[0] != "measurements[2].position duplicate" ||
+		codes[1] != "measurements[3].position not_found" || codes[2] != "measurements required" {
+		t.Errorf("errors = %q", codes)
+	}
+}
@@ -0,0 +1,564 @@
+// Package kiln tracks drying cycles. A charge loads stock lots into a unit
+// with drying equipment and dries them to a target moisture, taken from
+// the lots' product grade or species unless given. The kiln's loggers send
+// temperature, humidity and moisture readings in bulk; every upload
+// re-evaluates the charge's moisture curve, completing the charge once
+// moisture reaches the target and flagging it when it is dried too far or
+// not far enough in the hours planned.
+package kiln
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/validate"
+)
+
+// Charge states, in the order a charge goes through them
+const (
+	StatusDrying    = "drying"
+	StatusCompleted = "completed"
+	StatusUnloaded  = "unloaded"
+)
+
+// Flags raised on a charge
+const (
+	FlagOverDried  = "over_dried"
+	FlagUnderDried = "under_dried"
+)
+
+// Where a charge's target moisture came from
+const (
+	SourceCharge  = "charge"
+	SourceGrade   = "grade"
+	SourceSpecies = "species"
+)
+
+// MaxReadings caps the readings one upload may send, and MaxUploadBytes
+// the size of its body
+const (
+	MaxReadings    = 10000
+	MaxUploadBytes = 2 << 20
+)
+
+// tooMany reports an upload of more than MaxReadings readings
+func tooMany() validate.Errors {
+	return validate.Errors{{Field: "readings", Code: validate.CodeTooLarge,
+		Message: fmt.Sprintf("readings must list at most %d readings", MaxReadings)}}
+}
+
+// tolerance is how many moisture points either side of the target a charge
+// may end at when it does not set its own
+var tolerance = 1.0
+
+// SetMoistureTolerance sets the default number of moisture points a charge
+// may end above or below its target
+func SetMoistureTolerance(points float64) {
+	tolerance = points
+}
+
+// Service loads kiln charges, stores their readings and evaluates each
+// charge's moisture curve against its target
+type Service struct {
+	tx         repository.Transactor
+	kilns      repository.KilnRepository
+	plant      repository.ProcessingRepository
+	stock      repository.StockRepository
+	warehouses repository.WarehouseRepository
+	forests    repository.ForestRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, kilns: repos.Kilns, plant: repos.Processing, stock: repos.Stock,
+		warehouses: repos.Warehouses, forests: repos.Forests}
+}
+
+// Upload is the outcome of sending readings: how many were stored and the
+// charge as evaluated after them
+type Upload struct {
+	Readings int               `json:"readings"`
+	Charge   models.KilnCharge `json:"charge"`
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func whereIn(field string, values []int) query.Spec {
+	var spec query.Spec
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	spec.WhereIn(field, list...)
+	return spec
+}
+
+// timeLayouts are the forms loggers write times in; times without a zone
+// are UTC
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ==================== TARGET MOISTURE ====================
+
+var gradePattern = regexp.MustCompile(`(?i)^\s*(?:KD|MC)[\s-]*(\d+(?:\.\d+)?)\s*%?\s*$`)
+
+// gradeMoisture reads the moisture a kiln-dried grade promises: KD19 or
+// MC15 name it, and plain KD or S-DRY lumber is dried to 19%
+func gradeMoisture(grade string) (float64, bool) {
+	if m := gradePattern.FindStringSubmatch(grade); m != nil {
+		v, err := strconv.ParseFloat(m[1], 64)
+		return v, err == nil && v > 0
+	}
+	switch validate.Normalize(grade) {
+	case validate.Normalize("KD"), validate.Normalize("S-DRY"):
+		return 19, true
+	}
+	return 0, false
+}
+
+// lowest keeps the smaller of the targets seen, so every lot dries enough
+func lowest(current, v float64) float64 {
+	if current == 0 || v < current {
+		return v
+	}
+	return current
+}
+
+// target finds the moisture the lots must dry to: the driest their product
+// grades promise or, failing that, the driest their species hold
+func (s *Service) target(ctx context.Context, lots []models.StockItem) (float64, string, error) {
+	var typeIDs, batchIDs []int
+	for _, lot := range lots {
+		typeIDs = append(typeIDs, lot.ProductTypeID)
+		if lot.BatchID != nil {
+			batchIDs = append(batchIDs, *lot.BatchID)
+		}
+	}
+
+	types, err := s.warehouses.ListProductTypes(ctx, whereIn("product_type_id", typeIDs))
+	if err != nil {
+		return 0, "", err
+	}
+	var target float64
+	for _, pt := range types.Data {
+		if v, ok := gradeMoisture(pt.Grade); ok {
+			target = lowest(target, v)
+		}
+	}
+	if target > 0 {
+		return target, SourceGrade, nil
+	}
+
+	batches, err := s.forests.ListHarvestBatches(ctx, whereIn("batch_id", batchIDs))
+	if err != nil {
+		return 0, "", err
+	}
+	var speciesIDs []int
+	for _, b := range batches.Data {
+		speciesIDs = append(speciesIDs, b.SpeciesID)
+	}
+	species, err := s.forests.ListTreeSpecies(ctx, whereIn("species_id", speciesIDs))
+	if err != nil {
+		return 0, "", err
+	}
+	for _, sp := range species.Data {
+		if sp.MoistureContent > 0 {
+			target = lowest(target, sp.MoistureContent)
+		}
+	}
+	if target > 0 {
+		return target, SourceSpecies, nil
+	}
+	return 0, "", validate.Errors{{Field: "target_moisture", Code: validate.CodeRequired,
+		Message: "target_moisture is required when neither the lots' grade nor their species gives one"}}
+}
+
+// ==================== CHARGES ====================
+
+// kiln loads the unit a charge dries in and checks it can take one
+func (s *Service) kiln(ctx context.Context, unitID int) error {
+	var spec query.Spec
+	spec.Where("unit_id", unitID)
+	units, err := s.plant.ListProcessingUnits(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if len(units.Data) == 0 {
+		return &repository.ReferenceError{Field: "unit_id", Table: "ProcessingUnit"}
+	}
+	u := units.Data[0]
+	if !scheduling.Supports(u, processing.OpDrying) {
+		return validate.Errors{{Field: "unit_id", Code: "not_a_kiln",
+			Message: "unit_id refers to a unit without drying equipment"}}
+	}
+	if u.Status != "" && u.Status != "active" {
+		return &repository.RuleError{Code: "unit_unavailable", Message: fmt.Sprintf("The kiln is %s", u.Status),
+			Details: map[string]interface{}{"unit_id": unitID, "status": u.Status}}
+	}
+
+	spec.Where("status", StatusDrying)
+	spec.Limit = 1
+	busy, err := s.kilns.ListKilnCharges(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if len(busy.Data) > 0 {
+		return &repository.RuleError{Code: "kiln_busy", Message: "The kiln is still drying another charge",
+			Details: map[string]interface{}{"unit_id": unitID, "charge_id": busy.Data[0].ChargeID}}
+	}
+	return nil
+}
+
+// lots loads the stock items of a charge, reporting missing ones
+func (s *Service) lots(ctx context.Context, stockIDs []int) ([]models.StockItem, error) {
+	if len(stockIDs) == 0 {
+		return nil, validate.Errors{{Field: "stock_ids", Code: validate.CodeRequired,
+			Message: "stock_ids must list at least one stock item"}}
+	}
+	page, err := s.stock.ListStockItems(ctx, whereIn("stock_id", stockIDs))
+	if err != nil {
+		return nil, err
+	}
+	found := map[int]bool{}
+	for _, si := range page.Data {
+		found[si.StockID] = true
+	}
+	var errs validate.Errors
+	for i, id := range stockIDs {
+		if !found[id] {
+			field := fmt.Sprintf("stock_ids[%d]", i)
+			errs = append(errs, validate.FieldError{Field: field, Code: "not_found",
+				Message: field + " refers to a stock item that does not exist"})
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return page.Data, nil
+}
+
+// Create loads the lots into the kiln and starts drying them
+func (s *Service) Create(ctx context.Context, kc *models.KilnCharge) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.kiln(ctx, kc.UnitID); err != nil {
+			return err
+		}
+		var stockIDs []int
+		seen := map[int]bool{}
+		for _, id := range kc.StockIDs {
+			if !seen[id] {
+				seen[id] = true
+				stockIDs = append(stockIDs, id)
+			}
+		}
+		lots, err := s.lots(ctx, stockIDs)
+		if err != nil {
+			return err
+		}
+
+		source := SourceCharge
+		target := kc.TargetMoisture
+		if target == 0 {
+			if target, source, err = s.target(ctx, lots); err != nil {
+				return err
+			}
+		}
+		*kc = models.KilnCharge{
+			UnitID:         kc.UnitID,
+			Status:         StatusDrying,
+			TargetMoisture: target,
+			TargetSource:   source,
+			Tolerance:      kc.Tolerance,
+			PlannedHours:   kc.PlannedHours,
+			Notes:          kc.Notes,
+			StartedAt:      kc.StartedAt,
+			StockIDs:       stockIDs,
+		}
+		if kc.Tolerance == 0 {
+			kc.Tolerance = tolerance
+		}
+		return s.kilns.CreateKilnCharge(ctx, kc)
+	})
+}
+
+// Changes are what an update may change of a charge still drying; its
+// kiln and lots stay. A zero target keeps the current one and a zero
+// tolerance takes the default.
+type Changes struct {
+	TargetMoisture float64 `json:"target_moisture" validate:"min=0,max=100"`
+	Tolerance      float64 `json:"tolerance" validate:"min=0,max=100"`
+	PlannedHours   float64 `json:"planned_hours" validate:"min=0"`
+	Notes          string  `json:"notes"`
+}
+
+// Update applies changes to a charge still drying and re-evaluates its
+// readings against them
+func (s *Service) Update(ctx context.Context, id int, c Changes) (models.KilnCharge, error) {
+	var charge models.KilnCharge
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if charge, err = s.kilns.GetKilnCharge(ctx, id); err != nil {
+			return err
+		}
+		if charge.Status != StatusDrying {
+			return &repository.RuleError{Code: "not_editable", Message: "Only a charge still drying can be changed",
+				Details: map[string]interface{}{"charge_id": id, "status": charge.Status}}
+		}
+		if c.TargetMoisture > 0 {
+			charge.TargetMoisture, charge.TargetSource = c.TargetMoisture, SourceCharge
+		}
+		charge.Tolerance = c.Tolerance
+		if charge.Tolerance == 0 {
+			charge.Tolerance = tolerance
+		}
+		charge.PlannedHours = c.PlannedHours
+		charge.Notes = c.Notes
+		if err := s.evaluate(ctx, &charge); err != nil {
+			return err
+		}
+		return s.kilns.UpdateKilnCharge(ctx, id, &charge)
+	})
+	return charge, err
+}
+
+// ==================== READINGS ====================
+
+// check validates readings and writes their times as RFC 3339 UTC
+func check(readings []models.KilnReading) error {
+	var errs validate.Errors
+	add := func(i int, field, code, format string, args ...interface{}) {
+		name := fmt.Sprintf("readings[%d].%s", i, field)
+		errs = append(errs, validate.FieldError{Field: name, Code: code, Message: name + " " + fmt.Sprintf(format, args...)})
+	}
+	if len(readings) == 0 {
+		return validate.Errors{{Field: "readings", Code: validate.CodeRequired, Message: "readings must list at least one reading"}}
+	}
+	if len(readings) > MaxReadings {
+		return tooMany()
+	}
+	for i := range readings {
+		r := &readings[i]
+		if t, ok := parseTime(r.RecordedAt); ok {
+			r.RecordedAt = t.Format(time.RFC3339)
+		} else if strings.TrimSpace(r.RecordedAt) == "" {
+			add(i, "recorded_at", validate.CodeRequired, "is required")
+		} else {
+			add(i, "recorded_at", validate.CodeInvalidDate, "must be a time such as 2006-01-02T15:04:05Z")
+		}
+		if r.Temperature == nil && r.Humidity == nil && r.Moisture == nil {
+			add(i, "moisture", validate.CodeRequired, "is required unless temperature or humidity is given")
+		}
+		if r.Humidity != nil && (*r.Humidity < 0 || *r.Humidity > 100) {
+			add(i, "humidity", validate.CodeTooLarge, "must be between 0 and 100")
+		}
+		if r.Moisture != nil && *r.Moisture < 0 {
+			add(i, "moisture", validate.CodeTooSmall, "must be at least 0")
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Record stores readings of a charge not yet unloaded and re-evaluates it
+func (s *Service) Record(ctx context.Context, id int, readings []models.KilnReading) (Upload, error) {
+	var up Upload
+	if err := check(readings); err != nil {
+		return up, err
+	}
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		charge, err := s.kilns.GetKilnCharge(ctx, id)
+		if err != nil {
+			return err
+		}
+		if charge.Status == StatusUnloaded {
+			return &repository.RuleError{Code: "charge_unloaded", Message: "The charge has left the kiln",
+				Details: map[string]interface{}{"charge_id": id, "unloaded_at": charge.UnloadedAt}}
+		}
+		for i := range readings {
+			readings[i].ReadingID = 0
+		}
+		if err := s.kilns.SaveKilnReadings(ctx, id, readings); err != nil {
+			return err
+		}
+		if err := s.evaluate(ctx, &charge); err != nil {
+			return err
+		}
+		up = Upload{Readings: len(readings), Charge: charge}
+		return s.kilns.UpdateKilnCharge(ctx, id, &charge)
+	})
+	return up, err
+}
+
+// evaluate walks a charge's readings in time order. The first moisture
+// within tolerance above the target completes a drying charge; the latest
+// moisture below the band flags it over-dried, and readings past its
+// planned hours without completing flag it under-dried.
+func (s *Service) evaluate(ctx context.Context, kc *models.KilnCharge) error {
+	var spec query.Spec
+	spec.Where("charge_id", kc.ChargeID)
+	spec.Sort = []query.Order{{Field: "recorded_at"}}
+	page, err := s.kilns.ListKilnReadings(ctx, spec)
+	if err != nil {
+		return err
+	}
+	kc.Moisture = nil
+	var last time.Time
+	for _, r := range page.Data {
+		if t, ok := parseTime(r.RecordedAt); ok && t.After(last) {
+			last = t
+		}
+		if r.Moisture == nil {
+			continue
+		}
+		moisture := *r.Moisture
+		kc.Moisture = &moisture
+		if kc.Status == StatusDrying && moisture <= kc.TargetMoisture+kc.Tolerance {
+			kc.Status = StatusCompleted
+			at := r.RecordedAt
+			kc.CompletedAt = &at
+		}
+	}
+	kc.Flag = flag(*kc, last)
+	return nil
+}
+
+// flag judges a charge as of a time
+func flag(kc models.KilnCharge, at time.Time) string {
+	if kc.Moisture != nil && *kc.Moisture < kc.TargetMoisture-kc.Tolerance {
+		return FlagOverDried
+	}
+	if kc.CompletedAt != nil {
+		return ""
+	}
+	if kc.Status == StatusUnloaded {
+		return FlagUnderDried
+	}
+	if start, ok := parseTime(kc.StartedAt); ok && kc.PlannedHours > 0 &&
+		!at.Before(start.Add(time.Duration(kc.PlannedHours*float64(time.Hour)))) {
+		return FlagUnderDried
+	}
+	return ""
+}
+
+// Unload takes a charge out of the kiln; one that never reached its target
+// is flagged under-dried
+func (s *Service) Unload(ctx context.Context, id int) (models.KilnCharge, error) {
+	var charge models.KilnCharge
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if charge, err = s.kilns.GetKilnCharge(ctx, id); err != nil {
+			return err
+		}
+		if charge.Status == StatusUnloaded {
+			return &repository.RuleError{
+				Code:    "invalid_transition",
+				Message: "An unloaded charge cannot become unloaded",
+				Details: map[string]interface{}{"charge_id": id, "status": charge.Status},
+			}
+		}
+		charge.Status = StatusUnloaded
+		unloaded := now()
+		charge.UnloadedAt = &unloaded
+		charge.Flag = flag(charge, time.Now().UTC())
+		return s.kilns.UpdateKilnCharge(ctx, id, &charge)
+	})
+	return charge, err
+}
+
+// ReadCSV reads logger readings from CSV whose header names the columns:
+// recorded_at (or time or timestamp), temperature, humidity and moisture.
+// Other columns are ignored and empty cells left unset. It stops with a
+// validation error past MaxReadings rows.
+func ReadCSV(r io.Reader) ([]models.KilnReading, error) {
+	rows := csv.NewReader(r)
+	rows.TrimLeadingSpace = true
+	rows.FieldsPerRecord = -1
+	header, err := rows.Read()
+	if err == io.EOF {
+		return nil, validate.Errors{{Field: "readings", Code: validate.CodeRequired, Message: "readings must list at least one reading"}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "time", "timestamp":
+			name = "recorded_at"
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["recorded_at"]; !ok {
+		return nil, validate.Errors{{Field: "recorded_at", Code: validate.CodeRequired,
+			Message: "the CSV header must name a recorded_at column"}}
+	}
+
+	var readings []models.KilnReading
+	var errs validate.Errors
+	for i := 0; ; i++ {
+		record, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if i == MaxReadings {
+			return nil, tooMany()
+		}
+		cell := func(name string) string {
+			if col, ok := columns[name]; ok && col < len(record) {
+				return strings.TrimSpace(record[col])
+			}
+			return ""
+		}
+		number := func(name string) *float64 {
+			raw := cell(name)
+			if raw == "" {
+				return nil
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				field := fmt.Sprintf("readings[%d].%s", i, name)
+				errs = append(errs, validate.FieldError{Field: field, Code: validate.CodeInvalidType,
+					Message: field + " must be a number"})
+				return nil
+			}
+			return &v
+		}
+		readings = append(readings, models.KilnReading{
+			RecordedAt:  cell("recorded_at"),
+			Temperature: number("temperature"),
+			Humidity:    number("humidity"),
+			Moisture:    number("moisture"),
+		})
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return readings, nil
+}
@@ -0,0 +1,132 @@
+package kiln
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+func TestReadCSV(t *testing.T) {
+	readings, err := ReadCSV(strings.NewReader("\ufeffTimestamp, Temperature,moisture,operator\n" +
+		"2026-05-01 08:00,61.5,24,ann\n" +
+		"2026-05-01T12:00:00Z,,19.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readings) != 2 || readings[0].RecordedAt != "2026-05-01 08:00" || *readings[0].Temperature != 61.5 ||
+		readings[0].Humidity != nil || readings[1].Temperature != nil || *readings[1].Moisture != 19.5 {
+		t.Fatalf("readings = %+v", readings)
+	}
+
+	for name, tc := range map[string]struct {
+		csv, field string
+	}{
+		"empty":          {"", "readings"},
+		"no time column": {"moisture\n20\n", "recorded_at"},
+		"not a number":   {"time,moisture\n2026-05-01 08:00,20\n2026-05-01 09:00,wet\n", "readings[1].moisture"},
+		"too many rows":  {"time\n" + strings.Repeat("2026-05-01 08:00\n", MaxReadings+1), "readings"},
+	} {
+		_, err := ReadCSV(strings.NewReader(tc.csv))
+		var errs validate.Errors
+		if !errors.As(err, &errs) || errs[0].Field != tc.field {
+			t.Errorf("%s: err = %v, want a %s error", name, err, tc.field)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	wet, hot := 120.0, 70.0
+	readings := []models.KilnReading{
+		{RecordedAt: "2026-05-01 08:00", Temperature: &hot},
+		{RecordedAt: "yesterday", Humidity: &wet},
+		{},
+	}
+	err := check(readings)
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v", err)
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	want := "readings[1].recorded_at readings[1].humidity readings[2].recorded_at readings[2].moisture"
+	if got := strings.Join(fields, " "); got != want {
+		t.Errorf("fields = %s", got)
+	}
+	if readings[0].RecordedAt != "2026-05-01T08:00:00Z" {
+		t.Errorf("recorded_at = %s", readings[0].RecordedAt)
+	}
+	if err := check(nil); err == nil {
+		t.Error("no readings accepted")
+	}
+}
+
+func TestGradeMoisture(t *testing.T) {
+	for grade, want := range map[string]float64{"KD19": 19, "mc 15%": 15, "KD-12.5": 12.5, "kd": 19, "S-DRY": 19, "#2": 0, "KD0": 0} {
+		if got, _ := gradeMoisture(grade); got != want {
+			t.Errorf("gradeMoisture(%q) = %v, want %v", grade, got, want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	moisture := func(v float64) *float64 { return &v }
+
+	for _, tc := range []struct {
+		name      string
+		readings  []models.KilnReading
+		status    string
+		completed string
+		flag      string
+	}{
+		{"still drying", []models.KilnReading{
+			{RecordedAt: "2026-05-01T08:00:00Z", Moisture: moisture(30)},
+			{RecordedAt: "2026-05-01T20:00:00Z", Moisture: moisture(24)},
+		}, StatusDrying, "", ""},
+		// Readings arrive out of order; the first within tolerance completes
+		{"completed", []models.KilnReading{
+			{RecordedAt: "2026-05-02T20:00:00Z", Moisture: moisture(18.5)},
+			{RecordedAt: "2026-05-02T08:00:00Z", Moisture: moisture(20)},
+			{RecordedAt: "2026-05-01T08:00:00Z", Moisture: moisture(30)},
+		}, StatusCompleted, "2026-05-02T08:00:00Z", ""},
+		{"over-dried", []models.KilnReading{
+			{RecordedAt: "2026-05-01T08:00:00Z", Moisture: moisture(19)},
+			{RecordedAt: "2026-05-01T09:00:00Z", Moisture: moisture(16)},
+		}, StatusCompleted, "2026-05-01T08:00:00Z", FlagOverDried},
+		// Past the planned 48 hours without reaching the target
+		{"under-dried", []models.KilnReading{
+			{RecordedAt: "2026-05-01T08:00:00Z", Moisture: moisture(30)},
+			{RecordedAt: "2026-05-03T00:00:00Z", Temperature: moisture(60)},
+		}, StatusDrying, "", FlagUnderDried},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repos := repository.NewMemory()
+			s := NewService(repos)
+			kc := models.KilnCharge{UnitID: 1, Status: StatusDrying, TargetMoisture: 19, Tolerance: 1,
+				PlannedHours: 48, StartedAt: "2026-05-01T00:00:00Z"}
+			if err := repos.Kilns.CreateKilnCharge(ctx, &kc); err != nil {
+				t.Fatal(err)
+			}
+			if err := repos.Kilns.SaveKilnReadings(ctx, kc.ChargeID, tc.readings); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.evaluate(ctx, &kc); err != nil {
+				t.Fatal(err)
+			}
+			completed := ""
+			if kc.CompletedAt != nil {
+				completed = *kc.CompletedAt
+			}
+			if kc.Status != tc.status || completed != tc.completed || kc.Flag != tc.flag {
+				t.Errorf("charge = %s completed %q flag %q", kc.Status, completed, kc.Flag)
+			}
+		})
+	}
+}
@@ -1,39 +1,103 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"strings"
 
+	"lumber-erp-api/auditchain"
+	"lumber-erp-api/auth"
 	"lumber-erp-api/config"
+	"lumber-erp-api/kiln"
+	"lumber-erp-api/maintenance"
+	"lumber-erp-api/middleware"
+	"lumber-erp-api/migrations"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/routes"
+	"lumber-erp-api/spc"
+	"lumber-erp-api/utils"
 )
 
 func main() {
+	configPath := flag.String("config", "", "path to the YAML config file (default $LUMBER_CONFIG or ./config.yaml)")
+	flag.Parse()
+
+	// Load and validate configuration, failing fast with every problem listed
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+
+	level, _ := cfg.Log.SlogLevel()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if cfg.Auth.Secret != "" {
+		auth.SetSigningKey([]byte(cfg.Auth.Secret))
+	}
+	utils.SetAllowedOrigins(cfg.CORS.AllowedOrigins)
+	utils.SetTrustedProxies(cfg.HTTP.TrustedProxies)
+	if seed, err := cfg.Audit.Seed(); err == nil {
+		auditchain.SetSigningKey(seed)
+	}
+	processing.SetMassBalanceTolerance(cfg.Processing.MassBalanceTolerance)
+	kiln.SetMoistureTolerance(cfg.Processing.KilnMoistureTolerance)
+
 	// Initialize database
-	config.InitDB()
+	config.InitDB(cfg.Database)
 	defer config.DB.Close()
 
+	if flag.Arg(0) == "migrate" {
+		code := runMigrate(flag.Args()[1:])
+		config.DB.Close()
+		os.Exit(code)
+	}
+
+	// Refuse to serve against an outdated schema
+	if err := migrations.Check(config.DB); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		fmt.Fprintln(os.Stderr, "   run: lumber-erp-api migrate up")
+		os.Exit(1)
+	}
+
 	// Setup all routes
-	routes.SetupRoutes()
+	repos := repository.NewPostgres(config.DB)
+	routes.SetupRoutes(http.DefaultServeMux, repos)
 
-	// Server configuration
-	port := ":5000"
+	// Sign the head of the audit chain periodically
+	if cfg.Audit.CheckpointInterval > 0 {
+		go auditchain.Run(context.Background(), repos.Audit, cfg.Audit.CheckpointInterval)
+	}
+	// Raise the work orders of maintenance plans as they fall due
+	if cfg.Processing.MaintenanceCheckInterval > 0 {
+		go maintenance.Run(context.Background(), maintenance.NewService(repos), cfg.Processing.MaintenanceCheckInterval)
+	}
+	// Alert the quality team of moisture charts breaking a control rule
+	if cfg.Quality.SPCCheckInterval > 0 {
+		go spc.Run(context.Background(), spc.NewService(repos), cfg.Quality.SPCCheckInterval)
+	}
 
 	// Print startup banner
-	printStartupBanner(port)
+	printStartupBanner(cfg)
 
 	// Start server
 	fmt.Println("\n🎯 Server is ready to accept connections!")
-	log.Fatal(http.ListenAndServe(port, nil))
+	log.Fatal(http.ListenAndServe(cfg.HTTP.Addr, utils.WithCORS(middleware.RequestID(http.DefaultServeMux))))
 }
 
-func printStartupBanner(port string) {
+func printStartupBanner(cfg *config.Config) {
+	port := cfg.HTTP.Addr
 	fmt.Println("\n" + strings.Repeat("=", 70))
 	fmt.Println("🌲 LUMBER ERP API - COMPLETE SYSTEM v2.0")
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Printf("🚀 Server: http://localhost%s\n", port)
+	fmt.Printf("🌍 Environment: %s\n", cfg.Env)
 	fmt.Println(strings.Repeat("=", 70))
 
 	fmt.Println("\n📋 API ENDPOINTS BY CATEGORY:")
@@ -43,9 +107,16 @@ func printStartupBanner(port string) {
 		category string
 		routes   []string
 	}{
+		{"🔐 AUTHENTICATION", []string{
+			"POST        /api/auth/login",
+			"POST        /api/auth/logout",
+			"POST        /api/auth/refresh",
+			"GET         /api/auth/me",
+		}},
 		{"👥 USER MANAGEMENT", []string{
 			"GET/POST    /api/users",
 			"GET/PUT/DEL /api/user?id={id}",
+			"PUT         /api/user/password?id={id}",
 			"GET/POST    /api/permissions",
 			"PUT/DEL     /api/permission?id={id}",
 			"GET/POST    /api/roles",
@@ -80,18 +151,60 @@ func printStartupBanner(port string) {
 		{"🏗️ PROCESSING & SAWMILL", []string{
 			"GET/POST    /api/sawmills",
 			"PUT/DEL     /api/sawmill?id={id}",
+			"GET/POST    /api/v2/sawmills/{id}/schedule",
+			"POST        /api/v2/sawmills/{id}/schedule/dry-run",
+			"GET         /api/v2/sawmills/{id}/oee",
 			"GET/POST    /api/processingunits",
 			"PUT/DEL     /api/processingunit?id={id}",
+			"GET         /api/v2/processingunits/{id}/oee",
 			"GET/POST    /api/processingorders",
 			"PUT/DEL     /api/processingorder?id={id}",
+			"GET         /api/v2/processingorders/{id}",
+			"GET/POST    /api/v2/processingorders/{id}/batches",
+			"DEL         /api/v2/processingorders/{id}/batches/{batch_id}",
+			"POST        /api/v2/processingorders/{id}/release|start|complete|close",
 			"GET/POST    /api/maintenancerecords",
 			"PUT/DEL     /api/maintenancerecord?id={id}",
+			"GET/POST    /api/v2/maintenanceplans",
+			"PUT/DEL     /api/v2/maintenanceplans/{id}",
+			"POST        /api/v2/maintenanceplans/generate",
+			"GET/POST    /api/v2/maintenanceworkorders",
+			"POST        /api/v2/maintenanceworkorders/{id}/complete|cancel",
+			"GET/POST    /api/v2/kilncharges",
+			"GET/PUT/DEL /api/v2/kilncharges/{id}",
+			"GET/POST    /api/v2/kilncharges/{id}/readings",
+			"POST        /api/v2/kilncharges/{id}/unload",
 			"GET/POST    /api/wasterecords",
 			"PUT/DEL     /api/wasterecord?id={id}",
+			"POST        /api/v2/wasterecords/{id}/valorise",
+			"GET         /api/v2/wasteanalytics",
 		}},
 		{"✅ QUALITY CONTROL", []string{
 			"GET/POST    /api/qualityinspections",
 			"PUT/DEL     /api/qualityinspection?id={id}",
+			"GET         /api/v2/qualityinspections/{id}",
+			"GET/POST    /api/v2/defectcodes",
+			"PUT/DEL     /api/v2/defectcodes/{id}",
+			"GET/POST    /api/v2/inspectiontemplates",
+			"GET/PUT/DEL /api/v2/inspectiontemplates/{id}",
+			"GET/POST    /api/v2/nonconformances",
+			"GET/PUT/DEL /api/v2/nonconformances/{id}",
+			"POST        /api/v2/nonconformances/{id}/investigate|act|verify|close",
+			"GET/POST    /api/v2/nonconformances/{id}/actions",
+			"PUT/DEL     /api/v2/nonconformances/{id}/actions/{action_id}",
+			"GET         /api/v2/correctiveactions",
+			"GET         /api/v2/correctiveactions/overdue",
+			"POST        /api/v2/correctiveactions/{id}/complete",
+			"GET         /api/v2/spc/charts",
+			"POST        /api/v2/spc/alerts",
+			"GET         /api/v2/qualityalerts",
+			"POST        /api/v2/qualityalerts/{id}/resolve",
+			"GET/POST    /api/v2/samplingplans",
+			"PUT/DEL     /api/v2/samplingplans/{id}",
+			"GET         /api/v2/samplingplans/sample",
+			"GET         /api/v2/suppliers/{id}/sampling",
+			"GET/POST    /api/v2/inspectionlots",
+			"GET/DEL     /api/v2/inspectionlots/{id}",
 		}},
 		{"📦 WAREHOUSE & INVENTORY", []string{
 			"GET/POST    /api/warehouses",
@@ -100,10 +213,17 @@ func printStartupBanner(port string) {
 			"PUT/DEL     /api/producttype?id={id}",
 			"GET/POST    /api/stockitems",
 			"PUT/DEL     /api/stockitem?id={id}",
+			"GET/POST    /api/v2/stockitems/{id}/dispositions",
+			"GET         /api/v2/stockdispositions",
 			"GET/POST    /api/stockalerts",
 			"PUT/DEL     /api/stockalert?id={id}",
 			"GET/POST    /api/inventorytransactions",
-			"PUT/DEL     /api/inventorytransaction?id={id}",
+			"POST        /api/inventorytransaction/reverse?id={id}",
+			"GET/POST    /api/v2/transfers",
+			"GET/PUT/DEL /api/v2/transfers/{id}",
+			"POST        /api/v2/transfers/{id}/dispatch|transit|receive",
+			"GET         /api/v2/transfers/{id}/discrepancies",
+			"GET         /api/v2/trace/{qr_code}",
 		}},
 		{"🛒 PROCUREMENT", []string{
 			"GET/POST    /api/purchaseorders",
@@ -140,7 +260,10 @@ func printStartupBanner(port string) {
 			"PUT/DEL     /api/fuellog?id={id}",
 		}},
 		{"📊 AUDIT & LOGS", []string{
-			"GET/POST    /api/auditlogs",
+			"GET         /api/auditlogs",
+			"GET         /api/auditlogs/verify",
+			"GET/POST    /api/v2/auditlogs/checkpoints",
+			"GET         /api/v2/auditlogs/checkpoints/export",
 		}},
 	}
 
@@ -153,9 +276,9 @@ func printStartupBanner(port string) {
 
 	fmt.Println("\n" + strings.Repeat("=", 70))
 	fmt.Println("💡 USAGE EXAMPLES:")
-	fmt.Println("  curl http://localhost:5000/api/users")
-	fmt.Println("  curl -X POST http://localhost:5000/api/users -d '{...}'")
-	fmt.Println("  curl -X PUT http://localhost:5000/api/user?id=1 -d '{...}'")
-	fmt.Println("  curl -X DELETE http://localhost:5000/api/user?id=1")
+	fmt.Printf("  curl -X POST http://localhost%s/api/auth/login -d '{\"email\":\"...\",\"password\":\"...\"}'\n", port)
+	fmt.Printf("  curl -H 'Authorization: Bearer <token>' http://localhost%s/api/users\n", port)
+	fmt.Printf("  curl -H 'Authorization: Bearer <token>' -X PUT http://localhost%s/api/user?id=1 -d '{...}'\n", port)
+	fmt.Printf("  curl -H 'Authorization: Bearer <token>' -X DELETE http://localhost%s/api/user?id=1\n", port)
 	fmt.Println(strings.Repeat("=", 70))
 }
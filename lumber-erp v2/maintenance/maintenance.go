@@ -0,0 +1,644 @@
+// Package maintenance runs the preventive maintenance of processing units
+// and reports their overall equipment effectiveness (OEE). A plan falls due
+// after so many operating hours or days and raises a work order; the unit
+// is in maintenance while one is open. Completing the work order records
+// the service as a MaintenanceRecord and restarts the plan's intervals.
+//
+// A unit's operating hours are those of its completed processing orders:
+// the length of an order's slot, or its DurationHours when it was never
+// scheduled.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"lumber-erp-api/inspection"
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/validate"
+)
+
+// Work order states
+const (
+	StatusOpen      = "open"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+)
+
+// Why a work order was raised
+const (
+	ReasonHours  = "hours"
+	ReasonDays   = "days"
+	ReasonManual = "manual"
+)
+
+// Unit states the work orders switch between
+const (
+	UnitActive      = "active"
+	UnitMaintenance = "maintenance"
+)
+
+// Period is how far back OEE looks when no start is asked for
+const Period = 30 * 24 * time.Hour
+
+// Service keeps maintenance plans, raises and closes their work orders and
+// computes the operating hours and OEE of units and sawmills
+type Service struct {
+	tx      repository.Transactor
+	plans   repository.MaintenanceRepository
+	plant   repository.ProcessingRepository
+	quality repository.QualityRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, plans: repos.Maintenance, plant: repos.Processing, quality: repos.Quality}
+}
+
+// Completion is what the crew reports when a work order is done. The
+// maintenance date defaults to today and the description to the work
+// order's.
+type Completion struct {
+	MaintenanceDate string  `json:"maintenance_date" validate:"date"`
+	Description     string  `json:"description"`
+	Cost            float64 `json:"cost" validate:"min=0"`
+	PartsUsed       string  `json:"parts_used"`
+	DowntimeHours   float64 `json:"downtime_hours" validate:"min=0"`
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func where(field string, value interface{}) query.Spec {
+	var spec query.Spec
+	spec.Where(field, value)
+	return spec
+}
+
+func whereIn(field string, values []int) query.Spec {
+	var spec query.Spec
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	spec.WhereIn(field, list...)
+	return spec
+}
+
+// finished reports whether an order has run
+func finished(po models.ProcessingOrder) bool {
+	return po.Status == processing.StatusCompleted || po.Status == processing.StatusClosed
+}
+
+// runHours is how long an order kept its unit busy
+func runHours(po models.ProcessingOrder) float64 {
+	if po.ScheduledStart != nil && po.ScheduledEnd != nil {
+		start, err1 := scheduling.ParseTime(*po.ScheduledStart)
+		end, err2 := scheduling.ParseTime(*po.ScheduledEnd)
+		if err1 == nil && err2 == nil && end.After(start) {
+			return end.Sub(start).Hours()
+		}
+	}
+	return po.DurationHours
+}
+
+// unit loads one processing unit
+func (s *Service) unit(ctx context.Context, id int) (models.ProcessingUnit, error) {
+	page, err := s.plant.ListProcessingUnits(ctx, where("unit_id", id))
+	if err != nil {
+		return models.ProcessingUnit{}, err
+	}
+	if len(page.Data) == 0 {
+		return models.ProcessingUnit{}, repository.ErrNotFound
+	}
+	return page.Data[0], nil
+}
+
+// referenced loads the unit a plan or work order names, reporting a
+// missing one against unit_id
+func (s *Service) referenced(ctx context.Context, id int) (models.ProcessingUnit, error) {
+	u, err := s.unit(ctx, id)
+	if err == repository.ErrNotFound {
+		return u, &repository.ReferenceError{Field: "unit_id", Table: "ProcessingUnit"}
+	}
+	return u, err
+}
+
+// OperatingHours is the time a unit has run its completed orders
+func (s *Service) OperatingHours(ctx context.Context, unitID int) (float64, error) {
+	orders, err := s.plant.ListProcessingOrders(ctx, where("unit_id", unitID))
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, po := range orders.Data {
+		if finished(po) {
+			total += runHours(po)
+		}
+	}
+	return round(total), nil
+}
+
+// ==================== PLANS ====================
+
+// checkPlan refuses a plan with no interval to fall due on
+func checkPlan(mp models.MaintenancePlan) error {
+	if mp.IntervalHours > 0 || mp.IntervalDays > 0 {
+		return nil
+	}
+	return validate.Errors{{Field: "interval_hours", Code: validate.CodeRequired,
+		Message: "interval_hours or interval_days is required"}}
+}
+
+// plan loads one maintenance plan
+func (s *Service) plan(ctx context.Context, id int) (models.MaintenancePlan, error) {
+	page, err := s.plans.ListMaintenancePlans(ctx, where("plan_id", id))
+	if err != nil {
+		return models.MaintenancePlan{}, err
+	}
+	if len(page.Data) == 0 {
+		return models.MaintenancePlan{}, repository.ErrNotFound
+	}
+	return page.Data[0], nil
+}
+
+// CreatePlan stores mp counting from the unit's current operating hours
+// and from LastDoneAt, by default now
+func (s *Service) CreatePlan(ctx context.Context, mp *models.MaintenancePlan) error {
+	if err := checkPlan(*mp); err != nil {
+		return err
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.referenced(ctx, mp.UnitID); err != nil {
+			return err
+		}
+		hours, err := s.OperatingHours(ctx, mp.UnitID)
+		if err != nil {
+			return err
+		}
+		mp.LastDoneHours = hours
+		return s.plans.CreateMaintenancePlan(ctx, mp)
+	})
+}
+
+// UpdatePlan changes a plan's unit, intervals and description. The last
+// service stays as recorded unless LastDoneAt is given; moving the plan to
+// another unit counts its hours from that unit's current ones.
+func (s *Service) UpdatePlan(ctx context.Context, id int, mp *models.MaintenancePlan) error {
+	if err := checkPlan(*mp); err != nil {
+		return err
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		old, err := s.plan(ctx, id)
+		if err != nil {
+			return err
+		}
+		mp.LastDoneHours = old.LastDoneHours
+		if mp.LastDoneAt == "" {
+			mp.LastDoneAt = old.LastDoneAt
+		}
+		if mp.UnitID != old.UnitID {
+			if _, err := s.referenced(ctx, mp.UnitID); err != nil {
+				return err
+			}
+			if mp.LastDoneHours, err = s.OperatingHours(ctx, mp.UnitID); err != nil {
+				return err
+			}
+		}
+		return s.plans.UpdateMaintenancePlan(ctx, id, mp)
+	})
+}
+
+// due reports whether a plan has fallen due at a time, given its unit's
+// operating hours, and on which interval
+func due(mp models.MaintenancePlan, hours float64, at time.Time) (string, bool) {
+	if mp.IntervalHours > 0 && hours-mp.LastDoneHours >= mp.IntervalHours {
+		return ReasonHours, true
+	}
+	if mp.IntervalDays > 0 {
+		last, err := scheduling.ParseTime(mp.LastDoneAt)
+		if err == nil && !at.Before(last.AddDate(0, 0, mp.IntervalDays)) {
+			return ReasonDays, true
+		}
+	}
+	return "", false
+}
+
+// Generate raises a work order for every active plan that has fallen due
+// and has none open, putting their units in maintenance. The plans stay
+// locked until it ends, so a concurrent run waits and then finds their
+// work orders open.
+func (s *Service) Generate(ctx context.Context) ([]models.MaintenanceWorkOrder, error) {
+	raised := []models.MaintenanceWorkOrder{}
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		raised = raised[:0]
+		plans, err := s.plans.LockMaintenancePlans(ctx, where("active", true))
+		if err != nil {
+			return err
+		}
+		var planIDs []int
+		for _, mp := range plans {
+			planIDs = append(planIDs, mp.PlanID)
+		}
+		openSpec := whereIn("plan_id", planIDs)
+		openSpec.Where("status", StatusOpen)
+		open, err := s.plans.ListWorkOrders(ctx, openSpec)
+		if err != nil {
+			return err
+		}
+		pending := map[int]bool{}
+		for _, wo := range open.Data {
+			if wo.PlanID != nil {
+				pending[*wo.PlanID] = true
+			}
+		}
+
+		at := time.Now().UTC()
+		hours := map[int]float64{}
+		for _, mp := range plans {
+			if pending[mp.PlanID] {
+				continue
+			}
+			h, ok := hours[mp.UnitID]
+			if !ok {
+				if h, err = s.OperatingHours(ctx, mp.UnitID); err != nil {
+					return err
+				}
+				hours[mp.UnitID] = h
+			}
+			reason, ok := due(mp, h, at)
+			if !ok {
+				continue
+			}
+			planID := mp.PlanID
+			wo := models.MaintenanceWorkOrder{UnitID: mp.UnitID, PlanID: &planID, Status: StatusOpen,
+				Reason: reason, Description: mp.Description, OperatingHours: h}
+			if err := s.plans.CreateWorkOrder(ctx, &wo); err != nil {
+				return err
+			}
+			if err := s.hold(ctx, mp.UnitID); err != nil {
+				return err
+			}
+			raised = append(raised, wo)
+		}
+		return nil
+	})
+	return raised, err
+}
+
+// Run raises the work orders of due plans every interval until ctx is
+// cancelled
+func Run(ctx context.Context, s *Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Generate(ctx); err != nil {
+				log.Printf("⚠️  maintenance work orders not raised: %v", err)
+			}
+		}
+	}
+}
+
+// ==================== WORK ORDERS ====================
+
+// hold puts a unit in maintenance
+func (s *Service) hold(ctx context.Context, unitID int) error {
+	u, err := s.unit(ctx, unitID)
+	if err == repository.ErrNotFound || (err == nil && u.Status == UnitMaintenance) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	u.Status = UnitMaintenance
+	return s.plant.UpdateProcessingUnit(ctx, unitID, &u)
+}
+
+// release returns a unit in maintenance to service once none of its work
+// orders is open
+func (s *Service) release(ctx context.Context, unitID int) error {
+	spec := where("unit_id", unitID)
+	spec.Where("status", StatusOpen)
+	spec.Limit = 1
+	open, err := s.plans.ListWorkOrders(ctx, spec)
+	if err != nil || len(open.Data) > 0 {
+		return err
+	}
+	u, err := s.unit(ctx, unitID)
+	if err == repository.ErrNotFound || (err == nil && u.Status != UnitMaintenance) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	u.Status = UnitActive
+	return s.plant.UpdateProcessingUnit(ctx, unitID, &u)
+}
+
+// Open raises a work order by hand, putting its unit in maintenance. Naming
+// a plan services it early: completing the order restarts the plan.
+func (s *Service) Open(ctx context.Context, wo *models.MaintenanceWorkOrder) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.referenced(ctx, wo.UnitID); err != nil {
+			return err
+		}
+		if wo.PlanID != nil {
+			mp, err := s.plan(ctx, *wo.PlanID)
+			if err == repository.ErrNotFound {
+				return &repository.ReferenceError{Field: "plan_id", Table: "MaintenancePlan"}
+			}
+			if err != nil {
+				return err
+			}
+			if mp.UnitID != wo.UnitID {
+				return validate.Errors{{Field: "plan_id", Code: "wrong_unit",
+					Message: fmt.Sprintf("plan_id is a plan of unit %d", mp.UnitID)}}
+			}
+			spec := where("plan_id", mp.PlanID)
+			spec.Where("status", StatusOpen)
+			open, err := s.plans.ListWorkOrders(ctx, spec)
+			if err != nil {
+				return err
+			}
+			if len(open.Data) > 0 {
+				return &repository.RuleError{Code: "work_order_open",
+					Message: "The plan already has an open work order",
+					Details: map[string]interface{}{"plan_id": mp.PlanID, "work_order_id": open.Data[0].WorkOrderID}}
+			}
+		}
+		hours, err := s.OperatingHours(ctx, wo.UnitID)
+		if err != nil {
+			return err
+		}
+		*wo = models.MaintenanceWorkOrder{UnitID: wo.UnitID, PlanID: wo.PlanID, Status: StatusOpen,
+			Reason: ReasonManual, Description: wo.Description, OperatingHours: hours}
+		if err := s.plans.CreateWorkOrder(ctx, wo); err != nil {
+			return err
+		}
+		return s.hold(ctx, wo.UnitID)
+	})
+}
+
+// close loads work order id, checks it is open and stores it as to after
+// change has run, returning its unit to service when it was the last open
+func (s *Service) close(ctx context.Context, id int, to string, change func(context.Context, *models.MaintenanceWorkOrder) error) (models.MaintenanceWorkOrder, error) {
+	var wo models.MaintenanceWorkOrder
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if wo, err = s.plans.GetWorkOrder(ctx, id); err != nil {
+			return err
+		}
+		if wo.Status != StatusOpen {
+			return &repository.RuleError{
+				Code:    "invalid_transition",
+				Message: fmt.Sprintf("A %s work order cannot become %s", wo.Status, to),
+				Details: map[string]interface{}{"work_order_id": id, "status": wo.Status, "required_status": StatusOpen},
+			}
+		}
+		if change != nil {
+			if err := change(ctx, &wo); err != nil {
+				return err
+			}
+		}
+		wo.Status = to
+		wo.ClosedAt = new(string)
+		*wo.ClosedAt = now()
+		if err := s.plans.UpdateWorkOrder(ctx, id, &wo); err != nil {
+			return err
+		}
+		return s.release(ctx, wo.UnitID)
+	})
+	return wo, err
+}
+
+// Complete records the service of an open work order as a maintenance
+// record and restarts its plan from now and the hours it was raised at
+func (s *Service) Complete(ctx context.Context, id int, c Completion) (models.MaintenanceWorkOrder, error) {
+	return s.close(ctx, id, StatusCompleted, func(ctx context.Context, wo *models.MaintenanceWorkOrder) error {
+		record := models.MaintenanceRecord{
+			UnitID:          wo.UnitID,
+			MaintenanceDate: c.MaintenanceDate,
+			Description:     c.Description,
+			Cost:            c.Cost,
+			PartsUsed:       c.PartsUsed,
+			DowntimeHours:   c.DowntimeHours,
+		}
+		if record.MaintenanceDate == "" {
+			record.MaintenanceDate = time.Now().UTC().Format("2006-01-02")
+		}
+		if record.Description == "" {
+			record.Description = wo.Description
+		}
+		if err := s.plant.CreateMaintenanceRecord(ctx, &record); err != nil {
+			return err
+		}
+		wo.MaintenanceID = &record.MaintenanceID
+
+		if wo.PlanID == nil {
+			return nil
+		}
+		mp, err := s.plan(ctx, *wo.PlanID)
+		if err == repository.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		mp.LastDoneAt = now()
+		mp.LastDoneHours = wo.OperatingHours
+		return s.plans.UpdateMaintenancePlan(ctx, mp.PlanID, &mp)
+	})
+}
+
+// Cancel drops an open work order. A plan still due raises another on the
+// next run.
+func (s *Service) Cancel(ctx context.Context, id int) (models.MaintenanceWorkOrder, error) {
+	return s.close(ctx, id, StatusCancelled, nil)
+}
+
+// ==================== OEE ====================
+
+// OEE is the overall equipment effectiveness of a unit, or of a sawmill's
+// units together, over the orders completed and the maintenance done from
+// From up to To. Availability is run time over run time plus downtime,
+// performance the output over what the capacity allows in the run time and
+// quality the output of orders without a failed inspection over all
+// output; OEE is their product. Each is a percentage, left out when there
+// is nothing to compute it from.
+type OEE struct {
+	SawmillID         int      `json:"sawmill_id"`
+	UnitID            *int     `json:"unit_id,omitempty"`
+	From              string   `json:"from"`
+	To                string   `json:"to"`
+	Orders            int      `json:"orders"`
+	RunHours          float64  `json:"run_hours"`
+	DowntimeHours     float64  `json:"downtime_hours"`
+	OutputQuantity    float64  `json:"output_quantity"`
+	IdealQuantity     float64  `json:"ideal_quantity"`
+	RejectedQuantity  float64  `json:"rejected_quantity"`
+	FailedInspections int      `json:"failed_inspections"`
+	Availability      *float64 `json:"availability"`
+	Performance       *float64 `json:"performance"`
+	Quality           *float64 `json:"quality"`
+	OEE               *float64 `json:"oee"`
+	Units             []OEE    `json:"units,omitempty"`
+}
+
+// percent is part over whole as a percentage, or nil without a whole
+func percent(part, whole float64) *float64 {
+	if whole <= 0 {
+		return nil
+	}
+	v := round(part / whole * 100)
+	return &v
+}
+
+// add sums the figures of o into e
+func (e *OEE) add(o OEE) {
+	e.Orders += o.Orders
+	e.RunHours = round(e.RunHours + o.RunHours)
+	e.DowntimeHours = round(e.DowntimeHours + o.DowntimeHours)
+	e.OutputQuantity = round(e.OutputQuantity + o.OutputQuantity)
+	e.IdealQuantity = round(e.IdealQuantity + o.IdealQuantity)
+	e.RejectedQuantity = round(e.RejectedQuantity + o.RejectedQuantity)
+	e.FailedInspections += o.FailedInspections
+}
+
+// rate computes the ratios from the summed figures
+func (e *OEE) rate() {
+	e.Availability = percent(e.RunHours, e.RunHours+e.DowntimeHours)
+	e.Performance = percent(e.OutputQuantity, e.IdealQuantity)
+	e.Quality = percent(e.OutputQuantity-e.RejectedQuantity, e.OutputQuantity)
+	if e.Availability != nil && e.Performance != nil && e.Quality != nil {
+		v := round(*e.Availability * *e.Performance * *e.Quality / 10000)
+		e.OEE = &v
+	}
+}
+
+// within reports whether a date or time falls in [from, to)
+func within(s string, from, to time.Time) bool {
+	t, err := scheduling.ParseTime(s)
+	return err == nil && !t.Before(from) && t.Before(to)
+}
+
+// measure computes the OEE of each unit
+func (s *Service) measure(ctx context.Context, units []models.ProcessingUnit, from, to time.Time) ([]OEE, error) {
+	var unitIDs []int
+	for _, u := range units {
+		unitIDs = append(unitIDs, u.UnitID)
+	}
+	orders, err := s.plant.ListProcessingOrders(ctx, whereIn("unit_id", unitIDs))
+	if err != nil {
+		return nil, err
+	}
+	var ran []models.ProcessingOrder
+	var orderIDs []int
+	for _, po := range orders.Data {
+		if finished(po) && within(po.EndDate, from, to) {
+			ran = append(ran, po)
+			orderIDs = append(orderIDs, po.ProcessingID)
+		}
+	}
+	inspections, err := s.quality.ListQualityInspections(ctx, whereIn("processing_id", orderIDs))
+	if err != nil {
+		return nil, err
+	}
+	failed := map[int]int{}
+	for _, qi := range inspections.Data {
+		if qi.ProcessingID != nil && validate.Normalize(qi.Result) == inspection.ResultFail {
+			failed[*qi.ProcessingID]++
+		}
+	}
+	records, err := s.plant.ListMaintenanceRecords(ctx, whereIn("unit_id", unitIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	figures := make([]OEE, len(units))
+	index := map[int]int{}
+	for i, u := range units {
+		unitID := u.UnitID
+		figures[i] = OEE{SawmillID: u.SawmillID, UnitID: &unitID, From: from.Format(time.RFC3339), To: to.Format(time.RFC3339)}
+		index[u.UnitID] = i
+	}
+	for _, po := range ran {
+		e := &figures[index[po.UnitID]]
+		hours := runHours(po)
+		e.add(OEE{
+			Orders:            1,
+			RunHours:          hours,
+			OutputQuantity:    po.OutputQuantity,
+			IdealQuantity:     hours * units[index[po.UnitID]].Capacity,
+			FailedInspections: failed[po.ProcessingID],
+		})
+		if failed[po.ProcessingID] > 0 {
+			e.RejectedQuantity = round(e.RejectedQuantity + po.OutputQuantity)
+		}
+	}
+	for _, r := range records.Data {
+		if within(r.MaintenanceDate, from, to) {
+			e := &figures[index[r.UnitID]]
+			e.DowntimeHours = round(e.DowntimeHours + r.DowntimeHours)
+		}
+	}
+	for i := range figures {
+		figures[i].rate()
+	}
+	return figures, nil
+}
+
+// UnitOEE reports the OEE of one unit
+func (s *Service) UnitOEE(ctx context.Context, unitID int, from, to time.Time) (OEE, error) {
+	u, err := s.unit(ctx, unitID)
+	if err != nil {
+		return OEE{}, err
+	}
+	figures, err := s.measure(ctx, []models.ProcessingUnit{u}, from, to)
+	if err != nil {
+		return OEE{}, err
+	}
+	return figures[0], nil
+}
+
+// SawmillOEE reports the OEE of a sawmill's units together, with each
+// unit's
+func (s *Service) SawmillOEE(ctx context.Context, sawmillID int, from, to time.Time) (OEE, error) {
+	mills, err := s.plant.ListSawmills(ctx, where("sawmill_id", sawmillID))
+	if err != nil {
+		return OEE{}, err
+	}
+	if len(mills.Data) == 0 {
+		return OEE{}, repository.ErrNotFound
+	}
+	spec := where("sawmill_id", sawmillID)
+	spec.Sort = []query.Order{{Field: "unit_id"}}
+	units, err := s.plant.ListProcessingUnits(ctx, spec)
+	if err != nil {
+		return OEE{}, err
+	}
+	figures, err := s.measure(ctx, units.Data, from, to)
+	if err != nil {
+		return OEE{}, err
+	}
+	total := OEE{SawmillID: sawmillID, From: from.Format(time.RFC3339), To: to.Format(time.RFC3339), Units: figures}
+	for _, f := range figures {
+		total.add(f)
+	}
+	total.rate()
+	return total, nil
+}
@@ -0,0 +1,146 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/repository"
+)
+
+func TestDue(t *testing.T) {
+	at := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		plan   models.MaintenancePlan
+		hours  float64
+		reason string
+	}{
+		"hours run":           {models.MaintenancePlan{IntervalHours: 8, LastDoneHours: 2}, 10, ReasonHours},
+		"hours short":         {models.MaintenancePlan{IntervalHours: 8, LastDoneHours: 2}, 9.9, ""},
+		"days passed":         {models.MaintenancePlan{IntervalDays: 30, LastDoneAt: "2026-01-01"}, 0, ReasonDays},
+		"days short":          {models.MaintenancePlan{IntervalDays: 30, LastDoneAt: "2026-01-01T01:00:00Z"}, 0, ""},
+		"hours before days":   {models.MaintenancePlan{IntervalHours: 8, IntervalDays: 1, LastDoneAt: "2025-01-01"}, 8, ReasonHours},
+		"days when hours not": {models.MaintenancePlan{IntervalHours: 8, IntervalDays: 1, LastDoneAt: "2025-01-01"}, 7, ReasonDays},
+		"unreadable last":     {models.MaintenancePlan{IntervalDays: 1, LastDoneAt: "last spring"}, 0, ""},
+		"no interval":         {models.MaintenancePlan{LastDoneAt: "2025-01-01"}, 100, ""},
+	} {
+		reason, ok := due(tc.plan, tc.hours, at)
+		if reason != tc.reason || ok != (tc.reason != "") {
+			t.Errorf("%s: due = %q, %v; want %q", name, reason, ok, tc.reason)
+		}
+	}
+}
+
+// newPlant stores a sawmill with one active unit taking 10 an hour
+func newPlant(t *testing.T) (repository.Repositories, *Service) {
+	t.Helper()
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	if err := repos.Processing.CreateSawmill(ctx, &models.Sawmill{Name: "Main", Status: "operational"}); err != nil {
+		t.Fatal(err)
+	}
+	unit := models.ProcessingUnit{SawmillID: 1, Cutting: "band saw", Capacity: 10, Status: UnitActive}
+	if err := repos.Processing.CreateProcessingUnit(ctx, &unit); err != nil {
+		t.Fatal(err)
+	}
+	return repos, NewService(repos)
+}
+
+func TestUnitHold(t *testing.T) {
+	ctx := context.Background()
+	repos, s := newPlant(t)
+	status := func() string {
+		t.Helper()
+		u, err := s.unit(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Status
+	}
+
+	plan := models.MaintenancePlan{UnitID: 1, IntervalDays: 7, Active: true, LastDoneAt: "2020-01-01"}
+	if err := repos.Maintenance.CreateMaintenancePlan(ctx, &plan); err != nil {
+		t.Fatal(err)
+	}
+	raised, err := s.Generate(ctx)
+	if err != nil || len(raised) != 1 || raised[0].Reason != ReasonDays || status() != UnitMaintenance {
+		t.Fatalf("generate = %+v, %v; unit %s", raised, err, status())
+	}
+	if again, err := s.Generate(ctx); err != nil || len(again) != 0 {
+		t.Errorf("generate with the work order open = %+v, %v", again, err)
+	}
+	manual := models.MaintenanceWorkOrder{UnitID: 1, Description: "Noisy bearing"}
+	if err := s.Open(ctx, &manual); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unit stays in maintenance until its last work order closes
+	if _, err := s.Cancel(ctx, manual.WorkOrderID); err != nil || status() != UnitMaintenance {
+		t.Errorf("after cancelling one of two: %v, unit %s", err, status())
+	}
+	done, err := s.Complete(ctx, raised[0].WorkOrderID, Completion{DowntimeHours: 2})
+	if err != nil || done.MaintenanceID == nil || status() != UnitActive {
+		t.Fatalf("complete = %+v, %v; unit %s", done, err, status())
+	}
+	if _, err := s.Cancel(ctx, raised[0].WorkOrderID); err == nil {
+		t.Error("a completed work order was cancelled")
+	}
+	restarted, err := s.plan(ctx, plan.PlanID)
+	if err != nil || restarted.LastDoneAt == "2020-01-01" {
+		t.Errorf("plan after service = %+v, %v", restarted, err)
+	}
+}
+
+func TestUnitOEE(t *testing.T) {
+	ctx := context.Background()
+	repos, s := newPlant(t)
+	now := time.Now().UTC()
+	today := now.Format("2006-01-02")
+	from, to := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+
+	empty, err := s.UnitOEE(ctx, 1, from, to)
+	if err != nil || empty.Availability != nil || empty.OEE != nil {
+		t.Errorf("OEE without orders = %+v, %v", empty, err)
+	}
+
+	for _, po := range []models.ProcessingOrder{
+		{UnitID: 1, Status: processing.StatusCompleted, EndDate: today, DurationHours: 6, OutputQuantity: 50},
+		{UnitID: 1, Status: processing.StatusClosed, EndDate: today, DurationHours: 4, OutputQuantity: 30},
+		{UnitID: 1, Status: processing.StatusCompleted, EndDate: "2020-01-01", DurationHours: 8, OutputQuantity: 80},
+		{UnitID: 1, Status: processing.StatusPlanned, DurationHours: 8},
+	} {
+		if err := repos.Processing.CreateProcessingOrder(ctx, &po); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := 2
+	if err := repos.Quality.CreateQualityInspection(ctx, &models.QualityInspection{ProcessingID: &second, Result: "Fail"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Processing.CreateMaintenanceRecord(ctx, &models.MaintenanceRecord{UnitID: 1, MaintenanceDate: today, DowntimeHours: 2.5}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 h run and 2.5 h down; 80 of an ideal 100 made, 30 of it failed
+	oee, err := s.UnitOEE(ctx, 1, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oee.Orders != 2 || oee.RunHours != 10 || oee.RejectedQuantity != 30 || oee.FailedInspections != 1 {
+		t.Errorf("figures = %+v", oee)
+	}
+	for _, ratio := range []struct {
+		name string
+		got  *float64
+		want float64
+	}{{"availability", oee.Availability, 80}, {"performance", oee.Performance, 80}, {"quality", oee.Quality, 62.5}, {"oee", oee.OEE, 40}} {
+		if ratio.got == nil || *ratio.got != ratio.want {
+			t.Errorf("%s = %v, want %v", ratio.name, ratio.got, ratio.want)
+		}
+	}
+	if hours, err := s.OperatingHours(ctx, 1); err != nil || hours != 18 {
+		t.Errorf("operating hours = %v, %v", hours, err)
+	}
+}
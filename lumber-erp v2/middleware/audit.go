@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/auth"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/utils"
+)
+
+// AuditEntity describes the rows a route changes
+type AuditEntity struct {
+	Name string   // table recorded as EntityAffected
+	Key  []string // JSON fields identifying a row, e.g. forest_id
+	// ID reads the ID of the row a request targets, "" when it names none
+	ID func(r *http.Request) string
+	// Load returns the row with the given ID, or nil when there is none
+	Load func(ctx context.Context, id string) (interface{}, error)
+}
+
+// MaxAuditedBody caps the request body an audited route reads, and
+// MaxAuditedResponse the response it holds until its transaction commits
+const (
+	MaxAuditedBody     = 10 << 20
+	MaxAuditedResponse = 10 << 20
+)
+
+var (
+	// errDiscarded rolls back a request the handler refused
+	errDiscarded = errors.New("request failed")
+	// errResponseTooLarge rolls back a request whose response could not be
+	// held until it committed
+	errResponseTooLarge = errors.New("response too large to audit")
+)
+
+// Audit records every successful POST, PUT and DELETE handled by next in
+// the AuditLog, with the caller, client IP and a before/after diff of the
+// row. The change and its entry commit in one transaction, so a change is
+// rolled back when its entry cannot be written.
+func Audit(tx repository.Transactor, logs repository.AuditRepository, entity AuditEntity, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		action := ActionForMethod(r.Method)
+		if r.Method != "POST" && r.Method != "PUT" && r.Method != "DELETE" {
+			next(w, r)
+			return
+		}
+
+		// The body is kept so a created row's key can be read from it when
+		// the response does not repeat it
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxAuditedBody))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.RespondError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		buf := newBufferedWriter(w)
+		err = tx.WithinTx(r.Context(), func(ctx context.Context) error {
+			r := r.WithContext(ctx)
+			id := entity.ID(r)
+			var before interface{}
+			if id != "" && r.Method != "POST" {
+				var err error
+				if before, err = entity.Load(ctx, id); err != nil {
+					return err
+				}
+			}
+
+			next(buf, r)
+			if buf.overflow {
+				return errResponseTooLarge
+			}
+			if buf.status >= http.StatusMultipleChoices {
+				return errDiscarded
+			}
+
+			if id == "" {
+				if id = keyOf(buf.body.Bytes(), entity.Key); id == "" {
+					id = keyOf(body, entity.Key)
+				}
+			}
+			var after interface{}
+			if id != "" && r.Method != "DELETE" {
+				var err error
+				if after, err = entity.Load(ctx, id); err != nil {
+					return err
+				}
+			}
+			changes, err := diff(before, after)
+			if err != nil {
+				return err
+			}
+
+			entry := models.AuditLog{
+				ActionType:     action,
+				EntityAffected: entity.Name,
+				EntityID:       id,
+				Description:    r.Method + " " + r.URL.RequestURI(),
+				IPAddress:      utils.ClientIP(r),
+				Changes:        changes,
+			}
+			if claims := auth.ClaimsFromContext(ctx); claims != nil {
+				entry.UserID = claims.UserID
+			}
+			return logs.CreateAuditLog(ctx, &entry)
+		})
+		if err == errResponseTooLarge {
+			utils.RespondError(w, http.StatusInternalServerError, "Response too large to audit")
+			return
+		}
+		if err != nil && err != errDiscarded {
+			apierr.Respond(w, err)
+			return
+		}
+		buf.flush(w)
+	}
+}
+
+// keyOf joins the key fields of a JSON object with "/", returning "" when
+// any is missing. The keys of an array of objects, such as the rows a batch
+// run raised, are joined with "," without repeats.
+func keyOf(body []byte, key []string) string {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&value) != nil {
+		return ""
+	}
+	rows, ok := value.([]interface{})
+	if !ok {
+		return rowKey(value, key)
+	}
+	var ids []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		id := rowKey(row, key)
+		if id == "" {
+			return ""
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+// rowKey joins the key fields of one decoded JSON object
+func rowKey(row interface{}, key []string) string {
+	fields, ok := row.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	parts := make([]string, len(key))
+	for i, name := range key {
+		value, ok := fields[name]
+		if !ok || value == nil {
+			return ""
+		}
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, "/")
+}
+
+// diff renders {"before": ..., "after": ...}. For updates only the fields
+// that changed are kept; creates have no before and deletes no after.
+func diff(before, after interface{}) (json.RawMessage, error) {
+	b, err := jsonValue(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := jsonValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	was, okBefore := b.(map[string]interface{})
+	is, okAfter := a.(map[string]interface{})
+	if okBefore && okAfter {
+		for name, value := range is {
+			if previous, ok := was[name]; ok && reflect.DeepEqual(previous, value) {
+				delete(was, name)
+				delete(is, name)
+			}
+		}
+	}
+
+	changes := map[string]interface{}{}
+	if b != nil {
+		changes["before"] = b
+	}
+	if a != nil {
+		changes["after"] = a
+	}
+	return json.Marshal(changes)
+}
+
+// jsonValue converts v to the maps and slices it marshals to
+func jsonValue(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// bufferedWriter holds a response until the transaction it belongs to has
+// committed. It starts with the headers already set, such as X-Request-ID.
+// Writes past MaxAuditedResponse fail and mark it overflowed.
+type bufferedWriter struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func newBufferedWriter(w http.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{header: w.Header().Clone(), status: http.StatusOK}
+}
+
+func (b *bufferedWriter) Header() http.Header { return b.header }
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	if b.overflow || b.body.Len()+len(p) > MaxAuditedResponse {
+		b.overflow = true
+		return 0, errResponseTooLarge
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedWriter) WriteHeader(status int) { b.status = status }
+
+func (b *bufferedWriter) flush(w http.ResponseWriter) {
+	for name, values := range b.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
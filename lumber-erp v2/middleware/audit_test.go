@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+)
+
+func TestAuditLimits(t *testing.T) {
+	repos := repository.NewMemory()
+	entity := AuditEntity{
+		Name: "Forest",
+		Key:  []string{"forest_id"},
+		ID:   func(*http.Request) string { return "" },
+		Load: func(context.Context, string) (interface{}, error) { return map[string]int{"forest_id": 1}, nil },
+	}
+	respond := func(size int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"forest_id":1,"notes":"` + strings.Repeat("x", size) + `"}`))
+		}
+	}
+	entries := func() int {
+		page, err := repos.Audit.ListAuditLogs(context.Background(), query.Spec{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.Total
+	}
+
+	for _, tc := range []struct {
+		name     string
+		body     int
+		response int
+		status   int
+		entries  int
+	}{
+		{"within the limits", 10, 10, http.StatusCreated, 1},
+		{"request too large", MaxAuditedBody + 1, 10, http.StatusRequestEntityTooLarge, 1},
+		{"response too large", 10, MaxAuditedResponse, http.StatusInternalServerError, 1},
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v2/forests", strings.NewReader(strings.Repeat(" ", tc.body)))
+		Audit(repos.Tx, repos.Audit, entity, respond(tc.response))(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+		}
+		if rec.Body.Len() > MaxAuditedResponse {
+			t.Errorf("%s: answered %d bytes", tc.name, rec.Body.Len())
+		}
+		if n := entries(); n != tc.entries {
+			t.Errorf("%s: %d audit entries, want %d", tc.name, n, tc.entries)
+		}
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	for body, want := range map[string]string{
+		`{"plan_id":3,"unit_id":1}`: "3/1",
+		`{"plan_id":3}`:             "",
+		`[{"plan_id":3,"unit_id":1},{"plan_id":4,"unit_id":1},{"plan_id":3,"unit_id":1}]`: "3/1,4/1",
+		`[{"plan_id":3,"unit_id":1},{"plan_id":null,"unit_id":1}]`:                        "",
+		`[]`:       "",
+		`not json`: "",
+	} {
+		if got := keyOf([]byte(body), []string{"plan_id", "unit_id"}); got != want {
+			t.Errorf("keyOf(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"lumber-erp-api/apierr"
+	"lumber-erp-api/auth"
+	"lumber-erp-api/models"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/utils"
+)
+
+// Actions stored in Permission.ActionType
+const (
+	ActionRead   = "READ"
+	ActionCreate = "CREATE"
+	ActionUpdate = "UPDATE"
+	ActionDelete = "DELETE"
+)
+
+// PermissionChecker resolves whether a user's roles grant an action on a
+// module; repository.AccessRepository satisfies it
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID int, module, action string) (bool, error)
+}
+
+// SessionChecker looks up the session an access token was issued for;
+// repository.SessionRepository satisfies it
+type SessionChecker interface {
+	GetSession(ctx context.Context, sessionID, userID int) (models.UserSession, error)
+}
+
+// ActionForMethod maps an HTTP method to the permission action it requires
+func ActionForMethod(method string) string {
+	switch method {
+	case "POST":
+		return ActionCreate
+	case "PUT", "PATCH":
+		return ActionUpdate
+	case "DELETE":
+		return ActionDelete
+	default:
+		return ActionRead
+	}
+}
+
+// RequireAuth rejects requests without a valid bearer access token and stores
+// the caller's claims in the request context. A token stops working once its
+// session is revoked, by logging out or changing the password, even before
+// it expires.
+func RequireAuth(sessions SessionChecker, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		utils.EnableCORS(&w)
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		token := auth.BearerToken(r.Header.Get("Authorization"))
+		if token == "" {
+			respondDenied(w, http.StatusUnauthorized, "missing_token", "Authentication required", "", "")
+			return
+		}
+
+		claims, err := auth.ParseToken(token, auth.TokenTypeAccess)
+		if err == auth.ErrExpiredToken {
+			respondDenied(w, http.StatusUnauthorized, "token_expired", "Access token has expired", "", "")
+			return
+		}
+		if err != nil {
+			respondDenied(w, http.StatusUnauthorized, "invalid_token", "Access token is invalid", "", "")
+			return
+		}
+
+		session, err := sessions.GetSession(r.Context(), claims.SessionID, claims.UserID)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && session.RevokedAt != nil) {
+			respondDenied(w, http.StatusUnauthorized, "session_revoked", "Session has ended", "", "")
+			return
+		}
+		if err != nil {
+			apierr.Respond(w, err)
+			return
+		}
+
+		next(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	}
+}
+
+// RequirePermission authenticates the caller and checks that one of their roles
+// grants the action implied by the HTTP method on the given module
+func RequirePermission(sessions SessionChecker, checker PermissionChecker, module string, next http.HandlerFunc) http.HandlerFunc {
+	return RequireAuth(sessions, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		claims := auth.ClaimsFromContext(r.Context())
+		action := ActionForMethod(r.Method)
+
+		allowed, err := checker.HasPermission(r.Context(), claims.UserID, module, action)
+		if err != nil {
+			apierr.Respond(w, err)
+			return
+		}
+		if !allowed {
+			respondDenied(w, http.StatusForbidden, "missing_permission",
+				"You do not have "+action+" permission on "+module, module, action)
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+func respondDenied(w http.ResponseWriter, status int, reason, message, module, action string) {
+	var details interface{}
+	if module != "" {
+		details = map[string]string{"module": module, "action": action}
+	}
+	utils.RespondErrorCode(w, status, reason, message, details)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"lumber-erp-api/config"
+	"lumber-erp-api/migrations"
+)
+
+const migrateUsage = `usage: lumber-erp-api [-config file] migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down      roll back the most recent migration
+  status    list migrations and whether they are applied
+  to N      migrate up or down to version N (0 rolls back everything)`
+
+// runMigrate implements the "migrate" subcommand and returns the exit code
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	logStep := func(msg string) { fmt.Println("✅", msg) }
+
+	var err error
+	switch args[0] {
+	case "up":
+		err = migrations.Up(config.DB, logStep)
+	case "down":
+		err = migrations.Down(config.DB, logStep)
+	case "to":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		target, convErr := strconv.Atoi(args[1])
+		if convErr != nil || target < 0 {
+			fmt.Fprintf(os.Stderr, "❌ invalid version %q\n", args[1])
+			return 2
+		}
+		err = migrations.To(config.DB, target, logStep)
+	case "status":
+		err = printMigrationStatus()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
+
+func printMigrationStatus() error {
+	statuses, err := migrations.StatusOf(config.DB)
+	if err != nil {
+		return err
+	}
+	current, err := migrations.Current(config.DB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Schema version: %d\n\n", current)
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %04d  %-32s %s  %s\n", s.Version, s.Name, s.Checksum()[:12], applied)
+	}
+	return nil
+}
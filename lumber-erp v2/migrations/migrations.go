@@ -0,0 +1,329 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the pg_advisory_lock key that serializes concurrent migrators
+const lockID = 7294316021
+
+// Migration is one numbered schema change with its up and down SQL
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied and when
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Checksum is the SHA-256 of the migration's up and down SQL. A released
+// migration must keep its checksum; change the schema with a new one.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\x00" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+// All returns the embedded migrations ordered by version. Files are named
+// NNNN_description.up.sql and NNNN_description.down.sql.
+func All() ([]Migration, error) {
+	return read(files)
+}
+
+// read loads the migrations in the sql directory of fsys
+func read(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version number", name)
+		}
+
+		body, err := fs.ReadFile(fsys, "sql/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all, nil
+}
+
+// Latest returns the highest embedded migration version
+func Latest() (int, error) {
+	all, err := All()
+	if err != nil || len(all) == 0 {
+		return 0, err
+	}
+	return all[len(all)-1].Version, nil
+}
+
+// conn is satisfied by both *sql.DB and the pinned *sql.Conn used while
+// holding the migration lock
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ensureTable creates the tracking table on first use
+func ensureTable(db conn) error {
+	_, err := db.ExecContext(context.Background(), `CREATE TABLE IF NOT EXISTS schema_migrations (
+                        version INTEGER PRIMARY KEY,
+                        name VARCHAR(200) NOT NULL,
+                        applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)`)
+	return err
+}
+
+// applied returns the applied versions and their timestamps
+func applied(db conn) (map[int]time.Time, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(context.Background(), `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		versions[version] = at
+	}
+	return versions, rows.Err()
+}
+
+// Current returns the highest applied version, or 0 for an empty database
+func Current(db *sql.DB) (int, error) {
+	if err := ensureTable(db); err != nil {
+		return 0, err
+	}
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// StatusOf lists every embedded migration and whether it has been applied
+func StatusOf(db *sql.DB) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(all))
+	for i, m := range all {
+		statuses[i].Migration = m
+		if at, ok := done[m.Version]; ok {
+			at := at
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func Pending(db *sql.DB) ([]Migration, error) {
+	statuses, err := StatusOf(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Check returns an error when the database schema is behind the binary
+func Check(db *sql.DB) error {
+	pending, err := Pending(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	names := make([]string, len(pending))
+	for i, m := range pending {
+		names[i] = fmt.Sprintf("%04d_%s", m.Version, m.Name)
+	}
+	return fmt.Errorf("database schema is behind, %d pending migration(s): %s",
+		len(pending), strings.Join(names, ", "))
+}
+
+// Up applies every pending migration
+func Up(db *sql.DB, log func(string)) error {
+	latest, err := Latest()
+	if err != nil {
+		return err
+	}
+	return To(db, latest, log)
+}
+
+// Down rolls back the most recently applied migration
+func Down(db *sql.DB, log func(string)) error {
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		log("nothing to roll back")
+		return nil
+	}
+
+	statuses, err := StatusOf(db)
+	if err != nil {
+		return err
+	}
+	target := 0
+	for _, s := range statuses {
+		if s.AppliedAt != nil && s.Version < current {
+			target = s.Version
+		}
+	}
+	return To(db, target, log)
+}
+
+// To migrates up or down until exactly the migrations up to target are applied
+func To(db *sql.DB, target int, log func(string)) error {
+	all, err := All()
+	if err != nil {
+		return err
+	}
+	if target != 0 {
+		found := false
+		for _, m := range all {
+			found = found || m.Version == target
+		}
+		if !found {
+			return fmt.Errorf("no migration with version %d", target)
+		}
+	}
+
+	// Advisory locks belong to a session, so pin one connection for the run
+	ctx := context.Background()
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if _, err := c.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer c.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockID)
+
+	done, err := applied(c)
+	if err != nil {
+		return err
+	}
+
+	// Roll back newest first, then apply oldest first
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if _, ok := done[m.Version]; ok && m.Version > target {
+			if err := run(c, m, false); err != nil {
+				return err
+			}
+			log(fmt.Sprintf("rolled back %04d_%s", m.Version, m.Name))
+		}
+	}
+	for _, m := range all {
+		if _, ok := done[m.Version]; !ok && m.Version <= target {
+			if err := run(c, m, true); err != nil {
+				return err
+			}
+			log(fmt.Sprintf("applied %04d_%s", m.Version, m.Name))
+		}
+	}
+	return nil
+}
+
+// run executes one migration and records it in the same transaction
+func run(db conn, m Migration, up bool) error {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	script, record, args := m.Down, `DELETE FROM schema_migrations WHERE version = $1`, []interface{}{m.Version}
+	if up {
+		script, record, args = m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, []interface{}{m.Version, m.Name}
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// released pins the checksum of every migration that has shipped. Add the
+// checksum of a new migration here once it is released; never change one.
+var released = map[int]string{
+	1:  "2b2c2a33ac3d0d21020b5c3cf95c280cac4d007e2408dc7e6ec235299adbf0ac",
+	2:  "a817b107a27962595f24ca75d4757999294f03d4cdc4315dd1eb342c0749d10a",
+	3:  "51c8e0baa8717a8f7069217dfb14ab192f8e98eca18e0050851d5d3ef025a893",
+	4:  "99a3fd5401cfe3af5faee06c163e554f5c0e73ab3b178375320fc159f5dfe1f7",
+	5:  "828a15afa5d6ee990d154cee9d44fad7887c00c95e1b3adad78e83291cfe9ba7",
+	6:  "e6c9f85e2e2f6259f7bf5f68ffd78e3b562e1d9cfb3033a3afccdc016fe469dc",
+	7:  "33e960bdd86200daeafcd7358026f4fb45c9851fc0f5bbcdfa14325edd60ed50",
+	8:  "f03cab3fc931f1c4091fca72a93af6225a3c02c3fd85316dc70dd1a7e961434c",
+	9:  "3f4e226f08ceb8dbe857f4840a5daefca1fa0c5c7a20315ab1bf45b068c41b70",
+	10: "dddf0cc652a10ef01968a4ce8c2a9cbfe9bbb12f711d474d872dfbb9ee84fa95",
+	11: "4599e0aa30052214d2acb8c9f43cf2b674b7e5175c24fd5bd8311f61fd21a64a",
+	12: "9f025df7cd54eb6f514151d91403ba11c56b5941849094a506535393484ec79e",
+	13: "1e23f3ec9258254f65f0cb575a942842acf86fecdfcc93367095a0c069192317",
+	14: "e0b0e9c8bfce211183bd13fc9054d456d15bd26cf127102e7d321598482d2cff",
+	15: "73862a8f06cb84e7794040e55fae07f04b16c2524759f4e81fdd44fbe9d452f6",
+	16: "438eb94ccfa34bc36a4b72dcf2a10989ab1dfccda5a729e0f05396ee3b733de3",
+	17: "bd550a120114cf64982358cac748100951795da56cd4f579dab94be028561640",
+	18: "d67e44d03cd91698f300d77ffe2350260d0cd7613d426a92b0a1bf995acd6fbe",
+	19: "d3b97fe93270e3019b1e2188bb34c4a43a00f4f5cb4a999477209505bfd195ac",
+	20: "252d0d8c63efe316f130c66452d44f0afc90bed51e3673861374012182b2d2d1",
+	21: "b73de1fe823620922a75eb918202370eac5baad7771e750a92224823a2bbbcc5",
+}
+
+func TestAll(t *testing.T) {
+	all, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d is %04d_%s; versions must run from 1 without gaps", i+1, m.Version, m.Name)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("%04d_%s has an empty up or down script", m.Version, m.Name)
+		}
+		want, ok := released[m.Version]
+		if !ok {
+			t.Errorf("%04d_%s: pin its checksum %s in released", m.Version, m.Name, m.Checksum())
+		} else if m.Checksum() != want {
+			t.Errorf("%04d_%s changed after release; add a new migration instead", m.Version, m.Name)
+		}
+	}
+	if len(all) != len(released) {
+		t.Errorf("%d migrations embedded, %d released", len(all), len(released))
+	}
+}
+
+func TestReadOrder(t *testing.T) {
+	all, err := read(fstest.MapFS{
+		"sql/10_later.up.sql":    {Data: []byte("up 10")},
+		"sql/10_later.down.sql":  {Data: []byte("down 10")},
+		"sql/9_earlier.up.sql":   {Data: []byte("up 9")},
+		"sql/9_earlier.down.sql": {Data: []byte("down 9")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Version != 9 || all[0].Name != "earlier" || all[1].Up != "up 10" {
+		t.Errorf("all = %+v", all)
+	}
+	if all[0].Checksum() == all[1].Checksum() {
+		t.Error("different migrations share a checksum")
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files fstest.MapFS
+		err   string
+	}{
+		"wrong suffix": {fstest.MapFS{"sql/1_a.sql": {}}, "expected .up.sql or .down.sql"},
+		"no version":   {fstest.MapFS{"sql/a_b.up.sql": {}}, "positive version number"},
+		"two names": {fstest.MapFS{"sql/1_a.up.sql": {}, "sql/1_b.down.sql": {}},
+			"has two names"},
+		"no down": {fstest.MapFS{"sql/1_a.up.sql": {Data: []byte("up")}}, "needs both an up and a down file"},
+	} {
+		if _, err := read(tc.files); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: err = %v, want %q", name, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+-- Drops every baseline table, newest first
+
+DROP TABLE IF EXISTS SupplierContract CASCADE;
+DROP TABLE IF EXISTS SupplierPerformance CASCADE;
+DROP TABLE IF EXISTS Management_Insights CASCADE;
+DROP TABLE IF EXISTS User_Employee_Assignment CASCADE;
+DROP TABLE IF EXISTS AuditLog CASCADE;
+DROP TABLE IF EXISTS FuelLog CASCADE;
+DROP TABLE IF EXISTS WorkerAssignment CASCADE;
+DROP TABLE IF EXISTS MaintenanceRecord CASCADE;
+DROP TABLE IF EXISTS WasteRecord CASCADE;
+DROP TABLE IF EXISTS HarvestBatch_Processing CASCADE;
+DROP TABLE IF EXISTS Payment CASCADE;
+DROP TABLE IF EXISTS Invoice CASCADE;
+DROP TABLE IF EXISTS InventoryTransaction CASCADE;
+DROP TABLE IF EXISTS StockAlert CASCADE;
+DROP TABLE IF EXISTS QualityInspection CASCADE;
+DROP TABLE IF EXISTS Shipment CASCADE;
+DROP TABLE IF EXISTS Route CASCADE;
+DROP TABLE IF EXISTS Driver CASCADE;
+DROP TABLE IF EXISTS Truck CASCADE;
+DROP TABLE IF EXISTS SalesOrderItem CASCADE;
+DROP TABLE IF EXISTS SalesOrder CASCADE;
+DROP TABLE IF EXISTS PurchaseOrderItem CASCADE;
+DROP TABLE IF EXISTS PurchaseOrder CASCADE;
+DROP TABLE IF EXISTS StockItem CASCADE;
+DROP TABLE IF EXISTS ProcessingOrder CASCADE;
+DROP TABLE IF EXISTS ProcessingUnit CASCADE;
+DROP TABLE IF EXISTS HarvestBatch CASCADE;
+DROP TABLE IF EXISTS HarvestSchedule CASCADE;
+DROP TABLE IF EXISTS TransportCompany CASCADE;
+DROP TABLE IF EXISTS Sawmill CASCADE;
+DROP TABLE IF EXISTS Warehouse CASCADE;
+DROP TABLE IF EXISTS TreeSpecies CASCADE;
+DROP TABLE IF EXISTS Forest CASCADE;
+DROP TABLE IF EXISTS Customer CASCADE;
+DROP TABLE IF EXISTS ProductType CASCADE;
+DROP TABLE IF EXISTS Supplier CASCADE;
+DROP TABLE IF EXISTS Employee CASCADE;
+DROP TABLE IF EXISTS Role_Permission CASCADE;
+DROP TABLE IF EXISTS Role CASCADE;
+DROP TABLE IF EXISTS Permission CASCADE;
+DROP TABLE IF EXISTS "User" CASCADE;
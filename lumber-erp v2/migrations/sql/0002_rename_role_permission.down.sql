@@ -0,0 +1 @@
+ALTER TABLE RolePermission RENAME TO Role_Permission;
@@ -0,0 +1,15 @@
+-- The handlers and permission middleware use RolePermission; the original
+-- script created Role_Permission. Databases patched by hand may already have
+-- the new name, so only rename when needed.
+
+DO $$
+BEGIN
+    IF to_regclass('role_permission') IS NOT NULL AND to_regclass('rolepermission') IS NULL THEN
+        ALTER TABLE Role_Permission RENAME TO RolePermission;
+    ELSIF to_regclass('role_permission') IS NOT NULL THEN
+        INSERT INTO RolePermission (Role_ID, PermissionID)
+        SELECT Role_ID, PermissionID FROM Role_Permission
+        ON CONFLICT DO NOTHING;
+        DROP TABLE Role_Permission;
+    END IF;
+END $$;
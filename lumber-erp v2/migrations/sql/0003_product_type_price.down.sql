@@ -0,0 +1,5 @@
+UPDATE ProductType
+SET Description = to_char(Price, 'FM999999999990.00')
+WHERE Description IS NULL OR Description = '';
+
+ALTER TABLE ProductType DROP COLUMN Price;
@@ -0,0 +1,14 @@
+-- ProductType had no price column, so the API stored the unit price as text in
+-- Description. Move numeric descriptions into the new column.
+
+ALTER TABLE ProductType ADD COLUMN Price DECIMAL(12,2) NOT NULL DEFAULT 0;
+
+-- Only plain decimals that fit DECIMAL(12,2) once rounded are moved; the
+-- CASE keeps the cast from running on anything else. Descriptions that do
+-- not qualify stay in Description, with Price left at 0, so no value is
+-- lost and none aborts the migration.
+UPDATE ProductType
+SET Price = Description::DECIMAL(12,2), Description = NULL
+WHERE CASE WHEN Description ~ '^\s*-?[0-9]{1,10}(\.[0-9]+)?\s*$'
+           THEN abs(Description::NUMERIC) < 9999999999.995
+           ELSE FALSE END;
@@ -0,0 +1,14 @@
+-- Refresh-token sessions used by /api/auth/login, /refresh and /logout
+
+CREATE TABLE IF NOT EXISTS UserSession (
+    SessionID SERIAL PRIMARY KEY,
+    User_ID INTEGER REFERENCES "User"(User_ID) ON DELETE CASCADE,
+    RefreshTokenHash VARCHAR(64) NOT NULL,
+    IPAddress VARCHAR(45),
+    CreatedAt TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    LastUsedAt TIMESTAMP,
+    ExpiresAt TIMESTAMP NOT NULL,
+    RevokedAt TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_usersession_user ON UserSession (User_ID);
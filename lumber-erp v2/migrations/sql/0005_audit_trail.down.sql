@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_auditlog_entity;
+
+ALTER TABLE AuditLog DROP COLUMN Changes;
+ALTER TABLE AuditLog DROP COLUMN EntityID;
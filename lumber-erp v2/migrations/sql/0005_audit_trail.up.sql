@@ -0,0 +1,7 @@
+-- Audit entries written automatically for every create, update and delete
+-- name the row they touched and carry its before/after diff
+
+ALTER TABLE AuditLog ADD COLUMN EntityID VARCHAR(100);
+ALTER TABLE AuditLog ADD COLUMN Changes JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_auditlog_entity ON AuditLog (EntityAffected, EntityID);
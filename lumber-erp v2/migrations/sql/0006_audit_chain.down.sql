@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS auditcheckpoint_append_only ON AuditCheckpoint;
+DROP TRIGGER IF EXISTS auditlog_append_only ON AuditLog;
+DROP FUNCTION IF EXISTS audit_append_only();
+
+DROP TABLE IF EXISTS AuditCheckpoint;
+
+ALTER TABLE AuditLog ADD CONSTRAINT auditlog_user_id_fkey
+    FOREIGN KEY (User_ID) REFERENCES "User"(User_ID) ON DELETE SET NULL NOT VALID;
+
+ALTER TABLE AuditLog DROP COLUMN Hash;
+ALTER TABLE AuditLog DROP COLUMN PrevHash;
@@ -0,0 +1,28 @@
+-- Tamper-evident audit trail: every AuditLog entry stores the SHA-256 of its
+-- content and of the entry before it, and checkpoints sign the chain head
+
+ALTER TABLE AuditLog ADD COLUMN PrevHash CHAR(64);
+ALTER TABLE AuditLog ADD COLUMN Hash CHAR(64);
+
+-- Entries outlive the users they name; ON DELETE SET NULL would rewrite them
+ALTER TABLE AuditLog DROP CONSTRAINT IF EXISTS auditlog_user_id_fkey;
+
+CREATE TABLE IF NOT EXISTS AuditCheckpoint (
+    CheckpointID SERIAL PRIMARY KEY,
+    LogID INTEGER NOT NULL,
+    Hash CHAR(64) NOT NULL,
+    CreatedAt TIMESTAMP NOT NULL,
+    Signature TEXT NOT NULL
+);
+
+-- The API only appends; refuse edits made behind its back as well
+CREATE OR REPLACE FUNCTION audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER auditlog_append_only BEFORE UPDATE OR DELETE ON AuditLog
+    FOR EACH ROW EXECUTE FUNCTION audit_append_only();
+CREATE TRIGGER auditcheckpoint_append_only BEFORE UPDATE OR DELETE ON AuditCheckpoint
+    FOR EACH ROW EXECUTE FUNCTION audit_append_only();
@@ -0,0 +1,5 @@
+ALTER TABLE InventoryTransaction DROP CONSTRAINT IF EXISTS inventorytransaction_type_check;
+ALTER TABLE InventoryTransaction DROP COLUMN ReversesID;
+ALTER TABLE InventoryTransaction DROP COLUMN BalanceAfter;
+
+ALTER TABLE Warehouse DROP COLUMN AllowNegativeStock;
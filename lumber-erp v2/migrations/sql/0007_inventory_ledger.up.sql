@@ -0,0 +1,13 @@
+-- Inventory transactions move StockItem.Quantity and are never edited:
+-- each stores the stock balance it left behind, and corrections are
+-- reversing entries that point at the transaction they undo.
+
+ALTER TABLE Warehouse ADD COLUMN AllowNegativeStock BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE InventoryTransaction ADD COLUMN BalanceAfter DECIMAL(10,2);
+ALTER TABLE InventoryTransaction ADD COLUMN ReversesID INTEGER UNIQUE
+    REFERENCES InventoryTransaction(TransactionID);
+
+-- Earlier free-form types such as IN and OUT are left as recorded
+ALTER TABLE InventoryTransaction ADD CONSTRAINT inventorytransaction_type_check
+    CHECK (TransactionType IN ('receipt', 'issue', 'adjustment', 'transfer_out', 'transfer_in', 'scrap')) NOT VALID;
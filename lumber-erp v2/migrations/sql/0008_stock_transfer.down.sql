@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS StockTransferLine;
+DROP TABLE IF EXISTS StockTransfer;
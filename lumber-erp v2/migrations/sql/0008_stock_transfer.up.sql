@@ -0,0 +1,35 @@
+-- Stock moves between warehouses on transfer documents. Dispatching posts a
+-- transfer_out per line and receiving a transfer_in into the stock item of
+-- the same product and batch at the destination, so the ledger keeps the
+-- history that editing StockItem.WarehouseID lost.
+
+CREATE TABLE IF NOT EXISTS StockTransfer (
+    TransferID SERIAL PRIMARY KEY,
+    FromWarehouseID INTEGER NOT NULL REFERENCES Warehouse(WarehouseID),
+    ToWarehouseID INTEGER NOT NULL REFERENCES Warehouse(WarehouseID),
+    Status VARCHAR(20) NOT NULL DEFAULT 'draft'
+        CHECK (Status IN ('draft', 'dispatched', 'in_transit', 'received')),
+    ShipmentID INTEGER REFERENCES Shipment(ShipmentID) ON DELETE SET NULL,
+    TruckID INTEGER REFERENCES Truck(TruckID) ON DELETE SET NULL,
+    Remarks TEXT,
+    CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    DispatchedAt TIMESTAMP,
+    InTransitAt TIMESTAMP,
+    ReceivedAt TIMESTAMP,
+    CHECK (FromWarehouseID <> ToWarehouseID)
+);
+
+CREATE TABLE IF NOT EXISTS StockTransferLine (
+    LineID SERIAL PRIMARY KEY,
+    TransferID INTEGER NOT NULL REFERENCES StockTransfer(TransferID) ON DELETE CASCADE,
+    StockID INTEGER NOT NULL REFERENCES StockItem(StockID),
+    Quantity DECIMAL(10,2) NOT NULL CHECK (Quantity > 0),
+    ReceivedQuantity DECIMAL(10,2) CHECK (ReceivedQuantity >= 0),
+    DestStockID INTEGER REFERENCES StockItem(StockID),
+    OutTransactionID INTEGER REFERENCES InventoryTransaction(TransactionID),
+    InTransactionID INTEGER REFERENCES InventoryTransaction(TransactionID),
+    DiscrepancyNote TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_stocktransfer_status ON StockTransfer (Status);
+CREATE INDEX IF NOT EXISTS idx_stocktransferline_transfer ON StockTransferLine (TransferID);
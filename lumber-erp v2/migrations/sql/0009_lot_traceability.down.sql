@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_harvestbatch_processing_batch;
+DROP INDEX IF EXISTS idx_salesorderitem_stock;
+DROP INDEX IF EXISTS idx_stockitem_processing;
+DROP INDEX IF EXISTS idx_stockitem_batch;
+
+ALTER TABLE SalesOrderItem DROP COLUMN StockID;
+ALTER TABLE StockItem DROP COLUMN ProcessingID;
@@ -0,0 +1,13 @@
+-- Stock is held per lot: a StockItem carries the HarvestBatch it was cut
+-- from or the ProcessingOrder whose output it is, and a SalesOrderItem the
+-- stock item it was picked from, so a lot can be traced to the customer.
+
+ALTER TABLE StockItem ADD COLUMN ProcessingID INTEGER
+    REFERENCES ProcessingOrder(ProcessingID) ON DELETE SET NULL;
+ALTER TABLE SalesOrderItem ADD COLUMN StockID INTEGER
+    REFERENCES StockItem(StockID) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_stockitem_batch ON StockItem (BatchID);
+CREATE INDEX IF NOT EXISTS idx_stockitem_processing ON StockItem (ProcessingID);
+CREATE INDEX IF NOT EXISTS idx_salesorderitem_stock ON SalesOrderItem (StockID);
+CREATE INDEX IF NOT EXISTS idx_harvestbatch_processing_batch ON HarvestBatch_Processing (BatchID);
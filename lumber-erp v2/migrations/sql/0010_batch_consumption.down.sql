@@ -0,0 +1 @@
+ALTER TABLE HarvestBatch_Processing DROP COLUMN ConsumedQuantity;
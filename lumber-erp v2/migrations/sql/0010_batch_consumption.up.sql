@@ -0,0 +1,5 @@
+-- Processing orders record how much of each harvest batch they consumed, so
+-- a batch cannot be consumed beyond its harvested quantity.
+
+ALTER TABLE HarvestBatch_Processing ADD COLUMN ConsumedQuantity DECIMAL(10,2) NOT NULL DEFAULT 0
+    CHECK (ConsumedQuantity >= 0);
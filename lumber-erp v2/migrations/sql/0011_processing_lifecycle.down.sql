@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_processingorder_status;
+
+ALTER TABLE ProcessingOrder
+    DROP COLUMN MassBalanceFlagged,
+    DROP COLUMN MassBalanceVariance,
+    DROP COLUMN WasteQuantity,
+    DROP COLUMN InputQuantity,
+    DROP COLUMN WarehouseID,
+    DROP COLUMN Status,
+    ALTER COLUMN EfficiencyRate TYPE DECIMAL(5,2);
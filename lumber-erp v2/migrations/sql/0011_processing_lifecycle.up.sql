@@ -0,0 +1,18 @@
+-- Processing orders go planned → released → in_progress → completed →
+-- closed. Completion records the volumes the server computed yield and
+-- mass balance from; WarehouseID receives the output.
+
+ALTER TABLE ProcessingOrder
+    ADD COLUMN Status VARCHAR(20) NOT NULL DEFAULT 'planned'
+        CHECK (Status IN ('planned', 'released', 'in_progress', 'completed', 'closed')),
+    ADD COLUMN WarehouseID INTEGER REFERENCES Warehouse(WarehouseID) ON DELETE SET NULL,
+    ADD COLUMN InputQuantity DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN WasteQuantity DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN MassBalanceVariance DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN MassBalanceFlagged BOOLEAN NOT NULL DEFAULT FALSE,
+    ALTER COLUMN EfficiencyRate TYPE DECIMAL(7,2);
+
+-- Orders that already ended are history
+UPDATE ProcessingOrder SET Status = 'closed' WHERE EndDate IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_processingorder_status ON ProcessingOrder (Status);
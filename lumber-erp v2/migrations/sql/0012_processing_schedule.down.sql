@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_maintenancerecord_unit;
+DROP INDEX IF EXISTS idx_processingorder_slot;
+
+ALTER TABLE ProcessingOrder
+    DROP CONSTRAINT processingorder_slot,
+    DROP COLUMN ScheduledEnd,
+    DROP COLUMN ScheduledStart,
+    DROP COLUMN Operations,
+    DROP COLUMN DurationHours,
+    DROP COLUMN PlannedQuantity;
@@ -0,0 +1,14 @@
+-- Processing orders are planned with a quantity, a duration and the
+-- operations they need (cutting, drying, finishing), and scheduled into a
+-- slot on a processing unit able to perform them.
+
+ALTER TABLE ProcessingOrder
+    ADD COLUMN PlannedQuantity DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (PlannedQuantity >= 0),
+    ADD COLUMN DurationHours DECIMAL(7,2) NOT NULL DEFAULT 0 CHECK (DurationHours >= 0),
+    ADD COLUMN Operations VARCHAR(100) NOT NULL DEFAULT '',
+    ADD COLUMN ScheduledStart TIMESTAMPTZ,
+    ADD COLUMN ScheduledEnd TIMESTAMPTZ,
+    ADD CONSTRAINT processingorder_slot CHECK (ScheduledEnd IS NULL OR ScheduledEnd > ScheduledStart);
+
+CREATE INDEX IF NOT EXISTS idx_processingorder_slot ON ProcessingOrder (UnitID, ScheduledStart);
+CREATE INDEX IF NOT EXISTS idx_maintenancerecord_unit ON MaintenanceRecord (UnitID, MaintenanceDate);
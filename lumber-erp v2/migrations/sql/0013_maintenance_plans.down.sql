@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS MaintenanceWorkOrder;
+DROP TABLE IF EXISTS MaintenancePlan;
@@ -0,0 +1,37 @@
+-- Processing units are serviced on preventive maintenance plans, every so
+-- many operating hours or days. A plan that falls due raises a work order;
+-- the unit is in maintenance while one is open, and completing it writes
+-- the MaintenanceRecord with the cost and downtime.
+
+CREATE TABLE IF NOT EXISTS MaintenancePlan (
+    PlanID SERIAL PRIMARY KEY,
+    UnitID INTEGER NOT NULL REFERENCES ProcessingUnit(UnitID) ON DELETE CASCADE,
+    Description TEXT,
+    IntervalHours DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (IntervalHours >= 0),
+    IntervalDays INTEGER NOT NULL DEFAULT 0 CHECK (IntervalDays >= 0),
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    LastDoneAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    LastDoneHours DECIMAL(12,2) NOT NULL DEFAULT 0,
+    CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (IntervalHours > 0 OR IntervalDays > 0)
+);
+
+CREATE TABLE IF NOT EXISTS MaintenanceWorkOrder (
+    WorkOrderID SERIAL PRIMARY KEY,
+    UnitID INTEGER NOT NULL REFERENCES ProcessingUnit(UnitID) ON DELETE CASCADE,
+    PlanID INTEGER REFERENCES MaintenancePlan(PlanID) ON DELETE SET NULL,
+    Status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (Status IN ('open', 'completed', 'cancelled')),
+    Reason VARCHAR(20) NOT NULL DEFAULT 'manual'
+        CHECK (Reason IN ('hours', 'days', 'manual')),
+    Description TEXT,
+    OperatingHours DECIMAL(12,2) NOT NULL DEFAULT 0,
+    CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ClosedAt TIMESTAMP,
+    MaintenanceID INTEGER REFERENCES MaintenanceRecord(MaintenanceID) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenanceplan_unit ON MaintenancePlan (UnitID);
+CREATE INDEX IF NOT EXISTS idx_maintenanceworkorder_unit ON MaintenanceWorkOrder (UnitID, Status);
+-- A plan has at most one open work order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_maintenanceworkorder_open_plan ON MaintenanceWorkOrder (PlanID) WHERE Status = 'open';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS KilnReading;
+DROP TABLE IF EXISTS KilnChargeLot;
+DROP TABLE IF EXISTS KilnCharge;
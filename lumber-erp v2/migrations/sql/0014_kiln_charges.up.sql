@@ -0,0 +1,42 @@
+-- A kiln charge is a load of stock lots drying in a processing unit. The
+-- kiln's loggers report temperature, relative humidity and wood moisture
+-- over time; the charge completes once moisture reaches its target and is
+-- flagged when it is dried too far or not far enough.
+
+CREATE TABLE IF NOT EXISTS KilnCharge (
+    ChargeID SERIAL PRIMARY KEY,
+    UnitID INTEGER NOT NULL REFERENCES ProcessingUnit(UnitID),
+    Status VARCHAR(20) NOT NULL DEFAULT 'drying'
+        CHECK (Status IN ('drying', 'completed', 'unloaded')),
+    Flag VARCHAR(20) NOT NULL DEFAULT ''
+        CHECK (Flag IN ('', 'over_dried', 'under_dried')),
+    TargetMoisture DECIMAL(5,2) NOT NULL CHECK (TargetMoisture > 0),
+    TargetSource VARCHAR(20) NOT NULL DEFAULT 'charge'
+        CHECK (TargetSource IN ('charge', 'grade', 'species')),
+    Tolerance DECIMAL(5,2) NOT NULL DEFAULT 1 CHECK (Tolerance >= 0),
+    PlannedHours DECIMAL(7,2) NOT NULL DEFAULT 0 CHECK (PlannedHours >= 0),
+    Moisture DECIMAL(6,2),
+    Notes TEXT,
+    StartedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CompletedAt TIMESTAMPTZ,
+    UnloadedAt TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS KilnChargeLot (
+    ChargeID INTEGER NOT NULL REFERENCES KilnCharge(ChargeID) ON DELETE CASCADE,
+    StockID INTEGER NOT NULL REFERENCES StockItem(StockID),
+    PRIMARY KEY (ChargeID, StockID)
+);
+
+CREATE TABLE IF NOT EXISTS KilnReading (
+    ReadingID SERIAL PRIMARY KEY,
+    ChargeID INTEGER NOT NULL REFERENCES KilnCharge(ChargeID) ON DELETE CASCADE,
+    RecordedAt TIMESTAMPTZ NOT NULL,
+    Temperature DECIMAL(6,2),
+    Humidity DECIMAL(5,2) CHECK (Humidity BETWEEN 0 AND 100),
+    Moisture DECIMAL(6,2) CHECK (Moisture >= 0),
+    UNIQUE (ChargeID, RecordedAt)
+);
+
+CREATE INDEX IF NOT EXISTS idx_kilncharge_unit ON KilnCharge (UnitID, Status);
+CREATE INDEX IF NOT EXISTS idx_kilnchargelot_stock ON KilnChargeLot (StockID);
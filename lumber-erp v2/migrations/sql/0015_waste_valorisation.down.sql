@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_wasterecord_processing;
+
+ALTER TABLE WasteRecord
+    DROP COLUMN StockID,
+    DROP COLUMN DisposalCost;
@@ -0,0 +1,9 @@
+-- Disposing of waste costs DisposalCost. Waste sold on as a by-product
+-- (biomass, pellets) is received into the stock lot StockID names and
+-- counts as recycled.
+
+ALTER TABLE WasteRecord
+    ADD COLUMN DisposalCost DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (DisposalCost >= 0),
+    ADD COLUMN StockID INTEGER REFERENCES StockItem(StockID) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_wasterecord_processing ON WasteRecord (ProcessingID);
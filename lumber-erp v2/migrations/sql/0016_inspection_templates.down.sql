@@ -0,0 +1,16 @@
+ALTER TABLE StockItem
+    DROP COLUMN GradedBy,
+    DROP COLUMN Grade;
+
+DROP TABLE IF EXISTS InspectionDefect;
+DROP TABLE IF EXISTS InspectionMeasurement;
+
+ALTER TABLE QualityInspection
+    DROP COLUMN Grade,
+    DROP COLUMN Stage,
+    DROP COLUMN TemplateID;
+
+DROP TABLE IF EXISTS InspectionGrade;
+DROP TABLE IF EXISTS InspectionTemplateItem;
+DROP TABLE IF EXISTS InspectionTemplate;
+DROP TABLE IF EXISTS DefectCode;
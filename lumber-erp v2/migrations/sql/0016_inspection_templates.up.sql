@@ -0,0 +1,84 @@
+-- Inspection templates list what to check of a product type at a stage of
+-- production, as typed checklist items with acceptance bounds, and the
+-- grades a piece earns by the defects found. An inspection against a
+-- template records a measurement per item and the catalogued defects it
+-- found; the grade it derives is written onto the stock lots inspected.
+
+CREATE TABLE IF NOT EXISTS DefectCode (
+    DefectID SERIAL PRIMARY KEY,
+    Code VARCHAR(20) NOT NULL UNIQUE,
+    Name VARCHAR(100) NOT NULL,
+    Category VARCHAR(20) NOT NULL DEFAULT 'other'
+        CHECK (Category IN ('knots', 'wane', 'split', 'warp', 'moisture', 'dimension', 'other')),
+    Severity VARCHAR(10) NOT NULL DEFAULT 'minor' CHECK (Severity IN ('minor', 'major', 'critical')),
+    Description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS InspectionTemplate (
+    TemplateID SERIAL PRIMARY KEY,
+    Name VARCHAR(100) NOT NULL,
+    -- NULL applies the template to every product type at the stage
+    ProductTypeID INTEGER REFERENCES ProductType(ProductTypeID) ON DELETE CASCADE,
+    Stage VARCHAR(20) NOT NULL
+        CHECK (Stage IN ('log_intake', 'green_lumber', 'kiln_dried', 'finished')),
+    Active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- One active template per product type and stage
+CREATE UNIQUE INDEX IF NOT EXISTS ux_inspectiontemplate_scope
+    ON InspectionTemplate (COALESCE(ProductTypeID, 0), Stage) WHERE Active;
+
+CREATE TABLE IF NOT EXISTS InspectionTemplateItem (
+    TemplateID INTEGER NOT NULL REFERENCES InspectionTemplate(TemplateID) ON DELETE CASCADE,
+    Position INTEGER NOT NULL CHECK (Position > 0),
+    Kind VARCHAR(20) NOT NULL CHECK (Kind IN ('knots', 'wane', 'split', 'warp', 'moisture', 'dimension')),
+    Label VARCHAR(100) NOT NULL DEFAULT '',
+    Unit VARCHAR(20) NOT NULL DEFAULT '',
+    MinValue DECIMAL(10,2),
+    MaxValue DECIMAL(10,2),
+    Required BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (TemplateID, Position),
+    CHECK (MinValue IS NULL OR MaxValue IS NULL OR MinValue <= MaxValue)
+);
+
+-- A piece takes the best ranked grade whose limits it stays within; a NULL
+-- limit does not restrict the grade
+CREATE TABLE IF NOT EXISTS InspectionGrade (
+    TemplateID INTEGER NOT NULL REFERENCES InspectionTemplate(TemplateID) ON DELETE CASCADE,
+    Rank INTEGER NOT NULL CHECK (Rank > 0),
+    Grade VARCHAR(50) NOT NULL,
+    MaxKnots DECIMAL(10,2),
+    MaxWane DECIMAL(10,2),
+    MaxSplit DECIMAL(10,2),
+    MaxWarp DECIMAL(10,2),
+    MaxMinorDefects INTEGER,
+    MaxMajorDefects INTEGER,
+    MaxCriticalDefects INTEGER,
+    PRIMARY KEY (TemplateID, Rank),
+    UNIQUE (TemplateID, Grade)
+);
+
+ALTER TABLE QualityInspection
+    ADD COLUMN TemplateID INTEGER REFERENCES InspectionTemplate(TemplateID) ON DELETE SET NULL,
+    ADD COLUMN Stage VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN Grade VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS InspectionMeasurement (
+    InspectionID INTEGER NOT NULL REFERENCES QualityInspection(InspectionID) ON DELETE CASCADE,
+    Position INTEGER NOT NULL,
+    Kind VARCHAR(20) NOT NULL,
+    Value DECIMAL(10,2) NOT NULL,
+    Passed BOOLEAN NOT NULL,
+    PRIMARY KEY (InspectionID, Position)
+);
+
+CREATE TABLE IF NOT EXISTS InspectionDefect (
+    InspectionID INTEGER NOT NULL REFERENCES QualityInspection(InspectionID) ON DELETE CASCADE,
+    Code VARCHAR(20) NOT NULL REFERENCES DefectCode(Code) ON UPDATE CASCADE,
+    Count INTEGER NOT NULL DEFAULT 1 CHECK (Count > 0),
+    PRIMARY KEY (InspectionID, Code)
+);
+
+ALTER TABLE StockItem
+    ADD COLUMN Grade VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN GradedBy INTEGER REFERENCES QualityInspection(InspectionID) ON DELETE SET NULL;
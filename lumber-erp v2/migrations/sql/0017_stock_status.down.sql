@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS StockDisposition;
+
+DROP INDEX IF EXISTS idx_stockitem_status;
+
+ALTER TABLE StockItem
+    DROP COLUMN POItemID,
+    DROP COLUMN Status;
@@ -0,0 +1,31 @@
+-- Stock lots carry a status: available, reserved for sale, held in
+-- quarantine after a failed inspection or rejected once disposed of.
+-- Lots received against a purchase order item name it in POItemID, so a
+-- failed intake inspection finds them. StockDisposition records every
+-- status step of a lot with what it moved and the rows it created.
+
+ALTER TABLE StockItem
+    ADD COLUMN Status VARCHAR(20) NOT NULL DEFAULT 'available'
+        CHECK (Status IN ('available', 'quarantine', 'rejected', 'reserved')),
+    ADD COLUMN POItemID INTEGER REFERENCES PurchaseOrderItem(POItemID) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_stockitem_status ON StockItem (Status) WHERE Status <> 'available';
+
+CREATE TABLE IF NOT EXISTS StockDisposition (
+    DispositionID SERIAL PRIMARY KEY,
+    StockID INTEGER NOT NULL REFERENCES StockItem(StockID) ON DELETE CASCADE,
+    Action VARCHAR(20) NOT NULL CHECK (Action IN ('quarantine', 'release', 'rework', 'scrap',
+        'return_to_supplier', 'reserve', 'unreserve')),
+    FromStatus VARCHAR(20) NOT NULL,
+    ToStatus VARCHAR(20) NOT NULL,
+    Quantity DECIMAL(10,2) NOT NULL DEFAULT 0,
+    InspectionID INTEGER REFERENCES QualityInspection(InspectionID) ON DELETE SET NULL,
+    ProcessingID INTEGER REFERENCES ProcessingOrder(ProcessingID) ON DELETE SET NULL,
+    WasteID INTEGER REFERENCES WasteRecord(WasteID) ON DELETE SET NULL,
+    TransactionID INTEGER REFERENCES InventoryTransaction(TransactionID) ON DELETE SET NULL,
+    EmployeeID INTEGER REFERENCES Employee(EmployeeID) ON DELETE SET NULL,
+    Reason TEXT NOT NULL DEFAULT '',
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stockdisposition_stock ON StockDisposition (StockID, CreatedAt);
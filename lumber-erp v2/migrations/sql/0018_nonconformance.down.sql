@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS CorrectiveAction;
+DROP TABLE IF EXISTS NonConformance;
@@ -0,0 +1,46 @@
+-- Non-conformance reports (NCR) record what was found not to conform, by a
+-- quality inspection or otherwise, and who caused it: a supplier, a
+-- processing unit or something else. An NCR goes open → investigating →
+-- action → verified → closed; its corrective and preventive actions
+-- (CAPA) are owned by employees and due by a date. A supplier caused NCR
+-- lowers the quality score of the supplier's latest review by
+-- ScoreDeducted points.
+
+CREATE TABLE IF NOT EXISTS NonConformance (
+    NCRID SERIAL PRIMARY KEY,
+    Title VARCHAR(200) NOT NULL,
+    Description TEXT NOT NULL DEFAULT '',
+    Origin VARCHAR(20) NOT NULL CHECK (Origin IN ('supplier', 'process', 'other')),
+    Severity VARCHAR(10) NOT NULL DEFAULT 'minor' CHECK (Severity IN ('minor', 'major', 'critical')),
+    InspectionID INTEGER REFERENCES QualityInspection(InspectionID) ON DELETE SET NULL,
+    SupplierID INTEGER REFERENCES Supplier(SupplierID) ON DELETE SET NULL,
+    UnitID INTEGER REFERENCES ProcessingUnit(UnitID) ON DELETE SET NULL,
+    RaisedBy INTEGER REFERENCES Employee(EmployeeID) ON DELETE SET NULL,
+    OwnerID INTEGER REFERENCES Employee(EmployeeID) ON DELETE SET NULL,
+    Status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (Status IN ('open', 'investigating', 'action', 'verified', 'closed')),
+    RootCause TEXT NOT NULL DEFAULT '',
+    DueDate DATE,
+    ScoreDeducted DECIMAL(5,2) NOT NULL DEFAULT 0,
+    PerformanceID INTEGER REFERENCES SupplierPerformance(PerformanceID) ON DELETE SET NULL,
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ClosedAt TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_nonconformance_supplier ON NonConformance (SupplierID);
+
+CREATE TABLE IF NOT EXISTS CorrectiveAction (
+    ActionID SERIAL PRIMARY KEY,
+    NCRID INTEGER NOT NULL REFERENCES NonConformance(NCRID) ON DELETE CASCADE,
+    Kind VARCHAR(20) NOT NULL CHECK (Kind IN ('corrective', 'preventive')),
+    Description TEXT NOT NULL,
+    OwnerID INTEGER NOT NULL REFERENCES Employee(EmployeeID),
+    DueDate DATE NOT NULL,
+    Status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (Status IN ('open', 'done')),
+    Notes TEXT NOT NULL DEFAULT '',
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CompletedAt TIMESTAMPTZ
+);
+
+-- Overdue actions are the open ones past their due date
+CREATE INDEX IF NOT EXISTS idx_correctiveaction_open ON CorrectiveAction (DueDate) WHERE Status = 'open';
@@ -0,0 +1,22 @@
+-- Quality alerts notify the quality team of statistical process control
+-- (SPC) rule violations: a subgroup of inspections of a processing unit and
+-- species whose mean, range or failure rate breaks a Western Electric rule
+-- on its chart. An alert is keyed by the inspection completing the
+-- subgroup, so checking the charts again raises nothing new.
+
+CREATE TABLE IF NOT EXISTS QualityAlert (
+    AlertID SERIAL PRIMARY KEY,
+    UnitID INTEGER REFERENCES ProcessingUnit(UnitID) ON DELETE CASCADE,
+    SpeciesID INTEGER REFERENCES TreeSpecies(SpeciesID) ON DELETE CASCADE,
+    Metric VARCHAR(30) NOT NULL,
+    Chart VARCHAR(10) NOT NULL CHECK (Chart IN ('xbar', 'range', 'p')),
+    Rule INTEGER NOT NULL CHECK (Rule BETWEEN 1 AND 4),
+    InspectionID INTEGER NOT NULL REFERENCES QualityInspection(InspectionID) ON DELETE CASCADE,
+    Value DECIMAL(12,4) NOT NULL,
+    Message TEXT NOT NULL DEFAULT '',
+    Status VARCHAR(20) NOT NULL DEFAULT 'Active',
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_qualityalert_violation ON QualityAlert
+    (COALESCE(UnitID, 0), COALESCE(SpeciesID, 0), Metric, Chart, Rule, InspectionID);
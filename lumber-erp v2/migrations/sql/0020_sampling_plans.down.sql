@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS InspectionLot;
+DROP TABLE IF EXISTS SamplingPlan;
@@ -0,0 +1,46 @@
+-- Acceptance sampling of received purchase order lots, after ISO 2859-1 /
+-- ANSI Z1.4. A sampling plan sets the acceptable quality level (AQL, in
+-- percent nonconforming) and general inspection level of the lots a
+-- supplier delivers of a product type; NULL covers every supplier or
+-- product type. An inspection lot is a purchase order item received for
+-- inspection: its size picks the sample size code letter, the supplier's
+-- switching state (normal, tightened or reduced) the plan, and the defects
+-- its sample inspections record decide it accepted or rejected.
+
+CREATE TABLE IF NOT EXISTS SamplingPlan (
+    PlanID SERIAL PRIMARY KEY,
+    SupplierID INTEGER REFERENCES Supplier(SupplierID) ON DELETE CASCADE,
+    ProductTypeID INTEGER REFERENCES ProductType(ProductTypeID) ON DELETE CASCADE,
+    InspectionLevel VARCHAR(3) NOT NULL DEFAULT 'II' CHECK (InspectionLevel IN ('I', 'II', 'III')),
+    AQL DECIMAL(6,3) NOT NULL CHECK (AQL > 0),
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    Notes TEXT NOT NULL DEFAULT ''
+);
+
+-- One active plan per supplier and product type
+CREATE UNIQUE INDEX IF NOT EXISTS ux_samplingplan_scope
+    ON SamplingPlan (COALESCE(SupplierID, 0), COALESCE(ProductTypeID, 0)) WHERE Active;
+
+CREATE TABLE IF NOT EXISTS InspectionLot (
+    LotID SERIAL PRIMARY KEY,
+    POItemID INTEGER NOT NULL UNIQUE REFERENCES PurchaseOrderItem(POItemID) ON DELETE CASCADE,
+    PlanID INTEGER REFERENCES SamplingPlan(PlanID) ON DELETE SET NULL,
+    SupplierID INTEGER NOT NULL REFERENCES Supplier(SupplierID) ON DELETE CASCADE,
+    ProductTypeID INTEGER REFERENCES ProductType(ProductTypeID) ON DELETE SET NULL,
+    LotSize INTEGER NOT NULL CHECK (LotSize > 0),
+    InspectionLevel VARCHAR(3) NOT NULL,
+    AQL DECIMAL(6,3) NOT NULL,
+    Severity VARCHAR(10) NOT NULL CHECK (Severity IN ('normal', 'tightened', 'reduced')),
+    CodeLetter CHAR(1) NOT NULL,
+    SampleSize INTEGER NOT NULL CHECK (SampleSize > 0),
+    AcceptNumber INTEGER NOT NULL,
+    RejectNumber INTEGER NOT NULL,
+    Inspected INTEGER NOT NULL DEFAULT 0,
+    Defects INTEGER NOT NULL DEFAULT 0,
+    Status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (Status IN ('open', 'accepted', 'rejected')),
+    CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    DecidedAt TIMESTAMPTZ
+);
+
+-- The switching state of a supplier is replayed from its decided lots
+CREATE INDEX IF NOT EXISTS idx_inspectionlot_supplier ON InspectionLot (SupplierID, DecidedAt) WHERE Status <> 'open';
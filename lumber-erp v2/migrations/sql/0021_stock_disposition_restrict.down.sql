@@ -0,0 +1,4 @@
+ALTER TABLE StockDisposition
+    DROP CONSTRAINT IF EXISTS stockdisposition_stockid_fkey,
+    ADD CONSTRAINT stockdisposition_stockid_fkey
+        FOREIGN KEY (StockID) REFERENCES StockItem(StockID) ON DELETE CASCADE;
@@ -0,0 +1,8 @@
+-- The disposition trail of a lot is its quarantine history and must outlive
+-- any attempt to delete the lot: refuse deleting a lot that has one rather
+-- than cascading the delete into StockDisposition.
+
+ALTER TABLE StockDisposition
+    DROP CONSTRAINT IF EXISTS stockdisposition_stockid_fkey,
+    ADD CONSTRAINT stockdisposition_stockid_fkey
+        FOREIGN KEY (StockID) REFERENCES StockItem(StockID) ON DELETE RESTRICT;
@@ -1,37 +1,53 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // ============================================
 // 👥 USER MANAGEMENT
 // ============================================
 
 type User struct {
 	UserID      int    `json:"user_id"`
-	Email       string `json:"email"`
+	Email       string `json:"email" validate:"required,email"`
 	Password    string `json:"password,omitempty"`
 	FirstName   string `json:"first_name"`
 	LastName    string `json:"last_name"`
 	PhoneNumber string `json:"phone_number"`
-	Status      string `json:"status"`
+	Status      string `json:"status" validate:"oneof=active|inactive"`
 	CreatedAt   string `json:"created_at"`
 }
 
 type Permission struct {
 	PermissionID int    `json:"permission_id"`
-	ModuleName   string `json:"module_name"`
-	ActionType   string `json:"action_type"`
+	ModuleName   string `json:"module_name" validate:"required"`
+	ActionType   string `json:"action_type" validate:"required"`
 }
 
 type Role struct {
 	RoleID      int    `json:"role_id"`
 	UserID      int    `json:"user_id"`
-	RoleName    string `json:"role_name"`
+	RoleName    string `json:"role_name" validate:"required"`
 	Description string `json:"description"`
 }
 
-
 type RolePermission struct {
-	RoleID       int `json:"role_id"`
-	PermissionID int `json:"permission_id"`
+	RoleID       int `json:"role_id" validate:"required"`
+	PermissionID int `json:"permission_id" validate:"required"`
+}
+
+// UserSession tracks one signed-in device; the refresh token is stored hashed
+type UserSession struct {
+	SessionID        int        `json:"session_id"`
+	UserID           int        `json:"user_id"`
+	RefreshTokenHash string     `json:"-"`
+	IPAddress        string     `json:"ip_address"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
 }
 
 type UserEmployeeAssignment struct {
@@ -45,24 +61,24 @@ type UserEmployeeAssignment struct {
 
 type Employee struct {
 	EmployeeID        int     `json:"employee_id"`
-	FullName          string  `json:"full_name"`
+	FullName          string  `json:"full_name" validate:"required"`
 	Department        string  `json:"department"`
 	Position          string  `json:"position"`
-	HireDate          string  `json:"hire_date"`
-	PerformanceRating float64 `json:"performance_rating"`
+	HireDate          string  `json:"hire_date" validate:"date"`
+	PerformanceRating float64 `json:"performance_rating" validate:"min=0,max=9.99"`
 }
 
 type WorkerAssignment struct {
 	AssignmentID int    `json:"assignment_id"`
-	EmployeeID   int    `json:"employee_id"`
-	ProcessingID int    `json:"processing_id"`
+	EmployeeID   int    `json:"employee_id" validate:"required"`
+	ProcessingID int    `json:"processing_id" validate:"required"`
 	RoleInTask   string `json:"role_in_task"`
 	Notes        string `json:"notes"`
 }
 
 type ManagementInsights struct {
 	ReportID   int    `json:"report_id"`
-	EmployeeID int    `json:"employee_id"`
+	EmployeeID int    `json:"employee_id" validate:"required"`
 	KPIType    string `json:"kpi_type"`
 	TimePeriod string `json:"time_period"`
 }
@@ -73,32 +89,32 @@ type ManagementInsights struct {
 
 type Supplier struct {
 	SupplierID       int    `json:"supplier_id"`
-	CompanyName      string `json:"company_name"`
+	CompanyName      string `json:"company_name" validate:"required"`
 	ContactPerson    string `json:"contact_person"`
-	Email            string `json:"email"`
+	Email            string `json:"email" validate:"email"`
 	Phone            string `json:"phone"`
-	ComplianceStatus string `json:"compliance_status"`
+	ComplianceStatus string `json:"compliance_status" validate:"oneof=pending|certified|premium_certified|compliant|non_compliant|suspended"`
 	Raw              bool   `json:"raw"`
 	SemiProcessed    bool   `json:"semi_processed"`
 }
 
 type SupplierPerformance struct {
 	PerformanceID      int     `json:"performance_id"`
-	SupplierID         int     `json:"supplier_id"`
-	Rating             float64 `json:"rating"`
-	DeliveryTimeliness float64 `json:"delivery_timeliness"`
-	QualityScore       float64 `json:"quality_score"`
-	ReviewDate         string  `json:"review_date"`
+	SupplierID         int     `json:"supplier_id" validate:"required"`
+	Rating             float64 `json:"rating" validate:"min=0,max=9.99"`
+	DeliveryTimeliness float64 `json:"delivery_timeliness" validate:"min=0,max=100"`
+	QualityScore       float64 `json:"quality_score" validate:"min=0,max=100"`
+	ReviewDate         string  `json:"review_date" validate:"date"`
 }
 
 type SupplierContract struct {
 	ContractID    int     `json:"contract_id"`
-	SupplierID    int     `json:"supplier_id"`
-	StartDate     string  `json:"start_date"`
-	EndDate       string  `json:"end_date"`
+	SupplierID    int     `json:"supplier_id" validate:"required"`
+	StartDate     string  `json:"start_date" validate:"date"`
+	EndDate       string  `json:"end_date" validate:"date,after=start_date"`
 	Terms         string  `json:"terms"`
-	ContractValue float64 `json:"contract_value"`
-	Status        string  `json:"status"`
+	ContractValue float64 `json:"contract_value" validate:"min=0"`
+	Status        string  `json:"status" validate:"oneof=active|expired|terminated|under_review"`
 }
 
 // ============================================
@@ -107,28 +123,28 @@ type SupplierContract struct {
 
 type Forest struct {
 	ForestID      int     `json:"forest_id"`
-	ForestName    string  `json:"forest_name"`
+	ForestName    string  `json:"forest_name" validate:"required"`
 	GeoLocation   string  `json:"geo_location"`
-	AreaSize      float64 `json:"area_size"`
+	AreaSize      float64 `json:"area_size" validate:"min=0"`
 	OwnershipType string  `json:"ownership_type"`
-	Status        string  `json:"status"`
+	Status        string  `json:"status" validate:"oneof=active|inactive|under_review"`
 }
 
 type TreeSpecies struct {
 	SpeciesID       int     `json:"species_id"`
-	SpeciesName     string  `json:"species_name"`
-	AverageHeight   float64 `json:"average_height"`
-	Density         float64 `json:"density"`
-	MoistureContent float64 `json:"moisture_content"`
+	SpeciesName     string  `json:"species_name" validate:"required"`
+	AverageHeight   float64 `json:"average_height" validate:"min=0"`
+	Density         float64 `json:"density" validate:"min=0"`
+	MoistureContent float64 `json:"moisture_content" validate:"min=0,max=100"`
 	Grade           string  `json:"grade"`
 }
 
 type HarvestSchedule struct {
 	ScheduleID int    `json:"schedule_id"`
-	ForestID   int    `json:"forest_id"`
-	StartDate  string `json:"start_date"`
-	EndDate    string `json:"end_date"`
-	Status     string `json:"status"`
+	ForestID   int    `json:"forest_id" validate:"required"`
+	StartDate  string `json:"start_date" validate:"date"`
+	EndDate    string `json:"end_date" validate:"date,after=start_date"`
+	Status     string `json:"status" validate:"oneof=scheduled|in_progress|completed|cancelled"`
 }
 
 type HarvestBatch struct {
@@ -136,15 +152,18 @@ type HarvestBatch struct {
 	ForestID         int     `json:"forest_id"`
 	SpeciesID        int     `json:"species_id"`
 	ScheduleID       int     `json:"schedule_id"`
-	Quantity         float64 `json:"quantity"`
-	HarvestDate      string  `json:"harvest_date"`
+	Quantity         float64 `json:"quantity" validate:"required,min=0"`
+	HarvestDate      string  `json:"harvest_date" validate:"date"`
 	QualityIndicator string  `json:"quality_indicator"`
 	QRCode           string  `json:"qr_code"`
 }
 
+// HarvestBatchProcessing records that a processing order consumed
+// ConsumedQuantity of a harvest batch
 type HarvestBatchProcessing struct {
-	ProcessingID int `json:"processing_id"`
-	BatchID      int `json:"batch_id"`
+	ProcessingID     int     `json:"processing_id"`
+	BatchID          int     `json:"batch_id" validate:"required"`
+	ConsumedQuantity float64 `json:"consumed_quantity" validate:"required,min=0"`
 }
 
 // ============================================
@@ -153,66 +172,341 @@ type HarvestBatchProcessing struct {
 
 type Sawmill struct {
 	SawmillID int     `json:"sawmill_id"`
-	Name      string  `json:"name"`
+	Name      string  `json:"name" validate:"required"`
 	Location  string  `json:"location"`
-	Capacity  float64 `json:"capacity"`
-	Status    string  `json:"status"`
+	Capacity  float64 `json:"capacity" validate:"min=0"`
+	Status    string  `json:"status" validate:"oneof=operational|active|maintenance|inactive"`
 }
 
 type ProcessingUnit struct {
 	UnitID    int     `json:"unit_id"`
-	SawmillID int     `json:"sawmill_id"`
+	SawmillID int     `json:"sawmill_id" validate:"required"`
 	Cutting   string  `json:"cutting"`
 	Drying    string  `json:"drying"`
 	Finishing string  `json:"finishing"`
-	Capacity  float64 `json:"capacity"`
-	Status    string  `json:"status"`
+	Capacity  float64 `json:"capacity" validate:"min=0"`
+	Status    string  `json:"status" validate:"oneof=active|inactive|maintenance"`
 }
 
+// ProcessingOrder goes planned → released → in_progress → completed →
+// closed. Completion posts its inputs and output to the stock ledger and
+// computes the rate and volumes after OutputQuantity; the client cannot
+// set them.
 type ProcessingOrder struct {
 	ProcessingID   int     `json:"processing_id"`
 	ProductTypeID  int     `json:"product_type_id"`
 	UnitID         int     `json:"unit_id"`
-	StartDate      string  `json:"start_date"`
-	EndDate        string  `json:"end_date"`
-	OutputQuantity float64 `json:"output_quantity"`
+	StartDate      string  `json:"start_date" validate:"date"`
+	EndDate        string  `json:"end_date" validate:"date,after=start_date"`
+	OutputQuantity float64 `json:"output_quantity" validate:"min=0"`
 	EfficiencyRate float64 `json:"efficiency_rate"`
+	Status         string  `json:"status"`
+	// WarehouseID receives the output
+	WarehouseID   *int    `json:"warehouse_id"`
+	InputQuantity float64 `json:"input_quantity"`
+	WasteQuantity float64 `json:"waste_quantity"`
+	// MassBalanceVariance is input minus output minus waste; orders where it
+	// exceeds the tolerance are flagged
+	MassBalanceVariance float64 `json:"mass_balance_variance"`
+	MassBalanceFlagged  bool    `json:"mass_balance_flagged"`
+	// PlannedQuantity and DurationHours size the order for scheduling; a
+	// zero duration is derived from the unit's capacity per hour
+	PlannedQuantity float64 `json:"planned_quantity" validate:"min=0"`
+	DurationHours   float64 `json:"duration_hours" validate:"min=0"`
+	// Operations lists what the unit must do, comma separated: cutting,
+	// drying, finishing
+	Operations     string  `json:"operations"`
+	ScheduledStart *string `json:"scheduled_start"`
+	ScheduledEnd   *string `json:"scheduled_end"`
+	// Inputs are the harvest batches the order consumed; only a single
+	// fetched order carries them
+	Inputs []HarvestBatchProcessing `json:"inputs,omitempty"`
 }
 
 type MaintenanceRecord struct {
 	MaintenanceID   int     `json:"maintenance_id"`
-	UnitID          int     `json:"unit_id"`
-	MaintenanceDate string  `json:"maintenance_date"`
+	UnitID          int     `json:"unit_id" validate:"required"`
+	MaintenanceDate string  `json:"maintenance_date" validate:"date"`
 	Description     string  `json:"description"`
-	Cost            float64 `json:"cost"`
+	Cost            float64 `json:"cost" validate:"min=0"`
 	PartsUsed       string  `json:"parts_used"`
-	DowntimeHours   float64 `json:"downtime_hours"`
-}
-
+	DowntimeHours   float64 `json:"downtime_hours" validate:"min=0"`
+}
+
+// MaintenancePlan services a unit every IntervalHours operating hours or
+// every IntervalDays days, whichever comes first; zero leaves an interval
+// unused. Completing a work order of the plan moves LastDoneAt and
+// LastDoneHours, the unit's operating hours at that service, forward.
+type MaintenancePlan struct {
+	PlanID        int     `json:"plan_id"`
+	UnitID        int     `json:"unit_id" validate:"required"`
+	Description   string  `json:"description"`
+	IntervalHours float64 `json:"interval_hours" validate:"min=0"`
+	IntervalDays  int     `json:"interval_days" validate:"min=0"`
+	Active        bool    `json:"active"`
+	LastDoneAt    string  `json:"last_done_at" validate:"date"`
+	LastDoneHours float64 `json:"last_done_hours"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// MaintenanceWorkOrder is maintenance to carry out on a unit, raised by a
+// plan falling due (Reason hours or days) or by hand (manual). It goes open
+// → completed or cancelled; completing it records a MaintenanceRecord.
+type MaintenanceWorkOrder struct {
+	WorkOrderID    int     `json:"work_order_id"`
+	UnitID         int     `json:"unit_id" validate:"required"`
+	PlanID         *int    `json:"plan_id"`
+	Status         string  `json:"status"`
+	Reason         string  `json:"reason"`
+	Description    string  `json:"description"`
+	OperatingHours float64 `json:"operating_hours"` // the unit's when raised
+	CreatedAt      string  `json:"created_at"`
+	ClosedAt       *string `json:"closed_at"`
+	MaintenanceID  *int    `json:"maintenance_id"`
+}
+
+// KilnCharge is a load of stock lots drying in a unit. It goes drying →
+// completed once a reading's moisture is within Tolerance of
+// TargetMoisture, then unloaded; Flag marks it over_dried or under_dried.
+// TargetMoisture is taken from the lots' grade or species when not given
+// (TargetSource says which), and Moisture is the latest reading's.
+type KilnCharge struct {
+	ChargeID       int      `json:"charge_id"`
+	UnitID         int      `json:"unit_id" validate:"required"`
+	Status         string   `json:"status"`
+	Flag           string   `json:"flag"`
+	TargetMoisture float64  `json:"target_moisture" validate:"min=0,max=100"`
+	TargetSource   string   `json:"target_source"`
+	Tolerance      float64  `json:"tolerance" validate:"min=0,max=100"`
+	PlannedHours   float64  `json:"planned_hours" validate:"min=0"`
+	Moisture       *float64 `json:"moisture"`
+	Notes          string   `json:"notes"`
+	StartedAt      string   `json:"started_at" validate:"date"`
+	CompletedAt    *string  `json:"completed_at"`
+	UnloadedAt     *string  `json:"unloaded_at"`
+	StockIDs       []int    `json:"stock_ids,omitempty"`
+}
+
+// KilnReading is what a kiln's loggers reported at RecordedAt: air
+// temperature (°C), relative humidity and wood moisture content (%)
+type KilnReading struct {
+	ReadingID   int      `json:"reading_id"`
+	ChargeID    int      `json:"charge_id"`
+	RecordedAt  string   `json:"recorded_at"`
+	Temperature *float64 `json:"temperature"`
+	Humidity    *float64 `json:"humidity"`
+	Moisture    *float64 `json:"moisture"`
+}
+
+// WasteRecord is waste a processing order left. DisposalCost is what getting
+// rid of it cost; StockID is the by-product lot it was valorised into, which
+// only valorising sets.
 type WasteRecord struct {
 	WasteID        int     `json:"waste_id"`
-	ProcessingID   int     `json:"processing_id"`
+	ProcessingID   int     `json:"processing_id" validate:"required"`
 	WasteType      string  `json:"waste_type"`
-	Volume         float64 `json:"volume"`
+	Volume         float64 `json:"volume" validate:"min=0"`
 	DisposalMethod string  `json:"disposal_method"`
 	Recycled       bool    `json:"recycled"`
+	DisposalCost   float64 `json:"disposal_cost" validate:"min=0"`
+	StockID        *int    `json:"stock_id"`
 }
 
 // ============================================
 // ✅ QUALITY CONTROL
 // ============================================
 
-
+// QualityInspection records the inspection of a harvest batch, of the
+// output of a processing order or of a purchase order item. One made
+// against a template (TemplateID, or the template of its Stage) carries a
+// measurement per checklist item and the catalogued defects found, and its
+// Result and Grade are derived from them.
 type QualityInspection struct {
-	InspectionID    int      `json:"inspection_id"`
-	EmployeeID      int      `json:"employee_id"`
-	ProcessingID    *int     `json:"processing_id"`   // pointer allows NULL
-	POItemID        *int     `json:"po_item_id"`      // pointer allows NULL
-	BatchID         int      `json:"batch_id"`
-	Result          string   `json:"result"`
-	MoistureLevel   float64  `json:"moisture_level"`
-	CertificationID string   `json:"certification_id"`
-	Date            string   `json:"date"`
+	InspectionID    int                     `json:"inspection_id"`
+	EmployeeID      int                     `json:"employee_id"`
+	ProcessingID    *int                    `json:"processing_id"` // pointer allows NULL
+	POItemID        *int                    `json:"po_item_id"`    // pointer allows NULL
+	BatchID         int                     `json:"batch_id"`
+	Result          string                  `json:"result" validate:"oneof=pass|fail"`
+	MoistureLevel   float64                 `json:"moisture_level" validate:"min=0,max=100"`
+	CertificationID string                  `json:"certification_id"`
+	Date            string                  `json:"date" validate:"date"`
+	TemplateID      *int                    `json:"template_id"`
+	Stage           string                  `json:"stage" validate:"oneof=log_intake|green_lumber|kiln_dried|finished"`
+	Grade           string                  `json:"grade"`
+	Measurements    []InspectionMeasurement `json:"measurements,omitempty"`
+	Defects         []InspectionDefect      `json:"defects,omitempty"`
+}
+
+// InspectionMeasurement is the value measured for the checklist item at
+// Position of the inspection's template, and whether it was within bounds
+type InspectionMeasurement struct {
+	Position int     `json:"position"`
+	Kind     string  `json:"kind"`
+	Value    float64 `json:"value"`
+	Passed   bool    `json:"passed"`
+}
+
+// InspectionDefect counts the defects of a catalogued code an inspection
+// found
+type InspectionDefect struct {
+	Code  string `json:"code"`
+	Count int    `json:"count"`
+}
+
+// DefectCode catalogues a defect inspectors record. Category is the
+// checklist kind it belongs to and Severity (minor, major, critical) what
+// grades tolerate of it.
+type DefectCode struct {
+	DefectID    int    `json:"defect_id"`
+	Code        string `json:"code" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	Category    string `json:"category" validate:"oneof=knots|wane|split|warp|moisture|dimension|other"`
+	Severity    string `json:"severity" validate:"oneof=minor|major|critical"`
+	Description string `json:"description"`
+}
+
+// InspectionTemplate lists what to check of a product type (every product
+// type when ProductTypeID is nil) at a stage: log_intake, green_lumber,
+// kiln_dried or finished. Items and Grades are replaced with the template.
+type InspectionTemplate struct {
+	TemplateID    int                      `json:"template_id"`
+	Name          string                   `json:"name" validate:"required"`
+	ProductTypeID *int                     `json:"product_type_id"`
+	Stage         string                   `json:"stage" validate:"required,oneof=log_intake|green_lumber|kiln_dried|finished"`
+	Active        bool                     `json:"active"`
+	Items         []InspectionTemplateItem `json:"items,omitempty"`
+	Grades        []InspectionGrade        `json:"grades,omitempty"`
+}
+
+// InspectionTemplateItem is a checklist item: knots (a count), wane (% of
+// the edge), split and warp (mm), moisture (%) or a dimension, passing
+// when its value is within Min and Max
+type InspectionTemplateItem struct {
+	Position int      `json:"position"`
+	Kind     string   `json:"kind"`
+	Label    string   `json:"label"`
+	Unit     string   `json:"unit"`
+	Min      *float64 `json:"min"`
+	Max      *float64 `json:"max"`
+	Required bool     `json:"required"`
+}
+
+// InspectionGrade is a grade of a template, such as FAS, Select or #1
+// Common. Rank 1 is the best; a nil limit does not restrict the grade.
+type InspectionGrade struct {
+	Rank               int      `json:"rank"`
+	Grade              string   `json:"grade"`
+	MaxKnots           *float64 `json:"max_knots"`
+	MaxWane            *float64 `json:"max_wane"`
+	MaxSplit           *float64 `json:"max_split"`
+	MaxWarp            *float64 `json:"max_warp"`
+	MaxMinorDefects    *int     `json:"max_minor_defects"`
+	MaxMajorDefects    *int     `json:"max_major_defects"`
+	MaxCriticalDefects *int     `json:"max_critical_defects"`
+}
+
+// NonConformance is a non-conformance report (NCR): something found not to
+// conform, by an inspection (InspectionID) or otherwise, caused by a
+// supplier, a processing unit or something else (Origin). It goes open →
+// investigating → action → verified → closed as its root cause is found
+// and its corrective and preventive actions are done. A supplier caused
+// NCR takes ScoreDeducted points off the supplier's quality score in the
+// review PerformanceID.
+type NonConformance struct {
+	NCRID         int                `json:"ncr_id"`
+	Title         string             `json:"title" validate:"required"`
+	Description   string             `json:"description"`
+	Origin        string             `json:"origin" validate:"required,oneof=supplier|process|other"`
+	Severity      string             `json:"severity" validate:"oneof=minor|major|critical"`
+	InspectionID  *int               `json:"inspection_id"`
+	SupplierID    *int               `json:"supplier_id"`
+	UnitID        *int               `json:"unit_id"`
+	RaisedBy      *int               `json:"raised_by"`
+	OwnerID       *int               `json:"owner_id"`
+	Status        string             `json:"status"`
+	RootCause     string             `json:"root_cause"`
+	DueDate       *string            `json:"due_date" validate:"date"`
+	ScoreDeducted float64            `json:"score_deducted"`
+	PerformanceID *int               `json:"performance_id"`
+	CreatedAt     string             `json:"created_at"`
+	ClosedAt      *string            `json:"closed_at"`
+	Actions       []CorrectiveAction `json:"actions,omitempty"`
+}
+
+// CorrectiveAction is a CAPA of a non-conformance: a corrective action
+// removing what went wrong or a preventive one keeping it from happening
+// again, owned by an employee until done by its due date
+type CorrectiveAction struct {
+	ActionID    int     `json:"action_id"`
+	NCRID       int     `json:"ncr_id"`
+	Kind        string  `json:"kind" validate:"required,oneof=corrective|preventive"`
+	Description string  `json:"description" validate:"required"`
+	OwnerID     int     `json:"owner_id" validate:"required"`
+	DueDate     string  `json:"due_date" validate:"required,date"`
+	Status      string  `json:"status"`
+	Notes       string  `json:"notes"`
+	CreatedAt   string  `json:"created_at"`
+	CompletedAt *string `json:"completed_at"`
+}
+
+// QualityAlert notifies the quality team that the SPC chart of a metric
+// broke a Western Electric rule (1 to 4) at the subgroup InspectionID
+// completed. Chart is xbar, range or p; Status is Active or Resolved, as
+// for stock alerts.
+type QualityAlert struct {
+	AlertID      int     `json:"alert_id"`
+	UnitID       *int    `json:"unit_id"`
+	SpeciesID    *int    `json:"species_id"`
+	Metric       string  `json:"metric"`
+	Chart        string  `json:"chart"`
+	Rule         int     `json:"rule"`
+	InspectionID int     `json:"inspection_id"`
+	Value        float64 `json:"value"`
+	Message      string  `json:"message"`
+	Status       string  `json:"status"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// SamplingPlan is how lots received from a supplier of a product type are
+// sampled; leaving either out covers them all. AQL is the acceptable
+// quality level in percent nonconforming and InspectionLevel the general
+// inspection level.
+type SamplingPlan struct {
+	PlanID          int     `json:"plan_id"`
+	SupplierID      *int    `json:"supplier_id"`
+	ProductTypeID   *int    `json:"product_type_id"`
+	InspectionLevel string  `json:"inspection_level" validate:"oneof=I|II|III"`
+	AQL             float64 `json:"aql" validate:"required,min=0"`
+	Active          bool    `json:"active"`
+	Notes           string  `json:"notes"`
+}
+
+// InspectionLot is a purchase order item received for acceptance sampling.
+// The plan it was opened with is kept: Severity is the switching state of
+// the supplier then (normal, tightened or reduced), and SampleSize,
+// AcceptNumber and RejectNumber what the code letter of LotSize calls for.
+// Inspected and Defects tally its sample inspections until Status turns
+// accepted or rejected.
+type InspectionLot struct {
+	LotID           int     `json:"lot_id"`
+	POItemID        int     `json:"po_item_id" validate:"required"`
+	PlanID          *int    `json:"plan_id"`
+	SupplierID      int     `json:"supplier_id"`
+	ProductTypeID   *int    `json:"product_type_id"`
+	LotSize         int     `json:"lot_size" validate:"min=0"`
+	InspectionLevel string  `json:"inspection_level"`
+	AQL             float64 `json:"aql"`
+	Severity        string  `json:"severity"`
+	CodeLetter      string  `json:"code_letter"`
+	SampleSize      int     `json:"sample_size"`
+	AcceptNumber    int     `json:"accept_number"`
+	RejectNumber    int     `json:"reject_number"`
+	Inspected       int     `json:"inspected"`
+	Defects         int     `json:"defects"`
+	Status          string  `json:"status"`
+	CreatedAt       string  `json:"created_at"`
+	DecidedAt       *string `json:"decided_at"`
 }
 
 // ============================================
@@ -221,47 +515,118 @@ type QualityInspection struct {
 
 type Warehouse struct {
 	WarehouseID int     `json:"warehouse_id"`
-	Name        string  `json:"name"`
+	Name        string  `json:"name" validate:"required"`
 	Location    string  `json:"location"`
-	Capacity    float64 `json:"capacity"`
+	Capacity    float64 `json:"capacity" validate:"min=0"`
 	Contact     string  `json:"contact"`
+	// AllowNegativeStock lets transactions take stock below zero
+	AllowNegativeStock bool `json:"allow_negative_stock"`
 }
 
 type ProductType struct {
-	ProductTypeID int    `json:"product_type_id"`
-	Name          string `json:"name"`
-	Description   string `json:"description"`
-	Grade         string `json:"grade"`
-	UnitOfMeasure string `json:"unit_of_measure"`
-}
-
+	ProductTypeID int     `json:"product_type_id"`
+	Name          string  `json:"name"`
+	Description   string  `json:"description"`
+	Price         float64 `json:"price"`
+	Grade         string  `json:"grade"`
+	UnitOfMeasure string  `json:"unit_of_measure"`
+}
+
+// StockItem is one lot of a product in a warehouse: BatchID is the harvest
+// batch it came from, ProcessingID the processing order it is output of and
+// POItemID the purchase order item it was received against. Grade is the
+// grade the inspection GradedBy derived for it. Status is available,
+// reserved, quarantine or rejected; only dispositions change it.
 type StockItem struct {
 	StockID       int     `json:"stock_id"`
 	ProductTypeID int     `json:"product_type_id"`
 	WarehouseID   int     `json:"warehouse_id"`
-	BatchID       int     `json:"batch_id"`
+	BatchID       *int    `json:"batch_id"`
+	ProcessingID  *int    `json:"processing_id"`
 	Quantity      float64 `json:"quantity"`
 	ShelfLocation string  `json:"shelf_location"`
+	Grade         string  `json:"grade"`
+	GradedBy      *int    `json:"graded_by"`
+	Status        string  `json:"status"`
+	POItemID      *int    `json:"po_item_id"`
+}
+
+// StockDisposition records a status step of a stock lot: quarantine after
+// a failed inspection (InspectionID), release, reserve and unreserve, or
+// disposing of Quantity by rework (in processing order ProcessingID),
+// scrap (as waste record WasteID) or return to the supplier. TransactionID
+// is the ledger entry that took the quantity out of the lot.
+type StockDisposition struct {
+	DispositionID int     `json:"disposition_id"`
+	StockID       int     `json:"stock_id"`
+	Action        string  `json:"action"`
+	FromStatus    string  `json:"from_status"`
+	ToStatus      string  `json:"to_status"`
+	Quantity      float64 `json:"quantity"`
+	InspectionID  *int    `json:"inspection_id"`
+	ProcessingID  *int    `json:"processing_id"`
+	WasteID       *int    `json:"waste_id"`
+	TransactionID *int    `json:"transaction_id"`
+	EmployeeID    *int    `json:"employee_id"`
+	Reason        string  `json:"reason"`
+	CreatedAt     string  `json:"created_at"`
 }
 
 type StockAlert struct {
 	AlertID     int    `json:"alert_id"`
 	StockID     int    `json:"stock_id"`
-	WarehouseID int    `json:"warehouse_id"`
+	WarehouseID *int   `json:"warehouse_id"`
 	AlertType   string `json:"alert_type"`
 	CreatedAt   string `json:"created_at"`
 	Status      string `json:"status"`
 }
 
+// InventoryTransaction is an entry of the stock ledger. Quantity is the
+// signed change it made to the stock item, and BalanceAfter the quantity it
+// left; ReversesID names the transaction a reversing entry undoes.
 type InventoryTransaction struct {
-	TransactionID   int     `json:"transaction_id"`
-	EmployeeID      int     `json:"employee_id"`
-	StockID         int     `json:"stock_id"`
-	WarehouseID     int     `json:"warehouse_id"`
-	TransactionType string  `json:"transaction_type"`
-	Quantity        float64 `json:"quantity"`
-	TransactionDate string  `json:"transaction_date"`
-	Remarks         string  `json:"remarks"`
+	TransactionID   int      `json:"transaction_id"`
+	EmployeeID      *int     `json:"employee_id"`
+	StockID         int      `json:"stock_id"`
+	WarehouseID     *int     `json:"warehouse_id"`
+	TransactionType string   `json:"transaction_type"`
+	Quantity        float64  `json:"quantity"`
+	BalanceAfter    *float64 `json:"balance_after"`
+	ReversesID      *int     `json:"reverses_id"`
+	TransactionDate string   `json:"transaction_date"`
+	Remarks         string   `json:"remarks"`
+}
+
+// StockTransfer moves stock between warehouses. It goes draft → dispatched
+// → in_transit → received; lines can only be edited while it is a draft.
+type StockTransfer struct {
+	TransferID      int                 `json:"transfer_id"`
+	FromWarehouseID int                 `json:"from_warehouse_id" validate:"required"`
+	ToWarehouseID   int                 `json:"to_warehouse_id" validate:"required"`
+	Status          string              `json:"status"`
+	ShipmentID      *int                `json:"shipment_id"`
+	TruckID         *int                `json:"truck_id"`
+	Remarks         string              `json:"remarks"`
+	CreatedAt       string              `json:"created_at"`
+	DispatchedAt    *string             `json:"dispatched_at"`
+	InTransitAt     *string             `json:"in_transit_at"`
+	ReceivedAt      *string             `json:"received_at"`
+	Lines           []StockTransferLine `json:"lines,omitempty" validate:"required"`
+}
+
+// StockTransferLine sends Quantity of a stock item of the source warehouse.
+// Receiving records what arrived in the destination stock item and the
+// ledger entries on both sides.
+type StockTransferLine struct {
+	LineID           int      `json:"line_id"`
+	TransferID       int      `json:"transfer_id"`
+	StockID          int      `json:"stock_id"`
+	Quantity         float64  `json:"quantity"`
+	ReceivedQuantity *float64 `json:"received_quantity"`
+	DestStockID      *int     `json:"dest_stock_id"`
+	OutTransactionID *int     `json:"out_transaction_id"`
+	InTransactionID  *int     `json:"in_transaction_id"`
+	DiscrepancyNote  string   `json:"discrepancy_note"`
 }
 
 // ============================================
@@ -272,19 +637,19 @@ type PurchaseOrder struct {
 	POID                 int     `json:"poid"`
 	EmployeeID           int     `json:"employee_id"`
 	SupplierID           int     `json:"supplier_id"`
-	OrderDate            string  `json:"order_date"`
-	ExpectedDeliveryDate string  `json:"expected_delivery_date"`
-	Status               string  `json:"status"`
-	TotalAmount          float64 `json:"total_amount"`
+	OrderDate            string  `json:"order_date" validate:"required,date"`
+	ExpectedDeliveryDate string  `json:"expected_delivery_date" validate:"date,after=order_date"`
+	Status               string  `json:"status" validate:"oneof=pending|approved|received|delivered|completed|cancelled"`
+	TotalAmount          float64 `json:"total_amount" validate:"min=0"`
 }
 
 type PurchaseOrderItem struct {
 	POItemID      int     `json:"po_item_id"`
-	POID          int     `json:"poid"`
+	POID          int     `json:"poid" validate:"required"`
 	ProductTypeID int     `json:"product_type_id"`
-	Quantity      float64 `json:"quantity"`
-	UnitPrice     float64 `json:"unit_price"`
-	Subtotal      float64 `json:"subtotal"`
+	Quantity      float64 `json:"quantity" validate:"required,min=0"`
+	UnitPrice     float64 `json:"unit_price" validate:"min=0"`
+	Subtotal      float64 `json:"subtotal" validate:"min=0"`
 }
 
 // ============================================
@@ -293,7 +658,7 @@ type PurchaseOrderItem struct {
 
 type Customer struct {
 	CustomerID  int    `json:"customer_id"`
-	Name        string `json:"name"`
+	Name        string `json:"name" validate:"required"`
 	Retailer    bool   `json:"retailer"`
 	EndUser     bool   `json:"end_user"`
 	ContactInfo string `json:"contact_info"`
@@ -305,20 +670,21 @@ type SalesOrder struct {
 	SOID         int     `json:"soid"`
 	EmployeeID   int     `json:"employee_id"`
 	CustomerID   int     `json:"customer_id"`
-	OrderDate    string  `json:"order_date"`
-	DeliveryDate string  `json:"delivery_date"`
-	Status       string  `json:"status"`
-	TotalAmount  float64 `json:"total_amount"`
+	OrderDate    string  `json:"order_date" validate:"required,date"`
+	DeliveryDate string  `json:"delivery_date" validate:"date,after=order_date"`
+	Status       string  `json:"status" validate:"oneof=pending|processing|shipped|delivered|completed|cancelled"`
+	TotalAmount  float64 `json:"total_amount" validate:"min=0"`
 }
 
 type SalesOrderItem struct {
 	SOItemID      int     `json:"so_item_id"`
-	SOID          int     `json:"soid"`
+	SOID          int     `json:"soid" validate:"required"`
 	ProductTypeID int     `json:"product_type_id"`
-	Quantity      float64 `json:"quantity"`
-	UnitPrice     float64 `json:"unit_price"`
-	Discount      float64 `json:"discount"`
-	Subtotal      float64 `json:"subtotal"`
+	StockID       *int    `json:"stock_id"` // the lot the item is picked from
+	Quantity      float64 `json:"quantity" validate:"required,min=0"`
+	UnitPrice     float64 `json:"unit_price" validate:"min=0"`
+	Discount      float64 `json:"discount" validate:"min=0"`
+	Subtotal      float64 `json:"subtotal" validate:"min=0"`
 }
 
 // ============================================
@@ -327,23 +693,23 @@ type SalesOrderItem struct {
 
 type Invoice struct {
 	InvoiceID   int     `json:"invoice_id"`
-	SOID        int     `json:"soid"`
-	InvoiceDate string  `json:"invoice_date"`
-	DueDate     string  `json:"due_date"`
-	TotalAmount float64 `json:"total_amount"`
-	Tax         float64 `json:"tax"`
+	SOID        int     `json:"soid" validate:"required"`
+	InvoiceDate string  `json:"invoice_date" validate:"required,date"`
+	DueDate     string  `json:"due_date" validate:"date,after=invoice_date"`
+	TotalAmount float64 `json:"total_amount" validate:"min=0"`
+	Tax         float64 `json:"tax" validate:"min=0"`
 	Currency    string  `json:"currency"`
-	Status      string  `json:"status"`
+	Status      string  `json:"status" validate:"oneof=unpaid|partially_paid|paid|overdue"`
 }
 
 type Payment struct {
 	PaymentID   int     `json:"payment_id"`
-	InvoiceID   int     `json:"invoice_id"`
-	PaymentDate string  `json:"payment_date"`
-	Amount      float64 `json:"amount"`
+	InvoiceID   int     `json:"invoice_id" validate:"required"`
+	PaymentDate string  `json:"payment_date" validate:"required,date"`
+	Amount      float64 `json:"amount" validate:"required,min=0"`
 	Method      string  `json:"method"`
 	ReferenceNo string  `json:"reference_no"`
-	Status      string  `json:"status"`
+	Status      string  `json:"status" validate:"oneof=pending|completed|failed|refunded"`
 }
 
 // ============================================
@@ -352,34 +718,34 @@ type Payment struct {
 
 type TransportCompany struct {
 	CompanyID     int     `json:"company_id"`
-	CompanyName   string  `json:"company_name"`
+	CompanyName   string  `json:"company_name" validate:"required"`
 	ContactInfo   string  `json:"contact_info"`
 	LicenseNumber string  `json:"license_number"`
-	Rating        float64 `json:"rating"`
+	Rating        float64 `json:"rating" validate:"min=0,max=9.99"`
 }
 
 type Truck struct {
 	TruckID     int     `json:"truck_id"`
 	CompanyID   int     `json:"company_id"`
-	PlateNumber string  `json:"plate_number"`
-	Capacity    float64 `json:"capacity"`
+	PlateNumber string  `json:"plate_number" validate:"required"`
+	Capacity    float64 `json:"capacity" validate:"min=0"`
 	FuelType    string  `json:"fuel_type"`
-	Status      string  `json:"status"`
+	Status      string  `json:"status" validate:"oneof=available|in_use|maintenance|out_of_service"`
 }
 
 type Driver struct {
 	DriverID        int    `json:"driver_id"`
-	EmployeeID      int    `json:"employee_id"`
-	LicenseNumber   string `json:"license_number"`
-	ExperienceYears int    `json:"experience_years"`
-	Status          string `json:"status"`
+	EmployeeID      int    `json:"employee_id" validate:"required"`
+	LicenseNumber   string `json:"license_number" validate:"required"`
+	ExperienceYears int    `json:"experience_years" validate:"min=0"`
+	Status          string `json:"status" validate:"oneof=active|inactive|on_leave|suspended"`
 }
 
 type Route struct {
 	RouteID       int     `json:"route_id"`
-	StartLocation string  `json:"start_location"`
-	EndLocation   string  `json:"end_location"`
-	DistanceKM    float64 `json:"distance_km"`
+	StartLocation string  `json:"start_location" validate:"required"`
+	EndLocation   string  `json:"end_location" validate:"required"`
+	DistanceKM    float64 `json:"distance_km" validate:"min=0"`
 	EstimatedTime string  `json:"estimated_time"`
 }
 
@@ -390,8 +756,8 @@ type Shipment struct {
 	DriverID        int    `json:"driver_id"`
 	CompanyID       int    `json:"company_id"`
 	RouteID         int    `json:"route_id"`
-	ShipmentDate    string `json:"shipment_date"`
-	Status          string `json:"status"`
+	ShipmentDate    string `json:"shipment_date" validate:"date"`
+	Status          string `json:"status" validate:"oneof=scheduled|pending|dispatched|in_transit|delivered|cancelled"`
 	ProofOfDelivery string `json:"proof_of_delivery"`
 }
 
@@ -399,8 +765,8 @@ type FuelLog struct {
 	FuelLogID        int     `json:"fuel_log_id"`
 	DriverID         int     `json:"driver_id"`
 	TruckID          int     `json:"truck_id"`
-	TripDate         string  `json:"trip_date"`
-	DistanceTraveled float64 `json:"distance_traveled"`
+	TripDate         string  `json:"trip_date" validate:"date"`
+	DistanceTraveled float64 `json:"distance_traveled" validate:"min=0"`
 }
 
 // ============================================
@@ -408,11 +774,25 @@ type FuelLog struct {
 // ============================================
 
 type AuditLog struct {
-	LogID          int    `json:"log_id"`
-	UserID         int    `json:"user_id"`
-	ActionType     string `json:"action_type"`
-	EntityAffected string `json:"entity_affected"`
-	Timestamp      string `json:"timestamp"`
-	Description    string `json:"description"`
-	IPAddress      string `json:"ip_address"`
-}
\ No newline at end of file
+	LogID          int             `json:"log_id"`
+	UserID         int             `json:"user_id"`
+	ActionType     string          `json:"action_type" validate:"required"`
+	EntityAffected string          `json:"entity_affected"`
+	EntityID       string          `json:"entity_id"`
+	Timestamp      string          `json:"timestamp"`
+	Description    string          `json:"description"`
+	IPAddress      string          `json:"ip_address"`
+	Changes        json.RawMessage `json:"changes,omitempty"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `json:"hash"`
+}
+
+// AuditCheckpoint signs the head of the audit hash chain, so an exported
+// copy proves which entries existed at CreatedAt
+type AuditCheckpoint struct {
+	CheckpointID int    `json:"checkpoint_id"`
+	LogID        int    `json:"log_id"`
+	Hash         string `json:"hash"`
+	CreatedAt    string `json:"created_at"`
+	Signature    string `json:"signature"`
+}
@@ -0,0 +1,564 @@
+// Package ncr runs non-conformance reports (NCR) and their corrective and
+// preventive actions (CAPA). A report goes open → investigating → action →
+// verified → closed: investigating needs an owner, action a root cause and
+// a corrective action, and verified every action done. Reports raised from
+// an inspection take the supplier of its purchase order item and the unit
+// of its processing order.
+//
+// A supplier caused report lowers the quality score of the supplier's
+// latest performance review by its severity's penalty; editing or deleting
+// the report gives the points back first.
+package ncr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/validate"
+)
+
+// Report states, in order
+const (
+	StatusOpen          = "open"
+	StatusInvestigating = "investigating"
+	StatusAction        = "action"
+	StatusVerified      = "verified"
+	StatusClosed        = "closed"
+)
+
+// What caused a non-conformance
+const (
+	OriginSupplier = "supplier"
+	OriginProcess  = "process"
+	OriginOther    = "other"
+)
+
+// Action kinds and states
+const (
+	KindCorrective = "corrective"
+	KindPreventive = "preventive"
+
+	ActionOpen = "open"
+	ActionDone = "done"
+)
+
+// Penalty is how many quality score points a supplier caused report takes
+// off by severity
+var Penalty = map[string]float64{"minor": 2, "major": 5, "critical": 10}
+
+// Service moves reports through their workflow, keeps their corrective
+// actions and charges supplier caused reports to the supplier's score
+type Service struct {
+	tx        repository.Transactor
+	quality   repository.QualityRepository
+	suppliers repository.SupplierRepository
+	plant     repository.ProcessingRepository
+	orders    repository.PurchaseOrderRepository
+	employees repository.EmployeeRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, quality: repos.Quality, suppliers: repos.Suppliers, plant: repos.Processing,
+		orders: repos.PurchaseOrders, employees: repos.Employees}
+}
+
+// Step is what moving a report on may bring: the owner taking it on when
+// investigation starts and the root cause found
+type Step struct {
+	OwnerID   *int   `json:"owner_id"`
+	RootCause string `json:"root_cause"`
+}
+
+// Overdue is an open action past its due date with the report it belongs to
+type Overdue struct {
+	models.CorrectiveAction
+	Title       string `json:"ncr_title"`
+	NCRStatus   string `json:"ncr_status"`
+	DaysOverdue int    `json:"days_overdue"`
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func where(field string, value interface{}) query.Spec {
+	var spec query.Spec
+	spec.Where(field, value)
+	return spec
+}
+
+// exists reports a missing row of a list as a reference error
+func exists[T any](page query.Page[T], err error, field, table string) error {
+	if err != nil {
+		return err
+	}
+	if len(page.Data) == 0 {
+		return &repository.ReferenceError{Field: field, Table: table}
+	}
+	return nil
+}
+
+// employee checks an employee named by field exists
+func (s *Service) employee(ctx context.Context, field string, id *int) error {
+	if id == nil {
+		return nil
+	}
+	page, err := s.employees.ListEmployees(ctx, where("employee_id", *id))
+	return exists(page, err, field, "employee")
+}
+
+// ==================== REPORTS ====================
+
+// link checks what a report refers to and fills in the supplier and unit
+// of its inspection when it names none
+func (s *Service) link(ctx context.Context, nc *models.NonConformance) error {
+	if nc.InspectionID != nil {
+		qi, err := s.quality.GetQualityInspection(ctx, *nc.InspectionID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return &repository.ReferenceError{Field: "inspection_id", Table: "qualityinspection"}
+		}
+		if err != nil {
+			return err
+		}
+		if nc.UnitID == nil && qi.ProcessingID != nil {
+			order, err := s.plant.GetProcessingOrder(ctx, *qi.ProcessingID)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			if err == nil {
+				nc.UnitID = &order.UnitID
+			}
+		}
+		if nc.SupplierID == nil && qi.POItemID != nil {
+			if nc.SupplierID, err = s.supplierOf(ctx, *qi.POItemID); err != nil {
+				return err
+			}
+		}
+	}
+	if nc.SupplierID != nil {
+		page, err := s.suppliers.ListSuppliers(ctx, where("supplier_id", *nc.SupplierID))
+		if err := exists(page, err, "supplier_id", "supplier"); err != nil {
+			return err
+		}
+	}
+	if nc.UnitID != nil {
+		page, err := s.plant.ListProcessingUnits(ctx, where("unit_id", *nc.UnitID))
+		if err := exists(page, err, "unit_id", "processingunit"); err != nil {
+			return err
+		}
+	}
+	if err := s.employee(ctx, "raised_by", nc.RaisedBy); err != nil {
+		return err
+	}
+	if err := s.employee(ctx, "owner_id", nc.OwnerID); err != nil {
+		return err
+	}
+
+	switch {
+	case nc.Origin == OriginSupplier && nc.SupplierID == nil:
+		return validate.Errors{{Field: "supplier_id", Code: validate.CodeRequired,
+			Message: "supplier_id is required of a supplier caused report"}}
+	case nc.Origin == OriginProcess && nc.UnitID == nil:
+		return validate.Errors{{Field: "unit_id", Code: validate.CodeRequired,
+			Message: "unit_id is required of a process caused report"}}
+	}
+	return nil
+}
+
+// supplierOf finds the supplier a purchase order item was bought from
+func (s *Service) supplierOf(ctx context.Context, poItemID int) (*int, error) {
+	items, err := s.orders.ListPurchaseOrderItems(ctx, where("po_item_id", poItemID))
+	if err != nil || len(items.Data) == 0 {
+		return nil, err
+	}
+	orders, err := s.orders.ListPurchaseOrders(ctx, where("poid", items.Data[0].POID))
+	if err != nil || len(orders.Data) == 0 || orders.Data[0].SupplierID == 0 {
+		return nil, err
+	}
+	return &orders.Data[0].SupplierID, nil
+}
+
+func normalize(nc *models.NonConformance) {
+	nc.Origin = validate.Normalize(nc.Origin)
+	nc.Severity = validate.Normalize(nc.Severity)
+	if nc.Severity == "" {
+		nc.Severity = "minor"
+	}
+}
+
+// deduct lowers the quality score of the latest review of the supplier
+// that caused nc by the penalty of its severity, never below zero. A
+// supplier with no review has no score to lower.
+func (s *Service) deduct(ctx context.Context, nc *models.NonConformance) error {
+	nc.ScoreDeducted, nc.PerformanceID = 0, nil
+	if nc.Origin != OriginSupplier || nc.SupplierID == nil {
+		return nil
+	}
+	spec := where("supplier_id", *nc.SupplierID)
+	spec.Sort = []query.Order{{Field: "review_date", Desc: true}, {Field: "performance_id", Desc: true}}
+	spec.Limit = 1
+	reviews, err := s.suppliers.ListSupplierPerformances(ctx, spec)
+	if err != nil || len(reviews.Data) == 0 {
+		return err
+	}
+	id := reviews.Data[0].PerformanceID
+	applied, err := s.suppliers.AdjustQualityScore(ctx, id, -Penalty[nc.Severity])
+	if err != nil || applied == 0 {
+		return err
+	}
+	nc.ScoreDeducted, nc.PerformanceID = round(-applied), &id
+	return nil
+}
+
+// restore gives back the points nc took off its supplier's review, if the
+// review is still there
+func (s *Service) restore(ctx context.Context, nc models.NonConformance) error {
+	if nc.PerformanceID == nil || nc.ScoreDeducted == 0 {
+		return nil
+	}
+	_, err := s.suppliers.AdjustQualityScore(ctx, *nc.PerformanceID, nc.ScoreDeducted)
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Get returns a report with its actions
+func (s *Service) Get(ctx context.Context, id int) (models.NonConformance, error) {
+	nc, err := s.quality.GetNonConformance(ctx, id)
+	if err != nil {
+		return nc, err
+	}
+	actions, err := s.quality.ListCorrectiveActions(ctx, where("ncr_id", id))
+	nc.Actions = actions.Data
+	return nc, err
+}
+
+// Create opens a report, lowering its supplier's score when the supplier
+// caused it
+func (s *Service) Create(ctx context.Context, nc *models.NonConformance) error {
+	normalize(nc)
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.link(ctx, nc); err != nil {
+			return err
+		}
+		nc.Status, nc.ClosedAt, nc.Actions = StatusOpen, nil, nil
+		if err := s.deduct(ctx, nc); err != nil {
+			return err
+		}
+		return s.quality.CreateNonConformance(ctx, nc)
+	})
+}
+
+// Update changes what a report says, keeping its state. Changing who
+// caused it or how severe it is rescores the supplier.
+func (s *Service) Update(ctx context.Context, id int, nc *models.NonConformance) error {
+	normalize(nc)
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		old, err := s.quality.GetNonConformance(ctx, id)
+		if err != nil {
+			return err
+		}
+		if old.Status == StatusClosed {
+			return &repository.RuleError{Code: "ncr_closed", Message: "A closed report cannot be changed",
+				Details: map[string]interface{}{"ncr_id": id}}
+		}
+		if err := s.link(ctx, nc); err != nil {
+			return err
+		}
+		nc.Status, nc.CreatedAt, nc.ClosedAt, nc.Actions = old.Status, old.CreatedAt, old.ClosedAt, nil
+		nc.ScoreDeducted, nc.PerformanceID = old.ScoreDeducted, old.PerformanceID
+		if nc.Origin != old.Origin || nc.Severity != old.Severity || !sameID(nc.SupplierID, old.SupplierID) {
+			if err := s.restore(ctx, old); err != nil {
+				return err
+			}
+			if err := s.deduct(ctx, nc); err != nil {
+				return err
+			}
+		}
+		return s.quality.UpdateNonConformance(ctx, id, nc)
+	})
+}
+
+// Delete removes a report with its actions, giving its supplier the points
+// back
+func (s *Service) Delete(ctx context.Context, id int) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		nc, err := s.quality.GetNonConformance(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.restore(ctx, nc); err != nil {
+			return err
+		}
+		return s.quality.DeleteNonConformance(ctx, id)
+	})
+}
+
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// move loads report id, checks it is in from and stores it as to after
+// check has passed
+func (s *Service) move(ctx context.Context, id int, from, to string, check func(context.Context, *models.NonConformance) error) (models.NonConformance, error) {
+	var nc models.NonConformance
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if nc, err = s.quality.GetNonConformance(ctx, id); err != nil {
+			return err
+		}
+		if nc.Status != from {
+			return &repository.RuleError{
+				Code:    "invalid_transition",
+				Message: fmt.Sprintf("A report that is %s cannot become %s", nc.Status, to),
+				Details: map[string]interface{}{"ncr_id": id, "status": nc.Status, "required_status": from},
+			}
+		}
+		if check != nil {
+			if err := check(ctx, &nc); err != nil {
+				return err
+			}
+		}
+		nc.Status = to
+		if to == StatusClosed {
+			nc.ClosedAt = new(string)
+			*nc.ClosedAt = now()
+		}
+		if err := s.quality.UpdateNonConformance(ctx, id, &nc); err != nil {
+			return err
+		}
+		nc, err = s.Get(ctx, id)
+		return err
+	})
+	return nc, err
+}
+
+// Investigate starts investigating an open report, by the owner the step
+// names or the one it already has
+func (s *Service) Investigate(ctx context.Context, id int, step Step) (models.NonConformance, error) {
+	return s.move(ctx, id, StatusOpen, StatusInvestigating, func(ctx context.Context, nc *models.NonConformance) error {
+		if step.OwnerID != nil {
+			if err := s.employee(ctx, "owner_id", step.OwnerID); err != nil {
+				return err
+			}
+			nc.OwnerID = step.OwnerID
+		}
+		if nc.OwnerID == nil {
+			return validate.Errors{{Field: "owner_id", Code: validate.CodeRequired,
+				Message: "owner_id is required to investigate a report"}}
+		}
+		return nil
+	})
+}
+
+// Act moves an investigated report on to its actions once its root cause
+// is known and a corrective action planned
+func (s *Service) Act(ctx context.Context, id int, step Step) (models.NonConformance, error) {
+	return s.move(ctx, id, StatusInvestigating, StatusAction, func(ctx context.Context, nc *models.NonConformance) error {
+		if step.RootCause != "" {
+			nc.RootCause = step.RootCause
+		}
+		if nc.RootCause == "" {
+			return validate.Errors{{Field: "root_cause", Code: validate.CodeRequired,
+				Message: "root_cause is required to act on a report"}}
+		}
+		spec := where("ncr_id", id)
+		spec.Where("kind", KindCorrective)
+		spec.Limit = 1
+		actions, err := s.quality.ListCorrectiveActions(ctx, spec)
+		if err != nil {
+			return err
+		}
+		if len(actions.Data) == 0 {
+			return &repository.RuleError{Code: "no_corrective_action",
+				Message: "The report has no corrective action planned", Details: map[string]interface{}{"ncr_id": id}}
+		}
+		return nil
+	})
+}
+
+// Verify confirms the actions of a report are all done
+func (s *Service) Verify(ctx context.Context, id int) (models.NonConformance, error) {
+	return s.move(ctx, id, StatusAction, StatusVerified, func(ctx context.Context, nc *models.NonConformance) error {
+		spec := where("ncr_id", id)
+		spec.Where("status", ActionOpen)
+		open, err := s.quality.ListCorrectiveActions(ctx, spec)
+		if err != nil {
+			return err
+		}
+		if len(open.Data) > 0 {
+			ids := make([]int, len(open.Data))
+			for i, ca := range open.Data {
+				ids[i] = ca.ActionID
+			}
+			return &repository.RuleError{Code: "actions_open", Message: "The report has actions not done yet",
+				Details: map[string]interface{}{"ncr_id": id, "action_ids": ids}}
+		}
+		return nil
+	})
+}
+
+// Close closes a verified report
+func (s *Service) Close(ctx context.Context, id int) (models.NonConformance, error) {
+	return s.move(ctx, id, StatusVerified, StatusClosed, nil)
+}
+
+// ==================== ACTIONS ====================
+
+// editable loads the report of an action, which can only be planned or
+// changed until the report is verified
+func (s *Service) editable(ctx context.Context, ncrID int) error {
+	nc, err := s.quality.GetNonConformance(ctx, ncrID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &repository.ReferenceError{Field: "ncr_id", Table: "nonconformance"}
+	}
+	if err != nil {
+		return err
+	}
+	if nc.Status == StatusVerified || nc.Status == StatusClosed {
+		return &repository.RuleError{Code: "ncr_closed",
+			Message: fmt.Sprintf("A report that is %s takes no more actions", nc.Status),
+			Details: map[string]interface{}{"ncr_id": ncrID, "status": nc.Status}}
+	}
+	return nil
+}
+
+// action loads one action
+func (s *Service) action(ctx context.Context, id int) (models.CorrectiveAction, error) {
+	page, err := s.quality.ListCorrectiveActions(ctx, where("action_id", id))
+	if err != nil {
+		return models.CorrectiveAction{}, err
+	}
+	if len(page.Data) == 0 {
+		return models.CorrectiveAction{}, repository.ErrNotFound
+	}
+	return page.Data[0], nil
+}
+
+// CreateAction plans an action of a report
+func (s *Service) CreateAction(ctx context.Context, ca *models.CorrectiveAction) error {
+	ca.Kind = validate.Normalize(ca.Kind)
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.editable(ctx, ca.NCRID); err != nil {
+			return err
+		}
+		if err := s.employee(ctx, "owner_id", &ca.OwnerID); err != nil {
+			return err
+		}
+		ca.Status, ca.CompletedAt = ActionOpen, nil
+		return s.quality.CreateCorrectiveAction(ctx, ca)
+	})
+}
+
+// UpdateAction changes what an action is, who owns it and when it is due,
+// keeping whether it is done
+func (s *Service) UpdateAction(ctx context.Context, id int, ca *models.CorrectiveAction) error {
+	ca.Kind = validate.Normalize(ca.Kind)
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		old, err := s.action(ctx, id)
+		if err != nil {
+			return err
+		}
+		if ca.NCRID == 0 {
+			ca.NCRID = old.NCRID
+		}
+		if ca.NCRID != old.NCRID {
+			return repository.ErrNotFound
+		}
+		if err := s.editable(ctx, old.NCRID); err != nil {
+			return err
+		}
+		if err := s.employee(ctx, "owner_id", &ca.OwnerID); err != nil {
+			return err
+		}
+		ca.Status, ca.CompletedAt = old.Status, old.CompletedAt
+		return s.quality.UpdateCorrectiveAction(ctx, id, ca)
+	})
+}
+
+// DeleteAction drops an action of a report not yet verified; ncrID is 0
+// when the action was not reached through its report
+func (s *Service) DeleteAction(ctx context.Context, ncrID, id int) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		old, err := s.action(ctx, id)
+		if err != nil {
+			return err
+		}
+		if ncrID != 0 && ncrID != old.NCRID {
+			return repository.ErrNotFound
+		}
+		if err := s.editable(ctx, old.NCRID); err != nil {
+			return err
+		}
+		return s.quality.DeleteCorrectiveAction(ctx, id)
+	})
+}
+
+// CompleteAction marks an open action done, with notes on what was done
+func (s *Service) CompleteAction(ctx context.Context, id int, notes string) (models.CorrectiveAction, error) {
+	var ca models.CorrectiveAction
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if ca, err = s.action(ctx, id); err != nil {
+			return err
+		}
+		if ca.Status != ActionOpen {
+			return &repository.RuleError{Code: "invalid_transition", Message: "The action is already done",
+				Details: map[string]interface{}{"action_id": id, "status": ca.Status}}
+		}
+		if err := s.editable(ctx, ca.NCRID); err != nil {
+			return err
+		}
+		ca.Status = ActionDone
+		ca.CompletedAt = new(string)
+		*ca.CompletedAt = now()
+		if notes != "" {
+			ca.Notes = notes
+		}
+		return s.quality.UpdateCorrectiveAction(ctx, id, &ca)
+	})
+	return ca, err
+}
+
+// Overdue lists the open actions due before the day of at, the longest
+// overdue first
+func (s *Service) Overdue(ctx context.Context, at time.Time) ([]Overdue, error) {
+	day := at.UTC().Truncate(24 * time.Hour)
+	actions, err := s.quality.ListCorrectiveActions(ctx, where("status", ActionOpen))
+	if err != nil {
+		return nil, err
+	}
+	overdue := []Overdue{}
+	reports := map[int]models.NonConformance{}
+	for _, ca := range actions.Data {
+		due, err := scheduling.ParseTime(ca.DueDate)
+		if err != nil || !due.Before(day) {
+			continue
+		}
+		nc, ok := reports[ca.NCRID]
+		if !ok {
+			if nc, err = s.quality.GetNonConformance(ctx, ca.NCRID); err != nil {
+				return nil, err
+			}
+			reports[ca.NCRID] = nc
+		}
+		overdue = append(overdue, Overdue{CorrectiveAction: ca, Title: nc.Title, NCRStatus: nc.Status,
+			DaysOverdue: int(day.Sub(due.UTC().Truncate(24*time.Hour)).Hours() / 24)})
+	}
+	return overdue, nil
+}
@@ -0,0 +1,250 @@
+package ncr
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// newQuality stores an employee, a unit, and a supplier reviewed at 80 in
+// January and at 4 in March, with a purchase order item inspected twice
+// and an order on the unit inspected once
+func newQuality(t *testing.T) (repository.Repositories, *Service) {
+	t.Helper()
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	if err := repos.Employees.CreateEmployee(ctx, &models.Employee{FullName: "Ana Quality"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Processing.CreateSawmill(ctx, &models.Sawmill{Name: "Main", Status: "operational"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Processing.CreateProcessingUnit(ctx, &models.ProcessingUnit{SawmillID: 1, Capacity: 10, Status: "active"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Processing.CreateProcessingOrder(ctx, &models.ProcessingOrder{UnitID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Suppliers.CreateSupplier(ctx, &models.Supplier{CompanyName: "Timber Co"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sp := range []models.SupplierPerformance{
+		{SupplierID: 1, QualityScore: 80, ReviewDate: "2026-01-01"},
+		{SupplierID: 1, QualityScore: 4, ReviewDate: "2026-03-01"},
+	} {
+		if err := repos.Suppliers.CreateSupplierPerformance(ctx, &sp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.PurchaseOrders.CreatePurchaseOrder(ctx, &models.PurchaseOrder{SupplierID: 1, OrderDate: "2026-03-02"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.PurchaseOrders.CreatePurchaseOrderItem(ctx, &models.PurchaseOrderItem{POID: 1, Quantity: 10}); err != nil {
+		t.Fatal(err)
+	}
+	item, order := 1, 1
+	for _, qi := range []models.QualityInspection{{POItemID: &item}, {ProcessingID: &order}} {
+		if err := repos.Quality.CreateQualityInspection(ctx, &qi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repos, NewService(repos)
+}
+
+// code is the rule a step broke, or "" when it did not break one
+func code(err error) string {
+	var rule *repository.RuleError
+	if errors.As(err, &rule) {
+		return rule.Code
+	}
+	return ""
+}
+
+// field is the first field a validation or reference error names
+func field(err error) string {
+	var errs validate.Errors
+	if errors.As(err, &errs) && len(errs) > 0 {
+		return errs[0].Field
+	}
+	var ref *repository.ReferenceError
+	if errors.As(err, &ref) {
+		return ref.Field
+	}
+	return ""
+}
+
+// scores lists the quality scores of the supplier's reviews, oldest first
+func scores(t *testing.T, repos repository.Repositories) []float64 {
+	t.Helper()
+	spec := query.Spec{Sort: []query.Order{{Field: "performance_id"}}}
+	page, err := repos.Suppliers.ListSupplierPerformances(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float64
+	for _, sp := range page.Data {
+		got = append(got, sp.QualityScore)
+	}
+	return got
+}
+
+func TestLink(t *testing.T) {
+	ctx := context.Background()
+	_, s := newQuality(t)
+	inspected, unknown := 1, 9
+	for name, tc := range map[string]struct {
+		nc    models.NonConformance
+		field string
+	}{
+		"supplier of the inspection":   {nc: models.NonConformance{Origin: "Supplier", InspectionID: &inspected}},
+		"supplier caused, no supplier": {nc: models.NonConformance{Origin: OriginSupplier}, field: "supplier_id"},
+		"process caused, no unit":      {nc: models.NonConformance{Origin: OriginProcess}, field: "unit_id"},
+		"unknown inspection":           {nc: models.NonConformance{Origin: OriginOther, InspectionID: &unknown}, field: "inspection_id"},
+		"unknown unit":                 {nc: models.NonConformance{Origin: OriginProcess, UnitID: &unknown}, field: "unit_id"},
+		"unknown owner":                {nc: models.NonConformance{Origin: OriginOther, OwnerID: &unknown}, field: "owner_id"},
+	} {
+		nc := tc.nc
+		nc.Title = name
+		if err := s.Create(ctx, &nc); field(err) != tc.field || (tc.field == "") != (err == nil) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	second := 2
+	nc := models.NonConformance{Title: "Checked on the band saw", Origin: OriginProcess, InspectionID: &second}
+	if err := s.Create(ctx, &nc); err != nil || nc.UnitID == nil || *nc.UnitID != 1 {
+		t.Errorf("unit of the inspection = %v, %v", nc.UnitID, err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	_, s := newQuality(t)
+	nc := models.NonConformance{Title: "Cupped boards", Origin: OriginOther}
+	if err := s.Create(ctx, &nc); err != nil || nc.Status != StatusOpen || nc.Severity != "minor" {
+		t.Fatalf("create = %+v, %v", nc, err)
+	}
+	id := nc.NCRID
+	if _, err := s.Act(ctx, id, Step{RootCause: "Drying too fast"}); code(err) != "invalid_transition" {
+		t.Errorf("act on an open report: %v", err)
+	}
+
+	if _, err := s.Investigate(ctx, id, Step{}); field(err) != "owner_id" {
+		t.Errorf("investigate without an owner: %v", err)
+	}
+	owner := 1
+	if got, err := s.Investigate(ctx, id, Step{OwnerID: &owner}); err != nil || got.Status != StatusInvestigating {
+		t.Fatalf("investigate = %+v, %v", got, err)
+	}
+
+	if _, err := s.Act(ctx, id, Step{}); field(err) != "root_cause" {
+		t.Errorf("act without a root cause: %v", err)
+	}
+	prevent := models.CorrectiveAction{NCRID: id, Kind: "Preventive", Description: "Train the kiln crew", OwnerID: 1, DueDate: "2026-01-01"}
+	if err := s.CreateAction(ctx, &prevent); err != nil || prevent.Status != ActionOpen || prevent.Kind != KindPreventive {
+		t.Fatalf("preventive action = %+v, %v", prevent, err)
+	}
+	if _, err := s.Act(ctx, id, Step{RootCause: "Drying too fast"}); code(err) != "no_corrective_action" {
+		t.Errorf("act with only a preventive action: %v", err)
+	}
+	correct := models.CorrectiveAction{NCRID: id, Kind: KindCorrective, Description: "Slow the schedule", OwnerID: 1, DueDate: "2026-01-05"}
+	if err := s.CreateAction(ctx, &correct); err != nil {
+		t.Fatal(err)
+	}
+	acting, err := s.Act(ctx, id, Step{RootCause: "Drying too fast"})
+	if err != nil || acting.Status != StatusAction || acting.RootCause != "Drying too fast" || len(acting.Actions) != 2 {
+		t.Fatalf("act = %+v, %v", acting, err)
+	}
+
+	overdue, err := s.Overdue(ctx, time.Date(2026, 1, 3, 15, 0, 0, 0, time.UTC))
+	if err != nil || len(overdue) != 1 || overdue[0].ActionID != prevent.ActionID || overdue[0].DaysOverdue != 2 ||
+		overdue[0].Title != "Cupped boards" || overdue[0].NCRStatus != StatusAction {
+		t.Errorf("overdue = %+v, %v", overdue, err)
+	}
+
+	_, err = s.Verify(ctx, id)
+	var rule *repository.RuleError
+	if !errors.As(err, &rule) || rule.Code != "actions_open" ||
+		!reflect.DeepEqual(rule.Details.(map[string]interface{})["action_ids"], []int{prevent.ActionID, correct.ActionID}) {
+		t.Errorf("verify with actions open: %v", err)
+	}
+	for _, ca := range []models.CorrectiveAction{prevent, correct} {
+		done, err := s.CompleteAction(ctx, ca.ActionID, "Done")
+		if err != nil || done.Status != ActionDone || done.CompletedAt == nil || done.Notes != "Done" {
+			t.Fatalf("complete = %+v, %v", done, err)
+		}
+	}
+	if _, err := s.CompleteAction(ctx, prevent.ActionID, ""); code(err) != "invalid_transition" {
+		t.Errorf("completing an action twice: %v", err)
+	}
+	if got, err := s.Verify(ctx, id); err != nil || got.Status != StatusVerified {
+		t.Fatalf("verify = %+v, %v", got, err)
+	}
+
+	late := models.CorrectiveAction{NCRID: id, Kind: KindCorrective, Description: "Late", OwnerID: 1, DueDate: "2026-02-01"}
+	if err := s.CreateAction(ctx, &late); code(err) != "ncr_closed" {
+		t.Errorf("action on a verified report: %v", err)
+	}
+	if err := s.DeleteAction(ctx, id, prevent.ActionID); code(err) != "ncr_closed" {
+		t.Errorf("deleting an action of a verified report: %v", err)
+	}
+	closed, err := s.Close(ctx, id)
+	if err != nil || closed.Status != StatusClosed || closed.ClosedAt == nil {
+		t.Fatalf("close = %+v, %v", closed, err)
+	}
+	if _, err := s.Close(ctx, id); code(err) != "invalid_transition" {
+		t.Errorf("closing twice: %v", err)
+	}
+	if err := s.Update(ctx, id, &models.NonConformance{Title: "Reopened", Origin: OriginOther}); code(err) != "ncr_closed" {
+		t.Errorf("update of a closed report: %v", err)
+	}
+}
+
+func TestScore(t *testing.T) {
+	ctx := context.Background()
+	repos, s := newQuality(t)
+	supplier := 1
+
+	// The latest review only has 4 points of the major penalty's 5 to give
+	nc := models.NonConformance{Title: "Wet logs", Origin: OriginSupplier, Severity: "Major", SupplierID: &supplier}
+	if err := s.Create(ctx, &nc); err != nil {
+		t.Fatal(err)
+	}
+	if nc.ScoreDeducted != 4 || nc.PerformanceID == nil || *nc.PerformanceID != 2 {
+		t.Errorf("deducted %g from %v", nc.ScoreDeducted, nc.PerformanceID)
+	}
+	if got := scores(t, repos); !reflect.DeepEqual(got, []float64{80, 0}) {
+		t.Errorf("scores after create = %v", got)
+	}
+
+	nc.Severity = "minor"
+	if err := s.Update(ctx, nc.NCRID, &nc); err != nil || nc.ScoreDeducted != 2 {
+		t.Errorf("update = %+v, %v", nc, err)
+	}
+	if got := scores(t, repos); !reflect.DeepEqual(got, []float64{80, 2}) {
+		t.Errorf("scores after update = %v", got)
+	}
+	nc.Title = "Wet logs again"
+	if err := s.Update(ctx, nc.NCRID, &nc); err != nil || scores(t, repos)[1] != 2 {
+		t.Errorf("retitling rescored: %v, %v", err, scores(t, repos))
+	}
+
+	other := models.NonConformance{Title: "Wet logs", Origin: OriginOther, SupplierID: &supplier}
+	if err := s.Create(ctx, &other); err != nil || other.ScoreDeducted != 0 || other.PerformanceID != nil {
+		t.Errorf("report not caused by the supplier = %+v, %v", other, err)
+	}
+
+	if err := s.Delete(ctx, nc.NCRID); err != nil {
+		t.Fatal(err)
+	}
+	if got := scores(t, repos); !reflect.DeepEqual(got, []float64{80, 4}) {
+		t.Errorf("scores after delete = %v", got)
+	}
+}
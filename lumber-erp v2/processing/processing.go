@@ -0,0 +1,427 @@
+// Package processing runs the lifecycle of processing orders. An order is
+// planned, released to the floor, started and completed; completion issues
+// the harvest batches it consumed from their lots, receives its output into
+// a warehouse and computes its yield and mass balance. Closing it freezes
+// the figures once late waste records are in.
+package processing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// Order states, in the order an order goes through them
+const (
+	StatusPlanned    = "planned"
+	StatusReleased   = "released"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusClosed     = "closed"
+)
+
+// Operations a processing unit can perform
+const (
+	OpCutting   = "cutting"
+	OpDrying    = "drying"
+	OpFinishing = "finishing"
+)
+
+// Operations splits the comma-separated operations an order needs
+func Operations(po models.ProcessingOrder) []string {
+	var ops []string
+	for _, op := range strings.Split(po.Operations, ",") {
+		if op = strings.ToLower(strings.TrimSpace(op)); op != "" {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// checkOperations refuses operations no unit can perform
+func checkOperations(po models.ProcessingOrder) error {
+	for _, op := range Operations(po) {
+		if op != OpCutting && op != OpDrying && op != OpFinishing {
+			return validate.Errors{{Field: "operations", Code: validate.CodeInvalidChoice,
+				Message: fmt.Sprintf("operations: %q must be one of cutting, drying, finishing", op)}}
+		}
+	}
+	return nil
+}
+
+// tolerance is the share of the input volume, in percent, that input may
+// differ from output plus waste before an order is flagged
+var tolerance = 2.0
+
+// SetMassBalanceTolerance sets the percentage of the input volume an order's
+// mass balance may be off by before it is flagged
+func SetMassBalanceTolerance(percent float64) {
+	tolerance = percent
+}
+
+// Service moves processing orders through their lifecycle, attaching the
+// harvest batches they consume and posting the stock they use and produce
+type Service struct {
+	tx     repository.Transactor
+	orders repository.ProcessingRepository
+	stock  repository.StockRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, orders: repos.Processing, stock: repos.Stock}
+}
+
+// Completion is what the floor reports when an order is done
+type Completion struct {
+	OutputQuantity *float64 `json:"output_quantity"`
+	// WarehouseID overrides the order's warehouse for the output
+	WarehouseID *int `json:"warehouse_id"`
+	EmployeeID  *int `json:"employee_id"`
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// transition refuses a step the order's status does not allow
+func transition(po models.ProcessingOrder, from, to string) error {
+	if po.Status == from {
+		return nil
+	}
+	return &repository.RuleError{
+		Code:    "invalid_transition",
+		Message: fmt.Sprintf("A %s processing order cannot become %s", po.Status, to),
+		Details: map[string]interface{}{"processing_id": po.ProcessingID, "status": po.Status, "required_status": from},
+	}
+}
+
+// editable refuses changes to an order that is already underway
+func editable(po models.ProcessingOrder, what string) error {
+	if po.Status == StatusPlanned || po.Status == StatusReleased {
+		return nil
+	}
+	return &repository.RuleError{
+		Code:    "not_editable",
+		Message: fmt.Sprintf("Only a planned or released processing order can %s", what),
+		Details: map[string]interface{}{"processing_id": po.ProcessingID, "status": po.Status},
+	}
+}
+
+// Create stores po as planned and unscheduled; the computed figures start
+// at zero
+func (s *Service) Create(ctx context.Context, po *models.ProcessingOrder) error {
+	if err := checkOperations(*po); err != nil {
+		return err
+	}
+	*po = models.ProcessingOrder{
+		ProductTypeID:   po.ProductTypeID,
+		UnitID:          po.UnitID,
+		StartDate:       po.StartDate,
+		EndDate:         po.EndDate,
+		WarehouseID:     po.WarehouseID,
+		PlannedQuantity: po.PlannedQuantity,
+		DurationHours:   po.DurationHours,
+		Operations:      strings.Join(Operations(*po), ","),
+		Status:          StatusPlanned,
+	}
+	return s.orders.CreateProcessingOrder(ctx, po)
+}
+
+// Update replaces what was planned of an order that has not started yet.
+// Changing its unit, size or operations drops its scheduled slot.
+func (s *Service) Update(ctx context.Context, id int, po *models.ProcessingOrder) error {
+	if err := checkOperations(*po); err != nil {
+		return err
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		old, err := s.orders.GetProcessingOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := editable(old, "be changed"); err != nil {
+			return err
+		}
+		operations := strings.Join(Operations(*po), ",")
+		if po.UnitID != old.UnitID || po.PlannedQuantity != old.PlannedQuantity ||
+			po.DurationHours != old.DurationHours || operations != old.Operations {
+			old.ScheduledStart, old.ScheduledEnd = nil, nil
+		}
+		old.ProductTypeID, old.UnitID, old.WarehouseID = po.ProductTypeID, po.UnitID, po.WarehouseID
+		old.StartDate, old.EndDate = po.StartDate, po.EndDate
+		old.PlannedQuantity, old.DurationHours, old.Operations = po.PlannedQuantity, po.DurationHours, operations
+		return s.orders.UpdateProcessingOrder(ctx, id, &old)
+	})
+}
+
+// Delete removes an order that has not started yet
+func (s *Service) Delete(ctx context.Context, id int) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		po, err := s.orders.GetProcessingOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := editable(po, "be deleted"); err != nil {
+			return err
+		}
+		return s.orders.DeleteProcessingOrder(ctx, id)
+	})
+}
+
+// inputsOpen refuses changes to the inputs of a completed order, whose
+// consumption is already posted
+func inputsOpen(po models.ProcessingOrder) error {
+	if po.Status == StatusCompleted || po.Status == StatusClosed {
+		return &repository.RuleError{
+			Code:    "not_editable",
+			Message: "The inputs of a completed processing order cannot change",
+			Details: map[string]interface{}{"processing_id": po.ProcessingID, "status": po.Status},
+		}
+	}
+	return nil
+}
+
+// Attach records that the order consumes part of a harvest batch
+func (s *Service) Attach(ctx context.Context, link *models.HarvestBatchProcessing) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		po, err := s.orders.GetProcessingOrder(ctx, link.ProcessingID)
+		if err == repository.ErrNotFound {
+			return &repository.ReferenceError{Field: "processing_id", Table: "processingorder"}
+		}
+		if err != nil {
+			return err
+		}
+		if err := inputsOpen(po); err != nil {
+			return err
+		}
+		return s.orders.AttachHarvestBatch(ctx, link)
+	})
+}
+
+// Detach removes a harvest batch from the inputs of an order
+func (s *Service) Detach(ctx context.Context, processingID, batchID int) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		po, err := s.orders.GetProcessingOrder(ctx, processingID)
+		if err != nil {
+			return err
+		}
+		if err := inputsOpen(po); err != nil {
+			return err
+		}
+		return s.orders.DetachHarvestBatch(ctx, processingID, batchID)
+	})
+}
+
+// step loads order id, checks it may go from → to and stores it after
+// change has run
+func (s *Service) step(ctx context.Context, id int, from, to string, change func(context.Context, *models.ProcessingOrder) error) (models.ProcessingOrder, error) {
+	var po models.ProcessingOrder
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if po, err = s.orders.GetProcessingOrder(ctx, id); err != nil {
+			return err
+		}
+		if err := transition(po, from, to); err != nil {
+			return err
+		}
+		if change != nil {
+			if err := change(ctx, &po); err != nil {
+				return err
+			}
+		}
+		po.Status = to
+		return s.orders.UpdateProcessingOrder(ctx, id, &po)
+	})
+	return po, err
+}
+
+// Release hands a planned order to the floor
+func (s *Service) Release(ctx context.Context, id int) (models.ProcessingOrder, error) {
+	return s.step(ctx, id, StatusPlanned, StatusReleased, nil)
+}
+
+// Start begins a released order, dating it today unless a start date was
+// planned
+func (s *Service) Start(ctx context.Context, id int) (models.ProcessingOrder, error) {
+	return s.step(ctx, id, StatusReleased, StatusInProgress, func(ctx context.Context, po *models.ProcessingOrder) error {
+		if po.StartDate == "" {
+			po.StartDate = today()
+		}
+		return nil
+	})
+}
+
+// Complete finishes an order in progress: it issues each input batch from
+// the lots holding it, receives the output into the order's warehouse as a
+// lot of the order and computes yield and mass balance
+func (s *Service) Complete(ctx context.Context, id int, c Completion) (models.ProcessingOrder, error) {
+	return s.step(ctx, id, StatusInProgress, StatusCompleted, func(ctx context.Context, po *models.ProcessingOrder) error {
+		if c.WarehouseID != nil {
+			po.WarehouseID = c.WarehouseID
+		}
+		var errs validate.Errors
+		if c.OutputQuantity == nil {
+			errs = append(errs, validate.FieldError{Field: "output_quantity", Code: validate.CodeRequired,
+				Message: "output_quantity is required"})
+		} else if *c.OutputQuantity < 0 {
+			errs = append(errs, validate.FieldError{Field: "output_quantity", Code: validate.CodeTooSmall,
+				Message: "output_quantity must be at least 0"})
+		}
+		if po.WarehouseID == nil {
+			errs = append(errs, validate.FieldError{Field: "warehouse_id", Code: validate.CodeRequired,
+				Message: "warehouse_id is required unless the order has one"})
+		}
+		if errs != nil {
+			return errs
+		}
+		if len(po.Inputs) == 0 {
+			return &repository.RuleError{Code: "no_inputs", Message: "Attach the harvest batches consumed before completing the order",
+				Details: map[string]interface{}{"processing_id": id}}
+		}
+
+		for _, input := range po.Inputs {
+			if err := s.consume(ctx, *po, input, c.EmployeeID); err != nil {
+				return err
+			}
+		}
+		po.OutputQuantity = round(*c.OutputQuantity)
+		if po.OutputQuantity > 0 {
+			if err := s.receive(ctx, *po, c.EmployeeID); err != nil {
+				return err
+			}
+		}
+		if po.EndDate == "" {
+			po.EndDate = today()
+		}
+		return s.balance(ctx, po)
+	})
+}
+
+// Close freezes a completed order, recomputing its mass balance so waste
+// recorded after completion counts
+func (s *Service) Close(ctx context.Context, id int) (models.ProcessingOrder, error) {
+	return s.step(ctx, id, StatusCompleted, StatusClosed, s.balance)
+}
+
+// consume issues the quantity the order consumed of a harvest batch from
+// the lots of the batch, oldest first. Lots in quarantine or rejected are
+// passed over; when the rest fall short, the first of them is the error.
+func (s *Service) consume(ctx context.Context, po models.ProcessingOrder, input models.HarvestBatchProcessing, employeeID *int) error {
+	spec := query.Spec{Sort: []query.Order{{Field: "stock_id"}}}
+	spec.Where("batch_id", input.BatchID)
+	lots, err := s.stock.LockStockItems(ctx, spec)
+	if err != nil {
+		return err
+	}
+	remaining := input.ConsumedQuantity
+	var held error
+	for _, lot := range lots {
+		// Lots of the batch that are already processing output are not raw
+		if lot.ProcessingID != nil || lot.Quantity <= 0 || remaining <= 0 {
+			continue
+		}
+		if err := repository.CheckStockAvailable(lot); err != nil {
+			if held == nil {
+				held = err
+			}
+			continue
+		}
+		issued := math.Min(lot.Quantity, remaining)
+		it := models.InventoryTransaction{
+			EmployeeID:      employeeID,
+			StockID:         lot.StockID,
+			TransactionType: repository.TxIssue,
+			Quantity:        repository.StockMovement(repository.TxIssue, issued),
+			Remarks:         fmt.Sprintf("Consumed by processing order %d", po.ProcessingID),
+		}
+		if err := s.stock.CreateInventoryTransaction(ctx, &it); err != nil {
+			return err
+		}
+		remaining = round(remaining - issued)
+	}
+	if remaining > 0 && held != nil {
+		return held
+	}
+	if remaining > 0 {
+		return &repository.RuleError{
+			Code:    "insufficient_stock",
+			Message: fmt.Sprintf("The lots of harvest batch %d do not hold the %g the order consumed", input.BatchID, input.ConsumedQuantity),
+			Details: map[string]interface{}{"batch_id": input.BatchID, "requested": input.ConsumedQuantity, "missing": remaining},
+		}
+	}
+	return nil
+}
+
+// receive books the output into the order's lot in its warehouse, creating
+// the lot when there is none
+func (s *Service) receive(ctx context.Context, po models.ProcessingOrder, employeeID *int) error {
+	spec := query.Spec{}
+	spec.Where("processing_id", po.ProcessingID)
+	spec.Where("warehouse_id", *po.WarehouseID)
+	spec.Where("product_type_id", po.ProductTypeID)
+	page, err := s.stock.ListStockItems(ctx, spec)
+	if err != nil {
+		return err
+	}
+	var lot models.StockItem
+	if len(page.Data) > 0 {
+		lot = page.Data[0]
+	} else {
+		processingID := po.ProcessingID
+		lot = models.StockItem{ProductTypeID: po.ProductTypeID, WarehouseID: *po.WarehouseID, ProcessingID: &processingID}
+		if err := s.stock.CreateStockItem(ctx, &lot); err != nil {
+			return err
+		}
+	}
+	it := models.InventoryTransaction{
+		EmployeeID:      employeeID,
+		StockID:         lot.StockID,
+		TransactionType: repository.TxReceipt,
+		Quantity:        po.OutputQuantity,
+		Remarks:         fmt.Sprintf("Output of processing order %d", po.ProcessingID),
+	}
+	return s.stock.CreateInventoryTransaction(ctx, &it)
+}
+
+// balance computes yield and mass balance from the inputs, the output and
+// the waste recorded against the order
+func (s *Service) balance(ctx context.Context, po *models.ProcessingOrder) error {
+	spec := query.Spec{}
+	spec.Where("processing_id", po.ProcessingID)
+	waste, err := s.orders.ListWasteRecords(ctx, spec)
+	if err != nil {
+		return err
+	}
+	massBalance(po, waste.Data)
+	return nil
+}
+
+// massBalance flags an order whose input differs from its output plus waste
+// by more than the tolerance
+func massBalance(po *models.ProcessingOrder, waste []models.WasteRecord) {
+	po.InputQuantity, po.WasteQuantity = 0, 0
+	for _, input := range po.Inputs {
+		po.InputQuantity += input.ConsumedQuantity
+	}
+	for _, w := range waste {
+		po.WasteQuantity += w.Volume
+	}
+	po.InputQuantity, po.WasteQuantity = round(po.InputQuantity), round(po.WasteQuantity)
+
+	po.EfficiencyRate = 0
+	if po.InputQuantity > 0 {
+		po.EfficiencyRate = round(po.OutputQuantity / po.InputQuantity * 100)
+	}
+	po.MassBalanceVariance = round(po.InputQuantity - po.OutputQuantity - po.WasteQuantity)
+	po.MassBalanceFlagged = math.Abs(po.MassBalanceVariance) > po.InputQuantity*tolerance/100
+}
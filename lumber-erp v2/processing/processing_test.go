@@ -0,0 +1,70 @@
+package processing
+
+import (
+	"testing"
+
+	"lumber-erp-api/models"
+)
+
+func TestMassBalance(t *testing.T) {
+	defer SetMassBalanceTolerance(tolerance)
+	SetMassBalanceTolerance(2)
+
+	inputs := []models.HarvestBatchProcessing{{ConsumedQuantity: 60}, {ConsumedQuantity: 40}}
+	for _, tc := range []struct {
+		name     string
+		output   float64
+		waste    []float64
+		variance float64
+		flagged  bool
+	}{
+		{"balanced", 80, []float64{15, 5}, 0, false},
+		{"at the tolerance", 80, []float64{18}, 2, false},
+		{"over the tolerance", 80, []float64{17.99}, 2.01, true},
+		{"more out than in", 90, []float64{12.5}, -2.5, true},
+		{"nothing weighed", 0, nil, 100, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			po := models.ProcessingOrder{Inputs: inputs, OutputQuantity: tc.output}
+			var waste []models.WasteRecord
+			for _, v := range tc.waste {
+				waste = append(waste, models.WasteRecord{Volume: v})
+			}
+			massBalance(&po, waste)
+			if po.InputQuantity != 100 || po.EfficiencyRate != tc.output {
+				t.Errorf("input %v, efficiency %v", po.InputQuantity, po.EfficiencyRate)
+			}
+			if po.MassBalanceVariance != tc.variance || po.MassBalanceFlagged != tc.flagged {
+				t.Errorf("variance %v flagged %v, want %v %v", po.MassBalanceVariance, po.MassBalanceFlagged, tc.variance, tc.flagged)
+			}
+		})
+	}
+
+	// A wider tolerance lets the same order through
+	SetMassBalanceTolerance(5)
+	po := models.ProcessingOrder{Inputs: inputs, OutputQuantity: 90}
+	massBalance(&po, []models.WasteRecord{{Volume: 12.5}})
+	if po.MassBalanceFlagged {
+		t.Errorf("flagged within a 5%% tolerance: %+v", po)
+	}
+
+	// An order without inputs has no yield to report
+	empty := models.ProcessingOrder{OutputQuantity: 3}
+	massBalance(&empty, nil)
+	if empty.EfficiencyRate != 0 || empty.MassBalanceVariance != -3 || !empty.MassBalanceFlagged {
+		t.Errorf("order without inputs = %+v", empty)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	po := models.ProcessingOrder{Operations: " Cutting, drying ,,FINISHING"}
+	if ops := Operations(po); len(ops) != 3 || ops[0] != OpCutting || ops[2] != OpFinishing {
+		t.Fatalf("operations = %q", ops)
+	}
+	if err := checkOperations(po); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkOperations(models.ProcessingOrder{Operations: "cutting,planing"}); err == nil {
+		t.Fatal("planing accepted")
+	}
+}
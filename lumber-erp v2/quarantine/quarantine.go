@@ -0,0 +1,389 @@
+// Package quarantine holds nonconforming stock. A failed quality inspection
+// moves the lots it looked at into quarantine, where they cannot be sold
+// or shipped until a disposition releases them, reworks them in a new
+// processing order, scraps them as waste or returns them to the supplier.
+// Every status step of a lot is recorded as a stock disposition.
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// Disposition actions
+const (
+	ActionQuarantine       = "quarantine"
+	ActionRelease          = "release"
+	ActionRework           = "rework"
+	ActionScrap            = "scrap"
+	ActionReturnToSupplier = "return_to_supplier"
+	ActionReserve          = "reserve"
+	ActionUnreserve        = "unreserve"
+)
+
+// steps lists the statuses each action applies to and the status it moves
+// the lot to. Rework, scrap and returns leave a lot in quarantine until
+// they take all of it, which rejects it.
+var steps = map[string]struct {
+	from []string
+	to   string
+}{
+	ActionQuarantine:       {[]string{repository.StockAvailable, repository.StockReserved}, repository.StockQuarantine},
+	ActionRelease:          {[]string{repository.StockQuarantine}, repository.StockAvailable},
+	ActionRework:           {[]string{repository.StockQuarantine}, repository.StockRejected},
+	ActionScrap:            {[]string{repository.StockQuarantine}, repository.StockRejected},
+	ActionReturnToSupplier: {[]string{repository.StockQuarantine}, repository.StockRejected},
+	ActionReserve:          {[]string{repository.StockAvailable}, repository.StockReserved},
+	ActionUnreserve:        {[]string{repository.StockReserved}, repository.StockAvailable},
+}
+
+// Service holds and disposes of nonconforming lots and refuses sales and
+// shipments of stock that is not sellable
+type Service struct {
+	tx         repository.Transactor
+	stock      repository.StockRepository
+	plant      repository.ProcessingRepository
+	sales      repository.SalesOrderRepository
+	processing *processing.Service
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, stock: repos.Stock, plant: repos.Processing, sales: repos.SalesOrders,
+		processing: processing.NewService(repos)}
+}
+
+// Disposition is what to do with a lot. Quantity defaults to all of it and
+// only applies to rework, scrap and returns. Rework needs the unit to run
+// the order on; scrap books the waste against ProcessingID, which defaults
+// to the order the lot is output of.
+type Disposition struct {
+	Action         string   `json:"action" validate:"required,oneof=quarantine|release|rework|scrap|return_to_supplier|reserve|unreserve"`
+	Quantity       *float64 `json:"quantity"`
+	Reason         string   `json:"reason"`
+	EmployeeID     *int     `json:"employee_id"`
+	UnitID         int      `json:"unit_id"`
+	Operations     string   `json:"operations"`
+	ProcessingID   *int     `json:"processing_id"`
+	DisposalMethod string   `json:"disposal_method"`
+	DisposalCost   float64  `json:"disposal_cost" validate:"min=0"`
+}
+
+// Outcome is a recorded disposition with the lot after it and the rows it
+// created
+type Outcome struct {
+	Disposition models.StockDisposition      `json:"disposition"`
+	Stock       models.StockItem             `json:"stock"`
+	Order       *models.ProcessingOrder      `json:"order,omitempty"`
+	Waste       *models.WasteRecord          `json:"waste,omitempty"`
+	Transaction *models.InventoryTransaction `json:"transaction,omitempty"`
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// lot loads a lot, locked until the caller's transaction ends
+func (s *Service) lot(ctx context.Context, id int) (models.StockItem, error) {
+	var spec query.Spec
+	spec.Where("stock_id", id)
+	lots, err := s.stock.LockStockItems(ctx, spec)
+	if err != nil {
+		return models.StockItem{}, err
+	}
+	if len(lots) == 0 {
+		return models.StockItem{}, repository.ErrNotFound
+	}
+	return lots[0], nil
+}
+
+// Sellable reports whether a lot's status lets it be sold and shipped
+func Sellable(status string) bool {
+	return repository.CheckStockAvailable(models.StockItem{Status: status}) == nil
+}
+
+// ==================== HOLDS ====================
+
+// Hold quarantines the lots a failed inspection looked at: the output of
+// its processing order, the lots received against its purchase order item
+// or, for a harvest batch alone, the batch's raw lots. Lots already held
+// or rejected are left as they are.
+func (s *Service) Hold(ctx context.Context, qi models.QualityInspection) ([]models.StockDisposition, error) {
+	var spec query.Spec
+	switch {
+	case qi.ProcessingID != nil:
+		spec.Where("processing_id", *qi.ProcessingID)
+	case qi.POItemID != nil:
+		spec.Where("po_item_id", *qi.POItemID)
+	case qi.BatchID != 0:
+		spec.Where("batch_id", qi.BatchID)
+	default:
+		return nil, nil
+	}
+	var employeeID *int
+	if qi.EmployeeID != 0 {
+		employeeID = &qi.EmployeeID
+	}
+	rawOnly := qi.ProcessingID == nil && qi.POItemID == nil
+	return s.hold(ctx, spec, rawOnly, qi.InspectionID, employeeID,
+		fmt.Sprintf("Failed inspection %d", qi.InspectionID))
+}
+
+// HoldReceipt quarantines the lots received against a purchase order item
+// for reason, recording the inspection that decided it
+func (s *Service) HoldReceipt(ctx context.Context, poItemID, inspectionID int, employeeID *int, reason string) ([]models.StockDisposition, error) {
+	var spec query.Spec
+	spec.Where("po_item_id", poItemID)
+	return s.hold(ctx, spec, false, inspectionID, employeeID, reason)
+}
+
+// hold quarantines the sellable lots of spec, skipping processing output
+// when rawOnly is set. The lots are locked so nothing issues, transfers or
+// allocates them between the read and the status change.
+func (s *Service) hold(ctx context.Context, spec query.Spec, rawOnly bool, inspectionID int, employeeID *int, reason string) ([]models.StockDisposition, error) {
+	var held []models.StockDisposition
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		lots, err := s.stock.LockStockItems(ctx, spec)
+		if err != nil {
+			return err
+		}
+		for _, lot := range lots {
+			if rawOnly && lot.ProcessingID != nil {
+				continue
+			}
+			if !Sellable(lot.Status) {
+				continue
+			}
+			inspectionID := inspectionID
+			sd := models.StockDisposition{
+				StockID:      lot.StockID,
+				Action:       ActionQuarantine,
+				FromStatus:   lot.Status,
+				ToStatus:     repository.StockQuarantine,
+				Quantity:     lot.Quantity,
+				InspectionID: &inspectionID,
+				EmployeeID:   employeeID,
+				Reason:       reason,
+			}
+			if err := s.stock.CreateStockDisposition(ctx, &sd); err != nil {
+				return err
+			}
+			held = append(held, sd)
+		}
+		return nil
+	})
+	return held, err
+}
+
+// ==================== DISPOSITIONS ====================
+
+// quantity checks the part of a lot a disposition takes
+func quantity(lot models.StockItem, d Disposition) (float64, error) {
+	q := lot.Quantity
+	if d.Quantity != nil {
+		q = round(*d.Quantity)
+	}
+	switch {
+	case q <= 0:
+		return 0, validate.Errors{{Field: "quantity", Code: validate.CodeTooSmall,
+			Message: "quantity must be more than 0"}}
+	case q > lot.Quantity:
+		return 0, validate.Errors{{Field: "quantity", Code: validate.CodeTooLarge,
+			Message: fmt.Sprintf("quantity must not exceed the %g the lot holds", lot.Quantity)}}
+	}
+	return q, nil
+}
+
+// Dispose applies a disposition to a lot and records it
+func (s *Service) Dispose(ctx context.Context, stockID int, d Disposition) (Outcome, error) {
+	var out Outcome
+	d.Action = validate.Normalize(d.Action)
+	step, ok := steps[d.Action]
+	if !ok {
+		return out, validate.Errors{{Field: "action", Code: validate.CodeInvalidChoice,
+			Message: "action must be one of quarantine, release, rework, scrap, return_to_supplier, reserve, unreserve"}}
+	}
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		lot, err := s.lot(ctx, stockID)
+		if err != nil {
+			return err
+		}
+		allowed := false
+		for _, from := range step.from {
+			allowed = allowed || lot.Status == from
+		}
+		if !allowed {
+			return &repository.RuleError{Code: "invalid_status",
+				Message: fmt.Sprintf("A %s stock item cannot be given a %s disposition", lot.Status, d.Action),
+				Details: map[string]interface{}{"stock_id": stockID, "status": lot.Status, "action": d.Action}}
+		}
+
+		sd := models.StockDisposition{StockID: stockID, Action: d.Action, FromStatus: lot.Status, ToStatus: step.to,
+			Quantity: lot.Quantity, EmployeeID: d.EmployeeID, Reason: d.Reason}
+		switch d.Action {
+		case ActionRework, ActionScrap, ActionReturnToSupplier:
+			if sd.Quantity, err = quantity(lot, d); err != nil {
+				return err
+			}
+			if sd.Quantity < lot.Quantity {
+				sd.ToStatus = lot.Status
+			}
+			if err := s.dispose(ctx, lot, d, &sd, &out); err != nil {
+				return err
+			}
+		}
+		if err := s.stock.CreateStockDisposition(ctx, &sd); err != nil {
+			return err
+		}
+		out.Disposition = sd
+		out.Stock, err = s.lot(ctx, stockID)
+		return err
+	})
+	return out, err
+}
+
+// dispose creates the rework order or waste record of a disposition and
+// takes its quantity out of the lot
+func (s *Service) dispose(ctx context.Context, lot models.StockItem, d Disposition, sd *models.StockDisposition, out *Outcome) error {
+	entry := models.InventoryTransaction{EmployeeID: d.EmployeeID, StockID: lot.StockID,
+		TransactionType: repository.TxIssue}
+	switch d.Action {
+	case ActionRework:
+		if d.UnitID == 0 {
+			return validate.Errors{{Field: "unit_id", Code: validate.CodeRequired,
+				Message: "unit_id is required to rework stock"}}
+		}
+		warehouseID := lot.WarehouseID
+		order := models.ProcessingOrder{ProductTypeID: lot.ProductTypeID, UnitID: d.UnitID, WarehouseID: &warehouseID,
+			PlannedQuantity: sd.Quantity, Operations: d.Operations}
+		if err := s.processing.Create(ctx, &order); err != nil {
+			return err
+		}
+		sd.ProcessingID, out.Order = &order.ProcessingID, &order
+		entry.Remarks = fmt.Sprintf("Issued to rework order %d", order.ProcessingID)
+
+	case ActionScrap:
+		processingID := d.ProcessingID
+		if processingID == nil {
+			processingID = lot.ProcessingID
+		}
+		if processingID == nil {
+			return validate.Errors{{Field: "processing_id", Code: validate.CodeRequired,
+				Message: "processing_id is required to scrap stock that is not the output of an order"}}
+		}
+		method := d.DisposalMethod
+		if method == "" {
+			method = "scrap"
+		}
+		waste := models.WasteRecord{ProcessingID: *processingID, WasteType: "Rejected stock", Volume: sd.Quantity,
+			DisposalMethod: method, DisposalCost: d.DisposalCost}
+		if err := s.plant.CreateWasteRecord(ctx, &waste); err != nil {
+			return err
+		}
+		sd.WasteID, out.Waste = &waste.WasteID, &waste
+		entry.TransactionType = repository.TxScrap
+		entry.Remarks = fmt.Sprintf("Scrapped as waste record %d", waste.WasteID)
+
+	case ActionReturnToSupplier:
+		if lot.POItemID == nil {
+			return &repository.RuleError{Code: "not_purchased",
+				Message: "Only stock received against a purchase order item can be returned to the supplier",
+				Details: map[string]interface{}{"stock_id": lot.StockID}}
+		}
+		entry.Remarks = fmt.Sprintf("Returned to supplier against purchase order item %d", *lot.POItemID)
+	}
+	if d.Reason != "" {
+		entry.Remarks += ": " + d.Reason
+	}
+	entry.Quantity = repository.StockMovement(entry.TransactionType, sd.Quantity)
+	if err := s.stock.CreateInventoryTransaction(ctx, &entry); err != nil {
+		return err
+	}
+	sd.TransactionID, out.Transaction = &entry.TransactionID, &entry
+	return nil
+}
+
+// ==================== STOCK LEDGER ====================
+
+// CheckIssue refuses to issue, scrap or transfer out stock by hand from a
+// lot in quarantine or rejected, which only its dispositions may move. Call
+// it within the transaction posting the entry: the lot stays locked until
+// it ends.
+func (s *Service) CheckIssue(ctx context.Context, it models.InventoryTransaction) error {
+	switch it.TransactionType {
+	case repository.TxIssue, repository.TxScrap, repository.TxTransferOut:
+	default:
+		return nil
+	}
+	lot, err := s.lot(ctx, it.StockID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &repository.ReferenceError{Field: "stock_id", Table: "stockitem"}
+	}
+	if err != nil {
+		return err
+	}
+	return repository.CheckStockAvailable(lot)
+}
+
+// ==================== SALES AND SHIPMENTS ====================
+
+// CheckAllocation refuses to pick a sales order item from a lot in
+// quarantine or rejected. Call it within the transaction saving the item:
+// the lot stays locked until it ends.
+func (s *Service) CheckAllocation(ctx context.Context, soi models.SalesOrderItem) error {
+	if soi.StockID == nil {
+		return nil
+	}
+	lot, err := s.lot(ctx, *soi.StockID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &repository.ReferenceError{Field: "stock_id", Table: "stockitem"}
+	}
+	if err != nil {
+		return err
+	}
+	return repository.CheckStockAvailable(lot)
+}
+
+// CheckShipment refuses to ship a sales order whose items are picked from
+// lots in quarantine or rejected. Cancelling a shipment is always allowed.
+// Call it within the transaction saving the shipment, which keeps the lots
+// locked.
+func (s *Service) CheckShipment(ctx context.Context, ship models.Shipment) error {
+	if validate.Normalize(ship.Status) == "cancelled" || ship.SOID == 0 {
+		return nil
+	}
+	var spec query.Spec
+	spec.Where("soid", ship.SOID)
+	items, err := s.sales.ListSalesOrderItems(ctx, spec)
+	if err != nil {
+		return err
+	}
+	var stockIDs []interface{}
+	for _, item := range items.Data {
+		if item.StockID != nil {
+			stockIDs = append(stockIDs, *item.StockID)
+		}
+	}
+	if len(stockIDs) == 0 {
+		return nil
+	}
+	var lots query.Spec
+	lots.WhereIn("stock_id", stockIDs...)
+	picked, err := s.stock.LockStockItems(ctx, lots)
+	if err != nil {
+		return err
+	}
+	for _, lot := range picked {
+		if err := repository.CheckStockAvailable(lot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
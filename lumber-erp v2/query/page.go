@@ -0,0 +1,202 @@
+package query
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// NewPage trims rows fetched by Select, which holds at most one row more than
+// the limit, and sets next_cursor when that extra row shows another page
+func NewPage[T any](rows []T, res Resource, s Spec, total int) Page[T] {
+	page := Page[T]{Data: rows, Total: total}
+	if s.Limit > 0 && len(rows) > s.Limit {
+		page.Data = rows[:s.Limit]
+		last := reflect.ValueOf(page.Data[s.Limit-1])
+		page.NextCursor = res.encodeCursor(s, sortValues(last, res.orders(s)))
+	}
+	return page
+}
+
+// Apply evaluates s over rows in memory with the same semantics as the SQL
+// built by Select and Count
+func Apply[T any](rows []T, res Resource, s Spec) Page[T] {
+	orders := res.orders(s)
+	var matched []T
+	var keys [][]interface{}
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		if matches(v, s.Filters) {
+			matched = append(matched, row)
+			keys = append(keys, sortValues(v, orders))
+		}
+	}
+
+	index := make([]int, len(matched))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool {
+		return compareKeys(keys[index[i]], keys[index[j]], orders) < 0
+	})
+
+	page := []T{}
+	for _, i := range index {
+		if s.After != nil && compareKeys(keys[i], s.After, orders) <= 0 {
+			continue
+		}
+		if s.Limit > 0 && len(page) > s.Limit {
+			break
+		}
+		page = append(page, matched[i])
+	}
+	return NewPage(page, res, s, len(matched))
+}
+
+func matches(row reflect.Value, filters []Filter) bool {
+	for _, f := range filters {
+		value := fieldValue(row, f.Field)
+		if value == nil {
+			return false // comparisons with NULL are never true
+		}
+		switch f.Op {
+		case In:
+			found := false
+			for _, v := range f.Value.([]interface{}) {
+				found = found || compare(value, v) == 0
+			}
+			if !found {
+				return false
+			}
+		case Eq:
+			if compare(value, f.Value) != 0 {
+				return false
+			}
+		case Ne:
+			if compare(value, f.Value) == 0 {
+				return false
+			}
+		case Gt:
+			if compare(value, f.Value) <= 0 {
+				return false
+			}
+		case Gte:
+			if compare(value, f.Value) < 0 {
+				return false
+			}
+		case Lt:
+			if compare(value, f.Value) >= 0 {
+				return false
+			}
+		case Lte:
+			if compare(value, f.Value) > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sortValues(row reflect.Value, orders []Order) []interface{} {
+	values := make([]interface{}, len(orders))
+	for i, order := range orders {
+		values[i] = fieldValue(row, order.Field)
+	}
+	return values
+}
+
+// compareKeys orders two rows by their sort values
+func compareKeys(a, b []interface{}, orders []Order) int {
+	for i, order := range orders {
+		c := compare(a[i], b[i])
+		if order.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compare orders two normalized values; NULL sorts after everything
+func compare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(float64); ok {
+			return compare(float64(x), y)
+		}
+		y := b.(int64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			y = float64(b.(int64))
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case bool:
+		y := b.(bool)
+		switch {
+		case !x && y:
+			return -1
+		case x && !y:
+			return 1
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	return 0
+}
+
+// fieldValue reads the struct field tagged with the JSON name, normalized to
+// int64, float64, bool, string or nil for a nil pointer
+func fieldValue(row reflect.Value, name string) interface{} {
+	t := row.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag == name {
+			return normalize(row.Field(i).Interface())
+		}
+	}
+	return nil
+}
+
+func normalize(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	}
+	return value
+}
@@ -0,0 +1,300 @@
+// Package query parses the pagination, filtering and sorting parameters
+// shared by every list endpoint:
+//
+//	?limit=50&cursor=<next_cursor>&sort=-transaction_date&status=pending&order_date[gte]=2026-01-01
+//
+// Each resource whitelists the fields clients may filter and sort on.
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits applied when a request does not ask for one, and the largest page
+// a client may request
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Type is the type of a filterable field; filter values are parsed into it
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Float
+	Bool
+	Date
+)
+
+// Field maps a JSON field name to the SQL expression it is read from. The
+// expression must match the selected column, COALESCE included, so keyset
+// cursors compare the values clients saw.
+type Field struct {
+	Column string
+	Type   Type
+}
+
+// Resource whitelists the fields of one list endpoint
+type Resource struct {
+	Key    []string         // fields that identify a row and break sort ties
+	Sort   []Order          // order used when the request has no sort
+	Fields map[string]Field // filterable and sortable fields by JSON name
+}
+
+// Order sorts on one field
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Op compares a field with a filter value
+type Op string
+
+const (
+	Eq  Op = "eq"
+	Ne  Op = "ne"
+	Gt  Op = "gt"
+	Gte Op = "gte"
+	Lt  Op = "lt"
+	Lte Op = "lte"
+	In  Op = "in"
+)
+
+// Filter restricts a field; Value is a []interface{} for In
+type Filter struct {
+	Field string
+	Op    Op
+	Value interface{}
+}
+
+// Spec is a parsed list request. The zero Spec lists every row in the
+// resource's default order.
+type Spec struct {
+	Limit   int // 0 returns every row
+	Sort    []Order
+	Filters []Filter
+	After   []interface{} // cursor values, one per order field
+}
+
+// Page is the envelope list endpoints respond with
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int    `json:"total"`
+}
+
+// Error reports a malformed list request
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func invalid(format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// Parse reads a list request for res. Parameters other than limit, cursor
+// and sort must name a whitelisted field, optionally with an [op] suffix.
+func Parse(values url.Values, res Resource) (Spec, error) {
+	spec := Spec{Limit: DefaultLimit}
+
+	// Sorted so equal requests build identical SQL
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	for _, param := range params {
+		value := values.Get(param)
+		switch param {
+		case "limit":
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > MaxLimit {
+				return spec, invalid("limit must be between 1 and %d", MaxLimit)
+			}
+			spec.Limit = limit
+		case "cursor", "sort":
+			// read below, once the sort is known
+		default:
+			filter, err := parseFilter(param, value, res)
+			if err != nil {
+				return spec, err
+			}
+			spec.Filters = append(spec.Filters, filter)
+		}
+	}
+
+	if sorting := values.Get("sort"); sorting != "" {
+		for _, name := range strings.Split(sorting, ",") {
+			order := Order{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+			if _, ok := res.Fields[order.Field]; !ok {
+				return spec, invalid("Cannot sort on %s", order.Field)
+			}
+			spec.Sort = append(spec.Sort, order)
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		after, err := res.decodeCursor(cursor, spec)
+		if err != nil {
+			return spec, err
+		}
+		spec.After = after
+	}
+	return spec, nil
+}
+
+func parseFilter(param, value string, res Resource) (Filter, error) {
+	filter := Filter{Field: param, Op: Eq}
+	if open := strings.IndexByte(param, '['); open > 0 && strings.HasSuffix(param, "]") {
+		filter.Field, filter.Op = param[:open], Op(param[open+1:len(param)-1])
+	}
+	field, ok := res.Fields[filter.Field]
+	if !ok {
+		return filter, invalid("Unknown filter field %s", filter.Field)
+	}
+
+	switch filter.Op {
+	case Eq, Ne, Gt, Gte, Lt, Lte:
+		v, err := field.parse(value)
+		if err != nil {
+			return filter, invalid("Invalid value for %s", filter.Field)
+		}
+		filter.Value = v
+	case In:
+		var list []interface{}
+		for _, item := range strings.Split(value, ",") {
+			v, err := field.parse(item)
+			if err != nil {
+				return filter, invalid("Invalid value for %s", filter.Field)
+			}
+			list = append(list, v)
+		}
+		filter.Value = list
+	default:
+		return filter, invalid("Unknown filter operator %s", filter.Op)
+	}
+	return filter, nil
+}
+
+// parse converts a filter value to the field's type
+func (f Field) parse(value string) (interface{}, error) {
+	switch f.Type {
+	case Int:
+		return strconv.ParseInt(value, 10, 64)
+	case Float:
+		return strconv.ParseFloat(value, 64)
+	case Bool:
+		return strconv.ParseBool(value)
+	case Date:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return value, nil
+}
+
+// Where restricts the spec to rows whose field equals value, e.g. the order
+// of a nested /salesorders/{soid}/items route
+func (s *Spec) Where(field string, value interface{}) {
+	s.Filters = append(s.Filters, Filter{Field: field, Op: Eq, Value: normalize(value)})
+}
+
+// WhereIn restricts the spec to rows whose field is one of values. An empty
+// list matches no row.
+func (s *Spec) WhereIn(field string, values ...interface{}) {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = normalize(v)
+	}
+	s.Filters = append(s.Filters, Filter{Field: field, Op: In, Value: list})
+}
+
+// orders is the effective sort: the requested or default order, followed
+// by the key fields so every row has a distinct position
+func (res Resource) orders(s Spec) []Order {
+	orders := s.Sort
+	if len(orders) == 0 {
+		orders = res.Sort
+	}
+	orders = orders[:len(orders):len(orders)]
+	for _, key := range res.Key {
+		sorted := false
+		for _, order := range orders {
+			sorted = sorted || order.Field == key
+		}
+		if !sorted {
+			orders = append(orders, Order{Field: key})
+		}
+	}
+	return orders
+}
+
+func sortKey(orders []Order) string {
+	names := make([]string, len(orders))
+	for i, order := range orders {
+		names[i] = order.Field
+		if order.Desc {
+			names[i] = "-" + order.Field
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// cursor is the decoded form of next_cursor: the sort it was issued for and
+// the last row's values of the sort fields
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+func (res Resource) encodeCursor(s Spec, values []interface{}) string {
+	raw, _ := json.Marshal(cursor{Sort: sortKey(res.orders(s)), Values: values})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func (res Resource) decodeCursor(encoded string, s Spec) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid("Invalid cursor")
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(raw)))
+	decoder.UseNumber()
+	var c cursor
+	if err := decoder.Decode(&c); err != nil {
+		return nil, invalid("Invalid cursor")
+	}
+
+	orders := res.orders(s)
+	if c.Sort != sortKey(orders) || len(c.Values) != len(orders) {
+		return nil, invalid("Cursor does not match the requested sort")
+	}
+	for i, order := range orders {
+		number, ok := c.Values[i].(json.Number)
+		if !ok {
+			continue
+		}
+		if res.Fields[order.Field].Type == Int {
+			c.Values[i], err = number.Int64()
+		} else {
+			c.Values[i], err = number.Float64()
+		}
+		if err != nil {
+			return nil, invalid("Invalid cursor")
+		}
+	}
+	return c.Values, nil
+}
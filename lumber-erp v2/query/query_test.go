@@ -0,0 +1,87 @@
+package query
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var orders = Resource{
+	Key:  []string{"soid"},
+	Sort: []Order{{Field: "order_date", Desc: true}},
+	Fields: map[string]Field{
+		"soid":         {Column: "SOID", Type: Int},
+		"status":       {Column: "Status", Type: String},
+		"order_date":   {Column: "OrderDate", Type: Date},
+		"total_amount": {Column: "COALESCE(TotalAmount, 0)", Type: Float},
+	},
+}
+
+func TestSelect(t *testing.T) {
+	values, _ := url.ParseQuery("status[in]=pending,shipped&order_date[gte]=2026-01-01&limit=10")
+	spec, err := Parse(values, orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.After = []interface{}{"2026-02-01", int64(7)}
+
+	sql, args := orders.Select("SOID, Status", "SalesOrder", spec)
+	want := "SELECT SOID, Status FROM SalesOrder WHERE OrderDate >= $1 AND Status IN ($2, $3) AND " +
+		"((OrderDate < $4) OR (OrderDate = $5 AND (SOID > $6 OR SOID IS NULL))) ORDER BY OrderDate DESC, SOID LIMIT 11"
+	if sql != want {
+		t.Fatalf("sql = %s", sql)
+	}
+	if len(args) != 6 || args[3] != "2026-02-01" || args[5] != int64(7) {
+		t.Fatalf("args = %v", args)
+	}
+
+	count, countArgs := orders.Count("SalesOrder", Spec{Filters: []Filter{{Field: "soid", Op: Ne, Value: int64(3)}}})
+	if count != "SELECT COUNT(*) FROM SalesOrder WHERE SOID <> $1" || len(countArgs) != 1 {
+		t.Fatalf("count = %s %v", count, countArgs)
+	}
+
+	var none Spec
+	none.WhereIn("soid")
+	if count, _ := orders.Count("SalesOrder", none); count != "SELECT COUNT(*) FROM SalesOrder WHERE FALSE" {
+		t.Fatalf("empty in = %s", count)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	type order struct {
+		SOID        int     `json:"soid"`
+		OrderDate   string  `json:"order_date"`
+		TotalAmount float64 `json:"total_amount"`
+	}
+	rows := []order{
+		{1, "2026-01-03", 10}, {2, "2026-01-01", 30}, {3, "2026-01-02", 20}, {4, "2026-01-02", 5},
+	}
+
+	values := url.Values{"limit": {"3"}}
+	var seen []int
+	for {
+		spec, err := Parse(values, orders)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := Apply(rows, orders, spec)
+		if page.Total != 4 {
+			t.Fatalf("total = %d", page.Total)
+		}
+		for _, row := range page.Data {
+			seen = append(seen, row.SOID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		values.Set("cursor", page.NextCursor)
+	}
+	if want := []int{1, 3, 4, 2}; !reflect.DeepEqual(seen, want) {
+		t.Fatalf("seen %v, want %v", seen, want)
+	}
+
+	values.Set("sort", "total_amount")
+	if _, err := Parse(values, orders); err == nil {
+		t.Fatal("cursor accepted for a different sort")
+	}
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+var sqlOps = map[Op]string{Eq: "=", Ne: "<>", Gt: ">", Gte: ">=", Lt: "<", Lte: "<="}
+
+// builder collects SQL conditions with numbered placeholders
+type builder struct {
+	conds []string
+	args  []interface{}
+}
+
+func (b *builder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+func (b *builder) where() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conds, " AND ")
+}
+
+func (b *builder) filter(res Resource, filters []Filter) {
+	for _, f := range filters {
+		column := res.Fields[f.Field].Column
+		if f.Op == In {
+			var placeholders []string
+			for _, v := range f.Value.([]interface{}) {
+				placeholders = append(placeholders, b.arg(v))
+			}
+			if len(placeholders) == 0 {
+				b.conds = append(b.conds, "FALSE")
+				continue
+			}
+			b.conds = append(b.conds, column+" IN ("+strings.Join(placeholders, ", ")+")")
+			continue
+		}
+		b.conds = append(b.conds, column+" "+sqlOps[f.Op]+" "+b.arg(f.Value))
+	}
+}
+
+// after adds the keyset condition for rows that sort after the cursor. It
+// follows Postgres' default of NULLs last ascending and first descending.
+func (b *builder) after(res Resource, orders []Order, values []interface{}) {
+	var alternatives []string
+	for i, order := range orders {
+		var conds []string
+		for j := 0; j < i; j++ {
+			column := res.Fields[orders[j].Field].Column
+			if values[j] == nil {
+				conds = append(conds, column+" IS NULL")
+			} else {
+				conds = append(conds, column+" = "+b.arg(values[j]))
+			}
+		}
+
+		column := res.Fields[order.Field].Column
+		switch {
+		case order.Desc && values[i] == nil:
+			conds = append(conds, column+" IS NOT NULL")
+		case order.Desc:
+			conds = append(conds, column+" < "+b.arg(values[i]))
+		case values[i] == nil:
+			continue // nothing sorts after NULL
+		default:
+			conds = append(conds, "("+column+" > "+b.arg(values[i])+" OR "+column+" IS NULL)")
+		}
+		alternatives = append(alternatives, "("+strings.Join(conds, " AND ")+")")
+	}
+	if len(alternatives) == 0 {
+		alternatives = []string{"FALSE"}
+	}
+	b.conds = append(b.conds, "("+strings.Join(alternatives, " OR ")+")")
+}
+
+// Select builds the page query for s. columns and from are the select list
+// and table expression; one row more than the limit is fetched to tell
+// whether another page follows.
+func (res Resource) Select(columns, from string, s Spec) (string, []interface{}) {
+	var b builder
+	b.filter(res, s.Filters)
+	orders := res.orders(s)
+	if s.After != nil {
+		b.after(res, orders, s.After)
+	}
+
+	terms := make([]string, len(orders))
+	for i, order := range orders {
+		terms[i] = res.Fields[order.Field].Column
+		if order.Desc {
+			terms[i] += " DESC"
+		}
+	}
+	query := "SELECT " + columns + " FROM " + from + b.where() + " ORDER BY " + strings.Join(terms, ", ")
+	if s.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(s.Limit+1)
+	}
+	return query, b.args
+}
+
+// Count builds the query for the number of rows matching the filters of s
+func (res Resource) Count(from string, s Spec) (string, []interface{}) {
+	var b builder
+	b.filter(res, s.Filters)
+	return "SELECT COUNT(*) FROM " + from + b.where(), b.args
+}
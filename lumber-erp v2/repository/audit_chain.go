@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"lumber-erp-api/models"
+)
+
+// AuditHash is the chain hash of an entry: the SHA-256 of the previous
+// entry's hash and of the entry's content. Changes are hashed in canonical
+// form, since Postgres stores them as JSONB and returns them reformatted.
+func AuditHash(al models.AuditLog) string {
+	content, _ := json.Marshal(struct {
+		UserID         int         `json:"user_id"`
+		ActionType     string      `json:"action_type"`
+		EntityAffected string      `json:"entity_affected"`
+		EntityID       string      `json:"entity_id"`
+		Timestamp      string      `json:"timestamp"`
+		Description    string      `json:"description"`
+		IPAddress      string      `json:"ip_address"`
+		Changes        interface{} `json:"changes"`
+	}{al.UserID, al.ActionType, al.EntityAffected, al.EntityID, al.Timestamp,
+		al.Description, al.IPAddress, canonicalJSON(al.Changes)})
+
+	sum := sha256.New()
+	sum.Write([]byte(al.PrevHash))
+	sum.Write([]byte{'\n'})
+	sum.Write(content)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// canonicalJSON decodes raw so that it marshals with sorted keys and no
+// whitespace; invalid JSON is kept as text
+func canonicalJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+	return value
+}
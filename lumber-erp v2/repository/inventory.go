@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+
+	"lumber-erp-api/models"
+)
+
+// Inventory transaction types
+const (
+	TxReceipt     = "receipt"
+	TxIssue       = "issue"
+	TxAdjustment  = "adjustment"
+	TxTransferOut = "transfer_out"
+	TxTransferIn  = "transfer_in"
+	TxScrap       = "scrap"
+)
+
+// Stock lot statuses. Only available and reserved lots may be sold and
+// shipped.
+const (
+	StockAvailable  = "available"
+	StockReserved   = "reserved"
+	StockQuarantine = "quarantine"
+	StockRejected   = "rejected"
+)
+
+// CheckStockAvailable refuses a lot in quarantine or rejected, which may not
+// be sold, shipped or consumed
+func CheckStockAvailable(lot models.StockItem) error {
+	if lot.Status == "" || lot.Status == StockAvailable || lot.Status == StockReserved {
+		return nil
+	}
+	return &RuleError{Code: "stock_unavailable",
+		Message: fmt.Sprintf("Stock item %d is %s and cannot be sold, shipped or consumed", lot.StockID, lot.Status),
+		Details: map[string]interface{}{"stock_id": lot.StockID, "status": lot.Status}}
+}
+
+// StockMovement returns the signed change a transaction of the given type
+// makes. Receipts and transfers in add quantity; issues, transfers out and
+// scrap remove it; adjustments carry their own sign.
+func StockMovement(transactionType string, quantity float64) float64 {
+	switch transactionType {
+	case TxIssue, TxTransferOut, TxScrap:
+		return -quantity
+	}
+	return quantity
+}
+
+// moveStock returns the balance of a stock item after movement, refusing to
+// go below zero unless its warehouse allows it
+func moveStock(stock models.StockItem, movement float64, allowNegative bool) (float64, error) {
+	// Quantities are DECIMAL(10,2); rounding keeps float sums exact
+	balance := math.Round((stock.Quantity+movement)*100) / 100
+	if balance < 0 && !allowNegative {
+		return 0, &RuleError{
+			Code:    "insufficient_stock",
+			Message: fmt.Sprintf("Stock item %d holds %g, which does not cover %g", stock.StockID, stock.Quantity, -movement),
+			Details: map[string]interface{}{"stock_id": stock.StockID, "available": stock.Quantity, "requested": -movement},
+		}
+	}
+	return balance, nil
+}
+
+// reversal fills it as the entry undoing orig
+func reversal(orig models.InventoryTransaction, it *models.InventoryTransaction) error {
+	if orig.ReversesID != nil {
+		return &RuleError{Code: "not_reversible", Message: "A reversing entry cannot itself be reversed"}
+	}
+	if orig.StockID == 0 {
+		return &RuleError{Code: "not_reversible", Message: "The stock item of the transaction no longer exists"}
+	}
+	it.StockID = orig.StockID
+	it.TransactionType = orig.TransactionType
+	it.Quantity = -orig.Quantity
+	it.ReversesID = &orig.TransactionID
+	if it.Remarks == "" {
+		it.Remarks = fmt.Sprintf("Reversal of transaction %d", orig.TransactionID)
+	}
+	return nil
+}
+
+// openingBalance is the receipt recording the starting quantity of si
+func openingBalance(si models.StockItem) models.InventoryTransaction {
+	return models.InventoryTransaction{
+		StockID:         si.StockID,
+		TransactionType: TxReceipt,
+		Quantity:        si.Quantity,
+		Remarks:         "Opening balance",
+	}
+}
@@ -0,0 +1,666 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// memory implements every repository with in-process maps. It backs the
+// handler tests and local demos; lists honour the same query specs as
+// Postgres and nothing survives a restart.
+type memory struct {
+	mu sync.RWMutex
+	// txMu serialises transactions, which may hold several mu sections
+	txMu sync.Mutex
+	tables
+}
+
+// tables holds every relation of the memory store
+type tables struct {
+	users           table[models.User]
+	sessions        table[models.UserSession]
+	permissions     table[models.Permission]
+	roles           table[models.Role]
+	rolePermissions []models.RolePermission
+	auditLogs       table[models.AuditLog]
+	checkpoints     table[models.AuditCheckpoint]
+
+	employees             table[models.Employee]
+	workerAssignments     table[models.WorkerAssignment]
+	managementInsights    table[models.ManagementInsights]
+	suppliers             table[models.Supplier]
+	supplierPerformances  table[models.SupplierPerformance]
+	supplierContracts     table[models.SupplierContract]
+	forests               table[models.Forest]
+	treeSpecies           table[models.TreeSpecies]
+	harvestSchedules      table[models.HarvestSchedule]
+	harvestBatches        table[models.HarvestBatch]
+	sawmills              table[models.Sawmill]
+	processingUnits       table[models.ProcessingUnit]
+	processingOrders      table[models.ProcessingOrder]
+	batchProcessing       []models.HarvestBatchProcessing
+	maintenanceRecords    table[models.MaintenanceRecord]
+	maintenancePlans      table[models.MaintenancePlan]
+	workOrders            table[models.MaintenanceWorkOrder]
+	kilnCharges           table[models.KilnCharge]
+	kilnReadings          table[models.KilnReading]
+	wasteRecords          table[models.WasteRecord]
+	qualityInspections    table[models.QualityInspection]
+	defectCodes           table[models.DefectCode]
+	inspectionTemplates   table[models.InspectionTemplate]
+	nonConformances       table[models.NonConformance]
+	correctiveActions     table[models.CorrectiveAction]
+	qualityAlerts         table[models.QualityAlert]
+	samplingPlans         table[models.SamplingPlan]
+	inspectionLots        table[models.InspectionLot]
+	warehouses            table[models.Warehouse]
+	productTypes          table[models.ProductType]
+	stockItems            table[models.StockItem]
+	stockAlerts           table[models.StockAlert]
+	stockDispositions     table[models.StockDisposition]
+	inventoryTransactions table[models.InventoryTransaction]
+	stockTransfers        table[models.StockTransfer]
+	stockTransferLines    table[models.StockTransferLine]
+	purchaseOrders        table[models.PurchaseOrder]
+	purchaseOrderItems    table[models.PurchaseOrderItem]
+	customers             table[models.Customer]
+	salesOrders           table[models.SalesOrder]
+	salesOrderItems       table[models.SalesOrderItem]
+	invoices              table[models.Invoice]
+	payments              table[models.Payment]
+	transportCompanies    table[models.TransportCompany]
+	trucks                table[models.Truck]
+	drivers               table[models.Driver]
+	routes                table[models.Route]
+	shipments             table[models.Shipment]
+	fuelLogs              table[models.FuelLog]
+}
+
+// NewMemory returns empty repositories that keep everything in memory
+func NewMemory() Repositories {
+	return newRepositories(&memory{})
+}
+
+// table is one in-memory relation keyed by a SERIAL-style ID
+type table[T any] struct {
+	last int
+	rows map[int]T
+}
+
+// nextID reserves the next ID, creating the map on first use
+func (t *table[T]) nextID() int {
+	if t.rows == nil {
+		t.rows = map[int]T{}
+	}
+	t.last++
+	return t.last
+}
+
+// clone copies the rows so later writes leave the copy untouched
+func (t table[T]) clone() table[T] {
+	rows := make(map[int]T, len(t.rows))
+	for id, row := range t.rows {
+		rows[id] = row
+	}
+	return table[T]{last: t.last, rows: rows}
+}
+
+// clone copies every relation
+func (t tables) clone() tables {
+	t.users = t.users.clone()
+	t.sessions = t.sessions.clone()
+	t.permissions = t.permissions.clone()
+	t.roles = t.roles.clone()
+	t.rolePermissions = append([]models.RolePermission(nil), t.rolePermissions...)
+	t.auditLogs = t.auditLogs.clone()
+	t.checkpoints = t.checkpoints.clone()
+	t.employees = t.employees.clone()
+	t.workerAssignments = t.workerAssignments.clone()
+	t.managementInsights = t.managementInsights.clone()
+	t.suppliers = t.suppliers.clone()
+	t.supplierPerformances = t.supplierPerformances.clone()
+	t.supplierContracts = t.supplierContracts.clone()
+	t.forests = t.forests.clone()
+	t.treeSpecies = t.treeSpecies.clone()
+	t.harvestSchedules = t.harvestSchedules.clone()
+	t.harvestBatches = t.harvestBatches.clone()
+	t.sawmills = t.sawmills.clone()
+	t.processingUnits = t.processingUnits.clone()
+	t.processingOrders = t.processingOrders.clone()
+	t.batchProcessing = append([]models.HarvestBatchProcessing(nil), t.batchProcessing...)
+	t.maintenanceRecords = t.maintenanceRecords.clone()
+	t.maintenancePlans = t.maintenancePlans.clone()
+	t.workOrders = t.workOrders.clone()
+	t.kilnCharges = t.kilnCharges.clone()
+	t.kilnReadings = t.kilnReadings.clone()
+	t.wasteRecords = t.wasteRecords.clone()
+	t.qualityInspections = t.qualityInspections.clone()
+	t.defectCodes = t.defectCodes.clone()
+	t.inspectionTemplates = t.inspectionTemplates.clone()
+	t.nonConformances = t.nonConformances.clone()
+	t.correctiveActions = t.correctiveActions.clone()
+	t.qualityAlerts = t.qualityAlerts.clone()
+	t.samplingPlans = t.samplingPlans.clone()
+	t.inspectionLots = t.inspectionLots.clone()
+	t.warehouses = t.warehouses.clone()
+	t.productTypes = t.productTypes.clone()
+	t.stockItems = t.stockItems.clone()
+	t.stockAlerts = t.stockAlerts.clone()
+	t.stockDispositions = t.stockDispositions.clone()
+	t.inventoryTransactions = t.inventoryTransactions.clone()
+	t.stockTransfers = t.stockTransfers.clone()
+	t.stockTransferLines = t.stockTransferLines.clone()
+	t.purchaseOrders = t.purchaseOrders.clone()
+	t.purchaseOrderItems = t.purchaseOrderItems.clone()
+	t.customers = t.customers.clone()
+	t.salesOrders = t.salesOrders.clone()
+	t.salesOrderItems = t.salesOrderItems.clone()
+	t.invoices = t.invoices.clone()
+	t.payments = t.payments.clone()
+	t.transportCompanies = t.transportCompanies.clone()
+	t.trucks = t.trucks.clone()
+	t.drivers = t.drivers.clone()
+	t.routes = t.routes.clone()
+	t.shipments = t.shipments.clone()
+	t.fuelLogs = t.fuelLogs.clone()
+	return t
+}
+
+// memoryTx marks a context that is inside a memory transaction
+type memoryTx struct{}
+
+// WithinTx runs fn with transactions serialised, restoring the tables as
+// they were before fn when it fails
+func (m *memory) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(memoryTx{}) != nil {
+		return fn(ctx)
+	}
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.RLock()
+	saved := m.tables.clone()
+	m.mu.RUnlock()
+
+	if err := fn(context.WithValue(ctx, memoryTx{}, true)); err != nil {
+		m.mu.Lock()
+		m.tables = saved
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// list returns the rows ordered by ID
+func (t *table[T]) list() []T {
+	ids := make([]int, 0, len(t.rows))
+	for id := range t.rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	rows := make([]T, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, t.rows[id])
+	}
+	return rows
+}
+
+// now formats the current time the way timestamp columns are returned
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// ==================== USERS ====================
+func (m *memory) ListUsers(ctx context.Context, spec query.Spec) (query.Page[models.User], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	users := m.users.list()
+	for i := range users {
+		users[i].Password = ""
+	}
+	return query.Apply(users, UserResource, spec), nil
+}
+
+func (m *memory) GetUser(ctx context.Context, id int) (models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users.rows[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	user.Password = ""
+	return user, nil
+}
+
+func (m *memory) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users.list() {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (m *memory) PasswordHash(ctx context.Context, id int) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users.rows[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return user.Password, nil
+}
+
+func (m *memory) CreateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.users.rows {
+		if existing.Email == user.Email {
+			return &ConflictError{Field: "email"}
+		}
+	}
+	user.UserID = m.users.nextID()
+	user.CreatedAt = now()
+	m.users.rows[user.UserID] = *user
+	return nil
+}
+
+func (m *memory) UpdateUser(ctx context.Context, id int, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.users.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	old.Email = user.Email
+	old.FirstName = user.FirstName
+	old.LastName = user.LastName
+	old.PhoneNumber = user.PhoneNumber
+	old.Status = user.Status
+	m.users.rows[id] = old
+	if user.Status != "active" {
+		m.revokeSessionsLocked(id)
+	}
+	return nil
+}
+
+func (m *memory) DeleteUser(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.users.rows, id)
+	// Roles and sessions reference the user with ON DELETE CASCADE
+	for roleID, role := range m.roles.rows {
+		if role.UserID == id {
+			m.deleteRoleLocked(roleID)
+		}
+	}
+	for sessionID, s := range m.sessions.rows {
+		if s.UserID == id {
+			delete(m.sessions.rows, sessionID)
+		}
+	}
+	return nil
+}
+
+func (m *memory) SetPassword(ctx context.Context, id int, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user, ok := m.users.rows[id]; ok {
+		user.Password = hash
+		m.users.rows[id] = user
+	}
+	return nil
+}
+
+func (m *memory) ChangePassword(ctx context.Context, id int, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user, ok := m.users.rows[id]; ok {
+		user.Password = hash
+		m.users.rows[id] = user
+	}
+	m.revokeSessionsLocked(id)
+	return nil
+}
+
+// revokeSessionsLocked ends every live session of a user
+func (m *memory) revokeSessionsLocked(userID int) {
+	revokedAt := time.Now()
+	for sessionID, s := range m.sessions.rows {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &revokedAt
+			m.sessions.rows[sessionID] = s
+		}
+	}
+}
+
+// ==================== SESSIONS ====================
+func (m *memory) CreateSession(ctx context.Context, session *models.UserSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.SessionID = m.sessions.nextID()
+	session.CreatedAt = time.Now()
+	m.sessions.rows[session.SessionID] = *session
+	return nil
+}
+
+func (m *memory) GetSession(ctx context.Context, sessionID, userID int) (models.UserSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions.rows[sessionID]
+	if !ok || s.UserID != userID {
+		return models.UserSession{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *memory) RotateSession(ctx context.Context, sessionID int, oldHash, newHash string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions.rows[sessionID]
+	if !ok || s.RefreshTokenHash != oldHash || s.RevokedAt != nil {
+		return ErrNotFound
+	}
+	lastUsedAt := time.Now()
+	s.RefreshTokenHash = newHash
+	s.ExpiresAt = expiresAt
+	s.LastUsedAt = &lastUsedAt
+	m.sessions.rows[sessionID] = s
+	return nil
+}
+
+func (m *memory) RevokeSession(ctx context.Context, sessionID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions.rows[sessionID]; ok && s.UserID == userID && s.RevokedAt == nil {
+		revokedAt := time.Now()
+		s.RevokedAt = &revokedAt
+		m.sessions.rows[sessionID] = s
+	}
+	return nil
+}
+
+// ==================== PERMISSIONS ====================
+func (m *memory) ListPermissions(ctx context.Context, spec query.Spec) (query.Page[models.Permission], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.permissions.list(), PermissionResource, spec), nil
+}
+
+func (m *memory) CreatePermission(ctx context.Context, perm *models.Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	perm.PermissionID = m.permissions.nextID()
+	m.permissions.rows[perm.PermissionID] = *perm
+	return nil
+}
+
+func (m *memory) UpdatePermission(ctx context.Context, id int, perm *models.Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.permissions.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *perm
+	row.PermissionID = id
+	m.permissions.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeletePermission(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.permissions.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.permissions.rows, id)
+	m.removeGrantsLocked(func(rp models.RolePermission) bool { return rp.PermissionID == id })
+	return nil
+}
+
+func (m *memory) ListUserPermissions(ctx context.Context, userID int) ([]models.Permission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	granted := map[int]bool{}
+	for _, rp := range m.rolePermissions {
+		if role, ok := m.roles.rows[rp.RoleID]; ok && role.UserID == userID {
+			granted[rp.PermissionID] = true
+		}
+	}
+
+	perms := []models.Permission{}
+	for _, perm := range m.permissions.list() {
+		if granted[perm.PermissionID] {
+			perms = append(perms, perm)
+		}
+	}
+	return perms, nil
+}
+
+func (m *memory) HasPermission(ctx context.Context, userID int, module, action string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, role := range m.roles.rows {
+		if role.UserID != userID {
+			continue
+		}
+		if grants(role.RoleName, "", "", module, action) {
+			return true, nil
+		}
+		for _, rp := range m.rolePermissions {
+			perm, ok := m.permissions.rows[rp.PermissionID]
+			if ok && rp.RoleID == role.RoleID && grants(role.RoleName, perm.ModuleName, perm.ActionType, module, action) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ==================== ROLES ====================
+func (m *memory) ListRoles(ctx context.Context, spec query.Spec) (query.Page[models.Role], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.roles.list(), RoleResource, spec), nil
+}
+
+func (m *memory) ListRolesByUser(ctx context.Context, userID int) ([]models.Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	roles := []models.Role{}
+	for _, role := range m.roles.list() {
+		if role.UserID == userID {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (m *memory) CreateRole(ctx context.Context, role *models.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	role.RoleID = m.roles.nextID()
+	m.roles.rows[role.RoleID] = *role
+	return nil
+}
+
+func (m *memory) UpdateRole(ctx context.Context, id int, role *models.Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.roles.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *role
+	row.RoleID = id
+	m.roles.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteRole(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.roles.rows[id]; !ok {
+		return ErrNotFound
+	}
+	m.deleteRoleLocked(id)
+	return nil
+}
+
+func (m *memory) deleteRoleLocked(id int) {
+	delete(m.roles.rows, id)
+	m.removeGrantsLocked(func(rp models.RolePermission) bool { return rp.RoleID == id })
+}
+
+// ==================== ROLE PERMISSIONS ====================
+func (m *memory) ListRolePermissions(ctx context.Context, spec query.Spec) (query.Page[models.RolePermission], error) {
+	return query.Apply(m.filterGrants(func(models.RolePermission) bool { return true }), RolePermissionResource, spec), nil
+}
+
+func (m *memory) ListRolePermissionsByRole(ctx context.Context, roleID int) ([]models.RolePermission, error) {
+	return m.filterGrants(func(rp models.RolePermission) bool { return rp.RoleID == roleID }), nil
+}
+
+func (m *memory) ListRolePermissionsByPermission(ctx context.Context, permissionID int) ([]models.RolePermission, error) {
+	return m.filterGrants(func(rp models.RolePermission) bool { return rp.PermissionID == permissionID }), nil
+}
+
+func (m *memory) filterGrants(keep func(models.RolePermission) bool) []models.RolePermission {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rolePermissions := []models.RolePermission{}
+	for _, rp := range m.rolePermissions {
+		if keep(rp) {
+			rolePermissions = append(rolePermissions, rp)
+		}
+	}
+	sort.SliceStable(rolePermissions, func(i, j int) bool { return rolePermissions[i].RoleID < rolePermissions[j].RoleID })
+	return rolePermissions
+}
+
+func (m *memory) CreateRolePermission(ctx context.Context, rp *models.RolePermission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.grantLocked(rp.RoleID, rp.PermissionID)
+}
+
+func (m *memory) DeleteRolePermission(ctx context.Context, roleID, permissionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.rolePermissions)
+	m.removeGrantsLocked(func(rp models.RolePermission) bool {
+		return rp.RoleID == roleID && rp.PermissionID == permissionID
+	})
+	if len(m.rolePermissions) == before {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *memory) AssignPermissions(ctx context.Context, roleID int, permissionIDs []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous := m.rolePermissions
+	m.removeGrantsLocked(func(rp models.RolePermission) bool { return rp.RoleID == roleID })
+	for _, permID := range permissionIDs {
+		if err := m.grantLocked(roleID, permID); err != nil {
+			// Leave the grants as they were, like the rolled back transaction
+			m.rolePermissions = previous
+			return err
+		}
+	}
+	return nil
+}
+
+// grantLocked enforces the foreign keys and primary key of RolePermission
+func (m *memory) grantLocked(roleID, permissionID int) error {
+	if _, ok := m.roles.rows[roleID]; !ok {
+		return &ReferenceError{Field: "role_id", Table: "role"}
+	}
+	if _, ok := m.permissions.rows[permissionID]; !ok {
+		return &ReferenceError{Field: "permission_id", Table: "permission"}
+	}
+	for _, rp := range m.rolePermissions {
+		if rp.RoleID == roleID && rp.PermissionID == permissionID {
+			return &ConflictError{Field: "role_id, permission_id"}
+		}
+	}
+	m.rolePermissions = append(m.rolePermissions, models.RolePermission{RoleID: roleID, PermissionID: permissionID})
+	return nil
+}
+
+// removeGrantsLocked copies so a saved slice header stays intact for rollback
+func (m *memory) removeGrantsLocked(drop func(models.RolePermission) bool) {
+	kept := []models.RolePermission{}
+	for _, rp := range m.rolePermissions {
+		if !drop(rp) {
+			kept = append(kept, rp)
+		}
+	}
+	m.rolePermissions = kept
+}
+
+// ==================== AUDIT LOGS ====================
+func (m *memory) ListAuditLogs(ctx context.Context, spec query.Spec) (query.Page[models.AuditLog], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.auditLogs.list(), AuditLogResource, spec), nil
+}
+
+func (m *memory) CreateAuditLog(ctx context.Context, al *models.AuditLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	al.PrevHash = m.auditLogs.rows[m.auditLogs.last].Hash
+	al.LogID = m.auditLogs.nextID()
+	al.Timestamp = now()
+	al.Hash = AuditHash(*al)
+	m.auditLogs.rows[al.LogID] = *al
+	return nil
+}
+
+func (m *memory) WalkAuditLogs(ctx context.Context, fn func(models.AuditLog) error) error {
+	m.mu.RLock()
+	logs := m.auditLogs.list()
+	m.mu.RUnlock()
+	for _, al := range logs {
+		if err := fn(al); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memory) LatestAuditLog(ctx context.Context) (models.AuditLog, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	al, ok := m.auditLogs.rows[m.auditLogs.last]
+	if !ok {
+		return al, ErrNotFound
+	}
+	return al, nil
+}
+
+func (m *memory) ListAuditCheckpoints(ctx context.Context) ([]models.AuditCheckpoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.checkpoints.list(), nil
+}
+
+func (m *memory) CreateAuditCheckpoint(ctx context.Context, cp *models.AuditCheckpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp.CheckpointID = m.checkpoints.nextID()
+	m.checkpoints.rows[cp.CheckpointID] = *cp
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== EMPLOYEES ====================
+func (m *memory) ListEmployees(ctx context.Context, spec query.Spec) (query.Page[models.Employee], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.employees.list(), EmployeeResource, spec), nil
+}
+
+func (m *memory) CreateEmployee(ctx context.Context, emp *models.Employee) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	emp.EmployeeID = m.employees.nextID()
+	m.employees.rows[emp.EmployeeID] = *emp
+	return nil
+}
+
+func (m *memory) UpdateEmployee(ctx context.Context, id int, emp *models.Employee) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.employees.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *emp
+	row.EmployeeID = id
+	m.employees.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteEmployee(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.employees.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.employees.rows, id)
+	return nil
+}
+
+// ==================== WORKER ASSIGNMENTS ====================
+func (m *memory) ListWorkerAssignments(ctx context.Context, spec query.Spec) (query.Page[models.WorkerAssignment], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.workerAssignments.list(), WorkerAssignmentResource, spec), nil
+}
+
+func (m *memory) CreateWorkerAssignment(ctx context.Context, wa *models.WorkerAssignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wa.AssignmentID = m.workerAssignments.nextID()
+	m.workerAssignments.rows[wa.AssignmentID] = *wa
+	return nil
+}
+
+func (m *memory) UpdateWorkerAssignment(ctx context.Context, id int, wa *models.WorkerAssignment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.workerAssignments.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *wa
+	row.AssignmentID = id
+	m.workerAssignments.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteWorkerAssignment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.workerAssignments.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.workerAssignments.rows, id)
+	return nil
+}
+
+// ==================== MANAGEMENT INSIGHTS ====================
+func (m *memory) ListManagementInsights(ctx context.Context, spec query.Spec) (query.Page[models.ManagementInsights], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.managementInsights.list(), ManagementInsightsResource, spec), nil
+}
+
+func (m *memory) CreateManagementInsights(ctx context.Context, mi *models.ManagementInsights) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mi.ReportID = m.managementInsights.nextID()
+	m.managementInsights.rows[mi.ReportID] = *mi
+	return nil
+}
+
+func (m *memory) UpdateManagementInsights(ctx context.Context, id int, mi *models.ManagementInsights) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.managementInsights.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *mi
+	row.ReportID = id
+	m.managementInsights.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteManagementInsights(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.managementInsights.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.managementInsights.rows, id)
+	return nil
+}
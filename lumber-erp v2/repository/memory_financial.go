@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== INVOICES ====================
+func (m *memory) ListInvoices(ctx context.Context, spec query.Spec) (query.Page[models.Invoice], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.invoices.list(), InvoiceResource, spec), nil
+}
+
+func (m *memory) CreateInvoice(ctx context.Context, inv *models.Invoice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inv.InvoiceID = m.invoices.nextID()
+	m.invoices.rows[inv.InvoiceID] = *inv
+	return nil
+}
+
+func (m *memory) UpdateInvoice(ctx context.Context, id int, inv *models.Invoice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invoices.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *inv
+	row.InvoiceID = id
+	m.invoices.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteInvoice(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invoices.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.invoices.rows, id)
+	return nil
+}
+
+// ==================== PAYMENTS ====================
+func (m *memory) ListPayments(ctx context.Context, spec query.Spec) (query.Page[models.Payment], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.payments.list(), PaymentResource, spec), nil
+}
+
+func (m *memory) CreatePayment(ctx context.Context, pay *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pay.PaymentID = m.payments.nextID()
+	m.payments.rows[pay.PaymentID] = *pay
+	return nil
+}
+
+func (m *memory) UpdatePayment(ctx context.Context, id int, pay *models.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.payments.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *pay
+	row.PaymentID = id
+	m.payments.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeletePayment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.payments.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.payments.rows, id)
+	return nil
+}
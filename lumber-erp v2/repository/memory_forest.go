@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== FORESTS ====================
+func (m *memory) ListForests(ctx context.Context, spec query.Spec) (query.Page[models.Forest], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.forests.list(), ForestResource, spec), nil
+}
+
+func (m *memory) CreateForest(ctx context.Context, forest *models.Forest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	forest.ForestID = m.forests.nextID()
+	m.forests.rows[forest.ForestID] = *forest
+	return nil
+}
+
+func (m *memory) UpdateForest(ctx context.Context, id int, forest *models.Forest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.forests.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *forest
+	row.ForestID = id
+	m.forests.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteForest(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.forests.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.forests.rows, id)
+	return nil
+}
+
+// ==================== TREE SPECIES ====================
+func (m *memory) ListTreeSpecies(ctx context.Context, spec query.Spec) (query.Page[models.TreeSpecies], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.treeSpecies.list(), TreeSpeciesResource, spec), nil
+}
+
+func (m *memory) CreateTreeSpecies(ctx context.Context, ts *models.TreeSpecies) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ts.SpeciesID = m.treeSpecies.nextID()
+	m.treeSpecies.rows[ts.SpeciesID] = *ts
+	return nil
+}
+
+func (m *memory) UpdateTreeSpecies(ctx context.Context, id int, ts *models.TreeSpecies) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.treeSpecies.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *ts
+	row.SpeciesID = id
+	m.treeSpecies.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteTreeSpecies(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.treeSpecies.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.treeSpecies.rows, id)
+	return nil
+}
+
+// ==================== HARVEST SCHEDULES ====================
+func (m *memory) ListHarvestSchedules(ctx context.Context, spec query.Spec) (query.Page[models.HarvestSchedule], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.harvestSchedules.list(), HarvestScheduleResource, spec), nil
+}
+
+func (m *memory) CreateHarvestSchedule(ctx context.Context, hs *models.HarvestSchedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hs.ScheduleID = m.harvestSchedules.nextID()
+	m.harvestSchedules.rows[hs.ScheduleID] = *hs
+	return nil
+}
+
+func (m *memory) UpdateHarvestSchedule(ctx context.Context, id int, hs *models.HarvestSchedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.harvestSchedules.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *hs
+	row.ScheduleID = id
+	m.harvestSchedules.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteHarvestSchedule(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.harvestSchedules.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.harvestSchedules.rows, id)
+	return nil
+}
+
+// ==================== HARVEST BATCHES ====================
+func (m *memory) ListHarvestBatches(ctx context.Context, spec query.Spec) (query.Page[models.HarvestBatch], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.harvestBatches.list(), HarvestBatchResource, spec), nil
+}
+
+func (m *memory) CreateHarvestBatch(ctx context.Context, hb *models.HarvestBatch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hb.BatchID = m.harvestBatches.nextID()
+	m.harvestBatches.rows[hb.BatchID] = *hb
+	return nil
+}
+
+func (m *memory) UpdateHarvestBatch(ctx context.Context, id int, hb *models.HarvestBatch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.harvestBatches.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *hb
+	row.BatchID = id
+	m.harvestBatches.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteHarvestBatch(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.harvestBatches.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.harvestBatches.rows, id)
+	m.removeBatchProcessing(func(link models.HarvestBatchProcessing) bool { return link.BatchID == id })
+	return nil
+}
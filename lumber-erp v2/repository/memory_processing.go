@@ -0,0 +1,478 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== SAWMILLS ====================
+func (m *memory) ListSawmills(ctx context.Context, spec query.Spec) (query.Page[models.Sawmill], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.sawmills.list(), SawmillResource, spec), nil
+}
+
+func (m *memory) CreateSawmill(ctx context.Context, sm *models.Sawmill) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sm.SawmillID = m.sawmills.nextID()
+	m.sawmills.rows[sm.SawmillID] = *sm
+	return nil
+}
+
+func (m *memory) UpdateSawmill(ctx context.Context, id int, sm *models.Sawmill) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sawmills.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *sm
+	row.SawmillID = id
+	m.sawmills.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteSawmill(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.sawmills.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.sawmills.rows, id)
+	return nil
+}
+
+// ==================== PROCESSING UNITS ====================
+func (m *memory) ListProcessingUnits(ctx context.Context, spec query.Spec) (query.Page[models.ProcessingUnit], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.processingUnits.list(), ProcessingUnitResource, spec), nil
+}
+
+func (m *memory) CreateProcessingUnit(ctx context.Context, pu *models.ProcessingUnit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pu.UnitID = m.processingUnits.nextID()
+	m.processingUnits.rows[pu.UnitID] = *pu
+	return nil
+}
+
+func (m *memory) UpdateProcessingUnit(ctx context.Context, id int, pu *models.ProcessingUnit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.processingUnits.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *pu
+	row.UnitID = id
+	m.processingUnits.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteProcessingUnit(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.processingUnits.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.processingUnits.rows, id)
+	return nil
+}
+
+// ==================== PROCESSING ORDERS ====================
+func (m *memory) ListProcessingOrders(ctx context.Context, spec query.Spec) (query.Page[models.ProcessingOrder], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.processingOrders.list(), ProcessingOrderResource, spec), nil
+}
+
+func (m *memory) GetProcessingOrder(ctx context.Context, id int) (models.ProcessingOrder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	po, ok := m.processingOrders.rows[id]
+	if !ok {
+		return po, ErrNotFound
+	}
+	for _, link := range m.batchProcessing {
+		if link.ProcessingID == id {
+			po.Inputs = append(po.Inputs, link)
+		}
+	}
+	return po, nil
+}
+
+func (m *memory) CreateProcessingOrder(ctx context.Context, po *models.ProcessingOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	po.ProcessingID = m.processingOrders.nextID()
+	row := *po
+	row.Inputs = nil
+	m.processingOrders.rows[po.ProcessingID] = row
+	return nil
+}
+
+func (m *memory) UpdateProcessingOrder(ctx context.Context, id int, po *models.ProcessingOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.processingOrders.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *po
+	row.ProcessingID = id
+	row.Inputs = nil
+	m.processingOrders.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteProcessingOrder(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.processingOrders.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.processingOrders.rows, id)
+	m.removeBatchProcessing(func(link models.HarvestBatchProcessing) bool { return link.ProcessingID == id })
+	return nil
+}
+
+// ==================== HARVEST BATCH PROCESSING ====================
+func (m *memory) ListHarvestBatchProcessing(ctx context.Context, spec query.Spec) (query.Page[models.HarvestBatchProcessing], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(append([]models.HarvestBatchProcessing(nil), m.batchProcessing...), HarvestBatchProcessingResource, spec), nil
+}
+
+func (m *memory) AttachHarvestBatch(ctx context.Context, link *models.HarvestBatchProcessing) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.processingOrders.rows[link.ProcessingID]; !ok {
+		return &ReferenceError{Field: "processing_id", Table: "processingorder"}
+	}
+	batch, ok := m.harvestBatches.rows[link.BatchID]
+	if !ok {
+		return &ReferenceError{Field: "batch_id", Table: "harvestbatch"}
+	}
+	consumed := 0.0
+	for _, other := range m.batchProcessing {
+		if other.BatchID != link.BatchID {
+			continue
+		}
+		if other.ProcessingID == link.ProcessingID {
+			return &ConflictError{Field: "batch_id"}
+		}
+		consumed += other.ConsumedQuantity
+	}
+	if err := consumeBatch(link.BatchID, batch.Quantity, consumed, link.ConsumedQuantity); err != nil {
+		return err
+	}
+	m.batchProcessing = append(m.batchProcessing, *link)
+	return nil
+}
+
+func (m *memory) DetachHarvestBatch(ctx context.Context, processingID, batchID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.batchProcessing)
+	m.removeBatchProcessing(func(link models.HarvestBatchProcessing) bool {
+		return link.ProcessingID == processingID && link.BatchID == batchID
+	})
+	if len(m.batchProcessing) == before {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// removeBatchProcessing drops the links matching remove; the caller holds
+// m.mu
+func (m *memory) removeBatchProcessing(remove func(models.HarvestBatchProcessing) bool) {
+	kept := m.batchProcessing[:0:0]
+	for _, link := range m.batchProcessing {
+		if !remove(link) {
+			kept = append(kept, link)
+		}
+	}
+	m.batchProcessing = kept
+}
+
+// ==================== MAINTENANCE RECORDS ====================
+func (m *memory) ListMaintenanceRecords(ctx context.Context, spec query.Spec) (query.Page[models.MaintenanceRecord], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.maintenanceRecords.list(), MaintenanceRecordResource, spec), nil
+}
+
+func (m *memory) CreateMaintenanceRecord(ctx context.Context, mr *models.MaintenanceRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mr.MaintenanceID = m.maintenanceRecords.nextID()
+	m.maintenanceRecords.rows[mr.MaintenanceID] = *mr
+	return nil
+}
+
+func (m *memory) UpdateMaintenanceRecord(ctx context.Context, id int, mr *models.MaintenanceRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.maintenanceRecords.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *mr
+	row.MaintenanceID = id
+	m.maintenanceRecords.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteMaintenanceRecord(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.maintenanceRecords.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.maintenanceRecords.rows, id)
+	return nil
+}
+
+// ==================== MAINTENANCE PLANS ====================
+func (m *memory) ListMaintenancePlans(ctx context.Context, spec query.Spec) (query.Page[models.MaintenancePlan], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.maintenancePlans.list(), MaintenancePlanResource, spec), nil
+}
+
+func (m *memory) LockMaintenancePlans(ctx context.Context, spec query.Spec) ([]models.MaintenancePlan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	spec.Limit = 0
+	return query.Apply(m.maintenancePlans.list(), MaintenancePlanResource, spec).Data, nil
+}
+
+func (m *memory) CreateMaintenancePlan(ctx context.Context, mp *models.MaintenancePlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mp.PlanID = m.maintenancePlans.nextID()
+	mp.CreatedAt = now()
+	if mp.LastDoneAt == "" {
+		mp.LastDoneAt = mp.CreatedAt
+	}
+	m.maintenancePlans.rows[mp.PlanID] = *mp
+	return nil
+}
+
+func (m *memory) UpdateMaintenancePlan(ctx context.Context, id int, mp *models.MaintenancePlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.maintenancePlans.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *mp
+	row.PlanID = id
+	row.CreatedAt = old.CreatedAt
+	m.maintenancePlans.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteMaintenancePlan(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.maintenancePlans.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.maintenancePlans.rows, id)
+	for woID, wo := range m.workOrders.rows {
+		if wo.PlanID != nil && *wo.PlanID == id {
+			wo.PlanID = nil
+			m.workOrders.rows[woID] = wo
+		}
+	}
+	return nil
+}
+
+// ==================== MAINTENANCE WORK ORDERS ====================
+func (m *memory) ListWorkOrders(ctx context.Context, spec query.Spec) (query.Page[models.MaintenanceWorkOrder], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.workOrders.list(), MaintenanceWorkOrderResource, spec), nil
+}
+
+func (m *memory) GetWorkOrder(ctx context.Context, id int) (models.MaintenanceWorkOrder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	wo, ok := m.workOrders.rows[id]
+	if !ok {
+		return wo, ErrNotFound
+	}
+	return wo, nil
+}
+
+func (m *memory) CreateWorkOrder(ctx context.Context, wo *models.MaintenanceWorkOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wo.WorkOrderID = m.workOrders.nextID()
+	wo.CreatedAt = now()
+	m.workOrders.rows[wo.WorkOrderID] = *wo
+	return nil
+}
+
+func (m *memory) UpdateWorkOrder(ctx context.Context, id int, wo *models.MaintenanceWorkOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.workOrders.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *wo
+	row.WorkOrderID = id
+	row.CreatedAt = old.CreatedAt
+	m.workOrders.rows[id] = row
+	return nil
+}
+
+// ==================== KILN CHARGES ====================
+func (m *memory) ListKilnCharges(ctx context.Context, spec query.Spec) (query.Page[models.KilnCharge], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	charges := m.kilnCharges.list()
+	for i := range charges {
+		charges[i].StockIDs = nil
+	}
+	return query.Apply(charges, KilnChargeResource, spec), nil
+}
+
+func (m *memory) GetKilnCharge(ctx context.Context, id int) (models.KilnCharge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	kc, ok := m.kilnCharges.rows[id]
+	if !ok {
+		return kc, ErrNotFound
+	}
+	kc.StockIDs = append([]int(nil), kc.StockIDs...)
+	return kc, nil
+}
+
+func (m *memory) CreateKilnCharge(ctx context.Context, kc *models.KilnCharge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kc.ChargeID = m.kilnCharges.nextID()
+	if kc.StartedAt == "" {
+		kc.StartedAt = now()
+	}
+	row := *kc
+	row.StockIDs = append([]int(nil), kc.StockIDs...)
+	m.kilnCharges.rows[kc.ChargeID] = row
+	return nil
+}
+
+func (m *memory) UpdateKilnCharge(ctx context.Context, id int, kc *models.KilnCharge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.kilnCharges.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *kc
+	row.ChargeID = id
+	row.StockIDs = old.StockIDs
+	m.kilnCharges.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteKilnCharge(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.kilnCharges.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.kilnCharges.rows, id)
+	for readingID, r := range m.kilnReadings.rows {
+		if r.ChargeID == id {
+			delete(m.kilnReadings.rows, readingID)
+		}
+	}
+	return nil
+}
+
+func (m *memory) ListKilnReadings(ctx context.Context, spec query.Spec) (query.Page[models.KilnReading], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.kilnReadings.list(), KilnReadingResource, spec), nil
+}
+
+func (m *memory) SaveKilnReadings(ctx context.Context, chargeID int, readings []models.KilnReading) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.kilnCharges.rows[chargeID]; !ok {
+		return ErrNotFound
+	}
+	existing := map[string]int{}
+	for id, r := range m.kilnReadings.rows {
+		if r.ChargeID == chargeID {
+			existing[r.RecordedAt] = id
+		}
+	}
+	for i := range readings {
+		readings[i].ChargeID = chargeID
+		id, ok := existing[readings[i].RecordedAt]
+		if !ok {
+			id = m.kilnReadings.nextID()
+			existing[readings[i].RecordedAt] = id
+		}
+		readings[i].ReadingID = id
+		m.kilnReadings.rows[id] = readings[i]
+	}
+	return nil
+}
+
+// ==================== WASTE RECORDS ====================
+func (m *memory) ListWasteRecords(ctx context.Context, spec query.Spec) (query.Page[models.WasteRecord], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.wasteRecords.list(), WasteRecordResource, spec), nil
+}
+
+func (m *memory) CreateWasteRecord(ctx context.Context, wr *models.WasteRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wr.WasteID = m.wasteRecords.nextID()
+	wr.StockID = nil
+	m.wasteRecords.rows[wr.WasteID] = *wr
+	return nil
+}
+
+func (m *memory) UpdateWasteRecord(ctx context.Context, id int, wr *models.WasteRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.wasteRecords.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *wr
+	row.WasteID = id
+	row.StockID = old.StockID
+	m.wasteRecords.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteWasteRecord(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.wasteRecords.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.wasteRecords.rows, id)
+	return nil
+}
+
+func (m *memory) ValoriseWasteRecord(ctx context.Context, id, stockID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.wasteRecords.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row.Recycled = true
+	row.StockID = &stockID
+	m.wasteRecords.rows[id] = row
+	return nil
+}
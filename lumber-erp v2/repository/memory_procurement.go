@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== PURCHASE ORDERS ====================
+func (m *memory) ListPurchaseOrders(ctx context.Context, spec query.Spec) (query.Page[models.PurchaseOrder], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.purchaseOrders.list(), PurchaseOrderResource, spec), nil
+}
+
+func (m *memory) CreatePurchaseOrder(ctx context.Context, po *models.PurchaseOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	po.POID = m.purchaseOrders.nextID()
+	m.purchaseOrders.rows[po.POID] = *po
+	return nil
+}
+
+func (m *memory) UpdatePurchaseOrder(ctx context.Context, id int, po *models.PurchaseOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.purchaseOrders.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *po
+	row.POID = id
+	m.purchaseOrders.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeletePurchaseOrder(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.purchaseOrders.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.purchaseOrders.rows, id)
+	return nil
+}
+
+// ==================== PURCHASE ORDER ITEMS ====================
+func (m *memory) ListPurchaseOrderItems(ctx context.Context, spec query.Spec) (query.Page[models.PurchaseOrderItem], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.purchaseOrderItems.list(), PurchaseOrderItemResource, spec), nil
+}
+
+func (m *memory) CreatePurchaseOrderItem(ctx context.Context, poi *models.PurchaseOrderItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	poi.POItemID = m.purchaseOrderItems.nextID()
+	m.purchaseOrderItems.rows[poi.POItemID] = *poi
+	return nil
+}
+
+func (m *memory) UpdatePurchaseOrderItem(ctx context.Context, id int, poi *models.PurchaseOrderItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.purchaseOrderItems.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *poi
+	row.POItemID = id
+	m.purchaseOrderItems.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeletePurchaseOrderItem(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.purchaseOrderItems.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.purchaseOrderItems.rows, id)
+	return nil
+}
@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== QUALITY INSPECTIONS ====================
+func (m *memory) ListQualityInspections(ctx context.Context, spec query.Spec) (query.Page[models.QualityInspection], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	inspections := m.qualityInspections.list()
+	for i := range inspections {
+		inspections[i].Measurements, inspections[i].Defects = nil, nil
+	}
+	return query.Apply(inspections, QualityInspectionResource, spec), nil
+}
+
+func (m *memory) ListQualityInspectionsWithFindings(ctx context.Context, spec query.Spec) (query.Page[models.QualityInspection], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	inspections := m.qualityInspections.list()
+	for i := range inspections {
+		inspections[i] = copyInspection(inspections[i])
+	}
+	return query.Apply(inspections, QualityInspectionResource, spec), nil
+}
+
+func (m *memory) GetQualityInspection(ctx context.Context, id int) (models.QualityInspection, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	qi, ok := m.qualityInspections.rows[id]
+	if !ok {
+		return qi, ErrNotFound
+	}
+	return copyInspection(qi), nil
+}
+
+func (m *memory) CreateQualityInspection(ctx context.Context, qi *models.QualityInspection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	qi.InspectionID = m.qualityInspections.nextID()
+	m.qualityInspections.rows[qi.InspectionID] = copyInspection(*qi)
+	return nil
+}
+
+func (m *memory) UpdateQualityInspection(ctx context.Context, id int, qi *models.QualityInspection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.qualityInspections.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := copyInspection(*qi)
+	row.InspectionID = id
+	m.qualityInspections.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteQualityInspection(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.qualityInspections.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.qualityInspections.rows, id)
+	for stockID, si := range m.stockItems.rows {
+		if si.GradedBy != nil && *si.GradedBy == id {
+			si.GradedBy = nil
+			m.stockItems.rows[stockID] = si
+		}
+	}
+	for ncrID, nc := range m.nonConformances.rows {
+		if nc.InspectionID != nil && *nc.InspectionID == id {
+			nc.InspectionID = nil
+			m.nonConformances.rows[ncrID] = nc
+		}
+	}
+	return nil
+}
+
+// copyInspection copies the measurements and defects of qi, so the stored
+// row shares nothing with the caller
+func copyInspection(qi models.QualityInspection) models.QualityInspection {
+	qi.Measurements = append([]models.InspectionMeasurement(nil), qi.Measurements...)
+	qi.Defects = append([]models.InspectionDefect(nil), qi.Defects...)
+	return qi
+}
+
+// ==================== DEFECT CODES ====================
+func (m *memory) ListDefectCodes(ctx context.Context, spec query.Spec) (query.Page[models.DefectCode], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.defectCodes.list(), DefectCodeResource, spec), nil
+}
+
+func (m *memory) CreateDefectCode(ctx context.Context, dc *models.DefectCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.defectCodeTaken(dc.Code, 0) {
+		return &ConflictError{Field: "code"}
+	}
+	dc.DefectID = m.defectCodes.nextID()
+	m.defectCodes.rows[dc.DefectID] = *dc
+	return nil
+}
+
+func (m *memory) UpdateDefectCode(ctx context.Context, id int, dc *models.DefectCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.defectCodes.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if m.defectCodeTaken(dc.Code, id) {
+		return &ConflictError{Field: "code"}
+	}
+	row := *dc
+	row.DefectID = id
+	m.defectCodes.rows[id] = row
+	// Recorded defects follow a renamed code, as ON UPDATE CASCADE does
+	if old.Code != row.Code {
+		for inspectionID, qi := range m.qualityInspections.rows {
+			qi = copyInspection(qi)
+			for i := range qi.Defects {
+				if qi.Defects[i].Code == old.Code {
+					qi.Defects[i].Code = row.Code
+				}
+			}
+			m.qualityInspections.rows[inspectionID] = qi
+		}
+	}
+	return nil
+}
+
+func (m *memory) DeleteDefectCode(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.defectCodes.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.defectCodes.rows, id)
+	return nil
+}
+
+// defectCodeTaken reports whether a defect code other than id uses code
+func (m *memory) defectCodeTaken(code string, id int) bool {
+	for existingID, existing := range m.defectCodes.rows {
+		if existingID != id && existing.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ==================== INSPECTION TEMPLATES ====================
+func (m *memory) ListInspectionTemplates(ctx context.Context, spec query.Spec) (query.Page[models.InspectionTemplate], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	templates := m.inspectionTemplates.list()
+	for i := range templates {
+		templates[i].Items, templates[i].Grades = nil, nil
+	}
+	return query.Apply(templates, InspectionTemplateResource, spec), nil
+}
+
+func (m *memory) GetInspectionTemplate(ctx context.Context, id int) (models.InspectionTemplate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.inspectionTemplates.rows[id]
+	if !ok {
+		return t, ErrNotFound
+	}
+	return copyTemplate(t), nil
+}
+
+func (m *memory) CreateInspectionTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.TemplateID = m.inspectionTemplates.nextID()
+	m.inspectionTemplates.rows[t.TemplateID] = copyTemplate(*t)
+	return nil
+}
+
+func (m *memory) UpdateInspectionTemplate(ctx context.Context, id int, t *models.InspectionTemplate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.inspectionTemplates.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := copyTemplate(*t)
+	row.TemplateID = id
+	m.inspectionTemplates.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteInspectionTemplate(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.inspectionTemplates.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.inspectionTemplates.rows, id)
+	for inspectionID, qi := range m.qualityInspections.rows {
+		if qi.TemplateID != nil && *qi.TemplateID == id {
+			qi.TemplateID = nil
+			m.qualityInspections.rows[inspectionID] = qi
+		}
+	}
+	return nil
+}
+
+// copyTemplate copies the items and grades of t
+func copyTemplate(t models.InspectionTemplate) models.InspectionTemplate {
+	t.Items = append([]models.InspectionTemplateItem(nil), t.Items...)
+	t.Grades = append([]models.InspectionGrade(nil), t.Grades...)
+	return t
+}
+
+// ==================== NON-CONFORMANCES ====================
+func (m *memory) ListNonConformances(ctx context.Context, spec query.Spec) (query.Page[models.NonConformance], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.nonConformances.list(), NonConformanceResource, spec), nil
+}
+
+func (m *memory) GetNonConformance(ctx context.Context, id int) (models.NonConformance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	nc, ok := m.nonConformances.rows[id]
+	if !ok {
+		return nc, ErrNotFound
+	}
+	return nc, nil
+}
+
+func (m *memory) CreateNonConformance(ctx context.Context, nc *models.NonConformance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nc.NCRID = m.nonConformances.nextID()
+	nc.CreatedAt = now()
+	row := *nc
+	row.Actions = nil
+	m.nonConformances.rows[nc.NCRID] = row
+	return nil
+}
+
+func (m *memory) UpdateNonConformance(ctx context.Context, id int, nc *models.NonConformance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.nonConformances.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *nc
+	row.NCRID = id
+	row.CreatedAt = old.CreatedAt
+	row.Actions = nil
+	m.nonConformances.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteNonConformance(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.nonConformances.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.nonConformances.rows, id)
+	for actionID, ca := range m.correctiveActions.rows {
+		if ca.NCRID == id {
+			delete(m.correctiveActions.rows, actionID)
+		}
+	}
+	return nil
+}
+
+// ==================== CORRECTIVE ACTIONS ====================
+func (m *memory) ListCorrectiveActions(ctx context.Context, spec query.Spec) (query.Page[models.CorrectiveAction], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.correctiveActions.list(), CorrectiveActionResource, spec), nil
+}
+
+func (m *memory) CreateCorrectiveAction(ctx context.Context, ca *models.CorrectiveAction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ca.ActionID = m.correctiveActions.nextID()
+	ca.CreatedAt = now()
+	m.correctiveActions.rows[ca.ActionID] = *ca
+	return nil
+}
+
+func (m *memory) UpdateCorrectiveAction(ctx context.Context, id int, ca *models.CorrectiveAction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.correctiveActions.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *ca
+	row.ActionID = id
+	row.CreatedAt = old.CreatedAt
+	m.correctiveActions.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteCorrectiveAction(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.correctiveActions.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.correctiveActions.rows, id)
+	return nil
+}
+
+// ==================== QUALITY ALERTS ====================
+func (m *memory) ListQualityAlerts(ctx context.Context, spec query.Spec) (query.Page[models.QualityAlert], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.qualityAlerts.list(), QualityAlertResource, spec), nil
+}
+
+func (m *memory) RaiseQualityAlert(ctx context.Context, qa *models.QualityAlert) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, other := range m.qualityAlerts.rows {
+		if sameRef(other.UnitID, qa.UnitID) && sameRef(other.SpeciesID, qa.SpeciesID) && other.Metric == qa.Metric &&
+			other.Chart == qa.Chart && other.Rule == qa.Rule && other.InspectionID == qa.InspectionID {
+			return false, nil
+		}
+	}
+	qa.AlertID = m.qualityAlerts.nextID()
+	qa.CreatedAt = now()
+	m.qualityAlerts.rows[qa.AlertID] = *qa
+	return true, nil
+}
+
+func (m *memory) UpdateQualityAlert(ctx context.Context, id int, qa *models.QualityAlert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.qualityAlerts.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *qa
+	row.AlertID = id
+	row.CreatedAt = old.CreatedAt
+	m.qualityAlerts.rows[id] = row
+	return nil
+}
+
+// ==================== SAMPLING PLANS ====================
+func (m *memory) ListSamplingPlans(ctx context.Context, spec query.Spec) (query.Page[models.SamplingPlan], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.samplingPlans.list(), SamplingPlanResource, spec), nil
+}
+
+func (m *memory) CreateSamplingPlan(ctx context.Context, sp *models.SamplingPlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sp.PlanID = m.samplingPlans.nextID()
+	m.samplingPlans.rows[sp.PlanID] = *sp
+	return nil
+}
+
+func (m *memory) UpdateSamplingPlan(ctx context.Context, id int, sp *models.SamplingPlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.samplingPlans.rows[id]; !ok {
+		return ErrNotFound
+	}
+	sp.PlanID = id
+	m.samplingPlans.rows[id] = *sp
+	return nil
+}
+
+// DeleteSamplingPlan leaves the lots opened with the plan, which keep what
+// it called for
+func (m *memory) DeleteSamplingPlan(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.samplingPlans.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.samplingPlans.rows, id)
+	for lotID, il := range m.inspectionLots.rows {
+		if il.PlanID != nil && *il.PlanID == id {
+			il.PlanID = nil
+			m.inspectionLots.rows[lotID] = il
+		}
+	}
+	return nil
+}
+
+// ==================== INSPECTION LOTS ====================
+func (m *memory) ListInspectionLots(ctx context.Context, spec query.Spec) (query.Page[models.InspectionLot], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.inspectionLots.list(), InspectionLotResource, spec), nil
+}
+
+func (m *memory) GetInspectionLot(ctx context.Context, id int) (models.InspectionLot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	il, ok := m.inspectionLots.rows[id]
+	if !ok {
+		return il, ErrNotFound
+	}
+	return il, nil
+}
+
+func (m *memory) CreateInspectionLot(ctx context.Context, il *models.InspectionLot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, other := range m.inspectionLots.rows {
+		if other.POItemID == il.POItemID {
+			return &ConflictError{Field: "po_item_id"}
+		}
+	}
+	il.LotID = m.inspectionLots.nextID()
+	il.CreatedAt = now()
+	m.inspectionLots.rows[il.LotID] = *il
+	return nil
+}
+
+func (m *memory) UpdateInspectionLot(ctx context.Context, id int, il *models.InspectionLot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.inspectionLots.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *il
+	row.LotID = id
+	row.POItemID = old.POItemID
+	row.CreatedAt = old.CreatedAt
+	m.inspectionLots.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteInspectionLot(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.inspectionLots.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.inspectionLots.rows, id)
+	return nil
+}
+
+// sameRef reports whether two optional references name the same row
+func sameRef(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== CUSTOMERS ====================
+func (m *memory) ListCustomers(ctx context.Context, spec query.Spec) (query.Page[models.Customer], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.customers.list(), CustomerResource, spec), nil
+}
+
+func (m *memory) CreateCustomer(ctx context.Context, cust *models.Customer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cust.CustomerID = m.customers.nextID()
+	m.customers.rows[cust.CustomerID] = *cust
+	return nil
+}
+
+func (m *memory) UpdateCustomer(ctx context.Context, id int, cust *models.Customer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.customers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *cust
+	row.CustomerID = id
+	m.customers.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteCustomer(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.customers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.customers.rows, id)
+	return nil
+}
+
+// ==================== SALES ORDERS ====================
+func (m *memory) ListSalesOrders(ctx context.Context, spec query.Spec) (query.Page[models.SalesOrder], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.salesOrders.list(), SalesOrderResource, spec), nil
+}
+
+func (m *memory) CreateSalesOrder(ctx context.Context, so *models.SalesOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	so.SOID = m.salesOrders.nextID()
+	m.salesOrders.rows[so.SOID] = *so
+	return nil
+}
+
+func (m *memory) UpdateSalesOrder(ctx context.Context, id int, so *models.SalesOrder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.salesOrders.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *so
+	row.SOID = id
+	m.salesOrders.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteSalesOrder(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.salesOrders.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.salesOrders.rows, id)
+	return nil
+}
+
+// ==================== SALES ORDER ITEMS ====================
+func (m *memory) ListSalesOrderItems(ctx context.Context, spec query.Spec) (query.Page[models.SalesOrderItem], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.salesOrderItems.list(), SalesOrderItemResource, spec), nil
+}
+
+func (m *memory) CreateSalesOrderItem(ctx context.Context, soi *models.SalesOrderItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	soi.SOItemID = m.salesOrderItems.nextID()
+	m.salesOrderItems.rows[soi.SOItemID] = *soi
+	return nil
+}
+
+func (m *memory) UpdateSalesOrderItem(ctx context.Context, id int, soi *models.SalesOrderItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.salesOrderItems.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *soi
+	row.SOItemID = id
+	m.salesOrderItems.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteSalesOrderItem(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.salesOrderItems.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.salesOrderItems.rows, id)
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"math"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== SUPPLIERS ====================
+func (m *memory) ListSuppliers(ctx context.Context, spec query.Spec) (query.Page[models.Supplier], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.suppliers.list(), SupplierResource, spec), nil
+}
+
+func (m *memory) CreateSupplier(ctx context.Context, sup *models.Supplier) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sup.SupplierID = m.suppliers.nextID()
+	m.suppliers.rows[sup.SupplierID] = *sup
+	return nil
+}
+
+func (m *memory) UpdateSupplier(ctx context.Context, id int, sup *models.Supplier) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.suppliers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *sup
+	row.SupplierID = id
+	m.suppliers.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteSupplier(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.suppliers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.suppliers.rows, id)
+	return nil
+}
+
+// ==================== SUPPLIER PERFORMANCES ====================
+func (m *memory) ListSupplierPerformances(ctx context.Context, spec query.Spec) (query.Page[models.SupplierPerformance], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.supplierPerformances.list(), SupplierPerformanceResource, spec), nil
+}
+
+func (m *memory) CreateSupplierPerformance(ctx context.Context, sp *models.SupplierPerformance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sp.PerformanceID = m.supplierPerformances.nextID()
+	m.supplierPerformances.rows[sp.PerformanceID] = *sp
+	return nil
+}
+
+func (m *memory) UpdateSupplierPerformance(ctx context.Context, id int, sp *models.SupplierPerformance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.supplierPerformances.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *sp
+	row.PerformanceID = id
+	m.supplierPerformances.rows[id] = row
+	return nil
+}
+
+func (m *memory) AdjustQualityScore(ctx context.Context, id int, delta float64) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	row, ok := m.supplierPerformances.rows[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	old := row.QualityScore
+	row.QualityScore = math.Round(math.Max(0, math.Min(old+delta, 100))*100) / 100
+	m.supplierPerformances.rows[id] = row
+	return row.QualityScore - old, nil
+}
+
+func (m *memory) DeleteSupplierPerformance(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.supplierPerformances.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.supplierPerformances.rows, id)
+	return nil
+}
+
+// ==================== SUPPLIER CONTRACTS ====================
+func (m *memory) ListSupplierContracts(ctx context.Context, spec query.Spec) (query.Page[models.SupplierContract], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.supplierContracts.list(), SupplierContractResource, spec), nil
+}
+
+func (m *memory) CreateSupplierContract(ctx context.Context, sc *models.SupplierContract) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sc.ContractID = m.supplierContracts.nextID()
+	m.supplierContracts.rows[sc.ContractID] = *sc
+	return nil
+}
+
+func (m *memory) UpdateSupplierContract(ctx context.Context, id int, sc *models.SupplierContract) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.supplierContracts.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *sc
+	row.ContractID = id
+	m.supplierContracts.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteSupplierContract(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.supplierContracts.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.supplierContracts.rows, id)
+	return nil
+}
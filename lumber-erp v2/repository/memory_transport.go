@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== TRANSPORT COMPANIES ====================
+func (m *memory) ListTransportCompanies(ctx context.Context, spec query.Spec) (query.Page[models.TransportCompany], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.transportCompanies.list(), TransportCompanyResource, spec), nil
+}
+
+func (m *memory) CreateTransportCompany(ctx context.Context, tc *models.TransportCompany) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tc.CompanyID = m.transportCompanies.nextID()
+	m.transportCompanies.rows[tc.CompanyID] = *tc
+	return nil
+}
+
+func (m *memory) UpdateTransportCompany(ctx context.Context, id int, tc *models.TransportCompany) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.transportCompanies.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *tc
+	row.CompanyID = id
+	m.transportCompanies.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteTransportCompany(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.transportCompanies.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.transportCompanies.rows, id)
+	return nil
+}
+
+// ==================== TRUCKS ====================
+func (m *memory) ListTrucks(ctx context.Context, spec query.Spec) (query.Page[models.Truck], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.trucks.list(), TruckResource, spec), nil
+}
+
+func (m *memory) CreateTruck(ctx context.Context, truck *models.Truck) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	truck.TruckID = m.trucks.nextID()
+	m.trucks.rows[truck.TruckID] = *truck
+	return nil
+}
+
+func (m *memory) UpdateTruck(ctx context.Context, id int, truck *models.Truck) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.trucks.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *truck
+	row.TruckID = id
+	m.trucks.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteTruck(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.trucks.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.trucks.rows, id)
+	return nil
+}
+
+// ==================== DRIVERS ====================
+func (m *memory) ListDrivers(ctx context.Context, spec query.Spec) (query.Page[models.Driver], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.drivers.list(), DriverResource, spec), nil
+}
+
+func (m *memory) CreateDriver(ctx context.Context, driver *models.Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	driver.DriverID = m.drivers.nextID()
+	m.drivers.rows[driver.DriverID] = *driver
+	return nil
+}
+
+func (m *memory) UpdateDriver(ctx context.Context, id int, driver *models.Driver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.drivers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *driver
+	row.DriverID = id
+	m.drivers.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteDriver(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.drivers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.drivers.rows, id)
+	return nil
+}
+
+// ==================== ROUTES ====================
+func (m *memory) ListRoutes(ctx context.Context, spec query.Spec) (query.Page[models.Route], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.routes.list(), RouteResource, spec), nil
+}
+
+func (m *memory) CreateRoute(ctx context.Context, route *models.Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	route.RouteID = m.routes.nextID()
+	m.routes.rows[route.RouteID] = *route
+	return nil
+}
+
+func (m *memory) UpdateRoute(ctx context.Context, id int, route *models.Route) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.routes.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *route
+	row.RouteID = id
+	m.routes.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteRoute(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.routes.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.routes.rows, id)
+	return nil
+}
+
+// ==================== SHIPMENTS ====================
+func (m *memory) ListShipments(ctx context.Context, spec query.Spec) (query.Page[models.Shipment], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.shipments.list(), ShipmentResource, spec), nil
+}
+
+func (m *memory) CreateShipment(ctx context.Context, ship *models.Shipment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ship.ShipmentID = m.shipments.nextID()
+	m.shipments.rows[ship.ShipmentID] = *ship
+	return nil
+}
+
+func (m *memory) UpdateShipment(ctx context.Context, id int, ship *models.Shipment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.shipments.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *ship
+	row.ShipmentID = id
+	m.shipments.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteShipment(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.shipments.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.shipments.rows, id)
+	return nil
+}
+
+// ==================== FUEL LOGS ====================
+func (m *memory) ListFuelLogs(ctx context.Context, spec query.Spec) (query.Page[models.FuelLog], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.fuelLogs.list(), FuelLogResource, spec), nil
+}
+
+func (m *memory) CreateFuelLog(ctx context.Context, fl *models.FuelLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fl.FuelLogID = m.fuelLogs.nextID()
+	m.fuelLogs.rows[fl.FuelLogID] = *fl
+	return nil
+}
+
+func (m *memory) UpdateFuelLog(ctx context.Context, id int, fl *models.FuelLog) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.fuelLogs.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *fl
+	row.FuelLogID = id
+	m.fuelLogs.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteFuelLog(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.fuelLogs.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.fuelLogs.rows, id)
+	return nil
+}
@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== WAREHOUSES ====================
+func (m *memory) ListWarehouses(ctx context.Context, spec query.Spec) (query.Page[models.Warehouse], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.warehouses.list(), WarehouseResource, spec), nil
+}
+
+func (m *memory) CreateWarehouse(ctx context.Context, wh *models.Warehouse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wh.WarehouseID = m.warehouses.nextID()
+	m.warehouses.rows[wh.WarehouseID] = *wh
+	return nil
+}
+
+func (m *memory) UpdateWarehouse(ctx context.Context, id int, wh *models.Warehouse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.warehouses.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *wh
+	row.WarehouseID = id
+	m.warehouses.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteWarehouse(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.warehouses.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.warehouses.rows, id)
+	return nil
+}
+
+// ==================== PRODUCT TYPES ====================
+func (m *memory) ListProductTypes(ctx context.Context, spec query.Spec) (query.Page[models.ProductType], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.productTypes.list(), ProductTypeResource, spec), nil
+}
+
+func (m *memory) CreateProductType(ctx context.Context, pt *models.ProductType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pt.ProductTypeID = m.productTypes.nextID()
+	m.productTypes.rows[pt.ProductTypeID] = *pt
+	return nil
+}
+
+func (m *memory) UpdateProductType(ctx context.Context, id int, pt *models.ProductType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.productTypes.rows[id]; !ok {
+		return ErrNotFound
+	}
+	row := *pt
+	row.ProductTypeID = id
+	m.productTypes.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteProductType(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.productTypes.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.productTypes.rows, id)
+	return nil
+}
+
+// ==================== STOCK ITEMS ====================
+func (m *memory) ListStockItems(ctx context.Context, spec query.Spec) (query.Page[models.StockItem], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.stockItems.list(), StockItemResource, spec), nil
+}
+
+func (m *memory) LockStockItems(ctx context.Context, spec query.Spec) ([]models.StockItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	spec.Limit = 0
+	return query.Apply(m.stockItems.list(), StockItemResource, spec).Data, nil
+}
+
+func (m *memory) CreateStockItem(ctx context.Context, si *models.StockItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	opening := si.Quantity
+	si.StockID = m.stockItems.nextID()
+	si.Quantity = 0
+	si.Grade, si.GradedBy = "", nil
+	si.Status = StockAvailable
+	m.stockItems.rows[si.StockID] = *si
+	if opening == 0 {
+		return nil
+	}
+	si.Quantity = opening
+	entry := openingBalance(*si)
+	return m.postInventoryTransaction(&entry)
+}
+
+func (m *memory) UpdateStockItem(ctx context.Context, id int, si *models.StockItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.stockItems.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *si
+	row.StockID = id
+	row.Quantity = old.Quantity
+	row.WarehouseID = old.WarehouseID
+	row.Grade, row.GradedBy = old.Grade, old.GradedBy
+	row.Status = old.Status
+	m.stockItems.rows[id] = row
+	return nil
+}
+
+func (m *memory) GradeStockItem(ctx context.Context, id int, grade string, inspectionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	si, ok := m.stockItems.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	si.Grade, si.GradedBy = grade, &inspectionID
+	m.stockItems.rows[id] = si
+	return nil
+}
+
+func (m *memory) DeleteStockItem(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.stockItems.rows[id]; !ok {
+		return ErrNotFound
+	}
+	// StockDisposition restricts the delete, keeping a lot's trail
+	for _, sd := range m.stockDispositions.rows {
+		if sd.StockID == id {
+			return &RuleError{Code: "in_use", Message: "The row is still referenced from stockdisposition",
+				Details: map[string]string{"table": "stockdisposition"}}
+		}
+	}
+	delete(m.stockItems.rows, id)
+	return nil
+}
+
+// ==================== STOCK DISPOSITIONS ====================
+func (m *memory) ListStockDispositions(ctx context.Context, spec query.Spec) (query.Page[models.StockDisposition], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.stockDispositions.list(), StockDispositionResource, spec), nil
+}
+
+func (m *memory) CreateStockDisposition(ctx context.Context, sd *models.StockDisposition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	si, ok := m.stockItems.rows[sd.StockID]
+	if !ok {
+		return &ReferenceError{Field: "stock_id", Table: "stockitem"}
+	}
+	si.Status = sd.ToStatus
+	m.stockItems.rows[si.StockID] = si
+	sd.DispositionID = m.stockDispositions.nextID()
+	sd.CreatedAt = now()
+	m.stockDispositions.rows[sd.DispositionID] = *sd
+	return nil
+}
+
+// ==================== STOCK ALERTS ====================
+func (m *memory) ListStockAlerts(ctx context.Context, spec query.Spec) (query.Page[models.StockAlert], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.stockAlerts.list(), StockAlertResource, spec), nil
+}
+
+func (m *memory) CreateStockAlert(ctx context.Context, sa *models.StockAlert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sa.AlertID = m.stockAlerts.nextID()
+	sa.CreatedAt = now()
+	m.stockAlerts.rows[sa.AlertID] = *sa
+	return nil
+}
+
+func (m *memory) UpdateStockAlert(ctx context.Context, id int, sa *models.StockAlert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.stockAlerts.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *sa
+	row.AlertID = id
+	row.CreatedAt = old.CreatedAt
+	m.stockAlerts.rows[id] = row
+	return nil
+}
+
+func (m *memory) DeleteStockAlert(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.stockAlerts.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.stockAlerts.rows, id)
+	return nil
+}
+
+// ==================== INVENTORY TRANSACTIONS ====================
+func (m *memory) ListInventoryTransactions(ctx context.Context, spec query.Spec) (query.Page[models.InventoryTransaction], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.inventoryTransactions.list(), InventoryTransactionResource, spec), nil
+}
+
+func (m *memory) CreateInventoryTransaction(ctx context.Context, it *models.InventoryTransaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.postInventoryTransaction(it)
+}
+
+func (m *memory) ReverseInventoryTransaction(ctx context.Context, id int, it *models.InventoryTransaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	orig, ok := m.inventoryTransactions.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, row := range m.inventoryTransactions.rows {
+		if row.ReversesID != nil && *row.ReversesID == id {
+			return &ConflictError{Field: "reverses_id"}
+		}
+	}
+	if err := reversal(orig, it); err != nil {
+		return err
+	}
+	return m.postInventoryTransaction(it)
+}
+
+// postInventoryTransaction moves the stock of it and stores it; the caller
+// holds m.mu
+func (m *memory) postInventoryTransaction(it *models.InventoryTransaction) error {
+	stock, ok := m.stockItems.rows[it.StockID]
+	if !ok {
+		return &ReferenceError{Field: "stock_id", Table: "stockitem"}
+	}
+	balance, err := moveStock(stock, it.Quantity, m.warehouses.rows[stock.WarehouseID].AllowNegativeStock)
+	if err != nil {
+		return err
+	}
+	stock.Quantity = balance
+	m.stockItems.rows[stock.StockID] = stock
+
+	warehouseID := stock.WarehouseID
+	it.TransactionID = m.inventoryTransactions.nextID()
+	it.WarehouseID = &warehouseID
+	it.BalanceAfter = &balance
+	if it.TransactionDate == "" {
+		it.TransactionDate = now()
+	}
+	m.inventoryTransactions.rows[it.TransactionID] = *it
+	return nil
+}
+
+// ==================== STOCK TRANSFERS ====================
+func (m *memory) ListTransfers(ctx context.Context, spec query.Spec) (query.Page[models.StockTransfer], error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return query.Apply(m.stockTransfers.list(), StockTransferResource, spec), nil
+}
+
+func (m *memory) GetTransfer(ctx context.Context, id int) (models.StockTransfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.stockTransfers.rows[id]
+	if !ok {
+		return t, ErrNotFound
+	}
+	for _, line := range m.stockTransferLines.list() {
+		if line.TransferID == id {
+			t.Lines = append(t.Lines, line)
+		}
+	}
+	return t, nil
+}
+
+func (m *memory) CreateTransfer(ctx context.Context, t *models.StockTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.TransferID = m.stockTransfers.nextID()
+	t.CreatedAt = now()
+	row := *t
+	row.Lines = nil
+	m.stockTransfers.rows[t.TransferID] = row
+	m.insertTransferLines(t.TransferID, t.Lines)
+	return nil
+}
+
+func (m *memory) UpdateTransfer(ctx context.Context, id int, t *models.StockTransfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.stockTransfers.rows[id]
+	if !ok {
+		return ErrNotFound
+	}
+	row := *t
+	row.TransferID = id
+	row.CreatedAt = old.CreatedAt
+	row.Lines = nil
+	m.stockTransfers.rows[id] = row
+	return nil
+}
+
+func (m *memory) ReplaceTransferLines(ctx context.Context, id int, lines []models.StockTransferLine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.stockTransfers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	m.deleteTransferLines(id)
+	m.insertTransferLines(id, lines)
+	return nil
+}
+
+func (m *memory) UpdateTransferLine(ctx context.Context, line *models.StockTransferLine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.stockTransferLines.rows[line.LineID]; !ok {
+		return ErrNotFound
+	}
+	m.stockTransferLines.rows[line.LineID] = *line
+	return nil
+}
+
+func (m *memory) DeleteTransfer(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.stockTransfers.rows[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.stockTransfers.rows, id)
+	m.deleteTransferLines(id)
+	return nil
+}
+
+// insertTransferLines numbers and stores the lines of transfer id; the
+// caller holds m.mu
+func (m *memory) insertTransferLines(id int, lines []models.StockTransferLine) {
+	for i := range lines {
+		lines[i].LineID = m.stockTransferLines.nextID()
+		lines[i].TransferID = id
+		m.stockTransferLines.rows[lines[i].LineID] = lines[i]
+	}
+}
+
+func (m *memory) deleteTransferLines(id int) {
+	for lineID, line := range m.stockTransferLines.rows {
+		if line.TransferID == id {
+			delete(m.stockTransferLines.rows, lineID)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// postgres implements every repository on top of the shared *sql.DB
+type postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres returns repositories backed by the given database
+func NewPostgres(db *sql.DB) Repositories {
+	return newRepositories(&postgres{db: db})
+}
+
+// dbtx is the part of *sql.DB and *sql.Tx the queries use
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txKey carries the open transaction in a context
+type txKey struct{}
+
+// conn returns the transaction ctx belongs to, or the pool outside one
+func (p *postgres) conn(ctx context.Context) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return p.db
+}
+
+// WithinTx runs fn in a transaction, committing when it returns nil. Calls
+// inside a transaction join it instead of starting another.
+func (p *postgres) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// listPage runs the page and count queries of a list endpoint. columns and
+// from are spliced into the SQL built by res; scan reads one row.
+func listPage[T any](ctx context.Context, db dbtx, res query.Resource, spec query.Spec,
+	columns, from string, scan func(*sql.Rows, *T) error) (query.Page[T], error) {
+	var page query.Page[T]
+	countSQL, countArgs := res.Count(from, spec)
+	if err := db.QueryRowContext(ctx, countSQL, countArgs...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+
+	selectSQL, args := res.Select(columns, from, spec)
+	rows, err := db.QueryContext(ctx, selectSQL, args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	items := []T{}
+	for rows.Next() {
+		var x T
+		if err := scan(rows, &x); err != nil {
+			return page, err
+		}
+		items = append(items, x)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	return query.NewPage(items, res, spec, page.Total), nil
+}
+
+// execOne runs an UPDATE or DELETE of one row and reports ErrNotFound when
+// it matched nothing
+func execOne(ctx context.Context, db dbtx, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ==================== USERS ====================
+func (p *postgres) ListUsers(ctx context.Context, spec query.Spec) (query.Page[models.User], error) {
+	return listPage(ctx, p.conn(ctx), UserResource, spec,
+		`User_ID, Email, First_Name, Last_Name, Phone_Number, Status, CreatedAt`,
+		`"User"`,
+		func(rows *sql.Rows, x *models.User) error {
+			return rows.Scan(&x.UserID, &x.Email, &x.FirstName, &x.LastName, &x.PhoneNumber, &x.Status, &x.CreatedAt)
+		})
+}
+
+func (p *postgres) GetUser(ctx context.Context, id int) (models.User, error) {
+	var user models.User
+	query := `SELECT User_ID, Email, First_Name, Last_Name, Phone_Number, Status, CreatedAt
+              FROM "User" WHERE User_ID = $1`
+	err := p.conn(ctx).QueryRowContext(ctx, query, id).Scan(&user.UserID, &user.Email, &user.FirstName,
+		&user.LastName, &user.PhoneNumber, &user.Status, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+func (p *postgres) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	query := `SELECT User_ID, Email, Password, First_Name, Last_Name, Phone_Number, Status, CreatedAt
+              FROM "User" WHERE LOWER(Email) = LOWER($1)`
+	err := p.conn(ctx).QueryRowContext(ctx, query, email).Scan(&user.UserID, &user.Email, &user.Password,
+		&user.FirstName, &user.LastName, &user.PhoneNumber, &user.Status, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+func (p *postgres) PasswordHash(ctx context.Context, id int) (string, error) {
+	var hash string
+	err := p.conn(ctx).QueryRowContext(ctx, `SELECT Password FROM "User" WHERE User_ID = $1`, id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return hash, ErrNotFound
+	}
+	return hash, err
+}
+
+func (p *postgres) CreateUser(ctx context.Context, user *models.User) error {
+	query := `INSERT INTO "User" (Email, Password, First_Name, Last_Name, Phone_Number, Status)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING User_ID`
+	return p.conn(ctx).QueryRowContext(ctx, query, user.Email, user.Password, user.FirstName,
+		user.LastName, user.PhoneNumber, user.Status).Scan(&user.UserID)
+}
+
+func (p *postgres) UpdateUser(ctx context.Context, id int, user *models.User) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE "User" SET Email = $2, First_Name = $3,
+                  Last_Name = $4, Phone_Number = $5, Status = $6 WHERE User_ID = $1`
+		err := execOne(ctx, p.conn(ctx), query, id, user.Email, user.FirstName,
+			user.LastName, user.PhoneNumber, user.Status)
+		if err != nil || user.Status == "active" {
+			return err
+		}
+		// Deactivating the user signs them out everywhere
+		_, err = p.conn(ctx).ExecContext(ctx, `UPDATE UserSession SET RevokedAt = CURRENT_TIMESTAMP
+              WHERE User_ID = $1 AND RevokedAt IS NULL`, id)
+		return err
+	})
+}
+
+func (p *postgres) DeleteUser(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM "User" WHERE User_ID = $1`, id)
+}
+
+func (p *postgres) SetPassword(ctx context.Context, id int, hash string) error {
+	_, err := p.conn(ctx).ExecContext(ctx, `UPDATE "User" SET Password = $2 WHERE User_ID = $1`, id, hash)
+	return err
+}
+
+func (p *postgres) ChangePassword(ctx context.Context, id int, hash string) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		tx := p.conn(ctx)
+		if _, err := tx.ExecContext(ctx, `UPDATE "User" SET Password = $2 WHERE User_ID = $1`, id, hash); err != nil {
+			return err
+		}
+		// Changing the password signs the user out everywhere
+		_, err := tx.ExecContext(ctx, `UPDATE UserSession SET RevokedAt = CURRENT_TIMESTAMP
+              WHERE User_ID = $1 AND RevokedAt IS NULL`, id)
+		return err
+	})
+}
+
+// ==================== SESSIONS ====================
+func (p *postgres) CreateSession(ctx context.Context, session *models.UserSession) error {
+	return p.conn(ctx).QueryRowContext(ctx, `INSERT INTO UserSession (User_ID, RefreshTokenHash, ExpiresAt, IPAddress)
+              VALUES ($1, $2, $3, $4) RETURNING SessionID, CreatedAt`,
+		session.UserID, session.RefreshTokenHash, session.ExpiresAt, session.IPAddress).Scan(&session.SessionID, &session.CreatedAt)
+}
+
+func (p *postgres) GetSession(ctx context.Context, sessionID, userID int) (models.UserSession, error) {
+	var s models.UserSession
+	var ip sql.NullString
+	err := p.conn(ctx).QueryRowContext(ctx, `SELECT SessionID, User_ID, RefreshTokenHash, IPAddress, CreatedAt,
+              LastUsedAt, ExpiresAt, RevokedAt FROM UserSession
+              WHERE SessionID = $1 AND User_ID = $2`, sessionID, userID).Scan(&s.SessionID, &s.UserID,
+		&s.RefreshTokenHash, &ip, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt)
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	s.IPAddress = ip.String
+	return s, err
+}
+
+func (p *postgres) RotateSession(ctx context.Context, sessionID int, oldHash, newHash string, expiresAt time.Time) error {
+	return execOne(ctx, p.conn(ctx), `UPDATE UserSession SET RefreshTokenHash = $3, ExpiresAt = $4, LastUsedAt = CURRENT_TIMESTAMP
+              WHERE SessionID = $1 AND RefreshTokenHash = $2 AND RevokedAt IS NULL`, sessionID, oldHash, newHash, expiresAt)
+}
+
+func (p *postgres) RevokeSession(ctx context.Context, sessionID, userID int) error {
+	_, err := p.conn(ctx).ExecContext(ctx, `UPDATE UserSession SET RevokedAt = CURRENT_TIMESTAMP
+              WHERE SessionID = $1 AND User_ID = $2 AND RevokedAt IS NULL`, sessionID, userID)
+	return err
+}
+
+// ==================== PERMISSIONS ====================
+func (p *postgres) ListPermissions(ctx context.Context, spec query.Spec) (query.Page[models.Permission], error) {
+	return listPage(ctx, p.conn(ctx), PermissionResource, spec,
+		`PermissionID, ModuleName, ActionType`,
+		`Permission`,
+		func(rows *sql.Rows, x *models.Permission) error {
+			return rows.Scan(&x.PermissionID, &x.ModuleName, &x.ActionType)
+		})
+}
+
+func (p *postgres) CreatePermission(ctx context.Context, perm *models.Permission) error {
+	query := `INSERT INTO Permission (ModuleName, ActionType) VALUES ($1, $2) RETURNING PermissionID`
+	return p.conn(ctx).QueryRowContext(ctx, query, perm.ModuleName, perm.ActionType).Scan(&perm.PermissionID)
+}
+
+func (p *postgres) UpdatePermission(ctx context.Context, id int, perm *models.Permission) error {
+	query := `UPDATE Permission SET ModuleName = $2, ActionType = $3 WHERE PermissionID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, perm.ModuleName, perm.ActionType)
+}
+
+func (p *postgres) DeletePermission(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Permission WHERE PermissionID = $1`, id)
+}
+
+func (p *postgres) ListUserPermissions(ctx context.Context, userID int) ([]models.Permission, error) {
+	return p.queryPermissions(ctx, `SELECT DISTINCT p.PermissionID, p.ModuleName, p.ActionType
+                           FROM Permission p
+                           JOIN RolePermission rp ON rp.PermissionID = p.PermissionID
+                           JOIN Role r ON r.Role_ID = rp.Role_ID
+                           WHERE r.User_ID = $1
+                           ORDER BY p.ModuleName`, userID)
+}
+
+func (p *postgres) queryPermissions(ctx context.Context, query string, args ...interface{}) ([]models.Permission, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []models.Permission{}
+	for rows.Next() {
+		var perm models.Permission
+		rows.Scan(&perm.PermissionID, &perm.ModuleName, &perm.ActionType)
+		perms = append(perms, perm)
+	}
+	return perms, nil
+}
+
+func (p *postgres) HasPermission(ctx context.Context, userID int, module, action string) (bool, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, `SELECT r.Role_Name, COALESCE(p.ModuleName, ''), COALESCE(p.ActionType, '')
+                           FROM Role r
+                           LEFT JOIN RolePermission rp ON rp.Role_ID = r.Role_ID
+                           LEFT JOIN Permission p ON p.PermissionID = rp.PermissionID
+                           WHERE r.User_ID = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roleName, moduleName, actionType string
+		if err := rows.Scan(&roleName, &moduleName, &actionType); err != nil {
+			return false, err
+		}
+		if grants(roleName, moduleName, actionType, module, action) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// grants reports whether a role/permission pair allows the action on the module
+func grants(roleName, moduleName, actionType, module, action string) bool {
+	if strings.EqualFold(roleName, AdminRole) {
+		return true
+	}
+	return strings.EqualFold(moduleName, module) && strings.EqualFold(actionType, action)
+}
+
+// ==================== ROLES ====================
+func (p *postgres) ListRoles(ctx context.Context, spec query.Spec) (query.Page[models.Role], error) {
+	return listPage(ctx, p.conn(ctx), RoleResource, spec,
+		`Role_ID, User_ID, Role_Name, Description`,
+		`Role`,
+		func(rows *sql.Rows, x *models.Role) error {
+			return rows.Scan(&x.RoleID, &x.UserID, &x.RoleName, &x.Description)
+		})
+}
+
+func (p *postgres) ListRolesByUser(ctx context.Context, userID int) ([]models.Role, error) {
+	return p.queryRoles(ctx, `SELECT Role_ID, User_ID, Role_Name, Description FROM Role
+                           WHERE User_ID = $1 ORDER BY Role_Name`, userID)
+}
+
+func (p *postgres) queryRoles(ctx context.Context, query string, args ...interface{}) ([]models.Role, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		rows.Scan(&role.RoleID, &role.UserID, &role.RoleName, &role.Description)
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (p *postgres) CreateRole(ctx context.Context, role *models.Role) error {
+	query := `INSERT INTO Role (User_ID, Role_Name, Description) VALUES ($1, $2, $3) RETURNING Role_ID`
+	return p.conn(ctx).QueryRowContext(ctx, query, role.UserID, role.RoleName, role.Description).Scan(&role.RoleID)
+}
+
+func (p *postgres) UpdateRole(ctx context.Context, id int, role *models.Role) error {
+	query := `UPDATE Role SET User_ID = $2, Role_Name = $3, Description = $4 WHERE Role_ID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, role.UserID, role.RoleName, role.Description)
+}
+
+func (p *postgres) DeleteRole(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Role WHERE Role_ID = $1`, id)
+}
+
+// ==================== ROLE PERMISSIONS ====================
+func (p *postgres) ListRolePermissions(ctx context.Context, spec query.Spec) (query.Page[models.RolePermission], error) {
+	return listPage(ctx, p.conn(ctx), RolePermissionResource, spec,
+		`Role_ID, PermissionID`,
+		`RolePermission`,
+		func(rows *sql.Rows, x *models.RolePermission) error {
+			return rows.Scan(&x.RoleID, &x.PermissionID)
+		})
+}
+
+func (p *postgres) ListRolePermissionsByRole(ctx context.Context, roleID int) ([]models.RolePermission, error) {
+	return p.queryRolePermissions(ctx, `SELECT Role_ID, PermissionID FROM RolePermission WHERE Role_ID = $1`, roleID)
+}
+
+func (p *postgres) ListRolePermissionsByPermission(ctx context.Context, permissionID int) ([]models.RolePermission, error) {
+	return p.queryRolePermissions(ctx, `SELECT Role_ID, PermissionID FROM RolePermission WHERE PermissionID = $1`, permissionID)
+}
+
+func (p *postgres) queryRolePermissions(ctx context.Context, query string, args ...interface{}) ([]models.RolePermission, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rolePermissions := []models.RolePermission{}
+	for rows.Next() {
+		var rp models.RolePermission
+		rows.Scan(&rp.RoleID, &rp.PermissionID)
+		rolePermissions = append(rolePermissions, rp)
+	}
+	return rolePermissions, nil
+}
+
+func (p *postgres) CreateRolePermission(ctx context.Context, rp *models.RolePermission) error {
+	_, err := p.conn(ctx).ExecContext(ctx, `INSERT INTO RolePermission (Role_ID, PermissionID) VALUES ($1, $2)`,
+		rp.RoleID, rp.PermissionID)
+	return err
+}
+
+func (p *postgres) DeleteRolePermission(ctx context.Context, roleID, permissionID int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM RolePermission WHERE Role_ID = $1 AND PermissionID = $2`,
+		roleID, permissionID)
+}
+
+func (p *postgres) AssignPermissions(ctx context.Context, roleID int, permissionIDs []int) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		tx := p.conn(ctx)
+
+		// Delete existing permissions for this role
+		if _, err := tx.ExecContext(ctx, `DELETE FROM RolePermission WHERE Role_ID = $1`, roleID); err != nil {
+			return err
+		}
+
+		// Insert new permissions
+		for _, permID := range permissionIDs {
+			_, err := tx.ExecContext(ctx, `INSERT INTO RolePermission (Role_ID, PermissionID) VALUES ($1, $2)`, roleID, permID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ==================== AUDIT LOGS ====================
+func (p *postgres) ListAuditLogs(ctx context.Context, spec query.Spec) (query.Page[models.AuditLog], error) {
+	return listPage(ctx, p.conn(ctx), AuditLogResource, spec, auditLogColumns, `AuditLog`, scanAuditLog)
+}
+
+const auditLogColumns = `LogID, COALESCE(User_ID, 0), ActionType, COALESCE(EntityAffected, ''), COALESCE(EntityID, ''),
+		 Timestamp, COALESCE(Description, ''), COALESCE(IPAddress, ''), Changes,
+		 COALESCE(PrevHash, ''), COALESCE(Hash, '')`
+
+func scanAuditLog(rows *sql.Rows, x *models.AuditLog) error {
+	var changes []byte
+	err := rows.Scan(&x.LogID, &x.UserID, &x.ActionType, &x.EntityAffected, &x.EntityID,
+		&x.Timestamp, &x.Description, &x.IPAddress, &changes, &x.PrevHash, &x.Hash)
+	if changes != nil {
+		x.Changes = changes
+	}
+	return err
+}
+
+// auditChainLock serialises appends so each entry links to the one
+// committed before it
+const auditChainLock = 0x61756469 // "audi"
+
+func (p *postgres) CreateAuditLog(ctx context.Context, al *models.AuditLog) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		db := p.conn(ctx)
+		if _, err := db.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+			return err
+		}
+		err := db.QueryRowContext(ctx, `SELECT COALESCE(Hash, '') FROM AuditLog ORDER BY LogID DESC LIMIT 1`).Scan(&al.PrevHash)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		// The timestamp is hashed, so it is set here in the form it reads back
+		timestamp := time.Now().UTC().Truncate(time.Microsecond)
+		al.Timestamp = timestamp.Format(time.RFC3339Nano)
+		al.Hash = AuditHash(*al)
+
+		var changes interface{}
+		if len(al.Changes) > 0 {
+			changes = string(al.Changes)
+		}
+		query := `INSERT INTO AuditLog (User_ID, ActionType, EntityAffected, EntityID, Timestamp, Description,
+                  IPAddress, Changes, PrevHash, Hash)
+                  VALUES (NULLIF($1, 0), $2, $3, NULLIF($4, ''), $5, $6, $7, $8::jsonb, NULLIF($9, ''), $10)
+                  RETURNING LogID`
+		return db.QueryRowContext(ctx, query, al.UserID, al.ActionType, al.EntityAffected, al.EntityID,
+			timestamp, al.Description, al.IPAddress, changes, al.PrevHash, al.Hash).Scan(&al.LogID)
+	})
+}
+
+func (p *postgres) WalkAuditLogs(ctx context.Context, fn func(models.AuditLog) error) error {
+	rows, err := p.conn(ctx).QueryContext(ctx, `SELECT `+auditLogColumns+` FROM AuditLog ORDER BY LogID`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var al models.AuditLog
+		if err := scanAuditLog(rows, &al); err != nil {
+			return err
+		}
+		if err := fn(al); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (p *postgres) LatestAuditLog(ctx context.Context) (models.AuditLog, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, `SELECT `+auditLogColumns+` FROM AuditLog ORDER BY LogID DESC LIMIT 1`)
+	if err != nil {
+		return models.AuditLog{}, err
+	}
+	defer rows.Close()
+	var al models.AuditLog
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return al, err
+		}
+		return al, ErrNotFound
+	}
+	return al, scanAuditLog(rows, &al)
+}
+
+func (p *postgres) ListAuditCheckpoints(ctx context.Context) ([]models.AuditCheckpoint, error) {
+	rows, err := p.conn(ctx).QueryContext(ctx, `SELECT CheckpointID, LogID, Hash, CreatedAt, Signature
+              FROM AuditCheckpoint ORDER BY CheckpointID`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := []models.AuditCheckpoint{}
+	for rows.Next() {
+		var cp models.AuditCheckpoint
+		if err := rows.Scan(&cp.CheckpointID, &cp.LogID, &cp.Hash, &cp.CreatedAt, &cp.Signature); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, rows.Err()
+}
+
+func (p *postgres) CreateAuditCheckpoint(ctx context.Context, cp *models.AuditCheckpoint) error {
+	query := `INSERT INTO AuditCheckpoint (LogID, Hash, CreatedAt, Signature)
+              VALUES ($1, $2, $3, $4) RETURNING CheckpointID`
+	return p.conn(ctx).QueryRowContext(ctx, query, cp.LogID, cp.Hash, cp.CreatedAt, cp.Signature).Scan(&cp.CheckpointID)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== EMPLOYEES ====================
+func (p *postgres) ListEmployees(ctx context.Context, spec query.Spec) (query.Page[models.Employee], error) {
+	return listPage(ctx, p.conn(ctx), EmployeeResource, spec,
+		`EmployeeID, FullName, Department, Position, HireDate, PerformanceRating`,
+		`Employee`,
+		func(rows *sql.Rows, x *models.Employee) error {
+			return rows.Scan(&x.EmployeeID, &x.FullName, &x.Department, &x.Position, &x.HireDate, &x.PerformanceRating)
+		})
+}
+
+func (p *postgres) CreateEmployee(ctx context.Context, emp *models.Employee) error {
+	query := `INSERT INTO Employee (FullName, Department, Position, HireDate, PerformanceRating)
+              VALUES ($1, $2, $3, $4, $5) RETURNING EmployeeID`
+	return p.conn(ctx).QueryRowContext(ctx, query, emp.FullName, emp.Department, emp.Position, emp.HireDate,
+		emp.PerformanceRating).Scan(&emp.EmployeeID)
+}
+
+func (p *postgres) UpdateEmployee(ctx context.Context, id int, emp *models.Employee) error {
+	query := `UPDATE Employee SET FullName = $2, Department = $3, Position = $4,
+              HireDate = $5, PerformanceRating = $6 WHERE EmployeeID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, emp.FullName, emp.Department, emp.Position, emp.HireDate,
+		emp.PerformanceRating)
+}
+
+func (p *postgres) DeleteEmployee(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Employee WHERE EmployeeID = $1`, id)
+}
+
+// ==================== WORKER ASSIGNMENTS ====================
+func (p *postgres) ListWorkerAssignments(ctx context.Context, spec query.Spec) (query.Page[models.WorkerAssignment], error) {
+	return listPage(ctx, p.conn(ctx), WorkerAssignmentResource, spec,
+		`AssignmentID, EmployeeID, ProcessingID, RoleInTask, Notes`,
+		`WorkerAssignment`,
+		func(rows *sql.Rows, x *models.WorkerAssignment) error {
+			return rows.Scan(&x.AssignmentID, &x.EmployeeID, &x.ProcessingID, &x.RoleInTask, &x.Notes)
+		})
+}
+
+func (p *postgres) CreateWorkerAssignment(ctx context.Context, wa *models.WorkerAssignment) error {
+	query := `INSERT INTO WorkerAssignment (EmployeeID, ProcessingID, RoleInTask, Notes)
+              VALUES ($1, $2, $3, $4) RETURNING AssignmentID`
+	return p.conn(ctx).QueryRowContext(ctx, query, wa.EmployeeID, wa.ProcessingID, wa.RoleInTask, wa.Notes).Scan(&wa.AssignmentID)
+}
+
+func (p *postgres) UpdateWorkerAssignment(ctx context.Context, id int, wa *models.WorkerAssignment) error {
+	query := `UPDATE WorkerAssignment SET EmployeeID = $2, ProcessingID = $3,
+              RoleInTask = $4, Notes = $5 WHERE AssignmentID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, wa.EmployeeID, wa.ProcessingID, wa.RoleInTask, wa.Notes)
+}
+
+func (p *postgres) DeleteWorkerAssignment(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM WorkerAssignment WHERE AssignmentID = $1`, id)
+}
+
+// ==================== MANAGEMENT INSIGHTS ====================
+func (p *postgres) ListManagementInsights(ctx context.Context, spec query.Spec) (query.Page[models.ManagementInsights], error) {
+	return listPage(ctx, p.conn(ctx), ManagementInsightsResource, spec,
+		`Report_ID, EmployeeID, KPI_Type, Time_Period`,
+		`Management_Insights`,
+		func(rows *sql.Rows, x *models.ManagementInsights) error {
+			return rows.Scan(&x.ReportID, &x.EmployeeID, &x.KPIType, &x.TimePeriod)
+		})
+}
+
+func (p *postgres) CreateManagementInsights(ctx context.Context, mi *models.ManagementInsights) error {
+	query := `INSERT INTO Management_Insights (EmployeeID, KPI_Type, Time_Period)
+              VALUES ($1, $2, $3) RETURNING Report_ID`
+	return p.conn(ctx).QueryRowContext(ctx, query, mi.EmployeeID, mi.KPIType, mi.TimePeriod).Scan(&mi.ReportID)
+}
+
+func (p *postgres) UpdateManagementInsights(ctx context.Context, id int, mi *models.ManagementInsights) error {
+	query := `UPDATE Management_Insights SET EmployeeID = $2, KPI_Type = $3,
+              Time_Period = $4 WHERE Report_ID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, mi.EmployeeID, mi.KPIType, mi.TimePeriod)
+}
+
+func (p *postgres) DeleteManagementInsights(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Management_Insights WHERE Report_ID = $1`, id)
+}
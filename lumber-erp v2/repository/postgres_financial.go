@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== INVOICES ====================
+func (p *postgres) ListInvoices(ctx context.Context, spec query.Spec) (query.Page[models.Invoice], error) {
+	return listPage(ctx, p.conn(ctx), InvoiceResource, spec,
+		`InvoiceID, SOID, InvoiceDate, DueDate, TotalAmount, Tax, Currency, Status`,
+		`Invoice`,
+		func(rows *sql.Rows, x *models.Invoice) error {
+			return rows.Scan(&x.InvoiceID, &x.SOID, &x.InvoiceDate, &x.DueDate, &x.TotalAmount, &x.Tax, &x.Currency, &x.Status)
+		})
+}
+
+func (p *postgres) CreateInvoice(ctx context.Context, inv *models.Invoice) error {
+	query := `INSERT INTO Invoice (SOID, InvoiceDate, DueDate, TotalAmount, Tax, Currency, Status)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING InvoiceID`
+	return p.conn(ctx).QueryRowContext(ctx, query, inv.SOID, inv.InvoiceDate, inv.DueDate, inv.TotalAmount,
+		inv.Tax, inv.Currency, inv.Status).Scan(&inv.InvoiceID)
+}
+
+func (p *postgres) UpdateInvoice(ctx context.Context, id int, inv *models.Invoice) error {
+	query := `UPDATE Invoice SET SOID = $2, InvoiceDate = $3, DueDate = $4, TotalAmount = $5,
+              Tax = $6, Currency = $7, Status = $8 WHERE InvoiceID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, inv.SOID, inv.InvoiceDate, inv.DueDate, inv.TotalAmount,
+		inv.Tax, inv.Currency, inv.Status)
+}
+
+func (p *postgres) DeleteInvoice(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Invoice WHERE InvoiceID = $1`, id)
+}
+
+// ==================== PAYMENTS ====================
+func (p *postgres) ListPayments(ctx context.Context, spec query.Spec) (query.Page[models.Payment], error) {
+	return listPage(ctx, p.conn(ctx), PaymentResource, spec,
+		`PaymentID, InvoiceID, PaymentDate, Amount, Method, ReferenceNo, Status`,
+		`Payment`,
+		func(rows *sql.Rows, x *models.Payment) error {
+			return rows.Scan(&x.PaymentID, &x.InvoiceID, &x.PaymentDate, &x.Amount, &x.Method, &x.ReferenceNo, &x.Status)
+		})
+}
+
+func (p *postgres) CreatePayment(ctx context.Context, pay *models.Payment) error {
+	query := `INSERT INTO Payment (InvoiceID, PaymentDate, Amount, Method, ReferenceNo, Status)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING PaymentID`
+	return p.conn(ctx).QueryRowContext(ctx, query, pay.InvoiceID, pay.PaymentDate, pay.Amount, pay.Method,
+		pay.ReferenceNo, pay.Status).Scan(&pay.PaymentID)
+}
+
+func (p *postgres) UpdatePayment(ctx context.Context, id int, pay *models.Payment) error {
+	query := `UPDATE Payment SET InvoiceID = $2, PaymentDate = $3, Amount = $4,
+              Method = $5, ReferenceNo = $6, Status = $7 WHERE PaymentID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, pay.InvoiceID, pay.PaymentDate, pay.Amount, pay.Method,
+		pay.ReferenceNo, pay.Status)
+}
+
+func (p *postgres) DeletePayment(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Payment WHERE PaymentID = $1`, id)
+}
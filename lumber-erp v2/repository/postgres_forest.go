@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== FORESTS ====================
+func (p *postgres) ListForests(ctx context.Context, spec query.Spec) (query.Page[models.Forest], error) {
+	return listPage(ctx, p.conn(ctx), ForestResource, spec,
+		`ForestID, ForestName, GeoLocation, AreaSize, OwnershipType, Status`,
+		`Forest`,
+		func(rows *sql.Rows, x *models.Forest) error {
+			return rows.Scan(&x.ForestID, &x.ForestName, &x.GeoLocation, &x.AreaSize, &x.OwnershipType, &x.Status)
+		})
+}
+
+func (p *postgres) CreateForest(ctx context.Context, forest *models.Forest) error {
+	query := `INSERT INTO Forest (ForestName, GeoLocation, AreaSize, OwnershipType, Status)
+              VALUES ($1, $2, $3, $4, $5) RETURNING ForestID`
+	return p.conn(ctx).QueryRowContext(ctx, query, forest.ForestName, forest.GeoLocation, forest.AreaSize,
+		forest.OwnershipType, forest.Status).Scan(&forest.ForestID)
+}
+
+func (p *postgres) UpdateForest(ctx context.Context, id int, forest *models.Forest) error {
+	query := `UPDATE Forest SET ForestName = $2, GeoLocation = $3, AreaSize = $4,
+              OwnershipType = $5, Status = $6 WHERE ForestID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, forest.ForestName, forest.GeoLocation, forest.AreaSize,
+		forest.OwnershipType, forest.Status)
+}
+
+func (p *postgres) DeleteForest(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Forest WHERE ForestID = $1`, id)
+}
+
+// ==================== TREE SPECIES ====================
+func (p *postgres) ListTreeSpecies(ctx context.Context, spec query.Spec) (query.Page[models.TreeSpecies], error) {
+	return listPage(ctx, p.conn(ctx), TreeSpeciesResource, spec,
+		`SpeciesID, SpeciesName, AverageHeight, Density, MoistureContent, Grade`,
+		`TreeSpecies`,
+		func(rows *sql.Rows, x *models.TreeSpecies) error {
+			return rows.Scan(&x.SpeciesID, &x.SpeciesName, &x.AverageHeight, &x.Density, &x.MoistureContent, &x.Grade)
+		})
+}
+
+func (p *postgres) CreateTreeSpecies(ctx context.Context, ts *models.TreeSpecies) error {
+	query := `INSERT INTO TreeSpecies (SpeciesName, AverageHeight, Density, MoistureContent, Grade)
+              VALUES ($1, $2, $3, $4, $5) RETURNING SpeciesID`
+	return p.conn(ctx).QueryRowContext(ctx, query, ts.SpeciesName, ts.AverageHeight, ts.Density,
+		ts.MoistureContent, ts.Grade).Scan(&ts.SpeciesID)
+}
+
+func (p *postgres) UpdateTreeSpecies(ctx context.Context, id int, ts *models.TreeSpecies) error {
+	query := `UPDATE TreeSpecies SET SpeciesName = $2, AverageHeight = $3, Density = $4,
+              MoistureContent = $5, Grade = $6 WHERE SpeciesID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, ts.SpeciesName, ts.AverageHeight, ts.Density,
+		ts.MoistureContent, ts.Grade)
+}
+
+func (p *postgres) DeleteTreeSpecies(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM TreeSpecies WHERE SpeciesID = $1`, id)
+}
+
+// ==================== HARVEST SCHEDULES ====================
+func (p *postgres) ListHarvestSchedules(ctx context.Context, spec query.Spec) (query.Page[models.HarvestSchedule], error) {
+	return listPage(ctx, p.conn(ctx), HarvestScheduleResource, spec,
+		`ScheduleID, ForestID, StartDate, EndDate, Status`,
+		`HarvestSchedule`,
+		func(rows *sql.Rows, x *models.HarvestSchedule) error {
+			return rows.Scan(&x.ScheduleID, &x.ForestID, &x.StartDate, &x.EndDate, &x.Status)
+		})
+}
+
+func (p *postgres) CreateHarvestSchedule(ctx context.Context, hs *models.HarvestSchedule) error {
+	query := `INSERT INTO HarvestSchedule (ForestID, StartDate, EndDate, Status)
+              VALUES ($1, $2, $3, $4) RETURNING ScheduleID`
+	return p.conn(ctx).QueryRowContext(ctx, query, hs.ForestID, hs.StartDate, hs.EndDate, hs.Status).Scan(&hs.ScheduleID)
+}
+
+func (p *postgres) UpdateHarvestSchedule(ctx context.Context, id int, hs *models.HarvestSchedule) error {
+	query := `UPDATE HarvestSchedule SET ForestID = $2, StartDate = $3,
+              EndDate = $4, Status = $5 WHERE ScheduleID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, hs.ForestID, hs.StartDate, hs.EndDate, hs.Status)
+}
+
+func (p *postgres) DeleteHarvestSchedule(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM HarvestSchedule WHERE ScheduleID = $1`, id)
+}
+
+// ==================== HARVEST BATCHES ====================
+func (p *postgres) ListHarvestBatches(ctx context.Context, spec query.Spec) (query.Page[models.HarvestBatch], error) {
+	return listPage(ctx, p.conn(ctx), HarvestBatchResource, spec,
+		`BatchID, ForestID, SpeciesID, ScheduleID, Quantity, HarvestDate, QualityIndicator, QRCode`,
+		`HarvestBatch`,
+		func(rows *sql.Rows, x *models.HarvestBatch) error {
+			return rows.Scan(&x.BatchID, &x.ForestID, &x.SpeciesID, &x.ScheduleID, &x.Quantity, &x.HarvestDate, &x.QualityIndicator, &x.QRCode)
+		})
+}
+
+func (p *postgres) CreateHarvestBatch(ctx context.Context, hb *models.HarvestBatch) error {
+	query := `INSERT INTO HarvestBatch (ForestID, SpeciesID, ScheduleID, Quantity, HarvestDate, QualityIndicator, QRCode)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING BatchID`
+	return p.conn(ctx).QueryRowContext(ctx, query, hb.ForestID, hb.SpeciesID, hb.ScheduleID, hb.Quantity,
+		hb.HarvestDate, hb.QualityIndicator, hb.QRCode).Scan(&hb.BatchID)
+}
+
+func (p *postgres) UpdateHarvestBatch(ctx context.Context, id int, hb *models.HarvestBatch) error {
+	query := `UPDATE HarvestBatch SET ForestID = $2, SpeciesID = $3, ScheduleID = $4, Quantity = $5,
+              HarvestDate = $6, QualityIndicator = $7, QRCode = $8 WHERE BatchID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, hb.ForestID, hb.SpeciesID, hb.ScheduleID, hb.Quantity,
+		hb.HarvestDate, hb.QualityIndicator, hb.QRCode)
+}
+
+func (p *postgres) DeleteHarvestBatch(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM HarvestBatch WHERE BatchID = $1`, id)
+}
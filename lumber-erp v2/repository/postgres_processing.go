@@ -0,0 +1,448 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== SAWMILLS ====================
+func (p *postgres) ListSawmills(ctx context.Context, spec query.Spec) (query.Page[models.Sawmill], error) {
+	return listPage(ctx, p.conn(ctx), SawmillResource, spec,
+		`SawmillID, Name, Location, Capacity, Status`,
+		`Sawmill`,
+		func(rows *sql.Rows, x *models.Sawmill) error {
+			return rows.Scan(&x.SawmillID, &x.Name, &x.Location, &x.Capacity, &x.Status)
+		})
+}
+
+func (p *postgres) CreateSawmill(ctx context.Context, sm *models.Sawmill) error {
+	query := `INSERT INTO Sawmill (Name, Location, Capacity, Status)
+              VALUES ($1, $2, $3, $4) RETURNING SawmillID`
+	return p.conn(ctx).QueryRowContext(ctx, query, sm.Name, sm.Location, sm.Capacity, sm.Status).Scan(&sm.SawmillID)
+}
+
+func (p *postgres) UpdateSawmill(ctx context.Context, id int, sm *models.Sawmill) error {
+	query := `UPDATE Sawmill SET Name = $2, Location = $3,
+              Capacity = $4, Status = $5 WHERE SawmillID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, sm.Name, sm.Location, sm.Capacity, sm.Status)
+}
+
+func (p *postgres) DeleteSawmill(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Sawmill WHERE SawmillID = $1`, id)
+}
+
+// ==================== PROCESSING UNITS ====================
+func (p *postgres) ListProcessingUnits(ctx context.Context, spec query.Spec) (query.Page[models.ProcessingUnit], error) {
+	return listPage(ctx, p.conn(ctx), ProcessingUnitResource, spec,
+		`UnitID, SawmillID, Cutting, Drying, Finishing, Capacity, Status`,
+		`ProcessingUnit`,
+		func(rows *sql.Rows, x *models.ProcessingUnit) error {
+			return rows.Scan(&x.UnitID, &x.SawmillID, &x.Cutting, &x.Drying, &x.Finishing, &x.Capacity, &x.Status)
+		})
+}
+
+func (p *postgres) CreateProcessingUnit(ctx context.Context, pu *models.ProcessingUnit) error {
+	query := `INSERT INTO ProcessingUnit (SawmillID, Cutting, Drying, Finishing, Capacity, Status)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING UnitID`
+	return p.conn(ctx).QueryRowContext(ctx, query, pu.SawmillID, pu.Cutting, pu.Drying, pu.Finishing,
+		pu.Capacity, pu.Status).Scan(&pu.UnitID)
+}
+
+func (p *postgres) UpdateProcessingUnit(ctx context.Context, id int, pu *models.ProcessingUnit) error {
+	query := `UPDATE ProcessingUnit SET SawmillID = $2, Cutting = $3, Drying = $4,
+              Finishing = $5, Capacity = $6, Status = $7 WHERE UnitID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, pu.SawmillID, pu.Cutting, pu.Drying, pu.Finishing,
+		pu.Capacity, pu.Status)
+}
+
+func (p *postgres) DeleteProcessingUnit(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM ProcessingUnit WHERE UnitID = $1`, id)
+}
+
+// ==================== PROCESSING ORDERS ====================
+const processingOrderColumns = `ProcessingID, ProductTypeID, UnitID, StartDate, EndDate, OutputQuantity, EfficiencyRate,
+		Status, WarehouseID, InputQuantity, WasteQuantity, MassBalanceVariance, MassBalanceFlagged,
+		PlannedQuantity, DurationHours, Operations, ScheduledStart, ScheduledEnd`
+
+func scanProcessingOrder(row interface{ Scan(...interface{}) error }, x *models.ProcessingOrder) error {
+	return row.Scan(&x.ProcessingID, &x.ProductTypeID, &x.UnitID, &x.StartDate, &x.EndDate, &x.OutputQuantity,
+		&x.EfficiencyRate, &x.Status, &x.WarehouseID, &x.InputQuantity, &x.WasteQuantity, &x.MassBalanceVariance,
+		&x.MassBalanceFlagged, &x.PlannedQuantity, &x.DurationHours, &x.Operations, &x.ScheduledStart, &x.ScheduledEnd)
+}
+
+func (p *postgres) ListProcessingOrders(ctx context.Context, spec query.Spec) (query.Page[models.ProcessingOrder], error) {
+	return listPage(ctx, p.conn(ctx), ProcessingOrderResource, spec, processingOrderColumns, `ProcessingOrder`,
+		func(rows *sql.Rows, x *models.ProcessingOrder) error {
+			return scanProcessingOrder(rows, x)
+		})
+}
+
+// GetProcessingOrder locks the order when called within a transaction, so
+// its lifecycle steps run one at a time
+func (p *postgres) GetProcessingOrder(ctx context.Context, id int) (models.ProcessingOrder, error) {
+	var po models.ProcessingOrder
+	stmt := `SELECT ` + processingOrderColumns + ` FROM ProcessingOrder WHERE ProcessingID = $1`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	err := scanProcessingOrder(p.conn(ctx).QueryRowContext(ctx, stmt, id), &po)
+	if errors.Is(err, sql.ErrNoRows) {
+		return po, ErrNotFound
+	}
+	if err != nil {
+		return po, err
+	}
+
+	var spec query.Spec
+	spec.Where("processing_id", id)
+	inputs, err := p.ListHarvestBatchProcessing(ctx, spec)
+	po.Inputs = inputs.Data
+	return po, err
+}
+
+func (p *postgres) CreateProcessingOrder(ctx context.Context, po *models.ProcessingOrder) error {
+	query := `INSERT INTO ProcessingOrder (ProductTypeID, UnitID, StartDate, EndDate, OutputQuantity, EfficiencyRate,
+              Status, WarehouseID, InputQuantity, WasteQuantity, MassBalanceVariance, MassBalanceFlagged,
+              PlannedQuantity, DurationHours, Operations, ScheduledStart, ScheduledEnd)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+              RETURNING ProcessingID`
+	return p.conn(ctx).QueryRowContext(ctx, query, po.ProductTypeID, po.UnitID, po.StartDate, po.EndDate,
+		po.OutputQuantity, po.EfficiencyRate, po.Status, po.WarehouseID, po.InputQuantity, po.WasteQuantity,
+		po.MassBalanceVariance, po.MassBalanceFlagged, po.PlannedQuantity, po.DurationHours, po.Operations,
+		po.ScheduledStart, po.ScheduledEnd).Scan(&po.ProcessingID)
+}
+
+func (p *postgres) UpdateProcessingOrder(ctx context.Context, id int, po *models.ProcessingOrder) error {
+	query := `UPDATE ProcessingOrder SET ProductTypeID = $2, UnitID = $3, StartDate = $4,
+              EndDate = $5, OutputQuantity = $6, EfficiencyRate = $7, Status = $8, WarehouseID = $9,
+              InputQuantity = $10, WasteQuantity = $11, MassBalanceVariance = $12, MassBalanceFlagged = $13,
+              PlannedQuantity = $14, DurationHours = $15, Operations = $16, ScheduledStart = $17, ScheduledEnd = $18
+              WHERE ProcessingID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, po.ProductTypeID, po.UnitID, po.StartDate, po.EndDate,
+		po.OutputQuantity, po.EfficiencyRate, po.Status, po.WarehouseID, po.InputQuantity, po.WasteQuantity,
+		po.MassBalanceVariance, po.MassBalanceFlagged, po.PlannedQuantity, po.DurationHours, po.Operations,
+		po.ScheduledStart, po.ScheduledEnd)
+}
+
+func (p *postgres) DeleteProcessingOrder(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM ProcessingOrder WHERE ProcessingID = $1`, id)
+}
+
+// ==================== HARVEST BATCH PROCESSING ====================
+func (p *postgres) ListHarvestBatchProcessing(ctx context.Context, spec query.Spec) (query.Page[models.HarvestBatchProcessing], error) {
+	return listPage(ctx, p.conn(ctx), HarvestBatchProcessingResource, spec,
+		`ProcessingID, BatchID, ConsumedQuantity`,
+		`HarvestBatch_Processing`,
+		func(rows *sql.Rows, x *models.HarvestBatchProcessing) error {
+			return rows.Scan(&x.ProcessingID, &x.BatchID, &x.ConsumedQuantity)
+		})
+}
+
+// AttachHarvestBatch locks the batch row so concurrent orders cannot
+// together consume more than it holds
+func (p *postgres) AttachHarvestBatch(ctx context.Context, link *models.HarvestBatchProcessing) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		db := p.conn(ctx)
+		var quantity, consumed float64
+		err := db.QueryRowContext(ctx, `SELECT Quantity FROM HarvestBatch WHERE BatchID = $1 FOR UPDATE`,
+			link.BatchID).Scan(&quantity)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &ReferenceError{Field: "batch_id", Table: "harvestbatch"}
+		}
+		if err != nil {
+			return err
+		}
+		err = db.QueryRowContext(ctx, `SELECT COALESCE(SUM(ConsumedQuantity), 0) FROM HarvestBatch_Processing
+              WHERE BatchID = $1 AND ProcessingID <> $2`, link.BatchID, link.ProcessingID).Scan(&consumed)
+		if err != nil {
+			return err
+		}
+		if err := consumeBatch(link.BatchID, quantity, consumed, link.ConsumedQuantity); err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO HarvestBatch_Processing (ProcessingID, BatchID, ConsumedQuantity)
+              VALUES ($1, $2, $3)`, link.ProcessingID, link.BatchID, link.ConsumedQuantity)
+		return err
+	})
+}
+
+func (p *postgres) DetachHarvestBatch(ctx context.Context, processingID, batchID int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM HarvestBatch_Processing WHERE ProcessingID = $1 AND BatchID = $2`,
+		processingID, batchID)
+}
+
+// ==================== MAINTENANCE RECORDS ====================
+func (p *postgres) ListMaintenanceRecords(ctx context.Context, spec query.Spec) (query.Page[models.MaintenanceRecord], error) {
+	return listPage(ctx, p.conn(ctx), MaintenanceRecordResource, spec,
+		`MaintenanceID, UnitID, MaintenanceDate, Description, Cost, PartsUsed, DowntimeHours`,
+		`MaintenanceRecord`,
+		func(rows *sql.Rows, x *models.MaintenanceRecord) error {
+			return rows.Scan(&x.MaintenanceID, &x.UnitID, &x.MaintenanceDate, &x.Description, &x.Cost, &x.PartsUsed, &x.DowntimeHours)
+		})
+}
+
+func (p *postgres) CreateMaintenanceRecord(ctx context.Context, mr *models.MaintenanceRecord) error {
+	query := `INSERT INTO MaintenanceRecord (UnitID, MaintenanceDate, Description, Cost, PartsUsed, DowntimeHours)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING MaintenanceID`
+	return p.conn(ctx).QueryRowContext(ctx, query, mr.UnitID, mr.MaintenanceDate, mr.Description, mr.Cost,
+		mr.PartsUsed, mr.DowntimeHours).Scan(&mr.MaintenanceID)
+}
+
+func (p *postgres) UpdateMaintenanceRecord(ctx context.Context, id int, mr *models.MaintenanceRecord) error {
+	query := `UPDATE MaintenanceRecord SET UnitID = $2, MaintenanceDate = $3, Description = $4,
+              Cost = $5, PartsUsed = $6, DowntimeHours = $7 WHERE MaintenanceID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, mr.UnitID, mr.MaintenanceDate, mr.Description, mr.Cost,
+		mr.PartsUsed, mr.DowntimeHours)
+}
+
+func (p *postgres) DeleteMaintenanceRecord(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM MaintenanceRecord WHERE MaintenanceID = $1`, id)
+}
+
+// ==================== MAINTENANCE PLANS ====================
+const maintenancePlanColumns = `PlanID, UnitID, COALESCE(Description, ''), IntervalHours, IntervalDays, Active,
+	LastDoneAt, LastDoneHours, CreatedAt`
+
+func scanMaintenancePlan(row interface{ Scan(...interface{}) error }, x *models.MaintenancePlan) error {
+	return row.Scan(&x.PlanID, &x.UnitID, &x.Description, &x.IntervalHours, &x.IntervalDays, &x.Active,
+		&x.LastDoneAt, &x.LastDoneHours, &x.CreatedAt)
+}
+
+func (p *postgres) ListMaintenancePlans(ctx context.Context, spec query.Spec) (query.Page[models.MaintenancePlan], error) {
+	return listPage(ctx, p.conn(ctx), MaintenancePlanResource, spec, maintenancePlanColumns, `MaintenancePlan`,
+		func(rows *sql.Rows, x *models.MaintenancePlan) error { return scanMaintenancePlan(rows, x) })
+}
+
+func (p *postgres) LockMaintenancePlans(ctx context.Context, spec query.Spec) ([]models.MaintenancePlan, error) {
+	spec.Limit = 0
+	stmt, args := MaintenancePlanResource.Select(maintenancePlanColumns, `MaintenancePlan`, spec)
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	rows, err := p.conn(ctx).QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var plans []models.MaintenancePlan
+	for rows.Next() {
+		var mp models.MaintenancePlan
+		if err := scanMaintenancePlan(rows, &mp); err != nil {
+			return nil, err
+		}
+		plans = append(plans, mp)
+	}
+	return plans, rows.Err()
+}
+
+func (p *postgres) CreateMaintenancePlan(ctx context.Context, mp *models.MaintenancePlan) error {
+	var lastDone interface{}
+	if mp.LastDoneAt != "" {
+		lastDone = mp.LastDoneAt
+	}
+	query := `INSERT INTO MaintenancePlan (UnitID, Description, IntervalHours, IntervalDays, Active, LastDoneAt, LastDoneHours)
+              VALUES ($1, $2, $3, $4, $5, COALESCE($6::timestamp, CURRENT_TIMESTAMP), $7)
+              RETURNING PlanID, LastDoneAt, CreatedAt`
+	return p.conn(ctx).QueryRowContext(ctx, query, mp.UnitID, mp.Description, mp.IntervalHours, mp.IntervalDays,
+		mp.Active, lastDone, mp.LastDoneHours).Scan(&mp.PlanID, &mp.LastDoneAt, &mp.CreatedAt)
+}
+
+func (p *postgres) UpdateMaintenancePlan(ctx context.Context, id int, mp *models.MaintenancePlan) error {
+	query := `UPDATE MaintenancePlan SET UnitID = $2, Description = $3, IntervalHours = $4, IntervalDays = $5,
+              Active = $6, LastDoneAt = $7, LastDoneHours = $8 WHERE PlanID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, mp.UnitID, mp.Description, mp.IntervalHours, mp.IntervalDays,
+		mp.Active, mp.LastDoneAt, mp.LastDoneHours)
+}
+
+func (p *postgres) DeleteMaintenancePlan(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM MaintenancePlan WHERE PlanID = $1`, id)
+}
+
+// ==================== MAINTENANCE WORK ORDERS ====================
+const workOrderColumns = `WorkOrderID, UnitID, PlanID, Status, Reason, COALESCE(Description, ''), OperatingHours,
+	CreatedAt, ClosedAt, MaintenanceID`
+
+func scanWorkOrder(row interface{ Scan(...interface{}) error }, x *models.MaintenanceWorkOrder) error {
+	return row.Scan(&x.WorkOrderID, &x.UnitID, &x.PlanID, &x.Status, &x.Reason, &x.Description, &x.OperatingHours,
+		&x.CreatedAt, &x.ClosedAt, &x.MaintenanceID)
+}
+
+func (p *postgres) ListWorkOrders(ctx context.Context, spec query.Spec) (query.Page[models.MaintenanceWorkOrder], error) {
+	return listPage(ctx, p.conn(ctx), MaintenanceWorkOrderResource, spec, workOrderColumns, `MaintenanceWorkOrder`,
+		func(rows *sql.Rows, x *models.MaintenanceWorkOrder) error { return scanWorkOrder(rows, x) })
+}
+
+func (p *postgres) GetWorkOrder(ctx context.Context, id int) (models.MaintenanceWorkOrder, error) {
+	var wo models.MaintenanceWorkOrder
+	stmt := `SELECT ` + workOrderColumns + ` FROM MaintenanceWorkOrder WHERE WorkOrderID = $1`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	err := scanWorkOrder(p.conn(ctx).QueryRowContext(ctx, stmt, id), &wo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return wo, ErrNotFound
+	}
+	return wo, err
+}
+
+func (p *postgres) CreateWorkOrder(ctx context.Context, wo *models.MaintenanceWorkOrder) error {
+	query := `INSERT INTO MaintenanceWorkOrder (UnitID, PlanID, Status, Reason, Description, OperatingHours)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING WorkOrderID, CreatedAt`
+	return p.conn(ctx).QueryRowContext(ctx, query, wo.UnitID, wo.PlanID, wo.Status, wo.Reason, wo.Description,
+		wo.OperatingHours).Scan(&wo.WorkOrderID, &wo.CreatedAt)
+}
+
+func (p *postgres) UpdateWorkOrder(ctx context.Context, id int, wo *models.MaintenanceWorkOrder) error {
+	query := `UPDATE MaintenanceWorkOrder SET UnitID = $2, PlanID = $3, Status = $4, Reason = $5, Description = $6,
+              OperatingHours = $7, ClosedAt = $8, MaintenanceID = $9 WHERE WorkOrderID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, wo.UnitID, wo.PlanID, wo.Status, wo.Reason, wo.Description,
+		wo.OperatingHours, wo.ClosedAt, wo.MaintenanceID)
+}
+
+// ==================== KILN CHARGES ====================
+const kilnChargeColumns = `ChargeID, UnitID, Status, Flag, TargetMoisture, TargetSource, Tolerance, PlannedHours,
+	Moisture, COALESCE(Notes, ''), StartedAt, CompletedAt, UnloadedAt`
+
+func scanKilnCharge(row interface{ Scan(...interface{}) error }, x *models.KilnCharge) error {
+	return row.Scan(&x.ChargeID, &x.UnitID, &x.Status, &x.Flag, &x.TargetMoisture, &x.TargetSource, &x.Tolerance,
+		&x.PlannedHours, &x.Moisture, &x.Notes, &x.StartedAt, &x.CompletedAt, &x.UnloadedAt)
+}
+
+func (p *postgres) ListKilnCharges(ctx context.Context, spec query.Spec) (query.Page[models.KilnCharge], error) {
+	return listPage(ctx, p.conn(ctx), KilnChargeResource, spec, kilnChargeColumns, `KilnCharge`,
+		func(rows *sql.Rows, x *models.KilnCharge) error { return scanKilnCharge(rows, x) })
+}
+
+func (p *postgres) GetKilnCharge(ctx context.Context, id int) (models.KilnCharge, error) {
+	var kc models.KilnCharge
+	stmt := `SELECT ` + kilnChargeColumns + ` FROM KilnCharge WHERE ChargeID = $1`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	db := p.conn(ctx)
+	err := scanKilnCharge(db.QueryRowContext(ctx, stmt, id), &kc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return kc, ErrNotFound
+	}
+	if err != nil {
+		return kc, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT StockID FROM KilnChargeLot WHERE ChargeID = $1 ORDER BY StockID`, id)
+	if err != nil {
+		return kc, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var stockID int
+		if err := rows.Scan(&stockID); err != nil {
+			return kc, err
+		}
+		kc.StockIDs = append(kc.StockIDs, stockID)
+	}
+	return kc, rows.Err()
+}
+
+func (p *postgres) CreateKilnCharge(ctx context.Context, kc *models.KilnCharge) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		var started interface{}
+		if kc.StartedAt != "" {
+			started = kc.StartedAt
+		}
+		query := `INSERT INTO KilnCharge (UnitID, Status, Flag, TargetMoisture, TargetSource, Tolerance, PlannedHours,
+                  Moisture, Notes, StartedAt)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::timestamptz, CURRENT_TIMESTAMP))
+                  RETURNING ChargeID, StartedAt`
+		db := p.conn(ctx)
+		err := db.QueryRowContext(ctx, query, kc.UnitID, kc.Status, kc.Flag, kc.TargetMoisture, kc.TargetSource,
+			kc.Tolerance, kc.PlannedHours, kc.Moisture, kc.Notes, started).Scan(&kc.ChargeID, &kc.StartedAt)
+		if err != nil {
+			return err
+		}
+		for _, stockID := range kc.StockIDs {
+			if _, err := db.ExecContext(ctx, `INSERT INTO KilnChargeLot (ChargeID, StockID) VALUES ($1, $2)`,
+				kc.ChargeID, stockID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *postgres) UpdateKilnCharge(ctx context.Context, id int, kc *models.KilnCharge) error {
+	query := `UPDATE KilnCharge SET UnitID = $2, Status = $3, Flag = $4, TargetMoisture = $5, TargetSource = $6,
+              Tolerance = $7, PlannedHours = $8, Moisture = $9, Notes = $10, StartedAt = $11, CompletedAt = $12,
+              UnloadedAt = $13 WHERE ChargeID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, kc.UnitID, kc.Status, kc.Flag, kc.TargetMoisture, kc.TargetSource,
+		kc.Tolerance, kc.PlannedHours, kc.Moisture, kc.Notes, kc.StartedAt, kc.CompletedAt, kc.UnloadedAt)
+}
+
+func (p *postgres) DeleteKilnCharge(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM KilnCharge WHERE ChargeID = $1`, id)
+}
+
+func (p *postgres) ListKilnReadings(ctx context.Context, spec query.Spec) (query.Page[models.KilnReading], error) {
+	return listPage(ctx, p.conn(ctx), KilnReadingResource, spec,
+		`ReadingID, ChargeID, RecordedAt, Temperature, Humidity, Moisture`,
+		`KilnReading`,
+		func(rows *sql.Rows, x *models.KilnReading) error {
+			return rows.Scan(&x.ReadingID, &x.ChargeID, &x.RecordedAt, &x.Temperature, &x.Humidity, &x.Moisture)
+		})
+}
+
+func (p *postgres) SaveKilnReadings(ctx context.Context, chargeID int, readings []models.KilnReading) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO KilnReading (ChargeID, RecordedAt, Temperature, Humidity, Moisture)
+                  VALUES ($1, $2, $3, $4, $5)
+                  ON CONFLICT (ChargeID, RecordedAt) DO UPDATE SET Temperature = EXCLUDED.Temperature,
+                  Humidity = EXCLUDED.Humidity, Moisture = EXCLUDED.Moisture
+                  RETURNING ReadingID`
+		for i := range readings {
+			readings[i].ChargeID = chargeID
+			err := p.conn(ctx).QueryRowContext(ctx, query, chargeID, readings[i].RecordedAt, readings[i].Temperature,
+				readings[i].Humidity, readings[i].Moisture).Scan(&readings[i].ReadingID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ==================== WASTE RECORDS ====================
+func (p *postgres) ListWasteRecords(ctx context.Context, spec query.Spec) (query.Page[models.WasteRecord], error) {
+	return listPage(ctx, p.conn(ctx), WasteRecordResource, spec,
+		`WasteID, ProcessingID, WasteType, Volume, DisposalMethod, Recycled, DisposalCost, StockID`,
+		`WasteRecord`,
+		func(rows *sql.Rows, x *models.WasteRecord) error {
+			return rows.Scan(&x.WasteID, &x.ProcessingID, &x.WasteType, &x.Volume, &x.DisposalMethod, &x.Recycled,
+				&x.DisposalCost, &x.StockID)
+		})
+}
+
+func (p *postgres) CreateWasteRecord(ctx context.Context, wr *models.WasteRecord) error {
+	wr.StockID = nil
+	query := `INSERT INTO WasteRecord (ProcessingID, WasteType, Volume, DisposalMethod, Recycled, DisposalCost)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING WasteID`
+	return p.conn(ctx).QueryRowContext(ctx, query, wr.ProcessingID, wr.WasteType, wr.Volume, wr.DisposalMethod,
+		wr.Recycled, wr.DisposalCost).Scan(&wr.WasteID)
+}
+
+func (p *postgres) UpdateWasteRecord(ctx context.Context, id int, wr *models.WasteRecord) error {
+	query := `UPDATE WasteRecord SET ProcessingID = $2, WasteType = $3, Volume = $4,
+              DisposalMethod = $5, Recycled = $6, DisposalCost = $7 WHERE WasteID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, wr.ProcessingID, wr.WasteType, wr.Volume, wr.DisposalMethod,
+		wr.Recycled, wr.DisposalCost)
+}
+
+func (p *postgres) DeleteWasteRecord(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM WasteRecord WHERE WasteID = $1`, id)
+}
+
+func (p *postgres) ValoriseWasteRecord(ctx context.Context, id, stockID int) error {
+	return execOne(ctx, p.conn(ctx), `UPDATE WasteRecord SET Recycled = TRUE, StockID = $2 WHERE WasteID = $1`, id, stockID)
+}
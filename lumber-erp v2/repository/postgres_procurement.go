@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== PURCHASE ORDERS ====================
+func (p *postgres) ListPurchaseOrders(ctx context.Context, spec query.Spec) (query.Page[models.PurchaseOrder], error) {
+	return listPage(ctx, p.conn(ctx), PurchaseOrderResource, spec,
+		`POID, EmployeeID, SupplierID, OrderDate, ExpectedDeliveryDate, Status, TotalAmount`,
+		`PurchaseOrder`,
+		func(rows *sql.Rows, x *models.PurchaseOrder) error {
+			return rows.Scan(&x.POID, &x.EmployeeID, &x.SupplierID, &x.OrderDate, &x.ExpectedDeliveryDate, &x.Status, &x.TotalAmount)
+		})
+}
+
+func (p *postgres) CreatePurchaseOrder(ctx context.Context, po *models.PurchaseOrder) error {
+	query := `INSERT INTO PurchaseOrder (EmployeeID, SupplierID, OrderDate, ExpectedDeliveryDate, Status, TotalAmount)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING POID`
+	return p.conn(ctx).QueryRowContext(ctx, query, po.EmployeeID, po.SupplierID, po.OrderDate,
+		po.ExpectedDeliveryDate, po.Status, po.TotalAmount).Scan(&po.POID)
+}
+
+func (p *postgres) UpdatePurchaseOrder(ctx context.Context, id int, po *models.PurchaseOrder) error {
+	query := `UPDATE PurchaseOrder SET EmployeeID = $2, SupplierID = $3, OrderDate = $4,
+              ExpectedDeliveryDate = $5, Status = $6, TotalAmount = $7 WHERE POID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, po.EmployeeID, po.SupplierID, po.OrderDate,
+		po.ExpectedDeliveryDate, po.Status, po.TotalAmount)
+}
+
+func (p *postgres) DeletePurchaseOrder(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM PurchaseOrder WHERE POID = $1`, id)
+}
+
+// ==================== PURCHASE ORDER ITEMS ====================
+func (p *postgres) ListPurchaseOrderItems(ctx context.Context, spec query.Spec) (query.Page[models.PurchaseOrderItem], error) {
+	return listPage(ctx, p.conn(ctx), PurchaseOrderItemResource, spec,
+		`POItemID, POID, ProductTypeID, Quantity, UnitPrice, Subtotal`,
+		`PurchaseOrderItem`,
+		func(rows *sql.Rows, x *models.PurchaseOrderItem) error {
+			return rows.Scan(&x.POItemID, &x.POID, &x.ProductTypeID, &x.Quantity, &x.UnitPrice, &x.Subtotal)
+		})
+}
+
+func (p *postgres) CreatePurchaseOrderItem(ctx context.Context, poi *models.PurchaseOrderItem) error {
+	query := `INSERT INTO PurchaseOrderItem (POID, ProductTypeID, Quantity, UnitPrice, Subtotal)
+              VALUES ($1, $2, $3, $4, $5) RETURNING POItemID`
+	return p.conn(ctx).QueryRowContext(ctx, query, poi.POID, poi.ProductTypeID, poi.Quantity, poi.UnitPrice,
+		poi.Subtotal).Scan(&poi.POItemID)
+}
+
+func (p *postgres) UpdatePurchaseOrderItem(ctx context.Context, id int, poi *models.PurchaseOrderItem) error {
+	query := `UPDATE PurchaseOrderItem SET POID = $2, ProductTypeID = $3, Quantity = $4,
+              UnitPrice = $5, Subtotal = $6 WHERE POItemID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, poi.POID, poi.ProductTypeID, poi.Quantity, poi.UnitPrice,
+		poi.Subtotal)
+}
+
+func (p *postgres) DeletePurchaseOrderItem(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM PurchaseOrderItem WHERE POItemID = $1`, id)
+}
@@ -0,0 +1,484 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== QUALITY INSPECTIONS ====================
+const qualityInspectionColumns = `InspectionID, EmployeeID, ProcessingID, POItemID, BatchID, Result, MoistureLevel,
+	CertificationID, Date, TemplateID, Stage, Grade`
+
+func scanQualityInspection(row interface{ Scan(...interface{}) error }, x *models.QualityInspection) error {
+	return row.Scan(&x.InspectionID, &x.EmployeeID, &x.ProcessingID, &x.POItemID, &x.BatchID, &x.Result,
+		&x.MoistureLevel, &x.CertificationID, &x.Date, &x.TemplateID, &x.Stage, &x.Grade)
+}
+
+func (p *postgres) ListQualityInspections(ctx context.Context, spec query.Spec) (query.Page[models.QualityInspection], error) {
+	return listPage(ctx, p.conn(ctx), QualityInspectionResource, spec, qualityInspectionColumns, `QualityInspection`,
+		func(rows *sql.Rows, x *models.QualityInspection) error { return scanQualityInspection(rows, x) })
+}
+
+// inspectionFindingColumns aggregate the measurements and defects of each
+// inspection row into JSON arrays
+const inspectionFindingColumns = `
+	(SELECT COALESCE(json_agg(json_build_object('position', m.Position, 'kind', m.Kind, 'value', m.Value,
+		'passed', m.Passed) ORDER BY m.Position), '[]') FROM InspectionMeasurement m
+	 WHERE m.InspectionID = QualityInspection.InspectionID),
+	(SELECT COALESCE(json_agg(json_build_object('code', d.Code, 'count', d.Count) ORDER BY d.Code), '[]')
+	 FROM InspectionDefect d WHERE d.InspectionID = QualityInspection.InspectionID)`
+
+func (p *postgres) ListQualityInspectionsWithFindings(ctx context.Context, spec query.Spec) (query.Page[models.QualityInspection], error) {
+	return listPage(ctx, p.conn(ctx), QualityInspectionResource, spec,
+		qualityInspectionColumns+`,`+inspectionFindingColumns, `QualityInspection`,
+		func(rows *sql.Rows, x *models.QualityInspection) error {
+			var measurements, defects []byte
+			err := rows.Scan(&x.InspectionID, &x.EmployeeID, &x.ProcessingID, &x.POItemID, &x.BatchID, &x.Result,
+				&x.MoistureLevel, &x.CertificationID, &x.Date, &x.TemplateID, &x.Stage, &x.Grade, &measurements, &defects)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(measurements, &x.Measurements); err != nil {
+				return err
+			}
+			return json.Unmarshal(defects, &x.Defects)
+		})
+}
+
+func (p *postgres) GetQualityInspection(ctx context.Context, id int) (models.QualityInspection, error) {
+	var qi models.QualityInspection
+	db := p.conn(ctx)
+	err := scanQualityInspection(db.QueryRowContext(ctx,
+		`SELECT `+qualityInspectionColumns+` FROM QualityInspection WHERE InspectionID = $1`, id), &qi)
+	if errors.Is(err, sql.ErrNoRows) {
+		return qi, ErrNotFound
+	}
+	if err != nil {
+		return qi, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT Position, Kind, Value, Passed FROM InspectionMeasurement
+                                       WHERE InspectionID = $1 ORDER BY Position`, id)
+	if err != nil {
+		return qi, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m models.InspectionMeasurement
+		if err := rows.Scan(&m.Position, &m.Kind, &m.Value, &m.Passed); err != nil {
+			return qi, err
+		}
+		qi.Measurements = append(qi.Measurements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return qi, err
+	}
+
+	defects, err := db.QueryContext(ctx, `SELECT Code, Count FROM InspectionDefect WHERE InspectionID = $1 ORDER BY Code`, id)
+	if err != nil {
+		return qi, err
+	}
+	defer defects.Close()
+	for defects.Next() {
+		var d models.InspectionDefect
+		if err := defects.Scan(&d.Code, &d.Count); err != nil {
+			return qi, err
+		}
+		qi.Defects = append(qi.Defects, d)
+	}
+	return qi, defects.Err()
+}
+
+func (p *postgres) CreateQualityInspection(ctx context.Context, qi *models.QualityInspection) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO QualityInspection (EmployeeID, ProcessingID, POItemID, BatchID, Result, MoistureLevel,
+                  CertificationID, Date, TemplateID, Stage, Grade)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING InspectionID`
+		err := p.conn(ctx).QueryRowContext(ctx, query, qi.EmployeeID, qi.ProcessingID, qi.POItemID, qi.BatchID,
+			qi.Result, qi.MoistureLevel, qi.CertificationID, qi.Date, qi.TemplateID, qi.Stage, qi.Grade).Scan(&qi.InspectionID)
+		if err != nil {
+			return err
+		}
+		return p.saveInspectionFindings(ctx, qi)
+	})
+}
+
+func (p *postgres) UpdateQualityInspection(ctx context.Context, id int, qi *models.QualityInspection) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE QualityInspection SET EmployeeID = $2, ProcessingID = $3, POItemID = $4, BatchID = $5,
+                  Result = $6, MoistureLevel = $7, CertificationID = $8, Date = $9, TemplateID = $10, Stage = $11,
+                  Grade = $12 WHERE InspectionID = $1`
+		err := execOne(ctx, p.conn(ctx), query, id, qi.EmployeeID, qi.ProcessingID, qi.POItemID, qi.BatchID,
+			qi.Result, qi.MoistureLevel, qi.CertificationID, qi.Date, qi.TemplateID, qi.Stage, qi.Grade)
+		if err != nil {
+			return err
+		}
+		db := p.conn(ctx)
+		if _, err := db.ExecContext(ctx, `DELETE FROM InspectionMeasurement WHERE InspectionID = $1`, id); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM InspectionDefect WHERE InspectionID = $1`, id); err != nil {
+			return err
+		}
+		qi.InspectionID = id
+		return p.saveInspectionFindings(ctx, qi)
+	})
+}
+
+// saveInspectionFindings inserts the measurements and defects of qi
+func (p *postgres) saveInspectionFindings(ctx context.Context, qi *models.QualityInspection) error {
+	db := p.conn(ctx)
+	for _, m := range qi.Measurements {
+		if _, err := db.ExecContext(ctx, `INSERT INTO InspectionMeasurement (InspectionID, Position, Kind, Value, Passed)
+                                          VALUES ($1, $2, $3, $4, $5)`,
+			qi.InspectionID, m.Position, m.Kind, m.Value, m.Passed); err != nil {
+			return err
+		}
+	}
+	for _, d := range qi.Defects {
+		if _, err := db.ExecContext(ctx, `INSERT INTO InspectionDefect (InspectionID, Code, Count) VALUES ($1, $2, $3)`,
+			qi.InspectionID, d.Code, d.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *postgres) DeleteQualityInspection(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM QualityInspection WHERE InspectionID = $1`, id)
+}
+
+// ==================== DEFECT CODES ====================
+func (p *postgres) ListDefectCodes(ctx context.Context, spec query.Spec) (query.Page[models.DefectCode], error) {
+	return listPage(ctx, p.conn(ctx), DefectCodeResource, spec,
+		`DefectID, Code, Name, Category, Severity, Description`,
+		`DefectCode`,
+		func(rows *sql.Rows, x *models.DefectCode) error {
+			return rows.Scan(&x.DefectID, &x.Code, &x.Name, &x.Category, &x.Severity, &x.Description)
+		})
+}
+
+func (p *postgres) CreateDefectCode(ctx context.Context, dc *models.DefectCode) error {
+	query := `INSERT INTO DefectCode (Code, Name, Category, Severity, Description)
+              VALUES ($1, $2, $3, $4, $5) RETURNING DefectID`
+	return p.conn(ctx).QueryRowContext(ctx, query, dc.Code, dc.Name, dc.Category, dc.Severity,
+		dc.Description).Scan(&dc.DefectID)
+}
+
+func (p *postgres) UpdateDefectCode(ctx context.Context, id int, dc *models.DefectCode) error {
+	query := `UPDATE DefectCode SET Code = $2, Name = $3, Category = $4, Severity = $5, Description = $6
+              WHERE DefectID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, dc.Code, dc.Name, dc.Category, dc.Severity, dc.Description)
+}
+
+func (p *postgres) DeleteDefectCode(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM DefectCode WHERE DefectID = $1`, id)
+}
+
+// ==================== INSPECTION TEMPLATES ====================
+const inspectionTemplateColumns = `TemplateID, Name, ProductTypeID, Stage, Active`
+
+func scanInspectionTemplate(row interface{ Scan(...interface{}) error }, x *models.InspectionTemplate) error {
+	return row.Scan(&x.TemplateID, &x.Name, &x.ProductTypeID, &x.Stage, &x.Active)
+}
+
+func (p *postgres) ListInspectionTemplates(ctx context.Context, spec query.Spec) (query.Page[models.InspectionTemplate], error) {
+	return listPage(ctx, p.conn(ctx), InspectionTemplateResource, spec, inspectionTemplateColumns, `InspectionTemplate`,
+		func(rows *sql.Rows, x *models.InspectionTemplate) error { return scanInspectionTemplate(rows, x) })
+}
+
+func (p *postgres) GetInspectionTemplate(ctx context.Context, id int) (models.InspectionTemplate, error) {
+	var t models.InspectionTemplate
+	db := p.conn(ctx)
+	err := scanInspectionTemplate(db.QueryRowContext(ctx,
+		`SELECT `+inspectionTemplateColumns+` FROM InspectionTemplate WHERE TemplateID = $1`, id), &t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT Position, Kind, Label, Unit, MinValue, MaxValue, Required
+                                       FROM InspectionTemplateItem WHERE TemplateID = $1 ORDER BY Position`, id)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item models.InspectionTemplateItem
+		if err := rows.Scan(&item.Position, &item.Kind, &item.Label, &item.Unit, &item.Min, &item.Max,
+			&item.Required); err != nil {
+			return t, err
+		}
+		t.Items = append(t.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return t, err
+	}
+
+	grades, err := db.QueryContext(ctx, `SELECT Rank, Grade, MaxKnots, MaxWane, MaxSplit, MaxWarp, MaxMinorDefects,
+                                         MaxMajorDefects, MaxCriticalDefects
+                                         FROM InspectionGrade WHERE TemplateID = $1 ORDER BY Rank`, id)
+	if err != nil {
+		return t, err
+	}
+	defer grades.Close()
+	for grades.Next() {
+		var g models.InspectionGrade
+		if err := grades.Scan(&g.Rank, &g.Grade, &g.MaxKnots, &g.MaxWane, &g.MaxSplit, &g.MaxWarp,
+			&g.MaxMinorDefects, &g.MaxMajorDefects, &g.MaxCriticalDefects); err != nil {
+			return t, err
+		}
+		t.Grades = append(t.Grades, g)
+	}
+	return t, grades.Err()
+}
+
+func (p *postgres) CreateInspectionTemplate(ctx context.Context, t *models.InspectionTemplate) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO InspectionTemplate (Name, ProductTypeID, Stage, Active)
+                  VALUES ($1, $2, $3, $4) RETURNING TemplateID`
+		err := p.conn(ctx).QueryRowContext(ctx, query, t.Name, t.ProductTypeID, t.Stage, t.Active).Scan(&t.TemplateID)
+		if err != nil {
+			return err
+		}
+		return p.saveTemplateRules(ctx, t)
+	})
+}
+
+func (p *postgres) UpdateInspectionTemplate(ctx context.Context, id int, t *models.InspectionTemplate) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE InspectionTemplate SET Name = $2, ProductTypeID = $3, Stage = $4, Active = $5
+                  WHERE TemplateID = $1`
+		if err := execOne(ctx, p.conn(ctx), query, id, t.Name, t.ProductTypeID, t.Stage, t.Active); err != nil {
+			return err
+		}
+		db := p.conn(ctx)
+		if _, err := db.ExecContext(ctx, `DELETE FROM InspectionTemplateItem WHERE TemplateID = $1`, id); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM InspectionGrade WHERE TemplateID = $1`, id); err != nil {
+			return err
+		}
+		t.TemplateID = id
+		return p.saveTemplateRules(ctx, t)
+	})
+}
+
+// saveTemplateRules inserts the items and grades of t
+func (p *postgres) saveTemplateRules(ctx context.Context, t *models.InspectionTemplate) error {
+	db := p.conn(ctx)
+	for _, item := range t.Items {
+		if _, err := db.ExecContext(ctx, `INSERT INTO InspectionTemplateItem (TemplateID, Position, Kind, Label, Unit,
+                                          MinValue, MaxValue, Required) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			t.TemplateID, item.Position, item.Kind, item.Label, item.Unit, item.Min, item.Max, item.Required); err != nil {
+			return err
+		}
+	}
+	for _, g := range t.Grades {
+		if _, err := db.ExecContext(ctx, `INSERT INTO InspectionGrade (TemplateID, Rank, Grade, MaxKnots, MaxWane,
+                                          MaxSplit, MaxWarp, MaxMinorDefects, MaxMajorDefects, MaxCriticalDefects)
+                                          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			t.TemplateID, g.Rank, g.Grade, g.MaxKnots, g.MaxWane, g.MaxSplit, g.MaxWarp, g.MaxMinorDefects,
+			g.MaxMajorDefects, g.MaxCriticalDefects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *postgres) DeleteInspectionTemplate(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM InspectionTemplate WHERE TemplateID = $1`, id)
+}
+
+// ==================== NON-CONFORMANCES ====================
+const nonConformanceColumns = `NCRID, Title, Description, Origin, Severity, InspectionID, SupplierID, UnitID, RaisedBy,
+	OwnerID, Status, RootCause, DueDate, ScoreDeducted, PerformanceID, CreatedAt, ClosedAt`
+
+func scanNonConformance(row interface{ Scan(...interface{}) error }, x *models.NonConformance) error {
+	return row.Scan(&x.NCRID, &x.Title, &x.Description, &x.Origin, &x.Severity, &x.InspectionID, &x.SupplierID,
+		&x.UnitID, &x.RaisedBy, &x.OwnerID, &x.Status, &x.RootCause, &x.DueDate, &x.ScoreDeducted, &x.PerformanceID,
+		&x.CreatedAt, &x.ClosedAt)
+}
+
+func (p *postgres) ListNonConformances(ctx context.Context, spec query.Spec) (query.Page[models.NonConformance], error) {
+	return listPage(ctx, p.conn(ctx), NonConformanceResource, spec, nonConformanceColumns, `NonConformance`,
+		func(rows *sql.Rows, x *models.NonConformance) error { return scanNonConformance(rows, x) })
+}
+
+func (p *postgres) GetNonConformance(ctx context.Context, id int) (models.NonConformance, error) {
+	var nc models.NonConformance
+	stmt := `SELECT ` + nonConformanceColumns + ` FROM NonConformance WHERE NCRID = $1`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	err := scanNonConformance(p.conn(ctx).QueryRowContext(ctx, stmt, id), &nc)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nc, ErrNotFound
+	}
+	return nc, err
+}
+
+func (p *postgres) CreateNonConformance(ctx context.Context, nc *models.NonConformance) error {
+	query := `INSERT INTO NonConformance (Title, Description, Origin, Severity, InspectionID, SupplierID, UnitID,
+              RaisedBy, OwnerID, Status, RootCause, DueDate, ScoreDeducted, PerformanceID)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING NCRID, CreatedAt`
+	return p.conn(ctx).QueryRowContext(ctx, query, nc.Title, nc.Description, nc.Origin, nc.Severity,
+		nc.InspectionID, nc.SupplierID, nc.UnitID, nc.RaisedBy, nc.OwnerID, nc.Status, nc.RootCause, nc.DueDate,
+		nc.ScoreDeducted, nc.PerformanceID).Scan(&nc.NCRID, &nc.CreatedAt)
+}
+
+func (p *postgres) UpdateNonConformance(ctx context.Context, id int, nc *models.NonConformance) error {
+	query := `UPDATE NonConformance SET Title = $2, Description = $3, Origin = $4, Severity = $5, InspectionID = $6,
+              SupplierID = $7, UnitID = $8, RaisedBy = $9, OwnerID = $10, Status = $11, RootCause = $12,
+              DueDate = $13, ScoreDeducted = $14, PerformanceID = $15, ClosedAt = $16 WHERE NCRID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, nc.Title, nc.Description, nc.Origin, nc.Severity, nc.InspectionID,
+		nc.SupplierID, nc.UnitID, nc.RaisedBy, nc.OwnerID, nc.Status, nc.RootCause, nc.DueDate, nc.ScoreDeducted,
+		nc.PerformanceID, nc.ClosedAt)
+}
+
+func (p *postgres) DeleteNonConformance(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM NonConformance WHERE NCRID = $1`, id)
+}
+
+// ==================== CORRECTIVE ACTIONS ====================
+const correctiveActionColumns = `ActionID, NCRID, Kind, Description, OwnerID, DueDate, Status, Notes, CreatedAt,
+	CompletedAt`
+
+func (p *postgres) ListCorrectiveActions(ctx context.Context, spec query.Spec) (query.Page[models.CorrectiveAction], error) {
+	return listPage(ctx, p.conn(ctx), CorrectiveActionResource, spec, correctiveActionColumns, `CorrectiveAction`,
+		func(rows *sql.Rows, x *models.CorrectiveAction) error {
+			return rows.Scan(&x.ActionID, &x.NCRID, &x.Kind, &x.Description, &x.OwnerID, &x.DueDate, &x.Status,
+				&x.Notes, &x.CreatedAt, &x.CompletedAt)
+		})
+}
+
+func (p *postgres) CreateCorrectiveAction(ctx context.Context, ca *models.CorrectiveAction) error {
+	query := `INSERT INTO CorrectiveAction (NCRID, Kind, Description, OwnerID, DueDate, Status, Notes)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ActionID, CreatedAt`
+	return p.conn(ctx).QueryRowContext(ctx, query, ca.NCRID, ca.Kind, ca.Description, ca.OwnerID, ca.DueDate,
+		ca.Status, ca.Notes).Scan(&ca.ActionID, &ca.CreatedAt)
+}
+
+func (p *postgres) UpdateCorrectiveAction(ctx context.Context, id int, ca *models.CorrectiveAction) error {
+	query := `UPDATE CorrectiveAction SET NCRID = $2, Kind = $3, Description = $4, OwnerID = $5, DueDate = $6,
+              Status = $7, Notes = $8, CompletedAt = $9 WHERE ActionID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, ca.NCRID, ca.Kind, ca.Description, ca.OwnerID, ca.DueDate,
+		ca.Status, ca.Notes, ca.CompletedAt)
+}
+
+func (p *postgres) DeleteCorrectiveAction(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM CorrectiveAction WHERE ActionID = $1`, id)
+}
+
+// ==================== QUALITY ALERTS ====================
+func (p *postgres) ListQualityAlerts(ctx context.Context, spec query.Spec) (query.Page[models.QualityAlert], error) {
+	return listPage(ctx, p.conn(ctx), QualityAlertResource, spec,
+		`AlertID, UnitID, SpeciesID, Metric, Chart, Rule, InspectionID, Value, Message, Status, CreatedAt`,
+		`QualityAlert`,
+		func(rows *sql.Rows, x *models.QualityAlert) error {
+			return rows.Scan(&x.AlertID, &x.UnitID, &x.SpeciesID, &x.Metric, &x.Chart, &x.Rule, &x.InspectionID,
+				&x.Value, &x.Message, &x.Status, &x.CreatedAt)
+		})
+}
+
+func (p *postgres) RaiseQualityAlert(ctx context.Context, qa *models.QualityAlert) (bool, error) {
+	query := `INSERT INTO QualityAlert (UnitID, SpeciesID, Metric, Chart, Rule, InspectionID, Value, Message, Status)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT DO NOTHING RETURNING AlertID, CreatedAt`
+	err := p.conn(ctx).QueryRowContext(ctx, query, qa.UnitID, qa.SpeciesID, qa.Metric, qa.Chart, qa.Rule,
+		qa.InspectionID, qa.Value, qa.Message, qa.Status).Scan(&qa.AlertID, &qa.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *postgres) UpdateQualityAlert(ctx context.Context, id int, qa *models.QualityAlert) error {
+	query := `UPDATE QualityAlert SET Message = $2, Status = $3 WHERE AlertID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, qa.Message, qa.Status)
+}
+
+// ==================== SAMPLING PLANS ====================
+func (p *postgres) ListSamplingPlans(ctx context.Context, spec query.Spec) (query.Page[models.SamplingPlan], error) {
+	return listPage(ctx, p.conn(ctx), SamplingPlanResource, spec,
+		`PlanID, SupplierID, ProductTypeID, InspectionLevel, AQL, Active, Notes`, `SamplingPlan`,
+		func(rows *sql.Rows, x *models.SamplingPlan) error {
+			return rows.Scan(&x.PlanID, &x.SupplierID, &x.ProductTypeID, &x.InspectionLevel, &x.AQL, &x.Active,
+				&x.Notes)
+		})
+}
+
+func (p *postgres) CreateSamplingPlan(ctx context.Context, sp *models.SamplingPlan) error {
+	query := `INSERT INTO SamplingPlan (SupplierID, ProductTypeID, InspectionLevel, AQL, Active, Notes)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING PlanID`
+	return p.conn(ctx).QueryRowContext(ctx, query, sp.SupplierID, sp.ProductTypeID, sp.InspectionLevel, sp.AQL,
+		sp.Active, sp.Notes).Scan(&sp.PlanID)
+}
+
+func (p *postgres) UpdateSamplingPlan(ctx context.Context, id int, sp *models.SamplingPlan) error {
+	query := `UPDATE SamplingPlan SET SupplierID = $2, ProductTypeID = $3, InspectionLevel = $4, AQL = $5,
+              Active = $6, Notes = $7 WHERE PlanID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, sp.SupplierID, sp.ProductTypeID, sp.InspectionLevel, sp.AQL,
+		sp.Active, sp.Notes)
+}
+
+func (p *postgres) DeleteSamplingPlan(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM SamplingPlan WHERE PlanID = $1`, id)
+}
+
+// ==================== INSPECTION LOTS ====================
+const inspectionLotColumns = `LotID, POItemID, PlanID, SupplierID, ProductTypeID, LotSize, InspectionLevel, AQL,
+	Severity, CodeLetter, SampleSize, AcceptNumber, RejectNumber, Inspected, Defects, Status, CreatedAt, DecidedAt`
+
+func scanInspectionLot(row interface{ Scan(...interface{}) error }, x *models.InspectionLot) error {
+	return row.Scan(&x.LotID, &x.POItemID, &x.PlanID, &x.SupplierID, &x.ProductTypeID, &x.LotSize,
+		&x.InspectionLevel, &x.AQL, &x.Severity, &x.CodeLetter, &x.SampleSize, &x.AcceptNumber, &x.RejectNumber,
+		&x.Inspected, &x.Defects, &x.Status, &x.CreatedAt, &x.DecidedAt)
+}
+
+func (p *postgres) ListInspectionLots(ctx context.Context, spec query.Spec) (query.Page[models.InspectionLot], error) {
+	return listPage(ctx, p.conn(ctx), InspectionLotResource, spec, inspectionLotColumns, `InspectionLot`,
+		func(rows *sql.Rows, x *models.InspectionLot) error { return scanInspectionLot(rows, x) })
+}
+
+func (p *postgres) GetInspectionLot(ctx context.Context, id int) (models.InspectionLot, error) {
+	var il models.InspectionLot
+	stmt := `SELECT ` + inspectionLotColumns + ` FROM InspectionLot WHERE LotID = $1`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	err := scanInspectionLot(p.conn(ctx).QueryRowContext(ctx, stmt, id), &il)
+	if errors.Is(err, sql.ErrNoRows) {
+		return il, ErrNotFound
+	}
+	return il, err
+}
+
+func (p *postgres) CreateInspectionLot(ctx context.Context, il *models.InspectionLot) error {
+	query := `INSERT INTO InspectionLot (POItemID, PlanID, SupplierID, ProductTypeID, LotSize, InspectionLevel, AQL,
+              Severity, CodeLetter, SampleSize, AcceptNumber, RejectNumber, Inspected, Defects, Status, DecidedAt)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+              RETURNING LotID, CreatedAt`
+	return p.conn(ctx).QueryRowContext(ctx, query, il.POItemID, il.PlanID, il.SupplierID, il.ProductTypeID,
+		il.LotSize, il.InspectionLevel, il.AQL, il.Severity, il.CodeLetter, il.SampleSize, il.AcceptNumber,
+		il.RejectNumber, il.Inspected, il.Defects, il.Status, il.DecidedAt).Scan(&il.LotID, &il.CreatedAt)
+}
+
+func (p *postgres) UpdateInspectionLot(ctx context.Context, id int, il *models.InspectionLot) error {
+	query := `UPDATE InspectionLot SET PlanID = $2, SupplierID = $3, ProductTypeID = $4, LotSize = $5,
+              InspectionLevel = $6, AQL = $7, Severity = $8, CodeLetter = $9, SampleSize = $10, AcceptNumber = $11,
+              RejectNumber = $12, Inspected = $13, Defects = $14, Status = $15, DecidedAt = $16 WHERE LotID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, il.PlanID, il.SupplierID, il.ProductTypeID, il.LotSize,
+		il.InspectionLevel, il.AQL, il.Severity, il.CodeLetter, il.SampleSize, il.AcceptNumber, il.RejectNumber,
+		il.Inspected, il.Defects, il.Status, il.DecidedAt)
+}
+
+func (p *postgres) DeleteInspectionLot(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM InspectionLot WHERE LotID = $1`, id)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== CUSTOMERS ====================
+func (p *postgres) ListCustomers(ctx context.Context, spec query.Spec) (query.Page[models.Customer], error) {
+	return listPage(ctx, p.conn(ctx), CustomerResource, spec,
+		`CustomerID, Name, Retailer, EndUser, ContactInfo, Address, TaxNumber`,
+		`Customer`,
+		func(rows *sql.Rows, x *models.Customer) error {
+			return rows.Scan(&x.CustomerID, &x.Name, &x.Retailer, &x.EndUser, &x.ContactInfo, &x.Address, &x.TaxNumber)
+		})
+}
+
+func (p *postgres) CreateCustomer(ctx context.Context, cust *models.Customer) error {
+	query := `INSERT INTO Customer (Name, Retailer, EndUser, ContactInfo, Address, TaxNumber)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING CustomerID`
+	return p.conn(ctx).QueryRowContext(ctx, query, cust.Name, cust.Retailer, cust.EndUser, cust.ContactInfo,
+		cust.Address, cust.TaxNumber).Scan(&cust.CustomerID)
+}
+
+func (p *postgres) UpdateCustomer(ctx context.Context, id int, cust *models.Customer) error {
+	query := `UPDATE Customer SET Name = $2, Retailer = $3, EndUser = $4,
+              ContactInfo = $5, Address = $6, TaxNumber = $7 WHERE CustomerID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, cust.Name, cust.Retailer, cust.EndUser, cust.ContactInfo,
+		cust.Address, cust.TaxNumber)
+}
+
+func (p *postgres) DeleteCustomer(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Customer WHERE CustomerID = $1`, id)
+}
+
+// ==================== SALES ORDERS ====================
+func (p *postgres) ListSalesOrders(ctx context.Context, spec query.Spec) (query.Page[models.SalesOrder], error) {
+	return listPage(ctx, p.conn(ctx), SalesOrderResource, spec,
+		`SOID, EmployeeID, CustomerID, OrderDate, DeliveryDate, Status, TotalAmount`,
+		`SalesOrder`,
+		func(rows *sql.Rows, x *models.SalesOrder) error {
+			return rows.Scan(&x.SOID, &x.EmployeeID, &x.CustomerID, &x.OrderDate, &x.DeliveryDate, &x.Status, &x.TotalAmount)
+		})
+}
+
+func (p *postgres) CreateSalesOrder(ctx context.Context, so *models.SalesOrder) error {
+	query := `INSERT INTO SalesOrder (EmployeeID, CustomerID, OrderDate, DeliveryDate, Status, TotalAmount)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING SOID`
+	return p.conn(ctx).QueryRowContext(ctx, query, so.EmployeeID, so.CustomerID, so.OrderDate, so.DeliveryDate,
+		so.Status, so.TotalAmount).Scan(&so.SOID)
+}
+
+func (p *postgres) UpdateSalesOrder(ctx context.Context, id int, so *models.SalesOrder) error {
+	query := `UPDATE SalesOrder SET EmployeeID = $2, CustomerID = $3, OrderDate = $4,
+              DeliveryDate = $5, Status = $6, TotalAmount = $7 WHERE SOID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, so.EmployeeID, so.CustomerID, so.OrderDate, so.DeliveryDate,
+		so.Status, so.TotalAmount)
+}
+
+func (p *postgres) DeleteSalesOrder(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM SalesOrder WHERE SOID = $1`, id)
+}
+
+// ==================== SALES ORDER ITEMS ====================
+func (p *postgres) ListSalesOrderItems(ctx context.Context, spec query.Spec) (query.Page[models.SalesOrderItem], error) {
+	return listPage(ctx, p.conn(ctx), SalesOrderItemResource, spec,
+		`SOItemID, SOID, ProductTypeID, StockID, Quantity, UnitPrice, Discount, Subtotal`,
+		`SalesOrderItem`,
+		func(rows *sql.Rows, x *models.SalesOrderItem) error {
+			return rows.Scan(&x.SOItemID, &x.SOID, &x.ProductTypeID, &x.StockID, &x.Quantity, &x.UnitPrice, &x.Discount, &x.Subtotal)
+		})
+}
+
+func (p *postgres) CreateSalesOrderItem(ctx context.Context, soi *models.SalesOrderItem) error {
+	query := `INSERT INTO SalesOrderItem (SOID, ProductTypeID, StockID, Quantity, UnitPrice, Discount, Subtotal)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING SOItemID`
+	return p.conn(ctx).QueryRowContext(ctx, query, soi.SOID, soi.ProductTypeID, soi.StockID, soi.Quantity, soi.UnitPrice,
+		soi.Discount, soi.Subtotal).Scan(&soi.SOItemID)
+}
+
+func (p *postgres) UpdateSalesOrderItem(ctx context.Context, id int, soi *models.SalesOrderItem) error {
+	query := `UPDATE SalesOrderItem SET SOID = $2, ProductTypeID = $3, StockID = $4, Quantity = $5,
+              UnitPrice = $6, Discount = $7, Subtotal = $8 WHERE SOItemID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, soi.SOID, soi.ProductTypeID, soi.StockID, soi.Quantity, soi.UnitPrice,
+		soi.Discount, soi.Subtotal)
+}
+
+func (p *postgres) DeleteSalesOrderItem(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM SalesOrderItem WHERE SOItemID = $1`, id)
+}
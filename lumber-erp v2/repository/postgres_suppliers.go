@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== SUPPLIERS ====================
+func (p *postgres) ListSuppliers(ctx context.Context, spec query.Spec) (query.Page[models.Supplier], error) {
+	return listPage(ctx, p.conn(ctx), SupplierResource, spec,
+		`SupplierID, CompanyName, ContactPerson, Email, Phone, ComplianceStatus, Raw, Semi_Processed`,
+		`Supplier`,
+		func(rows *sql.Rows, x *models.Supplier) error {
+			return rows.Scan(&x.SupplierID, &x.CompanyName, &x.ContactPerson, &x.Email, &x.Phone, &x.ComplianceStatus, &x.Raw, &x.SemiProcessed)
+		})
+}
+
+func (p *postgres) CreateSupplier(ctx context.Context, sup *models.Supplier) error {
+	query := `INSERT INTO Supplier (CompanyName, ContactPerson, Email, Phone, ComplianceStatus, Raw, Semi_Processed)
+              VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING SupplierID`
+	return p.conn(ctx).QueryRowContext(ctx, query, sup.CompanyName, sup.ContactPerson, sup.Email, sup.Phone,
+		sup.ComplianceStatus, sup.Raw, sup.SemiProcessed).Scan(&sup.SupplierID)
+}
+
+func (p *postgres) UpdateSupplier(ctx context.Context, id int, sup *models.Supplier) error {
+	query := `UPDATE Supplier SET CompanyName = $2, ContactPerson = $3, Email = $4, Phone = $5,
+              ComplianceStatus = $6, Raw = $7, Semi_Processed = $8 WHERE SupplierID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, sup.CompanyName, sup.ContactPerson, sup.Email, sup.Phone,
+		sup.ComplianceStatus, sup.Raw, sup.SemiProcessed)
+}
+
+func (p *postgres) DeleteSupplier(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Supplier WHERE SupplierID = $1`, id)
+}
+
+// ==================== SUPPLIER PERFORMANCES ====================
+func (p *postgres) ListSupplierPerformances(ctx context.Context, spec query.Spec) (query.Page[models.SupplierPerformance], error) {
+	return listPage(ctx, p.conn(ctx), SupplierPerformanceResource, spec,
+		`PerformanceID, SupplierID, Rating, DeliveryTimeliness, QualityScore, ReviewDate`,
+		`SupplierPerformance`,
+		func(rows *sql.Rows, x *models.SupplierPerformance) error {
+			return rows.Scan(&x.PerformanceID, &x.SupplierID, &x.Rating, &x.DeliveryTimeliness, &x.QualityScore, &x.ReviewDate)
+		})
+}
+
+func (p *postgres) CreateSupplierPerformance(ctx context.Context, sp *models.SupplierPerformance) error {
+	query := `INSERT INTO SupplierPerformance (SupplierID, Rating, DeliveryTimeliness, QualityScore, ReviewDate)
+              VALUES ($1, $2, $3, $4, $5) RETURNING PerformanceID`
+	return p.conn(ctx).QueryRowContext(ctx, query, sp.SupplierID, sp.Rating, sp.DeliveryTimeliness,
+		sp.QualityScore, sp.ReviewDate).Scan(&sp.PerformanceID)
+}
+
+func (p *postgres) UpdateSupplierPerformance(ctx context.Context, id int, sp *models.SupplierPerformance) error {
+	query := `UPDATE SupplierPerformance SET SupplierID = $2, Rating = $3, DeliveryTimeliness = $4,
+              QualityScore = $5, ReviewDate = $6 WHERE PerformanceID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, sp.SupplierID, sp.Rating, sp.DeliveryTimeliness,
+		sp.QualityScore, sp.ReviewDate)
+}
+
+func (p *postgres) AdjustQualityScore(ctx context.Context, id int, delta float64) (float64, error) {
+	query := `WITH old AS (SELECT PerformanceID, QualityScore FROM SupplierPerformance
+                           WHERE PerformanceID = $1 AND QualityScore IS NOT NULL FOR UPDATE)
+              UPDATE SupplierPerformance sp SET QualityScore = LEAST(GREATEST(old.QualityScore + $2, 0), 100)
+              FROM old WHERE sp.PerformanceID = old.PerformanceID
+              RETURNING sp.QualityScore - old.QualityScore`
+	var applied float64
+	err := p.conn(ctx).QueryRowContext(ctx, query, id, delta).Scan(&applied)
+	if err == sql.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	return applied, err
+}
+
+func (p *postgres) DeleteSupplierPerformance(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM SupplierPerformance WHERE PerformanceID = $1`, id)
+}
+
+// ==================== SUPPLIER CONTRACTS ====================
+func (p *postgres) ListSupplierContracts(ctx context.Context, spec query.Spec) (query.Page[models.SupplierContract], error) {
+	return listPage(ctx, p.conn(ctx), SupplierContractResource, spec,
+		`ContractID, SupplierID, StartDate, EndDate, Terms, ContractValue, Status`,
+		`SupplierContract`,
+		func(rows *sql.Rows, x *models.SupplierContract) error {
+			return rows.Scan(&x.ContractID, &x.SupplierID, &x.StartDate, &x.EndDate, &x.Terms, &x.ContractValue, &x.Status)
+		})
+}
+
+func (p *postgres) CreateSupplierContract(ctx context.Context, sc *models.SupplierContract) error {
+	query := `INSERT INTO SupplierContract (SupplierID, StartDate, EndDate, Terms, ContractValue, Status)
+              VALUES ($1, $2, $3, $4, $5, $6) RETURNING ContractID`
+	return p.conn(ctx).QueryRowContext(ctx, query, sc.SupplierID, sc.StartDate, sc.EndDate, sc.Terms,
+		sc.ContractValue, sc.Status).Scan(&sc.ContractID)
+}
+
+func (p *postgres) UpdateSupplierContract(ctx context.Context, id int, sc *models.SupplierContract) error {
+	query := `UPDATE SupplierContract SET SupplierID = $2, StartDate = $3, EndDate = $4,
+              Terms = $5, ContractValue = $6, Status = $7 WHERE ContractID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, sc.SupplierID, sc.StartDate, sc.EndDate, sc.Terms,
+		sc.ContractValue, sc.Status)
+}
+
+func (p *postgres) DeleteSupplierContract(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM SupplierContract WHERE ContractID = $1`, id)
+}
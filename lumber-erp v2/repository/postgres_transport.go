@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== TRANSPORT COMPANIES ====================
+func (p *postgres) ListTransportCompanies(ctx context.Context, spec query.Spec) (query.Page[models.TransportCompany], error) {
+	return listPage(ctx, p.conn(ctx), TransportCompanyResource, spec,
+		`CompanyID, CompanyName, ContactInfo, LicenseNumber, Rating`,
+		`TransportCompany`,
+		func(rows *sql.Rows, x *models.TransportCompany) error {
+			return rows.Scan(&x.CompanyID, &x.CompanyName, &x.ContactInfo, &x.LicenseNumber, &x.Rating)
+		})
+}
+
+func (p *postgres) CreateTransportCompany(ctx context.Context, tc *models.TransportCompany) error {
+	query := `INSERT INTO TransportCompany (CompanyName, ContactInfo, LicenseNumber, Rating)
+              VALUES ($1, $2, $3, $4) RETURNING CompanyID`
+	return p.conn(ctx).QueryRowContext(ctx, query, tc.CompanyName, tc.ContactInfo, tc.LicenseNumber, tc.Rating).Scan(&tc.CompanyID)
+}
+
+func (p *postgres) UpdateTransportCompany(ctx context.Context, id int, tc *models.TransportCompany) error {
+	query := `UPDATE TransportCompany SET CompanyName = $2, ContactInfo = $3,
+              LicenseNumber = $4, Rating = $5 WHERE CompanyID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, tc.CompanyName, tc.ContactInfo, tc.LicenseNumber, tc.Rating)
+}
+
+func (p *postgres) DeleteTransportCompany(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM TransportCompany WHERE CompanyID = $1`, id)
+}
+
+// ==================== TRUCKS ====================
+func (p *postgres) ListTrucks(ctx context.Context, spec query.Spec) (query.Page[models.Truck], error) {
+	return listPage(ctx, p.conn(ctx), TruckResource, spec,
+		`TruckID, CompanyID, PlateNumber, Capacity, FuelType, Status`,
+		`Truck`,
+		func(rows *sql.Rows, x *models.Truck) error {
+			return rows.Scan(&x.TruckID, &x.CompanyID, &x.PlateNumber, &x.Capacity, &x.FuelType, &x.Status)
+		})
+}
+
+func (p *postgres) CreateTruck(ctx context.Context, truck *models.Truck) error {
+	query := `INSERT INTO Truck (CompanyID, PlateNumber, Capacity, FuelType, Status)
+              VALUES ($1, $2, $3, $4, $5) RETURNING TruckID`
+	return p.conn(ctx).QueryRowContext(ctx, query, truck.CompanyID, truck.PlateNumber, truck.Capacity,
+		truck.FuelType, truck.Status).Scan(&truck.TruckID)
+}
+
+func (p *postgres) UpdateTruck(ctx context.Context, id int, truck *models.Truck) error {
+	query := `UPDATE Truck SET CompanyID = $2, PlateNumber = $3, Capacity = $4,
+              FuelType = $5, Status = $6 WHERE TruckID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, truck.CompanyID, truck.PlateNumber, truck.Capacity,
+		truck.FuelType, truck.Status)
+}
+
+func (p *postgres) DeleteTruck(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Truck WHERE TruckID = $1`, id)
+}
+
+// ==================== DRIVERS ====================
+func (p *postgres) ListDrivers(ctx context.Context, spec query.Spec) (query.Page[models.Driver], error) {
+	return listPage(ctx, p.conn(ctx), DriverResource, spec,
+		`DriverID, EmployeeID, LicenseNumber, ExperienceYears, Status`,
+		`Driver`,
+		func(rows *sql.Rows, x *models.Driver) error {
+			return rows.Scan(&x.DriverID, &x.EmployeeID, &x.LicenseNumber, &x.ExperienceYears, &x.Status)
+		})
+}
+
+func (p *postgres) CreateDriver(ctx context.Context, driver *models.Driver) error {
+	query := `INSERT INTO Driver (EmployeeID, LicenseNumber, ExperienceYears, Status)
+              VALUES ($1, $2, $3, $4) RETURNING DriverID`
+	return p.conn(ctx).QueryRowContext(ctx, query, driver.EmployeeID, driver.LicenseNumber,
+		driver.ExperienceYears, driver.Status).Scan(&driver.DriverID)
+}
+
+func (p *postgres) UpdateDriver(ctx context.Context, id int, driver *models.Driver) error {
+	query := `UPDATE Driver SET EmployeeID = $2, LicenseNumber = $3,
+              ExperienceYears = $4, Status = $5 WHERE DriverID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, driver.EmployeeID, driver.LicenseNumber,
+		driver.ExperienceYears, driver.Status)
+}
+
+func (p *postgres) DeleteDriver(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Driver WHERE DriverID = $1`, id)
+}
+
+// ==================== ROUTES ====================
+func (p *postgres) ListRoutes(ctx context.Context, spec query.Spec) (query.Page[models.Route], error) {
+	return listPage(ctx, p.conn(ctx), RouteResource, spec,
+		`RouteID, StartLocation, EndLocation, DistanceKM, EstimatedTime`,
+		`Route`,
+		func(rows *sql.Rows, x *models.Route) error {
+			return rows.Scan(&x.RouteID, &x.StartLocation, &x.EndLocation, &x.DistanceKM, &x.EstimatedTime)
+		})
+}
+
+func (p *postgres) CreateRoute(ctx context.Context, route *models.Route) error {
+	query := `INSERT INTO Route (StartLocation, EndLocation, DistanceKM, EstimatedTime)
+              VALUES ($1, $2, $3, $4) RETURNING RouteID`
+	return p.conn(ctx).QueryRowContext(ctx, query, route.StartLocation, route.EndLocation, route.DistanceKM,
+		route.EstimatedTime).Scan(&route.RouteID)
+}
+
+func (p *postgres) UpdateRoute(ctx context.Context, id int, route *models.Route) error {
+	query := `UPDATE Route SET StartLocation = $2, EndLocation = $3,
+              DistanceKM = $4, EstimatedTime = $5 WHERE RouteID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, route.StartLocation, route.EndLocation, route.DistanceKM,
+		route.EstimatedTime)
+}
+
+func (p *postgres) DeleteRoute(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Route WHERE RouteID = $1`, id)
+}
+
+// ==================== SHIPMENTS ====================
+func (p *postgres) ListShipments(ctx context.Context, spec query.Spec) (query.Page[models.Shipment], error) {
+	return listPage(ctx, p.conn(ctx), ShipmentResource, spec,
+		`ShipmentID, SOID, TruckID, DriverID, CompanyID, RouteID, ShipmentDate, Status, ProofOfDelivery`,
+		`Shipment`,
+		func(rows *sql.Rows, x *models.Shipment) error {
+			return rows.Scan(&x.ShipmentID, &x.SOID, &x.TruckID, &x.DriverID, &x.CompanyID, &x.RouteID, &x.ShipmentDate, &x.Status, &x.ProofOfDelivery)
+		})
+}
+
+func (p *postgres) CreateShipment(ctx context.Context, ship *models.Shipment) error {
+	query := `INSERT INTO Shipment (SOID, TruckID, DriverID, CompanyID, RouteID, ShipmentDate, Status, ProofOfDelivery)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING ShipmentID`
+	return p.conn(ctx).QueryRowContext(ctx, query, ship.SOID, ship.TruckID, ship.DriverID, ship.CompanyID,
+		ship.RouteID, ship.ShipmentDate, ship.Status, ship.ProofOfDelivery).Scan(&ship.ShipmentID)
+}
+
+func (p *postgres) UpdateShipment(ctx context.Context, id int, ship *models.Shipment) error {
+	query := `UPDATE Shipment SET SOID = $2, TruckID = $3, DriverID = $4, CompanyID = $5,
+              RouteID = $6, ShipmentDate = $7, Status = $8, ProofOfDelivery = $9 WHERE ShipmentID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, ship.SOID, ship.TruckID, ship.DriverID, ship.CompanyID,
+		ship.RouteID, ship.ShipmentDate, ship.Status, ship.ProofOfDelivery)
+}
+
+func (p *postgres) DeleteShipment(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Shipment WHERE ShipmentID = $1`, id)
+}
+
+// ==================== FUEL LOGS ====================
+func (p *postgres) ListFuelLogs(ctx context.Context, spec query.Spec) (query.Page[models.FuelLog], error) {
+	return listPage(ctx, p.conn(ctx), FuelLogResource, spec,
+		`FuelLogID, DriverID, TruckID, TripDate, DistanceTraveled`,
+		`FuelLog`,
+		func(rows *sql.Rows, x *models.FuelLog) error {
+			return rows.Scan(&x.FuelLogID, &x.DriverID, &x.TruckID, &x.TripDate, &x.DistanceTraveled)
+		})
+}
+
+func (p *postgres) CreateFuelLog(ctx context.Context, fl *models.FuelLog) error {
+	query := `INSERT INTO FuelLog (DriverID, TruckID, TripDate, DistanceTraveled)
+              VALUES ($1, $2, $3, $4) RETURNING FuelLogID`
+	return p.conn(ctx).QueryRowContext(ctx, query, fl.DriverID, fl.TruckID, fl.TripDate, fl.DistanceTraveled).Scan(&fl.FuelLogID)
+}
+
+func (p *postgres) UpdateFuelLog(ctx context.Context, id int, fl *models.FuelLog) error {
+	query := `UPDATE FuelLog SET DriverID = $2, TruckID = $3,
+              TripDate = $4, DistanceTraveled = $5 WHERE FuelLogID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, fl.DriverID, fl.TruckID, fl.TripDate, fl.DistanceTraveled)
+}
+
+func (p *postgres) DeleteFuelLog(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM FuelLog WHERE FuelLogID = $1`, id)
+}
@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ==================== WAREHOUSES ====================
+func (p *postgres) ListWarehouses(ctx context.Context, spec query.Spec) (query.Page[models.Warehouse], error) {
+	return listPage(ctx, p.conn(ctx), WarehouseResource, spec,
+		`WarehouseID, Name, Location, Capacity, Contact, AllowNegativeStock`,
+		`Warehouse`,
+		func(rows *sql.Rows, x *models.Warehouse) error {
+			return rows.Scan(&x.WarehouseID, &x.Name, &x.Location, &x.Capacity, &x.Contact, &x.AllowNegativeStock)
+		})
+}
+
+func (p *postgres) CreateWarehouse(ctx context.Context, wh *models.Warehouse) error {
+	query := `INSERT INTO Warehouse (Name, Location, Capacity, Contact, AllowNegativeStock)
+              VALUES ($1, $2, $3, $4, $5) RETURNING WarehouseID`
+	return p.conn(ctx).QueryRowContext(ctx, query, wh.Name, wh.Location, wh.Capacity, wh.Contact,
+		wh.AllowNegativeStock).Scan(&wh.WarehouseID)
+}
+
+func (p *postgres) UpdateWarehouse(ctx context.Context, id int, wh *models.Warehouse) error {
+	query := `UPDATE Warehouse SET Name = $2, Location = $3,
+              Capacity = $4, Contact = $5, AllowNegativeStock = $6 WHERE WarehouseID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, wh.Name, wh.Location, wh.Capacity, wh.Contact, wh.AllowNegativeStock)
+}
+
+func (p *postgres) DeleteWarehouse(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM Warehouse WHERE WarehouseID = $1`, id)
+}
+
+// ==================== PRODUCT TYPES ====================
+func (p *postgres) ListProductTypes(ctx context.Context, spec query.Spec) (query.Page[models.ProductType], error) {
+	return listPage(ctx, p.conn(ctx), ProductTypeResource, spec,
+		`ProductTypeID, Name, COALESCE(Description, ''), Price, COALESCE(Grade, ''), COALESCE(UnitOfMeasure, '')`,
+		`ProductType`,
+		func(rows *sql.Rows, x *models.ProductType) error {
+			return rows.Scan(&x.ProductTypeID, &x.Name, &x.Description, &x.Price, &x.Grade, &x.UnitOfMeasure)
+		})
+}
+
+func (p *postgres) CreateProductType(ctx context.Context, pt *models.ProductType) error {
+	query := `INSERT INTO ProductType (Name, Description, Price, Grade, UnitOfMeasure)
+              VALUES ($1, $2, $3, $4, $5) RETURNING ProductTypeID`
+	return p.conn(ctx).QueryRowContext(ctx, query, pt.Name, pt.Description, pt.Price, pt.Grade, pt.UnitOfMeasure).Scan(&pt.ProductTypeID)
+}
+
+func (p *postgres) UpdateProductType(ctx context.Context, id int, pt *models.ProductType) error {
+	query := `UPDATE ProductType SET Name = $2, Description = $3, Price = $4,
+              Grade = $5, UnitOfMeasure = $6 WHERE ProductTypeID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, pt.Name, pt.Description, pt.Price, pt.Grade, pt.UnitOfMeasure)
+}
+
+func (p *postgres) DeleteProductType(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM ProductType WHERE ProductTypeID = $1`, id)
+}
+
+// ==================== STOCK ITEMS ====================
+const stockItemColumns = `StockID, ProductTypeID, WarehouseID, BatchID, ProcessingID, Quantity, ShelfLocation,
+	Grade, GradedBy, Status, POItemID`
+
+func scanStockItem(row interface{ Scan(...interface{}) error }, x *models.StockItem) error {
+	return row.Scan(&x.StockID, &x.ProductTypeID, &x.WarehouseID, &x.BatchID, &x.ProcessingID, &x.Quantity,
+		&x.ShelfLocation, &x.Grade, &x.GradedBy, &x.Status, &x.POItemID)
+}
+
+func (p *postgres) ListStockItems(ctx context.Context, spec query.Spec) (query.Page[models.StockItem], error) {
+	return listPage(ctx, p.conn(ctx), StockItemResource, spec, stockItemColumns, `StockItem`,
+		func(rows *sql.Rows, x *models.StockItem) error { return scanStockItem(rows, x) })
+}
+
+func (p *postgres) LockStockItems(ctx context.Context, spec query.Spec) ([]models.StockItem, error) {
+	spec.Limit = 0
+	stmt, args := StockItemResource.Select(stockItemColumns, `StockItem`, spec)
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		stmt += ` FOR UPDATE`
+	}
+	rows, err := p.conn(ctx).QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var lots []models.StockItem
+	for rows.Next() {
+		var lot models.StockItem
+		if err := scanStockItem(rows, &lot); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+func (p *postgres) CreateStockItem(ctx context.Context, si *models.StockItem) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO StockItem (ProductTypeID, WarehouseID, BatchID, ProcessingID, Quantity, ShelfLocation,
+                  POItemID)
+                  VALUES ($1, $2, $3, $4, 0, $5, $6) RETURNING StockID, Status`
+		err := p.conn(ctx).QueryRowContext(ctx, query, si.ProductTypeID, si.WarehouseID, si.BatchID, si.ProcessingID,
+			si.ShelfLocation, si.POItemID).Scan(&si.StockID, &si.Status)
+		if err != nil || si.Quantity == 0 {
+			return err
+		}
+		entry := openingBalance(*si)
+		return p.postInventoryTransaction(ctx, &entry)
+	})
+}
+
+func (p *postgres) UpdateStockItem(ctx context.Context, id int, si *models.StockItem) error {
+	query := `UPDATE StockItem SET ProductTypeID = $2, BatchID = $3, ProcessingID = $4, ShelfLocation = $5,
+              POItemID = $6 WHERE StockID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, si.ProductTypeID, si.BatchID, si.ProcessingID, si.ShelfLocation,
+		si.POItemID)
+}
+
+func (p *postgres) GradeStockItem(ctx context.Context, id int, grade string, inspectionID int) error {
+	return execOne(ctx, p.conn(ctx), `UPDATE StockItem SET Grade = $2, GradedBy = $3 WHERE StockID = $1`,
+		id, grade, inspectionID)
+}
+
+func (p *postgres) DeleteStockItem(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM StockItem WHERE StockID = $1`, id)
+}
+
+// ==================== STOCK DISPOSITIONS ====================
+func (p *postgres) ListStockDispositions(ctx context.Context, spec query.Spec) (query.Page[models.StockDisposition], error) {
+	return listPage(ctx, p.conn(ctx), StockDispositionResource, spec,
+		`DispositionID, StockID, Action, FromStatus, ToStatus, Quantity, InspectionID, ProcessingID, WasteID,
+		TransactionID, EmployeeID, Reason, CreatedAt`,
+		`StockDisposition`,
+		func(rows *sql.Rows, x *models.StockDisposition) error {
+			return rows.Scan(&x.DispositionID, &x.StockID, &x.Action, &x.FromStatus, &x.ToStatus, &x.Quantity,
+				&x.InspectionID, &x.ProcessingID, &x.WasteID, &x.TransactionID, &x.EmployeeID, &x.Reason, &x.CreatedAt)
+		})
+}
+
+func (p *postgres) CreateStockDisposition(ctx context.Context, sd *models.StockDisposition) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		db := p.conn(ctx)
+		query := `INSERT INTO StockDisposition (StockID, Action, FromStatus, ToStatus, Quantity, InspectionID,
+                  ProcessingID, WasteID, TransactionID, EmployeeID, Reason)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING DispositionID, CreatedAt`
+		err := db.QueryRowContext(ctx, query, sd.StockID, sd.Action, sd.FromStatus, sd.ToStatus, sd.Quantity,
+			sd.InspectionID, sd.ProcessingID, sd.WasteID, sd.TransactionID, sd.EmployeeID,
+			sd.Reason).Scan(&sd.DispositionID, &sd.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return execOne(ctx, db, `UPDATE StockItem SET Status = $2 WHERE StockID = $1`, sd.StockID, sd.ToStatus)
+	})
+}
+
+// ==================== STOCK ALERTS ====================
+func (p *postgres) ListStockAlerts(ctx context.Context, spec query.Spec) (query.Page[models.StockAlert], error) {
+	return listPage(ctx, p.conn(ctx), StockAlertResource, spec,
+		`AlertID, StockID, WarehouseID, AlertType, CreatedAt, Status`,
+		`StockAlert`,
+		func(rows *sql.Rows, x *models.StockAlert) error {
+			return rows.Scan(&x.AlertID, &x.StockID, &x.WarehouseID, &x.AlertType, &x.CreatedAt, &x.Status)
+		})
+}
+
+func (p *postgres) CreateStockAlert(ctx context.Context, sa *models.StockAlert) error {
+	query := `INSERT INTO StockAlert (StockID, WarehouseID, AlertType, Status)
+              VALUES ($1, $2, $3, $4) RETURNING AlertID`
+	return p.conn(ctx).QueryRowContext(ctx, query, sa.StockID, sa.WarehouseID, sa.AlertType, sa.Status).Scan(&sa.AlertID)
+}
+
+func (p *postgres) UpdateStockAlert(ctx context.Context, id int, sa *models.StockAlert) error {
+	query := `UPDATE StockAlert SET StockID = $2, WarehouseID = $3,
+              AlertType = $4, Status = $5 WHERE AlertID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, sa.StockID, sa.WarehouseID, sa.AlertType, sa.Status)
+}
+
+func (p *postgres) DeleteStockAlert(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM StockAlert WHERE AlertID = $1`, id)
+}
+
+// ==================== INVENTORY TRANSACTIONS ====================
+func (p *postgres) ListInventoryTransactions(ctx context.Context, spec query.Spec) (query.Page[models.InventoryTransaction], error) {
+	return listPage(ctx, p.conn(ctx), InventoryTransactionResource, spec,
+		`TransactionID, EmployeeID, COALESCE(StockID, 0), WarehouseID, TransactionType, Quantity, BalanceAfter, ReversesID,
+		 TransactionDate, COALESCE(Remarks, '')`,
+		`InventoryTransaction`,
+		func(rows *sql.Rows, x *models.InventoryTransaction) error {
+			return rows.Scan(&x.TransactionID, &x.EmployeeID, &x.StockID, &x.WarehouseID, &x.TransactionType, &x.Quantity,
+				&x.BalanceAfter, &x.ReversesID, &x.TransactionDate, &x.Remarks)
+		})
+}
+
+func (p *postgres) CreateInventoryTransaction(ctx context.Context, it *models.InventoryTransaction) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		return p.postInventoryTransaction(ctx, it)
+	})
+}
+
+func (p *postgres) ReverseInventoryTransaction(ctx context.Context, id int, it *models.InventoryTransaction) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		// Locking the original serialises concurrent reversals of it
+		var orig models.InventoryTransaction
+		var reversed bool
+		err := p.conn(ctx).QueryRowContext(ctx, `
+			SELECT TransactionID, COALESCE(StockID, 0), TransactionType, Quantity, ReversesID,
+			       EXISTS (SELECT 1 FROM InventoryTransaction r WHERE r.ReversesID = t.TransactionID)
+			FROM InventoryTransaction t WHERE TransactionID = $1 FOR UPDATE`, id).
+			Scan(&orig.TransactionID, &orig.StockID, &orig.TransactionType, &orig.Quantity, &orig.ReversesID, &reversed)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		case err != nil:
+			return err
+		case reversed:
+			return &ConflictError{Field: "reverses_id"}
+		}
+		if err := reversal(orig, it); err != nil {
+			return err
+		}
+		return p.postInventoryTransaction(ctx, it)
+	})
+}
+
+// postInventoryTransaction locks the stock item of it, moves its quantity
+// and inserts it; the caller runs it in a transaction
+func (p *postgres) postInventoryTransaction(ctx context.Context, it *models.InventoryTransaction) error {
+	db := p.conn(ctx)
+	var stock models.StockItem
+	var warehouseID *int
+	var allowNegative bool
+	err := db.QueryRowContext(ctx, `
+		SELECT s.StockID, s.Quantity, s.WarehouseID, COALESCE(w.AllowNegativeStock, FALSE)
+		FROM StockItem s LEFT JOIN Warehouse w ON w.WarehouseID = s.WarehouseID
+		WHERE s.StockID = $1 FOR UPDATE OF s`, it.StockID).
+		Scan(&stock.StockID, &stock.Quantity, &warehouseID, &allowNegative)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ReferenceError{Field: "stock_id", Table: "stockitem"}
+	}
+	if err != nil {
+		return err
+	}
+
+	balance, err := moveStock(stock, it.Quantity, allowNegative)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, `UPDATE StockItem SET Quantity = $2 WHERE StockID = $1`, stock.StockID, balance); err != nil {
+		return err
+	}
+
+	it.WarehouseID = warehouseID
+	it.BalanceAfter = &balance
+	var date interface{}
+	if it.TransactionDate != "" {
+		date = it.TransactionDate
+	}
+	query := `INSERT INTO InventoryTransaction (EmployeeID, StockID, WarehouseID, TransactionType, Quantity,
+              BalanceAfter, ReversesID, Remarks, TransactionDate)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::timestamp, CURRENT_TIMESTAMP))
+              RETURNING TransactionID, TransactionDate`
+	return db.QueryRowContext(ctx, query, it.EmployeeID, it.StockID, it.WarehouseID, it.TransactionType,
+		it.Quantity, it.BalanceAfter, it.ReversesID, it.Remarks, date).Scan(&it.TransactionID, &it.TransactionDate)
+}
+
+// ==================== STOCK TRANSFERS ====================
+const stockTransferColumns = `TransferID, FromWarehouseID, ToWarehouseID, Status, ShipmentID, TruckID,
+	COALESCE(Remarks, ''), CreatedAt, DispatchedAt, InTransitAt, ReceivedAt`
+
+func scanStockTransfer(row interface{ Scan(...interface{}) error }, x *models.StockTransfer) error {
+	return row.Scan(&x.TransferID, &x.FromWarehouseID, &x.ToWarehouseID, &x.Status, &x.ShipmentID, &x.TruckID,
+		&x.Remarks, &x.CreatedAt, &x.DispatchedAt, &x.InTransitAt, &x.ReceivedAt)
+}
+
+func (p *postgres) ListTransfers(ctx context.Context, spec query.Spec) (query.Page[models.StockTransfer], error) {
+	return listPage(ctx, p.conn(ctx), StockTransferResource, spec, stockTransferColumns, `StockTransfer`,
+		func(rows *sql.Rows, x *models.StockTransfer) error { return scanStockTransfer(rows, x) })
+}
+
+func (p *postgres) GetTransfer(ctx context.Context, id int) (models.StockTransfer, error) {
+	var t models.StockTransfer
+	query := `SELECT ` + stockTransferColumns + ` FROM StockTransfer WHERE TransferID = $1`
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		query += ` FOR UPDATE`
+	}
+	db := p.conn(ctx)
+	err := scanStockTransfer(db.QueryRowContext(ctx, query, id), &t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, ErrNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT LineID, TransferID, StockID, Quantity, ReceivedQuantity, DestStockID, OutTransactionID,
+		       InTransactionID, COALESCE(DiscrepancyNote, '')
+		FROM StockTransferLine WHERE TransferID = $1 ORDER BY LineID`, id)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l models.StockTransferLine
+		if err := rows.Scan(&l.LineID, &l.TransferID, &l.StockID, &l.Quantity, &l.ReceivedQuantity, &l.DestStockID,
+			&l.OutTransactionID, &l.InTransactionID, &l.DiscrepancyNote); err != nil {
+			return t, err
+		}
+		t.Lines = append(t.Lines, l)
+	}
+	return t, rows.Err()
+}
+
+func (p *postgres) CreateTransfer(ctx context.Context, t *models.StockTransfer) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO StockTransfer (FromWarehouseID, ToWarehouseID, Status, ShipmentID, TruckID, Remarks)
+                  VALUES ($1, $2, $3, $4, $5, $6) RETURNING TransferID, CreatedAt`
+		err := p.conn(ctx).QueryRowContext(ctx, query, t.FromWarehouseID, t.ToWarehouseID, t.Status, t.ShipmentID,
+			t.TruckID, t.Remarks).Scan(&t.TransferID, &t.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return p.insertTransferLines(ctx, t.TransferID, t.Lines)
+	})
+}
+
+func (p *postgres) UpdateTransfer(ctx context.Context, id int, t *models.StockTransfer) error {
+	query := `UPDATE StockTransfer SET FromWarehouseID = $2, ToWarehouseID = $3, Status = $4, ShipmentID = $5,
+              TruckID = $6, Remarks = $7, DispatchedAt = $8, InTransitAt = $9, ReceivedAt = $10
+              WHERE TransferID = $1`
+	return execOne(ctx, p.conn(ctx), query, id, t.FromWarehouseID, t.ToWarehouseID, t.Status, t.ShipmentID,
+		t.TruckID, t.Remarks, t.DispatchedAt, t.InTransitAt, t.ReceivedAt)
+}
+
+func (p *postgres) ReplaceTransferLines(ctx context.Context, id int, lines []models.StockTransferLine) error {
+	return p.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := p.conn(ctx).ExecContext(ctx, `DELETE FROM StockTransferLine WHERE TransferID = $1`, id); err != nil {
+			return err
+		}
+		return p.insertTransferLines(ctx, id, lines)
+	})
+}
+
+func (p *postgres) UpdateTransferLine(ctx context.Context, line *models.StockTransferLine) error {
+	query := `UPDATE StockTransferLine SET ReceivedQuantity = $2, DestStockID = $3, OutTransactionID = $4,
+              InTransactionID = $5, DiscrepancyNote = $6 WHERE LineID = $1`
+	return execOne(ctx, p.conn(ctx), query, line.LineID, line.ReceivedQuantity, line.DestStockID,
+		line.OutTransactionID, line.InTransactionID, line.DiscrepancyNote)
+}
+
+func (p *postgres) DeleteTransfer(ctx context.Context, id int) error {
+	return execOne(ctx, p.conn(ctx), `DELETE FROM StockTransfer WHERE TransferID = $1`, id)
+}
+
+func (p *postgres) insertTransferLines(ctx context.Context, id int, lines []models.StockTransferLine) error {
+	for i := range lines {
+		lines[i].TransferID = id
+		err := p.conn(ctx).QueryRowContext(ctx, `INSERT INTO StockTransferLine (TransferID, StockID, Quantity)
+              VALUES ($1, $2, $3) RETURNING LineID`, id, lines[i].StockID, lines[i].Quantity).Scan(&lines[i].LineID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
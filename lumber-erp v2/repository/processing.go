@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+)
+
+// consumeBatch refuses to consume requested of a harvest batch whose
+// processing orders already consumed consumed of its quantity
+func consumeBatch(batchID int, quantity, consumed, requested float64) error {
+	// Quantities are DECIMAL(10,2); rounding keeps float sums exact
+	remaining := math.Round((quantity-consumed)*100) / 100
+	if requested > remaining {
+		return &RuleError{
+			Code:    "insufficient_batch_quantity",
+			Message: fmt.Sprintf("Harvest batch %d has %g left, which does not cover %g", batchID, remaining, requested),
+			Details: map[string]interface{}{"batch_id": batchID, "remaining": remaining, "requested": requested},
+		}
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package repository
+
+// Quality inspection results. The inspection package grades inspections
+// against them and sampling, which it imports, tallies them.
+const (
+	InspectionPass = "pass"
+	InspectionFail = "fail"
+)
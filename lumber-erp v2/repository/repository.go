@@ -0,0 +1,601 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+)
+
+// ErrNotFound is returned when a single row lookup, update or delete
+// matches nothing
+var ErrNotFound = errors.New("not found")
+
+// ConflictError reports a write that would duplicate a unique value
+type ConflictError struct {
+	Field string // JSON name of the conflicting field(s)
+}
+
+func (e *ConflictError) Error() string { return e.Field + " already exists" }
+
+// ReferenceError reports a write naming a row that does not exist
+type ReferenceError struct {
+	Field string // JSON name of the referencing field
+	Table string // referenced table
+}
+
+func (e *ReferenceError) Error() string {
+	return e.Field + " refers to a " + e.Table + " that does not exist"
+}
+
+// RuleError reports a write refused by a business rule, such as stock
+// going negative
+type RuleError struct {
+	Code    string // machine-readable reason, e.g. insufficient_stock
+	Message string
+	Details interface{}
+}
+
+func (e *RuleError) Error() string { return e.Message }
+
+// ============================================
+// 👥 USER MANAGEMENT
+// ============================================
+
+type UserRepository interface {
+	ListUsers(ctx context.Context, spec query.Spec) (query.Page[models.User], error)
+	GetUser(ctx context.Context, id int) (models.User, error)
+	// GetUserByEmail matches case-insensitively and includes the stored password
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	// CreateUser stores user.Password as given; callers pass a hash
+	// PasswordHash returns the stored hash of the user's password
+	PasswordHash(ctx context.Context, id int) (string, error)
+	CreateUser(ctx context.Context, user *models.User) error
+	// UpdateUser revokes every session of a user it leaves inactive, so
+	// their tokens stop working with it
+	UpdateUser(ctx context.Context, id int, user *models.User) error
+	DeleteUser(ctx context.Context, id int) error
+	SetPassword(ctx context.Context, id int, hash string) error
+	// ChangePassword sets the hash and revokes every session of the user atomically
+	ChangePassword(ctx context.Context, id int, hash string) error
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.UserSession) error
+	GetSession(ctx context.Context, sessionID, userID int) (models.UserSession, error)
+	// RotateSession replaces the refresh token hash of a live session that
+	// still holds oldHash, answering ErrNotFound when it does not, so only
+	// one of two requests presenting the same refresh token rotates it
+	RotateSession(ctx context.Context, sessionID int, oldHash, newHash string, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, sessionID, userID int) error
+}
+
+// AccessRepository covers permissions, roles and the grants between them
+type AccessRepository interface {
+	ListPermissions(ctx context.Context, spec query.Spec) (query.Page[models.Permission], error)
+	CreatePermission(ctx context.Context, perm *models.Permission) error
+	UpdatePermission(ctx context.Context, id int, perm *models.Permission) error
+	DeletePermission(ctx context.Context, id int) error
+
+	ListRoles(ctx context.Context, spec query.Spec) (query.Page[models.Role], error)
+	ListRolesByUser(ctx context.Context, userID int) ([]models.Role, error)
+	CreateRole(ctx context.Context, role *models.Role) error
+	UpdateRole(ctx context.Context, id int, role *models.Role) error
+	DeleteRole(ctx context.Context, id int) error
+
+	ListRolePermissions(ctx context.Context, spec query.Spec) (query.Page[models.RolePermission], error)
+	ListRolePermissionsByRole(ctx context.Context, roleID int) ([]models.RolePermission, error)
+	ListRolePermissionsByPermission(ctx context.Context, permissionID int) ([]models.RolePermission, error)
+	CreateRolePermission(ctx context.Context, rp *models.RolePermission) error
+	DeleteRolePermission(ctx context.Context, roleID, permissionID int) error
+	// AssignPermissions replaces every grant of the role in one transaction
+	AssignPermissions(ctx context.Context, roleID int, permissionIDs []int) error
+
+	// ListUserPermissions returns the distinct permissions granted to any of the user's roles
+	ListUserPermissions(ctx context.Context, userID int) ([]models.Permission, error)
+	// HasPermission reports whether one of the user's roles grants the action on
+	// the module; a role named "Admin" grants everything
+	HasPermission(ctx context.Context, userID int, module, action string) (bool, error)
+}
+
+// AdminRole is the role name that implicitly holds every permission
+const AdminRole = "Admin"
+
+// ============================================
+// 👷 HR & EMPLOYEES
+// ============================================
+
+type EmployeeRepository interface {
+	ListEmployees(ctx context.Context, spec query.Spec) (query.Page[models.Employee], error)
+	CreateEmployee(ctx context.Context, emp *models.Employee) error
+	UpdateEmployee(ctx context.Context, id int, emp *models.Employee) error
+	DeleteEmployee(ctx context.Context, id int) error
+
+	ListWorkerAssignments(ctx context.Context, spec query.Spec) (query.Page[models.WorkerAssignment], error)
+	CreateWorkerAssignment(ctx context.Context, wa *models.WorkerAssignment) error
+	UpdateWorkerAssignment(ctx context.Context, id int, wa *models.WorkerAssignment) error
+	DeleteWorkerAssignment(ctx context.Context, id int) error
+
+	ListManagementInsights(ctx context.Context, spec query.Spec) (query.Page[models.ManagementInsights], error)
+	CreateManagementInsights(ctx context.Context, mi *models.ManagementInsights) error
+	UpdateManagementInsights(ctx context.Context, id int, mi *models.ManagementInsights) error
+	DeleteManagementInsights(ctx context.Context, id int) error
+}
+
+// ============================================
+// 🏭 SUPPLIERS
+// ============================================
+
+type SupplierRepository interface {
+	ListSuppliers(ctx context.Context, spec query.Spec) (query.Page[models.Supplier], error)
+	CreateSupplier(ctx context.Context, sup *models.Supplier) error
+	UpdateSupplier(ctx context.Context, id int, sup *models.Supplier) error
+	DeleteSupplier(ctx context.Context, id int) error
+
+	ListSupplierPerformances(ctx context.Context, spec query.Spec) (query.Page[models.SupplierPerformance], error)
+	CreateSupplierPerformance(ctx context.Context, sp *models.SupplierPerformance) error
+	UpdateSupplierPerformance(ctx context.Context, id int, sp *models.SupplierPerformance) error
+	DeleteSupplierPerformance(ctx context.Context, id int) error
+	// AdjustQualityScore moves the quality score of a review by delta in one
+	// locked step, keeping it within 0 to 100, and returns the change made
+	AdjustQualityScore(ctx context.Context, id int, delta float64) (float64, error)
+
+	ListSupplierContracts(ctx context.Context, spec query.Spec) (query.Page[models.SupplierContract], error)
+	CreateSupplierContract(ctx context.Context, sc *models.SupplierContract) error
+	UpdateSupplierContract(ctx context.Context, id int, sc *models.SupplierContract) error
+	DeleteSupplierContract(ctx context.Context, id int) error
+}
+
+// ============================================
+// 🌲 FOREST & HARVESTING
+// ============================================
+
+type ForestRepository interface {
+	ListForests(ctx context.Context, spec query.Spec) (query.Page[models.Forest], error)
+	CreateForest(ctx context.Context, forest *models.Forest) error
+	UpdateForest(ctx context.Context, id int, forest *models.Forest) error
+	DeleteForest(ctx context.Context, id int) error
+
+	ListTreeSpecies(ctx context.Context, spec query.Spec) (query.Page[models.TreeSpecies], error)
+	CreateTreeSpecies(ctx context.Context, ts *models.TreeSpecies) error
+	UpdateTreeSpecies(ctx context.Context, id int, ts *models.TreeSpecies) error
+	DeleteTreeSpecies(ctx context.Context, id int) error
+
+	ListHarvestSchedules(ctx context.Context, spec query.Spec) (query.Page[models.HarvestSchedule], error)
+	CreateHarvestSchedule(ctx context.Context, hs *models.HarvestSchedule) error
+	UpdateHarvestSchedule(ctx context.Context, id int, hs *models.HarvestSchedule) error
+	DeleteHarvestSchedule(ctx context.Context, id int) error
+
+	ListHarvestBatches(ctx context.Context, spec query.Spec) (query.Page[models.HarvestBatch], error)
+	CreateHarvestBatch(ctx context.Context, hb *models.HarvestBatch) error
+	UpdateHarvestBatch(ctx context.Context, id int, hb *models.HarvestBatch) error
+	DeleteHarvestBatch(ctx context.Context, id int) error
+}
+
+// ============================================
+// 🏗️ PROCESSING & SAWMILL
+// ============================================
+
+type ProcessingRepository interface {
+	ListSawmills(ctx context.Context, spec query.Spec) (query.Page[models.Sawmill], error)
+	CreateSawmill(ctx context.Context, sm *models.Sawmill) error
+	UpdateSawmill(ctx context.Context, id int, sm *models.Sawmill) error
+	DeleteSawmill(ctx context.Context, id int) error
+
+	ListProcessingUnits(ctx context.Context, spec query.Spec) (query.Page[models.ProcessingUnit], error)
+	CreateProcessingUnit(ctx context.Context, pu *models.ProcessingUnit) error
+	UpdateProcessingUnit(ctx context.Context, id int, pu *models.ProcessingUnit) error
+	DeleteProcessingUnit(ctx context.Context, id int) error
+
+	ListProcessingOrders(ctx context.Context, spec query.Spec) (query.Page[models.ProcessingOrder], error)
+	// GetProcessingOrder includes the harvest batches the order consumed
+	GetProcessingOrder(ctx context.Context, id int) (models.ProcessingOrder, error)
+	CreateProcessingOrder(ctx context.Context, po *models.ProcessingOrder) error
+	UpdateProcessingOrder(ctx context.Context, id int, po *models.ProcessingOrder) error
+	DeleteProcessingOrder(ctx context.Context, id int) error
+
+	// ListHarvestBatchProcessing lists which harvest batches went into which
+	// processing orders
+	ListHarvestBatchProcessing(ctx context.Context, spec query.Spec) (query.Page[models.HarvestBatchProcessing], error)
+	// AttachHarvestBatch records that an order consumed part of a batch,
+	// refusing more than the batch has left after its other orders
+	AttachHarvestBatch(ctx context.Context, link *models.HarvestBatchProcessing) error
+	DetachHarvestBatch(ctx context.Context, processingID, batchID int) error
+
+	ListMaintenanceRecords(ctx context.Context, spec query.Spec) (query.Page[models.MaintenanceRecord], error)
+	CreateMaintenanceRecord(ctx context.Context, mr *models.MaintenanceRecord) error
+	UpdateMaintenanceRecord(ctx context.Context, id int, mr *models.MaintenanceRecord) error
+	DeleteMaintenanceRecord(ctx context.Context, id int) error
+
+	ListWasteRecords(ctx context.Context, spec query.Spec) (query.Page[models.WasteRecord], error)
+	CreateWasteRecord(ctx context.Context, wr *models.WasteRecord) error
+	// UpdateWasteRecord leaves the by-product lot alone
+	UpdateWasteRecord(ctx context.Context, id int, wr *models.WasteRecord) error
+	DeleteWasteRecord(ctx context.Context, id int) error
+	// ValoriseWasteRecord marks waste recycled into the by-product lot
+	// stockID
+	ValoriseWasteRecord(ctx context.Context, id, stockID int) error
+}
+
+// MaintenanceRepository stores preventive maintenance plans and their work
+// orders; the maintenance package decides when a plan falls due
+type MaintenanceRepository interface {
+	ListMaintenancePlans(ctx context.Context, spec query.Spec) (query.Page[models.MaintenancePlan], error)
+	// LockMaintenancePlans returns every plan spec matches, locked until the
+	// transaction ends when called within one, so runs raising their work
+	// orders take turns
+	LockMaintenancePlans(ctx context.Context, spec query.Spec) ([]models.MaintenancePlan, error)
+	CreateMaintenancePlan(ctx context.Context, mp *models.MaintenancePlan) error
+	UpdateMaintenancePlan(ctx context.Context, id int, mp *models.MaintenancePlan) error
+	// DeleteMaintenancePlan keeps the plan's work orders, unlinked from it
+	DeleteMaintenancePlan(ctx context.Context, id int) error
+
+	ListWorkOrders(ctx context.Context, spec query.Spec) (query.Page[models.MaintenanceWorkOrder], error)
+	// GetWorkOrder locks the work order when called within a transaction
+	GetWorkOrder(ctx context.Context, id int) (models.MaintenanceWorkOrder, error)
+	CreateWorkOrder(ctx context.Context, wo *models.MaintenanceWorkOrder) error
+	UpdateWorkOrder(ctx context.Context, id int, wo *models.MaintenanceWorkOrder) error
+}
+
+// KilnRepository stores kiln charges with their lots and the readings
+// logged while they dry; the kiln package evaluates them
+type KilnRepository interface {
+	// ListKilnCharges returns the charges without their lots
+	ListKilnCharges(ctx context.Context, spec query.Spec) (query.Page[models.KilnCharge], error)
+	// GetKilnCharge returns a charge with its lots. Within a transaction the
+	// charge stays locked until it ends.
+	GetKilnCharge(ctx context.Context, id int) (models.KilnCharge, error)
+	// CreateKilnCharge stores kc and its lots
+	CreateKilnCharge(ctx context.Context, kc *models.KilnCharge) error
+	// UpdateKilnCharge stores the header of kc, leaving the lots alone
+	UpdateKilnCharge(ctx context.Context, id int, kc *models.KilnCharge) error
+	DeleteKilnCharge(ctx context.Context, id int) error
+
+	ListKilnReadings(ctx context.Context, spec query.Spec) (query.Page[models.KilnReading], error)
+	// SaveKilnReadings stores readings of a charge, replacing any it already
+	// has at the same RecordedAt, so a log can be sent again safely
+	SaveKilnReadings(ctx context.Context, chargeID int, readings []models.KilnReading) error
+}
+
+// ============================================
+// ✅ QUALITY CONTROL
+// ============================================
+
+type QualityRepository interface {
+	// ListQualityInspections returns the inspections without their
+	// measurements and defects
+	ListQualityInspections(ctx context.Context, spec query.Spec) (query.Page[models.QualityInspection], error)
+	// ListQualityInspectionsWithFindings returns the inspections with their
+	// measurements and defects, read in one query
+	ListQualityInspectionsWithFindings(ctx context.Context, spec query.Spec) (query.Page[models.QualityInspection], error)
+	// GetQualityInspection returns an inspection with its measurements and
+	// defects
+	GetQualityInspection(ctx context.Context, id int) (models.QualityInspection, error)
+	// CreateQualityInspection and UpdateQualityInspection store qi with its
+	// measurements and defects, replacing those it had
+	CreateQualityInspection(ctx context.Context, qi *models.QualityInspection) error
+	UpdateQualityInspection(ctx context.Context, id int, qi *models.QualityInspection) error
+	DeleteQualityInspection(ctx context.Context, id int) error
+
+	ListDefectCodes(ctx context.Context, spec query.Spec) (query.Page[models.DefectCode], error)
+	CreateDefectCode(ctx context.Context, dc *models.DefectCode) error
+	UpdateDefectCode(ctx context.Context, id int, dc *models.DefectCode) error
+	DeleteDefectCode(ctx context.Context, id int) error
+
+	// ListInspectionTemplates returns the templates without their items and
+	// grades
+	ListInspectionTemplates(ctx context.Context, spec query.Spec) (query.Page[models.InspectionTemplate], error)
+	// GetInspectionTemplate returns a template with its items and grades
+	GetInspectionTemplate(ctx context.Context, id int) (models.InspectionTemplate, error)
+	// CreateInspectionTemplate and UpdateInspectionTemplate store t with its
+	// items and grades, replacing those it had
+	CreateInspectionTemplate(ctx context.Context, t *models.InspectionTemplate) error
+	UpdateInspectionTemplate(ctx context.Context, id int, t *models.InspectionTemplate) error
+	DeleteInspectionTemplate(ctx context.Context, id int) error
+
+	// ListNonConformances and GetNonConformance return the reports without
+	// their actions. Within a transaction GetNonConformance keeps the
+	// report locked until it ends.
+	ListNonConformances(ctx context.Context, spec query.Spec) (query.Page[models.NonConformance], error)
+	GetNonConformance(ctx context.Context, id int) (models.NonConformance, error)
+	CreateNonConformance(ctx context.Context, nc *models.NonConformance) error
+	UpdateNonConformance(ctx context.Context, id int, nc *models.NonConformance) error
+	// DeleteNonConformance deletes the report with its actions
+	DeleteNonConformance(ctx context.Context, id int) error
+
+	ListCorrectiveActions(ctx context.Context, spec query.Spec) (query.Page[models.CorrectiveAction], error)
+	CreateCorrectiveAction(ctx context.Context, ca *models.CorrectiveAction) error
+	UpdateCorrectiveAction(ctx context.Context, id int, ca *models.CorrectiveAction) error
+	DeleteCorrectiveAction(ctx context.Context, id int) error
+
+	ListQualityAlerts(ctx context.Context, spec query.Spec) (query.Page[models.QualityAlert], error)
+	// RaiseQualityAlert stores qa unless an alert of the same unit, species,
+	// metric, chart and rule was raised at the same inspection, reporting
+	// whether it did
+	RaiseQualityAlert(ctx context.Context, qa *models.QualityAlert) (bool, error)
+	UpdateQualityAlert(ctx context.Context, id int, qa *models.QualityAlert) error
+
+	ListSamplingPlans(ctx context.Context, spec query.Spec) (query.Page[models.SamplingPlan], error)
+	CreateSamplingPlan(ctx context.Context, sp *models.SamplingPlan) error
+	UpdateSamplingPlan(ctx context.Context, id int, sp *models.SamplingPlan) error
+	DeleteSamplingPlan(ctx context.Context, id int) error
+
+	ListInspectionLots(ctx context.Context, spec query.Spec) (query.Page[models.InspectionLot], error)
+	// GetInspectionLot returns a lot; within a transaction it keeps the row
+	// locked until the transaction ends
+	GetInspectionLot(ctx context.Context, id int) (models.InspectionLot, error)
+	// CreateInspectionLot reports a ConflictError on po_item_id when the
+	// item already has a lot
+	CreateInspectionLot(ctx context.Context, il *models.InspectionLot) error
+	UpdateInspectionLot(ctx context.Context, id int, il *models.InspectionLot) error
+	DeleteInspectionLot(ctx context.Context, id int) error
+}
+
+// ============================================
+// 📦 WAREHOUSE & INVENTORY
+// ============================================
+
+type WarehouseRepository interface {
+	ListWarehouses(ctx context.Context, spec query.Spec) (query.Page[models.Warehouse], error)
+	CreateWarehouse(ctx context.Context, wh *models.Warehouse) error
+	UpdateWarehouse(ctx context.Context, id int, wh *models.Warehouse) error
+	DeleteWarehouse(ctx context.Context, id int) error
+
+	ListProductTypes(ctx context.Context, spec query.Spec) (query.Page[models.ProductType], error)
+	CreateProductType(ctx context.Context, pt *models.ProductType) error
+	UpdateProductType(ctx context.Context, id int, pt *models.ProductType) error
+	DeleteProductType(ctx context.Context, id int) error
+}
+
+type StockRepository interface {
+	ListStockItems(ctx context.Context, spec query.Spec) (query.Page[models.StockItem], error)
+	// LockStockItems returns every lot spec matches, locked until the
+	// transaction ends when called within one, so their status and quantity
+	// cannot change under the caller
+	LockStockItems(ctx context.Context, spec query.Spec) ([]models.StockItem, error)
+	// CreateStockItem records a non-zero starting quantity as an opening
+	// receipt, so the ledger accounts for all of it
+	CreateStockItem(ctx context.Context, si *models.StockItem) error
+	// UpdateStockItem leaves Quantity and WarehouseID alone: stock only
+	// moves with transactions and transfers. The grade and status are left
+	// alone too.
+	UpdateStockItem(ctx context.Context, id int, si *models.StockItem) error
+	DeleteStockItem(ctx context.Context, id int) error
+	// GradeStockItem records the grade inspection inspectionID derived for
+	// a lot
+	GradeStockItem(ctx context.Context, id int, grade string, inspectionID int) error
+
+	// Dispositions are append-only; recording one moves the lot to its
+	// ToStatus
+	ListStockDispositions(ctx context.Context, spec query.Spec) (query.Page[models.StockDisposition], error)
+	CreateStockDisposition(ctx context.Context, sd *models.StockDisposition) error
+
+	ListStockAlerts(ctx context.Context, spec query.Spec) (query.Page[models.StockAlert], error)
+	CreateStockAlert(ctx context.Context, sa *models.StockAlert) error
+	UpdateStockAlert(ctx context.Context, id int, sa *models.StockAlert) error
+	DeleteStockAlert(ctx context.Context, id int) error
+
+	// Transactions are immutable: posting one moves the stock item's
+	// quantity under a row lock, and mistakes are undone by reversing them.
+	ListInventoryTransactions(ctx context.Context, spec query.Spec) (query.Page[models.InventoryTransaction], error)
+	// CreateInventoryTransaction posts it, whose Quantity is the signed
+	// change, filling in the warehouse and the balance it leaves. An empty
+	// TransactionDate is the time it is posted.
+	CreateInventoryTransaction(ctx context.Context, it *models.InventoryTransaction) error
+	// ReverseInventoryTransaction posts it as the entry undoing transaction
+	// id; only its EmployeeID and Remarks are taken from the caller
+	ReverseInventoryTransaction(ctx context.Context, id int, it *models.InventoryTransaction) error
+}
+
+// TransferRepository stores transfer documents; the transfers package runs
+// their workflow
+type TransferRepository interface {
+	// ListTransfers returns the documents without their lines
+	ListTransfers(ctx context.Context, spec query.Spec) (query.Page[models.StockTransfer], error)
+	// GetTransfer returns a document with its lines. Within a transaction the
+	// document stays locked until it ends.
+	GetTransfer(ctx context.Context, id int) (models.StockTransfer, error)
+	// CreateTransfer stores t and its lines
+	CreateTransfer(ctx context.Context, t *models.StockTransfer) error
+	// UpdateTransfer stores the header of t, leaving the lines alone
+	UpdateTransfer(ctx context.Context, id int, t *models.StockTransfer) error
+	// ReplaceTransferLines swaps the lines of a document for lines
+	ReplaceTransferLines(ctx context.Context, id int, lines []models.StockTransferLine) error
+	// UpdateTransferLine stores what was posted and received for a line
+	UpdateTransferLine(ctx context.Context, line *models.StockTransferLine) error
+	DeleteTransfer(ctx context.Context, id int) error
+}
+
+// ============================================
+// 🛒 PROCUREMENT
+// ============================================
+
+type PurchaseOrderRepository interface {
+	ListPurchaseOrders(ctx context.Context, spec query.Spec) (query.Page[models.PurchaseOrder], error)
+	CreatePurchaseOrder(ctx context.Context, po *models.PurchaseOrder) error
+	UpdatePurchaseOrder(ctx context.Context, id int, po *models.PurchaseOrder) error
+	DeletePurchaseOrder(ctx context.Context, id int) error
+
+	ListPurchaseOrderItems(ctx context.Context, spec query.Spec) (query.Page[models.PurchaseOrderItem], error)
+	CreatePurchaseOrderItem(ctx context.Context, poi *models.PurchaseOrderItem) error
+	UpdatePurchaseOrderItem(ctx context.Context, id int, poi *models.PurchaseOrderItem) error
+	DeletePurchaseOrderItem(ctx context.Context, id int) error
+}
+
+// ============================================
+// 🛍️ SALES & CUSTOMERS
+// ============================================
+
+type CustomerRepository interface {
+	ListCustomers(ctx context.Context, spec query.Spec) (query.Page[models.Customer], error)
+	CreateCustomer(ctx context.Context, cust *models.Customer) error
+	UpdateCustomer(ctx context.Context, id int, cust *models.Customer) error
+	DeleteCustomer(ctx context.Context, id int) error
+}
+
+type SalesOrderRepository interface {
+	ListSalesOrders(ctx context.Context, spec query.Spec) (query.Page[models.SalesOrder], error)
+	CreateSalesOrder(ctx context.Context, so *models.SalesOrder) error
+	UpdateSalesOrder(ctx context.Context, id int, so *models.SalesOrder) error
+	DeleteSalesOrder(ctx context.Context, id int) error
+
+	ListSalesOrderItems(ctx context.Context, spec query.Spec) (query.Page[models.SalesOrderItem], error)
+	CreateSalesOrderItem(ctx context.Context, soi *models.SalesOrderItem) error
+	UpdateSalesOrderItem(ctx context.Context, id int, soi *models.SalesOrderItem) error
+	DeleteSalesOrderItem(ctx context.Context, id int) error
+}
+
+// ============================================
+// 💰 INVOICING & PAYMENTS
+// ============================================
+
+type InvoiceRepository interface {
+	ListInvoices(ctx context.Context, spec query.Spec) (query.Page[models.Invoice], error)
+	CreateInvoice(ctx context.Context, inv *models.Invoice) error
+	UpdateInvoice(ctx context.Context, id int, inv *models.Invoice) error
+	DeleteInvoice(ctx context.Context, id int) error
+
+	ListPayments(ctx context.Context, spec query.Spec) (query.Page[models.Payment], error)
+	CreatePayment(ctx context.Context, pay *models.Payment) error
+	UpdatePayment(ctx context.Context, id int, pay *models.Payment) error
+	DeletePayment(ctx context.Context, id int) error
+}
+
+// ============================================
+// 🚚 TRANSPORTATION
+// ============================================
+
+type TransportRepository interface {
+	ListTransportCompanies(ctx context.Context, spec query.Spec) (query.Page[models.TransportCompany], error)
+	CreateTransportCompany(ctx context.Context, tc *models.TransportCompany) error
+	UpdateTransportCompany(ctx context.Context, id int, tc *models.TransportCompany) error
+	DeleteTransportCompany(ctx context.Context, id int) error
+
+	ListTrucks(ctx context.Context, spec query.Spec) (query.Page[models.Truck], error)
+	CreateTruck(ctx context.Context, truck *models.Truck) error
+	UpdateTruck(ctx context.Context, id int, truck *models.Truck) error
+	DeleteTruck(ctx context.Context, id int) error
+
+	ListDrivers(ctx context.Context, spec query.Spec) (query.Page[models.Driver], error)
+	CreateDriver(ctx context.Context, driver *models.Driver) error
+	UpdateDriver(ctx context.Context, id int, driver *models.Driver) error
+	DeleteDriver(ctx context.Context, id int) error
+
+	ListRoutes(ctx context.Context, spec query.Spec) (query.Page[models.Route], error)
+	CreateRoute(ctx context.Context, route *models.Route) error
+	UpdateRoute(ctx context.Context, id int, route *models.Route) error
+	DeleteRoute(ctx context.Context, id int) error
+
+	ListShipments(ctx context.Context, spec query.Spec) (query.Page[models.Shipment], error)
+	CreateShipment(ctx context.Context, ship *models.Shipment) error
+	UpdateShipment(ctx context.Context, id int, ship *models.Shipment) error
+	DeleteShipment(ctx context.Context, id int) error
+
+	ListFuelLogs(ctx context.Context, spec query.Spec) (query.Page[models.FuelLog], error)
+	CreateFuelLog(ctx context.Context, fl *models.FuelLog) error
+	UpdateFuelLog(ctx context.Context, id int, fl *models.FuelLog) error
+	DeleteFuelLog(ctx context.Context, id int) error
+}
+
+// ============================================
+// 📊 AUDIT & LOGS
+// ============================================
+
+// AuditRepository is append-only: entries and checkpoints are never updated
+// or deleted. CreateAuditLog links each entry to the one before it.
+type AuditRepository interface {
+	ListAuditLogs(ctx context.Context, spec query.Spec) (query.Page[models.AuditLog], error)
+	CreateAuditLog(ctx context.Context, al *models.AuditLog) error
+	// WalkAuditLogs calls fn with every entry in LogID order
+	WalkAuditLogs(ctx context.Context, fn func(models.AuditLog) error) error
+	LatestAuditLog(ctx context.Context) (models.AuditLog, error)
+	ListAuditCheckpoints(ctx context.Context) ([]models.AuditCheckpoint, error)
+	CreateAuditCheckpoint(ctx context.Context, cp *models.AuditCheckpoint) error
+}
+
+// Transactor runs a unit of work atomically. Repository calls made with
+// the context passed to fn take part in the transaction.
+//
+// The domain services run each of their operations through WithinTx, so
+// the rows an operation writes and the checks it makes before writing
+// them commit or roll back together. A call made inside a transaction
+// joins it, which lets handlers wrap a service check and their own write
+// in one unit of work.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Repositories bundles every aggregate repository for injection into handlers
+type Repositories struct {
+	Tx             Transactor
+	Users          UserRepository
+	Sessions       SessionRepository
+	Access         AccessRepository
+	Employees      EmployeeRepository
+	Suppliers      SupplierRepository
+	Forests        ForestRepository
+	Processing     ProcessingRepository
+	Maintenance    MaintenanceRepository
+	Kilns          KilnRepository
+	Quality        QualityRepository
+	Warehouses     WarehouseRepository
+	Stock          StockRepository
+	Transfers      TransferRepository
+	PurchaseOrders PurchaseOrderRepository
+	Customers      CustomerRepository
+	SalesOrders    SalesOrderRepository
+	Invoices       InvoiceRepository
+	Transport      TransportRepository
+	Audit          AuditRepository
+}
+
+// store is implemented by each backend and satisfies every repository
+type store interface {
+	Transactor
+	UserRepository
+	SessionRepository
+	AccessRepository
+	EmployeeRepository
+	SupplierRepository
+	ForestRepository
+	ProcessingRepository
+	MaintenanceRepository
+	KilnRepository
+	QualityRepository
+	WarehouseRepository
+	StockRepository
+	TransferRepository
+	PurchaseOrderRepository
+	CustomerRepository
+	SalesOrderRepository
+	InvoiceRepository
+	TransportRepository
+	AuditRepository
+}
+
+func newRepositories(s store) Repositories {
+	return Repositories{
+		Tx:             s,
+		Users:          s,
+		Sessions:       s,
+		Access:         s,
+		Employees:      s,
+		Suppliers:      s,
+		Forests:        s,
+		Processing:     s,
+		Maintenance:    s,
+		Kilns:          s,
+		Quality:        s,
+		Warehouses:     s,
+		Stock:          s,
+		Transfers:      s,
+		PurchaseOrders: s,
+		Customers:      s,
+		SalesOrders:    s,
+		Invoices:       s,
+		Transport:      s,
+		Audit:          s,
+	}
+}
@@ -0,0 +1,821 @@
+package repository
+
+import (
+	"strings"
+
+	"lumber-erp-api/query"
+)
+
+// List resources whitelist the fields each list endpoint filters and sorts
+// on. Columns repeat the SELECT expressions, COALESCE included, so both
+// stores compare the values clients see.
+
+// ==================== USER MANAGEMENT ====================
+var UserResource = query.Resource{
+	Key:  []string{"user_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"user_id":      {Column: "User_ID", Type: query.Int},
+		"email":        {Column: "Email", Type: query.String},
+		"first_name":   {Column: "First_Name", Type: query.String},
+		"last_name":    {Column: "Last_Name", Type: query.String},
+		"phone_number": {Column: "Phone_Number", Type: query.String},
+		"status":       {Column: "Status", Type: query.String},
+		"created_at":   {Column: "CreatedAt", Type: query.Date},
+	},
+}
+
+var PermissionResource = query.Resource{
+	Key:  []string{"permission_id"},
+	Sort: []query.Order{{Field: "module_name"}},
+	Fields: map[string]query.Field{
+		"permission_id": {Column: "PermissionID", Type: query.Int},
+		"module_name":   {Column: "ModuleName", Type: query.String},
+		"action_type":   {Column: "ActionType", Type: query.String},
+	},
+}
+
+var RoleResource = query.Resource{
+	Key:  []string{"role_id"},
+	Sort: []query.Order{{Field: "role_name"}},
+	Fields: map[string]query.Field{
+		"role_id":     {Column: "Role_ID", Type: query.Int},
+		"user_id":     {Column: "User_ID", Type: query.Int},
+		"role_name":   {Column: "Role_Name", Type: query.String},
+		"description": {Column: "Description", Type: query.String},
+	},
+}
+
+var RolePermissionResource = query.Resource{
+	Key:  []string{"role_id", "permission_id"},
+	Sort: []query.Order{{Field: "role_id"}},
+	Fields: map[string]query.Field{
+		"role_id":       {Column: "Role_ID", Type: query.Int},
+		"permission_id": {Column: "PermissionID", Type: query.Int},
+	},
+}
+
+// ==================== HR & EMPLOYEES ====================
+var EmployeeResource = query.Resource{
+	Key:  []string{"employee_id"},
+	Sort: []query.Order{{Field: "full_name"}},
+	Fields: map[string]query.Field{
+		"employee_id":        {Column: "EmployeeID", Type: query.Int},
+		"full_name":          {Column: "FullName", Type: query.String},
+		"department":         {Column: "Department", Type: query.String},
+		"position":           {Column: "Position", Type: query.String},
+		"hire_date":          {Column: "HireDate", Type: query.Date},
+		"performance_rating": {Column: "PerformanceRating", Type: query.Float},
+	},
+}
+
+var WorkerAssignmentResource = query.Resource{
+	Key:  []string{"assignment_id"},
+	Sort: []query.Order{{Field: "assignment_id"}},
+	Fields: map[string]query.Field{
+		"assignment_id": {Column: "AssignmentID", Type: query.Int},
+		"employee_id":   {Column: "EmployeeID", Type: query.Int},
+		"processing_id": {Column: "ProcessingID", Type: query.Int},
+		"role_in_task":  {Column: "RoleInTask", Type: query.String},
+		"notes":         {Column: "Notes", Type: query.String},
+	},
+}
+
+var ManagementInsightsResource = query.Resource{
+	Key:  []string{"report_id"},
+	Sort: []query.Order{{Field: "report_id", Desc: true}},
+	Fields: map[string]query.Field{
+		"report_id":   {Column: "Report_ID", Type: query.Int},
+		"employee_id": {Column: "EmployeeID", Type: query.Int},
+		"kpi_type":    {Column: "KPI_Type", Type: query.String},
+		"time_period": {Column: "Time_Period", Type: query.String},
+	},
+}
+
+// ==================== SUPPLIERS ====================
+var SupplierResource = query.Resource{
+	Key:  []string{"supplier_id"},
+	Sort: []query.Order{{Field: "company_name"}},
+	Fields: map[string]query.Field{
+		"supplier_id":       {Column: "SupplierID", Type: query.Int},
+		"company_name":      {Column: "CompanyName", Type: query.String},
+		"contact_person":    {Column: "ContactPerson", Type: query.String},
+		"email":             {Column: "Email", Type: query.String},
+		"phone":             {Column: "Phone", Type: query.String},
+		"compliance_status": {Column: "ComplianceStatus", Type: query.String},
+		"raw":               {Column: "Raw", Type: query.Bool},
+		"semi_processed":    {Column: "Semi_Processed", Type: query.Bool},
+	},
+}
+
+var SupplierPerformanceResource = query.Resource{
+	Key:  []string{"performance_id"},
+	Sort: []query.Order{{Field: "review_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"performance_id":      {Column: "PerformanceID", Type: query.Int},
+		"supplier_id":         {Column: "SupplierID", Type: query.Int},
+		"rating":              {Column: "Rating", Type: query.Float},
+		"delivery_timeliness": {Column: "DeliveryTimeliness", Type: query.Float},
+		"quality_score":       {Column: "QualityScore", Type: query.Float},
+		"review_date":         {Column: "ReviewDate", Type: query.Date},
+	},
+}
+
+var SupplierContractResource = query.Resource{
+	Key:  []string{"contract_id"},
+	Sort: []query.Order{{Field: "start_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"contract_id":    {Column: "ContractID", Type: query.Int},
+		"supplier_id":    {Column: "SupplierID", Type: query.Int},
+		"start_date":     {Column: "StartDate", Type: query.Date},
+		"end_date":       {Column: "EndDate", Type: query.Date},
+		"terms":          {Column: "Terms", Type: query.String},
+		"contract_value": {Column: "ContractValue", Type: query.Float},
+		"status":         {Column: "Status", Type: query.String},
+	},
+}
+
+// ==================== FOREST & HARVESTING ====================
+var ForestResource = query.Resource{
+	Key:  []string{"forest_id"},
+	Sort: []query.Order{{Field: "forest_name"}},
+	Fields: map[string]query.Field{
+		"forest_id":      {Column: "ForestID", Type: query.Int},
+		"forest_name":    {Column: "ForestName", Type: query.String},
+		"geo_location":   {Column: "GeoLocation", Type: query.String},
+		"area_size":      {Column: "AreaSize", Type: query.Float},
+		"ownership_type": {Column: "OwnershipType", Type: query.String},
+		"status":         {Column: "Status", Type: query.String},
+	},
+}
+
+var TreeSpeciesResource = query.Resource{
+	Key:  []string{"species_id"},
+	Sort: []query.Order{{Field: "species_name"}},
+	Fields: map[string]query.Field{
+		"species_id":       {Column: "SpeciesID", Type: query.Int},
+		"species_name":     {Column: "SpeciesName", Type: query.String},
+		"average_height":   {Column: "AverageHeight", Type: query.Float},
+		"density":          {Column: "Density", Type: query.Float},
+		"moisture_content": {Column: "MoistureContent", Type: query.Float},
+		"grade":            {Column: "Grade", Type: query.String},
+	},
+}
+
+var HarvestScheduleResource = query.Resource{
+	Key:  []string{"schedule_id"},
+	Sort: []query.Order{{Field: "start_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"schedule_id": {Column: "ScheduleID", Type: query.Int},
+		"forest_id":   {Column: "ForestID", Type: query.Int},
+		"start_date":  {Column: "StartDate", Type: query.Date},
+		"end_date":    {Column: "EndDate", Type: query.Date},
+		"status":      {Column: "Status", Type: query.String},
+	},
+}
+
+var HarvestBatchResource = query.Resource{
+	Key:  []string{"batch_id"},
+	Sort: []query.Order{{Field: "harvest_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"batch_id":          {Column: "BatchID", Type: query.Int},
+		"forest_id":         {Column: "ForestID", Type: query.Int},
+		"species_id":        {Column: "SpeciesID", Type: query.Int},
+		"schedule_id":       {Column: "ScheduleID", Type: query.Int},
+		"quantity":          {Column: "Quantity", Type: query.Float},
+		"harvest_date":      {Column: "HarvestDate", Type: query.Date},
+		"quality_indicator": {Column: "QualityIndicator", Type: query.String},
+		"qr_code":           {Column: "QRCode", Type: query.String},
+	},
+}
+
+// ==================== PROCESSING & SAWMILL ====================
+var SawmillResource = query.Resource{
+	Key:  []string{"sawmill_id"},
+	Sort: []query.Order{{Field: "name"}},
+	Fields: map[string]query.Field{
+		"sawmill_id": {Column: "SawmillID", Type: query.Int},
+		"name":       {Column: "Name", Type: query.String},
+		"location":   {Column: "Location", Type: query.String},
+		"capacity":   {Column: "Capacity", Type: query.Float},
+		"status":     {Column: "Status", Type: query.String},
+	},
+}
+
+var ProcessingUnitResource = query.Resource{
+	Key:  []string{"unit_id"},
+	Sort: []query.Order{{Field: "unit_id"}},
+	Fields: map[string]query.Field{
+		"unit_id":    {Column: "UnitID", Type: query.Int},
+		"sawmill_id": {Column: "SawmillID", Type: query.Int},
+		"cutting":    {Column: "Cutting", Type: query.String},
+		"drying":     {Column: "Drying", Type: query.String},
+		"finishing":  {Column: "Finishing", Type: query.String},
+		"capacity":   {Column: "Capacity", Type: query.Float},
+		"status":     {Column: "Status", Type: query.String},
+	},
+}
+
+var HarvestBatchProcessingResource = query.Resource{
+	Key:  []string{"processing_id", "batch_id"},
+	Sort: []query.Order{{Field: "processing_id"}},
+	Fields: map[string]query.Field{
+		"processing_id":     {Column: "ProcessingID", Type: query.Int},
+		"batch_id":          {Column: "BatchID", Type: query.Int},
+		"consumed_quantity": {Column: "ConsumedQuantity", Type: query.Float},
+	},
+}
+
+var ProcessingOrderResource = query.Resource{
+	Key:  []string{"processing_id"},
+	Sort: []query.Order{{Field: "start_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"processing_id":         {Column: "ProcessingID", Type: query.Int},
+		"product_type_id":       {Column: "ProductTypeID", Type: query.Int},
+		"unit_id":               {Column: "UnitID", Type: query.Int},
+		"start_date":            {Column: "StartDate", Type: query.Date},
+		"end_date":              {Column: "EndDate", Type: query.Date},
+		"output_quantity":       {Column: "OutputQuantity", Type: query.Float},
+		"efficiency_rate":       {Column: "EfficiencyRate", Type: query.Float},
+		"status":                {Column: "Status", Type: query.String},
+		"warehouse_id":          {Column: "WarehouseID", Type: query.Int},
+		"input_quantity":        {Column: "InputQuantity", Type: query.Float},
+		"waste_quantity":        {Column: "WasteQuantity", Type: query.Float},
+		"mass_balance_variance": {Column: "MassBalanceVariance", Type: query.Float},
+		"mass_balance_flagged":  {Column: "MassBalanceFlagged", Type: query.Bool},
+		"planned_quantity":      {Column: "PlannedQuantity", Type: query.Float},
+		"duration_hours":        {Column: "DurationHours", Type: query.Float},
+		"operations":            {Column: "Operations", Type: query.String},
+		"scheduled_start":       {Column: "ScheduledStart", Type: query.Date},
+		"scheduled_end":         {Column: "ScheduledEnd", Type: query.Date},
+	},
+}
+
+var MaintenanceRecordResource = query.Resource{
+	Key:  []string{"maintenance_id"},
+	Sort: []query.Order{{Field: "maintenance_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"maintenance_id":   {Column: "MaintenanceID", Type: query.Int},
+		"unit_id":          {Column: "UnitID", Type: query.Int},
+		"maintenance_date": {Column: "MaintenanceDate", Type: query.Date},
+		"description":      {Column: "Description", Type: query.String},
+		"cost":             {Column: "Cost", Type: query.Float},
+		"parts_used":       {Column: "PartsUsed", Type: query.String},
+		"downtime_hours":   {Column: "DowntimeHours", Type: query.Float},
+	},
+}
+
+var MaintenancePlanResource = query.Resource{
+	Key:  []string{"plan_id"},
+	Sort: []query.Order{{Field: "plan_id"}},
+	Fields: map[string]query.Field{
+		"plan_id":         {Column: "PlanID", Type: query.Int},
+		"unit_id":         {Column: "UnitID", Type: query.Int},
+		"description":     {Column: "COALESCE(Description, '')", Type: query.String},
+		"interval_hours":  {Column: "IntervalHours", Type: query.Float},
+		"interval_days":   {Column: "IntervalDays", Type: query.Int},
+		"active":          {Column: "Active", Type: query.Bool},
+		"last_done_at":    {Column: "LastDoneAt", Type: query.Date},
+		"last_done_hours": {Column: "LastDoneHours", Type: query.Float},
+		"created_at":      {Column: "CreatedAt", Type: query.Date},
+	},
+}
+
+var MaintenanceWorkOrderResource = query.Resource{
+	Key:  []string{"work_order_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"work_order_id":   {Column: "WorkOrderID", Type: query.Int},
+		"unit_id":         {Column: "UnitID", Type: query.Int},
+		"plan_id":         {Column: "PlanID", Type: query.Int},
+		"status":          {Column: "Status", Type: query.String},
+		"reason":          {Column: "Reason", Type: query.String},
+		"description":     {Column: "COALESCE(Description, '')", Type: query.String},
+		"operating_hours": {Column: "OperatingHours", Type: query.Float},
+		"created_at":      {Column: "CreatedAt", Type: query.Date},
+		"closed_at":       {Column: "ClosedAt", Type: query.Date},
+		"maintenance_id":  {Column: "MaintenanceID", Type: query.Int},
+	},
+}
+
+var KilnChargeResource = query.Resource{
+	Key:  []string{"charge_id"},
+	Sort: []query.Order{{Field: "started_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"charge_id":       {Column: "ChargeID", Type: query.Int},
+		"unit_id":         {Column: "UnitID", Type: query.Int},
+		"status":          {Column: "Status", Type: query.String},
+		"flag":            {Column: "Flag", Type: query.String},
+		"target_moisture": {Column: "TargetMoisture", Type: query.Float},
+		"target_source":   {Column: "TargetSource", Type: query.String},
+		"moisture":        {Column: "Moisture", Type: query.Float},
+		"started_at":      {Column: "StartedAt", Type: query.Date},
+		"completed_at":    {Column: "CompletedAt", Type: query.Date},
+		"unloaded_at":     {Column: "UnloadedAt", Type: query.Date},
+	},
+}
+
+var KilnReadingResource = query.Resource{
+	Key:  []string{"reading_id"},
+	Sort: []query.Order{{Field: "recorded_at"}},
+	Fields: map[string]query.Field{
+		"reading_id":  {Column: "ReadingID", Type: query.Int},
+		"charge_id":   {Column: "ChargeID", Type: query.Int},
+		"recorded_at": {Column: "RecordedAt", Type: query.Date},
+		"temperature": {Column: "Temperature", Type: query.Float},
+		"humidity":    {Column: "Humidity", Type: query.Float},
+		"moisture":    {Column: "Moisture", Type: query.Float},
+	},
+}
+
+var WasteRecordResource = query.Resource{
+	Key:  []string{"waste_id"},
+	Sort: []query.Order{{Field: "waste_id"}},
+	Fields: map[string]query.Field{
+		"waste_id":        {Column: "WasteID", Type: query.Int},
+		"processing_id":   {Column: "ProcessingID", Type: query.Int},
+		"waste_type":      {Column: "WasteType", Type: query.String},
+		"volume":          {Column: "Volume", Type: query.Float},
+		"disposal_method": {Column: "DisposalMethod", Type: query.String},
+		"recycled":        {Column: "Recycled", Type: query.Bool},
+		"disposal_cost":   {Column: "DisposalCost", Type: query.Float},
+		"stock_id":        {Column: "StockID", Type: query.Int},
+	},
+}
+
+// ==================== QUALITY CONTROL ====================
+var QualityInspectionResource = query.Resource{
+	Key:  []string{"inspection_id"},
+	Sort: []query.Order{{Field: "date", Desc: true}},
+	Fields: map[string]query.Field{
+		"inspection_id":    {Column: "InspectionID", Type: query.Int},
+		"employee_id":      {Column: "EmployeeID", Type: query.Int},
+		"processing_id":    {Column: "ProcessingID", Type: query.Int},
+		"po_item_id":       {Column: "POItemID", Type: query.Int},
+		"batch_id":         {Column: "BatchID", Type: query.Int},
+		"result":           {Column: "Result", Type: query.String},
+		"moisture_level":   {Column: "MoistureLevel", Type: query.Float},
+		"certification_id": {Column: "CertificationID", Type: query.String},
+		"date":             {Column: "Date", Type: query.Date},
+		"template_id":      {Column: "TemplateID", Type: query.Int},
+		"stage":            {Column: "Stage", Type: query.String},
+		"grade":            {Column: "Grade", Type: query.String},
+	},
+}
+
+var DefectCodeResource = query.Resource{
+	Key:  []string{"defect_id"},
+	Sort: []query.Order{{Field: "code"}},
+	Fields: map[string]query.Field{
+		"defect_id":   {Column: "DefectID", Type: query.Int},
+		"code":        {Column: "Code", Type: query.String},
+		"name":        {Column: "Name", Type: query.String},
+		"category":    {Column: "Category", Type: query.String},
+		"severity":    {Column: "Severity", Type: query.String},
+		"description": {Column: "Description", Type: query.String},
+	},
+}
+
+var InspectionTemplateResource = query.Resource{
+	Key:  []string{"template_id"},
+	Sort: []query.Order{{Field: "template_id"}},
+	Fields: map[string]query.Field{
+		"template_id":     {Column: "TemplateID", Type: query.Int},
+		"name":            {Column: "Name", Type: query.String},
+		"product_type_id": {Column: "ProductTypeID", Type: query.Int},
+		"stage":           {Column: "Stage", Type: query.String},
+		"active":          {Column: "Active", Type: query.Bool},
+	},
+}
+
+var NonConformanceResource = query.Resource{
+	Key:  []string{"ncr_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"ncr_id":         {Column: "NCRID", Type: query.Int},
+		"title":          {Column: "Title", Type: query.String},
+		"origin":         {Column: "Origin", Type: query.String},
+		"severity":       {Column: "Severity", Type: query.String},
+		"inspection_id":  {Column: "InspectionID", Type: query.Int},
+		"supplier_id":    {Column: "SupplierID", Type: query.Int},
+		"unit_id":        {Column: "UnitID", Type: query.Int},
+		"raised_by":      {Column: "RaisedBy", Type: query.Int},
+		"owner_id":       {Column: "OwnerID", Type: query.Int},
+		"status":         {Column: "Status", Type: query.String},
+		"due_date":       {Column: "DueDate", Type: query.Date},
+		"score_deducted": {Column: "ScoreDeducted", Type: query.Float},
+		"performance_id": {Column: "PerformanceID", Type: query.Int},
+		"created_at":     {Column: "CreatedAt", Type: query.Date},
+		"closed_at":      {Column: "ClosedAt", Type: query.Date},
+	},
+}
+
+var CorrectiveActionResource = query.Resource{
+	Key:  []string{"action_id"},
+	Sort: []query.Order{{Field: "due_date"}},
+	Fields: map[string]query.Field{
+		"action_id":    {Column: "ActionID", Type: query.Int},
+		"ncr_id":       {Column: "NCRID", Type: query.Int},
+		"kind":         {Column: "Kind", Type: query.String},
+		"owner_id":     {Column: "OwnerID", Type: query.Int},
+		"due_date":     {Column: "DueDate", Type: query.Date},
+		"status":       {Column: "Status", Type: query.String},
+		"created_at":   {Column: "CreatedAt", Type: query.Date},
+		"completed_at": {Column: "CompletedAt", Type: query.Date},
+	},
+}
+
+var QualityAlertResource = query.Resource{
+	Key:  []string{"alert_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"alert_id":      {Column: "AlertID", Type: query.Int},
+		"unit_id":       {Column: "UnitID", Type: query.Int},
+		"species_id":    {Column: "SpeciesID", Type: query.Int},
+		"metric":        {Column: "Metric", Type: query.String},
+		"chart":         {Column: "Chart", Type: query.String},
+		"rule":          {Column: "Rule", Type: query.Int},
+		"inspection_id": {Column: "InspectionID", Type: query.Int},
+		"value":         {Column: "Value", Type: query.Float},
+		"status":        {Column: "Status", Type: query.String},
+		"created_at":    {Column: "CreatedAt", Type: query.Date},
+	},
+}
+
+var SamplingPlanResource = query.Resource{
+	Key:  []string{"plan_id"},
+	Sort: []query.Order{{Field: "plan_id"}},
+	Fields: map[string]query.Field{
+		"plan_id":          {Column: "PlanID", Type: query.Int},
+		"supplier_id":      {Column: "SupplierID", Type: query.Int},
+		"product_type_id":  {Column: "ProductTypeID", Type: query.Int},
+		"inspection_level": {Column: "InspectionLevel", Type: query.String},
+		"aql":              {Column: "AQL", Type: query.Float},
+		"active":           {Column: "Active", Type: query.Bool},
+	},
+}
+
+var InspectionLotResource = query.Resource{
+	Key:  []string{"lot_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"lot_id":          {Column: "LotID", Type: query.Int},
+		"po_item_id":      {Column: "POItemID", Type: query.Int},
+		"plan_id":         {Column: "PlanID", Type: query.Int},
+		"supplier_id":     {Column: "SupplierID", Type: query.Int},
+		"product_type_id": {Column: "ProductTypeID", Type: query.Int},
+		"lot_size":        {Column: "LotSize", Type: query.Int},
+		"severity":        {Column: "Severity", Type: query.String},
+		"code_letter":     {Column: "CodeLetter", Type: query.String},
+		"sample_size":     {Column: "SampleSize", Type: query.Int},
+		"defects":         {Column: "Defects", Type: query.Int},
+		"status":          {Column: "Status", Type: query.String},
+		"created_at":      {Column: "CreatedAt", Type: query.Date},
+		"decided_at":      {Column: "DecidedAt", Type: query.Date},
+	},
+}
+
+// ==================== WAREHOUSE & INVENTORY ====================
+var WarehouseResource = query.Resource{
+	Key:  []string{"warehouse_id"},
+	Sort: []query.Order{{Field: "name"}},
+	Fields: map[string]query.Field{
+		"warehouse_id": {Column: "WarehouseID", Type: query.Int},
+		"name":         {Column: "Name", Type: query.String},
+		"location":     {Column: "Location", Type: query.String},
+		"capacity":     {Column: "Capacity", Type: query.Float},
+		"contact":      {Column: "Contact", Type: query.String},
+
+		"allow_negative_stock": {Column: "AllowNegativeStock", Type: query.Bool},
+	},
+}
+
+var ProductTypeResource = query.Resource{
+	Key:  []string{"product_type_id"},
+	Sort: []query.Order{{Field: "name"}},
+	Fields: map[string]query.Field{
+		"product_type_id": {Column: "ProductTypeID", Type: query.Int},
+		"name":            {Column: "Name", Type: query.String},
+		"description":     {Column: `COALESCE(Description, '')`, Type: query.String},
+		"price":           {Column: "Price", Type: query.Float},
+		"grade":           {Column: `COALESCE(Grade, '')`, Type: query.String},
+		"unit_of_measure": {Column: `COALESCE(UnitOfMeasure, '')`, Type: query.String},
+	},
+}
+
+var StockItemResource = query.Resource{
+	Key:  []string{"stock_id"},
+	Sort: []query.Order{{Field: "stock_id"}},
+	Fields: map[string]query.Field{
+		"stock_id":        {Column: "StockID", Type: query.Int},
+		"product_type_id": {Column: "ProductTypeID", Type: query.Int},
+		"warehouse_id":    {Column: "WarehouseID", Type: query.Int},
+		"batch_id":        {Column: "BatchID", Type: query.Int},
+		"processing_id":   {Column: "ProcessingID", Type: query.Int},
+		"quantity":        {Column: "Quantity", Type: query.Float},
+		"shelf_location":  {Column: "ShelfLocation", Type: query.String},
+		"grade":           {Column: "Grade", Type: query.String},
+		"graded_by":       {Column: "GradedBy", Type: query.Int},
+		"status":          {Column: "Status", Type: query.String},
+		"po_item_id":      {Column: "POItemID", Type: query.Int},
+	},
+}
+
+var StockAlertResource = query.Resource{
+	Key:  []string{"alert_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"alert_id":     {Column: "AlertID", Type: query.Int},
+		"stock_id":     {Column: "StockID", Type: query.Int},
+		"warehouse_id": {Column: "WarehouseID", Type: query.Int},
+		"alert_type":   {Column: "AlertType", Type: query.String},
+		"created_at":   {Column: "CreatedAt", Type: query.Date},
+		"status":       {Column: "Status", Type: query.String},
+	},
+}
+
+var StockDispositionResource = query.Resource{
+	Key:  []string{"disposition_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"disposition_id": {Column: "DispositionID", Type: query.Int},
+		"stock_id":       {Column: "StockID", Type: query.Int},
+		"action":         {Column: "Action", Type: query.String},
+		"from_status":    {Column: "FromStatus", Type: query.String},
+		"to_status":      {Column: "ToStatus", Type: query.String},
+		"quantity":       {Column: "Quantity", Type: query.Float},
+		"inspection_id":  {Column: "InspectionID", Type: query.Int},
+		"processing_id":  {Column: "ProcessingID", Type: query.Int},
+		"waste_id":       {Column: "WasteID", Type: query.Int},
+		"transaction_id": {Column: "TransactionID", Type: query.Int},
+		"employee_id":    {Column: "EmployeeID", Type: query.Int},
+		"created_at":     {Column: "CreatedAt", Type: query.Date},
+	},
+}
+
+var InventoryTransactionResource = query.Resource{
+	Key:  []string{"transaction_id"},
+	Sort: []query.Order{{Field: "transaction_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"transaction_id":   {Column: "TransactionID", Type: query.Int},
+		"employee_id":      {Column: "EmployeeID", Type: query.Int},
+		"stock_id":         {Column: "StockID", Type: query.Int},
+		"warehouse_id":     {Column: "WarehouseID", Type: query.Int},
+		"transaction_type": {Column: "TransactionType", Type: query.String},
+		"quantity":         {Column: "Quantity", Type: query.Float},
+		"balance_after":    {Column: "BalanceAfter", Type: query.Float},
+		"reverses_id":      {Column: "ReversesID", Type: query.Int},
+		"transaction_date": {Column: "TransactionDate", Type: query.Date},
+		"remarks":          {Column: "Remarks", Type: query.String},
+	},
+}
+
+var StockTransferResource = query.Resource{
+	Key:  []string{"transfer_id"},
+	Sort: []query.Order{{Field: "created_at", Desc: true}},
+	Fields: map[string]query.Field{
+		"transfer_id":       {Column: "TransferID", Type: query.Int},
+		"from_warehouse_id": {Column: "FromWarehouseID", Type: query.Int},
+		"to_warehouse_id":   {Column: "ToWarehouseID", Type: query.Int},
+		"status":            {Column: "Status", Type: query.String},
+		"shipment_id":       {Column: "ShipmentID", Type: query.Int},
+		"truck_id":          {Column: "TruckID", Type: query.Int},
+		"created_at":        {Column: "CreatedAt", Type: query.Date},
+		"dispatched_at":     {Column: "DispatchedAt", Type: query.Date},
+		"received_at":       {Column: "ReceivedAt", Type: query.Date},
+	},
+}
+
+// ==================== PROCUREMENT ====================
+var PurchaseOrderResource = query.Resource{
+	Key:  []string{"poid"},
+	Sort: []query.Order{{Field: "order_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"poid":                   {Column: "POID", Type: query.Int},
+		"employee_id":            {Column: "EmployeeID", Type: query.Int},
+		"supplier_id":            {Column: "SupplierID", Type: query.Int},
+		"order_date":             {Column: "OrderDate", Type: query.Date},
+		"expected_delivery_date": {Column: "ExpectedDeliveryDate", Type: query.Date},
+		"status":                 {Column: "Status", Type: query.String},
+		"total_amount":           {Column: "TotalAmount", Type: query.Float},
+	},
+}
+
+var PurchaseOrderItemResource = query.Resource{
+	Key:  []string{"po_item_id"},
+	Sort: []query.Order{{Field: "po_item_id"}},
+	Fields: map[string]query.Field{
+		"po_item_id":      {Column: "POItemID", Type: query.Int},
+		"poid":            {Column: "POID", Type: query.Int},
+		"product_type_id": {Column: "ProductTypeID", Type: query.Int},
+		"quantity":        {Column: "Quantity", Type: query.Float},
+		"unit_price":      {Column: "UnitPrice", Type: query.Float},
+		"subtotal":        {Column: "Subtotal", Type: query.Float},
+	},
+}
+
+// ==================== SALES & CUSTOMERS ====================
+var CustomerResource = query.Resource{
+	Key:  []string{"customer_id"},
+	Sort: []query.Order{{Field: "name"}},
+	Fields: map[string]query.Field{
+		"customer_id":  {Column: "CustomerID", Type: query.Int},
+		"name":         {Column: "Name", Type: query.String},
+		"retailer":     {Column: "Retailer", Type: query.Bool},
+		"end_user":     {Column: "EndUser", Type: query.Bool},
+		"contact_info": {Column: "ContactInfo", Type: query.String},
+		"address":      {Column: "Address", Type: query.String},
+		"tax_number":   {Column: "TaxNumber", Type: query.String},
+	},
+}
+
+var SalesOrderResource = query.Resource{
+	Key:  []string{"soid"},
+	Sort: []query.Order{{Field: "order_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"soid":          {Column: "SOID", Type: query.Int},
+		"employee_id":   {Column: "EmployeeID", Type: query.Int},
+		"customer_id":   {Column: "CustomerID", Type: query.Int},
+		"order_date":    {Column: "OrderDate", Type: query.Date},
+		"delivery_date": {Column: "DeliveryDate", Type: query.Date},
+		"status":        {Column: "Status", Type: query.String},
+		"total_amount":  {Column: "TotalAmount", Type: query.Float},
+	},
+}
+
+var SalesOrderItemResource = query.Resource{
+	Key:  []string{"so_item_id"},
+	Sort: []query.Order{{Field: "so_item_id"}},
+	Fields: map[string]query.Field{
+		"so_item_id":      {Column: "SOItemID", Type: query.Int},
+		"soid":            {Column: "SOID", Type: query.Int},
+		"product_type_id": {Column: "ProductTypeID", Type: query.Int},
+		"stock_id":        {Column: "StockID", Type: query.Int},
+		"quantity":        {Column: "Quantity", Type: query.Float},
+		"unit_price":      {Column: "UnitPrice", Type: query.Float},
+		"discount":        {Column: "Discount", Type: query.Float},
+		"subtotal":        {Column: "Subtotal", Type: query.Float},
+	},
+}
+
+// ==================== INVOICING & PAYMENTS ====================
+var InvoiceResource = query.Resource{
+	Key:  []string{"invoice_id"},
+	Sort: []query.Order{{Field: "invoice_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"invoice_id":   {Column: "InvoiceID", Type: query.Int},
+		"soid":         {Column: "SOID", Type: query.Int},
+		"invoice_date": {Column: "InvoiceDate", Type: query.Date},
+		"due_date":     {Column: "DueDate", Type: query.Date},
+		"total_amount": {Column: "TotalAmount", Type: query.Float},
+		"tax":          {Column: "Tax", Type: query.Float},
+		"currency":     {Column: "Currency", Type: query.String},
+		"status":       {Column: "Status", Type: query.String},
+	},
+}
+
+var PaymentResource = query.Resource{
+	Key:  []string{"payment_id"},
+	Sort: []query.Order{{Field: "payment_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"payment_id":   {Column: "PaymentID", Type: query.Int},
+		"invoice_id":   {Column: "InvoiceID", Type: query.Int},
+		"payment_date": {Column: "PaymentDate", Type: query.Date},
+		"amount":       {Column: "Amount", Type: query.Float},
+		"method":       {Column: "Method", Type: query.String},
+		"reference_no": {Column: "ReferenceNo", Type: query.String},
+		"status":       {Column: "Status", Type: query.String},
+	},
+}
+
+// ==================== TRANSPORTATION ====================
+var TransportCompanyResource = query.Resource{
+	Key:  []string{"company_id"},
+	Sort: []query.Order{{Field: "company_name"}},
+	Fields: map[string]query.Field{
+		"company_id":     {Column: "CompanyID", Type: query.Int},
+		"company_name":   {Column: "CompanyName", Type: query.String},
+		"contact_info":   {Column: "ContactInfo", Type: query.String},
+		"license_number": {Column: "LicenseNumber", Type: query.String},
+		"rating":         {Column: "Rating", Type: query.Float},
+	},
+}
+
+var TruckResource = query.Resource{
+	Key:  []string{"truck_id"},
+	Sort: []query.Order{{Field: "plate_number"}},
+	Fields: map[string]query.Field{
+		"truck_id":     {Column: "TruckID", Type: query.Int},
+		"company_id":   {Column: "CompanyID", Type: query.Int},
+		"plate_number": {Column: "PlateNumber", Type: query.String},
+		"capacity":     {Column: "Capacity", Type: query.Float},
+		"fuel_type":    {Column: "FuelType", Type: query.String},
+		"status":       {Column: "Status", Type: query.String},
+	},
+}
+
+var DriverResource = query.Resource{
+	Key:  []string{"driver_id"},
+	Sort: []query.Order{{Field: "driver_id"}},
+	Fields: map[string]query.Field{
+		"driver_id":        {Column: "DriverID", Type: query.Int},
+		"employee_id":      {Column: "EmployeeID", Type: query.Int},
+		"license_number":   {Column: "LicenseNumber", Type: query.String},
+		"experience_years": {Column: "ExperienceYears", Type: query.Int},
+		"status":           {Column: "Status", Type: query.String},
+	},
+}
+
+var RouteResource = query.Resource{
+	Key:  []string{"route_id"},
+	Sort: []query.Order{{Field: "route_id"}},
+	Fields: map[string]query.Field{
+		"route_id":       {Column: "RouteID", Type: query.Int},
+		"start_location": {Column: "StartLocation", Type: query.String},
+		"end_location":   {Column: "EndLocation", Type: query.String},
+		"distance_km":    {Column: "DistanceKM", Type: query.Float},
+		"estimated_time": {Column: "EstimatedTime", Type: query.String},
+	},
+}
+
+var ShipmentResource = query.Resource{
+	Key:  []string{"shipment_id"},
+	Sort: []query.Order{{Field: "shipment_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"shipment_id":       {Column: "ShipmentID", Type: query.Int},
+		"soid":              {Column: "SOID", Type: query.Int},
+		"truck_id":          {Column: "TruckID", Type: query.Int},
+		"driver_id":         {Column: "DriverID", Type: query.Int},
+		"company_id":        {Column: "CompanyID", Type: query.Int},
+		"route_id":          {Column: "RouteID", Type: query.Int},
+		"shipment_date":     {Column: "ShipmentDate", Type: query.Date},
+		"status":            {Column: "Status", Type: query.String},
+		"proof_of_delivery": {Column: "ProofOfDelivery", Type: query.String},
+	},
+}
+
+var FuelLogResource = query.Resource{
+	Key:  []string{"fuel_log_id"},
+	Sort: []query.Order{{Field: "trip_date", Desc: true}},
+	Fields: map[string]query.Field{
+		"fuel_log_id":       {Column: "FuelLogID", Type: query.Int},
+		"driver_id":         {Column: "DriverID", Type: query.Int},
+		"truck_id":          {Column: "TruckID", Type: query.Int},
+		"trip_date":         {Column: "TripDate", Type: query.Date},
+		"distance_traveled": {Column: "DistanceTraveled", Type: query.Float},
+	},
+}
+
+// ==================== AUDIT & LOGS ====================
+var AuditLogResource = query.Resource{
+	Key:  []string{"log_id"},
+	Sort: []query.Order{{Field: "timestamp", Desc: true}},
+	Fields: map[string]query.Field{
+		"log_id":          {Column: "LogID", Type: query.Int},
+		"user_id":         {Column: "COALESCE(User_ID, 0)", Type: query.Int},
+		"action_type":     {Column: "ActionType", Type: query.String},
+		"entity_affected": {Column: "COALESCE(EntityAffected, '')", Type: query.String},
+		"entity_id":       {Column: "COALESCE(EntityID, '')", Type: query.String},
+		"timestamp":       {Column: "Timestamp", Type: query.Date},
+		"description":     {Column: "COALESCE(Description, '')", Type: query.String},
+		"ip_address":      {Column: "COALESCE(IPAddress, '')", Type: query.String},
+	},
+}
+
+// resources lists every resource, so database column names can be mapped
+// back to JSON field names
+var resources = []query.Resource{
+	UserResource, PermissionResource, RoleResource, RolePermissionResource,
+	EmployeeResource, WorkerAssignmentResource, ManagementInsightsResource,
+	SupplierResource, SupplierPerformanceResource, SupplierContractResource,
+	ForestResource, TreeSpeciesResource, HarvestScheduleResource, HarvestBatchResource,
+	SawmillResource, ProcessingUnitResource, ProcessingOrderResource, HarvestBatchProcessingResource, MaintenanceRecordResource, MaintenancePlanResource, MaintenanceWorkOrderResource, KilnChargeResource, KilnReadingResource, WasteRecordResource,
+	QualityInspectionResource, DefectCodeResource, InspectionTemplateResource, NonConformanceResource, CorrectiveActionResource, QualityAlertResource,
+	SamplingPlanResource, InspectionLotResource,
+	WarehouseResource, ProductTypeResource, StockItemResource, StockAlertResource, StockDispositionResource, InventoryTransactionResource,
+	StockTransferResource,
+	PurchaseOrderResource, PurchaseOrderItemResource,
+	CustomerResource, SalesOrderResource, SalesOrderItemResource,
+	InvoiceResource, PaymentResource,
+	TransportCompanyResource, TruckResource, DriverResource, RouteResource, ShipmentResource, FuelLogResource,
+	AuditLogResource,
+}
+
+// ColumnField returns the JSON field name of a column as Postgres reports
+// it in constraint errors, e.g. plate_number for platenumber. Unknown
+// columns are returned unchanged.
+func ColumnField(column string) string {
+	for _, res := range resources {
+		for name, field := range res.Fields {
+			expr := field.Column
+			if open := strings.IndexByte(expr, '('); open >= 0 {
+				// COALESCE(Grade, '') is read from Grade
+				expr = strings.TrimSpace(strings.SplitN(expr[open+1:], ",", 2)[0])
+			}
+			if strings.EqualFold(strings.Trim(expr, `"`), column) {
+				return name
+			}
+		}
+	}
+	return column
+}
@@ -0,0 +1,150 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"lumber-erp-api/utils"
+)
+
+// Router dispatches requests by path and method. Patterns are slash
+// separated segments where {name} captures one segment, e.g.
+// /api/v2/salesorders/{soid}/items/{id}. Literal segments win over captures.
+type Router struct {
+	root node
+}
+
+type node struct {
+	literal map[string]*node
+	param   *node
+	routes  map[string]route
+}
+
+type route struct {
+	handler http.HandlerFunc
+	names   []string
+}
+
+// New returns an empty router
+func New() *Router {
+	return &Router{}
+}
+
+// Handle registers handler for method on pattern. It panics on a duplicate
+// registration, like http.ServeMux.
+func (rt *Router) Handle(method, pattern string, handler http.HandlerFunc) {
+	n := &rt.root
+	var names []string
+	for _, segment := range split(pattern) {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+			if n.param == nil {
+				n.param = &node{}
+			}
+			n = n.param
+			continue
+		}
+		if n.literal == nil {
+			n.literal = map[string]*node{}
+		}
+		if n.literal[segment] == nil {
+			n.literal[segment] = &node{}
+		}
+		n = n.literal[segment]
+	}
+
+	if n.routes == nil {
+		n.routes = map[string]route{}
+	}
+	if _, exists := n.routes[method]; exists {
+		panic("router: " + method + " " + pattern + " registered twice")
+	}
+	n.routes[method] = route{handler: handler, names: names}
+}
+
+// ServeHTTP answers 404 for unknown paths, 405 with an Allow header for
+// known paths without a handler for the method, and OPTIONS itself
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n, values := rt.root.match(split(r.URL.Path), nil)
+	if n == nil {
+		utils.EnableCORS(&w)
+		utils.RespondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	allow := n.allow()
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Allow", allow)
+		utils.EnableCORS(&w)
+		w.Header().Set("Access-Control-Allow-Methods", allow)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	rte, ok := n.routes[r.Method]
+	if !ok {
+		w.Header().Set("Allow", allow)
+		utils.EnableCORS(&w)
+		utils.RespondError(w, http.StatusMethodNotAllowed, r.Method+" method not allowed")
+		return
+	}
+
+	params := make(map[string]string, len(rte.names))
+	for i, name := range rte.names {
+		params[name] = values[i]
+	}
+	rte.handler(w, r.WithContext(context.WithValue(r.Context(), paramsKey{}, params)))
+}
+
+// match walks the tree, preferring literal children and backtracking to the
+// capture child, and returns the node holding routes for the path
+func (n *node) match(segments, values []string) (*node, []string) {
+	if len(segments) == 0 {
+		if len(n.routes) == 0 {
+			return nil, nil
+		}
+		return n, values
+	}
+
+	if child := n.literal[segments[0]]; child != nil {
+		if found, vals := child.match(segments[1:], values); found != nil {
+			return found, vals
+		}
+	}
+	if n.param != nil && segments[0] != "" {
+		return n.param.match(segments[1:], append(values, segments[0]))
+	}
+	return nil, nil
+}
+
+// allow lists the node's methods for the Allow header
+func (n *node) allow() string {
+	methods := []string{"OPTIONS"}
+	for method := range n.routes {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+type paramsKey struct{}
+
+// Param returns the path segment captured as {name}, or "" when the route
+// has no such parameter
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// Routed reports whether r was dispatched by a Router, as opposed to a
+// legacy route registered directly on the mux
+func Routed(r *http.Request) bool {
+	_, ok := r.Context().Value(paramsKey{}).(map[string]string)
+	return ok
+}
@@ -0,0 +1,351 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"lumber-erp-api/maintenance"
+	"lumber-erp-api/middleware"
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/router"
+	"lumber-erp-api/spc"
+)
+
+// auditEntities describes the rows each mutating route changes, keyed by
+// auditPath of its /api/v2 pattern
+func auditEntities(repos repository.Repositories) map[string]middleware.AuditEntity {
+	return map[string]middleware.AuditEntity{
+		// ==================== USER MANAGEMENT ====================
+		"/users":                entity("User", repository.UserResource, repos.Users.ListUsers),
+		"/users/{}/password":    entity("User", repository.UserResource, repos.Users.ListUsers),
+		"/permissions":          entity("Permission", repository.PermissionResource, repos.Access.ListPermissions),
+		"/roles":                entity("Role", repository.RoleResource, repos.Access.ListRoles),
+		"/roles/{}/permissions": grantsOfRole(repos.Access),
+		"/rolepermissions":      entity("RolePermission", repository.RolePermissionResource, repos.Access.ListRolePermissions),
+
+		// ==================== HR & EMPLOYEES ====================
+		"/employees":          entity("Employee", repository.EmployeeResource, repos.Employees.ListEmployees),
+		"/workerassignments":  entity("WorkerAssignment", repository.WorkerAssignmentResource, repos.Employees.ListWorkerAssignments),
+		"/managementinsights": entity("Management_Insights", repository.ManagementInsightsResource, repos.Employees.ListManagementInsights),
+
+		// ==================== SUPPLIERS ====================
+		"/suppliers":            entity("Supplier", repository.SupplierResource, repos.Suppliers.ListSuppliers),
+		"/supplierperformances": entity("SupplierPerformance", repository.SupplierPerformanceResource, repos.Suppliers.ListSupplierPerformances),
+		"/suppliercontracts":    entity("SupplierContract", repository.SupplierContractResource, repos.Suppliers.ListSupplierContracts),
+
+		// ==================== FOREST & HARVESTING ====================
+		"/forests":          entity("Forest", repository.ForestResource, repos.Forests.ListForests),
+		"/treespecies":      entity("TreeSpecies", repository.TreeSpeciesResource, repos.Forests.ListTreeSpecies),
+		"/harvestschedules": entity("HarvestSchedule", repository.HarvestScheduleResource, repos.Forests.ListHarvestSchedules),
+		"/harvestbatches":   entity("HarvestBatch", repository.HarvestBatchResource, repos.Forests.ListHarvestBatches),
+
+		// ==================== PROCESSING & SAWMILL ====================
+		"/sawmills":                          entity("Sawmill", repository.SawmillResource, repos.Processing.ListSawmills),
+		"/sawmills/{}/schedule":              slotsOf(repos.Processing),
+		"/processingunits":                   entity("ProcessingUnit", repository.ProcessingUnitResource, repos.Processing.ListProcessingUnits),
+		"/processingorders":                  entity("ProcessingOrder", repository.ProcessingOrderResource, repos.Processing.ListProcessingOrders),
+		"/processingorders/{}/release":       entity("ProcessingOrder", repository.ProcessingOrderResource, repos.Processing.ListProcessingOrders),
+		"/processingorders/{}/start":         entity("ProcessingOrder", repository.ProcessingOrderResource, repos.Processing.ListProcessingOrders),
+		"/processingorders/{}/complete":      entity("ProcessingOrder", repository.ProcessingOrderResource, repos.Processing.ListProcessingOrders),
+		"/processingorders/{}/close":         entity("ProcessingOrder", repository.ProcessingOrderResource, repos.Processing.ListProcessingOrders),
+		"/processingorders/{}/batches":       entity("HarvestBatch_Processing", repository.HarvestBatchProcessingResource, repos.Processing.ListHarvestBatchProcessing),
+		"/maintenancerecords":                entity("MaintenanceRecord", repository.MaintenanceRecordResource, repos.Processing.ListMaintenanceRecords),
+		"/maintenanceplans":                  entity("MaintenancePlan", repository.MaintenancePlanResource, repos.Maintenance.ListMaintenancePlans),
+		"/maintenanceplans/generate":         openWorkOrders(repos.Maintenance),
+		"/maintenanceworkorders":             entity("MaintenanceWorkOrder", repository.MaintenanceWorkOrderResource, repos.Maintenance.ListWorkOrders),
+		"/maintenanceworkorders/{}/complete": entity("MaintenanceWorkOrder", repository.MaintenanceWorkOrderResource, repos.Maintenance.ListWorkOrders),
+		"/maintenanceworkorders/{}/cancel":   entity("MaintenanceWorkOrder", repository.MaintenanceWorkOrderResource, repos.Maintenance.ListWorkOrders),
+		"/kilncharges":                       entity("KilnCharge", repository.KilnChargeResource, repos.Kilns.ListKilnCharges),
+		"/kilncharges/{}/readings":           entity("KilnCharge", repository.KilnChargeResource, repos.Kilns.ListKilnCharges),
+		"/kilncharges/{}/unload":             entity("KilnCharge", repository.KilnChargeResource, repos.Kilns.ListKilnCharges),
+		"/wasterecords":                      entity("WasteRecord", repository.WasteRecordResource, repos.Processing.ListWasteRecords),
+		"/wasterecords/{}/valorise":          entity("WasteRecord", repository.WasteRecordResource, repos.Processing.ListWasteRecords),
+
+		// ==================== QUALITY CONTROL ====================
+		"/qualityinspections":             entity("QualityInspection", repository.QualityInspectionResource, repos.Quality.ListQualityInspections),
+		"/defectcodes":                    entity("DefectCode", repository.DefectCodeResource, repos.Quality.ListDefectCodes),
+		"/inspectiontemplates":            entity("InspectionTemplate", repository.InspectionTemplateResource, repos.Quality.ListInspectionTemplates),
+		"/nonconformances":                entity("NonConformance", repository.NonConformanceResource, repos.Quality.ListNonConformances),
+		"/nonconformances/{}/investigate": entity("NonConformance", repository.NonConformanceResource, repos.Quality.ListNonConformances),
+		"/nonconformances/{}/act":         entity("NonConformance", repository.NonConformanceResource, repos.Quality.ListNonConformances),
+		"/nonconformances/{}/verify":      entity("NonConformance", repository.NonConformanceResource, repos.Quality.ListNonConformances),
+		"/nonconformances/{}/close":       entity("NonConformance", repository.NonConformanceResource, repos.Quality.ListNonConformances),
+		"/nonconformances/{}/actions":     entity("CorrectiveAction", repository.CorrectiveActionResource, repos.Quality.ListCorrectiveActions),
+		"/correctiveactions/{}/complete":  entity("CorrectiveAction", repository.CorrectiveActionResource, repos.Quality.ListCorrectiveActions),
+		"/spc/alerts":                     activeQualityAlerts(repos.Quality),
+		"/qualityalerts/{}/resolve":       entity("QualityAlert", repository.QualityAlertResource, repos.Quality.ListQualityAlerts),
+		"/samplingplans":                  entity("SamplingPlan", repository.SamplingPlanResource, repos.Quality.ListSamplingPlans),
+		"/inspectionlots":                 entity("InspectionLot", repository.InspectionLotResource, repos.Quality.ListInspectionLots),
+
+		// ==================== WAREHOUSE & INVENTORY ====================
+		"/warehouses":                       entity("Warehouse", repository.WarehouseResource, repos.Warehouses.ListWarehouses),
+		"/producttypes":                     entity("ProductType", repository.ProductTypeResource, repos.Warehouses.ListProductTypes),
+		"/stockitems":                       entity("StockItem", repository.StockItemResource, repos.Stock.ListStockItems),
+		"/stockitems/{}/dispositions":       entity("StockItem", repository.StockItemResource, repos.Stock.ListStockItems),
+		"/stockalerts":                      entity("StockAlert", repository.StockAlertResource, repos.Stock.ListStockAlerts),
+		"/inventorytransactions":            entity("InventoryTransaction", repository.InventoryTransactionResource, repos.Stock.ListInventoryTransactions),
+		"/inventorytransactions/{}/reverse": created(entity("InventoryTransaction", repository.InventoryTransactionResource, repos.Stock.ListInventoryTransactions)),
+		"/transfers":                        entity("StockTransfer", repository.StockTransferResource, repos.Transfers.ListTransfers),
+		"/transfers/{}/dispatch":            entity("StockTransfer", repository.StockTransferResource, repos.Transfers.ListTransfers),
+		"/transfers/{}/transit":             entity("StockTransfer", repository.StockTransferResource, repos.Transfers.ListTransfers),
+		"/transfers/{}/receive":             entity("StockTransfer", repository.StockTransferResource, repos.Transfers.ListTransfers),
+
+		// ==================== PROCUREMENT ====================
+		"/purchaseorders":          entity("PurchaseOrder", repository.PurchaseOrderResource, repos.PurchaseOrders.ListPurchaseOrders),
+		"/purchaseorders/{}/items": entity("PurchaseOrderItem", repository.PurchaseOrderItemResource, repos.PurchaseOrders.ListPurchaseOrderItems),
+
+		// ==================== SALES & CUSTOMERS ====================
+		"/customers":            entity("Customer", repository.CustomerResource, repos.Customers.ListCustomers),
+		"/salesorders":          entity("SalesOrder", repository.SalesOrderResource, repos.SalesOrders.ListSalesOrders),
+		"/salesorders/{}/items": entity("SalesOrderItem", repository.SalesOrderItemResource, repos.SalesOrders.ListSalesOrderItems),
+
+		// ==================== INVOICING & PAYMENTS ====================
+		"/invoices": entity("Invoice", repository.InvoiceResource, repos.Invoices.ListInvoices),
+		"/payments": entity("Payment", repository.PaymentResource, repos.Invoices.ListPayments),
+
+		// ==================== TRANSPORTATION ====================
+		"/transportcompanies": entity("TransportCompany", repository.TransportCompanyResource, repos.Transport.ListTransportCompanies),
+		"/trucks":             entity("Truck", repository.TruckResource, repos.Transport.ListTrucks),
+		"/drivers":            entity("Driver", repository.DriverResource, repos.Transport.ListDrivers),
+		"/routes":             entity("Route", repository.RouteResource, repos.Transport.ListRoutes),
+		"/shipments":          entity("Shipment", repository.ShipmentResource, repos.Transport.ListShipments),
+		"/fuellogs":           entity("FuelLog", repository.FuelLogResource, repos.Transport.ListFuelLogs),
+	}
+}
+
+// auditor wraps the handlers of mutating routes with middleware.Audit
+type auditor struct {
+	repos    repository.Repositories
+	entities map[string]middleware.AuditEntity
+}
+
+func newAuditor(repos repository.Repositories) auditor {
+	return auditor{repos: repos, entities: auditEntities(repos)}
+}
+
+// wrap audits handler when pattern, a legacy or /api/v2 route, changes an
+// audited entity; other routes are returned unchanged
+func (a auditor) wrap(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	if successor, ok := legacySuccessors[pattern]; ok {
+		pattern = successor
+	}
+	entity, ok := a.entities[auditPath(pattern)]
+	if !ok {
+		return handler
+	}
+	return middleware.Audit(a.repos.Tx, a.repos.Audit, entity, handler)
+}
+
+var (
+	trailingParams = regexp.MustCompile(`(/\{[^}]*\})+$`)
+	innerParam     = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// auditPath reduces a route pattern to its collection, so members and the
+// legacy aliases share an entity: /api/v2/purchaseorders/{poid}/items/{id}
+// becomes /purchaseorders/{}/items
+func auditPath(pattern string) string {
+	pattern = trailingParams.ReplaceAllString(strings.TrimPrefix(pattern, "/api/v2"), "")
+	return innerParam.ReplaceAllString(pattern, "{}")
+}
+
+// entity audits the rows of a list endpoint, loading one by filtering the
+// list on the resource key
+func entity[T any](name string, res query.Resource,
+	list func(context.Context, query.Spec) (query.Page[T], error)) middleware.AuditEntity {
+	return middleware.AuditEntity{
+		Name: name,
+		Key:  res.Key,
+		ID:   keyParams(res.Key),
+		Load: func(ctx context.Context, id string) (interface{}, error) {
+			parts := strings.Split(id, "/")
+			if len(parts) != len(res.Key) {
+				return nil, nil
+			}
+			spec := query.Spec{Limit: 1}
+			for i, part := range parts {
+				value, err := strconv.Atoi(part)
+				if err != nil {
+					return nil, nil
+				}
+				spec.Where(res.Key[i], value)
+			}
+			page, err := list(ctx, spec)
+			if err != nil || len(page.Data) == 0 {
+				return nil, err
+			}
+			return page.Data[0], nil
+		},
+	}
+}
+
+// created audits a POST to a member route, such as a reversal, as the
+// creation of the row it answers with rather than a change to the member
+func created(e middleware.AuditEntity) middleware.AuditEntity {
+	e.ID = func(*http.Request) string { return "" }
+	return e
+}
+
+// grantsOfRole audits replacing the permissions of a role as one change to
+// the role's set of grants
+func grantsOfRole(access repository.AccessRepository) middleware.AuditEntity {
+	return middleware.AuditEntity{
+		Name: "RolePermission",
+		Key:  []string{"role_id"},
+		ID:   keyParams([]string{"role_id"}),
+		Load: func(ctx context.Context, id string) (interface{}, error) {
+			roleID, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, nil
+			}
+			return access.ListRolePermissionsByRole(ctx, roleID)
+		},
+	}
+}
+
+// slotsOf audits a scheduling run of a sawmill as the change it made to the
+// slots of the orders still to be processed on its units, keyed by
+// processing_id
+func slotsOf(orders repository.ProcessingRepository) middleware.AuditEntity {
+	type slot struct {
+		UnitID         int     `json:"unit_id"`
+		ScheduledStart *string `json:"scheduled_start"`
+		ScheduledEnd   *string `json:"scheduled_end"`
+	}
+	return middleware.AuditEntity{
+		Name: "ProcessingOrder",
+		Key:  []string{"sawmill_id"},
+		ID:   keyParams([]string{"sawmill_id"}),
+		Load: func(ctx context.Context, id string) (interface{}, error) {
+			sawmillID, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, nil
+			}
+			var unitSpec query.Spec
+			unitSpec.Where("sawmill_id", sawmillID)
+			units, err := orders.ListProcessingUnits(ctx, unitSpec)
+			if err != nil {
+				return nil, err
+			}
+			slots := map[string]slot{}
+			if len(units.Data) == 0 {
+				return slots, nil
+			}
+			unitIDs := make([]interface{}, len(units.Data))
+			for i, pu := range units.Data {
+				unitIDs[i] = pu.UnitID
+			}
+			var spec query.Spec
+			spec.WhereIn("status", processing.StatusPlanned, processing.StatusReleased)
+			spec.WhereIn("unit_id", unitIDs...)
+			page, err := orders.ListProcessingOrders(ctx, spec)
+			if err != nil {
+				return nil, err
+			}
+			for _, po := range page.Data {
+				slots[strconv.Itoa(po.ProcessingID)] = slot{po.UnitID, po.ScheduledStart, po.ScheduledEnd}
+			}
+			return slots, nil
+		},
+	}
+}
+
+// openWorkOrders audits a run of the maintenance plans as the work orders
+// open after it on the plans it raised one for, keyed by work_order_id
+func openWorkOrders(plans repository.MaintenanceRepository) middleware.AuditEntity {
+	return middleware.AuditEntity{
+		Name: "MaintenanceWorkOrder",
+		Key:  []string{"plan_id"},
+		ID:   func(*http.Request) string { return "" },
+		Load: func(ctx context.Context, id string) (interface{}, error) {
+			planIDs, ok := idList(id)
+			if !ok {
+				return nil, nil
+			}
+			var spec query.Spec
+			spec.Where("status", maintenance.StatusOpen)
+			spec.WhereIn("plan_id", planIDs...)
+			page, err := plans.ListWorkOrders(ctx, spec)
+			if err != nil {
+				return nil, err
+			}
+			open := map[string]models.MaintenanceWorkOrder{}
+			for _, wo := range page.Data {
+				open[strconv.Itoa(wo.WorkOrderID)] = wo
+			}
+			return open, nil
+		},
+	}
+}
+
+// activeQualityAlerts audits raising SPC alerts as the change to the active
+// alerts of the inspections it raised one on, keyed by alert_id
+func activeQualityAlerts(quality repository.QualityRepository) middleware.AuditEntity {
+	return middleware.AuditEntity{
+		Name: "QualityAlert",
+		Key:  []string{"inspection_id"},
+		ID:   func(*http.Request) string { return "" },
+		Load: func(ctx context.Context, id string) (interface{}, error) {
+			inspectionIDs, ok := idList(id)
+			if !ok {
+				return nil, nil
+			}
+			var spec query.Spec
+			spec.Where("status", spc.AlertActive)
+			spec.WhereIn("inspection_id", inspectionIDs...)
+			page, err := quality.ListQualityAlerts(ctx, spec)
+			if err != nil {
+				return nil, err
+			}
+			active := map[string]models.QualityAlert{}
+			for _, qa := range page.Data {
+				active[strconv.Itoa(qa.AlertID)] = qa
+			}
+			return active, nil
+		},
+	}
+}
+
+// idList reads the comma-separated IDs of the rows a batch run answered with
+func idList(id string) ([]interface{}, bool) {
+	parts := strings.Split(id, ",")
+	ids := make([]interface{}, len(parts))
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		ids[i] = value
+	}
+	return ids, true
+}
+
+// keyParams reads a row's key from the path or, on legacy routes, the query
+// string. A single-field key is also accepted as id.
+func keyParams(key []string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		param := func(name string) string {
+			if value := router.Param(r, name); value != "" {
+				return value
+			}
+			return r.URL.Query().Get(name)
+		}
+		parts := make([]string, len(key))
+		for i, name := range key {
+			parts[i] = param(name)
+			if parts[i] == "" && len(key) == 1 {
+				parts[i] = param("id")
+			}
+			if parts[i] == "" {
+				return ""
+			}
+		}
+		return strings.Join(parts, "/")
+	}
+}
@@ -0,0 +1,140 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// legacySuccessors maps each deprecated /api route to its /api/v2
+// replacement. Placeholders are filled from the request's query string.
+var legacySuccessors = map[string]string{
+	"/api/auth/login":   "/api/v2/auth/login",
+	"/api/auth/logout":  "/api/v2/auth/logout",
+	"/api/auth/refresh": "/api/v2/auth/refresh",
+	"/api/auth/me":      "/api/v2/auth/me",
+
+	"/api/users":         "/api/v2/users",
+	"/api/user":          "/api/v2/users/{id}",
+	"/api/user/password": "/api/v2/users/{id}/password",
+	"/api/permissions":   "/api/v2/permissions",
+	"/api/permission":    "/api/v2/permissions/{id}",
+	"/api/roles":         "/api/v2/roles",
+	"/api/role":          "/api/v2/roles/{id}",
+
+	"/api/rolepermissions":            "/api/v2/rolepermissions",
+	"/api/rolepermissions/role":       "/api/v2/roles/{id}/permissions",
+	"/api/rolepermissions/permission": "/api/v2/permissions/{id}/roles",
+	"/api/rolepermissions/assign":     "/api/v2/roles/{role_id}/permissions",
+	"/api/rolepermission":             "/api/v2/rolepermissions/{role_id}/{permission_id}",
+
+	"/api/employees":          "/api/v2/employees",
+	"/api/employee":           "/api/v2/employees/{id}",
+	"/api/workerassignments":  "/api/v2/workerassignments",
+	"/api/workerassignment":   "/api/v2/workerassignments/{id}",
+	"/api/managementinsights": "/api/v2/managementinsights",
+	"/api/managementinsight":  "/api/v2/managementinsights/{id}",
+
+	"/api/suppliers":            "/api/v2/suppliers",
+	"/api/supplier":             "/api/v2/suppliers/{id}",
+	"/api/supplierperformances": "/api/v2/supplierperformances",
+	"/api/supplierperformance":  "/api/v2/supplierperformances/{id}",
+	"/api/suppliercontracts":    "/api/v2/suppliercontracts",
+	"/api/suppliercontract":     "/api/v2/suppliercontracts/{id}",
+
+	"/api/forests":          "/api/v2/forests",
+	"/api/forest":           "/api/v2/forests/{id}",
+	"/api/treespecies":      "/api/v2/treespecies",
+	"/api/treespecies-item": "/api/v2/treespecies/{id}",
+	"/api/harvestschedules": "/api/v2/harvestschedules",
+	"/api/harvestschedule":  "/api/v2/harvestschedules/{id}",
+	"/api/harvestbatches":   "/api/v2/harvestbatches",
+	"/api/harvestbatch":     "/api/v2/harvestbatches/{id}",
+
+	"/api/sawmills":           "/api/v2/sawmills",
+	"/api/sawmill":            "/api/v2/sawmills/{id}",
+	"/api/processingunits":    "/api/v2/processingunits",
+	"/api/processingunit":     "/api/v2/processingunits/{id}",
+	"/api/processingorders":   "/api/v2/processingorders",
+	"/api/processingorder":    "/api/v2/processingorders/{id}",
+	"/api/maintenancerecords": "/api/v2/maintenancerecords",
+	"/api/maintenancerecord":  "/api/v2/maintenancerecords/{id}",
+	"/api/wasterecords":       "/api/v2/wasterecords",
+	"/api/wasterecord":        "/api/v2/wasterecords/{id}",
+
+	"/api/qualityinspections": "/api/v2/qualityinspections",
+	"/api/qualityinspection":  "/api/v2/qualityinspections/{id}",
+
+	"/api/warehouses":                   "/api/v2/warehouses",
+	"/api/warehouse":                    "/api/v2/warehouses/{id}",
+	"/api/producttypes":                 "/api/v2/producttypes",
+	"/api/producttype":                  "/api/v2/producttypes/{id}",
+	"/api/stockitems":                   "/api/v2/stockitems",
+	"/api/stockitem":                    "/api/v2/stockitems/{id}",
+	"/api/stockalerts":                  "/api/v2/stockalerts",
+	"/api/stockalert":                   "/api/v2/stockalerts/{id}",
+	"/api/inventorytransactions":        "/api/v2/inventorytransactions",
+	"/api/inventorytransaction/reverse": "/api/v2/inventorytransactions/{id}/reverse",
+
+	"/api/purchaseorders":     "/api/v2/purchaseorders",
+	"/api/purchaseorder":      "/api/v2/purchaseorders/{id}",
+	"/api/purchaseorderitems": "/api/v2/purchaseorders/{poid}/items",
+	"/api/purchaseorderitem":  "/api/v2/purchaseorders/{poid}/items/{id}",
+
+	"/api/customers":       "/api/v2/customers",
+	"/api/customer":        "/api/v2/customers/{id}",
+	"/api/salesorders":     "/api/v2/salesorders",
+	"/api/salesorder":      "/api/v2/salesorders/{id}",
+	"/api/salesorderitems": "/api/v2/salesorders/{soid}/items",
+	"/api/salesorderitem":  "/api/v2/salesorders/{soid}/items/{id}",
+
+	"/api/invoices": "/api/v2/invoices",
+	"/api/invoice":  "/api/v2/invoices/{id}",
+	"/api/payments": "/api/v2/payments",
+	"/api/payment":  "/api/v2/payments/{id}",
+
+	"/api/transportcompanies": "/api/v2/transportcompanies",
+	"/api/transportcompany":   "/api/v2/transportcompanies/{id}",
+	"/api/trucks":             "/api/v2/trucks",
+	"/api/truck":              "/api/v2/trucks/{id}",
+	"/api/drivers":            "/api/v2/drivers",
+	"/api/driver":             "/api/v2/drivers/{id}",
+	"/api/routes":             "/api/v2/routes",
+	"/api/route":              "/api/v2/routes/{id}",
+	"/api/shipments":          "/api/v2/shipments",
+	"/api/shipment":           "/api/v2/shipments/{id}",
+	"/api/fuellogs":           "/api/v2/fuellogs",
+	"/api/fuellog":            "/api/v2/fuellogs/{id}",
+
+	"/api/auditlogs":        "/api/v2/auditlogs",
+	"/api/auditlogs/verify": "/api/v2/auditlogs/verify",
+}
+
+// legacyMux marks every route with a /api/v2 successor as deprecated, so
+// the React frontend keeps working while clients migrate
+type legacyMux struct {
+	Router
+}
+
+func (m legacyMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	if successor, ok := legacySuccessors[pattern]; ok {
+		handler = deprecated(successor, handler)
+	}
+	m.Router.HandleFunc(pattern, handler)
+}
+
+// deprecated adds the Deprecation header and a successor-version Link,
+// filling {name} placeholders from the query string where present
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		link := successor
+		for name, values := range r.URL.Query() {
+			if len(values) > 0 && values[0] != "" {
+				link = strings.ReplaceAll(link, "{"+name+"}", url.PathEscape(values[0]))
+			}
+		}
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
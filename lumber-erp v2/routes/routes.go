@@ -2,15 +2,90 @@ package routes
 
 import (
 	"net/http"
+	"strings"
 
 	"lumber-erp-api/handlers"
+	"lumber-erp-api/middleware"
+	"lumber-erp-api/repository"
 	"lumber-erp-api/utils"
 )
 
-// SetupRoutes configures all API routes
-func SetupRoutes() {
+// Permission modules guarding each route group. The names match the
+// Permission.ModuleName values managed from the Permissions screen.
+const (
+	ModuleUsers       = "User Management"
+	ModuleEmployees   = "HR & Employees"
+	ModuleSuppliers   = "Suppliers"
+	ModuleForest      = "Forest & Harvesting"
+	ModuleProcessing  = "Processing & Sawmill"
+	ModuleQuality     = "Quality Control"
+	ModuleWarehouse   = "Warehouse & Inventory"
+	ModuleProcurement = "Procurement"
+	ModuleSales       = "Sales & Customers"
+	ModuleFinancial   = "Invoicing & Payments"
+	ModuleTransport   = "Transportation"
+	ModuleAudit       = "Audit & Logs"
+)
+
+// Router is the part of *http.ServeMux that SetupRoutes needs, so tests can
+// record the registered patterns
+type Router interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// SetupRoutes configures all API routes on mux, serving them from repos
+func SetupRoutes(mux Router, repos repository.Repositories) {
+	authH := handlers.NewAuthHandler(repos.Users, repos.Sessions, repos.Access)
+	users := handlers.NewUserHandler(repos.Users, repos.Access)
+	employees := handlers.NewEmployeeHandler(repos.Employees)
+	suppliers := handlers.NewSupplierHandler(repos.Suppliers)
+	forests := handlers.NewForestHandler(repos.Forests)
+	processing := handlers.NewProcessingHandler(repos)
+	quality := handlers.NewQualityHandler(repos)
+	reports := handlers.NewNCRHandler(repos)
+	charts := handlers.NewSPCHandler(repos)
+	plans := handlers.NewSamplingHandler(repos)
+	warehouses := handlers.NewWarehouseHandler(repos)
+	procurement := handlers.NewProcurementHandler(repos.PurchaseOrders)
+	sales := handlers.NewSalesHandler(repos)
+	financial := handlers.NewFinancialHandler(repos.Invoices)
+	transport := handlers.NewTransportHandler(repos)
+	stockTransfers := handlers.NewTransferHandler(repos)
+	lots := handlers.NewTraceHandler(repos)
+	schedules := handlers.NewScheduleHandler(repos)
+	upkeep := handlers.NewMaintenanceHandler(repos)
+	kilns := handlers.NewKilnHandler(repos)
+	byProducts := handlers.NewWasteHandler(repos)
+	holds := handlers.NewQuarantineHandler(repos)
+	audit := handlers.NewAuditHandler(repos.Audit)
+	audited := newAuditor(repos)
+
+	// ==================== API V2 ====================
+	api, modules := newV2Router(v2Handlers{
+		auth: authH, users: users, employees: employees, suppliers: suppliers,
+		forests: forests, processing: processing, quality: quality, ncr: reports, spc: charts,
+		sampling: plans, warehouses: warehouses, procurement: procurement, sales: sales,
+		financial: financial, transport: transport, transfers: stockTransfers, trace: lots,
+		schedules: schedules, maintenance: upkeep, kilns: kilns, waste: byProducts, quarantine: holds,
+		audit: audit,
+	}, repos.Sessions, repos.Access, audited)
+	mux.HandleFunc("/api/v2/", api.ServeHTTP)
+
+	// The ?id= routes below are the deprecated aliases of /api/v2
+	mux = legacyMux{mux}
+
+	// handle registers a route guarded by the permission module of its
+	// /api/v2 successor and audits the changes it makes
+	handle := func(pattern string, handler http.HandlerFunc) {
+		module, ok := modules[modulePath(legacySuccessors[pattern])]
+		if !ok {
+			panic("routes: no /api/v2 successor for " + pattern)
+		}
+		mux.HandleFunc(pattern, middleware.RequirePermission(repos.Sessions, repos.Access, module, audited.wrap(pattern, handler)))
+	}
+
 	// Health check
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		utils.EnableCORS(&w)
 		utils.RespondJSON(w, http.StatusOK, map[string]string{
 			"message": "🌲 Lumber ERP API v2.0",
@@ -19,178 +94,186 @@ func SetupRoutes() {
 		})
 	})
 
+	// ==================== AUTHENTICATION ====================
+	mux.HandleFunc("/api/auth/login", HandleRequest(nil, authH.Login, nil, nil))
+	mux.HandleFunc("/api/auth/logout", HandleRequest(nil, authH.Logout, nil, nil))
+	mux.HandleFunc("/api/auth/refresh", HandleRequest(nil, authH.RefreshToken, nil, nil))
+	mux.HandleFunc("/api/auth/me", middleware.RequireAuth(repos.Sessions, HandleRequest(authH.GetCurrentUser, nil, nil, nil)))
+
 	// ==================== USER MANAGEMENT ====================
-	http.HandleFunc("/api/users", HandleRequest(handlers.GetUsers, handlers.CreateUser, nil, nil))
-	http.HandleFunc("/api/user", HandleRequest(handlers.GetUser, nil, handlers.UpdateUser, handlers.DeleteUser))
-	
-	http.HandleFunc("/api/permissions", HandleRequest(handlers.GetPermissions, handlers.CreatePermission, nil, nil))
-	http.HandleFunc("/api/permission", HandleRequest(nil, nil, handlers.UpdatePermission, handlers.DeletePermission))
-	
-	http.HandleFunc("/api/roles", HandleRequest(handlers.GetRoles, handlers.CreateRole, nil, nil))
-	http.HandleFunc("/api/role", HandleRequest(nil, nil, handlers.UpdateRole, handlers.DeleteRole))
+	handle("/api/users", HandleRequest(users.GetUsers, users.CreateUser, nil, nil))
+	handle("/api/user", HandleRequest(users.GetUser, nil, users.UpdateUser, users.DeleteUser))
+	mux.HandleFunc("/api/user/password", middleware.RequireAuth(repos.Sessions, audited.wrap("/api/user/password", HandleRequest(nil, nil, authH.ChangePassword, nil))))
+
+	handle("/api/permissions", HandleRequest(users.GetPermissions, users.CreatePermission, nil, nil))
+	handle("/api/permission", HandleRequest(nil, nil, users.UpdatePermission, users.DeletePermission))
+
+	handle("/api/roles", HandleRequest(users.GetRoles, users.CreateRole, nil, nil))
+	handle("/api/role", HandleRequest(nil, nil, users.UpdateRole, users.DeleteRole))
 
 	// ==================== HR & EMPLOYEES ====================
-	http.HandleFunc("/api/employees", HandleRequest(handlers.GetEmployees, handlers.CreateEmployee, nil, nil))
-	http.HandleFunc("/api/employee", HandleRequest(nil, nil, handlers.UpdateEmployee, handlers.DeleteEmployee))
-	
-	http.HandleFunc("/api/workerassignments", HandleRequest(handlers.GetWorkerAssignments, handlers.CreateWorkerAssignment, nil, nil))
-	http.HandleFunc("/api/workerassignment", HandleRequest(nil, nil, handlers.UpdateWorkerAssignment, handlers.DeleteWorkerAssignment))
-	
-	http.HandleFunc("/api/managementinsights", HandleRequest(handlers.GetManagementInsights, handlers.CreateManagementInsights, nil, nil))
-	http.HandleFunc("/api/managementinsight", HandleRequest(nil, nil, handlers.UpdateManagementInsights, handlers.DeleteManagementInsights))
+	handle("/api/employees", HandleRequest(employees.GetEmployees, employees.CreateEmployee, nil, nil))
+	handle("/api/employee", HandleRequest(nil, nil, employees.UpdateEmployee, employees.DeleteEmployee))
+
+	handle("/api/workerassignments", HandleRequest(employees.GetWorkerAssignments, employees.CreateWorkerAssignment, nil, nil))
+	handle("/api/workerassignment", HandleRequest(nil, nil, employees.UpdateWorkerAssignment, employees.DeleteWorkerAssignment))
+
+	handle("/api/managementinsights", HandleRequest(employees.GetManagementInsights, employees.CreateManagementInsights, nil, nil))
+	handle("/api/managementinsight", HandleRequest(nil, nil, employees.UpdateManagementInsights, employees.DeleteManagementInsights))
 
 	// ==================== SUPPLIERS ====================
-	http.HandleFunc("/api/suppliers", HandleRequest(handlers.GetSuppliers, handlers.CreateSupplier, nil, nil))
-	http.HandleFunc("/api/supplier", HandleRequest(nil, nil, handlers.UpdateSupplier, handlers.DeleteSupplier))
-	
-	http.HandleFunc("/api/supplierperformances", HandleRequest(handlers.GetSupplierPerformances, handlers.CreateSupplierPerformance, nil, nil))
-	http.HandleFunc("/api/supplierperformance", HandleRequest(nil, nil, handlers.UpdateSupplierPerformance, handlers.DeleteSupplierPerformance))
-	
-	http.HandleFunc("/api/suppliercontracts", HandleRequest(handlers.GetSupplierContracts, handlers.CreateSupplierContract, nil, nil))
-	http.HandleFunc("/api/suppliercontract", HandleRequest(nil, nil, handlers.UpdateSupplierContract, handlers.DeleteSupplierContract))
+	handle("/api/suppliers", HandleRequest(suppliers.GetSuppliers, suppliers.CreateSupplier, nil, nil))
+	handle("/api/supplier", HandleRequest(nil, nil, suppliers.UpdateSupplier, suppliers.DeleteSupplier))
+
+	handle("/api/supplierperformances", HandleRequest(suppliers.GetSupplierPerformances, suppliers.CreateSupplierPerformance, nil, nil))
+	handle("/api/supplierperformance", HandleRequest(nil, nil, suppliers.UpdateSupplierPerformance, suppliers.DeleteSupplierPerformance))
+
+	handle("/api/suppliercontracts", HandleRequest(suppliers.GetSupplierContracts, suppliers.CreateSupplierContract, nil, nil))
+	handle("/api/suppliercontract", HandleRequest(nil, nil, suppliers.UpdateSupplierContract, suppliers.DeleteSupplierContract))
 
 	// ==================== FOREST & HARVESTING ====================
-	http.HandleFunc("/api/forests", HandleRequest(handlers.GetForests, handlers.CreateForest, nil, nil))
-	http.HandleFunc("/api/forest", HandleRequest(nil, nil, handlers.UpdateForest, handlers.DeleteForest))
-	
-	http.HandleFunc("/api/treespecies", HandleRequest(handlers.GetTreeSpecies, handlers.CreateTreeSpecies, nil, nil))
-	http.HandleFunc("/api/treespecies-item", HandleRequest(nil, nil, handlers.UpdateTreeSpecies, handlers.DeleteTreeSpecies))
-	
-	http.HandleFunc("/api/harvestschedules", HandleRequest(handlers.GetHarvestSchedules, handlers.CreateHarvestSchedule, nil, nil))
-	http.HandleFunc("/api/harvestschedule", HandleRequest(nil, nil, handlers.UpdateHarvestSchedule, handlers.DeleteHarvestSchedule))
-	
-	http.HandleFunc("/api/harvestbatches", HandleRequest(handlers.GetHarvestBatches, handlers.CreateHarvestBatch, nil, nil))
-	http.HandleFunc("/api/harvestbatch", HandleRequest(nil, nil, handlers.UpdateHarvestBatch, handlers.DeleteHarvestBatch))
+	handle("/api/forests", HandleRequest(forests.GetForests, forests.CreateForest, nil, nil))
+	handle("/api/forest", HandleRequest(nil, nil, forests.UpdateForest, forests.DeleteForest))
+
+	handle("/api/treespecies", HandleRequest(forests.GetTreeSpecies, forests.CreateTreeSpecies, nil, nil))
+	handle("/api/treespecies-item", HandleRequest(nil, nil, forests.UpdateTreeSpecies, forests.DeleteTreeSpecies))
+
+	handle("/api/harvestschedules", HandleRequest(forests.GetHarvestSchedules, forests.CreateHarvestSchedule, nil, nil))
+	handle("/api/harvestschedule", HandleRequest(nil, nil, forests.UpdateHarvestSchedule, forests.DeleteHarvestSchedule))
+
+	handle("/api/harvestbatches", HandleRequest(forests.GetHarvestBatches, forests.CreateHarvestBatch, nil, nil))
+	handle("/api/harvestbatch", HandleRequest(nil, nil, forests.UpdateHarvestBatch, forests.DeleteHarvestBatch))
 
 	// ==================== PROCESSING & SAWMILL ====================
-	http.HandleFunc("/api/sawmills", HandleRequest(handlers.GetSawmills, handlers.CreateSawmill, nil, nil))
-	http.HandleFunc("/api/sawmill", HandleRequest(nil, nil, handlers.UpdateSawmill, handlers.DeleteSawmill))
-	
-	http.HandleFunc("/api/processingunits", HandleRequest(handlers.GetProcessingUnits, handlers.CreateProcessingUnit, nil, nil))
-	http.HandleFunc("/api/processingunit", HandleRequest(nil, nil, handlers.UpdateProcessingUnit, handlers.DeleteProcessingUnit))
-	
-	http.HandleFunc("/api/processingorders", HandleRequest(handlers.GetProcessingOrders, handlers.CreateProcessingOrder, nil, nil))
-	http.HandleFunc("/api/processingorder", HandleRequest(nil, nil, handlers.UpdateProcessingOrder, handlers.DeleteProcessingOrder))
-	
-	http.HandleFunc("/api/maintenancerecords", HandleRequest(handlers.GetMaintenanceRecords, handlers.CreateMaintenanceRecord, nil, nil))
-	http.HandleFunc("/api/maintenancerecord", HandleRequest(nil, nil, handlers.UpdateMaintenanceRecord, handlers.DeleteMaintenanceRecord))
-	
-	http.HandleFunc("/api/wasterecords", HandleRequest(handlers.GetWasteRecords, handlers.CreateWasteRecord, nil, nil))
-	http.HandleFunc("/api/wasterecord", HandleRequest(nil, nil, handlers.UpdateWasteRecord, handlers.DeleteWasteRecord))
+	handle("/api/sawmills", HandleRequest(processing.GetSawmills, processing.CreateSawmill, nil, nil))
+	handle("/api/sawmill", HandleRequest(nil, nil, processing.UpdateSawmill, processing.DeleteSawmill))
+
+	handle("/api/processingunits", HandleRequest(processing.GetProcessingUnits, processing.CreateProcessingUnit, nil, nil))
+	handle("/api/processingunit", HandleRequest(nil, nil, processing.UpdateProcessingUnit, processing.DeleteProcessingUnit))
+
+	handle("/api/processingorders", HandleRequest(processing.GetProcessingOrders, processing.CreateProcessingOrder, nil, nil))
+	handle("/api/processingorder", HandleRequest(nil, nil, processing.UpdateProcessingOrder, processing.DeleteProcessingOrder))
+
+	handle("/api/maintenancerecords", HandleRequest(processing.GetMaintenanceRecords, processing.CreateMaintenanceRecord, nil, nil))
+	handle("/api/maintenancerecord", HandleRequest(nil, nil, processing.UpdateMaintenanceRecord, processing.DeleteMaintenanceRecord))
+
+	handle("/api/wasterecords", HandleRequest(processing.GetWasteRecords, processing.CreateWasteRecord, nil, nil))
+	handle("/api/wasterecord", HandleRequest(nil, nil, processing.UpdateWasteRecord, processing.DeleteWasteRecord))
 
 	// ==================== QUALITY CONTROL ====================
-	http.HandleFunc("/api/qualityinspections", HandleRequest(handlers.GetQualityInspections, handlers.CreateQualityInspection, nil, nil))
-	http.HandleFunc("/api/qualityinspection", HandleRequest(nil, nil, handlers.UpdateQualityInspection, handlers.DeleteQualityInspection))
+	handle("/api/qualityinspections", HandleRequest(quality.GetQualityInspections, quality.CreateQualityInspection, nil, nil))
+	handle("/api/qualityinspection", HandleRequest(nil, nil, quality.UpdateQualityInspection, quality.DeleteQualityInspection))
 
 	// ==================== WAREHOUSE & INVENTORY ====================
-	http.HandleFunc("/api/warehouses", HandleRequest(handlers.GetWarehouses, handlers.CreateWarehouse, nil, nil))
-	http.HandleFunc("/api/warehouse", HandleRequest(nil, nil, handlers.UpdateWarehouse, handlers.DeleteWarehouse))
-	
-	http.HandleFunc("/api/producttypes", HandleRequest(handlers.GetProductTypes, handlers.CreateProductType, nil, nil))
-	http.HandleFunc("/api/producttype", HandleRequest(nil, nil, handlers.UpdateProductType, handlers.DeleteProductType))
-	
-	http.HandleFunc("/api/stockitems", HandleRequest(handlers.GetStockItems, handlers.CreateStockItem, nil, nil))
-	http.HandleFunc("/api/stockitem", HandleRequest(nil, nil, handlers.UpdateStockItem, handlers.DeleteStockItem))
-	
-	http.HandleFunc("/api/stockalerts", HandleRequest(handlers.GetStockAlerts, handlers.CreateStockAlert, nil, nil))
-	http.HandleFunc("/api/stockalert", HandleRequest(nil, nil, handlers.UpdateStockAlert, handlers.DeleteStockAlert))
-	
-	http.HandleFunc("/api/inventorytransactions", HandleRequest(handlers.GetInventoryTransactions, handlers.CreateInventoryTransaction, nil, nil))
-	http.HandleFunc("/api/inventorytransaction", HandleRequest(nil, nil, handlers.UpdateInventoryTransaction, handlers.DeleteInventoryTransaction))
+	handle("/api/warehouses", HandleRequest(warehouses.GetWarehouses, warehouses.CreateWarehouse, nil, nil))
+	handle("/api/warehouse", HandleRequest(nil, nil, warehouses.UpdateWarehouse, warehouses.DeleteWarehouse))
+
+	handle("/api/producttypes", HandleRequest(warehouses.GetProductTypes, warehouses.CreateProductType, nil, nil))
+	handle("/api/producttype", HandleRequest(nil, nil, warehouses.UpdateProductType, warehouses.DeleteProductType))
+
+	handle("/api/stockitems", HandleRequest(warehouses.GetStockItems, warehouses.CreateStockItem, nil, nil))
+	handle("/api/stockitem", HandleRequest(nil, nil, warehouses.UpdateStockItem, warehouses.DeleteStockItem))
+
+	handle("/api/stockalerts", HandleRequest(warehouses.GetStockAlerts, warehouses.CreateStockAlert, nil, nil))
+	handle("/api/stockalert", HandleRequest(nil, nil, warehouses.UpdateStockAlert, warehouses.DeleteStockAlert))
+
+	handle("/api/inventorytransactions", HandleRequest(warehouses.GetInventoryTransactions, warehouses.CreateInventoryTransaction, nil, nil))
+	handle("/api/inventorytransaction/reverse", HandleRequest(nil, warehouses.ReverseInventoryTransaction, nil, nil))
 
 	// ==================== PROCUREMENT ====================
-	http.HandleFunc("/api/purchaseorders", HandleRequest(handlers.GetPurchaseOrders, handlers.CreatePurchaseOrder, nil, nil))
-	http.HandleFunc("/api/purchaseorder", HandleRequest(nil, nil, handlers.UpdatePurchaseOrder, handlers.DeletePurchaseOrder))
-	
-	http.HandleFunc("/api/purchaseorderitems", HandleRequest(handlers.GetPurchaseOrderItems, handlers.CreatePurchaseOrderItem, nil, nil))
-	http.HandleFunc("/api/purchaseorderitem", HandleRequest(nil, nil, handlers.UpdatePurchaseOrderItem, handlers.DeletePurchaseOrderItem))
+	handle("/api/purchaseorders", HandleRequest(procurement.GetPurchaseOrders, procurement.CreatePurchaseOrder, nil, nil))
+	handle("/api/purchaseorder", HandleRequest(nil, nil, procurement.UpdatePurchaseOrder, procurement.DeletePurchaseOrder))
+
+	handle("/api/purchaseorderitems", HandleRequest(procurement.GetPurchaseOrderItems, procurement.CreatePurchaseOrderItem, nil, nil))
+	handle("/api/purchaseorderitem", HandleRequest(nil, nil, procurement.UpdatePurchaseOrderItem, procurement.DeletePurchaseOrderItem))
 
 	// ==================== SALES & CUSTOMERS ====================
-	http.HandleFunc("/api/customers", HandleRequest(handlers.GetCustomers, handlers.CreateCustomer, nil, nil))
-	http.HandleFunc("/api/customer", HandleRequest(nil, nil, handlers.UpdateCustomer, handlers.DeleteCustomer))
-	
-	http.HandleFunc("/api/salesorders", HandleRequest(handlers.GetSalesOrders, handlers.CreateSalesOrder, nil, nil))
-	http.HandleFunc("/api/salesorder", HandleRequest(nil, nil, handlers.UpdateSalesOrder, handlers.DeleteSalesOrder))
-	
-	http.HandleFunc("/api/salesorderitems", HandleRequest(handlers.GetSalesOrderItems, handlers.CreateSalesOrderItem, nil, nil))
-	http.HandleFunc("/api/salesorderitem", HandleRequest(nil, nil, handlers.UpdateSalesOrderItem, handlers.DeleteSalesOrderItem))
+	handle("/api/customers", HandleRequest(sales.GetCustomers, sales.CreateCustomer, nil, nil))
+	handle("/api/customer", HandleRequest(nil, nil, sales.UpdateCustomer, sales.DeleteCustomer))
+
+	handle("/api/salesorders", HandleRequest(sales.GetSalesOrders, sales.CreateSalesOrder, nil, nil))
+	handle("/api/salesorder", HandleRequest(nil, nil, sales.UpdateSalesOrder, sales.DeleteSalesOrder))
+
+	handle("/api/salesorderitems", HandleRequest(sales.GetSalesOrderItems, sales.CreateSalesOrderItem, nil, nil))
+	handle("/api/salesorderitem", HandleRequest(nil, nil, sales.UpdateSalesOrderItem, sales.DeleteSalesOrderItem))
 
 	// ==================== INVOICING & PAYMENTS ====================
-	http.HandleFunc("/api/invoices", HandleRequest(handlers.GetInvoices, handlers.CreateInvoice, nil, nil))
-	http.HandleFunc("/api/invoice", HandleRequest(nil, nil, handlers.UpdateInvoice, handlers.DeleteInvoice))
-	
-	http.HandleFunc("/api/payments", HandleRequest(handlers.GetPayments, handlers.CreatePayment, nil, nil))
-	http.HandleFunc("/api/payment", HandleRequest(nil, nil, handlers.UpdatePayment, handlers.DeletePayment))
+	handle("/api/invoices", HandleRequest(financial.GetInvoices, financial.CreateInvoice, nil, nil))
+	handle("/api/invoice", HandleRequest(nil, nil, financial.UpdateInvoice, financial.DeleteInvoice))
+
+	handle("/api/payments", HandleRequest(financial.GetPayments, financial.CreatePayment, nil, nil))
+	handle("/api/payment", HandleRequest(nil, nil, financial.UpdatePayment, financial.DeletePayment))
 
 	// ==================== TRANSPORTATION ====================
-	http.HandleFunc("/api/transportcompanies", HandleRequest(handlers.GetTransportCompanies, handlers.CreateTransportCompany, nil, nil))
-	http.HandleFunc("/api/transportcompany", HandleRequest(nil, nil, handlers.UpdateTransportCompany, handlers.DeleteTransportCompany))
-	
-	http.HandleFunc("/api/trucks", HandleRequest(handlers.GetTrucks, handlers.CreateTruck, nil, nil))
-	http.HandleFunc("/api/truck", HandleRequest(nil, nil, handlers.UpdateTruck, handlers.DeleteTruck))
-	
-	http.HandleFunc("/api/drivers", HandleRequest(handlers.GetDrivers, handlers.CreateDriver, nil, nil))
-	http.HandleFunc("/api/driver", HandleRequest(nil, nil, handlers.UpdateDriver, handlers.DeleteDriver))
-	
-	http.HandleFunc("/api/routes", HandleRequest(handlers.GetRoutes, handlers.CreateRoute, nil, nil))
-	http.HandleFunc("/api/route", HandleRequest(nil, nil, handlers.UpdateRoute, handlers.DeleteRoute))
-	
-	http.HandleFunc("/api/shipments", HandleRequest(handlers.GetShipments, handlers.CreateShipment, nil, nil))
-	http.HandleFunc("/api/shipment", HandleRequest(nil, nil, handlers.UpdateShipment, handlers.DeleteShipment))
-	
-	http.HandleFunc("/api/fuellogs", HandleRequest(handlers.GetFuelLogs, handlers.CreateFuelLog, nil, nil))
-	http.HandleFunc("/api/fuellog", HandleRequest(nil, nil, handlers.UpdateFuelLog, handlers.DeleteFuelLog))
+	handle("/api/transportcompanies", HandleRequest(transport.GetTransportCompanies, transport.CreateTransportCompany, nil, nil))
+	handle("/api/transportcompany", HandleRequest(nil, nil, transport.UpdateTransportCompany, transport.DeleteTransportCompany))
 
-	// ==================== AUDIT & LOGS ====================
-	http.HandleFunc("/api/auditlogs", HandleRequest(handlers.GetAuditLogs, handlers.CreateAuditLog, nil, nil))
+	handle("/api/trucks", HandleRequest(transport.GetTrucks, transport.CreateTruck, nil, nil))
+	handle("/api/truck", HandleRequest(nil, nil, transport.UpdateTruck, transport.DeleteTruck))
+
+	handle("/api/drivers", HandleRequest(transport.GetDrivers, transport.CreateDriver, nil, nil))
+	handle("/api/driver", HandleRequest(nil, nil, transport.UpdateDriver, transport.DeleteDriver))
+
+	handle("/api/routes", HandleRequest(transport.GetRoutes, transport.CreateRoute, nil, nil))
+	handle("/api/route", HandleRequest(nil, nil, transport.UpdateRoute, transport.DeleteRoute))
 
-// ==================== ROLE PERMISSIONS ====================
-http.HandleFunc("/api/rolepermissions", HandleRequest(handlers.GetRolePermissions, handlers.CreateRolePermission, nil, nil))
-http.HandleFunc("/api/rolepermissions/role", HandleRequest(handlers.GetRolePermissionsByRole, nil, nil, nil))
-http.HandleFunc("/api/rolepermissions/permission", HandleRequest(handlers.GetRolePermissionsByPermission, nil, nil, nil))
-http.HandleFunc("/api/rolepermissions/assign", HandleRequest(nil, handlers.AssignPermissionsToRole, nil, nil))
-http.HandleFunc("/api/rolepermission", HandleRequest(nil, nil, nil, handlers.DeleteRolePermission))
-	// Add these routes to your router setup
+	handle("/api/shipments", HandleRequest(transport.GetShipments, transport.CreateShipment, nil, nil))
+	handle("/api/shipment", HandleRequest(nil, nil, transport.UpdateShipment, transport.DeleteShipment))
 
+	handle("/api/fuellogs", HandleRequest(transport.GetFuelLogs, transport.CreateFuelLog, nil, nil))
+	handle("/api/fuellog", HandleRequest(nil, nil, transport.UpdateFuelLog, transport.DeleteFuelLog))
+
+	// ==================== AUDIT & LOGS ====================
+	// Entries are written by the audit middleware only
+	handle("/api/auditlogs", HandleRequest(audit.GetAuditLogs, nil, nil, nil))
+	handle("/api/auditlogs/verify", HandleRequest(audit.VerifyAuditLogs, nil, nil, nil))
 
+	// ==================== ROLE PERMISSIONS ====================
+	handle("/api/rolepermissions", HandleRequest(users.GetRolePermissions, users.CreateRolePermission, nil, nil))
+	handle("/api/rolepermissions/role", HandleRequest(users.GetRolePermissionsByRole, nil, nil, nil))
+	handle("/api/rolepermissions/permission", HandleRequest(users.GetRolePermissionsByPermission, nil, nil, nil))
+	handle("/api/rolepermissions/assign", HandleRequest(nil, users.AssignPermissionsToRole, nil, nil))
+	handle("/api/rolepermission", HandleRequest(nil, nil, nil, users.DeleteRolePermission))
 }
 
-// HandleRequest is a helper function to handle multiple HTTP methods
+// HandleRequest is a helper function to handle multiple HTTP methods.
+// Methods without a handler answer 405 with an Allow header.
 func HandleRequest(getFn, postFn, putFn, deleteFn http.HandlerFunc) http.HandlerFunc {
+	var allowed []string
+	for _, fn := range []struct {
+		method  string
+		handler http.HandlerFunc
+	}{{"DELETE", deleteFn}, {"GET", getFn}, {"OPTIONS", nil}, {"POST", postFn}, {"PUT", putFn}} {
+		if fn.handler != nil || fn.method == "OPTIONS" {
+			allowed = append(allowed, fn.method)
+		}
+	}
+	allow := strings.Join(allowed, ", ")
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		utils.EnableCORS(&w)
 		if r.Method == "OPTIONS" {
+			w.Header().Set("Allow", allow)
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		var fn http.HandlerFunc
 		switch r.Method {
 		case "GET":
-			if getFn != nil {
-				getFn(w, r)
-			} else {
-				utils.RespondError(w, http.StatusMethodNotAllowed, "GET method not allowed")
-			}
+			fn = getFn
 		case "POST":
-			if postFn != nil {
-				postFn(w, r)
-			} else {
-				utils.RespondError(w, http.StatusMethodNotAllowed, "POST method not allowed")
-			}
+			fn = postFn
 		case "PUT":
-			if putFn != nil {
-				putFn(w, r)
-			} else {
-				utils.RespondError(w, http.StatusMethodNotAllowed, "PUT method not allowed")
-			}
+			fn = putFn
 		case "DELETE":
-			if deleteFn != nil {
-				deleteFn(w, r)
-			} else {
-				utils.RespondError(w, http.StatusMethodNotAllowed, "DELETE method not allowed")
-			}
-		default:
-			utils.RespondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			fn = deleteFn
+		}
+		if fn == nil {
+			w.Header().Set("Allow", allow)
+			utils.RespondError(w, http.StatusMethodNotAllowed, r.Method+" method not allowed")
+			return
 		}
+		fn(w, r)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,2094 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"lumber-erp-api/auditchain"
+	"lumber-erp-api/auth"
+	"lumber-erp-api/kiln"
+	"lumber-erp-api/middleware"
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/trace"
+	"lumber-erp-api/utils"
+	"lumber-erp-api/validate"
+)
+
+const (
+	adminEmail    = "admin@example.com"
+	adminPassword = "correct horse"
+	clerkEmail    = "clerk@example.com"
+)
+
+// recordingMux remembers every pattern SetupRoutes registers
+type recordingMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *recordingMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.HandleFunc(pattern, handler)
+}
+
+// server holds a mux wired to a fresh in-memory store with an admin, a clerk
+// without roles, one permission and one role of the admin
+type server struct {
+	t          *testing.T
+	mux        *recordingMux
+	repos      repository.Repositories
+	admin      models.User
+	adminToken string
+	clerkToken string
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	auth.SetSigningKey([]byte("test-signing-key"))
+	ctx := context.Background()
+
+	s := &server{t: t, mux: &recordingMux{ServeMux: http.NewServeMux()}, repos: repository.NewMemory()}
+	SetupRoutes(s.mux, s.repos)
+
+	// MinCost keeps the suite fast; CheckPassword accepts any bcrypt cost
+	hash, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.admin = models.User{Email: adminEmail, Password: string(hash), Status: "active"}
+	clerk := models.User{Email: clerkEmail, Password: string(hash), Status: "active"}
+	for _, user := range []*models.User{&s.admin, &clerk} {
+		if err := s.repos.Users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.repos.Access.CreateRole(ctx, &models.Role{UserID: s.admin.UserID, RoleName: repository.AdminRole}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Access.CreatePermission(ctx, &models.Permission{ModuleName: ModuleWarehouse, ActionType: "READ"}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.adminToken = s.token(s.admin.UserID, adminEmail)
+	s.clerkToken = s.token(clerk.UserID, clerkEmail)
+	return s
+}
+
+// token opens a session for the user and issues an access token for it
+func (s *server) token(userID int, email string) string {
+	s.t.Helper()
+	session := models.UserSession{UserID: userID, ExpiresAt: time.Now().Add(auth.RefreshTokenTTL)}
+	if err := s.repos.Sessions.CreateSession(context.Background(), &session); err != nil {
+		s.t.Fatal(err)
+	}
+	tok, _, err := auth.IssueToken(auth.TokenTypeAccess, userID, email, session.SessionID)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return tok
+}
+
+// send makes a request as the admin and decodes the JSON it answers,
+// failing the test unless the status is the one expected
+func (s *server) send(method, target, body string, status int) map[string]interface{} {
+	s.t.Helper()
+	rec := s.do(method, target, s.adminToken, body)
+	var out map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&out)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s %s: status %d, want %d; body %v", method, target, body, rec.Code, status, out)
+	}
+	return out
+}
+
+func (s *server) do(method, target, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// crudRoutes pairs each collection route with its ?id= item route and the
+// smallest body that passes validation
+var crudRoutes = []struct {
+	list, item, body string
+}{
+	{"/api/employees", "/api/employee", `{"full_name":"Ada Berg"}`},
+	{"/api/workerassignments", "/api/workerassignment", `{"employee_id":1,"processing_id":1}`},
+	{"/api/managementinsights", "/api/managementinsight", `{"employee_id":1}`},
+	{"/api/suppliers", "/api/supplier", `{"company_name":"Fjord Timber"}`},
+	{"/api/supplierperformances", "/api/supplierperformance", `{"supplier_id":1,"rating":4.5}`},
+	{"/api/suppliercontracts", "/api/suppliercontract", `{"supplier_id":1,"start_date":"2026-01-01","end_date":"2026-12-31"}`},
+	{"/api/forests", "/api/forest", `{"forest_name":"Nordmarka"}`},
+	{"/api/treespecies", "/api/treespecies-item", `{"species_name":"Spruce"}`},
+	{"/api/harvestschedules", "/api/harvestschedule", `{"forest_id":1,"start_date":"2026-03-01","end_date":"2026-03-31"}`},
+	{"/api/harvestbatches", "/api/harvestbatch", `{"quantity":12.5}`},
+	{"/api/sawmills", "/api/sawmill", `{"name":"Main Mill"}`},
+	{"/api/processingunits", "/api/processingunit", `{"sawmill_id":1}`},
+	{"/api/processingorders", "/api/processingorder", `{}`},
+	{"/api/maintenancerecords", "/api/maintenancerecord", `{"unit_id":1}`},
+	{"/api/wasterecords", "/api/wasterecord", `{"processing_id":1}`},
+	{"/api/qualityinspections", "/api/qualityinspection", `{"result":"Pass"}`},
+	{"/api/warehouses", "/api/warehouse", `{"name":"North Yard"}`},
+	{"/api/producttypes", "/api/producttype", `{"product_name":"Pine Plank"}`},
+	{"/api/stockitems", "/api/stockitem", `{"warehouse_id":1,"product_type_id":1}`},
+	{"/api/stockalerts", "/api/stockalert", `{"stock_id":1,"alert_type":"Low Stock"}`},
+	{"/api/purchaseorders", "/api/purchaseorder", `{"order_date":"2026-01-10"}`},
+	{"/api/purchaseorderitems", "/api/purchaseorderitem", `{"poid":1,"quantity":2}`},
+	{"/api/customers", "/api/customer", `{"name":"Byggmakker"}`},
+	{"/api/salesorders", "/api/salesorder", `{"order_date":"2026-01-10"}`},
+	{"/api/salesorderitems", "/api/salesorderitem", `{"soid":1,"quantity":2}`},
+	{"/api/invoices", "/api/invoice", `{"soid":1,"invoice_date":"2026-01-10"}`},
+	{"/api/payments", "/api/payment", `{"invoice_id":1,"payment_date":"2026-01-20","amount":100}`},
+	{"/api/transportcompanies", "/api/transportcompany", `{"company_name":"Nord Haul"}`},
+	{"/api/trucks", "/api/truck", `{"plate_number":"EL 12345"}`},
+	{"/api/drivers", "/api/driver", `{"employee_id":1,"license_number":"D-1"}`},
+	{"/api/routes", "/api/route", `{"start_location":"Oslo","end_location":"Bergen"}`},
+	{"/api/shipments", "/api/shipment", `{}`},
+	{"/api/fuellogs", "/api/fuellog", `{}`},
+	{"/api/permissions", "/api/permission", `{"module_name":"WAREHOUSE","action_type":"READ"}`},
+	{"/api/roles", "/api/role", `{"role_name":"Clerk"}`},
+}
+
+// stockReceipt seeds stock item 1 as a stock item body, whose quantity
+// starts the ledger with an opening receipt, and receives 5 into it
+const stockReceipt = `{"warehouse_id":1,"product_type_id":1,"quantity_in_stock":10,` +
+	`"stock_id":1,"transaction_type":"receipt","quantity":5}`
+
+// stockIssue seeds an empty stock item 1 and issues 5 from it
+const stockIssue = `{"warehouse_id":1,"product_type_id":1,"stock_id":1,"transaction_type":"issue","quantity":5}`
+
+type routeCase struct {
+	name   string
+	method string
+	target string
+	token  string // "admin" (default), "clerk" or "none"
+	body   string
+	status int
+	// seed is a collection the body is first POSTed to, so that updates and
+	// deletes find row 1
+	seed string
+}
+
+func routeCases() []routeCase {
+	cases := []routeCase{
+		{name: "health", method: "GET", target: "/", token: "none", status: http.StatusOK},
+
+		{name: "me", method: "GET", target: "/api/auth/me", status: http.StatusOK},
+		{name: "me without token", method: "GET", target: "/api/auth/me", token: "none", status: http.StatusUnauthorized},
+		{name: "login with wrong password", method: "POST", target: "/api/auth/login", token: "none",
+			body: `{"email":"admin@example.com","password":"wrong"}`, status: http.StatusUnauthorized},
+		{name: "login with GET", method: "GET", target: "/api/auth/login", token: "none", status: http.StatusMethodNotAllowed},
+		{name: "refresh with bad token", method: "POST", target: "/api/auth/refresh", token: "none",
+			body: `{"refresh_token":"nope"}`, status: http.StatusUnauthorized},
+		{name: "logout without token", method: "POST", target: "/api/auth/logout", token: "none",
+			body: `{}`, status: http.StatusUnauthorized},
+
+		{name: "list users", method: "GET", target: "/api/users", status: http.StatusOK},
+		{name: "create user", method: "POST", target: "/api/users",
+			body: `{"email":"new@example.com","password":"s3cret-pass","status":"active"}`, status: http.StatusCreated},
+		{name: "create user without password", method: "POST", target: "/api/users",
+			body: `{"email":"new@example.com"}`, status: http.StatusUnprocessableEntity},
+		{name: "create user with short password", method: "POST", target: "/api/users",
+			body: `{"email":"new@example.com","password":"short"}`, status: http.StatusUnprocessableEntity},
+		{name: "get user", method: "GET", target: "/api/user?id=1", status: http.StatusOK},
+		{name: "get missing user", method: "GET", target: "/api/user?id=99", status: http.StatusNotFound},
+		{name: "update user", method: "PUT", target: "/api/user?id=1",
+			body: `{"email":"admin@example.com","status":"active"}`, status: http.StatusOK},
+		{name: "update user password field", method: "PUT", target: "/api/user?id=1",
+			body: `{"password":"x"}`, status: http.StatusBadRequest},
+		{name: "delete user", method: "DELETE", target: "/api/user?id=2", status: http.StatusOK},
+		{name: "change another user's password", method: "PUT", target: "/api/user/password?id=2",
+			body: `{"current_password":"correct horse","new_password":"another-pass"}`, status: http.StatusForbidden},
+		{name: "change password with wrong current", method: "PUT", target: "/api/user/password?id=1",
+			body: `{"current_password":"wrong","new_password":"another-pass"}`, status: http.StatusUnauthorized},
+
+		{name: "list role permissions", method: "GET", target: "/api/rolepermissions", status: http.StatusOK},
+		{name: "grant permission", method: "POST", target: "/api/rolepermissions",
+			body: `{"role_id":1,"permission_id":1}`, status: http.StatusCreated},
+		{name: "grant unknown permission", method: "POST", target: "/api/rolepermissions",
+			body: `{"role_id":1,"permission_id":99}`, status: http.StatusUnprocessableEntity},
+		{name: "grant permission twice", method: "POST", target: "/api/rolepermissions",
+			body: `{"role_id":1,"permission_id":1}`, status: http.StatusConflict, seed: "/api/rolepermissions"},
+		{name: "role permissions by role", method: "GET", target: "/api/rolepermissions/role?id=1", status: http.StatusOK},
+		{name: "role permissions by permission", method: "GET", target: "/api/rolepermissions/permission?id=1", status: http.StatusOK},
+		{name: "assign permissions", method: "POST", target: "/api/rolepermissions/assign",
+			body: `{"role_id":1,"permission_ids":[1]}`, status: http.StatusOK},
+		{name: "revoke permission", method: "DELETE", target: "/api/rolepermission?role_id=1&permission_id=1",
+			body: `{"role_id":1,"permission_id":1}`, status: http.StatusOK, seed: "/api/rolepermissions"},
+		{name: "revoke missing permission", method: "DELETE", target: "/api/rolepermission?role_id=1&permission_id=1", status: http.StatusNotFound},
+		{name: "revoke permission without ids", method: "DELETE", target: "/api/rolepermission", status: http.StatusBadRequest},
+
+		{name: "list audit logs", method: "GET", target: "/api/auditlogs", status: http.StatusOK},
+		{name: "create audit log", method: "POST", target: "/api/auditlogs",
+			body: `{"user_id":1,"action_type":"LOGIN"}`, status: http.StatusMethodNotAllowed},
+		{name: "update audit log", method: "PUT", target: "/api/auditlogs?id=1", body: `{}`, status: http.StatusMethodNotAllowed},
+		{name: "delete audit log", method: "DELETE", target: "/api/auditlogs?id=1", status: http.StatusMethodNotAllowed},
+		{name: "verify audit logs", method: "GET", target: "/api/auditlogs/verify", status: http.StatusOK},
+
+		{name: "list inventory transactions", method: "GET", target: "/api/inventorytransactions", status: http.StatusOK},
+		{name: "receive stock", method: "POST", target: "/api/inventorytransactions",
+			body: stockReceipt, status: http.StatusCreated, seed: "/api/stockitems"},
+		{name: "receive unknown stock", method: "POST", target: "/api/inventorytransactions",
+			body: stockReceipt, status: http.StatusUnprocessableEntity},
+		{name: "issue more than in stock", method: "POST", target: "/api/inventorytransactions",
+			body: stockIssue, status: http.StatusConflict, seed: "/api/stockitems"},
+		{name: "reverse transaction", method: "POST", target: "/api/inventorytransaction/reverse?id=1",
+			body: stockReceipt, status: http.StatusCreated, seed: "/api/stockitems"},
+		{name: "reverse missing transaction", method: "POST", target: "/api/inventorytransaction/reverse?id=1", status: http.StatusNotFound},
+		{name: "update inventory transaction", method: "PUT", target: "/api/inventorytransaction/reverse?id=1",
+			body: stockReceipt, status: http.StatusMethodNotAllowed},
+
+		{name: "clerk without permission", method: "GET", target: "/api/suppliers", token: "clerk", status: http.StatusForbidden},
+		{name: "no token", method: "GET", target: "/api/suppliers", token: "none", status: http.StatusUnauthorized},
+
+		{name: "v2 me", method: "GET", target: "/api/v2/auth/me", status: http.StatusOK},
+		{name: "v2 login with GET", method: "GET", target: "/api/v2/auth/login", token: "none", status: http.StatusMethodNotAllowed},
+		{name: "v2 logout without token", method: "POST", target: "/api/v2/auth/logout", token: "none",
+			body: `{}`, status: http.StatusUnauthorized},
+		{name: "v2 refresh with bad token", method: "POST", target: "/api/v2/auth/refresh", token: "none",
+			body: `{"refresh_token":"nope"}`, status: http.StatusUnauthorized},
+		{name: "v2 list users", method: "GET", target: "/api/v2/users", status: http.StatusOK},
+		{name: "v2 get user", method: "GET", target: "/api/v2/users/1", status: http.StatusOK},
+		{name: "v2 get user bad id", method: "GET", target: "/api/v2/users/abc", status: http.StatusBadRequest},
+		{name: "v2 delete user", method: "DELETE", target: "/api/v2/users/2", status: http.StatusOK},
+		{name: "v2 change another user's password", method: "PUT", target: "/api/v2/users/2/password",
+			body: `{"current_password":"correct horse","new_password":"another-pass"}`, status: http.StatusForbidden},
+		{name: "v2 permission roles", method: "GET", target: "/api/v2/permissions/1/roles", status: http.StatusOK},
+		{name: "v2 role permissions", method: "GET", target: "/api/v2/roles/1/permissions", status: http.StatusOK},
+		{name: "v2 assign permissions", method: "PUT", target: "/api/v2/roles/1/permissions",
+			body: `{"permission_ids":[1]}`, status: http.StatusOK},
+		{name: "v2 grant permission", method: "POST", target: "/api/v2/rolepermissions",
+			body: `{"role_id":1,"permission_id":1}`, status: http.StatusCreated},
+		{name: "v2 list role permissions", method: "GET", target: "/api/v2/rolepermissions", status: http.StatusOK},
+		{name: "v2 revoke permission", method: "DELETE", target: "/api/v2/rolepermissions/1/1",
+			body: `{"role_id":1,"permission_id":1}`, status: http.StatusOK, seed: "/api/rolepermissions"},
+		{name: "v2 list audit logs", method: "GET", target: "/api/v2/auditlogs", status: http.StatusOK},
+		{name: "v2 create audit log", method: "POST", target: "/api/v2/auditlogs",
+			body: `{"user_id":1,"action_type":"LOGIN"}`, status: http.StatusMethodNotAllowed},
+		{name: "v2 delete audit logs", method: "DELETE", target: "/api/v2/auditlogs", status: http.StatusMethodNotAllowed},
+		{name: "v2 verify audit logs", method: "GET", target: "/api/v2/auditlogs/verify", status: http.StatusOK},
+		{name: "v2 checkpoint empty audit log", method: "POST", target: "/api/v2/auditlogs/checkpoints", status: http.StatusConflict},
+		{name: "v2 export checkpoints", method: "GET", target: "/api/v2/auditlogs/checkpoints/export", status: http.StatusOK},
+		{name: "v2 list inventory transactions", method: "GET", target: "/api/v2/inventorytransactions", status: http.StatusOK},
+		{name: "v2 receive stock", method: "POST", target: "/api/v2/inventorytransactions",
+			body: stockReceipt, status: http.StatusCreated, seed: "/api/stockitems"},
+		{name: "v2 reverse transaction", method: "POST", target: "/api/v2/inventorytransactions/1/reverse",
+			body: stockReceipt, status: http.StatusCreated, seed: "/api/stockitems"},
+		{name: "v2 delete inventory transaction", method: "DELETE", target: "/api/v2/inventorytransactions/1/reverse",
+			status: http.StatusMethodNotAllowed},
+		{name: "v2 list transfers", method: "GET", target: "/api/v2/transfers", status: http.StatusOK},
+		{name: "v2 get missing transfer", method: "GET", target: "/api/v2/transfers/1", status: http.StatusNotFound},
+		{name: "v2 transfer within a warehouse", method: "POST", target: "/api/v2/transfers",
+			body: `{"from_warehouse_id":1,"to_warehouse_id":1,"lines":[]}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 dispatch missing transfer", method: "POST", target: "/api/v2/transfers/1/dispatch", status: http.StatusNotFound},
+		{name: "v2 get missing processing order", method: "GET", target: "/api/v2/processingorders/1", status: http.StatusNotFound},
+		{name: "v2 list order batches", method: "GET", target: "/api/v2/processingorders/1/batches", status: http.StatusOK},
+		{name: "v2 attach batch to missing order", method: "POST", target: "/api/v2/processingorders/1/batches",
+			body: `{"batch_id":1,"consumed_quantity":2}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 detach missing batch", method: "DELETE", target: "/api/v2/processingorders/1/batches/1", status: http.StatusNotFound},
+		{name: "v2 release missing processing order", method: "POST", target: "/api/v2/processingorders/1/release", status: http.StatusNotFound},
+		{name: "v2 schedule of missing sawmill", method: "GET", target: "/api/v2/sawmills/1/schedule", status: http.StatusNotFound},
+		{name: "v2 schedule bad window", method: "GET", target: "/api/v2/sawmills/1/schedule?from=soon", status: http.StatusBadRequest},
+		{name: "v2 oee of missing unit", method: "GET", target: "/api/v2/processingunits/1/oee", status: http.StatusNotFound},
+		{name: "v2 oee bad period", method: "GET", target: "/api/v2/sawmills/1/oee?to=later", status: http.StatusBadRequest},
+		{name: "v2 complete missing work order", method: "POST", target: "/api/v2/maintenanceworkorders/1/complete", status: http.StatusNotFound},
+		{name: "v2 valorise missing waste record", method: "POST", target: "/api/v2/wasterecords/1/valorise",
+			body: `{"product_type_id":1,"warehouse_id":1}`, status: http.StatusNotFound},
+		{name: "v2 waste analytics bad period", method: "GET", target: "/api/v2/wasteanalytics?from=2026-02-01&to=2026-01-01", status: http.StatusBadRequest},
+		{name: "v2 readings of missing kiln charge", method: "GET", target: "/api/v2/kilncharges/1/readings", status: http.StatusNotFound},
+		{name: "v2 kiln charge without lots", method: "POST", target: "/api/v2/kilncharges", body: `{"unit_id":1}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 investigate missing report", method: "POST", target: "/api/v2/nonconformances/1/investigate", status: http.StatusNotFound},
+		{name: "v2 overdue actions bad date", method: "GET", target: "/api/v2/correctiveactions/overdue?as_of=soon", status: http.StatusBadRequest},
+		{name: "v2 spc chart of unknown metric", method: "GET", target: "/api/v2/spc/charts?metric=color", status: http.StatusUnprocessableEntity},
+		{name: "v2 spc chart bad baseline", method: "GET", target: "/api/v2/spc/charts?baseline_from=soon", status: http.StatusBadRequest},
+		{name: "v2 resolve missing quality alert", method: "POST", target: "/api/v2/qualityalerts/1/resolve", status: http.StatusNotFound},
+		{name: "v2 sampling plan off the AQL steps", method: "POST", target: "/api/v2/samplingplans", body: `{"aql":3}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 sample without lot size", method: "GET", target: "/api/v2/samplingplans/sample?supplier_id=1", status: http.StatusBadRequest},
+		{name: "v2 inspection lot of missing item", method: "POST", target: "/api/v2/inspectionlots", body: `{"po_item_id":9}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 missing inspection template", method: "GET", target: "/api/v2/inspectiontemplates/1", status: http.StatusNotFound},
+		{name: "v2 disposition of missing lot", method: "POST", target: "/api/v2/stockitems/1/dispositions", body: `{"action":"release"}`, status: http.StatusNotFound},
+		{name: "v2 disposition without action", method: "POST", target: "/api/v2/stockitems/1/dispositions", body: `{}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 defect code without name", method: "POST", target: "/api/v2/defectcodes", body: `{"code":"KN"}`, status: http.StatusUnprocessableEntity},
+		{name: "v2 trace unknown lot", method: "GET", target: "/api/v2/trace/HB-0001", status: http.StatusNotFound},
+		{name: "v2 clerk without permission", method: "GET", target: "/api/v2/suppliers", token: "clerk", status: http.StatusForbidden},
+		{name: "v2 unknown path", method: "GET", target: "/api/v2/nothing", status: http.StatusNotFound},
+		{name: "v2 unknown sub-resource", method: "PUT", target: "/api/v2/warehouses/1/extra", status: http.StatusNotFound},
+	}
+
+	// The v2 successors of the ?id= routes take the ID from the path
+	ids := strings.NewReplacer("{id}", "1", "{soid}", "1", "{poid}", "1")
+	missing := strings.NewReplacer("{id}", "99", "{soid}", "1", "{poid}", "1")
+
+	for _, rt := range crudRoutes {
+		cases = append(cases,
+			routeCase{name: "list " + rt.list, method: "GET", target: rt.list, status: http.StatusOK},
+			routeCase{name: "create " + rt.list, method: "POST", target: rt.list, body: rt.body, status: http.StatusCreated},
+			routeCase{name: "update " + rt.item, method: "PUT", target: rt.item + "?id=1", body: rt.body, status: http.StatusOK, seed: rt.list},
+			routeCase{name: "update " + rt.item + " bad id", method: "PUT", target: rt.item + "?id=abc", body: rt.body, status: http.StatusBadRequest},
+			routeCase{name: "delete " + rt.item, method: "DELETE", target: rt.item + "?id=1", body: rt.body, status: http.StatusOK, seed: rt.list},
+			routeCase{name: "delete " + rt.item + " missing", method: "DELETE", target: rt.item + "?id=99", status: http.StatusNotFound},
+			routeCase{name: "patch " + rt.list, method: "PATCH", target: rt.list, status: http.StatusMethodNotAllowed},
+		)
+
+		list, item := ids.Replace(legacySuccessors[rt.list]), ids.Replace(legacySuccessors[rt.item])
+		cases = append(cases,
+			routeCase{name: "list " + list, method: "GET", target: list, status: http.StatusOK},
+			routeCase{name: "create " + list, method: "POST", target: list, body: rt.body, status: http.StatusCreated},
+			routeCase{name: "update " + item, method: "PUT", target: item, body: rt.body, status: http.StatusOK, seed: rt.list},
+			routeCase{name: "update " + item + " missing", method: "PUT", target: missing.Replace(legacySuccessors[rt.item]), body: rt.body, status: http.StatusNotFound},
+			routeCase{name: "delete " + item, method: "DELETE", target: item, body: rt.body, status: http.StatusOK, seed: rt.list},
+			routeCase{name: "get " + item, method: "GET", target: item, body: rt.body, status: http.StatusOK, seed: rt.list},
+			routeCase{name: "get " + item + " missing", method: "GET", target: missing.Replace(legacySuccessors[rt.item]), status: http.StatusNotFound},
+			routeCase{name: "patch " + list, method: "PATCH", target: list, status: http.StatusMethodNotAllowed},
+		)
+	}
+	return cases
+}
+
+func TestRoutes(t *testing.T) {
+	covered := map[string]bool{}
+	var patterns []string
+
+	for _, tc := range routeCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer(t)
+			patterns = s.mux.patterns
+
+			tok := s.adminToken
+			switch tc.token {
+			case "clerk":
+				tok = s.clerkToken
+			case "none":
+				tok = ""
+			}
+
+			if tc.seed != "" {
+				if rec := s.do("POST", tc.seed, s.adminToken, tc.body); rec.Code != http.StatusCreated {
+					t.Fatalf("seed %s: status %d; body %s", tc.seed, rec.Code, rec.Body)
+				}
+			}
+			rec := s.do(tc.method, tc.target, tok, tc.body)
+			if rec.Code != tc.status {
+				t.Fatalf("%s %s: status %d, want %d; body %s", tc.method, tc.target, rec.Code, tc.status, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+		u, _ := url.Parse(tc.target)
+		covered[u.Path] = true
+		if strings.HasPrefix(u.Path, "/api/v2/") {
+			covered["/api/v2/"] = true
+		}
+	}
+
+	for _, pattern := range patterns {
+		if !covered[pattern] {
+			t.Errorf("route %s is registered but has no test case", pattern)
+		}
+	}
+}
+
+func TestCreateThenList(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("POST", "/api/warehouses", s.adminToken, `{"name":"North Yard","location":"Oslo","capacity":500}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d", rec.Code)
+	}
+	var created models.Warehouse
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.WarehouseID == 0 {
+		t.Fatal("create did not return an ID")
+	}
+
+	s.do("PUT", "/api/warehouse?id=1", s.adminToken, `{"name":"South Yard","location":"Oslo","capacity":750}`)
+
+	var listed []models.Warehouse
+	json.NewDecoder(s.do("GET", "/api/warehouses", s.adminToken, "").Body).Decode(&listed)
+	if len(listed) != 1 || listed[0].Name != "South Yard" || listed[0].Capacity != 750 {
+		t.Fatalf("listed %+v", listed)
+	}
+
+	s.do("DELETE", "/api/warehouse?id=1", s.adminToken, "")
+	json.NewDecoder(s.do("GET", "/api/warehouses", s.adminToken, "").Body).Decode(&listed)
+	if len(listed) != 0 {
+		t.Fatalf("warehouse not deleted: %+v", listed)
+	}
+}
+
+func TestGrantedPermission(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+
+	role := models.Role{UserID: 2, RoleName: "Clerk"}
+	s.repos.Access.CreateRole(ctx, &role)
+	s.repos.Access.AssignPermissions(ctx, role.RoleID, []int{1}) // Warehouse & Inventory READ
+
+	if rec := s.do("GET", "/api/warehouses", s.clerkToken, ""); rec.Code != http.StatusOK {
+		t.Fatalf("granted READ: status %d", rec.Code)
+	}
+	if rec := s.do("POST", "/api/warehouses", s.clerkToken, `{}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("missing CREATE: status %d", rec.Code)
+	}
+
+	var me struct {
+		Permissions []models.Permission `json:"permissions"`
+	}
+	json.NewDecoder(s.do("GET", "/api/auth/me", s.clerkToken, "").Body).Decode(&me)
+	if len(me.Permissions) != 1 || me.Permissions[0].ModuleName != ModuleWarehouse {
+		t.Fatalf("me permissions = %+v", me.Permissions)
+	}
+}
+
+func TestAuthFlow(t *testing.T) {
+	s := newServer(t)
+
+	type tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	var login tokens
+	rec := s.do("POST", "/api/auth/login", "", `{"email":"ADMIN@example.com","password":"correct horse"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d; body %s", rec.Code, rec.Body)
+	}
+	json.NewDecoder(rec.Body).Decode(&login)
+
+	var refreshed tokens
+	rec = s.do("POST", "/api/auth/refresh", "", `{"refresh_token":"`+login.RefreshToken+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: status %d; body %s", rec.Code, rec.Body)
+	}
+	json.NewDecoder(rec.Body).Decode(&refreshed)
+
+	// Replaying the rotated-out token ends the session
+	if rec := s.do("POST", "/api/auth/refresh", "", `{"refresh_token":"`+login.RefreshToken+`"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token: status %d", rec.Code)
+	}
+	if rec := s.do("POST", "/api/auth/refresh", "", `{"refresh_token":"`+refreshed.RefreshToken+`"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh after reuse: status %d", rec.Code)
+	}
+
+	// Of two refreshes racing with one token, only one gets a new pair
+	rec = s.do("POST", "/api/auth/login", "", `{"email":"admin@example.com","password":"correct horse"}`)
+	json.NewDecoder(rec.Body).Decode(&login)
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			codes <- s.do("POST", "/api/auth/refresh", "", `{"refresh_token":"`+login.RefreshToken+`"}`).Code
+		}()
+	}
+	if a, b := <-codes, <-codes; a+b != http.StatusOK+http.StatusUnauthorized {
+		t.Fatalf("racing refreshes: statuses %d and %d", a, b)
+	}
+
+	rec = s.do("POST", "/api/auth/login", "", `{"email":"admin@example.com","password":"correct horse"}`)
+	json.NewDecoder(rec.Body).Decode(&login)
+	if rec := s.do("GET", "/api/v2/auth/me", login.AccessToken, ""); rec.Code != http.StatusOK {
+		t.Fatalf("me: status %d", rec.Code)
+	}
+	if rec := s.do("POST", "/api/auth/logout", login.AccessToken, `{}`); rec.Code != http.StatusOK {
+		t.Fatalf("logout: status %d", rec.Code)
+	}
+	// The access token dies with its session
+	if rec := s.do("GET", "/api/v2/auth/me", login.AccessToken, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("me after logout: status %d", rec.Code)
+	}
+	// and with its user's deactivation
+	if rec := s.do("GET", "/api/v2/auth/me", s.clerkToken, ""); rec.Code != http.StatusOK {
+		t.Fatalf("clerk me: status %d", rec.Code)
+	}
+	s.send("PUT", "/api/v2/users/2", `{"email":"`+clerkEmail+`","status":"inactive"}`, http.StatusOK)
+	if rec := s.do("GET", "/api/v2/auth/me", s.clerkToken, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("me of a deactivated user: status %d", rec.Code)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("PUT", "/api/user/password?id=1", s.adminToken,
+		`{"current_password":"correct horse","new_password":"battery staple"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("change password: status %d; body %s", rec.Code, rec.Body)
+	}
+	if rec := s.do("POST", "/api/auth/login", "", `{"email":"admin@example.com","password":"correct horse"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("old password still works: status %d", rec.Code)
+	}
+	if rec := s.do("GET", "/api/v2/warehouses", s.adminToken, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("token issued before the change still works: status %d", rec.Code)
+	}
+	if rec := s.do("PUT", "/api/v2/users/2/password", s.clerkToken,
+		`{"current_password":"correct horse","new_password":"short"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("short new password: status %d", rec.Code)
+	}
+	if rec := s.do("POST", "/api/auth/login", "", `{"email":"admin@example.com","password":"battery staple"}`); rec.Code != http.StatusOK {
+		t.Fatalf("new password rejected: status %d", rec.Code)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s := newServer(t)
+
+	for target, want := range map[string]string{
+		"/api/v2/warehouses/1": "DELETE, GET, OPTIONS, PUT",
+		"/api/warehouse?id=1":  "DELETE, OPTIONS, PUT",
+	} {
+		rec := s.do("POST", target, s.adminToken, "")
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("POST %s: status %d", target, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); allow != want {
+			t.Errorf("POST %s: Allow = %q, want %q", target, allow, want)
+		}
+	}
+
+	rec := s.do("OPTIONS", "/api/v2/salesorders/1/items", "", "")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Allow") != "GET, OPTIONS, POST" {
+		t.Fatalf("OPTIONS: status %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
+
+func TestNestedOrderItems(t *testing.T) {
+	s := newServer(t)
+
+	for _, body := range []string{
+		`{"customer_id":1,"order_date":"2026-01-10"}`,
+		`{"customer_id":2,"order_date":"2026-01-11"}`,
+	} {
+		if rec := s.do("POST", "/api/v2/salesorders", s.adminToken, body); rec.Code != http.StatusCreated {
+			t.Fatalf("create order: status %d", rec.Code)
+		}
+	}
+	// The path wins over the soid in the body
+	s.do("POST", "/api/v2/salesorders/2/items", s.adminToken, `{"soid":1,"quantity":3}`)
+	s.do("POST", "/api/v2/salesorders/1/items", s.adminToken, `{"quantity":5}`)
+
+	var page query.Page[models.SalesOrderItem]
+	json.NewDecoder(s.do("GET", "/api/v2/salesorders/2/items", s.adminToken, "").Body).Decode(&page)
+	if items := page.Data; len(items) != 1 || items[0].SOID != 2 || items[0].Quantity != 3 {
+		t.Fatalf("order 2 items = %+v", items)
+	}
+
+	var items []models.SalesOrderItem
+	json.NewDecoder(s.do("GET", "/api/salesorderitems", s.adminToken, "").Body).Decode(&items)
+	if len(items) != 2 {
+		t.Fatalf("legacy list = %+v", items)
+	}
+
+	if rec := s.do("GET", "/api/v2/purchaseorders/x/items", s.adminToken, ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad poid: status %d", rec.Code)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	s := newServer(t)
+
+	cases := []struct {
+		target, body string
+		status       int
+		errors       []validate.FieldError
+	}{
+		{"/api/v2/harvestschedules", `{"forest_id":1,"start_date":"2026-03-31","end_date":"2026-03-01","status":"Someday"}`,
+			http.StatusUnprocessableEntity, []validate.FieldError{
+				{Field: "end_date", Code: validate.CodeDateOrder, Message: "end_date must not be before start_date"},
+				{Field: "status", Code: validate.CodeInvalidChoice, Message: "status must be one of scheduled, in_progress, completed, cancelled"},
+			}},
+		{"/api/supplierperformances", `{"rating":10}`, http.StatusUnprocessableEntity, []validate.FieldError{
+			{Field: "supplier_id", Code: validate.CodeRequired, Message: "supplier_id is required"},
+			{Field: "rating", Code: validate.CodeTooLarge, Message: "rating must be at most 9.99"},
+		}},
+		{"/api/v2/suppliers", `{"company_name":"  ","email":"nobody"}`, http.StatusUnprocessableEntity, []validate.FieldError{
+			{Field: "company_name", Code: validate.CodeRequired, Message: "company_name is required"},
+			{Field: "email", Code: validate.CodeInvalidEmail, Message: "email must be a valid email address"},
+		}},
+		{"/api/v2/stockitems", `{"warehouse_id":"one"}`, http.StatusUnprocessableEntity, []validate.FieldError{
+			{Field: "warehouse_id", Code: validate.CodeInvalidType, Message: "warehouse_id must be an integer"},
+		}},
+		{"/api/v2/shipments", `{"status":"In Transit"}`, http.StatusCreated, nil},
+		{"/api/v2/forests", `{"forest_name":`, http.StatusBadRequest, nil},
+	}
+	for _, tc := range cases {
+		rec := s.do("POST", tc.target, s.adminToken, tc.body)
+		if rec.Code != tc.status {
+			t.Errorf("POST %s: status %d, want %d; body %s", tc.target, rec.Code, tc.status, rec.Body)
+			continue
+		}
+		if tc.errors == nil {
+			continue
+		}
+		var body struct {
+			Code    string                `json:"code"`
+			Details []validate.FieldError `json:"details"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		if body.Code != "validation_failed" || !reflect.DeepEqual(body.Details, tc.errors) {
+			t.Errorf("POST %s: %s %+v, want validation_failed %+v", tc.target, body.Code, body.Details, tc.errors)
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	s := newServer(t)
+	handler := middleware.RequestID(s.mux)
+
+	do := func(method, target, requestID, body string) (*httptest.ResponseRecorder, utils.ErrorBody) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+s.adminToken)
+		if requestID != "" {
+			req.Header.Set(utils.RequestIDHeader, requestID)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var envelope utils.ErrorBody
+		json.Unmarshal(rec.Body.Bytes(), &envelope)
+		return rec, envelope
+	}
+
+	rec, body := do("DELETE", "/api/v2/forests/99", "req-42", "")
+	if rec.Code != http.StatusNotFound || body.Code != "not_found" || body.RequestID != "req-42" {
+		t.Errorf("delete missing: %d %+v", rec.Code, body)
+	}
+	if rec.Header().Get(utils.RequestIDHeader) != "req-42" {
+		t.Errorf("X-Request-ID = %q", rec.Header().Get(utils.RequestIDHeader))
+	}
+	if body.Error != "" {
+		t.Errorf("v2 error carries the legacy alias %q", body.Error)
+	}
+
+	// Malformed IDs are replaced rather than echoed
+	rec, body = do("PUT", "/api/v2/forests/99", "bad id!", `{"forest_name":"Nordmarka"}`)
+	if rec.Code != http.StatusNotFound || body.RequestID == "" || body.RequestID == "bad id!" {
+		t.Errorf("update missing: %d %+v", rec.Code, body)
+	}
+
+	rec, body = do("POST", "/api/v2/users", "", `{"email":"`+adminEmail+`","password":"secret123","status":"active"}`)
+	details, _ := body.Details.(map[string]interface{})
+	if rec.Code != http.StatusConflict || body.Code != "conflict" || details["field"] != "email" {
+		t.Errorf("duplicate email: %d %+v", rec.Code, body)
+	}
+
+	// Legacy routes keep the error field the React frontend reads
+	rec, body = do("DELETE", "/api/forest?id=99", "", "")
+	if rec.Code != http.StatusNotFound || body.Error != body.Message || body.Error == "" {
+		t.Errorf("legacy delete missing: %d %+v", rec.Code, body)
+	}
+}
+
+// failingAudit rejects every entry, to check that changes roll back with it
+type failingAudit struct {
+	repository.AuditRepository
+}
+
+func (failingAudit) CreateAuditLog(ctx context.Context, al *models.AuditLog) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestAuditTrail(t *testing.T) {
+	s := newServer(t)
+	defer utils.SetTrustedProxies(nil)
+	utils.SetTrustedProxies([]string{"192.0.2.0/24"})
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+s.adminToken)
+		req.Header.Set("X-Forwarded-For", "198.51.100.4, 203.0.113.9")
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, step := range []struct {
+		method, target, body string
+		status               int
+	}{
+		{"POST", "/api/v2/forests", `{"forest_name":"Nordmarka","status":"active"}`, http.StatusCreated},
+		{"PUT", "/api/forest?id=1", `{"forest_name":"Sørmarka","status":"active"}`, http.StatusOK},
+		{"PUT", "/api/v2/forests/1", `{"forest_name":""}`, http.StatusUnprocessableEntity},
+		{"DELETE", "/api/v2/forests/1", "", http.StatusOK},
+	} {
+		if rec := do(step.method, step.target, step.body); rec.Code != step.status {
+			t.Fatalf("%s %s: status %d; body %s", step.method, step.target, rec.Code, rec.Body)
+		}
+	}
+
+	page, _ := s.repos.Audit.ListAuditLogs(context.Background(), query.Spec{Sort: []query.Order{{Field: "log_id"}}})
+	if len(page.Data) != 3 {
+		t.Fatalf("%d audit entries, want 3 (the rejected update is not audited): %+v", len(page.Data), page.Data)
+	}
+	for i, action := range []string{"CREATE", "UPDATE", "DELETE"} {
+		entry := page.Data[i]
+		if entry.ActionType != action || entry.EntityAffected != "Forest" || entry.EntityID != "1" ||
+			entry.UserID != s.admin.UserID || entry.IPAddress != "203.0.113.9" {
+			t.Errorf("entry %d = %+v, want %s of Forest 1 by %d from 203.0.113.9", i, entry, action, s.admin.UserID)
+		}
+	}
+	want := `{"after":{"forest_name":"Sørmarka"},"before":{"forest_name":"Nordmarka"}}`
+	if got := string(page.Data[1].Changes); got != want {
+		t.Errorf("update changes = %s, want %s", got, want)
+	}
+
+	// Without trust the header is ignored
+	utils.SetTrustedProxies(nil)
+	do("POST", "/api/v2/forests", `{"forest_name":"Finnskogen"}`)
+	page, _ = s.repos.Audit.ListAuditLogs(context.Background(), query.Spec{Sort: []query.Order{{Field: "log_id", Desc: true}}, Limit: 1})
+	if ip := page.Data[0].IPAddress; ip != "192.0.2.1" {
+		t.Errorf("untrusted IP = %q, want the connection address", ip)
+	}
+
+	// A change whose entry cannot be written is rolled back
+	repos := s.repos
+	repos.Audit = failingAudit{repos.Audit}
+	mux := http.NewServeMux()
+	SetupRoutes(mux, repos)
+	req := httptest.NewRequest("POST", "/api/v2/forests", strings.NewReader(`{"forest_name":"Lost"}`))
+	req.Header.Set("Authorization", "Bearer "+s.adminToken)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("failed audit: status %d", rec.Code)
+	}
+	forests, _ := s.repos.Forests.ListForests(context.Background(), query.Spec{})
+	if len(forests.Data) != 1 {
+		t.Errorf("%d forests after a failed audit, want 1", len(forests.Data))
+	}
+}
+
+// tamperedAudit edits entry 2 as it is read, as an UPDATE issued directly
+// against the database would
+type tamperedAudit struct {
+	repository.AuditRepository
+}
+
+func (a tamperedAudit) WalkAuditLogs(ctx context.Context, fn func(models.AuditLog) error) error {
+	return a.AuditRepository.WalkAuditLogs(ctx, func(al models.AuditLog) error {
+		if al.LogID == 2 {
+			al.Description = "GET /"
+		}
+		return fn(al)
+	})
+}
+
+func TestAuditChain(t *testing.T) {
+	s := newServer(t)
+	for _, name := range []string{"Nordmarka", "Sørmarka", "Finnskogen"} {
+		if rec := s.do("POST", "/api/v2/forests", s.adminToken, `{"forest_name":"`+name+`"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("create forest: %d %s", rec.Code, rec.Body)
+		}
+	}
+
+	rec := s.do("POST", "/api/v2/auditlogs/checkpoints", s.adminToken, "")
+	var checkpoint models.AuditCheckpoint
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &checkpoint) != nil || checkpoint.LogID != 3 {
+		t.Fatalf("checkpoint: %d %s", rec.Code, rec.Body)
+	}
+	if rec := s.do("POST", "/api/v2/auditlogs/checkpoints", s.adminToken, ""); rec.Code != http.StatusConflict {
+		t.Errorf("checkpoint of an unchanged head: %d", rec.Code)
+	}
+
+	var report auditchain.Report
+	rec = s.do("GET", "/api/v2/auditlogs/verify", s.adminToken, "")
+	if json.Unmarshal(rec.Body.Bytes(), &report) != nil || !report.Valid || report.Entries != 3 || report.Checkpoints != 1 {
+		t.Errorf("verify intact chain: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = s.do("GET", "/api/v2/auditlogs/checkpoints/export", s.adminToken, "")
+	var export auditchain.Export
+	if !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment") ||
+		json.Unmarshal(rec.Body.Bytes(), &export) != nil || export.PublicKey == "" || len(export.Checkpoints) != 1 {
+		t.Errorf("export: %v %s", rec.Header(), rec.Body)
+	}
+
+	repos := s.repos
+	repos.Audit = tamperedAudit{repos.Audit}
+	mux := http.NewServeMux()
+	SetupRoutes(mux, repos)
+	req := httptest.NewRequest("GET", "/api/v2/auditlogs/verify", nil)
+	req.Header.Set("Authorization", "Bearer "+s.adminToken)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	report = auditchain.Report{}
+	if json.Unmarshal(rec.Body.Bytes(), &report) != nil || report.Valid || report.BrokenAt != 2 {
+		t.Errorf("verify tampered chain: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestInventoryLedger(t *testing.T) {
+	s := newServer(t)
+	post := func(target, body string, status int) map[string]interface{} {
+		t.Helper()
+		rec := s.do("POST", target, s.adminToken, body)
+		var out map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&out)
+		if rec.Code != status {
+			t.Fatalf("POST %s %s: status %d, want %d; body %v", target, body, rec.Code, status, out)
+		}
+		return out
+	}
+	quantity := func(stockID int) float64 {
+		t.Helper()
+		page, _ := s.repos.Stock.ListStockItems(context.Background(), query.Spec{})
+		for _, si := range page.Data {
+			if si.StockID == stockID {
+				return si.Quantity
+			}
+		}
+		t.Fatalf("stock item %d not found", stockID)
+		return 0
+	}
+
+	post("/api/v2/warehouses", `{"name":"North Yard"}`, http.StatusCreated)
+	post("/api/v2/warehouses", `{"name":"Bonded","allow_negative_stock":true}`, http.StatusCreated)
+	post("/api/v2/stockitems", `{"warehouse_id":1,"product_type_id":1,"quantity_in_stock":10}`, http.StatusCreated)
+	post("/api/v2/stockitems", `{"warehouse_id":2,"product_type_id":1}`, http.StatusCreated)
+
+	issue := post("/api/v2/inventorytransactions", `{"stock_id":1,"transaction_type":"Issue","quantity":4}`, http.StatusCreated)
+	if issue["transaction_type"] != "issue" || issue["quantity"] != -4.0 || issue["balance_after"] != 6.0 || issue["warehouse_id"] != 1.0 {
+		t.Errorf("issue = %v", issue)
+	}
+	refused := post("/api/v2/inventorytransactions", `{"stock_id":1,"transaction_type":"scrap","quantity":7}`, http.StatusConflict)
+	if refused["code"] != "insufficient_stock" {
+		t.Errorf("overdraw = %v", refused)
+	}
+	post("/api/v2/inventorytransactions", `{"stock_id":1,"transaction_type":"issue","quantity":-1}`, http.StatusUnprocessableEntity)
+	if adjusted := post("/api/v2/inventorytransactions", `{"stock_id":1,"transaction_type":"adjustment","quantity":-1.5,"transaction_date":"2026-03-01"}`,
+		http.StatusCreated); adjusted["transaction_date"] != "2026-03-01" {
+		t.Errorf("dated adjustment = %v", adjusted)
+	}
+	post("/api/v2/inventorytransactions", `{"stock_id":1,"transaction_type":"receipt","quantity":1,"transaction_date":"March"}`,
+		http.StatusUnprocessableEntity)
+	post("/api/v2/inventorytransactions", `{"stock_id":2,"transaction_type":"transfer_out","quantity":3}`, http.StatusCreated)
+	if q1, q2 := quantity(1), quantity(2); q1 != 4.5 || q2 != -3 {
+		t.Errorf("quantities = %g, %g; want 4.5 and -3 in the warehouse allowing negative stock", q1, q2)
+	}
+
+	// Transaction 2 is the issue; transaction 1 the opening balance
+	reversal := post("/api/v2/inventorytransactions/2/reverse", `{"reference_id":"counted wrong"}`, http.StatusCreated)
+	if reversal["quantity"] != 4.0 || reversal["reverses_id"] != 2.0 || reversal["reference_id"] != "counted wrong" {
+		t.Errorf("reversal = %v", reversal)
+	}
+	post("/api/v2/inventorytransactions/2/reverse", "", http.StatusConflict)
+	if out := post(fmt.Sprintf("/api/v2/inventorytransactions/%v/reverse", reversal["transaction_id"]), "", http.StatusConflict); out["code"] != "not_reversible" {
+		t.Errorf("reversing a reversal = %v", out)
+	}
+	if q := quantity(1); q != 8.5 {
+		t.Errorf("quantity after reversal = %g, want 8.5", q)
+	}
+
+	// The quantity only moves through the ledger
+	if rec := s.do("PUT", "/api/v2/stockitems/1", s.adminToken, `{"warehouse_id":1,"product_type_id":1,"quantity_in_stock":99}`); rec.Code != http.StatusOK {
+		t.Fatalf("update stock item: %d", rec.Code)
+	}
+	if q := quantity(1); q != 8.5 {
+		t.Errorf("quantity after editing the stock item = %g, want 8.5", q)
+	}
+	page, _ := s.repos.Stock.ListInventoryTransactions(context.Background(), query.Spec{})
+	sum := 0.0
+	for _, it := range page.Data {
+		if it.StockID == 1 {
+			sum += it.Quantity
+		}
+	}
+	if sum != 8.5 {
+		t.Errorf("ledger sums to %g, want the stock quantity 8.5", sum)
+	}
+}
+
+func TestStockTransfer(t *testing.T) {
+	s := newServer(t)
+	stock := func() map[int]models.StockItem {
+		page, _ := s.repos.Stock.ListStockItems(context.Background(), query.Spec{})
+		items := map[int]models.StockItem{}
+		for _, si := range page.Data {
+			items[si.StockID] = si
+		}
+		return items
+	}
+
+	s.send("POST", "/api/v2/warehouses", `{"name":"North Yard"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/warehouses", `{"name":"Harbour"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems", `{"warehouse_id":1,"product_type_id":1,"batch_id":7,"quantity_in_stock":10}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems", `{"warehouse_id":1,"product_type_id":2,"quantity_in_stock":5}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems", `{"warehouse_id":2,"product_type_id":1,"batch_id":8}`, http.StatusCreated)
+
+	invalid := s.send("POST", "/api/v2/transfers", `{"from_warehouse_id":2,"to_warehouse_id":1,"lines":[{"stock_id":1,"quantity":4}]}`,
+		http.StatusUnprocessableEntity)
+	if fields, _ := invalid["details"].([]interface{}); len(fields) != 1 || fields[0].(map[string]interface{})["field"] != "lines[0].stock_id" {
+		t.Errorf("line from another warehouse = %v", invalid)
+	}
+	created := s.send("POST", "/api/v2/transfers", `{"from_warehouse_id":1,"to_warehouse_id":2,"lines":[{"stock_id":1,"quantity":4},{"stock_id":2,"quantity":5}]}`,
+		http.StatusCreated)
+	if created["status"] != "draft" || created["transfer_id"] != 1.0 {
+		t.Fatalf("created = %v", created)
+	}
+	if out := s.send("POST", "/api/v2/transfers/1/receive", "", http.StatusConflict); out["code"] != "invalid_transition" {
+		t.Errorf("receiving a draft = %v", out)
+	}
+
+	s.send("POST", "/api/v2/transfers/1/dispatch", `{"employee_id":1}`, http.StatusOK)
+	if items := stock(); items[1].Quantity != 6 || items[2].Quantity != 0 {
+		t.Errorf("source quantities after dispatch = %g, %g; want 6 and 0", items[1].Quantity, items[2].Quantity)
+	}
+	s.send("PUT", "/api/v2/transfers/1", `{"from_warehouse_id":1,"to_warehouse_id":2,"lines":[{"stock_id":1,"quantity":1}]}`, http.StatusConflict)
+	s.send("DELETE", "/api/v2/transfers/1", "", http.StatusConflict)
+
+	transit := s.send("POST", "/api/v2/transfers/1/transit", `{"truck_id":3}`, http.StatusOK)
+	if transit["status"] != "in_transit" || transit["truck_id"] != 3.0 {
+		t.Errorf("transit = %v", transit)
+	}
+	received := s.send("POST", "/api/v2/transfers/1/receive", `{"lines":[{"line_id":2,"received_quantity":4.5,"note":"one bundle split"}]}`, http.StatusOK)
+	if received["status"] != "received" || received["received_at"] == nil {
+		t.Errorf("received = %v", received)
+	}
+
+	// Batch 7 gets a new stock item in the harbour rather than joining batch 8
+	items := stock()
+	if len(items) != 5 || items[3].Quantity != 0 {
+		t.Fatalf("stock after receipt = %v", items)
+	}
+	if moved := items[4]; moved.WarehouseID != 2 || moved.BatchID == nil || *moved.BatchID != 7 || moved.Quantity != 4 {
+		t.Errorf("received batch 7 = %+v", moved)
+	}
+	if moved := items[5]; moved.WarehouseID != 2 || moved.ProductTypeID != 2 || moved.Quantity != 4.5 {
+		t.Errorf("received product 2 = %+v", moved)
+	}
+
+	report := s.send("GET", "/api/v2/transfers/1/discrepancies", "", http.StatusOK)
+	discrepancies, _ := report["discrepancies"].([]interface{})
+	if len(discrepancies) != 1 {
+		t.Fatalf("discrepancies = %v", report)
+	}
+	if d := discrepancies[0].(map[string]interface{}); d["line_id"] != 2.0 || d["difference"] != -0.5 || d["note"] != "one bundle split" {
+		t.Errorf("discrepancy = %v", d)
+	}
+
+	page, _ := s.repos.Stock.ListInventoryTransactions(context.Background(), query.Spec{})
+	types := map[string]int{}
+	for _, it := range page.Data {
+		types[it.TransactionType]++
+	}
+	if types["transfer_out"] != 2 || types["transfer_in"] != 2 {
+		t.Errorf("ledger entries = %v, want two transfers out and two in", types)
+	}
+}
+
+func TestLotTrace(t *testing.T) {
+	s := newServer(t)
+	s.send("POST", "/api/v2/forests", `{"forest_name":"Nordmarka"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/treespecies", `{"species_name":"Spruce"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/harvestschedules", `{"forest_id":1,"start_date":"2026-03-01"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/harvestbatches", `{"forest_id":1,"species_id":1,"schedule_id":1,"quantity":12.5,"qr_code":"HB-0001"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/harvestbatches", `{"forest_id":1,"species_id":1,"quantity":3,"qr_code":"HB-0002"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/qualityinspections", `{"batch_id":1,"result":"pass"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"product_type_id":1}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders/1/batches", `{"batch_id":1,"consumed_quantity":5}`, http.StatusCreated)
+	s.send("POST", "/api/v2/warehouses", `{"name":"North Yard"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/warehouses", `{"name":"Harbour"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems", `{"warehouse_id":1,"product_type_id":1,"batch_id":1,"quantity_in_stock":10}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems", `{"warehouse_id":1,"product_type_id":1,"batch_id":2,"quantity_in_stock":3}`, http.StatusCreated)
+	s.send("POST", "/api/v2/transfers", `{"from_warehouse_id":1,"to_warehouse_id":2,"lines":[{"stock_id":1,"quantity":4}]}`, http.StatusCreated)
+	for _, step := range []string{"dispatch", "transit", "receive"} {
+		s.send("POST", "/api/v2/transfers/1/"+step, "", http.StatusOK)
+	}
+	s.send("POST", "/api/v2/salesorders", `{"order_date":"2026-04-01"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/salesorders/1/items", `{"stock_id":3,"quantity":2}`, http.StatusCreated)
+	s.send("POST", "/api/v2/shipments", `{"soid":1}`, http.StatusCreated)
+
+	rec := s.do("GET", "/api/v2/trace/HB-0001", s.adminToken, "")
+	var g trace.Genealogy
+	if err := json.NewDecoder(rec.Body).Decode(&g); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("trace: status %d, %v", rec.Code, err)
+	}
+	if g.Batch.BatchID != 1 || g.Forest == nil || g.Forest.ForestName != "Nordmarka" || g.Species == nil || g.Schedule == nil {
+		t.Errorf("upstream = %+v, forest %v, species %v, schedule %v", g.Batch, g.Forest, g.Species, g.Schedule)
+	}
+	if len(g.Inspections) != 1 || len(g.ProcessingOrders) != 1 {
+		t.Errorf("inspections = %+v, processing orders = %+v", g.Inspections, g.ProcessingOrders)
+	}
+	// The lot in the harbour is the one the transfer created
+	if len(g.StockItems) != 2 || g.StockItems[1].StockID != 3 || g.StockItems[1].WarehouseID != 2 {
+		t.Errorf("stock items = %+v", g.StockItems)
+	}
+	var types []string
+	for _, it := range g.Movements {
+		types = append(types, it.TransactionType)
+	}
+	if strings.Join(types, ",") != "receipt,transfer_out,transfer_in" {
+		t.Errorf("movements = %v", types)
+	}
+	if len(g.SalesOrderItems) != 1 || len(g.SalesOrders) != 1 || len(g.Shipments) != 1 || g.Shipments[0].SOID != 1 {
+		t.Errorf("downstream = %+v, %+v, %+v", g.SalesOrderItems, g.SalesOrders, g.Shipments)
+	}
+}
+
+func TestBatchConsumption(t *testing.T) {
+	s := newServer(t)
+
+	s.send("POST", "/api/v2/harvestbatches", `{"quantity":10,"qr_code":"HB-0001"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{}`, http.StatusCreated)
+
+	s.send("POST", "/api/v2/processingorders/1/batches", `{"batch_id":1,"consumed_quantity":6.5}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders/1/batches", `{"batch_id":1,"consumed_quantity":1}`, http.StatusConflict)
+	s.send("POST", "/api/v2/processingorders/1/batches", `{"batch_id":2,"consumed_quantity":1}`, http.StatusUnprocessableEntity)
+	refused := s.send("POST", "/api/v2/processingorders/2/batches", `{"batch_id":1,"consumed_quantity":4}`, http.StatusConflict)
+	if refused["code"] != "insufficient_batch_quantity" {
+		t.Errorf("over-consumption = %v", refused)
+	}
+	s.send("POST", "/api/v2/processingorders/2/batches", `{"batch_id":1,"consumed_quantity":3.5}`, http.StatusCreated)
+
+	order := s.send("GET", "/api/v2/processingorders/1", "", http.StatusOK)
+	inputs, _ := order["inputs"].([]interface{})
+	if len(inputs) != 1 || inputs[0].(map[string]interface{})["consumed_quantity"] != 6.5 {
+		t.Errorf("order inputs = %v", order)
+	}
+
+	// Detaching frees the quantity for other orders
+	s.send("DELETE", "/api/v2/processingorders/1/batches/1", "", http.StatusOK)
+	s.send("DELETE", "/api/v2/processingorders/1/batches/1", "", http.StatusNotFound)
+	s.send("POST", "/api/v2/processingorders/2/batches", `{"batch_id":1,"consumed_quantity":1}`, http.StatusConflict)
+	if order := s.send("GET", "/api/v2/processingorders/1", "", http.StatusOK); order["inputs"] != nil {
+		t.Errorf("inputs after detaching = %v", order["inputs"])
+	}
+}
+
+func TestProcessingLifecycle(t *testing.T) {
+	s := newServer(t)
+
+	s.send("POST", "/api/v2/warehouses", `{"name":"North Yard"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/harvestbatches", `{"quantity":10,"qr_code":"HB-0001"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems", `{"warehouse_id":1,"product_type_id":1,"batch_id":1,"quantity_in_stock":10}`, http.StatusCreated)
+	created := s.send("POST", "/api/v2/processingorders", `{"product_type_id":2,"warehouse_id":1,"efficiency_rate":99}`, http.StatusCreated)
+	if created["status"] != "planned" || created["efficiency_rate"] != 0.0 {
+		t.Errorf("created = %v", created)
+	}
+	s.send("POST", "/api/v2/processingorders/1/batches", `{"batch_id":1,"consumed_quantity":8}`, http.StatusCreated)
+
+	if out := s.send("POST", "/api/v2/processingorders/1/complete", `{"output_quantity":6}`, http.StatusConflict); out["code"] != "invalid_transition" {
+		t.Errorf("completing a planned order = %v", out)
+	}
+	s.send("POST", "/api/v2/processingorders/1/release", "", http.StatusOK)
+	if started := s.send("POST", "/api/v2/processingorders/1/start", "", http.StatusOK); started["start_date"] == "" {
+		t.Errorf("started = %v", started)
+	}
+	if out := s.send("PUT", "/api/v2/processingorders/1", `{"product_type_id":3}`, http.StatusConflict); out["code"] != "not_editable" {
+		t.Errorf("editing an order in progress = %v", out)
+	}
+	s.send("POST", "/api/v2/processingorders/1/complete", `{}`, http.StatusUnprocessableEntity)
+
+	s.send("POST", "/api/v2/wasterecords", `{"processing_id":1,"volume":1.5}`, http.StatusCreated)
+	done := s.send("POST", "/api/v2/processingorders/1/complete", `{"output_quantity":6}`, http.StatusOK)
+	if done["status"] != "completed" || done["input_quantity"] != 8.0 || done["efficiency_rate"] != 75.0 ||
+		done["mass_balance_variance"] != 0.5 || done["mass_balance_flagged"] != true {
+		t.Errorf("completed = %v", done)
+	}
+
+	// The batch's lot was issued and the output received as a lot of the order
+	page, _ := s.repos.Stock.ListStockItems(context.Background(), query.Spec{})
+	if len(page.Data) != 2 || page.Data[0].Quantity != 2 || page.Data[1].Quantity != 6 ||
+		page.Data[1].ProcessingID == nil || *page.Data[1].ProcessingID != 1 {
+		t.Errorf("stock = %+v", page.Data)
+	}
+	s.send("POST", "/api/v2/processingorders/1/batches", `{"batch_id":1,"consumed_quantity":1}`, http.StatusConflict)
+
+	// Waste weighed after completion balances the order when it is closed
+	s.send("POST", "/api/v2/wasterecords", `{"processing_id":1,"volume":0.5}`, http.StatusCreated)
+	closed := s.send("POST", "/api/v2/processingorders/1/close", "", http.StatusOK)
+	if closed["status"] != "closed" || closed["mass_balance_variance"] != 0.0 || closed["mass_balance_flagged"] != false {
+		t.Errorf("closed = %v", closed)
+	}
+	s.send("DELETE", "/api/v2/processingorders/1", "", http.StatusConflict)
+}
+
+func TestProcessingSchedule(t *testing.T) {
+	s := newServer(t)
+	slots := func(plan map[string]interface{}) map[float64]string {
+		placed := map[float64]string{}
+		for _, p := range plan["placements"].([]interface{}) {
+			p := p.(map[string]interface{})
+			placed[p["processing_id"].(float64)] = fmt.Sprintf("unit %v %v-%v", p["unit_id"], p["start"], p["end"])
+		}
+		return placed
+	}
+
+	s.send("POST", "/api/v2/sawmills", `{"name":"Main","capacity":30,"status":"operational"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"cutting":"band saw","capacity":20,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"drying":"kiln","capacity":10,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/maintenancerecords", `{"unit_id":1,"maintenance_date":"2030-01-01","downtime_hours":4}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"planned_quantity":40,"operations":"cutting"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"planned_quantity":30,"duration_hours":3,"operations":"Cutting"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"planned_quantity":30,"duration_hours":5,"operations":"drying"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"duration_hours":1,"operations":"finishing"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"operations":"planing"}`, http.StatusUnprocessableEntity)
+	// An order on a unit of another sawmill is left to that sawmill's runs
+	s.send("POST", "/api/v2/sawmills", `{"name":"East","capacity":10,"status":"operational"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":2,"cutting":"band saw","capacity":10,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingorders", `{"unit_id":3,"duration_hours":1,"operations":"cutting"}`, http.StatusCreated)
+
+	want := map[float64]string{
+		1: "unit 1 2030-01-01T04:00:00Z-2030-01-01T06:00:00Z", // after the maintenance window
+		2: "unit 1 2030-01-01T06:00:00Z-2030-01-01T09:00:00Z",
+		3: "unit 2 2030-01-01T00:00:00Z-2030-01-01T05:00:00Z",
+	}
+	whatIf := s.send("POST", "/api/v2/sawmills/1/schedule/dry-run", `{"from":"2030-01-01"}`, http.StatusOK)
+	if got := slots(whatIf); !reflect.DeepEqual(got, want) {
+		t.Errorf("dry run placements = %v", got)
+	}
+	if unplaced := whatIf["unplaced"].([]interface{}); len(unplaced) != 1 ||
+		unplaced[0].(map[string]interface{})["code"] != "no_compatible_unit" {
+		t.Errorf("dry run unplaced = %v", unplaced)
+	}
+	if order := s.send("GET", "/api/v2/processingorders/1", "", http.StatusOK); order["scheduled_start"] != nil {
+		t.Errorf("dry run stored a slot: %v", order)
+	}
+
+	plan := s.send("POST", "/api/v2/sawmills/1/schedule", `{"from":"2030-01-01"}`, http.StatusOK)
+	if got := slots(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("placements = %v", got)
+	}
+	if order := s.send("GET", "/api/v2/processingorders/1", "", http.StatusOK); order["unit_id"] != 1.0 ||
+		order["scheduled_start"] != "2030-01-01T04:00:00Z" {
+		t.Errorf("scheduled order = %v", order)
+	}
+	timeline := s.send("GET", "/api/v2/sawmills/1/schedule?from=2030-01-01&to=2030-01-02", "", http.StatusOK)
+	lanes := timeline["lanes"].([]interface{})
+	if len(lanes) != 2 || len(lanes[0].(map[string]interface{})["bars"].([]interface{})) != 2 ||
+		len(lanes[0].(map[string]interface{})["maintenance"].([]interface{})) != 1 ||
+		len(timeline["overloads"].([]interface{})) != 0 {
+		t.Errorf("timeline = %v", timeline)
+	}
+
+	// Orders 1 and 3 running together overload a smaller sawmill
+	s.send("PUT", "/api/v2/sawmills/1", `{"name":"Main","capacity":25,"status":"operational"}`, http.StatusOK)
+	timeline = s.send("GET", "/api/v2/sawmills/1/schedule?from=2030-01-01&to=2030-01-02", "", http.StatusOK)
+	overloads := timeline["overloads"].([]interface{})
+	if len(overloads) != 1 {
+		t.Fatalf("overloads = %v", overloads)
+	}
+	if o := overloads[0].(map[string]interface{}); o["kind"] != "sawmill_capacity" || o["start"] != "2030-01-01T04:00:00Z" ||
+		o["end"] != "2030-01-01T05:00:00Z" || o["load"] != 26.0 {
+		t.Errorf("sawmill overload = %v", o)
+	}
+}
+
+func TestPreventiveMaintenance(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	generate := func() []interface{} {
+		t.Helper()
+		rec := s.do("POST", "/api/v2/maintenanceplans/generate", s.adminToken, "")
+		var raised []interface{}
+		json.NewDecoder(rec.Body).Decode(&raised)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("generate: status %d", rec.Code)
+		}
+		return raised
+	}
+	unitStatus := func(id int) string {
+		t.Helper()
+		page, _ := s.repos.Processing.ListProcessingUnits(ctx, query.Spec{})
+		return page.Data[id-1].Status
+	}
+
+	s.send("POST", "/api/v2/sawmills", `{"name":"Main","capacity":30,"status":"operational"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"cutting":"band saw","capacity":10,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"drying":"kiln","capacity":5,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/maintenanceplans", `{"unit_id":1,"description":"Blade change","interval_hours":8}`, http.StatusCreated)
+	s.send("POST", "/api/v2/maintenanceplans", `{"unit_id":2,"interval_days":30,"last_done_at":"2020-01-01"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/maintenanceplans", `{"unit_id":2,"interval_hours":1,"active":false}`, http.StatusCreated)
+	s.send("POST", "/api/v2/maintenanceplans", `{"unit_id":1}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/maintenanceplans", `{"unit_id":9,"interval_days":7}`, http.StatusUnprocessableEntity)
+
+	// Ten operating hours on unit 1 put its blade change due
+	today := time.Now().UTC().Format("2006-01-02")
+	for _, po := range []models.ProcessingOrder{
+		{UnitID: 1, Status: "completed", EndDate: today, DurationHours: 6, OutputQuantity: 50},
+		{UnitID: 1, Status: "closed", EndDate: today, DurationHours: 4, OutputQuantity: 30},
+	} {
+		if err := s.repos.Processing.CreateProcessingOrder(ctx, &po); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.send("POST", "/api/v2/qualityinspections", `{"processing_id":2,"batch_id":1,"result":"fail"}`, http.StatusCreated)
+
+	raised := generate()
+	if len(raised) != 2 || raised[0].(map[string]interface{})["reason"] != "hours" ||
+		raised[1].(map[string]interface{})["reason"] != "days" {
+		t.Fatalf("raised = %v", raised)
+	}
+	if unitStatus(1) != "maintenance" || unitStatus(2) != "maintenance" {
+		t.Errorf("unit statuses = %s, %s", unitStatus(1), unitStatus(2))
+	}
+	if again := generate(); len(again) != 0 {
+		t.Errorf("raised again = %v", again)
+	}
+	var audited []string
+	logs, _ := s.repos.Audit.ListAuditLogs(ctx, query.Spec{Sort: []query.Order{{Field: "log_id"}}})
+	for _, entry := range logs.Data {
+		if entry.EntityAffected == "MaintenanceWorkOrder" && entry.Description == "POST /api/v2/maintenanceplans/generate" {
+			audited = append(audited, entry.EntityID)
+		}
+	}
+	if want := []string{"1,2", ""}; !reflect.DeepEqual(audited, want) {
+		t.Errorf("generate runs audited on plans %q, want %q", audited, want)
+	}
+
+	done := s.send("POST", "/api/v2/maintenanceworkorders/1/complete", `{"cost":150,"downtime_hours":2.5}`, http.StatusOK)
+	if done["status"] != "completed" || done["maintenance_id"] != 1.0 || unitStatus(1) != "active" {
+		t.Errorf("completed work order = %v, unit %s", done, unitStatus(1))
+	}
+	plans, _ := s.repos.Maintenance.ListMaintenancePlans(ctx, query.Spec{})
+	if plans.Data[0].LastDoneHours != 10 {
+		t.Errorf("plan after service = %+v", plans.Data[0])
+	}
+	s.send("POST", "/api/v2/maintenanceworkorders/1/cancel", "", http.StatusConflict)
+	s.send("POST", "/api/v2/maintenanceworkorders/2/cancel", "", http.StatusOK)
+	if unitStatus(2) != "active" {
+		t.Errorf("unit 2 after cancel = %s", unitStatus(2))
+	}
+	s.send("POST", "/api/v2/maintenanceworkorders", `{"unit_id":1,"plan_id":2}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/maintenanceworkorders", `{"unit_id":1,"description":"Noisy bearing"}`, http.StatusCreated)
+	if unitStatus(1) != "maintenance" {
+		t.Errorf("unit 1 after manual work order = %s", unitStatus(1))
+	}
+
+	// 10 h run and 2.5 h down; 80 of an ideal 100 made, 30 of it failed
+	oee := s.send("GET", "/api/v2/processingunits/1/oee", "", http.StatusOK)
+	for field, want := range map[string]float64{"availability": 80, "performance": 80, "quality": 62.5, "oee": 40} {
+		if oee[field] != want {
+			t.Errorf("unit %s = %v, want %v", field, oee[field], want)
+		}
+	}
+	mill := s.send("GET", "/api/v2/sawmills/1/oee", "", http.StatusOK)
+	units := mill["units"].([]interface{})
+	if mill["oee"] != 40.0 || len(units) != 2 || units[1].(map[string]interface{})["availability"] != nil {
+		t.Errorf("sawmill oee = %v", mill)
+	}
+}
+
+func TestKilnCharges(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	upload := func(csv string, status int) map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/v2/kilncharges/1/readings", strings.NewReader(csv))
+		req.Header.Set("Authorization", "Bearer "+s.adminToken)
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+		rec := httptest.NewRecorder()
+		s.mux.ServeHTTP(rec, req)
+		var out map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&out)
+		if rec.Code != status {
+			t.Fatalf("CSV upload: status %d, want %d; body %v", rec.Code, status, out)
+		}
+		return out
+	}
+
+	s.send("POST", "/api/v2/sawmills", `{"name":"Main","capacity":30,"status":"operational"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"cutting":"band saw","capacity":10,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"drying":"kiln","capacity":5,"status":"active"}`, http.StatusCreated)
+	if err := s.repos.Forests.CreateTreeSpecies(ctx, &models.TreeSpecies{SpeciesName: "Pine", MoistureContent: 12}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Forests.CreateHarvestBatch(ctx, &models.HarvestBatch{SpeciesID: 1, Quantity: 10}); err != nil {
+		t.Fatal(err)
+	}
+	for _, grade := range []string{"KD19", "A"} {
+		if err := s.repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Board " + grade, Grade: grade}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batch := 1
+	for _, si := range []models.StockItem{{ProductTypeID: 1}, {ProductTypeID: 2, BatchID: &batch}, {ProductTypeID: 2}} {
+		if err := s.repos.Stock.CreateStockItem(ctx, &si); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.send("POST", "/api/v2/kilncharges", `{"unit_id":1,"stock_ids":[1]}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/kilncharges", `{"unit_id":2,"stock_ids":[3]}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/kilncharges", `{"unit_id":2,"stock_ids":[9]}`, http.StatusUnprocessableEntity)
+	charge := s.send("POST", "/api/v2/kilncharges",
+		`{"unit_id":2,"stock_ids":[2],"planned_hours":48,"started_at":"2026-01-01T00:00:00Z"}`, http.StatusCreated)
+	if charge["target_moisture"] != 12.0 || charge["target_source"] != "species" || charge["tolerance"] != 1.0 {
+		t.Fatalf("charge = %v", charge)
+	}
+	s.send("POST", "/api/v2/kilncharges", `{"unit_id":2,"stock_ids":[1]}`, http.StatusConflict)
+
+	s.send("POST", "/api/v2/kilncharges/1/readings", `[{"recorded_at":"2026-01-01T06:00:00Z","humidity":120}]`, http.StatusUnprocessableEntity)
+	up := s.send("POST", "/api/v2/kilncharges/1/readings",
+		`[{"recorded_at":"2026-01-01T06:00:00Z","temperature":60,"humidity":70,"moisture":30}]`, http.StatusCreated)
+	if c := up["charge"].(map[string]interface{}); c["status"] != "drying" || c["moisture"] != 30.0 {
+		t.Errorf("after first reading = %v", c)
+	}
+
+	// The curve is read in time order whatever order the rows arrive in
+	up = upload("recorded_at,temperature,humidity,moisture\n2026-01-02 12:00,70,40,12.8\n2026-01-01 12:00,65,55,20\n", http.StatusCreated)
+	c := up["charge"].(map[string]interface{})
+	if up["readings"] != 2.0 || c["status"] != "completed" || c["completed_at"] != "2026-01-02T12:00:00Z" || c["flag"] != "" {
+		t.Errorf("after CSV upload = %v", up)
+	}
+	upload("recorded_at,moisture\n2026-01-03 00:00,ten\n", http.StatusUnprocessableEntity)
+	upload("recorded_at,moisture\n"+strings.Repeat("2026-01-03 00:00,10\n", kiln.MaxReadings+1), http.StatusUnprocessableEntity)
+	upload("recorded_at,moisture,note\n"+strings.Repeat("2026-01-03 00:00,10,"+strings.Repeat("x", 1000)+"\n", kiln.MaxUploadBytes/1000),
+		http.StatusRequestEntityTooLarge)
+	s.send("POST", "/api/v2/kilncharges/1/readings", "["+strings.Repeat(`{"recorded_at":"2026-01-03T00:00:00Z"},`, kiln.MaxUploadBytes/30)+"]",
+		http.StatusRequestEntityTooLarge)
+	up = upload("time,moisture\n2026-01-03 00:00,10\n", http.StatusCreated)
+	if c := up["charge"].(map[string]interface{}); c["flag"] != "over_dried" || c["moisture"] != 10.0 {
+		t.Errorf("after over-drying = %v", c)
+	}
+
+	curve := s.send("GET", "/api/v2/kilncharges/1/readings", "", http.StatusOK)
+	data := curve["data"].([]interface{})
+	if curve["total"] != 4.0 || data[0].(map[string]interface{})["recorded_at"] != "2026-01-01T06:00:00Z" {
+		t.Errorf("curve = %v", curve)
+	}
+	s.send("POST", "/api/v2/kilncharges/1/unload", "", http.StatusOK)
+	s.send("POST", "/api/v2/kilncharges/1/unload", "", http.StatusConflict)
+	s.send("POST", "/api/v2/kilncharges/1/readings", `[{"recorded_at":"2026-01-04T00:00:00Z","moisture":9}]`, http.StatusConflict)
+
+	// A charge unloaded before reaching its target is under-dried
+	charge = s.send("POST", "/api/v2/kilncharges", `{"unit_id":2,"stock_ids":[1,2]}`, http.StatusCreated)
+	if charge["target_moisture"] != 19.0 || charge["target_source"] != "grade" {
+		t.Errorf("mixed charge = %v", charge)
+	}
+	s.send("PUT", "/api/v2/kilncharges/2", `{"target_moisture":15}`, http.StatusOK)
+	if c := s.send("POST", "/api/v2/kilncharges/2/unload", "", http.StatusOK); c["flag"] != "under_dried" || c["target_moisture"] != 15.0 {
+		t.Errorf("unloaded early = %v", c)
+	}
+	if got := s.send("GET", "/api/v2/kilncharges/2", "", http.StatusOK); len(got["stock_ids"].([]interface{})) != 2 {
+		t.Errorf("charge 2 = %v", got)
+	}
+}
+
+func TestWasteValorisationAndAnalytics(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	groups := func(groupBy string) map[string]map[string]interface{} {
+		t.Helper()
+		report := s.send("GET", "/api/v2/wasteanalytics?group_by="+groupBy, "", http.StatusOK)
+		byKey := map[string]map[string]interface{}{"total": report["total"].(map[string]interface{})}
+		for _, g := range report["groups"].([]interface{}) {
+			byKey[g.(map[string]interface{})["key"].(string)] = g.(map[string]interface{})
+		}
+		return byKey
+	}
+
+	for _, name := range []string{"North", "South"} {
+		s.send("POST", "/api/v2/sawmills", `{"name":"`+name+`","capacity":30,"status":"operational"}`, http.StatusCreated)
+	}
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":1,"cutting":"band saw","capacity":10,"status":"active"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/processingunits", `{"sawmill_id":2,"cutting":"band saw","capacity":10,"status":"active"}`, http.StatusCreated)
+	for _, name := range []string{"Pine", "Spruce"} {
+		if err := s.repos.Forests.CreateTreeSpecies(ctx, &models.TreeSpecies{SpeciesName: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for species := 1; species <= 2; species++ {
+		if err := s.repos.Forests.CreateHarvestBatch(ctx, &models.HarvestBatch{SpeciesID: species, Quantity: 100}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, po := range []models.ProcessingOrder{
+		{UnitID: 1, StartDate: "2026-01-10", OutputQuantity: 70},
+		{UnitID: 2, StartDate: "2026-02-05", OutputQuantity: 40},
+	} {
+		if err := s.repos.Processing.CreateProcessingOrder(ctx, &po); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, link := range []models.HarvestBatchProcessing{
+		{ProcessingID: 1, BatchID: 1, ConsumedQuantity: 60},
+		{ProcessingID: 1, BatchID: 2, ConsumedQuantity: 40},
+		{ProcessingID: 2, BatchID: 2, ConsumedQuantity: 50},
+	} {
+		if err := s.repos.Processing.AttachHarvestBatch(ctx, &link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: "Yard"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Pellets", UnitOfMeasure: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	s.send("POST", "/api/v2/wasterecords", `{"processing_id":1,"waste_type":"Sawdust","volume":10,"disposal_method":"landfill","disposal_cost":50}`, http.StatusCreated)
+	s.send("POST", "/api/v2/wasterecords", `{"processing_id":1,"waste_type":"Bark","volume":5,"disposal_method":"biomass","recycled":true}`, http.StatusCreated)
+	s.send("POST", "/api/v2/wasterecords", `{"processing_id":2,"waste_type":"Offcuts","volume":5,"disposal_method":"landfill","disposal_cost":20}`, http.StatusCreated)
+
+	// Valorising the sawdust receives pellets into a lot of its order
+	s.send("POST", "/api/v2/wasterecords/1/valorise", `{"product_type_id":1,"warehouse_id":9}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/wasterecords/1/valorise", `{"product_type_id":1,"warehouse_id":1,"quantity":0}`, http.StatusUnprocessableEntity)
+	v := s.send("POST", "/api/v2/wasterecords/1/valorise", `{"product_type_id":1,"warehouse_id":1,"quantity":8}`, http.StatusCreated)
+	if tx := v["transaction"].(map[string]interface{}); tx["transaction_type"] != "receipt" || tx["quantity"] != 8.0 {
+		t.Errorf("valorisation = %v", v)
+	}
+	s.send("POST", "/api/v2/wasterecords/1/valorise", `{"product_type_id":1,"warehouse_id":1}`, http.StatusConflict)
+	s.send("PUT", "/api/v2/wasterecords/1", `{"processing_id":1,"waste_type":"Sawdust","volume":10,"disposal_method":"pellets","recycled":true,"disposal_cost":50}`, http.StatusOK)
+	lots, _ := s.repos.Stock.ListStockItems(ctx, query.Spec{})
+	records, _ := s.repos.Processing.ListWasteRecords(ctx, query.Spec{})
+	if len(lots.Data) != 1 || lots.Data[0].Quantity != 8 || *lots.Data[0].ProcessingID != 1 ||
+		records.Data[0].StockID == nil || *records.Data[0].StockID != lots.Data[0].StockID {
+		t.Errorf("by-product lot = %+v, waste = %+v", lots.Data, records.Data[0])
+	}
+
+	check := func(g map[string]interface{}, want map[string]float64) {
+		t.Helper()
+		for field, value := range want {
+			if g[field] != value {
+				t.Errorf("%s %s = %v, want %v", g["key"], field, g[field], value)
+			}
+		}
+	}
+	mills := groups("sawmill")
+	check(mills["total"], map[string]float64{"waste_volume": 20, "input_volume": 150, "waste_percent": 13.33,
+		"recycling_rate": 75, "valorised_volume": 10, "disposal_cost": 70})
+	check(mills["1"], map[string]float64{"waste_percent": 15, "recycling_rate": 100, "disposal_cost": 50})
+	check(mills["2"], map[string]float64{"waste_percent": 10, "recycling_rate": 0, "disposal_cost": 20})
+
+	// The first order's waste is split 60/40 between its species
+	species := groups("species")
+	check(species["1"], map[string]float64{"waste_volume": 9, "input_volume": 60, "waste_percent": 15})
+	check(species["2"], map[string]float64{"waste_volume": 11, "input_volume": 90, "waste_percent": 12.22})
+
+	months := s.send("GET", "/api/v2/wasteanalytics?group_by=month&from=2026-02-01", "", http.StatusOK)
+	if g := months["groups"].([]interface{}); len(g) != 1 || g[0].(map[string]interface{})["key"] != "2026-02" {
+		t.Errorf("months from February = %v", months)
+	}
+	check(groups("waste_type")["sawdust"], map[string]float64{"waste_percent": 6.67, "records": 1})
+	s.send("GET", "/api/v2/wasteanalytics?group_by=colour", "", http.StatusBadRequest)
+}
+
+func TestInspectionGrading(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+
+	if err := s.repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: "Yard"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Oak boards", UnitOfMeasure: "m3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Forests.CreateHarvestBatch(ctx, &models.HarvestBatch{Quantity: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Processing.CreateProcessingOrder(ctx, &models.ProcessingOrder{ProductTypeID: 1, StartDate: "2026-03-01"}); err != nil {
+		t.Fatal(err)
+	}
+	one := 1
+	for _, lot := range []models.StockItem{
+		{ProductTypeID: 1, WarehouseID: 1, BatchID: &one, ProcessingID: &one},
+		{ProductTypeID: 1, WarehouseID: 1, BatchID: &one},
+	} {
+		if err := s.repos.Stock.CreateStockItem(ctx, &lot); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if dc := s.send("POST", "/api/v2/defectcodes", `{"code":"kn-1","name":"Loose knot","category":"knots"}`, http.StatusCreated); dc["code"] != "KN-1" || dc["severity"] != "minor" {
+		t.Errorf("defect code = %v", dc)
+	}
+	s.send("POST", "/api/v2/defectcodes", `{"code":"KN-1","name":"Again"}`, http.StatusConflict)
+	s.send("POST", "/api/v2/defectcodes", `{"code":"SPL","name":"End split","category":"split","severity":"critical"}`, http.StatusCreated)
+
+	template := `{"name":"Kiln dried boards","product_type_id":1,"stage":"Kiln Dried",
+		"items":[{"kind":"knots","label":"Knots per metre","max":6,"required":true},
+			{"kind":"moisture","unit":"%","min":8,"max":15,"required":true},{"kind":"wane","max":10}],
+		"grades":[{"grade":"FAS","max_knots":2,"max_wane":0,"max_minor_defects":0,"max_critical_defects":0},
+			{"grade":"Select","max_knots":4,"max_wane":5,"max_critical_defects":0},
+			{"grade":"#1 Common","max_critical_defects":0}]}`
+	s.send("POST", "/api/v2/inspectiontemplates", `{"name":"Bad","stage":"finished","items":[{"kind":"warp","min":5,"max":1}]}`, http.StatusUnprocessableEntity)
+	if tpl := s.send("POST", "/api/v2/inspectiontemplates", template, http.StatusCreated); tpl["stage"] != "kiln_dried" || tpl["active"] != true {
+		t.Errorf("template = %v", tpl)
+	}
+	s.send("POST", "/api/v2/inspectiontemplates", template, http.StatusConflict)
+	if tpl := s.send("GET", "/api/v2/inspectiontemplates/1", "", http.StatusOK); len(tpl["items"].([]interface{})) != 3 || len(tpl["grades"].([]interface{})) != 3 {
+		t.Errorf("template 1 = %v", tpl)
+	}
+
+	// The stage picks the template of the order's product type
+	inspect := `{"employee_id":1,"processing_id":1,"batch_id":1,"stage":"kiln_dried",
+		"measurements":[{"position":1,"value":3},{"position":2,"value":12}],"defects":[{"code":"kn-1"}]}`
+	s.send("POST", "/api/v2/qualityinspections", `{"processing_id":1,"batch_id":1,"stage":"kiln_dried","measurements":[{"position":1,"value":3}]}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/qualityinspections", `{"processing_id":1,"batch_id":1,"stage":"kiln_dried","measurements":[{"position":1,"value":3},{"position":2,"value":12}],"defects":[{"code":"ROT"}]}`, http.StatusUnprocessableEntity)
+	qi := s.send("POST", "/api/v2/qualityinspections", inspect, http.StatusCreated)
+	if qi["result"] != "pass" || qi["grade"] != "Select" || qi["template_id"] != 1.0 || qi["moisture_level"] != 12.0 {
+		t.Errorf("inspection = %v", qi)
+	}
+	lots, _ := s.repos.Stock.ListStockItems(ctx, query.Spec{})
+	if lots.Data[0].Grade != "Select" || lots.Data[0].GradedBy == nil || *lots.Data[0].GradedBy != 1 || lots.Data[1].Grade != "" {
+		t.Errorf("lots = %+v", lots.Data)
+	}
+
+	// A critical defect leaves no grade; moisture out of bounds fails the checklist
+	qi = s.send("POST", "/api/v2/qualityinspections", strings.Replace(inspect, "kn-1", "SPL", 1), http.StatusCreated)
+	if qi["result"] != "fail" || qi["grade"] != "" {
+		t.Errorf("split inspection = %v", qi)
+	}
+	s.send("PUT", "/api/v2/qualityinspections/2", strings.Replace(inspect, `"value":12`, `"value":18`, 1), http.StatusOK)
+	got := s.send("GET", "/api/v2/qualityinspections/2", "", http.StatusOK)
+	if got["result"] != "fail" || got["measurements"].([]interface{})[1].(map[string]interface{})["passed"] != false {
+		t.Errorf("inspection 2 = %v", got)
+	}
+
+	// Without a template the result is taken as given
+	if qi := s.send("POST", "/api/v2/qualityinspections", `{"batch_id":1,"result":"fail"}`, http.StatusCreated); qi["result"] != "fail" || qi["grade"] != "" {
+		t.Errorf("plain inspection = %v", qi)
+	}
+}
+
+func TestStockQuarantine(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	lot := func(id int) models.StockItem {
+		t.Helper()
+		spec := query.Spec{}
+		spec.Where("stock_id", id)
+		page, err := s.repos.Stock.ListStockItems(ctx, spec)
+		if err != nil || len(page.Data) != 1 {
+			t.Fatalf("stock item %d: %v", id, err)
+		}
+		return page.Data[0]
+	}
+
+	if err := s.repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: "Yard"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Pine boards", UnitOfMeasure: "m3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Processing.CreateProcessingOrder(ctx, &models.ProcessingOrder{ProductTypeID: 1, StartDate: "2026-03-01"}); err != nil {
+		t.Fatal(err)
+	}
+	one := 1
+	for _, si := range []models.StockItem{
+		{ProductTypeID: 1, WarehouseID: 1, BatchID: &one, ProcessingID: &one, Quantity: 10},
+		{ProductTypeID: 1, WarehouseID: 1, BatchID: &one, Quantity: 5},
+		{ProductTypeID: 1, WarehouseID: 1, POItemID: &one, Quantity: 4},
+	} {
+		if err := s.repos.Stock.CreateStockItem(ctx, &si); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.send("POST", "/api/v2/salesorders", `{"customer_id":1,"order_date":"2026-03-02"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/salesorders/1/items", `{"product_type_id":1,"stock_id":1,"quantity":2}`, http.StatusCreated)
+
+	// A failed inspection of the order holds its output but not the raw lot
+	s.send("POST", "/api/v2/qualityinspections", `{"processing_id":1,"batch_id":1,"result":"fail"}`, http.StatusCreated)
+	if lot(1).Status != "quarantine" || lot(2).Status != "available" {
+		t.Fatalf("lots after failed inspection = %+v, %+v", lot(1), lot(2))
+	}
+	s.send("POST", "/api/v2/salesorders/1/items", `{"product_type_id":1,"stock_id":1,"quantity":1}`, http.StatusConflict)
+	s.send("POST", "/api/v2/shipments", `{"soid":1,"status":"scheduled"}`, http.StatusConflict)
+	s.send("POST", "/api/v2/shipments", `{"soid":1,"status":"cancelled"}`, http.StatusCreated)
+	for _, tx := range []string{
+		`{"stock_id":1,"transaction_type":"issue","quantity":1}`,
+		`{"stock_id":1,"transaction_type":"Scrap","quantity":1}`,
+		`{"stock_id":1,"transaction_type":"transfer_out","quantity":1}`,
+	} {
+		if refused := s.send("POST", "/api/v2/inventorytransactions", tx, http.StatusConflict); refused["code"] != "stock_unavailable" {
+			t.Errorf("%s on a held lot = %v", tx, refused)
+		}
+	}
+	history := s.send("GET", "/api/v2/stockitems/1/dispositions", "", http.StatusOK)["data"].([]interface{})
+	if d := history[0].(map[string]interface{}); len(history) != 1 || d["action"] != "quarantine" || d["inspection_id"] != 1.0 {
+		t.Errorf("dispositions of lot 1 = %v", history)
+	}
+
+	// Scrap and rework take part of the lot, which stays held
+	scrap := s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"scrap","quantity":3,"reason":"rot"}`, http.StatusCreated)
+	if w := scrap["waste"].(map[string]interface{}); w["processing_id"] != 1.0 || w["volume"] != 3.0 ||
+		scrap["transaction"].(map[string]interface{})["quantity"] != -3.0 {
+		t.Errorf("scrap = %v", scrap)
+	}
+	s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"rework","quantity":2}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"rework","quantity":20,"unit_id":1}`, http.StatusUnprocessableEntity)
+	rework := s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"rework","quantity":2,"unit_id":1,"operations":"finishing"}`, http.StatusCreated)
+	if o := rework["order"].(map[string]interface{}); o["status"] != "planned" || o["planned_quantity"] != 2.0 || rework["stock"].(map[string]interface{})["status"] != "quarantine" {
+		t.Errorf("rework = %v", rework)
+	}
+	s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"return_to_supplier"}`, http.StatusConflict)
+	if l := lot(1); l.Quantity != 5 {
+		t.Errorf("lot 1 after scrap and rework = %+v", l)
+	}
+
+	// Once released the order ships
+	s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"release"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/stockitems/1/dispositions", `{"action":"release"}`, http.StatusConflict)
+	s.send("POST", "/api/v2/shipments", `{"soid":1,"status":"scheduled"}`, http.StatusCreated)
+
+	// A failed intake inspection of a purchase holds its lot, which goes back
+	s.send("POST", "/api/v2/qualityinspections", `{"po_item_id":1,"result":"Fail"}`, http.StatusCreated)
+	back := s.send("POST", "/api/v2/stockitems/3/dispositions", `{"action":"return_to_supplier"}`, http.StatusCreated)
+	if l := lot(3); l.Status != "rejected" || l.Quantity != 0 || back["disposition"].(map[string]interface{})["quantity"] != 4.0 {
+		t.Errorf("returned lot = %+v, %v", l, back)
+	}
+
+	// Reserved raw stock is held by a failed inspection of its batch
+	s.send("POST", "/api/v2/stockitems/2/dispositions", `{"action":"reserve"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/qualityinspections", `{"batch_id":1,"result":"fail"}`, http.StatusCreated)
+	if lot(2).Status != "quarantine" || lot(1).Status != "available" {
+		t.Errorf("lots after batch inspection = %+v, %+v", lot(1), lot(2))
+	}
+	if all := s.send("GET", "/api/v2/stockdispositions?action=quarantine", "", http.StatusOK); all["total"] != 3.0 {
+		t.Errorf("quarantines = %v", all)
+	}
+	s.send("GET", "/api/v2/stockitems/9/dispositions", "", http.StatusNotFound)
+
+	// Held raw stock is not consumed by processing either
+	s.send("POST", "/api/v2/harvestbatches", `{"quantity":10,"qr_code":"HB-0001"}`, http.StatusCreated)
+	order := s.send("POST", "/api/v2/processingorders", `{"product_type_id":1,"warehouse_id":1}`, http.StatusCreated)
+	steps := fmt.Sprintf("/api/v2/processingorders/%v", order["processing_id"])
+	s.send("POST", steps+"/batches", `{"batch_id":1,"consumed_quantity":2}`, http.StatusCreated)
+	s.send("POST", steps+"/release", "", http.StatusOK)
+	s.send("POST", steps+"/start", "", http.StatusOK)
+	if out := s.send("POST", steps+"/complete", `{"output_quantity":1}`, http.StatusConflict); out["code"] != "stock_unavailable" {
+		t.Errorf("consuming a held lot = %v", out)
+	}
+	if l := lot(2); l.Quantity != 5 {
+		t.Errorf("held lot after refused consumption = %+v", l)
+	}
+
+	// Held stock does not move to another warehouse, and a lot held while in
+	// transit arrives held
+	if err := s.repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: "Depot"}); err != nil {
+		t.Fatal(err)
+	}
+	s.send("POST", "/api/v2/transfers", `{"from_warehouse_id":1,"to_warehouse_id":2,"lines":[{"stock_id":2,"quantity":1}]}`,
+		http.StatusUnprocessableEntity)
+	transfer := s.send("POST", "/api/v2/transfers", `{"from_warehouse_id":1,"to_warehouse_id":2,"lines":[{"stock_id":1,"quantity":1}]}`,
+		http.StatusCreated)
+	moves := fmt.Sprintf("/api/v2/transfers/%v", transfer["transfer_id"])
+	s.send("POST", moves+"/dispatch", "", http.StatusOK)
+	s.send("POST", "/api/v2/qualityinspections", `{"processing_id":1,"batch_id":1,"result":"fail"}`, http.StatusCreated)
+	s.send("POST", moves+"/transit", "", http.StatusOK)
+	received := s.send("POST", moves+"/receive", "", http.StatusOK)
+	dest := lot(int(received["lines"].([]interface{})[0].(map[string]interface{})["dest_stock_id"].(float64)))
+	if dest.Status != "quarantine" || dest.ProcessingID == nil || *dest.ProcessingID != 1 || dest.Quantity != 1 {
+		t.Errorf("lot received from a held lot = %+v", dest)
+	}
+
+	// A lot keeps its disposition trail
+	s.send("DELETE", "/api/v2/stockitems/2", "", http.StatusConflict)
+}
+
+func TestNonConformanceWorkflow(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	score := func() float64 {
+		t.Helper()
+		spec := query.Spec{}
+		spec.Where("performance_id", 2) // the latest review
+		page, err := s.repos.Suppliers.ListSupplierPerformances(ctx, spec)
+		if err != nil || len(page.Data) != 1 {
+			t.Fatalf("supplier performance: %v", err)
+		}
+		return page.Data[0].QualityScore
+	}
+
+	if err := s.repos.Employees.CreateEmployee(ctx, &models.Employee{FullName: "Ana Silva"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Suppliers.CreateSupplier(ctx, &models.Supplier{CompanyName: "North Timber"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, sp := range []models.SupplierPerformance{
+		{SupplierID: 1, QualityScore: 70, ReviewDate: "2025-06-30"},
+		{SupplierID: 1, QualityScore: 90, ReviewDate: "2025-12-31"},
+	} {
+		if err := s.repos.Suppliers.CreateSupplierPerformance(ctx, &sp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.repos.PurchaseOrders.CreatePurchaseOrder(ctx, &models.PurchaseOrder{SupplierID: 1, OrderDate: "2026-01-02"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.PurchaseOrders.CreatePurchaseOrderItem(ctx, &models.PurchaseOrderItem{POID: 1, Quantity: 20}); err != nil {
+		t.Fatal(err)
+	}
+	s.send("POST", "/api/v2/qualityinspections", `{"po_item_id":1,"result":"fail","moisture_level":24}`, http.StatusCreated)
+
+	// The supplier comes from the inspection's purchase and pays for it
+	nc := s.send("POST", "/api/v2/nonconformances", `{"title":"Wet boards","origin":"supplier","severity":"major","inspection_id":1,"raised_by":1}`, http.StatusCreated)
+	if nc["supplier_id"] != 1.0 || nc["status"] != "open" || nc["score_deducted"] != 5.0 || score() != 85 {
+		t.Fatalf("report = %v, score %v", nc, score())
+	}
+	s.send("PUT", "/api/v2/nonconformances/1", `{"title":"Wet boards","origin":"supplier","severity":"critical","inspection_id":1}`, http.StatusOK)
+	if score() != 80 {
+		t.Errorf("score after raising severity = %v", score())
+	}
+	s.send("POST", "/api/v2/nonconformances", `{"title":"Planer chatter","origin":"process"}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/nonconformances", `{"title":"Wet boards","origin":"supplier","raised_by":7,"supplier_id":1}`, http.StatusUnprocessableEntity)
+
+	// Each step needs what the next state relies on
+	s.send("POST", "/api/v2/nonconformances/1/act", `{"root_cause":"Kiln fault"}`, http.StatusConflict)
+	s.send("POST", "/api/v2/nonconformances/1/investigate", "", http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/nonconformances/1/investigate", `{"owner_id":1}`, http.StatusOK)
+	s.send("POST", "/api/v2/nonconformances/1/act", "", http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/nonconformances/1/act", `{"root_cause":"Supplier kiln fault"}`, http.StatusConflict)
+	s.send("POST", "/api/v2/nonconformances/1/actions", `{"kind":"corrective","description":"Redry the lot","owner_id":1,"due_date":"2026-01-10"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/nonconformances/1/actions", `{"kind":"preventive","description":"Moisture check at intake","owner_id":1,"due_date":"2026-03-01"}`, http.StatusCreated)
+	s.send("POST", "/api/v2/nonconformances/1/actions", `{"kind":"preventive","description":"Audit","owner_id":9,"due_date":"2026-03-01"}`, http.StatusUnprocessableEntity)
+	s.send("POST", "/api/v2/nonconformances/1/act", `{"root_cause":"Supplier kiln fault"}`, http.StatusOK)
+
+	rec := s.do("GET", "/api/v2/correctiveactions/overdue?as_of=2026-01-15", s.adminToken, "")
+	var late []map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&late)
+	if len(late) != 1 || late[0]["action_id"] != 1.0 || late[0]["days_overdue"] != 5.0 || late[0]["ncr_title"] != "Wet boards" {
+		t.Errorf("overdue actions = %v", late)
+	}
+
+	s.send("POST", "/api/v2/nonconformances/1/verify", "", http.StatusConflict)
+	s.send("POST", "/api/v2/correctiveactions/1/complete", `{"notes":"Redried to 12%"}`, http.StatusOK)
+	s.send("POST", "/api/v2/correctiveactions/1/complete", "", http.StatusConflict)
+	s.send("POST", "/api/v2/correctiveactions/2/complete", "", http.StatusOK)
+	s.send("POST", "/api/v2/nonconformances/1/verify", "", http.StatusOK)
+	s.send("POST", "/api/v2/nonconformances/1/actions", `{"kind":"corrective","description":"Late","owner_id":1,"due_date":"2026-04-01"}`, http.StatusConflict)
+	closed := s.send("POST", "/api/v2/nonconformances/1/close", "", http.StatusOK)
+	if closed["status"] != "closed" || closed["closed_at"] == nil || len(closed["actions"].([]interface{})) != 2 {
+		t.Errorf("closed report = %v", closed)
+	}
+	s.send("PUT", "/api/v2/nonconformances/1", `{"title":"Dry boards","origin":"other"}`, http.StatusConflict)
+
+	// Deleting a report raised in error gives the points back
+	s.send("POST", "/api/v2/nonconformances", `{"title":"Short count","origin":"supplier","supplier_id":1}`, http.StatusCreated)
+	if score() != 78 {
+		t.Errorf("score after a minor report = %v", score())
+	}
+	s.send("DELETE", "/api/v2/nonconformances/2", "", http.StatusOK)
+	if score() != 80 {
+		t.Errorf("score after deleting the report = %v", score())
+	}
+}
+
+func TestSPCCharts(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	get := func(target string, status int, out interface{}) {
+		t.Helper()
+		rec := s.do("GET", target, s.adminToken, "")
+		if rec.Code != status {
+			t.Fatalf("GET %s: status %d, want %d; body %s", target, rec.Code, status, rec.Body)
+		}
+		json.NewDecoder(rec.Body).Decode(out)
+	}
+
+	if err := s.repos.Forests.CreateTreeSpecies(ctx, &models.TreeSpecies{SpeciesName: "Oak"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Forests.CreateHarvestBatch(ctx, &models.HarvestBatch{SpeciesID: 1, Quantity: 40}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Processing.CreateProcessingOrder(ctx, &models.ProcessingOrder{ProductTypeID: 1, UnitID: 1, StartDate: "2026-01-01"}); err != nil {
+		t.Fatal(err)
+	}
+	// Four steady subgroups of two make the baseline; the fifth runs wet and
+	// fails, the sixth spreads wide
+	one := 1
+	moisture := []float64{12, 13, 12, 14, 13, 13, 12, 13, 18, 19, 10, 14}
+	for i, m := range moisture {
+		qi := models.QualityInspection{ProcessingID: &one, BatchID: 1, Result: "pass", MoistureLevel: m,
+			Date: fmt.Sprintf("2026-01-%02d", i+1)}
+		if i == 3 || i == 8 || i == 9 {
+			qi.Result = "fail"
+		}
+		if err := s.repos.Quality.CreateQualityInspection(ctx, &qi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.repos.Quality.CreateQualityInspection(ctx, &models.QualityInspection{BatchID: 1, Result: "pass",
+		MoistureLevel: 30, Date: "2026-01-03"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const params = "?subgroup_size=2&baseline_to=2026-01-08"
+	var report struct {
+		Groups []struct {
+			UnitID            *int   `json:"unit_id"`
+			SpeciesName       string `json:"species_name"`
+			Samples           int    `json:"samples"`
+			BaselineSubgroups int    `json:"baseline_subgroups"`
+			XBar              struct {
+				Limits *struct{ Center, UCL, LCL float64 }
+				Points []struct{ Value float64 }
+			} `json:"xbar"`
+			Violations []struct {
+				Chart        string
+				Rule         int
+				InspectionID int `json:"inspection_id"`
+			}
+		}
+	}
+	get("/api/v2/spc/charts"+params, http.StatusOK, &report)
+	if len(report.Groups) != 2 || report.Groups[0].UnitID != nil || report.Groups[1].Samples != 12 {
+		t.Fatalf("groups = %+v", report.Groups)
+	}
+	g := report.Groups[1]
+	if l := g.XBar.Limits; g.SpeciesName != "Oak" || g.BaselineSubgroups != 4 || l == nil || l.Center != 12.75 ||
+		l.UCL != 14.63 || l.LCL != 10.87 || len(g.XBar.Points) != 6 || g.XBar.Points[4].Value != 18.5 {
+		t.Errorf("unit 1 charts = %+v", g)
+	}
+	type broken struct {
+		chart      string
+		rule, last int
+	}
+	var got []broken
+	for _, v := range g.Violations {
+		got = append(got, broken{v.Chart, v.Rule, v.InspectionID})
+	}
+	if want := []broken{{"xbar", 1, 10}, {"range", 1, 12}, {"p", 1, 10}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+	get("/api/v2/spc/charts?unit_id=1&metric=knots", http.StatusOK, &report)
+	if len(report.Groups) != 0 {
+		t.Errorf("knots charts without measurements = %+v", report.Groups)
+	}
+
+	// Each violation is alerted once
+	var raised []models.QualityAlert
+	rec := s.do("POST", "/api/v2/spc/alerts"+params+"&unit_id=1", s.adminToken, "")
+	json.NewDecoder(rec.Body).Decode(&raised)
+	if rec.Code != http.StatusOK || len(raised) != 3 || raised[0].Status != "Active" || *raised[0].SpeciesID != 1 {
+		t.Fatalf("raised alerts: %d %+v", rec.Code, raised)
+	}
+	rec = s.do("POST", "/api/v2/spc/alerts"+params, s.adminToken, "")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("alerts raised again: %d %s", rec.Code, rec.Body)
+	}
+	if rec := s.do("POST", "/api/v2/qualityalerts/1/resolve", s.adminToken, ""); rec.Code != http.StatusOK {
+		t.Errorf("resolve alert: %d %s", rec.Code, rec.Body)
+	}
+	var alerts query.Page[models.QualityAlert]
+	get("/api/v2/qualityalerts?status=Active", http.StatusOK, &alerts)
+	if alerts.Total != 2 {
+		t.Errorf("active alerts = %+v", alerts)
+	}
+}
+
+func TestAcceptanceSampling(t *testing.T) {
+	s := newServer(t)
+	ctx := context.Background()
+	plan := func(out map[string]interface{}) string {
+		return fmt.Sprintf("%v %v n=%v %v/%v %v", out["severity"], out["code_letter"], out["sample_size"],
+			out["accept_number"], out["reject_number"], out["status"])
+	}
+
+	if err := s.repos.Suppliers.CreateSupplier(ctx, &models.Supplier{CompanyName: "North Timber"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: "Yard"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Oak logs"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.repos.PurchaseOrders.CreatePurchaseOrder(ctx, &models.PurchaseOrder{SupplierID: 1, OrderDate: "2026-02-01"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := s.repos.PurchaseOrders.CreatePurchaseOrderItem(ctx, &models.PurchaseOrderItem{POID: 1, ProductTypeID: 1, Quantity: 40}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	two := 2
+	if err := s.repos.Stock.CreateStockItem(ctx, &models.StockItem{ProductTypeID: 1, WarehouseID: 1, Quantity: 40, POItemID: &two}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plan naming the supplier and product type wins over the general one
+	s.send("POST", "/api/v2/samplingplans", `{"aql":6.5}`, http.StatusCreated)
+	if sp := s.send("POST", "/api/v2/samplingplans", `{"supplier_id":1,"product_type_id":1,"aql":2.5,"inspection_level":"ii"}`, http.StatusCreated); sp["inspection_level"] != "II" || sp["active"] != true {
+		t.Errorf("plan = %v", sp)
+	}
+	s.send("POST", "/api/v2/samplingplans", `{"aql":1}`, http.StatusConflict)
+	if got := plan(s.send("GET", "/api/v2/samplingplans/sample?supplier_id=1&lot_size=1000", "", http.StatusOK)); got != "normal J n=80 10/11 <nil>" {
+		t.Errorf("general sample = %s", got)
+	}
+	if got := plan(s.send("GET", "/api/v2/samplingplans/sample?supplier_id=1&product_type_id=1&lot_size=1000", "", http.StatusOK)); got != "normal J n=80 5/6 <nil>" {
+		t.Errorf("supplier sample = %s", got)
+	}
+	s.send("GET", "/api/v2/samplingplans/sample?supplier_id=1&lot_size=0", "", http.StatusUnprocessableEntity)
+
+	// A lot is decided once its sample is inspected
+	if got := plan(s.send("POST", "/api/v2/inspectionlots", `{"po_item_id":1}`, http.StatusCreated)); got != "normal C n=5 0/1 open" {
+		t.Errorf("lot 1 = %s", got)
+	}
+	s.send("POST", "/api/v2/inspectionlots", `{"po_item_id":1}`, http.StatusConflict)
+	for i := 0; i < 4; i++ {
+		s.send("POST", "/api/v2/qualityinspections", `{"po_item_id":1,"result":"pass"}`, http.StatusCreated)
+	}
+	if lot := s.send("GET", "/api/v2/inspectionlots/1", "", http.StatusOK); lot["inspected"] != 4.0 || lot["status"] != "open" {
+		t.Errorf("lot 1 sampled in part = %v", lot)
+	}
+	s.send("POST", "/api/v2/qualityinspections", `{"po_item_id":1,"result":"pass"}`, http.StatusCreated)
+	if lot := s.send("GET", "/api/v2/inspectionlots/1", "", http.StatusOK); lot["status"] != "accepted" || lot["decided_at"] == nil {
+		t.Errorf("lot 1 = %v", lot)
+	}
+
+	// Reaching the reject number rejects the lot and holds what was received
+	s.send("POST", "/api/v2/inspectionlots", `{"po_item_id":2}`, http.StatusCreated)
+	s.send("POST", "/api/v2/qualityinspections", `{"po_item_id":2,"result":"fail"}`, http.StatusCreated)
+	if lot := s.send("GET", "/api/v2/inspectionlots/2", "", http.StatusOK); lot["status"] != "rejected" || lot["defects"] != 1.0 {
+		t.Errorf("lot 2 = %v", lot)
+	}
+	stock, err := s.repos.Stock.ListStockItems(ctx, query.Spec{})
+	if err != nil || stock.Data[0].Status != repository.StockQuarantine {
+		t.Errorf("stock of rejected lot = %+v, %v", stock.Data, err)
+	}
+	s.send("POST", "/api/v2/defectcodes", `{"code":"KN","name":"Loose knot","category":"knots"}`, http.StatusCreated)
+	if got := plan(s.send("POST", "/api/v2/inspectionlots", `{"po_item_id":3,"lot_size":1000}`, http.StatusCreated)); got != "normal J n=80 5/6 open" {
+		t.Errorf("lot 3 = %s", got)
+	}
+	s.send("POST", "/api/v2/qualityinspections", `{"po_item_id":3,"result":"pass","defects":[{"code":"kn","count":6}]}`, http.StatusCreated)
+
+	// Two rejections in five lots tighten inspection of the supplier
+	if st := s.send("GET", "/api/v2/suppliers/1/sampling", "", http.StatusOK); st["severity"] != "tightened" || st["lots"] != 3.0 {
+		t.Errorf("switching state = %v", st)
+	}
+	if got := plan(s.send("POST", "/api/v2/inspectionlots", `{"po_item_id":4}`, http.StatusCreated)); got != "tightened D n=8 0/1 open" {
+		t.Errorf("lot 4 = %s", got)
+	}
+}
+
+func TestLegacyDeprecation(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("DELETE", "/api/treespecies-item?id=7", s.adminToken, "")
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Errorf("Deprecation = %q", rec.Header().Get("Deprecation"))
+	}
+	if link := rec.Header().Get("Link"); link != `</api/v2/treespecies/7>; rel="successor-version"` {
+		t.Errorf("Link = %q", link)
+	}
+
+	rec = s.do("GET", "/api/v2/treespecies", s.adminToken, "")
+	if rec.Header().Get("Deprecation") != "" {
+		t.Error("v2 route marked deprecated")
+	}
+
+	// Every legacy route has a successor the v2 router serves
+	for _, pattern := range s.mux.patterns {
+		successor, ok := legacySuccessors[pattern]
+		if !ok {
+			continue
+		}
+		target := strings.NewReplacer("{id}", "1", "{soid}", "1", "{poid}", "1", "{role_id}", "1", "{permission_id}", "1").Replace(successor)
+		if rec := s.do("OPTIONS", target, "", ""); rec.Code != http.StatusNoContent {
+			t.Errorf("%s: successor %s is not routed", pattern, successor)
+		}
+	}
+	if len(legacySuccessors) != len(s.mux.patterns)-2 {
+		t.Errorf("%d successors for %d legacy routes", len(legacySuccessors), len(s.mux.patterns)-2)
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	s := newServer(t)
+	for _, si := range []string{`{"warehouse_id":1,"product_type_id":1}`, `{"warehouse_id":1,"product_type_id":2}`} {
+		if rec := s.do("POST", "/api/v2/stockitems", s.adminToken, si); rec.Code != http.StatusCreated {
+			t.Fatalf("create stock item: status %d", rec.Code)
+		}
+	}
+	for _, tx := range []string{
+		`{"stock_id":1,"transaction_type":"receipt","quantity":5}`,
+		`{"stock_id":1,"transaction_type":"adjustment","quantity":2}`,
+		`{"stock_id":2,"transaction_type":"receipt","quantity":9}`,
+		`{"stock_id":2,"transaction_type":"receipt","quantity":1}`,
+	} {
+		if rec := s.do("POST", "/api/v2/inventorytransactions", s.adminToken, tx); rec.Code != http.StatusCreated {
+			t.Fatalf("create transaction: status %d", rec.Code)
+		}
+	}
+
+	type transactions struct {
+		Data []struct {
+			TransactionID int     `json:"transaction_id"`
+			Quantity      float64 `json:"quantity"`
+		} `json:"data"`
+		NextCursor string `json:"next_cursor"`
+		Total      int    `json:"total"`
+	}
+	var quantities []float64
+	target := "/api/v2/inventorytransactions?transaction_type=receipt&sort=-quantity&limit=2"
+	for pages := 0; target != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("cursor never ran out")
+		}
+		rec := s.do("GET", target, s.adminToken, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d; body %s", target, rec.Code, rec.Body)
+		}
+		var page transactions
+		json.NewDecoder(rec.Body).Decode(&page)
+		if page.Total != 3 {
+			t.Fatalf("total = %d, want 3", page.Total)
+		}
+		for _, tx := range page.Data {
+			quantities = append(quantities, tx.Quantity)
+		}
+		target = ""
+		if page.NextCursor != "" {
+			target = "/api/v2/inventorytransactions?transaction_type=receipt&sort=-quantity&limit=2&cursor=" + page.NextCursor
+		}
+	}
+	if len(quantities) != 3 || quantities[0] != 9 || quantities[1] != 5 || quantities[2] != 1 {
+		t.Fatalf("quantities = %v", quantities)
+	}
+
+	var filtered transactions
+	json.NewDecoder(s.do("GET", "/api/v2/inventorytransactions?quantity[gte]=2&quantity[lt]=9&sort=quantity", s.adminToken, "").Body).Decode(&filtered)
+	if len(filtered.Data) != 2 || filtered.Data[0].Quantity != 2 || filtered.Data[1].Quantity != 5 {
+		t.Fatalf("filtered = %+v", filtered.Data)
+	}
+
+	// Legacy routes keep the bare array and report the total in a header
+	rec := s.do("GET", "/api/inventorytransactions?limit=1", s.adminToken, "")
+	var legacy []map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&legacy)
+	if len(legacy) != 1 || rec.Header().Get("X-Total-Count") != "4" || rec.Header().Get("X-Next-Cursor") == "" {
+		t.Fatalf("legacy page = %v, headers %v", legacy, rec.Header())
+	}
+
+	// and page at the default size when no limit is given
+	for i := 4; i <= query.DefaultLimit; i++ {
+		s.send("POST", "/api/v2/inventorytransactions", `{"stock_id":1,"transaction_type":"adjustment","quantity":1}`, http.StatusCreated)
+	}
+	rec = s.do("GET", "/api/inventorytransactions", s.adminToken, "")
+	json.NewDecoder(rec.Body).Decode(&legacy)
+	if len(legacy) != query.DefaultLimit || rec.Header().Get("X-Total-Count") != strconv.Itoa(query.DefaultLimit+1) || rec.Header().Get("X-Next-Cursor") == "" {
+		t.Fatalf("legacy list returned %d rows, headers %v", len(legacy), rec.Header())
+	}
+
+	for _, target := range []string{
+		"/api/v2/inventorytransactions?password=x",
+		"/api/v2/inventorytransactions?sort=secret",
+		"/api/v2/inventorytransactions?quantity[like]=1",
+		"/api/v2/inventorytransactions?quantity=lots",
+		"/api/v2/inventorytransactions?limit=0",
+		"/api/v2/inventorytransactions?cursor=garbage",
+		"/api/v2/inventorytransactions?transaction_date[gte]=yesterday",
+	} {
+		if rec := s.do("GET", target, s.adminToken, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want 400", target, rec.Code)
+		}
+	}
+}
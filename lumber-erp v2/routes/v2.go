@@ -0,0 +1,224 @@
+package routes
+
+import (
+	"net/http"
+
+	"lumber-erp-api/handlers"
+	"lumber-erp-api/middleware"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/router"
+)
+
+// v2Handlers bundles the handlers shared by the /api/v2 router and the
+// legacy routes
+type v2Handlers struct {
+	auth        *handlers.AuthHandler
+	users       *handlers.UserHandler
+	employees   *handlers.EmployeeHandler
+	suppliers   *handlers.SupplierHandler
+	forests     *handlers.ForestHandler
+	processing  *handlers.ProcessingHandler
+	quality     *handlers.QualityHandler
+	ncr         *handlers.NCRHandler
+	spc         *handlers.SPCHandler
+	sampling    *handlers.SamplingHandler
+	warehouses  *handlers.WarehouseHandler
+	procurement *handlers.ProcurementHandler
+	sales       *handlers.SalesHandler
+	financial   *handlers.FinancialHandler
+	transport   *handlers.TransportHandler
+	transfers   *handlers.TransferHandler
+	trace       *handlers.TraceHandler
+	schedules   *handlers.ScheduleHandler
+	maintenance *handlers.MaintenanceHandler
+	kilns       *handlers.KilnHandler
+	waste       *handlers.WasteHandler
+	quarantine  *handlers.QuarantineHandler
+	audit       *handlers.AuditHandler
+}
+
+// newV2Router builds the path-based /api/v2 API. Collections live at
+// /api/v2/<plural> and members at /api/v2/<plural>/{id}; the router answers
+// 405 with an Allow header for methods a path does not support. It also
+// returns the permission module of each route, keyed by modulePath, which
+// the legacy aliases inherit.
+func newV2Router(h v2Handlers, sessions repository.SessionRepository, access repository.AccessRepository, audited auditor) (*router.Router, map[string]string) {
+	api := router.New()
+	modules := map[string]string{}
+
+	// handle registers a route guarded by the permission module it belongs to
+	// and audits the changes it makes
+	handle := func(method, pattern, module string, handler http.HandlerFunc) {
+		pattern = "/api/v2" + pattern
+		modules[modulePath(pattern)] = module
+		api.Handle(method, pattern, middleware.RequirePermission(sessions, access, module, audited.wrap(pattern, handler)))
+	}
+	// resource registers the usual collection and member routes
+	resource := func(plural, module string, list, get, create, update, remove http.HandlerFunc) {
+		handle("GET", "/"+plural, module, list)
+		handle("POST", "/"+plural, module, create)
+		handle("GET", "/"+plural+"/{id}", module, get)
+		handle("PUT", "/"+plural+"/{id}", module, update)
+		handle("DELETE", "/"+plural+"/{id}", module, remove)
+	}
+
+	// ==================== AUTHENTICATION ====================
+	api.Handle("POST", "/api/v2/auth/login", h.auth.Login)
+	api.Handle("POST", "/api/v2/auth/logout", h.auth.Logout)
+	api.Handle("POST", "/api/v2/auth/refresh", h.auth.RefreshToken)
+	api.Handle("GET", "/api/v2/auth/me", middleware.RequireAuth(sessions, h.auth.GetCurrentUser))
+
+	// ==================== USER MANAGEMENT ====================
+	handle("GET", "/users", ModuleUsers, h.users.GetUsers)
+	handle("POST", "/users", ModuleUsers, h.users.CreateUser)
+	handle("GET", "/users/{id}", ModuleUsers, h.users.GetUser)
+	handle("PUT", "/users/{id}", ModuleUsers, h.users.UpdateUser)
+	handle("DELETE", "/users/{id}", ModuleUsers, h.users.DeleteUser)
+	api.Handle("PUT", "/api/v2/users/{id}/password", middleware.RequireAuth(sessions, audited.wrap("/api/v2/users/{id}/password", h.auth.ChangePassword)))
+
+	resource("permissions", ModuleUsers, h.users.GetPermissions, h.users.GetPermission, h.users.CreatePermission, h.users.UpdatePermission, h.users.DeletePermission)
+	handle("GET", "/permissions/{id}/roles", ModuleUsers, h.users.GetRolePermissionsByPermission)
+
+	resource("roles", ModuleUsers, h.users.GetRoles, h.users.GetRole, h.users.CreateRole, h.users.UpdateRole, h.users.DeleteRole)
+	handle("GET", "/roles/{id}/permissions", ModuleUsers, h.users.GetRolePermissionsByRole)
+	handle("PUT", "/roles/{id}/permissions", ModuleUsers, h.users.AssignPermissionsToRole)
+
+	handle("GET", "/rolepermissions", ModuleUsers, h.users.GetRolePermissions)
+	handle("POST", "/rolepermissions", ModuleUsers, h.users.CreateRolePermission)
+	handle("DELETE", "/rolepermissions/{role_id}/{permission_id}", ModuleUsers, h.users.DeleteRolePermission)
+
+	// ==================== HR & EMPLOYEES ====================
+	resource("employees", ModuleEmployees, h.employees.GetEmployees, h.employees.GetEmployee, h.employees.CreateEmployee, h.employees.UpdateEmployee, h.employees.DeleteEmployee)
+	resource("workerassignments", ModuleEmployees, h.employees.GetWorkerAssignments, h.employees.GetWorkerAssignment, h.employees.CreateWorkerAssignment, h.employees.UpdateWorkerAssignment, h.employees.DeleteWorkerAssignment)
+	resource("managementinsights", ModuleEmployees, h.employees.GetManagementInsights, h.employees.GetManagementInsight, h.employees.CreateManagementInsights, h.employees.UpdateManagementInsights, h.employees.DeleteManagementInsights)
+
+	// ==================== SUPPLIERS ====================
+	resource("suppliers", ModuleSuppliers, h.suppliers.GetSuppliers, h.suppliers.GetSupplier, h.suppliers.CreateSupplier, h.suppliers.UpdateSupplier, h.suppliers.DeleteSupplier)
+	resource("supplierperformances", ModuleSuppliers, h.suppliers.GetSupplierPerformances, h.suppliers.GetSupplierPerformance, h.suppliers.CreateSupplierPerformance, h.suppliers.UpdateSupplierPerformance, h.suppliers.DeleteSupplierPerformance)
+	resource("suppliercontracts", ModuleSuppliers, h.suppliers.GetSupplierContracts, h.suppliers.GetSupplierContract, h.suppliers.CreateSupplierContract, h.suppliers.UpdateSupplierContract, h.suppliers.DeleteSupplierContract)
+
+	// ==================== FOREST & HARVESTING ====================
+	resource("forests", ModuleForest, h.forests.GetForests, h.forests.GetForest, h.forests.CreateForest, h.forests.UpdateForest, h.forests.DeleteForest)
+	resource("treespecies", ModuleForest, h.forests.GetTreeSpecies, h.forests.GetTreeSpeciesItem, h.forests.CreateTreeSpecies, h.forests.UpdateTreeSpecies, h.forests.DeleteTreeSpecies)
+	resource("harvestschedules", ModuleForest, h.forests.GetHarvestSchedules, h.forests.GetHarvestSchedule, h.forests.CreateHarvestSchedule, h.forests.UpdateHarvestSchedule, h.forests.DeleteHarvestSchedule)
+	resource("harvestbatches", ModuleForest, h.forests.GetHarvestBatches, h.forests.GetHarvestBatch, h.forests.CreateHarvestBatch, h.forests.UpdateHarvestBatch, h.forests.DeleteHarvestBatch)
+	handle("GET", "/trace/{qr_code}", ModuleForest, h.trace.TraceLot)
+
+	// ==================== PROCESSING & SAWMILL ====================
+	resource("sawmills", ModuleProcessing, h.processing.GetSawmills, h.processing.GetSawmill, h.processing.CreateSawmill, h.processing.UpdateSawmill, h.processing.DeleteSawmill)
+	handle("GET", "/sawmills/{id}/schedule", ModuleProcessing, h.schedules.GetSawmillSchedule)
+	handle("POST", "/sawmills/{id}/schedule", ModuleProcessing, h.schedules.ScheduleSawmill)
+	// A dry run stores nothing, so it is not audited
+	handle("POST", "/sawmills/{id}/schedule/dry-run", ModuleProcessing, h.schedules.DryRunSchedule)
+	handle("GET", "/sawmills/{id}/oee", ModuleProcessing, h.maintenance.GetSawmillOEE)
+	resource("processingunits", ModuleProcessing, h.processing.GetProcessingUnits, h.processing.GetProcessingUnit, h.processing.CreateProcessingUnit, h.processing.UpdateProcessingUnit, h.processing.DeleteProcessingUnit)
+	handle("GET", "/processingunits/{id}/oee", ModuleProcessing, h.maintenance.GetUnitOEE)
+	resource("processingorders", ModuleProcessing, h.processing.GetProcessingOrders, h.processing.GetProcessingOrder, h.processing.CreateProcessingOrder, h.processing.UpdateProcessingOrder, h.processing.DeleteProcessingOrder)
+	handle("POST", "/processingorders/{id}/release", ModuleProcessing, h.processing.ReleaseProcessingOrder)
+	handle("POST", "/processingorders/{id}/start", ModuleProcessing, h.processing.StartProcessingOrder)
+	handle("POST", "/processingorders/{id}/complete", ModuleProcessing, h.processing.CompleteProcessingOrder)
+	handle("POST", "/processingorders/{id}/close", ModuleProcessing, h.processing.CloseProcessingOrder)
+	handle("GET", "/processingorders/{processing_id}/batches", ModuleProcessing, h.processing.GetHarvestBatchProcessing)
+	handle("POST", "/processingorders/{processing_id}/batches", ModuleProcessing, h.processing.AttachHarvestBatch)
+	handle("DELETE", "/processingorders/{processing_id}/batches/{batch_id}", ModuleProcessing, h.processing.DetachHarvestBatch)
+	resource("maintenancerecords", ModuleProcessing, h.processing.GetMaintenanceRecords, h.processing.GetMaintenanceRecord, h.processing.CreateMaintenanceRecord, h.processing.UpdateMaintenanceRecord, h.processing.DeleteMaintenanceRecord)
+	resource("maintenanceplans", ModuleProcessing, h.maintenance.GetMaintenancePlans, h.maintenance.GetMaintenancePlan, h.maintenance.CreateMaintenancePlan, h.maintenance.UpdateMaintenancePlan, h.maintenance.DeleteMaintenancePlan)
+	handle("POST", "/maintenanceplans/generate", ModuleProcessing, h.maintenance.GenerateWorkOrders)
+	handle("GET", "/maintenanceworkorders", ModuleProcessing, h.maintenance.GetWorkOrders)
+	handle("POST", "/maintenanceworkorders", ModuleProcessing, h.maintenance.CreateWorkOrder)
+	handle("POST", "/maintenanceworkorders/{id}/complete", ModuleProcessing, h.maintenance.CompleteWorkOrder)
+	handle("POST", "/maintenanceworkorders/{id}/cancel", ModuleProcessing, h.maintenance.CancelWorkOrder)
+	resource("kilncharges", ModuleProcessing, h.kilns.GetKilnCharges, h.kilns.GetKilnCharge, h.kilns.CreateKilnCharge, h.kilns.UpdateKilnCharge, h.kilns.DeleteKilnCharge)
+	handle("GET", "/kilncharges/{id}/readings", ModuleProcessing, h.kilns.GetKilnReadings)
+	handle("POST", "/kilncharges/{id}/readings", ModuleProcessing, h.kilns.RecordKilnReadings)
+	handle("POST", "/kilncharges/{id}/unload", ModuleProcessing, h.kilns.UnloadKilnCharge)
+	resource("wasterecords", ModuleProcessing, h.processing.GetWasteRecords, h.processing.GetWasteRecord, h.processing.CreateWasteRecord, h.processing.UpdateWasteRecord, h.processing.DeleteWasteRecord)
+	handle("POST", "/wasterecords/{id}/valorise", ModuleProcessing, h.waste.ValoriseWasteRecord)
+	handle("GET", "/wasteanalytics", ModuleProcessing, h.waste.GetWasteAnalytics)
+
+	// ==================== QUALITY CONTROL ====================
+	resource("qualityinspections", ModuleQuality, h.quality.GetQualityInspections, h.quality.GetQualityInspection, h.quality.CreateQualityInspection, h.quality.UpdateQualityInspection, h.quality.DeleteQualityInspection)
+	resource("defectcodes", ModuleQuality, h.quality.GetDefectCodes, h.quality.GetDefectCode, h.quality.CreateDefectCode, h.quality.UpdateDefectCode, h.quality.DeleteDefectCode)
+	resource("inspectiontemplates", ModuleQuality, h.quality.GetInspectionTemplates, h.quality.GetInspectionTemplate, h.quality.CreateInspectionTemplate, h.quality.UpdateInspectionTemplate, h.quality.DeleteInspectionTemplate)
+	resource("nonconformances", ModuleQuality, h.ncr.GetNonConformances, h.ncr.GetNonConformance, h.ncr.CreateNonConformance, h.ncr.UpdateNonConformance, h.ncr.DeleteNonConformance)
+	handle("POST", "/nonconformances/{id}/investigate", ModuleQuality, h.ncr.InvestigateNonConformance)
+	handle("POST", "/nonconformances/{id}/act", ModuleQuality, h.ncr.ActOnNonConformance)
+	handle("POST", "/nonconformances/{id}/verify", ModuleQuality, h.ncr.VerifyNonConformance)
+	handle("POST", "/nonconformances/{id}/close", ModuleQuality, h.ncr.CloseNonConformance)
+	resource("nonconformances/{ncr_id}/actions", ModuleQuality, h.ncr.GetCorrectiveActions, h.ncr.GetCorrectiveAction, h.ncr.CreateCorrectiveAction, h.ncr.UpdateCorrectiveAction, h.ncr.DeleteCorrectiveAction)
+	handle("GET", "/correctiveactions", ModuleQuality, h.ncr.GetCorrectiveActions)
+	handle("GET", "/correctiveactions/overdue", ModuleQuality, h.ncr.GetOverdueCorrectiveActions)
+	handle("POST", "/correctiveactions/{id}/complete", ModuleQuality, h.ncr.CompleteCorrectiveAction)
+	handle("GET", "/spc/charts", ModuleQuality, h.spc.GetSPCCharts)
+	handle("POST", "/spc/alerts", ModuleQuality, h.spc.RaiseSPCAlerts)
+	handle("GET", "/qualityalerts", ModuleQuality, h.spc.GetQualityAlerts)
+	handle("POST", "/qualityalerts/{id}/resolve", ModuleQuality, h.spc.ResolveQualityAlert)
+	resource("samplingplans", ModuleQuality, h.sampling.GetSamplingPlans, h.sampling.GetSamplingPlan, h.sampling.CreateSamplingPlan, h.sampling.UpdateSamplingPlan, h.sampling.DeleteSamplingPlan)
+	handle("GET", "/samplingplans/sample", ModuleQuality, h.sampling.GetSample)
+	handle("GET", "/suppliers/{id}/sampling", ModuleQuality, h.sampling.GetSamplingState)
+	handle("GET", "/inspectionlots", ModuleQuality, h.sampling.GetInspectionLots)
+	handle("POST", "/inspectionlots", ModuleQuality, h.sampling.CreateInspectionLot)
+	handle("GET", "/inspectionlots/{id}", ModuleQuality, h.sampling.GetInspectionLot)
+	handle("DELETE", "/inspectionlots/{id}", ModuleQuality, h.sampling.DeleteInspectionLot)
+
+	// ==================== WAREHOUSE & INVENTORY ====================
+	resource("warehouses", ModuleWarehouse, h.warehouses.GetWarehouses, h.warehouses.GetWarehouse, h.warehouses.CreateWarehouse, h.warehouses.UpdateWarehouse, h.warehouses.DeleteWarehouse)
+	resource("producttypes", ModuleWarehouse, h.warehouses.GetProductTypes, h.warehouses.GetProductType, h.warehouses.CreateProductType, h.warehouses.UpdateProductType, h.warehouses.DeleteProductType)
+	resource("stockitems", ModuleWarehouse, h.warehouses.GetStockItems, h.warehouses.GetStockItem, h.warehouses.CreateStockItem, h.warehouses.UpdateStockItem, h.warehouses.DeleteStockItem)
+	handle("GET", "/stockitems/{id}/dispositions", ModuleWarehouse, h.quarantine.GetStockDispositions)
+	handle("POST", "/stockitems/{id}/dispositions", ModuleWarehouse, h.quarantine.DisposeStockItem)
+	handle("GET", "/stockdispositions", ModuleWarehouse, h.quarantine.GetStockDispositions)
+	resource("stockalerts", ModuleWarehouse, h.warehouses.GetStockAlerts, h.warehouses.GetStockAlert, h.warehouses.CreateStockAlert, h.warehouses.UpdateStockAlert, h.warehouses.DeleteStockAlert)
+	// The stock ledger is append-only; mistakes are corrected by reversal
+	handle("GET", "/inventorytransactions", ModuleWarehouse, h.warehouses.GetInventoryTransactions)
+	handle("POST", "/inventorytransactions", ModuleWarehouse, h.warehouses.CreateInventoryTransaction)
+	handle("POST", "/inventorytransactions/{id}/reverse", ModuleWarehouse, h.warehouses.ReverseInventoryTransaction)
+
+	handle("GET", "/transfers", ModuleWarehouse, h.transfers.GetTransfers)
+	handle("POST", "/transfers", ModuleWarehouse, h.transfers.CreateTransfer)
+	handle("GET", "/transfers/{id}", ModuleWarehouse, h.transfers.GetTransfer)
+	handle("PUT", "/transfers/{id}", ModuleWarehouse, h.transfers.UpdateTransfer)
+	handle("DELETE", "/transfers/{id}", ModuleWarehouse, h.transfers.DeleteTransfer)
+	handle("POST", "/transfers/{id}/dispatch", ModuleWarehouse, h.transfers.DispatchTransfer)
+	handle("POST", "/transfers/{id}/transit", ModuleWarehouse, h.transfers.TransitTransfer)
+	handle("POST", "/transfers/{id}/receive", ModuleWarehouse, h.transfers.ReceiveTransfer)
+	handle("GET", "/transfers/{id}/discrepancies", ModuleWarehouse, h.transfers.GetTransferDiscrepancies)
+
+	// ==================== PROCUREMENT ====================
+	resource("purchaseorders", ModuleProcurement, h.procurement.GetPurchaseOrders, h.procurement.GetPurchaseOrder, h.procurement.CreatePurchaseOrder, h.procurement.UpdatePurchaseOrder, h.procurement.DeletePurchaseOrder)
+	resource("purchaseorders/{poid}/items", ModuleProcurement, h.procurement.GetPurchaseOrderItems, h.procurement.GetPurchaseOrderItem, h.procurement.CreatePurchaseOrderItem, h.procurement.UpdatePurchaseOrderItem, h.procurement.DeletePurchaseOrderItem)
+
+	// ==================== SALES & CUSTOMERS ====================
+	resource("customers", ModuleSales, h.sales.GetCustomers, h.sales.GetCustomer, h.sales.CreateCustomer, h.sales.UpdateCustomer, h.sales.DeleteCustomer)
+	resource("salesorders", ModuleSales, h.sales.GetSalesOrders, h.sales.GetSalesOrder, h.sales.CreateSalesOrder, h.sales.UpdateSalesOrder, h.sales.DeleteSalesOrder)
+	resource("salesorders/{soid}/items", ModuleSales, h.sales.GetSalesOrderItems, h.sales.GetSalesOrderItem, h.sales.CreateSalesOrderItem, h.sales.UpdateSalesOrderItem, h.sales.DeleteSalesOrderItem)
+
+	// ==================== INVOICING & PAYMENTS ====================
+	resource("invoices", ModuleFinancial, h.financial.GetInvoices, h.financial.GetInvoice, h.financial.CreateInvoice, h.financial.UpdateInvoice, h.financial.DeleteInvoice)
+	resource("payments", ModuleFinancial, h.financial.GetPayments, h.financial.GetPayment, h.financial.CreatePayment, h.financial.UpdatePayment, h.financial.DeletePayment)
+
+	// ==================== TRANSPORTATION ====================
+	resource("transportcompanies", ModuleTransport, h.transport.GetTransportCompanies, h.transport.GetTransportCompany, h.transport.CreateTransportCompany, h.transport.UpdateTransportCompany, h.transport.DeleteTransportCompany)
+	resource("trucks", ModuleTransport, h.transport.GetTrucks, h.transport.GetTruck, h.transport.CreateTruck, h.transport.UpdateTruck, h.transport.DeleteTruck)
+	resource("drivers", ModuleTransport, h.transport.GetDrivers, h.transport.GetDriver, h.transport.CreateDriver, h.transport.UpdateDriver, h.transport.DeleteDriver)
+	resource("routes", ModuleTransport, h.transport.GetRoutes, h.transport.GetRoute, h.transport.CreateRoute, h.transport.UpdateRoute, h.transport.DeleteRoute)
+	resource("shipments", ModuleTransport, h.transport.GetShipments, h.transport.GetShipment, h.transport.CreateShipment, h.transport.UpdateShipment, h.transport.DeleteShipment)
+	resource("fuellogs", ModuleTransport, h.transport.GetFuelLogs, h.transport.GetFuelLog, h.transport.CreateFuelLog, h.transport.UpdateFuelLog, h.transport.DeleteFuelLog)
+
+	// ==================== AUDIT & LOGS ====================
+	// Entries are written by the audit middleware only
+	handle("GET", "/auditlogs", ModuleAudit, h.audit.GetAuditLogs)
+	handle("GET", "/auditlogs/verify", ModuleAudit, h.audit.VerifyAuditLogs)
+	handle("GET", "/auditlogs/checkpoints", ModuleAudit, h.audit.GetAuditCheckpoints)
+	handle("POST", "/auditlogs/checkpoints", ModuleAudit, h.audit.CreateAuditCheckpoint)
+	handle("GET", "/auditlogs/checkpoints/export", ModuleAudit, h.audit.ExportAuditCheckpoints)
+
+	return api, modules
+}
+
+// modulePath reduces a route pattern to the form its permission module is
+// looked up by, so /roles/{id}/permissions and /roles/{role_id}/permissions
+// are the same route
+func modulePath(pattern string) string {
+	return innerParam.ReplaceAllString(pattern, "{}")
+}
@@ -0,0 +1,586 @@
+// Package sampling runs the acceptance sampling of lots received against
+// purchase orders, after ISO 2859-1 / ANSI Z1.4 single sampling. A plan
+// sets the acceptable quality level (AQL) and general inspection level for
+// a supplier and product type. The size of a received lot picks the sample
+// size code letter; the letter and AQL give the sample size and the accept
+// and reject numbers, following the arrows of the master tables to the
+// nearest plan that has them. Each inspection recorded against the
+// purchase order item is one sampled piece, and the lot is decided as soon
+// as its defects reach the reject number or the sample is complete.
+//
+// The switching state of a supplier is replayed from its decided lots:
+// normal inspection turns tightened when 2 of 5 consecutive lots are
+// rejected and reduced when the switching score reaches 30; tightened
+// returns to normal after 5 consecutive acceptances and reduced after a
+// rejection.
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// Inspection severities of the switching rules
+const (
+	SeverityNormal    = "normal"
+	SeverityTightened = "tightened"
+	SeverityReduced   = "reduced"
+)
+
+// Lot states
+const (
+	LotOpen     = "open"
+	LotAccepted = "accepted"
+	LotRejected = "rejected"
+)
+
+// DefaultLevel is the general inspection level plans use unless told
+// otherwise
+const DefaultLevel = "II"
+
+// Switching thresholds
+const (
+	// TightenAfter rejections among the last 5 lots under normal inspection
+	// tighten it
+	TightenAfter = 2
+	// RelaxAfter consecutive acceptances under tightened inspection return
+	// it to normal
+	RelaxAfter = 5
+	// ReduceAt is the switching score that reduces normal inspection
+	ReduceAt = 30
+)
+
+// AQLs are the acceptable quality levels, in percent nonconforming, plans
+// may use; they are the steps of the master tables from 0.065 up
+var AQLs = []float64{0.065, 0.10, 0.15, 0.25, 0.40, 0.65, 1.0, 1.5, 2.5, 4.0, 6.5, 10}
+
+// firstStep is the column of AQLs[0] in the master tables, which start at
+// 0.010
+const firstStep = 4
+
+var (
+	letters     = "ABCDEFGHJKLMNPQR"
+	sampleSizes = []int{2, 3, 5, 8, 13, 20, 32, 50, 80, 125, 200, 315, 500, 800, 1250, 2000}
+	// lotSizes are the upper bounds of the lot size ranges of the code
+	// letter table; larger lots fall in the last range
+	lotSizes = []int{8, 15, 25, 50, 90, 150, 280, 500, 1200, 3200, 10000, 35000, 150000, 500000}
+	// codeLetters is the code letter, as an index of letters, of each lot
+	// size range by general inspection level
+	codeLetters = map[string][]int{
+		"I":   {0, 0, 1, 2, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+		"II":  {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14},
+		"III": {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	}
+	// normalAccept and tightenedAccept are the acceptance numbers along the
+	// diagonals of the master tables after the 0/1 plans
+	normalAccept    = []int{1, 2, 3, 5, 7, 10, 14, 21}
+	tightenedAccept = []int{1, 2, 3, 5, 8, 12, 18}
+)
+
+// Service keeps sampling plans, opens inspection lots for receipts and
+// decides them from the inspections tallied against them
+type Service struct {
+	tx         repository.Transactor
+	quality    repository.QualityRepository
+	orders     repository.PurchaseOrderRepository
+	suppliers  repository.SupplierRepository
+	warehouses repository.WarehouseRepository
+	holds      *quarantine.Service
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, quality: repos.Quality, orders: repos.PurchaseOrders, suppliers: repos.Suppliers,
+		warehouses: repos.Warehouses, holds: quarantine.NewService(repos)}
+}
+
+// Sample is what a plan calls for of a lot: how many pieces to inspect and
+// the defects that accept or reject it
+type Sample struct {
+	PlanID          int     `json:"plan_id"`
+	LotSize         int     `json:"lot_size"`
+	InspectionLevel string  `json:"inspection_level"`
+	AQL             float64 `json:"aql"`
+	Severity        string  `json:"severity"`
+	CodeLetter      string  `json:"code_letter"`
+	SampleSize      int     `json:"sample_size"`
+	AcceptNumber    int     `json:"accept_number"`
+	RejectNumber    int     `json:"reject_number"`
+}
+
+// State is where a supplier stands in the switching rules. RecentRejected
+// counts the rejections among the last 5 lots under normal inspection and
+// ConsecutiveAccepted the acceptances in a row under tightened inspection.
+type State struct {
+	SupplierID          int    `json:"supplier_id"`
+	Severity            string `json:"severity"`
+	SwitchingScore      int    `json:"switching_score"`
+	RecentRejected      int    `json:"recent_rejected"`
+	ConsecutiveAccepted int    `json:"consecutive_accepted"`
+	Lots                int    `json:"lots"`
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func where(field string, value interface{}) query.Spec {
+	var spec query.Spec
+	spec.Where(field, value)
+	return spec
+}
+
+// ==================== TABLES ====================
+
+// step returns the master table column of an AQL, or -1 when it is not
+// one of AQLs
+func step(aql float64) int {
+	for i, v := range AQLs {
+		if math.Abs(v-aql) < 1e-9 {
+			return firstStep + i
+		}
+	}
+	return -1
+}
+
+// single returns the code letter and acceptance number of the single
+// sampling plan at a letter and AQL column. Plans lie on the diagonals
+// letter + column: the 0/1 plans on 14 with arrows on either side of them,
+// then the acceptance numbers of accept, beyond which the arrows point
+// back up.
+func single(letter, column int, accept []int) (int, int) {
+	d := letter + column
+	last := 16 + len(accept)
+	switch {
+	case d <= 15 && column <= 14:
+		return 14 - column, 0
+	case d < 17:
+		return 17 - column, accept[0]
+	case d > last:
+		return last - column, accept[len(accept)-1]
+	}
+	return letter, accept[d-17]
+}
+
+// Draw returns the sample a lot of lotSize calls for at an inspection
+// level, AQL and severity. Tightened plans sit a column right of the
+// normal ones and reduced plans two code letters down. A sample as large
+// as the lot inspects all of it.
+func Draw(lotSize int, level string, aql float64, severity string) Sample {
+	letter := codeLetters[level][sort.SearchInts(lotSizes, lotSize)]
+	column := step(aql)
+	var accept int
+	switch severity {
+	case SeverityTightened:
+		letter, accept = single(letter, column-1, tightenedAccept)
+	case SeverityReduced:
+		letter, accept = single(max(letter-2, 0), column, normalAccept)
+	default:
+		letter, accept = single(letter, column, normalAccept)
+	}
+	return Sample{
+		LotSize:         lotSize,
+		InspectionLevel: level,
+		AQL:             aql,
+		Severity:        severity,
+		CodeLetter:      string(letters[letter]),
+		SampleSize:      min(sampleSizes[letter], lotSize),
+		AcceptNumber:    accept,
+		RejectNumber:    accept + 1,
+	}
+}
+
+// ==================== SWITCHING ====================
+
+// tighter returns the acceptance number of the next tighter AQL of a
+// normal plan
+func tighter(accept int) int {
+	prev := 0
+	for _, a := range normalAccept {
+		if a >= accept {
+			break
+		}
+		prev = a
+	}
+	return prev
+}
+
+// score adds a lot decided under normal inspection to the switching score.
+// An accepted lot adds 2 when its acceptance number is 0 or 1, and 3 when
+// it would also have been accepted at the next tighter AQL; anything else
+// resets the score.
+func score(sum int, lot models.InspectionLot) int {
+	switch {
+	case lot.Status != LotAccepted:
+		return 0
+	case lot.AcceptNumber < 2:
+		return sum + 2
+	case lot.Defects <= tighter(lot.AcceptNumber):
+		return sum + 3
+	}
+	return 0
+}
+
+// Replay runs decided lots, in the order they were decided, through the
+// switching rules from normal inspection
+func Replay(lots []models.InspectionLot) State {
+	st := State{Severity: SeverityNormal}
+	var window []bool // the last lots under normal inspection, true when rejected
+	for _, lot := range lots {
+		rejected := lot.Status == LotRejected
+		st.Lots++
+		switch st.Severity {
+		case SeverityNormal:
+			window = append(window, rejected)
+			if len(window) > 5 {
+				window = window[1:]
+			}
+			st.SwitchingScore = score(st.SwitchingScore, lot)
+			switch {
+			case count(window) >= TightenAfter:
+				st.Severity, st.SwitchingScore, st.ConsecutiveAccepted, window = SeverityTightened, 0, 0, nil
+			case st.SwitchingScore >= ReduceAt:
+				st.Severity, st.SwitchingScore, window = SeverityReduced, 0, nil
+			}
+		case SeverityTightened:
+			st.ConsecutiveAccepted++
+			if rejected {
+				st.ConsecutiveAccepted = 0
+			}
+			if st.ConsecutiveAccepted >= RelaxAfter {
+				st.Severity, st.ConsecutiveAccepted = SeverityNormal, 0
+			}
+		case SeverityReduced:
+			if rejected {
+				st.Severity = SeverityNormal
+			}
+		}
+	}
+	st.RecentRejected = count(window)
+	return st
+}
+
+func count(window []bool) int {
+	n := 0
+	for _, rejected := range window {
+		if rejected {
+			n++
+		}
+	}
+	return n
+}
+
+// State replays the decided lots of a supplier
+func (s *Service) State(ctx context.Context, supplierID int) (State, error) {
+	if err := s.supplier(ctx, supplierID); err != nil {
+		return State{}, err
+	}
+	spec := where("supplier_id", supplierID)
+	spec.WhereIn("status", LotAccepted, LotRejected)
+	spec.Sort = []query.Order{{Field: "decided_at"}, {Field: "lot_id"}}
+	lots, err := s.quality.ListInspectionLots(ctx, spec)
+	if err != nil {
+		return State{}, err
+	}
+	st := Replay(lots.Data)
+	st.SupplierID = supplierID
+	return st, nil
+}
+
+// ==================== PLANS ====================
+
+// checkPlan normalises the inspection level of a plan and makes sure its
+// AQL is a column of the tables
+func checkPlan(sp *models.SamplingPlan) validate.Errors {
+	sp.InspectionLevel = strings.ToUpper(strings.TrimSpace(sp.InspectionLevel))
+	if sp.InspectionLevel == "" {
+		sp.InspectionLevel = DefaultLevel
+	}
+	if step(sp.AQL) < 0 {
+		steps := make([]string, len(AQLs))
+		for i, v := range AQLs {
+			steps[i] = fmt.Sprint(v)
+		}
+		return validate.Errors{{Field: "aql", Code: validate.CodeInvalidChoice,
+			Message: "aql must be one of " + strings.Join(steps, ", ")}}
+	}
+	return nil
+}
+
+func (s *Service) supplier(ctx context.Context, id int) error {
+	page, err := s.suppliers.ListSuppliers(ctx, where("supplier_id", id))
+	if err != nil {
+		return err
+	}
+	if len(page.Data) == 0 {
+		return &repository.ReferenceError{Field: "supplier_id", Table: "supplier"}
+	}
+	return nil
+}
+
+// checkScope makes sure the supplier and product type of a plan exist and
+// that no other active plan covers both
+func (s *Service) checkScope(ctx context.Context, sp *models.SamplingPlan) error {
+	if sp.SupplierID != nil {
+		if err := s.supplier(ctx, *sp.SupplierID); err != nil {
+			return err
+		}
+	}
+	if sp.ProductTypeID != nil {
+		types, err := s.warehouses.ListProductTypes(ctx, where("product_type_id", *sp.ProductTypeID))
+		if err != nil {
+			return err
+		}
+		if len(types.Data) == 0 {
+			return &repository.ReferenceError{Field: "product_type_id", Table: "producttype"}
+		}
+	}
+	if !sp.Active {
+		return nil
+	}
+	active, err := s.quality.ListSamplingPlans(ctx, where("active", true))
+	if err != nil {
+		return err
+	}
+	for _, other := range active.Data {
+		if other.PlanID != sp.PlanID && sameRef(other.SupplierID, sp.SupplierID) &&
+			sameRef(other.ProductTypeID, sp.ProductTypeID) {
+			return &repository.ConflictError{Field: "supplier_id, product_type_id"}
+		}
+	}
+	return nil
+}
+
+func sameRef(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// CreatePlan adds an active plan
+func (s *Service) CreatePlan(ctx context.Context, sp *models.SamplingPlan) error {
+	if errs := checkPlan(sp); errs != nil {
+		return errs
+	}
+	sp.Active = true
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.checkScope(ctx, sp); err != nil {
+			return err
+		}
+		return s.quality.CreateSamplingPlan(ctx, sp)
+	})
+}
+
+// UpdatePlan replaces a plan. Lots already opened keep what it called for.
+func (s *Service) UpdatePlan(ctx context.Context, id int, sp *models.SamplingPlan) error {
+	if errs := checkPlan(sp); errs != nil {
+		return errs
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		sp.PlanID = id
+		if err := s.checkScope(ctx, sp); err != nil {
+			return err
+		}
+		return s.quality.UpdateSamplingPlan(ctx, id, sp)
+	})
+}
+
+// plan finds the active plan covering a supplier and product type,
+// preferring one naming the supplier over one naming the product type over
+// the plan for everything. ok is false when there is none.
+func (s *Service) plan(ctx context.Context, supplierID int, productTypeID *int) (best models.SamplingPlan, ok bool, err error) {
+	active, err := s.quality.ListSamplingPlans(ctx, where("active", true))
+	if err != nil {
+		return best, false, err
+	}
+	rank := -1
+	for _, sp := range active.Data {
+		r := 0
+		switch {
+		case sp.SupplierID == nil:
+		case *sp.SupplierID == supplierID:
+			r += 2
+		default:
+			continue
+		}
+		switch {
+		case sp.ProductTypeID == nil:
+		case productTypeID != nil && *sp.ProductTypeID == *productTypeID:
+			r++
+		default:
+			continue
+		}
+		if r > rank {
+			best, rank = sp, r
+		}
+	}
+	return best, rank >= 0, nil
+}
+
+// Lookup returns the sample a lot of lotSize from a supplier calls for,
+// under the plan covering it and the supplier's switching state
+func (s *Service) Lookup(ctx context.Context, supplierID int, productTypeID *int, lotSize int) (Sample, error) {
+	if lotSize < 1 {
+		return Sample{}, validate.Errors{{Field: "lot_size", Code: validate.CodeTooSmall,
+			Message: "lot_size must be at least 1"}}
+	}
+	st, err := s.State(ctx, supplierID)
+	if err != nil {
+		return Sample{}, err
+	}
+	sp, ok, err := s.plan(ctx, supplierID, productTypeID)
+	if err != nil {
+		return Sample{}, err
+	}
+	if !ok {
+		details := map[string]interface{}{"supplier_id": supplierID}
+		if productTypeID != nil {
+			details["product_type_id"] = *productTypeID
+		}
+		return Sample{}, &repository.RuleError{Code: "no_sampling_plan",
+			Message: fmt.Sprintf("No active sampling plan covers supplier %d", supplierID), Details: details}
+	}
+	sample := Draw(lotSize, sp.InspectionLevel, sp.AQL, st.Severity)
+	sample.PlanID = sp.PlanID
+	return sample, nil
+}
+
+// ==================== LOTS ====================
+
+// Open receives a purchase order item for inspection. The lot size
+// defaults to the quantity ordered, rounded up; the supplier and product
+// type come from the item. Inspections already recorded against the item
+// count towards the sample.
+func (s *Service) Open(ctx context.Context, il *models.InspectionLot) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		items, err := s.orders.ListPurchaseOrderItems(ctx, where("po_item_id", il.POItemID))
+		if err != nil {
+			return err
+		}
+		if len(items.Data) == 0 {
+			return &repository.ReferenceError{Field: "po_item_id", Table: "purchaseorderitem"}
+		}
+		item := items.Data[0]
+		orders, err := s.orders.ListPurchaseOrders(ctx, where("poid", item.POID))
+		if err != nil {
+			return err
+		}
+		if len(orders.Data) == 0 {
+			return &repository.ReferenceError{Field: "poid", Table: "purchaseorder"}
+		}
+		il.SupplierID, il.ProductTypeID = orders.Data[0].SupplierID, nil
+		if item.ProductTypeID != 0 {
+			il.ProductTypeID = &item.ProductTypeID
+		}
+		if il.LotSize == 0 {
+			il.LotSize = int(math.Ceil(item.Quantity))
+		}
+
+		sample, err := s.Lookup(ctx, il.SupplierID, il.ProductTypeID, il.LotSize)
+		if err != nil {
+			return err
+		}
+		il.PlanID = &sample.PlanID
+		il.InspectionLevel, il.AQL, il.Severity = sample.InspectionLevel, sample.AQL, sample.Severity
+		il.CodeLetter, il.SampleSize = sample.CodeLetter, sample.SampleSize
+		il.AcceptNumber, il.RejectNumber = sample.AcceptNumber, sample.RejectNumber
+		il.Inspected, il.Defects, il.Status, il.DecidedAt = 0, 0, LotOpen, nil
+		if err := s.quality.CreateInspectionLot(ctx, il); err != nil {
+			return err
+		}
+		return s.decide(ctx, il)
+	})
+}
+
+// Tally counts an inspection of a purchase order item towards the item's
+// lot and decides the lot when it can. sampled is false when the item has
+// no lot, and the inspection stands on its own.
+func (s *Service) Tally(ctx context.Context, qi models.QualityInspection) (sampled bool, err error) {
+	if qi.POItemID == nil {
+		return false, nil
+	}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		lots, err := s.quality.ListInspectionLots(ctx, where("po_item_id", *qi.POItemID))
+		if err != nil || len(lots.Data) == 0 {
+			return err
+		}
+		sampled = true
+		lot, err := s.quality.GetInspectionLot(ctx, lots.Data[0].LotID)
+		if err != nil {
+			return err
+		}
+		return s.decide(ctx, &lot)
+	})
+	return sampled, err
+}
+
+// defects counts what an inspection found: its recorded defects, or one
+// when it failed without recording any
+func defects(qi models.QualityInspection) int {
+	n := 0
+	for _, d := range qi.Defects {
+		n += d.Count
+	}
+	if n == 0 && validate.Normalize(qi.Result) == repository.InspectionFail {
+		n = 1
+	}
+	return n
+}
+
+// decide tallies the sample inspections of an open lot in the order they
+// were recorded, stopping at the sample size, and accepts or rejects it. A
+// rejected lot holds the stock received against its item.
+func (s *Service) decide(ctx context.Context, lot *models.InspectionLot) error {
+	if lot.Status != LotOpen {
+		return nil
+	}
+	spec := where("po_item_id", lot.POItemID)
+	spec.Sort = []query.Order{{Field: "inspection_id"}}
+	page, err := s.quality.ListQualityInspectionsWithFindings(ctx, spec)
+	if err != nil {
+		return err
+	}
+	lot.Inspected, lot.Defects = 0, 0
+	var last models.QualityInspection
+	for _, qi := range page.Data {
+		if lot.Defects >= lot.RejectNumber || lot.Inspected >= lot.SampleSize {
+			break
+		}
+		lot.Inspected++
+		lot.Defects += defects(qi)
+		last = qi
+	}
+	switch {
+	case lot.Defects >= lot.RejectNumber:
+		lot.Status = LotRejected
+	case lot.Inspected >= lot.SampleSize:
+		lot.Status = LotAccepted
+	}
+	if lot.Status != LotOpen {
+		lot.DecidedAt = new(string)
+		*lot.DecidedAt = now()
+	}
+	if err := s.quality.UpdateInspectionLot(ctx, lot.LotID, lot); err != nil {
+		return err
+	}
+	if lot.Status != LotRejected {
+		return nil
+	}
+	var employeeID *int
+	if last.EmployeeID != 0 {
+		employeeID = &last.EmployeeID
+	}
+	_, err = s.holds.HoldReceipt(ctx, lot.POItemID, last.InspectionID, employeeID,
+		fmt.Sprintf("Rejected inspection lot %d", lot.LotID))
+	return err
+}
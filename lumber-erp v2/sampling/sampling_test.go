@@ -0,0 +1,104 @@
+package sampling
+
+import (
+	"testing"
+
+	"lumber-erp-api/models"
+)
+
+func TestDraw(t *testing.T) {
+	for _, tc := range []struct {
+		lotSize  int
+		level    string
+		aql      float64
+		severity string
+		letter   string
+		n, ac    int
+	}{
+		// Plans on the diagonals of the master tables
+		{500, "II", 1.0, SeverityNormal, "H", 50, 1},
+		{500, "II", 4.0, SeverityNormal, "H", 50, 5},
+		{500, "I", 4.0, SeverityNormal, "F", 20, 2},
+		{500, "III", 1.0, SeverityNormal, "J", 80, 2},
+		// Arrows down to the first plan below, and up to the 0/1 plan above
+		{500, "II", 0.65, SeverityNormal, "J", 80, 1},
+		{500, "II", 0.40, SeverityNormal, "G", 32, 0},
+		// Tightened plans sit a column to the right, reduced ones two code letters down
+		{500, "II", 1.0, SeverityTightened, "J", 80, 1},
+		{500, "II", 4.0, SeverityTightened, "H", 50, 3},
+		{500, "II", 4.0, SeverityReduced, "F", 20, 2},
+		// The largest lots and the last acceptance numbers
+		{200000, "II", 10, SeverityNormal, "K", 125, 21},
+		// A sample as large as the lot inspects all of it
+		{4, "II", 10, SeverityNormal, "C", 4, 1},
+	} {
+		got := Draw(tc.lotSize, tc.level, tc.aql, tc.severity)
+		if got.CodeLetter != tc.letter || got.SampleSize != tc.n || got.AcceptNumber != tc.ac || got.RejectNumber != tc.ac+1 {
+			t.Errorf("Draw(%d, %s, %g, %s) = %s n=%d %d/%d, want %s n=%d %d/%d", tc.lotSize, tc.level, tc.aql, tc.severity,
+				got.CodeLetter, got.SampleSize, got.AcceptNumber, got.RejectNumber, tc.letter, tc.n, tc.ac, tc.ac+1)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	lot := func(status string, accept, defects int) models.InspectionLot {
+		return models.InspectionLot{Status: status, AcceptNumber: accept, Defects: defects}
+	}
+	accepted, rejected := lot(LotAccepted, 1, 0), lot(LotRejected, 1, 2)
+	repeat := func(l models.InspectionLot, n int) []models.InspectionLot {
+		var out []models.InspectionLot
+		for i := 0; i < n; i++ {
+			out = append(out, l)
+		}
+		return out
+	}
+	join := func(runs ...[]models.InspectionLot) []models.InspectionLot {
+		var out []models.InspectionLot
+		for _, run := range runs {
+			out = append(out, run...)
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name  string
+		lots  []models.InspectionLot
+		state State
+	}{
+		{"no lots", nil, State{Severity: SeverityNormal}},
+		{"one rejection", join(repeat(accepted, 2), repeat(rejected, 1)),
+			State{Severity: SeverityNormal, RecentRejected: 1, Lots: 3}},
+		// Rejections further apart than 5 lots do not tighten
+		{"rejections apart", join(repeat(rejected, 1), repeat(accepted, 4), repeat(rejected, 1)),
+			State{Severity: SeverityNormal, SwitchingScore: 0, RecentRejected: 1, Lots: 6}},
+		{"tightened", join(repeat(rejected, 1), repeat(accepted, 3), repeat(rejected, 1)),
+			State{Severity: SeverityTightened, Lots: 5}},
+		{"tightened, a rejection restarts the run", join(repeat(rejected, 2), repeat(accepted, 4), repeat(rejected, 1), repeat(accepted, 4)),
+			State{Severity: SeverityTightened, ConsecutiveAccepted: 4, Lots: 11}},
+		{"back to normal", join(repeat(rejected, 2), repeat(accepted, 5)),
+			State{Severity: SeverityNormal, Lots: 7}},
+		// 0/1 plans score 2 each; 3 when the next tighter AQL accepts too
+		{"scoring", join(repeat(accepted, 3), repeat(lot(LotAccepted, 5, 3), 2), repeat(lot(LotAccepted, 5, 4), 1)),
+			State{Severity: SeverityNormal, SwitchingScore: 0, Lots: 6}},
+		{"reduced", join(repeat(accepted, 9), repeat(lot(LotAccepted, 5, 3), 4)),
+			State{Severity: SeverityReduced, Lots: 13}},
+		{"reduced until a rejection", join(repeat(accepted, 15), repeat(accepted, 3), repeat(rejected, 1)),
+			State{Severity: SeverityNormal, Lots: 19}},
+	} {
+		if got := Replay(tc.lots); got != tc.state {
+			t.Errorf("%s: state = %+v, want %+v", tc.name, got, tc.state)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	if tighter(5) != 3 || tighter(1) != 0 || tighter(21) != 14 {
+		t.Errorf("tighter = %d %d %d", tighter(5), tighter(1), tighter(21))
+	}
+	if s := score(10, models.InspectionLot{Status: LotAccepted, AcceptNumber: 5, Defects: 4}); s != 0 {
+		t.Errorf("accepted only at its own AQL scores %d", s)
+	}
+	if s := score(10, models.InspectionLot{Status: LotRejected}); s != 0 {
+		t.Errorf("rejected scores %d", s)
+	}
+}
@@ -0,0 +1,752 @@
+// Package scheduling places processing orders into slots on the processing
+// units able to perform them. A slot avoids the unit's other orders and its
+// maintenance windows and keeps the sawmill within its capacity; the
+// timeline of a sawmill shows every slot per unit with the overloads found.
+//
+// Capacities are quantities per hour. An order runs for its DurationHours,
+// or for its PlannedQuantity over the unit's capacity when no duration was
+// planned. A maintenance record blocks its unit from midnight (UTC) of its
+// date for its downtime hours.
+package scheduling
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// Horizon is how far a timeline reaches when no end is asked for
+const Horizon = 14 * 24 * time.Hour
+
+// Service schedules orders and draws timelines from the stored plant
+type Service struct {
+	tx     repository.Transactor
+	orders repository.ProcessingRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, orders: repos.Processing}
+}
+
+// Request asks for orders to be scheduled no earlier than From. Without
+// ProcessingIDs every planned or released order without a slot is, when it
+// has no unit yet or one of the sawmill's.
+type Request struct {
+	ProcessingIDs []int  `json:"processing_ids"`
+	From          string `json:"from"`
+}
+
+// Placement is the slot found for an order
+type Placement struct {
+	ProcessingID  int     `json:"processing_id"`
+	SawmillID     int     `json:"sawmill_id"`
+	UnitID        int     `json:"unit_id"`
+	Start         string  `json:"start"`
+	End           string  `json:"end"`
+	DurationHours float64 `json:"duration_hours"`
+}
+
+// Unplaced is an order no slot was found for, and why
+type Unplaced struct {
+	ProcessingID int    `json:"processing_id"`
+	Code         string `json:"code"`
+	Message      string `json:"message"`
+}
+
+// Plan is the outcome of a scheduling run with the sawmill's timeline
+// after it
+type Plan struct {
+	DryRun     bool        `json:"dry_run"`
+	Placements []Placement `json:"placements"`
+	Unplaced   []Unplaced  `json:"unplaced"`
+	Timeline   Timeline    `json:"timeline"`
+}
+
+// Bar is an order's slot on a unit
+type Bar struct {
+	ProcessingID    int     `json:"processing_id"`
+	Status          string  `json:"status"`
+	Operations      string  `json:"operations"`
+	PlannedQuantity float64 `json:"planned_quantity"`
+	Rate            float64 `json:"rate"` // quantity per hour
+	Start           string  `json:"start"`
+	End             string  `json:"end"`
+	WhatIf          bool    `json:"what_if,omitempty"` // placed by a dry run
+}
+
+// Window is a unit's maintenance downtime
+type Window struct {
+	MaintenanceID int    `json:"maintenance_id"`
+	Description   string `json:"description"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+}
+
+// Lane is one unit of a timeline
+type Lane struct {
+	UnitID       int      `json:"unit_id"`
+	Status       string   `json:"status"`
+	Capacity     float64  `json:"capacity"`
+	Capabilities []string `json:"capabilities"`
+	Bars         []Bar    `json:"bars"`
+	Maintenance  []Window `json:"maintenance"`
+}
+
+// Overload is a conflict among the slots of a timeline: two orders on one
+// unit at once (unit_overlap), an order during maintenance (maintenance),
+// an order faster than its unit (unit_capacity) or concurrent orders beyond
+// the sawmill's capacity (sawmill_capacity)
+type Overload struct {
+	Kind          string  `json:"kind"`
+	UnitID        *int    `json:"unit_id,omitempty"`
+	MaintenanceID *int    `json:"maintenance_id,omitempty"`
+	ProcessingIDs []int   `json:"processing_ids"`
+	Start         string  `json:"start"`
+	End           string  `json:"end"`
+	Load          float64 `json:"load,omitempty"`
+	Capacity      float64 `json:"capacity,omitempty"`
+}
+
+// Timeline is the Gantt view of a sawmill between From and To
+type Timeline struct {
+	SawmillID int        `json:"sawmill_id"`
+	Name      string     `json:"name"`
+	Capacity  float64    `json:"capacity"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Lanes     []Lane     `json:"lanes"`
+	Overloads []Overload `json:"overloads"`
+}
+
+// ParseTime reads an RFC 3339 time or a date, which starts at midnight UTC
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if len(s) > 10 {
+		s = s[:10]
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+func format(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func hours(h float64) time.Duration {
+	return time.Duration(h * float64(time.Hour)).Round(time.Minute)
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+func (a interval) overlaps(b interval) bool {
+	return a.start.Before(b.end) && b.start.Before(a.end)
+}
+
+// booking is an order holding a slot on a unit
+type booking struct {
+	order  models.ProcessingOrder
+	unitID int
+	slot   interval
+	rate   float64
+	whatIf bool
+}
+
+// active bookings still occupy their unit; finished orders are history
+func (b booking) active() bool {
+	return b.order.Status != processing.StatusCompleted && b.order.Status != processing.StatusClosed
+}
+
+type window struct {
+	record models.MaintenanceRecord
+	slot   interval
+}
+
+// plant is the state of the sawmills being scheduled
+type plant struct {
+	sawmills    map[int]models.Sawmill
+	units       []models.ProcessingUnit
+	unitMill    map[int]int
+	maintenance map[int][]window
+	bookings    []booking
+}
+
+func where(field string, value interface{}) query.Spec {
+	var spec query.Spec
+	spec.Where(field, value)
+	return spec
+}
+
+func whereIn(field string, values []int) query.Spec {
+	var spec query.Spec
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	spec.WhereIn(field, list...)
+	return spec
+}
+
+// load reads the sawmill with its units, their maintenance and the orders
+// holding slots on them
+func (s *Service) load(ctx context.Context, sawmillID int) (*plant, error) {
+	mills, err := s.orders.ListSawmills(ctx, where("sawmill_id", sawmillID))
+	if err != nil {
+		return nil, err
+	}
+	p := &plant{sawmills: map[int]models.Sawmill{}, unitMill: map[int]int{}, maintenance: map[int][]window{}}
+	var millIDs []int
+	for _, m := range mills.Data {
+		p.sawmills[m.SawmillID] = m
+		millIDs = append(millIDs, m.SawmillID)
+	}
+
+	unitSpec := whereIn("sawmill_id", millIDs)
+	unitSpec.Sort = []query.Order{{Field: "unit_id"}}
+	units, err := s.orders.ListProcessingUnits(ctx, unitSpec)
+	if err != nil {
+		return nil, err
+	}
+	p.units = units.Data
+	var unitIDs []int
+	for _, u := range p.units {
+		p.unitMill[u.UnitID] = u.SawmillID
+		unitIDs = append(unitIDs, u.UnitID)
+	}
+
+	records, err := s.orders.ListMaintenanceRecords(ctx, whereIn("unit_id", unitIDs))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records.Data {
+		day, err := ParseTime(r.MaintenanceDate)
+		if err != nil || r.DowntimeHours <= 0 {
+			continue
+		}
+		slot := interval{day, day.Add(hours(r.DowntimeHours))}
+		p.maintenance[r.UnitID] = append(p.maintenance[r.UnitID], window{record: r, slot: slot})
+	}
+
+	orders, err := s.orders.ListProcessingOrders(ctx, whereIn("unit_id", unitIDs))
+	if err != nil {
+		return nil, err
+	}
+	for _, po := range orders.Data {
+		if b, ok := slotOf(po); ok {
+			p.bookings = append(p.bookings, b)
+		}
+	}
+	return p, nil
+}
+
+// slotOf is the booking of an order that has a slot
+func slotOf(po models.ProcessingOrder) (booking, bool) {
+	if po.ScheduledStart == nil || po.ScheduledEnd == nil || po.UnitID == 0 {
+		return booking{}, false
+	}
+	start, err1 := ParseTime(*po.ScheduledStart)
+	end, err2 := ParseTime(*po.ScheduledEnd)
+	if err1 != nil || err2 != nil || !end.After(start) {
+		return booking{}, false
+	}
+	b := booking{order: po, unitID: po.UnitID, slot: interval{start, end}}
+	if h := end.Sub(start).Hours(); po.PlannedQuantity > 0 {
+		b.rate = round(po.PlannedQuantity / h)
+	}
+	return b, true
+}
+
+// Supports reports whether the unit can perform op. The capability columns
+// describe the equipment; empty or "none" means the unit has none.
+func Supports(u models.ProcessingUnit, op string) bool {
+	var equipment string
+	switch op {
+	case processing.OpCutting:
+		equipment = u.Cutting
+	case processing.OpDrying:
+		equipment = u.Drying
+	case processing.OpFinishing:
+		equipment = u.Finishing
+	}
+	switch strings.ToLower(strings.TrimSpace(equipment)) {
+	case "", "none", "no", "n/a", "false":
+		return false
+	}
+	return true
+}
+
+func capabilities(u models.ProcessingUnit) []string {
+	caps := []string{}
+	for _, op := range []string{processing.OpCutting, processing.OpDrying, processing.OpFinishing} {
+		if Supports(u, op) {
+			caps = append(caps, op)
+		}
+	}
+	return caps
+}
+
+// available reports whether the unit and its sawmill are in service
+func (p *plant) available(u models.ProcessingUnit) bool {
+	if u.Status != "" && u.Status != "active" {
+		return false
+	}
+	switch p.sawmills[u.SawmillID].Status {
+	case "", "operational", "active":
+		return true
+	}
+	return false
+}
+
+// load is the peak rate the sawmill's active bookings reach during slot
+func (p *plant) load(sawmillID int, slot interval) (float64, []int) {
+	var within []booking
+	for _, b := range p.bookings {
+		if b.active() && p.unitMill[b.unitID] == sawmillID && b.slot.overlaps(slot) {
+			within = append(within, b)
+		}
+	}
+	points := []time.Time{slot.start}
+	for _, b := range within {
+		if b.slot.start.After(slot.start) {
+			points = append(points, b.slot.start)
+		}
+	}
+	var peak float64
+	var ids []int
+	for _, pt := range points {
+		var sum float64
+		var at []int
+		for _, b := range within {
+			if !b.slot.start.After(pt) && b.slot.end.After(pt) {
+				sum += b.rate
+				at = append(at, b.order.ProcessingID)
+			}
+		}
+		if sum > peak {
+			peak, ids = sum, at
+		}
+	}
+	return round(peak), ids
+}
+
+// free reports whether the unit has neither an order nor maintenance
+// during slot
+func (p *plant) free(unitID int, slot interval) bool {
+	for _, b := range p.bookings {
+		if b.active() && b.unitID == unitID && b.slot.overlaps(slot) {
+			return false
+		}
+	}
+	for _, w := range p.maintenance[unitID] {
+		if w.slot.overlaps(slot) {
+			return false
+		}
+	}
+	return true
+}
+
+// earliest finds the first start from which the unit is free for length
+// and the sawmill can take rate on top of its load. Candidates are from and
+// every end of a booking or maintenance window that could be in the way;
+// the last of them is past all of them, so a slot is always found.
+func (p *plant) earliest(u models.ProcessingUnit, from time.Time, length time.Duration, rate float64) time.Time {
+	mill := p.sawmills[u.SawmillID]
+	candidates := []time.Time{from}
+	for _, b := range p.bookings {
+		if b.active() && p.unitMill[b.unitID] == u.SawmillID && b.slot.end.After(from) {
+			candidates = append(candidates, b.slot.end)
+		}
+	}
+	for _, w := range p.maintenance[u.UnitID] {
+		if w.slot.end.After(from) {
+			candidates = append(candidates, w.slot.end)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	for _, start := range candidates {
+		slot := interval{start, start.Add(length)}
+		if !p.free(u.UnitID, slot) {
+			continue
+		}
+		if peak, _ := p.load(u.SawmillID, slot); mill.Capacity > 0 && peak+rate > mill.Capacity {
+			continue
+		}
+		return start
+	}
+	return candidates[len(candidates)-1]
+}
+
+// place finds the earliest-ending slot for po among the units able to run
+// it, or says why there is none
+func (p *plant) place(po models.ProcessingOrder, from time.Time) (booking, *Unplaced) {
+	ops := processing.Operations(po)
+	unplaced := func(code, format string, args ...interface{}) (booking, *Unplaced) {
+		return booking{}, &Unplaced{ProcessingID: po.ProcessingID, Code: code, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if _, ok := p.unitMill[po.UnitID]; po.UnitID != 0 && po.ScheduledStart == nil && !ok {
+		return unplaced("unit_elsewhere", "The order is assigned to unit %d, which is not in this sawmill", po.UnitID)
+	}
+
+	var best booking
+	found, compatible, sized := false, false, false
+	for _, u := range p.units {
+		// An order given a unit before it was scheduled is pinned to it
+		if po.UnitID != 0 && po.ScheduledStart == nil && u.UnitID != po.UnitID {
+			continue
+		}
+		if !p.available(u) {
+			continue
+		}
+		capable := true
+		for _, op := range ops {
+			capable = capable && Supports(u, op)
+		}
+		if !capable {
+			continue
+		}
+		compatible = true
+
+		length := po.DurationHours
+		if length <= 0 {
+			if po.PlannedQuantity <= 0 || u.Capacity <= 0 {
+				continue
+			}
+			length = po.PlannedQuantity / u.Capacity
+		}
+		sized = true
+		var rate float64
+		if po.PlannedQuantity > 0 {
+			rate = round(po.PlannedQuantity / length)
+		}
+		mill := p.sawmills[u.SawmillID]
+		if (u.Capacity > 0 && rate > u.Capacity) || (mill.Capacity > 0 && rate > mill.Capacity) {
+			continue
+		}
+
+		start := p.earliest(u, from, hours(length), rate)
+		b := booking{order: po, unitID: u.UnitID, slot: interval{start, start.Add(hours(length))}, rate: rate}
+		if !found || b.slot.end.Before(best.slot.end) {
+			best, found = b, true
+		}
+	}
+
+	switch {
+	case found:
+		return best, nil
+	case !compatible:
+		return unplaced("no_compatible_unit", "No unit in service can perform %s", strings.Join(ops, ", "))
+	case !sized:
+		return unplaced("no_duration", "Give the order a duration_hours, or a planned_quantity and units a capacity")
+	}
+	return unplaced("over_capacity", "Every compatible unit is too slow to process %g in %g hours", po.PlannedQuantity, po.DurationHours)
+}
+
+// Schedule places the requested orders on the sawmill's units one after
+// the other, each in the earliest ending slot, and stores the slots unless
+// the run is a dry run
+func (s *Service) Schedule(ctx context.Context, sawmillID int, req Request, dryRun bool) (Plan, error) {
+	plan := Plan{DryRun: dryRun, Placements: []Placement{}, Unplaced: []Unplaced{}}
+	from := time.Now().UTC().Truncate(time.Hour).Add(time.Hour)
+	if req.From != "" {
+		t, err := ParseTime(req.From)
+		if err != nil {
+			return plan, validate.Errors{{Field: "from", Code: validate.CodeInvalidDate,
+				Message: "from must be a date (YYYY-MM-DD) or an RFC 3339 time"}}
+		}
+		from = t
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		p, err := s.load(ctx, sawmillID)
+		if err != nil {
+			return err
+		}
+		mill, ok := p.sawmills[sawmillID]
+		if !ok {
+			return repository.ErrNotFound
+		}
+		orders, err := s.pending(ctx, p, req.ProcessingIDs, &plan)
+		if err != nil {
+			return err
+		}
+
+		for _, po := range orders {
+			// Rescheduling frees the order's current slot first
+			kept := p.bookings[:0]
+			for _, b := range p.bookings {
+				if b.order.ProcessingID != po.ProcessingID {
+					kept = append(kept, b)
+				}
+			}
+			p.bookings = kept
+
+			b, why := p.place(po, from)
+			if why != nil {
+				plan.Unplaced = append(plan.Unplaced, *why)
+				continue
+			}
+			b.whatIf = dryRun
+			p.bookings = append(p.bookings, b)
+			plan.Placements = append(plan.Placements, Placement{
+				ProcessingID:  po.ProcessingID,
+				SawmillID:     sawmillID,
+				UnitID:        b.unitID,
+				Start:         format(b.slot.start),
+				End:           format(b.slot.end),
+				DurationHours: round(b.slot.end.Sub(b.slot.start).Hours()),
+			})
+			if dryRun {
+				continue
+			}
+			start, end := format(b.slot.start), format(b.slot.end)
+			po.UnitID, po.ScheduledStart, po.ScheduledEnd = b.unitID, &start, &end
+			if err := s.orders.UpdateProcessingOrder(ctx, po.ProcessingID, &po); err != nil {
+				return err
+			}
+		}
+
+		to := from.Add(Horizon)
+		for _, b := range p.bookings {
+			if b.slot.end.After(to) {
+				to = b.slot.end
+			}
+		}
+		plan.Timeline = p.timeline(mill, interval{from, to})
+		return nil
+	})
+	return plan, err
+}
+
+// pending lists the orders to schedule on the plant's units, noting in plan
+// those that cannot be
+func (s *Service) pending(ctx context.Context, p *plant, ids []int, plan *Plan) ([]models.ProcessingOrder, error) {
+	if len(ids) == 0 {
+		// Unit 0 is an order not yet given a unit
+		unitIDs := []int{0}
+		for _, u := range p.units {
+			unitIDs = append(unitIDs, u.UnitID)
+		}
+		spec := whereIn("unit_id", unitIDs)
+		spec.Sort = []query.Order{{Field: "processing_id"}}
+		spec.WhereIn("status", processing.StatusPlanned, processing.StatusReleased)
+		page, err := s.orders.ListProcessingOrders(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		var orders []models.ProcessingOrder
+		for _, po := range page.Data {
+			if po.ScheduledStart == nil {
+				orders = append(orders, po)
+			}
+		}
+		return orders, nil
+	}
+
+	page, err := s.orders.ListProcessingOrders(ctx, whereIn("processing_id", ids))
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int]models.ProcessingOrder{}
+	for _, po := range page.Data {
+		byID[po.ProcessingID] = po
+	}
+	var orders []models.ProcessingOrder
+	for _, id := range ids {
+		po, ok := byID[id]
+		switch {
+		case !ok:
+			plan.Unplaced = append(plan.Unplaced, Unplaced{ProcessingID: id, Code: "not_found",
+				Message: "The processing order does not exist"})
+		case po.Status != processing.StatusPlanned && po.Status != processing.StatusReleased:
+			plan.Unplaced = append(plan.Unplaced, Unplaced{ProcessingID: id, Code: "not_schedulable",
+				Message: fmt.Sprintf("A %s processing order cannot be scheduled", po.Status)})
+		default:
+			orders = append(orders, po)
+		}
+	}
+	return orders, nil
+}
+
+// Timeline draws the sawmill's units between from and to
+func (s *Service) Timeline(ctx context.Context, sawmillID int, from, to time.Time) (Timeline, error) {
+	p, err := s.load(ctx, sawmillID)
+	if err != nil {
+		return Timeline{}, err
+	}
+	mill, ok := p.sawmills[sawmillID]
+	if !ok {
+		return Timeline{}, repository.ErrNotFound
+	}
+	return p.timeline(mill, interval{from, to}), nil
+}
+
+// timeline draws the sawmill's units during span and finds its overloads
+func (p *plant) timeline(mill models.Sawmill, span interval) Timeline {
+	t := Timeline{
+		SawmillID: mill.SawmillID,
+		Name:      mill.Name,
+		Capacity:  mill.Capacity,
+		From:      format(span.start),
+		To:        format(span.end),
+		Lanes:     []Lane{},
+		Overloads: []Overload{},
+	}
+	var shown []booking
+	for _, u := range p.units {
+		if u.SawmillID != mill.SawmillID {
+			continue
+		}
+		lane := Lane{UnitID: u.UnitID, Status: u.Status, Capacity: u.Capacity, Capabilities: capabilities(u),
+			Bars: []Bar{}, Maintenance: []Window{}}
+		var bars []booking
+		for _, b := range p.bookings {
+			if b.unitID == u.UnitID && b.slot.overlaps(span) {
+				bars = append(bars, b)
+			}
+		}
+		sort.Slice(bars, func(i, j int) bool { return bars[i].slot.start.Before(bars[j].slot.start) })
+		for _, b := range bars {
+			lane.Bars = append(lane.Bars, Bar{
+				ProcessingID:    b.order.ProcessingID,
+				Status:          b.order.Status,
+				Operations:      b.order.Operations,
+				PlannedQuantity: b.order.PlannedQuantity,
+				Rate:            b.rate,
+				Start:           format(b.slot.start),
+				End:             format(b.slot.end),
+				WhatIf:          b.whatIf,
+			})
+			if b.active() {
+				shown = append(shown, b)
+			}
+		}
+		for _, w := range p.maintenance[u.UnitID] {
+			if w.slot.overlaps(span) {
+				lane.Maintenance = append(lane.Maintenance, Window{MaintenanceID: w.record.MaintenanceID,
+					Description: w.record.Description, Start: format(w.slot.start), End: format(w.slot.end)})
+			}
+		}
+		t.Lanes = append(t.Lanes, lane)
+	}
+	t.Overloads = p.overloads(mill, shown)
+	return t
+}
+
+// overloads finds the conflicts among the active bookings of a sawmill
+func (p *plant) overloads(mill models.Sawmill, bookings []booking) []Overload {
+	found := []Overload{}
+	units := map[int]models.ProcessingUnit{}
+	for _, u := range p.units {
+		units[u.UnitID] = u
+	}
+	for i, b := range bookings {
+		unitID := b.unitID
+		for _, other := range bookings[i+1:] {
+			if other.unitID == b.unitID && other.slot.overlaps(b.slot) {
+				found = append(found, Overload{Kind: "unit_overlap", UnitID: &unitID,
+					ProcessingIDs: []int{b.order.ProcessingID, other.order.ProcessingID},
+					Start:         format(maxTime(b.slot.start, other.slot.start)),
+					End:           format(minTime(b.slot.end, other.slot.end))})
+			}
+		}
+		for _, w := range p.maintenance[b.unitID] {
+			if w.slot.overlaps(b.slot) {
+				maintenanceID := w.record.MaintenanceID
+				found = append(found, Overload{Kind: "maintenance", UnitID: &unitID, MaintenanceID: &maintenanceID,
+					ProcessingIDs: []int{b.order.ProcessingID},
+					Start:         format(maxTime(b.slot.start, w.slot.start)),
+					End:           format(minTime(b.slot.end, w.slot.end))})
+			}
+		}
+		if u := units[b.unitID]; u.Capacity > 0 && b.rate > u.Capacity {
+			found = append(found, Overload{Kind: "unit_capacity", UnitID: &unitID,
+				ProcessingIDs: []int{b.order.ProcessingID},
+				Start:         format(b.slot.start), End: format(b.slot.end), Load: b.rate, Capacity: u.Capacity})
+		}
+	}
+	if mill.Capacity <= 0 {
+		return found
+	}
+
+	// Walk the segments between slot boundaries, merging adjacent ones over
+	// the sawmill's capacity
+	var points []time.Time
+	for _, b := range bookings {
+		points = append(points, b.slot.start, b.slot.end)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+	var open *Overload
+	for i := 0; i+1 < len(points); i++ {
+		seg := interval{points[i], points[i+1]}
+		if !seg.start.Before(seg.end) {
+			continue
+		}
+		var load float64
+		var ids []int
+		for _, b := range bookings {
+			if b.slot.overlaps(seg) {
+				load += b.rate
+				ids = append(ids, b.order.ProcessingID)
+			}
+		}
+		load = round(load)
+		if load <= mill.Capacity {
+			open = nil
+			continue
+		}
+		if open != nil && open.End == format(seg.start) {
+			open.End = format(seg.end)
+			open.Load = math.Max(open.Load, load)
+			open.ProcessingIDs = union(open.ProcessingIDs, ids)
+			continue
+		}
+		found = append(found, Overload{Kind: "sawmill_capacity", ProcessingIDs: ids,
+			Start: format(seg.start), End: format(seg.end), Load: load, Capacity: mill.Capacity})
+		open = &found[len(found)-1]
+	}
+	return found
+}
+
+func union(a, b []int) []int {
+	seen := map[int]bool{}
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			seen[id] = true
+			a = append(a, id)
+		}
+	}
+	return a
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
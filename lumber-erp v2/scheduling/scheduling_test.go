@@ -0,0 +1,114 @@
+package scheduling
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/processing"
+)
+
+func at(hour int) time.Time {
+	return time.Date(2030, 1, 1, hour, 0, 0, 0, time.UTC)
+}
+
+// testPlant is a sawmill taking 30 an hour with a band saw taking 10, busy
+// from 8 to 10 and serviced from 10 to 12, and a circular saw taking 20
+func testPlant() *plant {
+	return &plant{
+		sawmills: map[int]models.Sawmill{1: {SawmillID: 1, Capacity: 30, Status: "operational"}},
+		units: []models.ProcessingUnit{
+			{UnitID: 1, SawmillID: 1, Cutting: "band saw", Capacity: 10, Status: "active"},
+			{UnitID: 2, SawmillID: 1, Cutting: "circular saw", Drying: "none", Capacity: 20, Status: "active"},
+		},
+		unitMill: map[int]int{1: 1, 2: 1},
+		maintenance: map[int][]window{
+			1: {{record: models.MaintenanceRecord{MaintenanceID: 1}, slot: interval{at(10), at(12)}}},
+		},
+		bookings: []booking{{order: models.ProcessingOrder{ProcessingID: 1, Status: processing.StatusPlanned},
+			unitID: 1, slot: interval{at(8), at(10)}, rate: 10}},
+	}
+}
+
+func TestEarliest(t *testing.T) {
+	p := testPlant()
+	for name, tc := range map[string]struct {
+		unit   int
+		from   int
+		length time.Duration
+		rate   float64
+		start  int
+	}{
+		"before the order":                   {unit: 1, from: 7, length: time.Hour, rate: 5, start: 7},
+		"past the order and the maintenance": {unit: 1, from: 7, length: 2 * time.Hour, rate: 5, start: 12},
+		"once the sawmill has room":          {unit: 2, from: 8, length: time.Hour, rate: 25, start: 10},
+		"filling the sawmill":                {unit: 2, from: 8, length: time.Hour, rate: 20, start: 8},
+	} {
+		if got := p.earliest(p.units[tc.unit-1], at(tc.from), tc.length, tc.rate); !got.Equal(at(tc.start)) {
+			t.Errorf("%s: earliest = %s, want %s", name, format(got), format(at(tc.start)))
+		}
+	}
+}
+
+func TestPlace(t *testing.T) {
+	p := testPlant()
+	for name, tc := range map[string]struct {
+		order      models.ProcessingOrder
+		unit       int
+		start, end int
+		code       string
+	}{
+		"earliest ending unit":   {order: models.ProcessingOrder{Operations: "cutting", DurationHours: 2}, unit: 2, start: 8, end: 10},
+		"pinned to its unit":     {order: models.ProcessingOrder{UnitID: 1, DurationHours: 2}, unit: 1, start: 12, end: 14},
+		"sized by capacity":      {order: models.ProcessingOrder{Operations: "cutting", PlannedQuantity: 40}, unit: 2, start: 8, end: 10},
+		"unit of another mill":   {order: models.ProcessingOrder{UnitID: 9, DurationHours: 1}, code: "unit_elsewhere"},
+		"no unit dries":          {order: models.ProcessingOrder{Operations: "drying", DurationHours: 1}, code: "no_compatible_unit"},
+		"no duration":            {order: models.ProcessingOrder{Operations: "cutting"}, code: "no_duration"},
+		"faster than every unit": {order: models.ProcessingOrder{DurationHours: 1, PlannedQuantity: 100}, code: "over_capacity"},
+	} {
+		tc.order.ProcessingID = 2
+		b, why := p.place(tc.order, at(8))
+		switch {
+		case tc.code != "":
+			if why == nil || why.Code != tc.code || why.ProcessingID != 2 {
+				t.Errorf("%s: unplaced = %+v, want %s", name, why, tc.code)
+			}
+		case why != nil:
+			t.Errorf("%s: unplaced: %+v", name, why)
+		case b.unitID != tc.unit || !b.slot.start.Equal(at(tc.start)) || !b.slot.end.Equal(at(tc.end)):
+			t.Errorf("%s: placed on unit %d from %s to %s, want unit %d from %d to %d", name, b.unitID,
+				format(b.slot.start), format(b.slot.end), tc.unit, tc.start, tc.end)
+		}
+	}
+}
+
+func TestOverloads(t *testing.T) {
+	p := testPlant()
+	planned := func(id int) models.ProcessingOrder {
+		return models.ProcessingOrder{ProcessingID: id, Status: processing.StatusPlanned}
+	}
+	bookings := append(p.bookings,
+		booking{order: planned(2), unitID: 1, slot: interval{at(9), at(11)}, rate: 10},
+		booking{order: planned(3), unitID: 2, slot: interval{at(8), at(11)}, rate: 25},
+	)
+	var got []string
+	for _, o := range p.overloads(p.sawmills[1], bookings) {
+		got = append(got, fmt.Sprintf("%s %v %s-%s %g", o.Kind, o.ProcessingIDs, o.Start[11:16], o.End[11:16], o.Load))
+	}
+	want := []string{
+		"unit_overlap [1 2] 09:00-10:00 0",
+		"maintenance [2] 10:00-11:00 0",
+		"unit_capacity [3] 08:00-11:00 25",
+		"sawmill_capacity [1 3 2] 08:00-11:00 45",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overloads =\n%q\nwant\n%q", got, want)
+	}
+
+	p.sawmills[1] = models.Sawmill{SawmillID: 1}
+	if found := p.overloads(p.sawmills[1], bookings[:1]); len(found) != 0 {
+		t.Errorf("overloads of one order in an unbounded sawmill = %+v", found)
+	}
+}
@@ -0,0 +1,625 @@
+// Package spc charts quality inspections for statistical process control.
+// Inspections are grouped by the processing unit of their order and the
+// species of their harvest batch, taken in date order and cut into
+// subgroups of a fixed size. Each group gets an X-bar chart of the
+// subgroup means of a metric, an R chart of their ranges and a p-chart of
+// the share of failed inspections.
+//
+// Control limits come from a baseline window of subgroups and every
+// subgroup is checked against the Western Electric rules; a violation
+// raises a quality alert once.
+package spc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"lumber-erp-api/inspection"
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/scheduling"
+	"lumber-erp-api/validate"
+)
+
+// MetricMoisture is the moisture level every inspection records; the other
+// metrics are the checklist kinds of template measurements
+const MetricMoisture = "moisture_level"
+
+var metrics = map[string]bool{MetricMoisture: true, "knots": true, "wane": true, "split": true, "warp": true,
+	"moisture": true, "dimension": true}
+
+// Charts
+const (
+	ChartXBar  = "xbar"
+	ChartRange = "range"
+	ChartP     = "p"
+)
+
+// Alert states, as for stock alerts
+const (
+	AlertActive   = "Active"
+	AlertResolved = "Resolved"
+)
+
+// Subgroup sizes the chart constants are tabled for, and the defaults
+const (
+	MinSubgroupSize     = 2
+	MaxSubgroupSize     = 10
+	DefaultSubgroupSize = 5
+	// BaselineSubgroups is how many subgroups the limits come from when no
+	// baseline window is asked for
+	BaselineSubgroups = 20
+)
+
+// Control chart constants by subgroup size
+var (
+	a2 = map[int]float64{2: 1.880, 3: 1.023, 4: 0.729, 5: 0.577, 6: 0.483, 7: 0.419, 8: 0.373, 9: 0.337, 10: 0.308}
+	d3 = map[int]float64{2: 0, 3: 0, 4: 0, 5: 0, 6: 0, 7: 0.076, 8: 0.136, 9: 0.184, 10: 0.223}
+	d4 = map[int]float64{2: 3.267, 3: 2.574, 4: 2.282, 5: 2.114, 6: 2.004, 7: 1.924, 8: 1.864, 9: 1.816, 10: 1.777}
+)
+
+// rules describes the Western Electric rules by number
+var rules = map[int]string{
+	1: "a point beyond the 3 sigma limits",
+	2: "2 of 3 points beyond 2 sigma on one side",
+	3: "4 of 5 points beyond 1 sigma on one side",
+	4: "8 points in a row on one side of the center line",
+}
+
+// Params selects what to chart. UnitID and SpeciesID narrow the groups;
+// From and To the inspections. The baseline is the subgroups ending within
+// BaselineFrom and BaselineTo, or else the first BaselineSubgroups.
+type Params struct {
+	Metric       string
+	SubgroupSize int
+	UnitID       *int
+	SpeciesID    *int
+	From, To     *time.Time
+	BaselineFrom *time.Time
+	BaselineTo   *time.Time
+}
+
+// Limits are the center line and control limits of a chart, and the sigma
+// its zones are measured in
+type Limits struct {
+	Center float64 `json:"center"`
+	UCL    float64 `json:"ucl"`
+	LCL    float64 `json:"lcl"`
+	Sigma  float64 `json:"sigma"`
+}
+
+// Point is a subgroup plotted on a chart with the rules it breaks
+type Point struct {
+	Subgroup      int     `json:"subgroup"`
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Value         float64 `json:"value"`
+	InspectionIDs []int   `json:"inspection_ids"`
+	Baseline      bool    `json:"baseline"`
+	Violations    []int   `json:"violations"`
+}
+
+// Chart is one control chart; Limits is nil without a baseline of at least
+// two subgroups
+type Chart struct {
+	Limits *Limits `json:"limits"`
+	Points []Point `json:"points"`
+}
+
+// Violation is a rule a chart broke at a subgroup
+type Violation struct {
+	Chart        string  `json:"chart"`
+	Rule         int     `json:"rule"`
+	Subgroup     int     `json:"subgroup"`
+	InspectionID int     `json:"inspection_id"` // the last of the subgroup
+	Value        float64 `json:"value"`
+	Description  string  `json:"description"`
+}
+
+// Group holds the charts of the inspections of a unit and species; a nil
+// id stands for inspections without one
+type Group struct {
+	UnitID            *int        `json:"unit_id"`
+	SpeciesID         *int        `json:"species_id"`
+	SpeciesName       string      `json:"species_name"`
+	Samples           int         `json:"samples"`
+	BaselineSubgroups int         `json:"baseline_subgroups"`
+	XBar              Chart       `json:"xbar"`
+	Range             Chart       `json:"range"`
+	P                 Chart       `json:"p"`
+	Violations        []Violation `json:"violations"`
+}
+
+// Report is the answer of the SPC endpoint
+type Report struct {
+	Metric       string  `json:"metric"`
+	SubgroupSize int     `json:"subgroup_size"`
+	Groups       []Group `json:"groups"`
+}
+
+// Service charts inspections and raises alerts of violations
+type Service struct {
+	tx      repository.Transactor
+	quality repository.QualityRepository
+	plant   repository.ProcessingRepository
+	forests repository.ForestRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, quality: repos.Quality, plant: repos.Processing, forests: repos.Forests}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+func where(field string, value interface{}) query.Spec {
+	var spec query.Spec
+	spec.Where(field, value)
+	return spec
+}
+
+// Check fills in the defaults of p and refuses what cannot be charted
+func (p *Params) Check() error {
+	var errs validate.Errors
+	p.Metric = validate.Normalize(p.Metric)
+	if p.Metric == "" {
+		p.Metric = MetricMoisture
+	}
+	if !metrics[p.Metric] {
+		errs = append(errs, validate.FieldError{Field: "metric", Code: validate.CodeInvalidChoice,
+			Message: "metric must be moisture_level or a checklist kind: knots, wane, split, warp, moisture, dimension"})
+	}
+	if p.SubgroupSize == 0 {
+		p.SubgroupSize = DefaultSubgroupSize
+	}
+	switch {
+	case p.SubgroupSize < MinSubgroupSize:
+		errs = append(errs, validate.FieldError{Field: "subgroup_size", Code: validate.CodeTooSmall,
+			Message: fmt.Sprintf("subgroup_size must be at least %d", MinSubgroupSize)})
+	case p.SubgroupSize > MaxSubgroupSize:
+		errs = append(errs, validate.FieldError{Field: "subgroup_size", Code: validate.CodeTooLarge,
+			Message: fmt.Sprintf("subgroup_size must be at most %d", MaxSubgroupSize)})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// sample is one inspection's value of the metric
+type sample struct {
+	inspectionID int
+	date         time.Time
+	value        float64
+	failed       bool
+}
+
+type groupKey struct {
+	unit, species int // 0 when unknown
+}
+
+// origin finds the unit and species of the inspections it is asked about,
+// remembering what it loaded
+type origin struct {
+	s       *Service
+	units   map[int]int
+	species map[int]int
+	mixed   map[int]int
+	names   map[int]string
+}
+
+// unit is the unit of a processing order
+func (o *origin) unit(ctx context.Context, processingID int) (int, error) {
+	if id, ok := o.units[processingID]; ok {
+		return id, nil
+	}
+	orders, err := o.s.plant.ListProcessingOrders(ctx, where("processing_id", processingID))
+	if err != nil {
+		return 0, err
+	}
+	if len(orders.Data) > 0 {
+		o.units[processingID] = orders.Data[0].UnitID
+	}
+	return o.units[processingID], nil
+}
+
+// batchSpecies is the species of a harvest batch
+func (o *origin) batchSpecies(ctx context.Context, batchID int) (int, error) {
+	if id, ok := o.species[batchID]; ok {
+		return id, nil
+	}
+	batches, err := o.s.forests.ListHarvestBatches(ctx, where("batch_id", batchID))
+	if err != nil {
+		return 0, err
+	}
+	if len(batches.Data) > 0 {
+		o.species[batchID] = batches.Data[0].SpeciesID
+	}
+	return o.species[batchID], nil
+}
+
+// orderSpecies is the species of the batches a processing order consumed,
+// when they are all of one
+func (o *origin) orderSpecies(ctx context.Context, processingID int) (int, error) {
+	if id, ok := o.mixed[processingID]; ok {
+		return id, nil
+	}
+	links, err := o.s.plant.ListHarvestBatchProcessing(ctx, where("processing_id", processingID))
+	if err != nil {
+		return 0, err
+	}
+	species := 0
+	for i, link := range links.Data {
+		id, err := o.batchSpecies(ctx, link.BatchID)
+		if err != nil {
+			return 0, err
+		}
+		if i > 0 && id != species {
+			species = 0
+			break
+		}
+		species = id
+	}
+	o.mixed[processingID] = species
+	return species, nil
+}
+
+// of finds the unit and species of an inspection
+func (o *origin) of(ctx context.Context, qi models.QualityInspection) (groupKey, error) {
+	var key groupKey
+	var err error
+	if qi.ProcessingID != nil {
+		if key.unit, err = o.unit(ctx, *qi.ProcessingID); err != nil {
+			return key, err
+		}
+	}
+	switch {
+	case qi.BatchID != 0:
+		key.species, err = o.batchSpecies(ctx, qi.BatchID)
+	case qi.ProcessingID != nil:
+		key.species, err = o.orderSpecies(ctx, *qi.ProcessingID)
+	}
+	return key, err
+}
+
+// speciesName names a species for the chart legend
+func (o *origin) speciesName(ctx context.Context, id int) (string, error) {
+	if name, ok := o.names[id]; ok || id == 0 {
+		return name, nil
+	}
+	species, err := o.s.forests.ListTreeSpecies(ctx, where("species_id", id))
+	if err != nil {
+		return "", err
+	}
+	if len(species.Data) > 0 {
+		o.names[id] = species.Data[0].SpeciesName
+	}
+	return o.names[id], nil
+}
+
+// value is an inspection's value of the metric: its moisture level, or the
+// mean of its measurements of the checklist kind. ok is false when it has
+// none; a moisture level of 0 was not measured.
+func value(qi models.QualityInspection, metric string) (float64, bool) {
+	if metric == MetricMoisture {
+		return qi.MoistureLevel, qi.MoistureLevel > 0
+	}
+	var sum float64
+	n := 0
+	for _, m := range qi.Measurements {
+		if m.Kind == metric {
+			sum += m.Value
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// samples loads the inspections p selects, grouped by unit and species in
+// date order
+func (s *Service) samples(ctx context.Context, p Params, o *origin) (map[groupKey][]sample, error) {
+	inspections, err := s.quality.ListQualityInspectionsWithFindings(ctx, query.Spec{})
+	if err != nil {
+		return nil, err
+	}
+	groups := map[groupKey][]sample{}
+	for _, qi := range inspections.Data {
+		date, err := scheduling.ParseTime(qi.Date)
+		if err != nil || (p.From != nil && date.Before(*p.From)) || (p.To != nil && date.After(*p.To)) {
+			continue
+		}
+		key, err := o.of(ctx, qi)
+		if err != nil {
+			return nil, err
+		}
+		if (p.UnitID != nil && key.unit != *p.UnitID) || (p.SpeciesID != nil && key.species != *p.SpeciesID) {
+			continue
+		}
+		v, ok := value(qi, p.Metric)
+		if !ok {
+			continue
+		}
+		groups[key] = append(groups[key], sample{inspectionID: qi.InspectionID, date: date, value: v,
+			failed: validate.Normalize(qi.Result) == inspection.ResultFail})
+	}
+	for _, list := range groups {
+		sort.Slice(list, func(i, j int) bool {
+			if !list[i].date.Equal(list[j].date) {
+				return list[i].date.Before(list[j].date)
+			}
+			return list[i].inspectionID < list[j].inspectionID
+		})
+	}
+	return groups, nil
+}
+
+// subgroup is n consecutive samples
+type subgroup struct {
+	samples []sample
+	mean    float64
+	rng     float64
+	share   float64 // of failed inspections
+}
+
+func cut(list []sample, n int) []subgroup {
+	var out []subgroup
+	for start := 0; start+n <= len(list); start += n {
+		sg := subgroup{samples: list[start : start+n]}
+		lo, hi := math.Inf(1), math.Inf(-1)
+		failed := 0
+		for _, smp := range sg.samples {
+			sg.mean += smp.value
+			lo, hi = math.Min(lo, smp.value), math.Max(hi, smp.value)
+			if smp.failed {
+				failed++
+			}
+		}
+		sg.mean /= float64(n)
+		sg.rng = hi - lo
+		sg.share = float64(failed) / float64(n)
+		out = append(out, sg)
+	}
+	return out
+}
+
+// baseline marks the subgroups the limits come from
+func baseline(subgroups []subgroup, p Params) []bool {
+	in := make([]bool, len(subgroups))
+	for i, sg := range subgroups {
+		end := sg.samples[len(sg.samples)-1].date
+		if p.BaselineFrom == nil && p.BaselineTo == nil {
+			in[i] = i < BaselineSubgroups
+			continue
+		}
+		in[i] = (p.BaselineFrom == nil || !end.Before(*p.BaselineFrom)) && (p.BaselineTo == nil || !end.After(*p.BaselineTo))
+	}
+	return in
+}
+
+// limits computes the limits of the three charts from the baseline
+// subgroups; ok is false with fewer than two
+func limits(subgroups []subgroup, in []bool, n int) (xbar, r, pc Limits, ok bool) {
+	var means, ranges, shares float64
+	count := 0
+	for i, sg := range subgroups {
+		if in[i] {
+			means += sg.mean
+			ranges += sg.rng
+			shares += sg.share
+			count++
+		}
+	}
+	if count < 2 {
+		return xbar, r, pc, false
+	}
+	grand, rbar, pbar := means/float64(count), ranges/float64(count), shares/float64(count)
+
+	xbar = Limits{Center: grand, UCL: grand + a2[n]*rbar, LCL: grand - a2[n]*rbar, Sigma: a2[n] * rbar / 3}
+	r = Limits{Center: rbar, UCL: d4[n] * rbar, LCL: d3[n] * rbar, Sigma: (d4[n] - 1) * rbar / 3}
+	sigma := math.Sqrt(pbar * (1 - pbar) / float64(n))
+	pc = Limits{Center: pbar, UCL: math.Min(1, pbar+3*sigma), LCL: math.Max(0, pbar-3*sigma), Sigma: sigma}
+	return xbar, r, pc, true
+}
+
+// Violations returns the Western Electric rules each value breaks against
+// l, marking the point that completes a pattern. With all set, rules 2 to
+// 4 are checked too; otherwise only rule 1.
+func Violations(values []float64, l Limits, all bool) [][]int {
+	out := make([][]int, len(values))
+	// side is +1 or -1 when v is more than k sigma above or below the center
+	side := func(v float64, k float64) int {
+		switch {
+		case v > l.Center+k*l.Sigma:
+			return 1
+		case v < l.Center-k*l.Sigma:
+			return -1
+		}
+		return 0
+	}
+	// beyond counts the last m points up to i at least k sigma out on dir
+	beyond := func(i, m int, k float64, dir int) int {
+		count := 0
+		for j := i; j > i-m && j >= 0; j-- {
+			if side(values[j], k) == dir {
+				count++
+			}
+		}
+		return count
+	}
+	for i, v := range values {
+		if v > l.UCL || v < l.LCL {
+			out[i] = append(out[i], 1)
+		}
+		if !all {
+			continue
+		}
+		if dir := side(v, 2); dir != 0 && i >= 2 && beyond(i, 3, 2, dir) >= 2 {
+			out[i] = append(out[i], 2)
+		}
+		if dir := side(v, 1); dir != 0 && i >= 4 && beyond(i, 5, 1, dir) >= 4 {
+			out[i] = append(out[i], 3)
+		}
+		if dir := side(v, 0); dir != 0 && i >= 7 && beyond(i, 8, 0, dir) == 8 {
+			out[i] = append(out[i], 4)
+		}
+	}
+	return out
+}
+
+// plot draws a chart of one value of each subgroup
+func plot(name string, subgroups []subgroup, in []bool, l *Limits, all bool, value func(subgroup) float64, g *Group) Chart {
+	chart := Chart{Points: make([]Point, len(subgroups))}
+	values := make([]float64, len(subgroups))
+	for i, sg := range subgroups {
+		values[i] = value(sg)
+		ids := make([]int, len(sg.samples))
+		for j, smp := range sg.samples {
+			ids[j] = smp.inspectionID
+		}
+		chart.Points[i] = Point{Subgroup: i + 1, From: sg.samples[0].date.Format("2006-01-02"),
+			To: sg.samples[len(sg.samples)-1].date.Format("2006-01-02"), Value: round(values[i]),
+			InspectionIDs: ids, Baseline: in[i], Violations: []int{}}
+	}
+	if l == nil {
+		return chart
+	}
+	for i, broken := range Violations(values, *l, all) {
+		chart.Points[i].Violations = append(chart.Points[i].Violations, broken...)
+		for _, rule := range broken {
+			last := chart.Points[i].InspectionIDs[len(chart.Points[i].InspectionIDs)-1]
+			g.Violations = append(g.Violations, Violation{Chart: name, Rule: rule, Subgroup: i + 1,
+				InspectionID: last, Value: round(values[i]), Description: rules[rule]})
+		}
+	}
+	rounded := Limits{Center: round(l.Center), UCL: round(l.UCL), LCL: round(l.LCL), Sigma: round(l.Sigma)}
+	chart.Limits = &rounded
+	return chart
+}
+
+func ref(id int) *int {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// Charts draws the X-bar, R and p-charts of every unit and species p
+// selects, ordered by unit and species
+func (s *Service) Charts(ctx context.Context, p Params) (Report, error) {
+	if err := p.Check(); err != nil {
+		return Report{}, err
+	}
+	report := Report{Metric: p.Metric, SubgroupSize: p.SubgroupSize, Groups: []Group{}}
+	o := &origin{s: s, units: map[int]int{}, species: map[int]int{}, mixed: map[int]int{}, names: map[int]string{}}
+	groups, err := s.samples(ctx, p, o)
+	if err != nil {
+		return report, err
+	}
+	keys := make([]groupKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].unit != keys[j].unit {
+			return keys[i].unit < keys[j].unit
+		}
+		return keys[i].species < keys[j].species
+	})
+
+	for _, key := range keys {
+		list := groups[key]
+		g := Group{UnitID: ref(key.unit), SpeciesID: ref(key.species), Samples: len(list), Violations: []Violation{}}
+		if g.SpeciesName, err = o.speciesName(ctx, key.species); err != nil {
+			return report, err
+		}
+		subgroups := cut(list, p.SubgroupSize)
+		in := baseline(subgroups, p)
+		for _, b := range in {
+			if b {
+				g.BaselineSubgroups++
+			}
+		}
+		var lx, lr, lp *Limits
+		if x, r, pc, ok := limits(subgroups, in, p.SubgroupSize); ok {
+			lx, lr, lp = &x, &r, &pc
+		}
+		g.XBar = plot(ChartXBar, subgroups, in, lx, true, func(sg subgroup) float64 { return sg.mean }, &g)
+		g.Range = plot(ChartRange, subgroups, in, lr, false, func(sg subgroup) float64 { return sg.rng }, &g)
+		g.P = plot(ChartP, subgroups, in, lp, true, func(sg subgroup) float64 { return sg.share }, &g)
+		report.Groups = append(report.Groups, g)
+	}
+	return report, nil
+}
+
+// Alert charts what p selects and raises a quality alert for every
+// violation not alerted yet, returning those raised
+func (s *Service) Alert(ctx context.Context, p Params) ([]models.QualityAlert, error) {
+	raised := []models.QualityAlert{}
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		raised = raised[:0]
+		report, err := s.Charts(ctx, p)
+		if err != nil {
+			return err
+		}
+		for _, g := range report.Groups {
+			for _, v := range g.Violations {
+				qa := models.QualityAlert{UnitID: g.UnitID, SpeciesID: g.SpeciesID, Metric: report.Metric,
+					Chart: v.Chart, Rule: v.Rule, InspectionID: v.InspectionID, Value: v.Value, Status: AlertActive,
+					Message: fmt.Sprintf("%s chart of %s broke rule %d: %s", v.Chart, report.Metric, v.Rule, v.Description)}
+				ok, err := s.quality.RaiseQualityAlert(ctx, &qa)
+				if err != nil {
+					return err
+				}
+				if ok {
+					raised = append(raised, qa)
+				}
+			}
+		}
+		return nil
+	})
+	return raised, err
+}
+
+// Resolve marks an alert dealt with
+func (s *Service) Resolve(ctx context.Context, id int) (models.QualityAlert, error) {
+	var qa models.QualityAlert
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		alerts, err := s.quality.ListQualityAlerts(ctx, where("alert_id", id))
+		if err != nil {
+			return err
+		}
+		if len(alerts.Data) == 0 {
+			return repository.ErrNotFound
+		}
+		qa = alerts.Data[0]
+		qa.Status = AlertResolved
+		return s.quality.UpdateQualityAlert(ctx, id, &qa)
+	})
+	return qa, err
+}
+
+// Run raises the alerts of the moisture charts every interval until ctx
+// is cancelled
+func Run(ctx context.Context, s *Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Alert(ctx, Params{}); err != nil {
+				log.Printf("⚠️  SPC alerts not raised: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package spc
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"lumber-erp-api/validate"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestLimits(t *testing.T) {
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	var list []sample
+	for i, v := range []float64{10, 12, 11, 13, 9, 11, 14} {
+		list = append(list, sample{inspectionID: i + 1, date: day.AddDate(0, 0, i), value: v, failed: i == 1})
+	}
+	subgroups := cut(list, 2)
+	if len(subgroups) != 3 || subgroups[1].mean != 12 || subgroups[2].rng != 2 || subgroups[0].share != 0.5 {
+		t.Fatalf("subgroups = %+v", subgroups)
+	}
+
+	in := baseline(subgroups, Params{})
+	xbar, r, p, ok := limits(subgroups, in, 2)
+	if !ok {
+		t.Fatal("no limits from three subgroups")
+	}
+	if !near(xbar.Center, 11) || !near(xbar.UCL, 11+1.88*2) || !near(xbar.LCL, 11-1.88*2) || !near(xbar.Sigma, 1.88*2/3) {
+		t.Errorf("xbar = %+v", xbar)
+	}
+	if !near(r.Center, 2) || !near(r.UCL, 3.267*2) || r.LCL != 0 {
+		t.Errorf("range = %+v", r)
+	}
+	pbar := 0.5 / 3
+	if sigma := math.Sqrt(pbar * (1 - pbar) / 2); !near(p.Center, pbar) || !near(p.Sigma, sigma) || p.LCL != 0 || p.UCL != math.Min(1, pbar+3*sigma) {
+		t.Errorf("p = %+v", p)
+	}
+
+	// A baseline window ending before the third subgroup leaves two
+	to := day.AddDate(0, 0, 3)
+	if in := baseline(subgroups, Params{BaselineTo: &to}); !reflect.DeepEqual(in, []bool{true, true, false}) {
+		t.Errorf("baseline to %s = %v", to, in)
+	}
+	from := day.AddDate(0, 0, 5)
+	if _, _, _, ok := limits(subgroups, baseline(subgroups, Params{BaselineFrom: &from}), 2); ok {
+		t.Error("limits from a single subgroup")
+	}
+}
+
+func TestViolations(t *testing.T) {
+	l := Limits{Center: 0, UCL: 3, LCL: -3, Sigma: 1}
+	for _, tc := range []struct {
+		name   string
+		values []float64
+		all    bool
+		want   [][]int
+	}{
+		{"rule 1 beyond the limits", []float64{0, 3.5, -4}, true, [][]int{nil, {1}, {1}}},
+		{"rule 2, 2 of 3 beyond 2 sigma", []float64{2.5, 0, 2.2}, true, [][]int{nil, nil, {2}}},
+		{"rule 2 needs one side", []float64{2.5, 0, -2.2}, true, [][]int{nil, nil, nil}},
+		{"rule 3, 4 of 5 beyond 1 sigma", []float64{1.5, 1.2, 0, 1.1, 1.3}, true, [][]int{nil, nil, nil, nil, {3}}},
+		{"rule 4, 8 on one side", []float64{-.5, -.5, -.5, -.5, -.5, -.5, -.5, -.5}, true,
+			[][]int{nil, nil, nil, nil, nil, nil, nil, {4}}},
+		{"rule 4 broken by the center line", []float64{.5, .5, .5, 0, .5, .5, .5, .5}, true, make([][]int, 8)},
+		{"only rule 1 without all", []float64{2.5, 0, 2.2, 3.1}, false, [][]int{nil, nil, nil, {1}}},
+	} {
+		if got := Violations(tc.values, l, tc.all); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: violations = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParamsCheck(t *testing.T) {
+	var p Params
+	if err := p.Check(); err != nil || p.Metric != MetricMoisture || p.SubgroupSize != DefaultSubgroupSize {
+		t.Fatalf("defaults = %+v, %v", p, err)
+	}
+	p = Params{Metric: "colour", SubgroupSize: 11}
+	var errs validate.Errors
+	if err := p.Check(); !errors.As(err, &errs) || len(errs) != 2 || errs[0].Field != "metric" || errs[1].Code != validate.CodeTooLarge {
+		t.Errorf("check = %v", err)
+	}
+}
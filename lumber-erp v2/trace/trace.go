@@ -0,0 +1,167 @@
+// Package trace follows a harvest batch from the forest it was cut in,
+// through processing and the warehouses holding its lots, to the sales
+// orders and shipments that took it to customers.
+package trace
+
+import (
+	"context"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+)
+
+// Genealogy is everything recorded about one harvest batch, upstream and
+// downstream. Movements are the ledger entries of its lots in the order
+// they were posted.
+type Genealogy struct {
+	Batch            models.HarvestBatch           `json:"batch"`
+	Forest           *models.Forest                `json:"forest"`
+	Species          *models.TreeSpecies           `json:"species"`
+	Schedule         *models.HarvestSchedule       `json:"schedule"`
+	ProcessingOrders []models.ProcessingOrder      `json:"processing_orders"`
+	Inspections      []models.QualityInspection    `json:"inspections"`
+	StockItems       []models.StockItem            `json:"stock_items"`
+	Movements        []models.InventoryTransaction `json:"movements"`
+	SalesOrderItems  []models.SalesOrderItem       `json:"sales_order_items"`
+	SalesOrders      []models.SalesOrder           `json:"sales_orders"`
+	Shipments        []models.Shipment             `json:"shipments"`
+}
+
+// where is a spec matching field = value
+func where(field string, value interface{}) query.Spec {
+	var spec query.Spec
+	spec.Where(field, value)
+	return spec
+}
+
+// whereIn is a spec matching field in values
+func whereIn(field string, values []int) query.Spec {
+	var spec query.Spec
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	spec.WhereIn(field, list...)
+	return spec
+}
+
+// first returns the row matching spec, or nil
+func first[T any](ctx context.Context, list func(context.Context, query.Spec) (query.Page[T], error), spec query.Spec) (*T, error) {
+	spec.Limit = 1
+	page, err := list(ctx, spec)
+	if err != nil || len(page.Data) == 0 {
+		return nil, err
+	}
+	return &page.Data[0], nil
+}
+
+// merge appends the rows of more not already in rows, comparing by key
+func merge[T any](rows, more []T, key func(T) int) []T {
+	seen := map[int]bool{}
+	for _, row := range rows {
+		seen[key(row)] = true
+	}
+	for _, row := range more {
+		if !seen[key(row)] {
+			seen[key(row)] = true
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Lot builds the genealogy of the harvest batch labelled qrCode
+func Lot(ctx context.Context, repos repository.Repositories, qrCode string) (Genealogy, error) {
+	var g Genealogy
+	batch, err := first(ctx, repos.Forests.ListHarvestBatches, where("qr_code", qrCode))
+	if err != nil {
+		return g, err
+	}
+	if batch == nil {
+		return g, repository.ErrNotFound
+	}
+	g.Batch = *batch
+
+	// Upstream: where the batch was cut
+	if g.Forest, err = first(ctx, repos.Forests.ListForests, where("forest_id", batch.ForestID)); err != nil {
+		return g, err
+	}
+	if g.Species, err = first(ctx, repos.Forests.ListTreeSpecies, where("species_id", batch.SpeciesID)); err != nil {
+		return g, err
+	}
+	if g.Schedule, err = first(ctx, repos.Forests.ListHarvestSchedules, where("schedule_id", batch.ScheduleID)); err != nil {
+		return g, err
+	}
+
+	// Processing orders the batch went into
+	links, err := repos.Processing.ListHarvestBatchProcessing(ctx, where("batch_id", batch.BatchID))
+	if err != nil {
+		return g, err
+	}
+	var orderIDs []int
+	for _, link := range links.Data {
+		orderIDs = append(orderIDs, link.ProcessingID)
+	}
+	orders, err := repos.Processing.ListProcessingOrders(ctx, whereIn("processing_id", orderIDs))
+	if err != nil {
+		return g, err
+	}
+	g.ProcessingOrders = orders.Data
+
+	// Inspections of the batch itself and of its processing
+	inspections, err := repos.Quality.ListQualityInspections(ctx, where("batch_id", batch.BatchID))
+	if err != nil {
+		return g, err
+	}
+	ofOrders, err := repos.Quality.ListQualityInspections(ctx, whereIn("processing_id", orderIDs))
+	if err != nil {
+		return g, err
+	}
+	g.Inspections = merge(inspections.Data, ofOrders.Data, func(qi models.QualityInspection) int { return qi.InspectionID })
+
+	// Lots of the batch and of the processing output, in every warehouse
+	lots, err := repos.Stock.ListStockItems(ctx, where("batch_id", batch.BatchID))
+	if err != nil {
+		return g, err
+	}
+	output, err := repos.Stock.ListStockItems(ctx, whereIn("processing_id", orderIDs))
+	if err != nil {
+		return g, err
+	}
+	g.StockItems = merge(lots.Data, output.Data, func(si models.StockItem) int { return si.StockID })
+	var stockIDs []int
+	for _, si := range g.StockItems {
+		stockIDs = append(stockIDs, si.StockID)
+	}
+
+	spec := whereIn("stock_id", stockIDs)
+	spec.Sort = []query.Order{{Field: "transaction_id"}}
+	movements, err := repos.Stock.ListInventoryTransactions(ctx, spec)
+	if err != nil {
+		return g, err
+	}
+	g.Movements = movements.Data
+
+	// Downstream: the sales picked from those lots and their shipments
+	items, err := repos.SalesOrders.ListSalesOrderItems(ctx, whereIn("stock_id", stockIDs))
+	if err != nil {
+		return g, err
+	}
+	g.SalesOrderItems = items.Data
+	var orderNumbers []int
+	for _, item := range items.Data {
+		orderNumbers = append(orderNumbers, item.SOID)
+	}
+	sales, err := repos.SalesOrders.ListSalesOrders(ctx, whereIn("soid", orderNumbers))
+	if err != nil {
+		return g, err
+	}
+	g.SalesOrders = sales.Data
+	shipments, err := repos.Transport.ListShipments(ctx, whereIn("soid", orderNumbers))
+	if err != nil {
+		return g, err
+	}
+	g.Shipments = shipments.Data
+	return g, nil
+}
@@ -0,0 +1,426 @@
+// Package transfers moves stock between warehouses. A transfer is drafted
+// with its lines, dispatched (posting a transfer_out per line), put in
+// transit on a shipment or truck and received (posting a transfer_in into
+// the destination stock item of the same product and lot).
+package transfers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/quarantine"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// Transfer states, in the order a transfer goes through them
+const (
+	StatusDraft      = "draft"
+	StatusDispatched = "dispatched"
+	StatusInTransit  = "in_transit"
+	StatusReceived   = "received"
+)
+
+// Service drafts, dispatches and receives transfers, posting their stock
+// movements, and reports quantities received short or over
+type Service struct {
+	tx        repository.Transactor
+	transfers repository.TransferRepository
+	stock     repository.StockRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, transfers: repos.Transfers, stock: repos.Stock}
+}
+
+// Receipt is what arrived of one line. A nil ReceivedQuantity means the
+// whole line did.
+type Receipt struct {
+	LineID           int      `json:"line_id"`
+	ReceivedQuantity *float64 `json:"received_quantity"`
+	Note             string   `json:"note"`
+}
+
+// Discrepancy is a received line whose quantity differs from what was sent
+type Discrepancy struct {
+	LineID     int     `json:"line_id"`
+	StockID    int     `json:"stock_id"`
+	Sent       float64 `json:"sent"`
+	Received   float64 `json:"received"`
+	Difference float64 `json:"difference"` // received minus sent
+	Note       string  `json:"note,omitempty"`
+}
+
+// Report lists the discrepancies of a transfer
+type Report struct {
+	TransferID    int           `json:"transfer_id"`
+	Status        string        `json:"status"`
+	Lines         int           `json:"lines"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+func timestamp() *string {
+	now := time.Now().UTC().Format(time.RFC3339)
+	return &now
+}
+
+// transition refuses a step the transfer's status does not allow
+func transition(t models.StockTransfer, from, to string) error {
+	if t.Status == from {
+		return nil
+	}
+	return &repository.RuleError{
+		Code:    "invalid_transition",
+		Message: fmt.Sprintf("A %s transfer cannot become %s", t.Status, to),
+		Details: map[string]interface{}{"transfer_id": t.TransferID, "status": t.Status, "required_status": from},
+	}
+}
+
+// stockItem loads one stock item
+func (s *Service) stockItem(ctx context.Context, id int) (models.StockItem, error) {
+	spec := query.Spec{Limit: 1}
+	spec.Where("stock_id", id)
+	page, err := s.stock.ListStockItems(ctx, spec)
+	if err != nil {
+		return models.StockItem{}, err
+	}
+	if len(page.Data) == 0 {
+		return models.StockItem{}, repository.ErrNotFound
+	}
+	return page.Data[0], nil
+}
+
+// check validates the warehouses and lines of a draft
+func (s *Service) check(ctx context.Context, t *models.StockTransfer) error {
+	var errs validate.Errors
+	if t.FromWarehouseID == t.ToWarehouseID {
+		errs = append(errs, validate.FieldError{Field: "to_warehouse_id", Code: "same_warehouse",
+			Message: "to_warehouse_id must differ from from_warehouse_id"})
+	}
+	if len(t.Lines) == 0 {
+		errs = append(errs, validate.FieldError{Field: "lines", Code: validate.CodeRequired,
+			Message: "lines must list at least one stock item"})
+	}
+	for i, line := range t.Lines {
+		field := fmt.Sprintf("lines[%d]", i)
+		if line.Quantity <= 0 {
+			errs = append(errs, validate.FieldError{Field: field + ".quantity", Code: validate.CodeTooSmall,
+				Message: field + ".quantity must be greater than 0"})
+		}
+		stock, err := s.stockItem(ctx, line.StockID)
+		switch {
+		case err == repository.ErrNotFound:
+			errs = append(errs, validate.FieldError{Field: field + ".stock_id", Code: "not_found",
+				Message: fmt.Sprintf("%s.stock_id refers to a stock item that does not exist", field)})
+		case err != nil:
+			return err
+		case stock.WarehouseID != t.FromWarehouseID:
+			errs = append(errs, validate.FieldError{Field: field + ".stock_id", Code: "wrong_warehouse",
+				Message: fmt.Sprintf("%s.stock_id is not held in warehouse %d", field, t.FromWarehouseID)})
+		case repository.CheckStockAvailable(stock) != nil:
+			errs = append(errs, validate.FieldError{Field: field + ".stock_id", Code: "stock_unavailable",
+				Message: fmt.Sprintf("%s.stock_id is %s and cannot be transferred", field, stock.Status)})
+		}
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Create stores t as a draft with its lines
+func (s *Service) Create(ctx context.Context, t *models.StockTransfer) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.check(ctx, t); err != nil {
+			return err
+		}
+		t.Status = StatusDraft
+		t.DispatchedAt, t.InTransitAt, t.ReceivedAt = nil, nil, nil
+		return s.transfers.CreateTransfer(ctx, t)
+	})
+}
+
+// Update replaces the header and lines of a draft
+func (s *Service) Update(ctx context.Context, id int, t *models.StockTransfer) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		old, err := s.transfers.GetTransfer(ctx, id)
+		if err != nil {
+			return err
+		}
+		if old.Status != StatusDraft {
+			return &repository.RuleError{Code: "not_editable", Message: "Only a draft transfer can be changed"}
+		}
+		if err := s.check(ctx, t); err != nil {
+			return err
+		}
+		t.TransferID, t.Status, t.CreatedAt = id, StatusDraft, old.CreatedAt
+		if err := s.transfers.UpdateTransfer(ctx, id, t); err != nil {
+			return err
+		}
+		return s.transfers.ReplaceTransferLines(ctx, id, t.Lines)
+	})
+}
+
+// Delete removes a draft
+func (s *Service) Delete(ctx context.Context, id int) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		t, err := s.transfers.GetTransfer(ctx, id)
+		if err != nil {
+			return err
+		}
+		if t.Status != StatusDraft {
+			return &repository.RuleError{Code: "not_editable", Message: "Only a draft transfer can be deleted"}
+		}
+		return s.transfers.DeleteTransfer(ctx, id)
+	})
+}
+
+// Dispatch takes every line out of the source warehouse. The lines' lots
+// are locked first, and one put in quarantine or rejected since the draft
+// was saved refuses the dispatch.
+func (s *Service) Dispatch(ctx context.Context, id int, employeeID *int) (models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if t, err = s.transfers.GetTransfer(ctx, id); err != nil {
+			return err
+		}
+		if err := transition(t, StatusDraft, StatusDispatched); err != nil {
+			return err
+		}
+		ids := make([]interface{}, len(t.Lines))
+		for i, line := range t.Lines {
+			ids[i] = line.StockID
+		}
+		var spec query.Spec
+		spec.WhereIn("stock_id", ids...)
+		lots, err := s.stock.LockStockItems(ctx, spec)
+		if err != nil {
+			return err
+		}
+		for _, lot := range lots {
+			if err := repository.CheckStockAvailable(lot); err != nil {
+				return err
+			}
+		}
+		for i := range t.Lines {
+			line := &t.Lines[i]
+			it := models.InventoryTransaction{
+				EmployeeID:      employeeID,
+				StockID:         line.StockID,
+				TransactionType: repository.TxTransferOut,
+				Quantity:        repository.StockMovement(repository.TxTransferOut, line.Quantity),
+				Remarks:         fmt.Sprintf("Transfer %d to warehouse %d", t.TransferID, t.ToWarehouseID),
+			}
+			if err := s.stock.CreateInventoryTransaction(ctx, &it); err != nil {
+				return err
+			}
+			line.OutTransactionID = &it.TransactionID
+			if err := s.transfers.UpdateTransferLine(ctx, line); err != nil {
+				return err
+			}
+		}
+		t.Status, t.DispatchedAt = StatusDispatched, timestamp()
+		return s.transfers.UpdateTransfer(ctx, id, &t)
+	})
+	return t, err
+}
+
+// Transit marks a dispatched transfer as on its way, optionally on a
+// shipment or truck
+func (s *Service) Transit(ctx context.Context, id int, shipmentID, truckID *int) (models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if t, err = s.transfers.GetTransfer(ctx, id); err != nil {
+			return err
+		}
+		if err := transition(t, StatusDispatched, StatusInTransit); err != nil {
+			return err
+		}
+		if shipmentID != nil {
+			t.ShipmentID = shipmentID
+		}
+		if truckID != nil {
+			t.TruckID = truckID
+		}
+		t.Status, t.InTransitAt = StatusInTransit, timestamp()
+		return s.transfers.UpdateTransfer(ctx, id, &t)
+	})
+	return t, err
+}
+
+// Receive books what arrived into the destination warehouse. Lines without
+// a receipt arrived in full; a line received short or over keeps the
+// difference as a discrepancy rather than a ledger entry.
+func (s *Service) Receive(ctx context.Context, id int, receipts []Receipt, employeeID *int) (models.StockTransfer, error) {
+	var t models.StockTransfer
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if t, err = s.transfers.GetTransfer(ctx, id); err != nil {
+			return err
+		}
+		if err := transition(t, StatusInTransit, StatusReceived); err != nil {
+			return err
+		}
+
+		byLine := map[int]Receipt{}
+		var errs validate.Errors
+		for i, receipt := range receipts {
+			field := fmt.Sprintf("lines[%d]", i)
+			switch {
+			case !hasLine(t, receipt.LineID):
+				errs = append(errs, validate.FieldError{Field: field + ".line_id", Code: "not_found",
+					Message: fmt.Sprintf("%s.line_id is not a line of transfer %d", field, id)})
+			case receipt.ReceivedQuantity != nil && *receipt.ReceivedQuantity < 0:
+				errs = append(errs, validate.FieldError{Field: field + ".received_quantity", Code: validate.CodeTooSmall,
+					Message: field + ".received_quantity must be at least 0"})
+			}
+			byLine[receipt.LineID] = receipt
+		}
+		if errs != nil {
+			return errs
+		}
+
+		for i := range t.Lines {
+			line := &t.Lines[i]
+			received := line.Quantity
+			if receipt, ok := byLine[line.LineID]; ok {
+				if receipt.ReceivedQuantity != nil {
+					received = math.Round(*receipt.ReceivedQuantity*100) / 100
+				}
+				line.DiscrepancyNote = receipt.Note
+			}
+			line.ReceivedQuantity = &received
+
+			if received > 0 {
+				dest, err := s.destination(ctx, t, line.StockID, employeeID)
+				if err != nil {
+					return err
+				}
+				it := models.InventoryTransaction{
+					EmployeeID:      employeeID,
+					StockID:         dest.StockID,
+					TransactionType: repository.TxTransferIn,
+					Quantity:        received,
+					Remarks:         fmt.Sprintf("Transfer %d from warehouse %d", t.TransferID, t.FromWarehouseID),
+				}
+				if err := s.stock.CreateInventoryTransaction(ctx, &it); err != nil {
+					return err
+				}
+				line.DestStockID, line.InTransactionID = &dest.StockID, &it.TransactionID
+			}
+			if err := s.transfers.UpdateTransferLine(ctx, line); err != nil {
+				return err
+			}
+		}
+		t.Status, t.ReceivedAt = StatusReceived, timestamp()
+		return s.transfers.UpdateTransfer(ctx, id, &t)
+	})
+	return t, err
+}
+
+func hasLine(t models.StockTransfer, lineID int) bool {
+	for _, line := range t.Lines {
+		if line.LineID == lineID {
+			return true
+		}
+	}
+	return false
+}
+
+// destination finds the stock item of the destination warehouse holding the
+// same product and lot as source stock item sourceID, in the same status,
+// creating one when there is none, so lots keep their lineage across
+// warehouses. A new lot takes the source's receipt, grade and, when it was
+// held since dispatch, its quarantine or rejection.
+func (s *Service) destination(ctx context.Context, t models.StockTransfer, sourceID int, employeeID *int) (models.StockItem, error) {
+	source, err := s.stockItem(ctx, sourceID)
+	if err != nil {
+		return source, err
+	}
+	status := repository.StockAvailable
+	if repository.CheckStockAvailable(source) != nil {
+		status = source.Status
+	}
+	spec := query.Spec{}
+	spec.Where("warehouse_id", t.ToWarehouseID)
+	spec.Where("product_type_id", source.ProductTypeID)
+	lots, err := s.stock.LockStockItems(ctx, spec)
+	if err != nil {
+		return source, err
+	}
+	for _, si := range lots {
+		if sameLot(si.BatchID, source.BatchID) && sameLot(si.ProcessingID, source.ProcessingID) &&
+			sameLot(si.POItemID, source.POItemID) && (si.Status == status || si.Status == "" && status == repository.StockAvailable) {
+			return si, nil
+		}
+	}
+
+	dest := models.StockItem{
+		ProductTypeID: source.ProductTypeID,
+		WarehouseID:   t.ToWarehouseID,
+		BatchID:       source.BatchID,
+		ProcessingID:  source.ProcessingID,
+		POItemID:      source.POItemID,
+	}
+	if err := s.stock.CreateStockItem(ctx, &dest); err != nil {
+		return dest, err
+	}
+	if source.Grade != "" && source.GradedBy != nil {
+		if err := s.stock.GradeStockItem(ctx, dest.StockID, source.Grade, *source.GradedBy); err != nil {
+			return dest, err
+		}
+		dest.Grade, dest.GradedBy = source.Grade, source.GradedBy
+	}
+	if status != repository.StockAvailable {
+		sd := models.StockDisposition{
+			StockID:    dest.StockID,
+			Action:     quarantine.ActionQuarantine,
+			FromStatus: dest.Status,
+			ToStatus:   status,
+			EmployeeID: employeeID,
+			Reason:     fmt.Sprintf("Transfer %d of stock item %d, which is %s", t.TransferID, source.StockID, status),
+		}
+		if err := s.stock.CreateStockDisposition(ctx, &sd); err != nil {
+			return dest, err
+		}
+		dest.Status = status
+	}
+	return dest, nil
+}
+
+func sameLot(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// Discrepancies reports the lines of a received transfer that arrived short
+// or over
+func (s *Service) Discrepancies(ctx context.Context, id int) (Report, error) {
+	t, err := s.transfers.GetTransfer(ctx, id)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{TransferID: id, Status: t.Status, Lines: len(t.Lines), Discrepancies: []Discrepancy{}}
+	for _, line := range t.Lines {
+		if line.ReceivedQuantity == nil || *line.ReceivedQuantity == line.Quantity {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			LineID:     line.LineID,
+			StockID:    line.StockID,
+			Sent:       line.Quantity,
+			Received:   *line.ReceivedQuantity,
+			Difference: math.Round((*line.ReceivedQuantity-line.Quantity)*100) / 100,
+			Note:       line.DiscrepancyNote,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,178 @@
+package transfers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// newYards stores two warehouses and, in the first, lots 1 and 2 of 10 and
+// 5 pine boards
+func newYards(t *testing.T) (repository.Repositories, *Service) {
+	t.Helper()
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	for _, name := range []string{"North", "South"} {
+		if err := repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Pine boards", UnitOfMeasure: "m3"}); err != nil {
+		t.Fatal(err)
+	}
+	batch := 1
+	for _, si := range []models.StockItem{
+		{ProductTypeID: 1, WarehouseID: 1, BatchID: &batch, Quantity: 10},
+		{ProductTypeID: 1, WarehouseID: 1, Quantity: 5},
+	} {
+		if err := repos.Stock.CreateStockItem(ctx, &si); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repos, NewService(repos)
+}
+
+// code is the rule a step broke, or "" when it did not break one
+func code(err error) string {
+	var rule *repository.RuleError
+	if errors.As(err, &rule) {
+		return rule.Code
+	}
+	return ""
+}
+
+// fields lists the fields of a validation error
+func fields(err error) []string {
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	var names []string
+	for _, e := range errs {
+		names = append(names, e.Field+" "+e.Code)
+	}
+	return names
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	_, s := newYards(t)
+	err := s.Create(ctx, &models.StockTransfer{FromWarehouseID: 2, ToWarehouseID: 2, Lines: []models.StockTransferLine{
+		{StockID: 1, Quantity: 1}, {StockID: 9, Quantity: 0},
+	}})
+	want := []string{"to_warehouse_id same_warehouse", "lines[0].stock_id wrong_warehouse",
+		"lines[1].quantity too_small", "lines[1].stock_id not_found"}
+	if got := fields(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+	if got := fields(s.Create(ctx, &models.StockTransfer{FromWarehouseID: 1, ToWarehouseID: 2})); !reflect.DeepEqual(got, []string{"lines required"}) {
+		t.Errorf("errors without lines = %q", got)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repos, s := newYards(t)
+	quantity := func(id int) float64 {
+		t.Helper()
+		lot, err := s.stockItem(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lot.Quantity
+	}
+
+	tr := models.StockTransfer{FromWarehouseID: 1, ToWarehouseID: 2, Lines: []models.StockTransferLine{{StockID: 1, Quantity: 4}}}
+	if err := s.Create(ctx, &tr); err != nil || tr.Status != StatusDraft {
+		t.Fatalf("create = %+v, %v", tr, err)
+	}
+	if _, err := s.Transit(ctx, tr.TransferID, nil, nil); code(err) != "invalid_transition" {
+		t.Errorf("transit of a draft: %v", err)
+	}
+	if _, err := s.Receive(ctx, tr.TransferID, nil, nil); code(err) != "invalid_transition" {
+		t.Errorf("receipt of a draft: %v", err)
+	}
+	tr.Lines = append(tr.Lines, models.StockTransferLine{StockID: 2, Quantity: 5})
+	if err := s.Update(ctx, tr.TransferID, &tr); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatched, err := s.Dispatch(ctx, tr.TransferID, nil)
+	if err != nil || dispatched.Status != StatusDispatched || dispatched.DispatchedAt == nil ||
+		dispatched.Lines[1].OutTransactionID == nil {
+		t.Fatalf("dispatch = %+v, %v", dispatched, err)
+	}
+	if q1, q2 := quantity(1), quantity(2); q1 != 6 || q2 != 0 {
+		t.Errorf("source lots after dispatch = %g, %g", q1, q2)
+	}
+	if _, err := s.Dispatch(ctx, tr.TransferID, nil); code(err) != "invalid_transition" {
+		t.Errorf("second dispatch: %v", err)
+	}
+	if err := s.Update(ctx, tr.TransferID, &tr); code(err) != "not_editable" {
+		t.Errorf("update of a dispatched transfer: %v", err)
+	}
+	if err := s.Delete(ctx, tr.TransferID); code(err) != "not_editable" {
+		t.Errorf("deletion of a dispatched transfer: %v", err)
+	}
+
+	truck := 7
+	moving, err := s.Transit(ctx, tr.TransferID, nil, &truck)
+	if err != nil || moving.Status != StatusInTransit || moving.TruckID == nil || *moving.TruckID != 7 {
+		t.Fatalf("transit = %+v, %v", moving, err)
+	}
+
+	// The first line arrives short and the second, without a receipt, in full
+	short := 3.5
+	received, err := s.Receive(ctx, tr.TransferID, []Receipt{{LineID: dispatched.Lines[0].LineID, ReceivedQuantity: &short, Note: "wet"}}, nil)
+	if err != nil || received.Status != StatusReceived || received.ReceivedAt == nil {
+		t.Fatalf("receive = %+v, %v", received, err)
+	}
+	var spec query.Spec
+	spec.Where("warehouse_id", 2)
+	arrived, _ := repos.Stock.ListStockItems(ctx, spec)
+	if len(arrived.Data) != 2 || arrived.Data[0].Quantity != 3.5 || *arrived.Data[0].BatchID != 1 || arrived.Data[1].Quantity != 5 {
+		t.Errorf("destination lots = %+v", arrived.Data)
+	}
+	if _, err := s.Receive(ctx, tr.TransferID, nil, nil); code(err) != "invalid_transition" {
+		t.Errorf("second receipt: %v", err)
+	}
+
+	report, err := s.Discrepancies(ctx, tr.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Discrepancy{{LineID: dispatched.Lines[0].LineID, StockID: 1, Sent: 4, Received: 3.5, Difference: -0.5, Note: "wet"}}
+	if report.Status != StatusReceived || report.Lines != 2 || !reflect.DeepEqual(report.Discrepancies, want) {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestReceiveInvalid(t *testing.T) {
+	ctx := context.Background()
+	_, s := newYards(t)
+	tr := models.StockTransfer{FromWarehouseID: 1, ToWarehouseID: 2, Lines: []models.StockTransferLine{{StockID: 1, Quantity: 4}}}
+	if err := s.Create(ctx, &tr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Dispatch(ctx, tr.TransferID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Transit(ctx, tr.TransferID, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	lost := -1.0
+	_, err := s.Receive(ctx, tr.TransferID, []Receipt{{LineID: 99}, {LineID: tr.Lines[0].LineID, ReceivedQuantity: &lost}}, nil)
+	want := []string{"lines[0].line_id not_found", "lines[1].received_quantity too_small"}
+	if got := fields(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+	if report, err := s.Discrepancies(ctx, tr.TransferID); err != nil || report.Status != StatusInTransit || len(report.Discrepancies) != 0 {
+		t.Errorf("report before receipt = %+v, %v", report, err)
+	}
+}
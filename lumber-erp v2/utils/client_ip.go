@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies configures the proxies whose X-Forwarded-For header is
+// believed. Entries are CIDR ranges or single addresses; invalid entries are
+// rejected by config validation and skipped here.
+func SetTrustedProxies(proxies []string) {
+	trustedProxies = nil
+	for _, proxy := range proxies {
+		if network := ParseProxy(proxy); network != nil {
+			trustedProxies = append(trustedProxies, network)
+		}
+	}
+}
+
+// ParseProxy parses a trusted proxy entry such as 10.0.0.0/8 or 192.0.2.7,
+// returning nil when it is neither
+func ParseProxy(proxy string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(proxy); err == nil {
+		return network
+	}
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil
+	}
+	bits := 8 * len(ip.To4())
+	if bits == 0 {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+func trusted(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent r. X-Forwarded-For
+// is only read when the connection comes from a trusted proxy, and then from
+// the right, so a client cannot spoof its address by sending the header.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !trusted(ip) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			break
+		}
+		host = hop
+		if !trusted(ip) {
+			break
+		}
+	}
+	return host
+}
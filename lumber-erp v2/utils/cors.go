@@ -0,0 +1,30 @@
+package utils
+
+import "net/http"
+
+var allowedOrigins = map[string]bool{"*": true}
+
+// SetAllowedOrigins configures which browser origins may call the API.
+// A "*" entry allows any origin.
+func SetAllowedOrigins(origins []string) {
+	allowedOrigins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowedOrigins[origin] = true
+	}
+}
+
+// WithCORS sets Access-Control-Allow-Origin for allowed origins before the
+// request reaches the route handlers
+func WithCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		switch {
+		case allowedOrigins["*"]:
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowedOrigins[origin]:
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
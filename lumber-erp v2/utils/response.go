@@ -5,8 +5,12 @@ import (
 	"net/http"
 )
 
+// RequestIDHeader carries the ID that ties a response to the server logs
+const RequestIDHeader = "X-Request-ID"
+
+// EnableCORS sets the allowed methods and headers; the allowed origin is
+// decided once per request by WithCORS
 func EnableCORS(w *http.ResponseWriter) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
 	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 	(*w).Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 }
@@ -17,10 +21,55 @@ func RespondJSON(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
+// ErrorBody is the envelope of every error response. Deprecated legacy
+// routes also repeat the message under "error", which the React frontend
+// reads.
+type ErrorBody struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details"`
+	RequestID string      `json:"request_id"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// statusCodes are the default error codes of each status
+var statusCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusMethodNotAllowed:    "method_not_allowed",
+	http.StatusConflict:            "conflict",
+	http.StatusUnprocessableEntity: "unprocessable",
+	http.StatusInternalServerError: "internal",
+	http.StatusServiceUnavailable:  "unavailable",
+}
+
+// RespondError answers with the error envelope and the default code of
+// the status
 func RespondError(w http.ResponseWriter, status int, message string) {
-	RespondJSON(w, status, map[string]string{"error": message})
+	code, ok := statusCodes[status]
+	if !ok {
+		code = "error"
+	}
+	RespondErrorCode(w, status, code, message, nil)
+}
+
+// RespondErrorCode answers with the error envelope. The request ID is the
+// one the RequestID middleware put on the response.
+func RespondErrorCode(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	body := ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+	if w.Header().Get("Deprecation") == "true" {
+		body.Error = message
+	}
+	RespondJSON(w, status, body)
 }
 
 func RespondSuccess(w http.ResponseWriter, message string) {
 	RespondJSON(w, http.StatusOK, map[string]string{"message": message})
-}
\ No newline at end of file
+}
@@ -0,0 +1,202 @@
+// Package validate checks request bodies against the rules declared in
+// their `validate` struct tags:
+//
+//	Rating    float64 `json:"rating" validate:"min=0,max=9.99"`
+//	EndDate   string  `json:"end_date" validate:"date,after=start_date"`
+//	Status    string  `json:"status" validate:"oneof=active|expired"`
+//
+// Rules are separated by commas and fields are reported by their JSON name.
+// Apart from required, rules skip empty values so optional columns may be
+// left out. min and max bound the length of a string field. The fields of
+// an embedded struct are checked as the outer struct's own.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Error codes reported in FieldError.Code
+const (
+	CodeRequired      = "required"
+	CodeTooSmall      = "too_small"
+	CodeTooLarge      = "too_large"
+	CodeInvalidChoice = "invalid_choice"
+	CodeInvalidEmail  = "invalid_email"
+	CodeInvalidDate   = "invalid_date"
+	CodeDateOrder     = "date_order"
+	CodeInvalidType   = "invalid_type"
+)
+
+// FieldError describes one invalid field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors lists every invalid field; it is nil when the value is valid
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *Errors) add(field, code, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Struct checks the tagged fields of v, a struct or pointer to one. It
+// panics on a malformed tag, which is a programming error.
+func Struct(v interface{}) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	var errs Errors
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get("validate")
+		if tag == "" {
+			if rt.Field(i).Anonymous && rt.Field(i).Type.Kind() == reflect.Struct {
+				errs = append(errs, Struct(rv.Field(i).Interface())...)
+			}
+			continue
+		}
+		name := jsonName(rt.Field(i))
+		value := rv.Field(i)
+		for _, rule := range strings.Split(tag, ",") {
+			key, arg, _ := strings.Cut(rule, "=")
+			if !check(&errs, rv, name, value, key, arg) {
+				break // report one problem per field
+			}
+		}
+	}
+	return errs
+}
+
+// check applies one rule and reports whether the field passed it
+func check(errs *Errors, parent reflect.Value, name string, value reflect.Value, rule, arg string) bool {
+	before := len(*errs)
+	if rule == "required" {
+		if isEmpty(value) {
+			errs.add(name, CodeRequired, "%s is required", name)
+		}
+		return len(*errs) == before
+	}
+	if isEmpty(value) {
+		return true
+	}
+	value = reflect.Indirect(value)
+
+	switch rule {
+	case "min", "max":
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic("validate: bad bound in " + rule + "=" + arg)
+		}
+		var n float64
+		unit := ""
+		if value.Kind() == reflect.String {
+			n, unit = float64(utf8.RuneCountInString(value.String())), " characters long"
+		} else {
+			n = number(value)
+		}
+		if rule == "min" && n < bound {
+			errs.add(name, CodeTooSmall, "%s must be at least %s%s", name, arg, unit)
+		}
+		if rule == "max" && n > bound {
+			errs.add(name, CodeTooLarge, "%s must be at most %s%s", name, arg, unit)
+		}
+	case "oneof":
+		choices := strings.Split(arg, "|")
+		found := false
+		for _, choice := range choices {
+			found = found || Normalize(value.String()) == Normalize(choice)
+		}
+		if !found {
+			errs.add(name, CodeInvalidChoice, "%s must be one of %s", name, strings.Join(choices, ", "))
+		}
+	case "email":
+		if _, err := mail.ParseAddress(value.String()); err != nil {
+			errs.add(name, CodeInvalidEmail, "%s must be a valid email address", name)
+		}
+	case "date":
+		if _, ok := parseDate(value.String()); !ok {
+			errs.add(name, CodeInvalidDate, "%s must be a date such as 2006-01-02", name)
+		}
+	case "after":
+		other := fieldByJSONName(parent, arg)
+		start, okStart := parseDate(other.String())
+		end, okEnd := parseDate(value.String())
+		if okStart && okEnd && end.Before(start) {
+			errs.add(name, CodeDateOrder, "%s must not be before %s", name, arg)
+		}
+	default:
+		panic("validate: unknown rule " + rule)
+	}
+	return len(*errs) == before
+}
+
+// isEmpty reports whether a field holds its zero value; strings of only
+// whitespace count as empty
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	}
+	return value.IsZero()
+}
+
+func number(value reflect.Value) float64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	panic("validate: min and max need a numeric or string field, got " + value.Kind().String())
+}
+
+// Normalize lets enum values match regardless of case and of spaces,
+// hyphens or underscores between words, so the frontend's "In Transit"
+// and the reports' 'in_transit' are the same status
+func Normalize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
+}
+
+// parseDate accepts the DATE form and the RFC 3339 timestamps the API
+// returns for date columns
+func parseDate(s string) (time.Time, bool) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+func jsonName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+func fieldByJSONName(parent reflect.Value, name string) reflect.Value {
+	for i := 0; i < parent.NumField(); i++ {
+		if jsonName(parent.Type().Field(i)) == name {
+			return reflect.Indirect(parent.Field(i))
+		}
+	}
+	panic("validate: no field " + name)
+}
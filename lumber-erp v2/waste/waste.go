@@ -0,0 +1,435 @@
+// Package waste turns the waste processing orders leave into by-product
+// stock and reports on it. Valorising a waste record receives the biomass,
+// pellets or chips it made into a lot of the order; the report groups waste
+// by sawmill, species, month, waste type, disposal method or order with its
+// share of the input, recycling rate and disposal cost.
+package waste
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// Groupings the report can break waste down by
+const (
+	BySawmill         = "sawmill"
+	BySpecies         = "species"
+	ByMonth           = "month"
+	ByWasteType       = "waste_type"
+	ByDisposalMethod  = "disposal_method"
+	ByProcessingOrder = "processing_order"
+)
+
+// Groupings lists every grouping the report takes; waste type and disposal
+// method group the waste records, the others the orders
+var Groupings = []string{BySawmill, BySpecies, ByMonth, ByWasteType, ByDisposalMethod, ByProcessingOrder}
+
+// Service valorises waste records and builds the waste report
+type Service struct {
+	tx         repository.Transactor
+	plant      repository.ProcessingRepository
+	stock      repository.StockRepository
+	warehouses repository.WarehouseRepository
+	forests    repository.ForestRepository
+}
+
+func NewService(repos repository.Repositories) *Service {
+	return &Service{tx: repos.Tx, plant: repos.Processing, stock: repos.Stock, warehouses: repos.Warehouses,
+		forests: repos.Forests}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ==================== BY-PRODUCTS ====================
+
+// ByProduct is what valorising a waste record made
+type ByProduct struct {
+	ProductTypeID int `json:"product_type_id" validate:"required"`
+	WarehouseID   int `json:"warehouse_id" validate:"required"`
+	// Quantity is in the product type's unit; by default the waste's volume
+	Quantity   *float64 `json:"quantity"`
+	EmployeeID *int     `json:"employee_id"`
+}
+
+// Valorisation is a valorised waste record with the ledger entry that
+// received its by-product
+type Valorisation struct {
+	Waste       models.WasteRecord          `json:"waste"`
+	Transaction models.InventoryTransaction `json:"transaction"`
+}
+
+// exists reports whether a list holds the row a key names
+func exists[T any](ctx context.Context, list func(context.Context, query.Spec) (query.Page[T], error), field string, id int) (bool, error) {
+	spec := query.Spec{Limit: 1}
+	spec.Where(field, id)
+	page, err := list(ctx, spec)
+	return len(page.Data) > 0, err
+}
+
+// Valorise receives the by-product a waste record made into the lot of its
+// processing order holding that product in the warehouse, creating the lot
+// when there is none, and marks the waste recycled into it
+func (s *Service) Valorise(ctx context.Context, id int, bp ByProduct) (Valorisation, error) {
+	var v Valorisation
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		spec := query.Spec{Limit: 1}
+		spec.Where("waste_id", id)
+		page, err := s.plant.ListWasteRecords(ctx, spec)
+		if err != nil {
+			return err
+		}
+		if len(page.Data) == 0 {
+			return repository.ErrNotFound
+		}
+		wr := page.Data[0]
+		if wr.StockID != nil {
+			return &repository.RuleError{Code: "already_valorised", Message: "The waste has already been valorised",
+				Details: map[string]interface{}{"waste_id": id, "stock_id": *wr.StockID}}
+		}
+
+		quantity := wr.Volume
+		if bp.Quantity != nil {
+			quantity = *bp.Quantity
+		}
+		if quantity <= 0 {
+			return validate.Errors{{Field: "quantity", Code: validate.CodeTooSmall,
+				Message: "quantity must be more than 0"}}
+		}
+		if ok, err := exists(ctx, s.warehouses.ListProductTypes, "product_type_id", bp.ProductTypeID); err != nil {
+			return err
+		} else if !ok {
+			return &repository.ReferenceError{Field: "product_type_id", Table: "producttype"}
+		}
+		if ok, err := exists(ctx, s.warehouses.ListWarehouses, "warehouse_id", bp.WarehouseID); err != nil {
+			return err
+		} else if !ok {
+			return &repository.ReferenceError{Field: "warehouse_id", Table: "warehouse"}
+		}
+
+		lots := query.Spec{Limit: 1}
+		lots.Where("processing_id", wr.ProcessingID)
+		lots.Where("warehouse_id", bp.WarehouseID)
+		lots.Where("product_type_id", bp.ProductTypeID)
+		found, err := s.stock.ListStockItems(ctx, lots)
+		if err != nil {
+			return err
+		}
+		var lot models.StockItem
+		if len(found.Data) > 0 {
+			lot = found.Data[0]
+		} else {
+			processingID := wr.ProcessingID
+			lot = models.StockItem{ProductTypeID: bp.ProductTypeID, WarehouseID: bp.WarehouseID, ProcessingID: &processingID}
+			if err := s.stock.CreateStockItem(ctx, &lot); err != nil {
+				return err
+			}
+		}
+		v.Transaction = models.InventoryTransaction{
+			EmployeeID:      bp.EmployeeID,
+			StockID:         lot.StockID,
+			TransactionType: repository.TxReceipt,
+			Quantity:        round(quantity),
+			Remarks:         fmt.Sprintf("By-product of waste record %d", id),
+		}
+		if err := s.stock.CreateInventoryTransaction(ctx, &v.Transaction); err != nil {
+			return err
+		}
+		if err := s.plant.ValoriseWasteRecord(ctx, id, lot.StockID); err != nil {
+			return err
+		}
+		wr.Recycled, wr.StockID = true, &lot.StockID
+		v.Waste = wr
+		return nil
+	})
+	return v, err
+}
+
+// ==================== ANALYTICS ====================
+
+// Group is the waste of one sawmill, species, month, waste type, disposal
+// method or order. InputVolume is what the group's orders consumed; waste
+// type and disposal method groups share the input of every order in the
+// period. WastePercent and RecyclingRate are nil without input or waste.
+type Group struct {
+	Key               string   `json:"key"`
+	Name              string   `json:"name"`
+	Orders            int      `json:"orders"`
+	Records           int      `json:"records"`
+	InputVolume       float64  `json:"input_volume"`
+	OutputVolume      float64  `json:"output_volume"`
+	WasteVolume       float64  `json:"waste_volume"`
+	WastePercent      *float64 `json:"waste_percent"`
+	RecycledVolume    float64  `json:"recycled_volume"`
+	ValorisedVolume   float64  `json:"valorised_volume"`
+	RecyclingRate     *float64 `json:"recycling_rate"`
+	DisposalCost      float64  `json:"disposal_cost"`
+	AvgWastePerRecord float64  `json:"avg_waste_per_record"`
+}
+
+// Report is the waste of the orders dated within a period, in total and
+// grouped. From and To are empty when the period is open.
+type Report struct {
+	GroupBy string  `json:"group_by"`
+	From    string  `json:"from,omitempty"`
+	To      string  `json:"to,omitempty"`
+	Total   Group   `json:"total"`
+	Groups  []Group `json:"groups"`
+}
+
+// share is the part of an order that falls in a group: all of it, or for
+// species the part of its input that came from batches of the species
+type share struct {
+	key, name string
+	weight    float64
+}
+
+// add counts an order's volumes and waste into a group
+func (g *Group) add(po models.ProcessingOrder, input, weight float64, waste []models.WasteRecord) {
+	g.Orders++
+	g.InputVolume += input * weight
+	g.OutputVolume += po.OutputQuantity * weight
+	for _, wr := range waste {
+		g.addWaste(wr, weight)
+	}
+}
+
+func (g *Group) addWaste(wr models.WasteRecord, weight float64) {
+	g.Records++
+	g.WasteVolume += wr.Volume * weight
+	if wr.Recycled {
+		g.RecycledVolume += wr.Volume * weight
+	}
+	if wr.StockID != nil {
+		g.ValorisedVolume += wr.Volume * weight
+	}
+	g.DisposalCost += wr.DisposalCost * weight
+}
+
+// finish rounds the sums and derives the rates
+func (g *Group) finish() {
+	g.InputVolume, g.OutputVolume, g.WasteVolume = round(g.InputVolume), round(g.OutputVolume), round(g.WasteVolume)
+	g.RecycledVolume, g.ValorisedVolume, g.DisposalCost = round(g.RecycledVolume), round(g.ValorisedVolume), round(g.DisposalCost)
+	g.WastePercent, g.RecyclingRate, g.AvgWastePerRecord = nil, nil, 0
+	if g.InputVolume > 0 {
+		percent := round(g.WasteVolume / g.InputVolume * 100)
+		g.WastePercent = &percent
+	}
+	if g.WasteVolume > 0 {
+		rate := round(g.RecycledVolume / g.WasteVolume * 100)
+		g.RecyclingRate = &rate
+	}
+	if g.Records > 0 {
+		g.AvgWastePerRecord = round(g.WasteVolume / float64(g.Records))
+	}
+}
+
+// dated is the day an order counts on: its start, or its end when it has
+// no start
+func dated(po models.ProcessingOrder) (time.Time, bool) {
+	date := po.StartDate
+	if date == "" {
+		date = po.EndDate
+	}
+	if len(date) > 10 {
+		date = date[:10]
+	}
+	t, err := time.Parse("2006-01-02", date)
+	return t, err == nil
+}
+
+// Analyse reports the waste of the orders dated in [from, to), grouped by
+// groupBy; a zero from or to leaves that end of the period open
+func (s *Service) Analyse(ctx context.Context, groupBy string, from, to time.Time) (Report, error) {
+	report := Report{GroupBy: groupBy, Total: Group{Key: "total", Name: "Total"}, Groups: []Group{}}
+	if !from.IsZero() {
+		report.From = from.Format("2006-01-02")
+	}
+	if !to.IsZero() {
+		report.To = to.Format("2006-01-02")
+	}
+
+	orders, err := s.plant.ListProcessingOrders(ctx, query.Spec{})
+	if err != nil {
+		return report, err
+	}
+	records, err := s.plant.ListWasteRecords(ctx, query.Spec{})
+	if err != nil {
+		return report, err
+	}
+	links, err := s.plant.ListHarvestBatchProcessing(ctx, query.Spec{})
+	if err != nil {
+		return report, err
+	}
+	wasteOf := map[int][]models.WasteRecord{}
+	for _, wr := range records.Data {
+		wasteOf[wr.ProcessingID] = append(wasteOf[wr.ProcessingID], wr)
+	}
+	inputsOf := map[int][]models.HarvestBatchProcessing{}
+	for _, link := range links.Data {
+		inputsOf[link.ProcessingID] = append(inputsOf[link.ProcessingID], link)
+	}
+	shares, err := s.grouping(ctx, groupBy)
+	if err != nil {
+		return report, err
+	}
+
+	groups := map[string]*Group{}
+	group := func(key, name string) *Group {
+		if g, ok := groups[key]; ok {
+			return g
+		}
+		g := &Group{Key: key, Name: name}
+		groups[key] = g
+		return g
+	}
+	var inPeriod []models.ProcessingOrder
+	for _, po := range orders.Data {
+		day, ok := dated(po)
+		if (!from.IsZero() || !to.IsZero()) && (!ok || (!from.IsZero() && day.Before(from)) || (!to.IsZero() && !day.Before(to))) {
+			continue
+		}
+		inPeriod = append(inPeriod, po)
+		var input float64
+		for _, link := range inputsOf[po.ProcessingID] {
+			input += link.ConsumedQuantity
+		}
+		report.Total.add(po, input, 1, wasteOf[po.ProcessingID])
+
+		switch groupBy {
+		case ByWasteType, ByDisposalMethod:
+			for _, wr := range wasteOf[po.ProcessingID] {
+				value := wr.WasteType
+				if groupBy == ByDisposalMethod {
+					value = wr.DisposalMethod
+				}
+				key := validate.Normalize(value)
+				group(key, value).addWaste(wr, 1)
+			}
+		default:
+			for _, sh := range shares(po, inputsOf[po.ProcessingID], input) {
+				group(sh.key, sh.name).add(po, input, sh.weight, wasteOf[po.ProcessingID])
+			}
+		}
+	}
+
+	for _, g := range groups {
+		if groupBy == ByWasteType || groupBy == ByDisposalMethod {
+			g.InputVolume = report.Total.InputVolume
+		}
+		g.finish()
+		report.Groups = append(report.Groups, *g)
+	}
+	report.Total.finish()
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if groupBy == ByMonth || a.WasteVolume == b.WasteVolume {
+			return a.Key < b.Key
+		}
+		return a.WasteVolume > b.WasteVolume
+	})
+	return report, nil
+}
+
+// grouping loads what a grouping needs and answers how an order splits
+// into its groups. Orders without a sawmill, date or species fall in the
+// group keyed "unknown".
+func (s *Service) grouping(ctx context.Context, groupBy string) (func(models.ProcessingOrder, []models.HarvestBatchProcessing, float64) []share, error) {
+	unknown := []share{{key: "unknown", name: "Unknown", weight: 1}}
+	switch groupBy {
+	case ByWasteType, ByDisposalMethod:
+		// These group the waste records rather than the orders
+		return nil, nil
+
+	case ByProcessingOrder:
+		return func(po models.ProcessingOrder, _ []models.HarvestBatchProcessing, _ float64) []share {
+			return []share{{key: fmt.Sprint(po.ProcessingID), name: fmt.Sprintf("Processing order %d", po.ProcessingID), weight: 1}}
+		}, nil
+
+	case ByMonth:
+		return func(po models.ProcessingOrder, _ []models.HarvestBatchProcessing, _ float64) []share {
+			day, ok := dated(po)
+			if !ok {
+				return unknown
+			}
+			return []share{{key: day.Format("2006-01"), name: day.Format("January 2006"), weight: 1}}
+		}, nil
+
+	case BySawmill:
+		units, err := s.plant.ListProcessingUnits(ctx, query.Spec{})
+		if err != nil {
+			return nil, err
+		}
+		mills, err := s.plant.ListSawmills(ctx, query.Spec{})
+		if err != nil {
+			return nil, err
+		}
+		names := map[int]string{}
+		for _, sm := range mills.Data {
+			names[sm.SawmillID] = sm.Name
+		}
+		millOf := map[int]int{}
+		for _, u := range units.Data {
+			millOf[u.UnitID] = u.SawmillID
+		}
+		return func(po models.ProcessingOrder, _ []models.HarvestBatchProcessing, _ float64) []share {
+			id, ok := millOf[po.UnitID]
+			if !ok {
+				return unknown
+			}
+			return []share{{key: fmt.Sprint(id), name: names[id], weight: 1}}
+		}, nil
+
+	case BySpecies:
+		batches, err := s.forests.ListHarvestBatches(ctx, query.Spec{})
+		if err != nil {
+			return nil, err
+		}
+		species, err := s.forests.ListTreeSpecies(ctx, query.Spec{})
+		if err != nil {
+			return nil, err
+		}
+		names := map[int]string{}
+		for _, sp := range species.Data {
+			names[sp.SpeciesID] = sp.SpeciesName
+		}
+		speciesOf := map[int]int{}
+		for _, b := range batches.Data {
+			speciesOf[b.BatchID] = b.SpeciesID
+		}
+		// An order's waste is split between species by what it consumed
+		return func(_ models.ProcessingOrder, inputs []models.HarvestBatchProcessing, input float64) []share {
+			if input <= 0 {
+				return unknown
+			}
+			weights := map[int]float64{}
+			var order []int
+			for _, link := range inputs {
+				id := speciesOf[link.BatchID]
+				if _, seen := weights[id]; !seen {
+					order = append(order, id)
+				}
+				weights[id] += link.ConsumedQuantity / input
+			}
+			var shares []share
+			for _, id := range order {
+				if name, ok := names[id]; ok {
+					shares = append(shares, share{key: fmt.Sprint(id), name: name, weight: weights[id]})
+				} else {
+					shares = append(shares, share{key: "unknown", name: "Unknown", weight: weights[id]})
+				}
+			}
+			return shares
+		}, nil
+	}
+	return nil, fmt.Errorf("waste: unknown grouping %q", groupBy)
+}
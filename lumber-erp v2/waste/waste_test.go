@@ -0,0 +1,213 @@
+package waste
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"lumber-erp-api/models"
+	"lumber-erp-api/query"
+	"lumber-erp-api/repository"
+	"lumber-erp-api/validate"
+)
+
+// newMill stores a sawmill with one unit and three orders: the first, in
+// January on the unit, sawed 75 of pine and 25 of oak into 60 and left 20
+// of recycled sawdust and 10 of bark; the second, ending in February on no
+// unit, sawed 50 of pine into 40 and left 5 of sawdust; the third, in
+// December, sawed nothing
+func newMill(t *testing.T) (repository.Repositories, *Service) {
+	t.Helper()
+	ctx := context.Background()
+	repos := repository.NewMemory()
+	if err := repos.Processing.CreateSawmill(ctx, &models.Sawmill{Name: "Main", Status: "operational"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Processing.CreateProcessingUnit(ctx, &models.ProcessingUnit{SawmillID: 1, Capacity: 10, Status: "active"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Pine", "Oak"} {
+		if err := repos.Forests.CreateTreeSpecies(ctx, &models.TreeSpecies{SpeciesName: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, hb := range []models.HarvestBatch{{SpeciesID: 1, Quantity: 200}, {SpeciesID: 2, Quantity: 100}} {
+		if err := repos.Forests.CreateHarvestBatch(ctx, &hb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, po := range []models.ProcessingOrder{
+		{UnitID: 1, StartDate: "2026-01-10", OutputQuantity: 60},
+		{EndDate: "2026-02-03T16:00:00Z", OutputQuantity: 40},
+		{UnitID: 1, StartDate: "2025-12-01"},
+	} {
+		if err := repos.Processing.CreateProcessingOrder(ctx, &po); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, link := range []models.HarvestBatchProcessing{
+		{ProcessingID: 1, BatchID: 1, ConsumedQuantity: 75},
+		{ProcessingID: 1, BatchID: 2, ConsumedQuantity: 25},
+		{ProcessingID: 2, BatchID: 1, ConsumedQuantity: 50},
+	} {
+		if err := repos.Processing.AttachHarvestBatch(ctx, &link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, wr := range []models.WasteRecord{
+		{ProcessingID: 1, WasteType: "Sawdust", Volume: 20, DisposalMethod: "Pellets", Recycled: true, DisposalCost: 10},
+		{ProcessingID: 1, WasteType: "Bark", Volume: 10, DisposalMethod: "Landfill", DisposalCost: 30},
+		{ProcessingID: 2, WasteType: "sawdust", Volume: 5, DisposalMethod: "Pellets", Recycled: true},
+	} {
+		if err := repos.Processing.CreateWasteRecord(ctx, &wr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repos, NewService(repos)
+}
+
+// line sums up a group for comparison
+func line(g Group) string {
+	rate := func(v *float64) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	}
+	return fmt.Sprintf("%s %s: %d orders %d records in %g out %g waste %g (%s%%) recycled %g (%s%%) valorised %g cost %g avg %g",
+		g.Key, g.Name, g.Orders, g.Records, g.InputVolume, g.OutputVolume, g.WasteVolume, rate(g.WastePercent),
+		g.RecycledVolume, rate(g.RecyclingRate), g.ValorisedVolume, g.DisposalCost, g.AvgWastePerRecord)
+}
+
+func TestAnalyse(t *testing.T) {
+	ctx := context.Background()
+	_, s := newMill(t)
+	from, to := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for groupBy, want := range map[string][]string{
+		BySawmill: {
+			"1 Main: 1 orders 2 records in 100 out 60 waste 30 (30%) recycled 20 (66.67%) valorised 0 cost 40 avg 15",
+			"unknown Unknown: 1 orders 1 records in 50 out 40 waste 5 (10%) recycled 5 (100%) valorised 0 cost 0 avg 5",
+		},
+		BySpecies: {
+			"1 Pine: 2 orders 3 records in 125 out 85 waste 27.5 (22%) recycled 20 (72.73%) valorised 0 cost 30 avg 9.17",
+			"2 Oak: 1 orders 2 records in 25 out 15 waste 7.5 (30%) recycled 5 (66.67%) valorised 0 cost 10 avg 3.75",
+		},
+		ByMonth: {
+			"2026-01 January 2026: 1 orders 2 records in 100 out 60 waste 30 (30%) recycled 20 (66.67%) valorised 0 cost 40 avg 15",
+			"2026-02 February 2026: 1 orders 1 records in 50 out 40 waste 5 (10%) recycled 5 (100%) valorised 0 cost 0 avg 5",
+		},
+		ByWasteType: {
+			"sawdust Sawdust: 0 orders 2 records in 150 out 0 waste 25 (16.67%) recycled 25 (100%) valorised 0 cost 10 avg 12.5",
+			"bark Bark: 0 orders 1 records in 150 out 0 waste 10 (6.67%) recycled 0 (0%) valorised 0 cost 30 avg 10",
+		},
+		ByDisposalMethod: {
+			"pellets Pellets: 0 orders 2 records in 150 out 0 waste 25 (16.67%) recycled 25 (100%) valorised 0 cost 10 avg 12.5",
+			"landfill Landfill: 0 orders 1 records in 150 out 0 waste 10 (6.67%) recycled 0 (0%) valorised 0 cost 30 avg 10",
+		},
+	} {
+		report, err := s.Analyse(ctx, groupBy, from, to)
+		if err != nil {
+			t.Fatalf("%s: %v", groupBy, err)
+		}
+		total := "total Total: 2 orders 3 records in 150 out 100 waste 35 (23.33%) recycled 25 (71.43%) valorised 0 cost 40 avg 11.67"
+		if got := line(report.Total); got != total {
+			t.Errorf("%s: total = %s", groupBy, got)
+		}
+		var got []string
+		for _, g := range report.Groups {
+			got = append(got, line(g))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: groups =\n%q\nwant\n%q", groupBy, got, want)
+		}
+	}
+
+	open, err := s.Analyse(ctx, ByProcessingOrder, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.From != "" || open.To != "" || open.Total.Orders != 3 || len(open.Groups) != 3 {
+		t.Errorf("open period = %+v", open)
+	}
+	if last := open.Groups[2]; last.Key != "3" || last.WastePercent != nil || last.RecyclingRate != nil || last.AvgWastePerRecord != 0 {
+		t.Errorf("order without input or waste = %s", line(last))
+	}
+	if _, err := s.Analyse(ctx, "forest", from, to); err == nil {
+		t.Error("an unknown grouping was accepted")
+	}
+}
+
+func TestValorise(t *testing.T) {
+	ctx := context.Background()
+	repos, s := newMill(t)
+	if err := repos.Warehouses.CreateWarehouse(ctx, &models.Warehouse{Name: "Yard"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Warehouses.CreateProductType(ctx, &models.ProductType{Name: "Pellets", UnitOfMeasure: "m3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bark is received whole and the sawdust as what it made, both into
+	// one lot of the first order
+	bark, err := s.Valorise(ctx, 2, ByProduct{ProductTypeID: 1, WarehouseID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx := bark.Transaction; tx.TransactionType != repository.TxReceipt || tx.Quantity != 10 || tx.StockID != 1 ||
+		!bark.Waste.Recycled || bark.Waste.StockID == nil || *bark.Waste.StockID != 1 {
+		t.Errorf("bark = %+v", bark)
+	}
+	made := 4.456
+	sawdust, err := s.Valorise(ctx, 1, ByProduct{ProductTypeID: 1, WarehouseID: 1, Quantity: &made})
+	if err != nil || sawdust.Transaction.Quantity != 4.46 || sawdust.Transaction.StockID != 1 {
+		t.Errorf("sawdust = %+v, %v", sawdust, err)
+	}
+	var spec query.Spec
+	spec.Where("stock_id", 1)
+	lots, _ := repos.Stock.ListStockItems(ctx, spec)
+	if len(lots.Data) != 1 || lots.Data[0].Quantity != 14.46 || lots.Data[0].ProcessingID == nil || *lots.Data[0].ProcessingID != 1 {
+		t.Errorf("lot = %+v", lots.Data)
+	}
+
+	report, err := s.Analyse(ctx, ByProcessingOrder, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total.RecycledVolume != 35 || report.Total.ValorisedVolume != 30 {
+		t.Errorf("total after valorising = %s", line(report.Total))
+	}
+
+	nothing := 0.0
+	for name, tc := range map[string]struct {
+		id    int
+		bp    ByProduct
+		check func(error) bool
+	}{
+		"valorised twice": {2, ByProduct{ProductTypeID: 1, WarehouseID: 1}, func(err error) bool {
+			var rule *repository.RuleError
+			return errors.As(err, &rule) && rule.Code == "already_valorised"
+		}},
+		"nothing made": {3, ByProduct{ProductTypeID: 1, WarehouseID: 1, Quantity: &nothing}, func(err error) bool {
+			var errs validate.Errors
+			return errors.As(err, &errs) && errs[0].Field == "quantity"
+		}},
+		"unknown product": {3, ByProduct{ProductTypeID: 9, WarehouseID: 1}, func(err error) bool {
+			var ref *repository.ReferenceError
+			return errors.As(err, &ref) && ref.Field == "product_type_id"
+		}},
+		"unknown warehouse": {3, ByProduct{ProductTypeID: 1, WarehouseID: 9}, func(err error) bool {
+			var ref *repository.ReferenceError
+			return errors.As(err, &ref) && ref.Field == "warehouse_id"
+		}},
+		"unknown waste": {9, ByProduct{ProductTypeID: 1, WarehouseID: 1}, func(err error) bool {
+			return errors.Is(err, repository.ErrNotFound)
+		}},
+	} {
+		if _, err := s.Valorise(ctx, tc.id, tc.bp); !tc.check(err) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -2,7 +2,7 @@ import React, { useState } from 'react';
 import { useNavigate, Link } from 'react-router-dom';
 import { LogIn, Mail, Lock, Eye, EyeOff, AlertCircle } from 'lucide-react';
 import { useAuth } from '../../context/AuthContext';
-import { usersAPI } from '../../services/api';
+import { authAPI } from '../../services/api';
 import './Auth.css';
 
 const Login = () => {
@@ -54,38 +54,25 @@ const Login = () => {
     setApiError('');
 
     try {
-      // Get all users and find matching credentials
-      const response = await usersAPI.getAll();
-      const users = response.data || response;
-      
-      console.log('All users:', users);
-      
-      const user = users.find(
-        u => u.email.toLowerCase() === formData.email.toLowerCase()
-      );
-
-      console.log('Found user:', user);
-
-      if (!user) {
-        setApiError('Invalid email or password');
-        setLoading(false);
-        return;
-      }
+      // Credentials are verified by the backend
+      const response = await authAPI.login(formData.email, formData.password);
+      const { access_token, refresh_token, user } = response.data;
 
-      if (user.status !== 'active') {
-        setApiError('Your account is inactive. Please contact support.');
-        setLoading(false);
-        return;
-      }
+      localStorage.setItem('access_token', access_token);
+      localStorage.setItem('refresh_token', refresh_token);
 
-      // In production, password verification would be done on backend
-      // For now, we just check if user exists and is active
-      login(user);
+      await login(user);
       navigate('/', { replace: true });
     } catch (error) {
       console.error('Login error:', error);
       console.error('Error response:', error.response);
-      setApiError('Login failed. Please check your credentials and try again.');
+      if (error.response?.status === 401) {
+        setApiError('Invalid email or password');
+      } else if (error.response?.status === 403) {
+        setApiError('Your account is inactive. Please contact support.');
+      } else {
+        setApiError('Login failed. Please check your credentials and try again.');
+      }
     } finally {
       setLoading(false);
     }
@@ -2,7 +2,7 @@ import React, { useState } from 'react';
 import { useNavigate, Link } from 'react-router-dom';
 import { UserPlus, Mail, Lock, User, Phone, Eye, EyeOff, AlertCircle, CheckCircle } from 'lucide-react';
 import { useAuth } from '../../context/AuthContext';
-import { usersAPI } from '../../services/api';
+import { usersAPI, authAPI } from '../../services/api';
 import './Auth.css';
 
 const Register = () => {
@@ -99,18 +99,15 @@ const Register = () => {
 
       const response = await usersAPI.create(userData);
       console.log('Registration response:', response);
-      
-      // The response might be in response.data
-      const newUser = response.data || response;
-      
+
       // Auto login after registration
-      const userWithFullData = {
-        ...newUser,
-        ...userData,
-        created_at: new Date().toISOString(),
-      };
-      
-      login(userWithFullData);
+      const loginResponse = await authAPI.login(userData.email, userData.password);
+      const { access_token, refresh_token, user } = loginResponse.data;
+
+      localStorage.setItem('access_token', access_token);
+      localStorage.setItem('refresh_token', refresh_token);
+
+      await login(user);
       navigate('/', { replace: true });
     } catch (error) {
       console.error('Registration error:', error);
@@ -8,8 +8,9 @@ const ProcessingOrderForm = ({ item, onClose, onSuccess }) => {
     unit_id: '',
     start_date: '',
     end_date: '',
-    output_quantity: '',
-    efficiency_rate: '',
+    planned_quantity: '',
+    duration_hours: '',
+    operations: '',
   });
   const [productTypes, setProductTypes] = useState([]);
   const [units, setUnits] = useState([]);
@@ -24,8 +25,9 @@ const ProcessingOrderForm = ({ item, onClose, onSuccess }) => {
         unit_id: item.unit_id || '',
         start_date: item.start_date ? item.start_date.split('T')[0] : '',
         end_date: item.end_date ? item.end_date.split('T')[0] : '',
-        output_quantity: item.output_quantity || '',
-        efficiency_rate: item.efficiency_rate || '',
+        planned_quantity: item.planned_quantity || '',
+        duration_hours: item.duration_hours || '',
+        operations: item.operations || '',
       });
     } else {
       setFormData(prev => ({ ...prev, start_date: new Date().toISOString().split('T')[0] }));
@@ -50,7 +52,6 @@ const ProcessingOrderForm = ({ item, onClose, onSuccess }) => {
     if (!formData.product_type_id) newErrors.product_type_id = 'Product type is required';
     if (!formData.unit_id) newErrors.unit_id = 'Processing unit is required';
     if (!formData.start_date) newErrors.start_date = 'Start date is required';
-    if (!formData.output_quantity) newErrors.output_quantity = 'Output quantity is required';
     
     setErrors(newErrors);
     return Object.keys(newErrors).length === 0;
@@ -74,8 +75,8 @@ const ProcessingOrderForm = ({ item, onClose, onSuccess }) => {
         ...formData,
         product_type_id: parseInt(formData.product_type_id),
         unit_id: parseInt(formData.unit_id),
-        output_quantity: parseFloat(formData.output_quantity),
-        efficiency_rate: parseFloat(formData.efficiency_rate) || null,
+        planned_quantity: parseFloat(formData.planned_quantity) || 0,
+        duration_hours: parseFloat(formData.duration_hours) || 0,
       };
 
       if (item) {
@@ -173,33 +174,43 @@ const ProcessingOrderForm = ({ item, onClose, onSuccess }) => {
 
             <div className="form-grid">
               <div className="input-group">
-                <label htmlFor="output_quantity">Output Quantity *</label>
+                <label htmlFor="planned_quantity">Planned Quantity</label>
                 <input
-                  id="output_quantity"
-                  name="output_quantity"
+                  id="planned_quantity"
+                  name="planned_quantity"
                   type="number"
                   step="0.01"
-                  value={formData.output_quantity}
+                  min="0"
+                  value={formData.planned_quantity}
                   onChange={handleChange}
-                  className={errors.output_quantity ? 'error' : ''}
                 />
-                {errors.output_quantity && <span className="error-text">{errors.output_quantity}</span>}
               </div>
 
               <div className="input-group">
-                <label htmlFor="efficiency_rate">Efficiency Rate (%)</label>
+                <label htmlFor="duration_hours">Duration (hours)</label>
                 <input
-                  id="efficiency_rate"
-                  name="efficiency_rate"
+                  id="duration_hours"
+                  name="duration_hours"
                   type="number"
-                  step="0.1"
+                  step="0.25"
                   min="0"
-                  max="100"
-                  value={formData.efficiency_rate}
+                  value={formData.duration_hours}
                   onChange={handleChange}
                 />
               </div>
             </div>
+
+            <div className="input-group">
+              <label htmlFor="operations">Operations</label>
+              <input
+                id="operations"
+                name="operations"
+                type="text"
+                placeholder="cutting, drying, finishing"
+                value={formData.operations}
+                onChange={handleChange}
+              />
+            </div>
           </div>
 
           <div className="modal-footer">
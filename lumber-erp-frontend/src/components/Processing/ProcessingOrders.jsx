@@ -8,6 +8,7 @@ const ProcessingOrders = () => {
     { label: 'Processing ID', field: 'processing_id' },
     { label: 'Product Type ID', field: 'product_type_id' },
     { label: 'Unit ID', field: 'unit_id' },
+    { label: 'Status', field: 'status' },
     { 
       label: 'Start Date', 
       field: 'start_date',
@@ -28,6 +29,11 @@ const ProcessingOrders = () => {
       field: 'efficiency_rate',
       render: (row) => row.efficiency_rate + '%'
     },
+    {
+      label: 'Mass Balance',
+      field: 'mass_balance_variance',
+      render: (row) => row.mass_balance_flagged ? `⚠️ ${row.mass_balance_variance}` : 'OK'
+    },
   ];
 
   return (
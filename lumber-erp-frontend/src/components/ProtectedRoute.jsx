@@ -25,33 +25,19 @@ const ProtectedRoute = ({ children, requiredPermission }) => {
         setLoading(true);
         console.log('🔍 [ProtectedRoute] Fetching permissions for user:', user.user_id);
 
-        const rolesResponse = await axios.get(`${API_URL}/roles`);
-        const userRole = rolesResponse.data.find(role => role.user_id === user.user_id);
-        
-        if (!userRole) {
+        // Roles and permissions are resolved server-side for the signed-in user
+        const response = await axios.get(`${API_URL}/auth/me`);
+        const { roles = [], permissions = [] } = response.data;
+
+        if (roles.length === 0) {
           console.warn('⚠️ [ProtectedRoute] No role found for user');
           setUserPermissions([]);
           setLoading(false);
           return;
         }
 
-        console.log('✅ [ProtectedRoute] User role:', userRole);
-
-        const rolePermsResponse = await axios.get(`${API_URL}/rolepermissions`);
-        const userRolePerms = rolePermsResponse.data.filter(
-          rp => rp.role_id === userRole.role_id
-        );
-
-        const permissionsResponse = await axios.get(`${API_URL}/permissions`);
-        const allPermissions = permissionsResponse.data;
-
-        const userPermissionDetails = userRolePerms.map(rp => {
-          const permDetail = allPermissions.find(p => p.permission_id === rp.permission_id);
-          return permDetail;
-        }).filter(Boolean);
-
-        console.log('✅ [ProtectedRoute] User permissions:', userPermissionDetails);
-        setUserPermissions(userPermissionDetails);
+        console.log('✅ [ProtectedRoute] User permissions:', permissions);
+        setUserPermissions(permissions);
 
       } catch (error) {
         console.error('❌ [ProtectedRoute] Error fetching permissions:', error);
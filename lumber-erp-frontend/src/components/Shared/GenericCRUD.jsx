@@ -104,8 +104,8 @@ const GenericCRUD = ({
         <DataTable
           columns={columns}
           data={filteredItems}
-          onEdit={handleEdit}
-          onDelete={handleDelete}
+          onEdit={apiService.update ? handleEdit : undefined}
+          onDelete={apiService.delete ? handleDelete : undefined}
           idField={idField}
           loading={loading}
         />
@@ -5,7 +5,7 @@ import { inventoryTransactionsAPI, stockItemsAPI } from '../../services/api';
 const InventoryTransactionForm = ({ item, onClose, onSuccess }) => {
   const [formData, setFormData] = useState({
     stock_id: '',
-    transaction_type: 'IN',
+    transaction_type: 'receipt',
     quantity: '',
     transaction_date: '',
     reference_id: '',
@@ -14,14 +14,14 @@ const InventoryTransactionForm = ({ item, onClose, onSuccess }) => {
   const [loading, setLoading] = useState(false);
   const [errors, setErrors] = useState({});
 
-  const transactionTypes = ['IN', 'OUT', 'TRANSFER', 'ADJUSTMENT'];
+  const transactionTypes = ['receipt', 'issue', 'adjustment', 'transfer_out', 'transfer_in', 'scrap'];
 
   useEffect(() => {
     fetchStockItems();
     if (item) {
       setFormData({
         stock_id: item.stock_id || '',
-        transaction_type: item.transaction_type || 'IN',
+        transaction_type: item.transaction_type || 'receipt',
         quantity: item.quantity || '',
         transaction_date: item.transaction_date ? item.transaction_date.split('T')[0] : '',
         reference_id: item.reference_id || '',
@@ -75,11 +75,7 @@ const InventoryTransactionForm = ({ item, onClose, onSuccess }) => {
 
       console.log('Submitting data:', submitData);
 
-      if (item) {
-        await inventoryTransactionsAPI.update(item.transaction_id, submitData);
-      } else {
-        await inventoryTransactionsAPI.create(submitData);
-      }
+      await inventoryTransactionsAPI.create(submitData);
       onSuccess();
     } catch (error) {
       console.error('Error saving transaction:', error);
@@ -13,8 +13,7 @@ const InventoryTransactions = () => {
       field: 'transaction_type',
       render: (row) => (
         <span className={`badge badge-${
-          row.transaction_type === 'IN' ? 'success' : 
-          row.transaction_type === 'OUT' ? 'warning' : 'info'
+          row.reverses_id ? 'info' : row.quantity >= 0 ? 'success' : 'warning'
         }`}>
           {row.transaction_type}
         </span>
@@ -30,6 +29,11 @@ const InventoryTransactions = () => {
       field: 'transaction_date',
       render: (row) => row.transaction_date ? new Date(row.transaction_date).toLocaleDateString() : 'N/A'
     },
+    {
+      label: 'Balance After',
+      field: 'balance_after',
+      render: (row) => row.balance_after != null ? row.balance_after.toLocaleString() : 'N/A'
+    },
     { label: 'Reference', field: 'reference_id' },
   ];
 
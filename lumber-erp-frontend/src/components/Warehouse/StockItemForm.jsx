@@ -6,6 +6,8 @@ const StockItemForm = ({ item, onClose, onSuccess }) => {
   const [formData, setFormData] = useState({
     warehouse_id: '',
     product_type_id: '',
+    batch_id: '',
+    processing_id: '',
     quantity_in_stock: '',
     shelf_location: '',
     last_restocked: '',
@@ -21,6 +23,8 @@ const StockItemForm = ({ item, onClose, onSuccess }) => {
       setFormData({
         warehouse_id: item.warehouse_id || '',
         product_type_id: item.product_type_id || '',
+        batch_id: item.batch_id || '',
+        processing_id: item.processing_id || '',
         quantity_in_stock: item.quantity_in_stock || '',
         shelf_location: item.shelf_location || '',
         last_restocked: item.last_restocked ? item.last_restocked.split('T')[0] : '',
@@ -70,6 +74,8 @@ const StockItemForm = ({ item, onClose, onSuccess }) => {
       const submitData = {
         warehouse_id: parseInt(formData.warehouse_id),
         product_type_id: parseInt(formData.product_type_id),
+        batch_id: formData.batch_id ? parseInt(formData.batch_id) : null,
+        processing_id: formData.processing_id ? parseInt(formData.processing_id) : null,
         quantity_in_stock: parseFloat(formData.quantity_in_stock),
         shelf_location: formData.shelf_location,
         last_restocked: formData.last_restocked,
@@ -145,6 +151,30 @@ const StockItemForm = ({ item, onClose, onSuccess }) => {
               {errors.product_type_id && <span className="error-text">{errors.product_type_id}</span>}
             </div>
 
+            <div className="form-grid">
+              <div className="input-group">
+                <label htmlFor="batch_id">Harvest Batch ID</label>
+                <input
+                  id="batch_id"
+                  name="batch_id"
+                  type="number"
+                  value={formData.batch_id}
+                  onChange={handleChange}
+                />
+              </div>
+
+              <div className="input-group">
+                <label htmlFor="processing_id">Processing Order ID</label>
+                <input
+                  id="processing_id"
+                  name="processing_id"
+                  type="number"
+                  value={formData.processing_id}
+                  onChange={handleChange}
+                />
+              </div>
+            </div>
+
             <div className="form-grid">
               <div className="input-group">
                 <label htmlFor="quantity_in_stock">Quantity in Stock *</label>
@@ -8,6 +8,11 @@ const StockItems = () => {
     { label: 'Stock ID', field: 'stock_id' },
     { label: 'Warehouse ID', field: 'warehouse_id' },
     { label: 'Product Type ID', field: 'product_type_id' },
+    {
+      label: 'Lot',
+      field: 'batch_id',
+      render: (row) => row.batch_id ? `Batch ${row.batch_id}` : row.processing_id ? `Order ${row.processing_id}` : '-'
+    },
     { 
       label: 'Quantity', 
       field: 'quantity_in_stock',
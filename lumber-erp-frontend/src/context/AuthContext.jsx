@@ -119,10 +119,10 @@ export const AuthProvider = ({ children }) => {
   const fetchUserPermissions = async (roleId) => {
     try {
       console.log('📋 Fetching permissions for role_id:', roleId);
-      const response = await axios.get(`${API_URL}/rolepermissions/role?role_id=${roleId}`);
-      console.log('✅ Permissions loaded:', response.data);
+      const response = await axios.get(`${API_URL}/auth/me`);
+      console.log('✅ Permissions loaded:', response.data.permissions);
       
-      const perms = response.data || [];
+      const perms = response.data.permissions || [];
       setPermissions(perms);
       localStorage.setItem('permissions', JSON.stringify(perms));
       
@@ -140,8 +140,8 @@ export const AuthProvider = ({ children }) => {
   const fetchUserRole = async (userId) => {
     try {
       console.log('👤 Fetching role for user_id:', userId);
-      const response = await axios.get(`${API_URL}/roles`);
-      const userRole = response.data.find(role => role.user_id === userId);
+      const response = await axios.get(`${API_URL}/auth/me`);
+      const userRole = (response.data.roles || [])[0];
       
       if (userRole) {
         console.log('✅ Role found:', userRole);
@@ -211,10 +211,17 @@ export const AuthProvider = ({ children }) => {
   
   const logout = () => {
     console.log('👋 Logging out...');
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (refreshToken) {
+      axios.post(`${API_URL}/auth/logout`, { refresh_token: refreshToken })
+        .catch(error => console.error('❌ Error ending session:', error));
+    }
     setUser(null);
     setPermissions([]);
     localStorage.removeItem('user');
     localStorage.removeItem('permissions');
+    localStorage.removeItem('access_token');
+    localStorage.removeItem('refresh_token');
   };
 
   // ==================== UPDATE USER FUNCTION ====================
@@ -10,15 +10,49 @@ const api = axios.create({
   timeout: 10000,
 });
 
-// Response interceptor
+// Attach the access token to every request (also for components using axios directly)
+const attachToken = (config) => {
+  const token = localStorage.getItem('access_token');
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+};
+api.interceptors.request.use(attachToken);
+axios.interceptors.request.use(attachToken);
+
+// Response interceptor - retry once with a refreshed token on 401
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const original = error.config;
+    const refreshToken = localStorage.getItem('refresh_token');
+
+    if (error.response?.status === 401 && refreshToken && original && !original._retried) {
+      original._retried = true;
+      try {
+        const { data } = await axios.post(`${API_BASE_URL}/auth/refresh`, { refresh_token: refreshToken });
+        localStorage.setItem('access_token', data.access_token);
+        localStorage.setItem('refresh_token', data.refresh_token);
+        return api(original);
+      } catch (refreshError) {
+        localStorage.removeItem('access_token');
+        localStorage.removeItem('refresh_token');
+      }
+    }
+
     console.error('API Error:', error.response || error);
     return Promise.reject(error);
   }
 );
 
+// ===== AUTHENTICATION =====
+export const authAPI = {
+  login: (email, password) => api.post('/auth/login', { email, password }),
+  logout: (refreshToken) => api.post('/auth/logout', { refresh_token: refreshToken }),
+  refresh: (refreshToken) => api.post('/auth/refresh', { refresh_token: refreshToken }),
+};
+
 // ===== USER MANAGEMENT =====
 export const usersAPI = {
   getAll: () => api.get('/users'),
@@ -26,6 +60,8 @@ export const usersAPI = {
   create: (data) => api.post('/users', data),
   update: (id, data) => api.put(`/user?id=${id}`, data),
   delete: (id) => api.delete(`/user?id=${id}`),
+  changePassword: (id, currentPassword, newPassword) =>
+    api.put(`/user/password?id=${id}`, { current_password: currentPassword, new_password: newPassword }),
 };
 
 export const permissionsAPI = {
@@ -194,8 +230,8 @@ export const stockAlertsAPI = {
 export const inventoryTransactionsAPI = {
   getAll: () => api.get('/inventorytransactions'),
   create: (data) => api.post('/inventorytransactions', data),
-  update: (id, data) => api.put(`/inventorytransaction?id=${id}`, data),
-  delete: (id) => api.delete(`/inventorytransaction?id=${id}`),
+  // Transactions are immutable; a reversal posts the opposite entry
+  reverse: (id, data = {}) => api.post(`/inventorytransaction/reverse?id=${id}`, data),
 };
 
 // ===== PROCUREMENT =====
@@ -296,7 +332,6 @@ export const fuelLogsAPI = {
 // ===== AUDIT & LOGS =====
 export const auditLogsAPI = {
   getAll: () => api.get('/auditlogs'),
-  create: (data) => api.post('/auditlogs', data),
 };
 
 export default api;